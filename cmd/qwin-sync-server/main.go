@@ -0,0 +1,88 @@
+// Command qwin-sync-server keeps the usage change logs of qwin devices and serves them
+// back to the other devices. See docs/SYNC_PROTOCOL.md.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/syncserver"
+)
+
+// shutdownTimeout bounds waiting for requests in progress when stopping
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	addr := flag.String("addr", ":8787", "address to listen on")
+	dbPath := flag.String("db", "qwin-sync.db", "SQLite database keeping the change logs")
+	tokensPath := flag.String("tokens", "", "file of access tokens, one per line, each optionally followed by the device ID it may upload as")
+	certFile := flag.String("tls-cert", "", "TLS certificate file; serves plain HTTP when unset")
+	keyFile := flag.String("tls-key", "", "TLS private key file")
+	flag.Parse()
+
+	if *tokensPath == "" {
+		log.Fatal("-tokens is required")
+	}
+	if (*certFile == "") != (*keyFile == "") {
+		log.Fatal("-tls-cert and -tls-key must be set together")
+	}
+
+	file, err := os.Open(*tokensPath)
+	if err != nil {
+		log.Fatalf("Failed to open token file: %v", err)
+	}
+	tokens, err := syncserver.ParseTokens(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("Failed to read token file: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := syncserver.OpenStore(ctx, *dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer store.Close()
+
+	server, err := syncserver.NewServer(store, tokens, logging.NewDefaultLogger())
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		WriteTimeout:      time.Minute,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error during shutdown: %v", err)
+		}
+	}()
+
+	log.Printf("Serving %d access tokens on %s", len(tokens), *addr)
+	if *certFile != "" {
+		err = httpServer.ListenAndServeTLS(*certFile, *keyFile)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
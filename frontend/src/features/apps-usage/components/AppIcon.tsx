@@ -11,8 +11,11 @@ export function AppIcon({
   iconPath,
   className = "w-4 h-4",
 }: AppIconProps) {
-  // If we have a real extracted icon (base64 data URL), use it
-  if (iconPath && iconPath.startsWith("data:image/")) {
+  // If we have a real extracted icon (base64 data URL or stored icon URL), use it
+  if (
+    iconPath &&
+    (iconPath.startsWith("data:image/") || iconPath.startsWith("/icons/"))
+  ) {
     return (
       <img
         src={iconPath}
@@ -66,7 +66,7 @@ export function AppUsageChart({ apps, isLoading }: AppUsageChartProps) {
   }
 
   const chartData = apps.slice(0, APP_CONFIG.MAX_APPS_DISPLAY).map((app) => ({
-    appName: app.name,
+    appName: app.displayName || app.name, // alias, if the application has one
     AppUsage: Math.round(app.duration / 60), // Convert seconds to minutes
     iconPath: app.iconPath, // Store icon path for custom tick
   }));
@@ -0,0 +1,34 @@
+import { AlertTriangle, X } from "lucide-react";
+
+import { useDatabaseIntegrity } from "../hooks/useDatabaseIntegrity";
+
+/** Tells the user when the startup check found problems or had to recover the database */
+export function DatabaseNotice() {
+  const { integrity, message, dismiss } = useDatabaseIntegrity();
+
+  if (!message) {
+    return null;
+  }
+
+  const recovered = Boolean(integrity?.recovery);
+
+  return (
+    <div
+      className={`flex items-start gap-2 px-3 py-1.5 border-b text-xs ${
+        recovered ? "text-destructive" : "text-muted-foreground"
+      }`}
+      title={integrity?.recovery?.reason}
+    >
+      <AlertTriangle className="w-3.5 h-3.5 shrink-0 mt-0.5" />
+      <span className="flex-1">{message}</span>
+      <button
+        type="button"
+        onClick={dismiss}
+        className="shrink-0 hover:text-foreground"
+        aria-label="Dismiss"
+      >
+        <X className="w-3.5 h-3.5" />
+      </button>
+    </div>
+  );
+}
@@ -0,0 +1,23 @@
+import { Sparkles } from "lucide-react";
+
+import { useInsights } from "../hooks/useInsights";
+
+/** Shows the most significant insight about today's usage, if there is one */
+export function InsightBanner() {
+  const { insights } = useInsights();
+  const insight = insights[0];
+
+  if (!insight) {
+    return null;
+  }
+
+  return (
+    <div
+      className="flex items-center gap-2 px-3 py-1 border-b text-xs text-muted-foreground"
+      title={insight.explanation}
+    >
+      <Sparkles className="w-3.5 h-3.5 shrink-0 text-primary" />
+      <span className="truncate">{insight.message}</span>
+    </div>
+  );
+}
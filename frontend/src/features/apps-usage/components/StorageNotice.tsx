@@ -0,0 +1,22 @@
+import { DatabaseZap } from "lucide-react";
+
+import { useStorageStatus } from "../hooks/useStorageStatus";
+
+/** Tells the user while usage can't be saved because storage keeps failing */
+export function StorageNotice() {
+  const { status, isAvailable, message } = useStorageStatus();
+
+  if (isAvailable || !message) {
+    return null;
+  }
+
+  return (
+    <div
+      className="flex items-start gap-2 px-3 py-1.5 border-b text-xs text-destructive"
+      title={status?.lastError}
+    >
+      <DatabaseZap className="w-3.5 h-3.5 shrink-0 mt-0.5" />
+      <span className="flex-1">{message}</span>
+    </div>
+  );
+}
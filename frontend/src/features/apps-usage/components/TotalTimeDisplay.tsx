@@ -1,7 +1,9 @@
-import { ChevronRight, Clock } from "lucide-react";
+import { ChevronRight, Clock, EyeOff, Pause, Play } from "lucide-react";
 import { formatTime } from "@/utils/dateTimeFormatter";
 import { Button } from "@/components/ui/button";
 
+import { useTrackingState } from "../hooks/useTrackingState";
+
 interface TotalTimeDisplayProps {
   totalTime: number;
   isLoading: boolean;
@@ -11,6 +13,9 @@ export function TotalTimeDisplay({
   totalTime,
   isLoading,
 }: TotalTimeDisplayProps) {
+  const { isPaused, pausedUntil, isPrivate, pause, resume } =
+    useTrackingState();
+
   return (
     <div className="px-3 py-1 border-b">
       <div className="flex items-center justify-between gap-2">
@@ -23,8 +28,33 @@ export function TotalTimeDisplay({
               {formatTime(totalTime)}
             </span>
           )}
+          {isPaused && (
+            <span className="text-xs text-muted-foreground">
+              {pausedUntil
+                ? `Paused until ${pausedUntil.toLocaleTimeString([], {
+                    hour: "numeric",
+                    minute: "2-digit",
+                  })}`
+                : "Paused"}
+            </span>
+          )}
+          {isPrivate && (
+            <EyeOff
+              className="w-3.5 h-3.5 text-muted-foreground"
+              aria-label="Private mode"
+            />
+          )}
         </div>
-        <div className="p-1">
+        <div className="flex items-center gap-1 p-1">
+          <Button
+            variant="ghost"
+            size="icon"
+            className="text-muted-foreground rounded-full border border-white/5"
+            title={isPaused ? "Resume tracking" : "Pause tracking"}
+            onClick={() => (isPaused ? resume() : pause())}
+          >
+            {isPaused ? <Play /> : <Pause />}
+          </Button>
           <Button
             variant="ghost"
             size="icon"
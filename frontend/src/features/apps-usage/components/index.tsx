@@ -2,7 +2,10 @@ import { APP_CONFIG } from "@/constants/app";
 
 import { useScreenTime } from "../hooks/useScreenTime";
 
+import { DatabaseNotice } from "./DatabaseNotice";
+import { StorageNotice } from "./StorageNotice";
 import { TotalTimeDisplay } from "./TotalTimeDisplay";
+import { InsightBanner } from "./InsightBanner";
 import { AppUsageChart } from "./AppUsageChart";
 import { ErrorState } from "./ErrorState";
 
@@ -17,7 +20,10 @@ export function ScreenTimeWidget() {
 
   return (
     <>
+      <DatabaseNotice />
+      <StorageNotice />
       <TotalTimeDisplay totalTime={usageData.totalTime} isLoading={isLoading} />
+      <InsightBanner />
       <AppUsageChart apps={usageData.apps} isLoading={isLoading} />
     </>
   );
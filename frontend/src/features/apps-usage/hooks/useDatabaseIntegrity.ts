@@ -0,0 +1,27 @@
+import { useState, useEffect } from "react";
+import { GetDatabaseIntegrity } from "@wailsjs/go/app/App";
+import { app } from "@wailsjs/go/models";
+
+/**
+ * Custom hook for the outcome of the database check run at startup
+ * @returns The check's outcome, and a way to dismiss its message
+ */
+export function useDatabaseIntegrity() {
+  const [integrity, setIntegrity] = useState<app.DatabaseIntegrity | null>(
+    null
+  );
+  const [dismissed, setDismissed] = useState(false);
+
+  useEffect(() => {
+    // The check only runs once per launch, so there is nothing to poll for
+    GetDatabaseIntegrity()
+      .then(setIntegrity)
+      .catch((err) => console.error("Failed to load database integrity:", err));
+  }, []);
+
+  return {
+    integrity,
+    message: dismissed ? "" : integrity?.message ?? "",
+    dismiss: () => setDismissed(true),
+  };
+}
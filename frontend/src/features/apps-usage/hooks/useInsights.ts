@@ -0,0 +1,29 @@
+import { useState, useEffect } from "react";
+import { GetInsights } from "@wailsjs/go/app/App";
+import { types } from "@wailsjs/go/models";
+
+/** How often insights are re-checked; they change far slower than usage totals */
+const INSIGHTS_REFRESH_INTERVAL = 5 * 60 * 1000;
+
+/**
+ * Custom hook for insights about unusual usage
+ * @param days - How many recent days to include, today included (default: 1)
+ * @returns The insights, newest and most significant first
+ */
+export function useInsights(days: number = 1) {
+  const [insights, setInsights] = useState<types.Insight[]>([]);
+
+  useEffect(() => {
+    const loadInsights = () =>
+      GetInsights(days)
+        .then((result) => setInsights(result ?? []))
+        .catch((err) => console.error("Failed to load insights:", err));
+
+    loadInsights();
+    const interval = setInterval(loadInsights, INSIGHTS_REFRESH_INTERVAL);
+
+    return () => clearInterval(interval);
+  }, [days]);
+
+  return { insights };
+}
@@ -0,0 +1,31 @@
+import { useState, useEffect } from "react";
+import { GetStorageStatus } from "@wailsjs/go/app/App";
+import { app } from "@wailsjs/go/models";
+import { EventsOn } from "@wailsjs/runtime/runtime";
+
+/** Event emitted by the backend whenever storage becomes unavailable or available again */
+const STORAGE_STATUS_EVENT = "storage:status";
+
+/**
+ * Custom hook for whether usage can currently be saved to the database
+ * @returns The storage status, and whether storage is available
+ */
+export function useStorageStatus() {
+  const [status, setStatus] = useState<app.StorageStatus | null>(null);
+
+  useEffect(() => {
+    GetStorageStatus()
+      .then(setStatus)
+      .catch((err) => console.error("Failed to load storage status:", err));
+
+    return EventsOn(STORAGE_STATUS_EVENT, (next: app.StorageStatus) =>
+      setStatus(next)
+    );
+  }, []);
+
+  return {
+    status,
+    isAvailable: status?.available ?? true,
+    message: status?.message ?? "",
+  };
+}
@@ -0,0 +1,40 @@
+import { useState, useEffect } from "react";
+import {
+  GetTrackingState,
+  PauseTracking,
+  ResumeTracking,
+  SetPrivateMode,
+} from "@wailsjs/go/app/App";
+import { types } from "@wailsjs/go/models";
+import { EventsOn } from "@wailsjs/runtime/runtime";
+
+/** Event emitted by the backend whenever pause or private mode changes */
+const TRACKING_STATE_EVENT = "tracking:state";
+
+/**
+ * Custom hook exposing the tracker's pause and private mode state
+ * @returns The current tracking state and actions to change it
+ */
+export function useTrackingState() {
+  const [state, setState] = useState<types.TrackingState | null>(null);
+
+  useEffect(() => {
+    GetTrackingState()
+      .then(setState)
+      .catch((err) => console.error("Failed to load tracking state:", err));
+
+    // The backend also changes state on its own, e.g. when a timed pause ends
+    return EventsOn(TRACKING_STATE_EVENT, (next: types.TrackingState) =>
+      setState(next)
+    );
+  }, []);
+
+  return {
+    isPaused: state?.paused ?? false,
+    pausedUntil: state?.pausedUntil ? new Date(state.pausedUntil) : null,
+    isPrivate: state?.privateMode ?? false,
+    pause: (minutes: number = 0) => PauseTracking(minutes),
+    resume: () => ResumeTracking(),
+    setPrivateMode: (enabled: boolean) => SetPrivateMode(enabled),
+  };
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+// CompareThisWeekToLast compares this week so far with the same days of last week
+func (a *App) CompareThisWeekToLast() (*types.PeriodComparison, error) {
+	today := a.analytics.Today()
+	a.saveIfIncludesToday(today)
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+	return a.analytics.CompareWeekToDate(ctx, today)
+}
+
+// ComparePeriods compares a date range, both dates inclusive, with the equally long range before it
+func (a *App) ComparePeriods(startYear, startMonth, startDay, endYear, endMonth, endDay int) (*types.PeriodComparison, error) {
+	start := time.Date(startYear, time.Month(startMonth), startDay, 0, 0, 0, 0, time.UTC)
+	end := time.Date(endYear, time.Month(endMonth), endDay, 0, 0, 0, 0, time.UTC)
+	a.saveIfIncludesToday(end)
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+	return a.analytics.ComparePeriods(ctx, start, end)
+}
+
+// GetRollingAverages returns the daily totals of the last days days, today included,
+// each with the average over the window days ending on it
+func (a *App) GetRollingAverages(days, window int) ([]types.RollingAveragePoint, error) {
+	start, end, err := a.recentDays("GetRollingAverages", days)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+	return a.analytics.RollingAverages(ctx, start, end, window)
+}
+
+// GetAppTrends returns the usage trend of every app used in the last days days, today included
+func (a *App) GetAppTrends(days int) ([]types.AppTrend, error) {
+	start, end, err := a.recentDays("GetAppTrends", days)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+	return a.analytics.AppTrends(ctx, start, end)
+}
+
+// GetTopMovers returns up to limit apps whose usage over the last days days grew or shrank
+// the most compared with the days before
+func (a *App) GetTopMovers(days, limit int) (*types.AppMovers, error) {
+	start, end, err := a.recentDays("GetTopMovers", days)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+	return a.analytics.TopMovers(ctx, start, end, limit)
+}
+
+// GetInsights re-checks today and yesterday for unusual usage and returns the insights
+// found over the last days days, newest and most significant first
+func (a *App) GetInsights(days int) ([]types.Insight, error) {
+	start, end, err := a.recentDays("GetInsights", days)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+
+	if a.tracker.IsPersistenceEnabled() {
+		if err := a.anomalies.Refresh(ctx); err != nil {
+			a.logger.Warn("Failed to refresh usage insights", "error", err)
+		}
+	}
+	return a.anomalies.GetInsights(ctx, start, end)
+}
+
+// recentDays returns the range covering the last days usage days, ending today,
+// after saving the time tracked so far today
+func (a *App) recentDays(operation string, days int) (time.Time, time.Time, error) {
+	if days <= 0 {
+		return time.Time{}, time.Time{}, errors.NewRepositoryError(operation,
+			fmt.Errorf("days must be positive, got %d", days), errors.ErrCodeValidation)
+	}
+
+	end := a.analytics.Today()
+	a.saveIfIncludesToday(end)
+	return end.AddDate(0, 0, -(days - 1)), end, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"qwin/internal/database"
@@ -27,40 +29,118 @@ type App struct {
 	dbService   database.Service
 	repository  repository.UsageRepository
 	logger      logging.Logger
+	logOutput   *logging.LeveledLogger   // filters by level and writes the log file
+	errorLog    *logging.RecordingLogger // keeps recent warnings and errors for status reports
+
+	// startupCheck is what the integrity check found when the database was opened
+	startupCheck *database.StartupCheck
+
+	// Reconnection attempts while the database is unavailable; see persistence.go
+	recoveryMutex sync.Mutex
+	stopRecovery  chan struct{}
+
+	// Periodic health checks; see status.go
+	selfCheckMutex sync.Mutex
+	stopSelfCheck  chan struct{}
+
+	// What identifying detail recorded data keeps; see privacy.go
+	privacyMu     sync.RWMutex
+	privacyPolicy types.PrivacyPolicy
+
+	// Working hours usage is split by; see schedule.go
+	scheduleMu   sync.RWMutex
+	workSchedule types.WorkSchedule
+
+	// Usage synced with other devices through a shared folder or a sync server; see sync.go
+	syncMu sync.Mutex
+	syncer usageSyncer
+
+	// dataDir holds files kept next to the database; empty for in-memory databases
+	dataDir string
+
+	// Profiles and scheduled switches between them; see profiles.go. baseConfig opens the
+	// default profile's database, and the others are derived from it.
+	baseConfig            *database.Config
+	profileMu             sync.Mutex
+	profiles              *types.ProfileConfig
+	profileScheduleMu     sync.Mutex
+	stopProfileScheduleCh chan struct{}
+
+	reports         *services.ReportService
+	reportScheduler *services.ReportScheduler
+	analytics       *services.AnalyticsService
+	anomalies       *services.AnomalyDetector
 }
 
 // NewApp creates a new App application struct with dependency injection
 func NewApp(env string) (*App, error) {
-	// Initialize logger first (required by all other components)
-	logger := logging.NewDefaultLogger()
-
 	// Initialize database configuration based on environment
 	config := database.ConfigForEnvironment(env)
 
-	// Initialize database service with logger
-	dbService := database.NewSQLiteService(logger)
-	if err := dbService.Connect(context.Background(), config); err != nil {
+	// Initialize logger first (required by all other components); components log through
+	// child loggers, and recent warnings and errors are kept for status reports
+	logOutput := newLogger(env, config)
+	logger := logging.NewRecordingLogger(logOutput, recentErrorCapacity)
+	errors.SetRetryLogger(errors.NewLoggerBridge(logger.Component("retry")))
+
+	// Open the active profile's database; a damaged profile list falls back to the default profile
+	profiles, err := database.LoadProfiles(database.ProfilesPath(config))
+	if err != nil {
+		logger.Warn("Failed to load profiles, using the default profile", "error", err)
+		profiles = types.NewProfileConfig()
+	}
+	baseConfig := config
+	config = baseConfig.ForProfile(profiles.Active)
+
+	// Initialize database service with logger, recovering the database if it is corrupt
+	dbLogger := logger.Component("database")
+	dbService := database.NewSQLiteService(dbLogger)
+	startupCheck, err := database.OpenWithRecovery(context.Background(), dbService, config, dbLogger)
+	if err != nil {
+		logOutput.Close()
 		return nil, err
 	}
 
 	// Run database migrations
 	if err := dbService.Migrate(context.Background()); err != nil {
 		dbService.Close()
+		logOutput.Close()
 		return nil, err
 	}
 
 	// Initialize repository with database service and logger
-	repo := repository.NewSQLiteRepository(dbService, logger)
+	repo := repository.NewSQLiteRepository(dbService, logger.Component("repository"))
 
 	// Initialize services with repository dependency
-	tracker := services.NewScreenTimeTracker(repo, logger)
+	tracker := services.NewScreenTimeTracker(repo, logger.Component("tracker"))
+	reports := services.NewReportService(repo, logger.Component("reports"))
+
+	// Scheduled reports and the write-behind journal live next to the database;
+	// in-memory databases get neither
+	var reportScheduler *services.ReportScheduler
+	dataDir := ""
+	if config.Path != ":memory:" {
+		dataDir = filepath.Dir(config.Path)
+		reportScheduler = services.NewReportScheduler(reports, filepath.Join(dataDir, "reports"), logger.Component("reports"))
+		tracker.SetWriteBehindJournal(filepath.Join(dataDir, writeBehindJournalName))
+	}
 
 	return &App{
-		tracker:     tracker,
-		environment: env,
-		dbService:   dbService,
-		repository:  repo,
-		logger:      logger,
+		tracker:         tracker,
+		environment:     env,
+		dbService:       dbService,
+		repository:      repo,
+		logger:          logger,
+		logOutput:       logOutput,
+		errorLog:        logger,
+		startupCheck:    startupCheck,
+		reports:         reports,
+		reportScheduler: reportScheduler,
+		dataDir:         dataDir,
+		baseConfig:      baseConfig,
+		profiles:        profiles,
+		analytics:       services.NewAnalyticsService(repo, logger.Component("analytics")),
+		anomalies:       services.NewAnomalyDetector(repo, logger.Component("insights")),
 	}, nil
 }
 
@@ -68,18 +148,52 @@ func NewApp(env string) (*App, error) {
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
 
+	// Tell the frontend when storage keeps failing and usage can't be saved
+	a.watchStorageStatus()
+
 	// Initialize database and run migrations with proper error handling
 	if err := a.initializeDatabase(ctx); err != nil {
 		log.Printf("Database initialization failed: %v", err)
-		// Implement graceful degradation - continue without persistence
-		log.Printf("Continuing without database persistence - data will not be saved")
-		// TODO: Notify user through UI that data persistence is unavailable
-		a.tracker.SetPersistenceEnabled(false) // Add a flag to tracker to indicate no persistence
+		// Keep tracking without the database; usage is queued and journaled until it is back
+		log.Printf("Continuing without database persistence - usage will be written once it reconnects")
+		a.tracker.SetPersistenceEnabled(false)
+		a.startPersistenceRecovery(ctx)
 	}
 
+	// Apply the saved day boundary before the tracker picks today's date
+	a.loadDayBoundary(ctx)
+
+	// Ignore rules and the privacy policy must be in place before the first app is attributed
+	a.loadPrivacyPolicy(ctx)
+	a.loadWorkSchedule(ctx)
+	if a.tracker.IsPersistenceEnabled() {
+		if err := a.loadIgnoreRules(ctx); err != nil {
+			a.logger.Warn("Failed to load ignore rules", "error", err)
+		}
+	}
+
+	// Re-apply a pause or private mode left over from the last run before the first tick,
+	// so no time is attributed to the foreground app meanwhile, then report later changes
+	a.restoreTrackingState(ctx)
+	a.tracker.SetStateChangeHandler(a.onTrackingStateChange)
+
 	// Start the screen time tracker
 	a.tracker.Start()
 
+	// Write any weekly or monthly reports that came due while the app was closed
+	if a.reportScheduler != nil && a.tracker.IsPersistenceEnabled() {
+		a.reportScheduler.Start()
+	}
+
+	// Merge usage from other devices and share this one's
+	a.startSync(ctx)
+
+	// Switch profiles at their scheduled times, including one that came due while closed
+	a.startProfileSchedule(ctx)
+
+	// Check subsystem health in the background and report when it changes
+	a.startSelfCheck(ctx)
+
 	log.Printf("Application started successfully in %s mode", a.environment)
 }
 
@@ -119,12 +233,15 @@ func (a *App) initializeDatabase(ctx context.Context) error {
 // reconnectDatabase handles database reconnection and migration
 func (a *App) reconnectDatabase(ctx context.Context) error {
 	log.Printf("Database connection lost, attempting to reconnect...")
+	return a.connectDatabase(ctx, a.databaseConfig())
+}
 
+// connectDatabase replaces the database connection with one opened from config and migrates it
+func (a *App) connectDatabase(ctx context.Context, config *database.Config) error {
 	// Attempt reconnection with timeout
 	reconnectCtx, reconnectCancel := context.WithTimeout(ctx, 10*time.Second)
 	defer reconnectCancel()
 
-	config := database.ConfigForEnvironment(a.environment)
 	if err := a.dbService.Connect(reconnectCtx, config); err != nil {
 		return errors.NewRepositoryErrorWithContext("startup",
 			err,
@@ -149,7 +266,10 @@ func (a *App) reconnectDatabase(ctx context.Context) error {
 			})
 	}
 
-	log.Printf("Database reconnected and migrations completed successfully")
+	// Failures recorded against the old connection no longer apply
+	a.resetStorageStatus()
+
+	log.Printf("Database connected and migrations completed successfully")
 	return nil
 }
 
@@ -167,6 +287,9 @@ func (a *App) BeforeClose(ctx context.Context) (prevent bool) {
 func (a *App) Shutdown(ctx context.Context) {
 	log.Printf("Starting application shutdown sequence...")
 
+	// No profile switch may start while shutting down
+	a.stopProfileSchedule()
+
 	// Create a timeout context for shutdown operations
 	shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -176,15 +299,29 @@ func (a *App) Shutdown(ctx context.Context) {
 		log.Printf("Warning: Failed to persist final data during shutdown: %v", err)
 	}
 
+	a.stopPersistenceRecovery()
+	a.stopSelfChecks()
+
 	// Stop the tracker after ensuring data persistence
 	a.tracker.Stop()
 
+	if a.reportScheduler != nil {
+		a.reportScheduler.Stop()
+	}
+	a.stopSync()
+
 	// Close database connection with proper error handling
 	if err := a.closeDatabaseConnection(shutdownCtx); err != nil {
 		log.Printf("Error during database closure: %v", err)
 	}
 
 	log.Printf("Application shutdown completed")
+
+	if a.logOutput != nil {
+		if err := a.logOutput.Close(); err != nil {
+			log.Printf("Error closing log file: %v", err)
+		}
+	}
 }
 
 // ensureFinalDataPersistence saves any pending data before shutdown
@@ -295,18 +432,24 @@ func (a *App) GetHistoricalUsage(days int) (map[string]*types.UsageData, error)
 
 // GetUsageForDate returns usage data for a specific date
 func (a *App) GetUsageForDate(year, month, day int) (*types.UsageData, error) {
-	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
 	return a.tracker.GetUsageForDate(date)
 }
 
 // GetUsageForDateRange returns app usage data for a date range
 func (a *App) GetUsageForDateRange(startYear, startMonth, startDay, endYear, endMonth, endDay int) ([]types.AppUsage, error) {
-	startDate := time.Date(startYear, time.Month(startMonth), startDay, 0, 0, 0, 0, time.Local)
-	// Use start of next day for inclusive end date
-	endDate := time.Date(endYear, time.Month(endMonth), endDay+1, 0, 0, 0, 0, time.Local)
+	startDate := time.Date(startYear, time.Month(startMonth), startDay, 0, 0, 0, 0, time.UTC)
+	// Both bounds are inclusive date keys
+	endDate := time.Date(endYear, time.Month(endMonth), endDay, 0, 0, 0, 0, time.UTC)
 	return a.tracker.GetUsageForDateRange(startDate, endDate)
 }
 
+// RebuildUsageFromEventLog recomputes a day's usage from the focus event log, replacing stored totals
+func (a *App) RebuildUsageFromEventLog(year, month, day int) (*types.UsageData, error) {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	return a.tracker.RebuildUsageFromEventLog(date)
+}
+
 // GetAppUsageHistory returns historical usage data for a specific application
 func (a *App) GetAppUsageHistory(appName string, days int) ([]types.AppUsage, error) {
 	return a.tracker.GetAppUsageHistory(appName, days)
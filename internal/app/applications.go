@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// applicationOpTimeout bounds how long a single application edit may take
+	applicationOpTimeout = 10 * time.Second
+)
+
+// GetApplications returns every known application with its executable paths
+func (a *App) GetApplications() ([]types.Application, error) {
+	apps, err := a.applicationRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return apps.ListApplications(ctx)
+}
+
+// SetApplicationAlias sets the name shown for an application; an empty alias restores the display name
+func (a *App) SetApplicationAlias(id int64, alias string) (*types.Application, error) {
+	apps, err := a.applicationRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+
+	app, err := apps.GetApplication(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	app.Alias = alias
+	if err := apps.UpdateApplication(ctx, app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// MergeApplications folds the source applications and their history into the target application
+func (a *App) MergeApplications(targetID int64, sourceIDs []int64) error {
+	apps, err := a.applicationRepository()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return apps.MergeApplications(ctx, targetID, sourceIDs)
+}
+
+// SplitApplication moves the given executable paths and their history to a new application
+func (a *App) SplitApplication(id int64, exePaths []string) (*types.Application, error) {
+	apps, err := a.applicationRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return apps.SplitApplication(ctx, id, exePaths)
+}
+
+// GetApplicationUsageHistory returns an application's usage for each of the last days,
+// newest first, whatever names it was tracked under
+func (a *App) GetApplicationUsageHistory(id int64, days int) ([]types.AppUsage, error) {
+	apps, err := a.applicationRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	// Clamp to at least today, like GetAppUsageHistory
+	if days <= 0 {
+		days = 1
+	}
+	endDate := a.tracker.DayBoundary().DateOf(time.Now())
+	startDate := endDate.AddDate(0, 0, -days+1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return apps.GetAppUsageByApplicationAndDateRange(ctx, id, startDate, endDate)
+}
+
+// applicationRepository returns the repository when it supports application identities
+func (a *App) applicationRepository() (repository.ApplicationRepository, error) {
+	apps, ok := a.repository.(repository.ApplicationRepository)
+	if !ok {
+		return nil, errors.NewRepositoryError("applications",
+			fmt.Errorf("repository does not support applications"), errors.ErrCodeValidation)
+	}
+	return apps, nil
+}
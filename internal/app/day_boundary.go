@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// settingDayStartHour stores the local hour at which a usage day begins
+	settingDayStartHour = "day_start_hour"
+	// settingTimezone stores the IANA timezone that decides usage dates; empty means system local
+	settingTimezone = "timezone"
+
+	// settingsOpTimeout bounds how long reading or writing settings may take
+	settingsOpTimeout = 5 * time.Second
+)
+
+// GetDayBoundary returns the hour and timezone that decide which date usage is recorded on
+func (a *App) GetDayBoundary() types.DayBoundary {
+	return a.tracker.DayBoundary()
+}
+
+// SetDayBoundary changes when usage days start and saves the choice for later sessions
+func (a *App) SetDayBoundary(startHour int, timezone string) error {
+	boundary, err := types.NewDayBoundary(startHour, timezone)
+	if err != nil {
+		return errors.NewRepositoryError("SetDayBoundary", err, errors.ErrCodeValidation)
+	}
+
+	settings, err := a.settingsRepository()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settingsOpTimeout)
+	defer cancel()
+
+	if err := settings.SetSetting(ctx, settingDayStartHour, strconv.Itoa(boundary.StartHour)); err != nil {
+		return err
+	}
+	if err := settings.SetSetting(ctx, settingTimezone, boundary.Timezone); err != nil {
+		return err
+	}
+
+	a.applyDayBoundary(boundary)
+	return nil
+}
+
+// loadDayBoundary applies the saved day boundary, keeping the default when none is stored
+func (a *App) loadDayBoundary(ctx context.Context) {
+	settings, err := a.settingsRepository()
+	if err != nil || !a.tracker.IsPersistenceEnabled() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settingsOpTimeout)
+	defer cancel()
+
+	startHour := 0
+	if value, err := settings.GetSetting(ctx, settingDayStartHour); err == nil {
+		if startHour, err = strconv.Atoi(value); err != nil {
+			a.logger.Warn("Ignoring invalid day start hour setting", "value", value)
+			return
+		}
+	} else if !errors.IsNotFound(err) {
+		a.logger.Warn("Failed to load day start hour setting", "error", err)
+		return
+	}
+
+	timezone, err := settings.GetSetting(ctx, settingTimezone)
+	if err != nil && !errors.IsNotFound(err) {
+		a.logger.Warn("Failed to load timezone setting", "error", err)
+		return
+	}
+
+	boundary, err := types.NewDayBoundary(startHour, timezone)
+	if err != nil {
+		a.logger.Warn("Ignoring invalid day boundary settings", "start_hour", startHour, "timezone", timezone, "error", err)
+		return
+	}
+	a.applyDayBoundary(boundary)
+}
+
+// applyDayBoundary hands the boundary to everything that turns timestamps into usage dates
+func (a *App) applyDayBoundary(boundary types.DayBoundary) {
+	a.tracker.SetDayBoundary(boundary)
+	if a.reports != nil {
+		a.reports.SetDayBoundary(boundary)
+	}
+	if a.analytics != nil {
+		a.analytics.SetDayBoundary(boundary)
+	}
+	if a.anomalies != nil {
+		a.anomalies.SetDayBoundary(boundary)
+	}
+}
+
+// settingsRepository returns the repository when it supports persisted settings
+func (a *App) settingsRepository() (repository.SettingsRepository, error) {
+	settings, ok := a.repository.(repository.SettingsRepository)
+	if !ok {
+		return nil, errors.NewRepositoryError("settings",
+			fmt.Errorf("repository does not support settings"), errors.ErrCodeValidation)
+	}
+	return settings, nil
+}
@@ -0,0 +1,387 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"qwin/internal/database"
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+)
+
+const (
+	// diagnosticsTimeout bounds the database calls made while collecting a bundle
+	diagnosticsTimeout = 30 * time.Second
+	// diagnosticsLogFiles is how many log files, newest first, a bundle includes
+	diagnosticsLogFiles = 3
+	// diagnosticsLogBytes caps how much of each log file is included, keeping its end
+	diagnosticsLogBytes = 4 * 1024 * 1024
+	// redactedAppName replaces application names in bundles that exclude them
+	redactedAppName = "[app]"
+)
+
+// DiagnosticsOptions selects what a diagnostics bundle leaves out
+type DiagnosticsOptions struct {
+	// ExcludeAppNames replaces the names and executables of tracked applications
+	// everywhere in the bundle, including the logs
+	ExcludeAppNames bool `json:"excludeAppNames"`
+}
+
+// diagnosticsSummary is the bundle's summary.json, describing where the bundle came from
+type diagnosticsSummary struct {
+	CreatedAt       time.Time         `json:"createdAt"`
+	Environment     string            `json:"environment"`
+	OS              string            `json:"os"`
+	Arch            string            `json:"arch"`
+	GoVersion       string            `json:"goVersion"`
+	Backend         string            `json:"backend"`
+	ExcludeAppNames bool              `json:"excludeAppNames"`
+	LogFiles        []string          `json:"logFiles"`
+	Problems        map[string]string `json:"problems,omitempty"` // sections that couldn't be collected
+}
+
+// diagnosticsDatabase is the bundle's database.json
+type diagnosticsDatabase struct {
+	Stats     sql.DBStats         `json:"stats"`
+	RowCounts map[string]int64    `json:"rowCounts,omitempty"`
+	Files     *database.FileStats `json:"files,omitempty"`
+}
+
+// CreateDiagnosticsBundle writes a zip archive for troubleshooting to path: recent logs,
+// the database configuration with its paths redacted, migration status, connection and
+// table statistics, the platform backend, and the most recent classified repository
+// errors. An empty path writes the bundle next to the logs. It returns the path written.
+func (a *App) CreateDiagnosticsBundle(path string, options DiagnosticsOptions) (string, error) {
+	path, err := a.diagnosticsPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsTimeout)
+	defer cancel()
+
+	redact := func(data []byte) []byte { return data }
+	if options.ExcludeAppNames {
+		redactor, err := a.appNameRedactor(ctx)
+		if err != nil {
+			// Without the names the bundle can't be guaranteed not to contain them
+			return "", err
+		}
+		redact = redactor.redact
+	}
+
+	files, summary := a.collectDiagnostics(ctx)
+	summary.ExcludeAppNames = options.ExcludeAppNames
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+		if strings.HasPrefix(name, "logs/") {
+			summary.LogFiles = append(summary.LogFiles, strings.TrimPrefix(name, "logs/"))
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(summary.LogFiles)
+	files["summary.json"], _ = json.MarshalIndent(summary, "", "  ")
+	names = append([]string{"summary.json"}, names...)
+
+	// Write next to the destination and rename, so a failed bundle leaves nothing behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return "", diagnosticsWriteError(path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zipWriter := zip.NewWriter(tmp)
+	for _, name := range names {
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: summary.CreatedAt})
+		if err == nil {
+			_, err = w.Write(redact(redactHomeDir(files[name])))
+		}
+		if err != nil {
+			tmp.Close()
+			return "", diagnosticsWriteError(path, err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		tmp.Close()
+		return "", diagnosticsWriteError(path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", diagnosticsWriteError(path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", diagnosticsWriteError(path, err)
+	}
+
+	a.logger.Info("Created diagnostics bundle", "path", path, "exclude_app_names", options.ExcludeAppNames)
+	return path, nil
+}
+
+// diagnosticsWriteError reports a failure to write the bundle to path
+func diagnosticsWriteError(path string, err error) error {
+	return errors.NewRepositoryErrorWithContext("CreateDiagnosticsBundle", err, errors.ClassifyError(err), map[string]string{
+		"path": path,
+	})
+}
+
+// diagnosticsPath returns where to write a bundle, defaulting to the log directory
+func (a *App) diagnosticsPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	dir, err := logging.DefaultLogDir(appName)
+	if err != nil {
+		return "", errors.NewRepositoryError("CreateDiagnosticsBundle", err, errors.ErrCodeValidation)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.NewRepositoryError("CreateDiagnosticsBundle", err, errors.ClassifyError(err))
+	}
+	name := fmt.Sprintf("%s-diagnostics-%s.zip", appName, time.Now().Format("2006-01-02T15-04-05"))
+	return filepath.Join(dir, name), nil
+}
+
+// collectDiagnostics gathers the bundle's files by name. Sections that can't be collected
+// are left out and noted in the summary, so a broken subsystem still produces a bundle.
+func (a *App) collectDiagnostics(ctx context.Context) (map[string][]byte, *diagnosticsSummary) {
+	files := make(map[string][]byte)
+	summary := &diagnosticsSummary{
+		CreatedAt:   time.Now(),
+		Environment: a.environment,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		GoVersion:   runtime.Version(),
+		Backend:     a.tracker.PlatformBackend(),
+		LogFiles:    []string{},
+		Problems:    make(map[string]string),
+	}
+	addJSON := func(name string, value interface{}) {
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			summary.Problems[name] = err.Error()
+			return
+		}
+		files[name] = data
+	}
+
+	// The status repeats the database details below, without the recorded errors
+	status := a.systemStatus(ctx)
+	status.RecentErrors = nil
+	if status.Database.Files != nil {
+		status.Database.Files = redactFileStats(status.Database.Files)
+	}
+	addJSON("status.json", status)
+
+	if a.dbService == nil {
+		summary.Problems["database"] = "database service not initialized"
+	} else {
+		if config := a.dbService.Config(); config != nil {
+			addJSON("config.json", config.Redacted())
+		} else {
+			summary.Problems["config.json"] = "database not connected"
+		}
+
+		if migrations, err := a.dbService.MigrationStatus(ctx); err != nil {
+			summary.Problems["migrations.json"] = err.Error()
+		} else {
+			addJSON("migrations.json", migrations)
+		}
+
+		db := diagnosticsDatabase{Stats: a.dbService.GetStats()}
+		if counts, err := a.dbService.TableRowCounts(ctx); err != nil {
+			summary.Problems["rowCounts"] = err.Error()
+		} else {
+			db.RowCounts = counts
+		}
+		if fileStats, err := a.dbService.FileStats(); err != nil {
+			summary.Problems["files"] = err.Error()
+		} else {
+			db.Files = redactFileStats(fileStats)
+		}
+		addJSON("database.json", db)
+	}
+
+	addJSON("errors.json", a.recentRepositoryErrors())
+
+	if a.logOutput != nil {
+		logFiles, err := a.logOutput.LogFiles()
+		if err != nil {
+			summary.Problems["logs"] = err.Error()
+		}
+		if len(logFiles) > diagnosticsLogFiles {
+			logFiles = logFiles[:diagnosticsLogFiles]
+		}
+		for _, logFile := range logFiles {
+			data, err := readLogTail(logFile, diagnosticsLogBytes)
+			if err != nil {
+				summary.Problems["logs/"+filepath.Base(logFile)] = err.Error()
+				continue
+			}
+			files["logs/"+strings.TrimSuffix(filepath.Base(logFile), ".gz")] = data
+		}
+	}
+	return files, summary
+}
+
+// recentRepositoryErrors returns the recorded errors that were classified by the
+// repository, newest first
+func (a *App) recentRepositoryErrors() []logging.RecordedError {
+	classified := []logging.RecordedError{}
+	if a.errorLog == nil {
+		return classified
+	}
+	for _, entry := range a.errorLog.RecentErrors() {
+		if entry.Fields["error_code"] == "" {
+			continue
+		}
+		classified = append(classified, entry)
+	}
+	return classified
+}
+
+// redactFileStats returns a copy of stats with the directories of its paths removed
+func redactFileStats(stats *database.FileStats) *database.FileStats {
+	redacted := *stats
+	redacted.Path = database.RedactPath(stats.Path)
+	if stats.LastBackupPath != "" {
+		redacted.LastBackupPath = database.RedactPath(stats.LastBackupPath)
+	}
+	return &redacted
+}
+
+// homeDir is replaced in logs and messages, where paths can't be picked out reliably
+var homeDir, _ = os.UserHomeDir()
+
+// redactHomeDir replaces the user's home directory in data with ~, also where it appears
+// escaped in JSON
+func redactHomeDir(data []byte) []byte {
+	if homeDir == "" {
+		return data
+	}
+	data = bytes.ReplaceAll(data, []byte(homeDir), []byte("~"))
+	if escaped, err := json.Marshal(homeDir); err == nil {
+		data = bytes.ReplaceAll(data, bytes.Trim(escaped, `"`), []byte("~"))
+	}
+	return data
+}
+
+// readLogTail returns up to limit bytes from the end of a log file, decompressing
+// rotated files. A line cut off by the limit is dropped.
+func readLogTail(path string, limit int) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		reader = gz
+	}
+	// Log files are rotated at a few megabytes, so reading them whole is fine
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= limit {
+		return data, nil
+	}
+	data = data[len(data)-limit:]
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return data, nil
+}
+
+// appNameRedactor replaces application names in bundle files
+type appNameRedactor struct {
+	pattern *regexp.Regexp // nil when there are no names to replace
+}
+
+// minRedactedNameLength keeps very short names from matching unrelated words
+const minRedactedNameLength = 3
+
+// appNameRedactor builds a redactor for every application the user has tracked
+func (a *App) appNameRedactor(ctx context.Context) (*appNameRedactor, error) {
+	apps, err := a.applicationRepository()
+	if err != nil {
+		return nil, err
+	}
+	known, err := apps.ListApplications(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, app := range known {
+		names = append(names, app.Name, app.DisplayName, app.Alias)
+		for _, exePath := range app.ExePaths {
+			names = append(names, exePath, filepath.Base(exePath))
+		}
+	}
+	// Usage tracked since the last write may not have an application yet
+	if usage := a.tracker.GetUsageData(); usage != nil {
+		for _, app := range usage.Apps {
+			names = append(names, app.Name, app.ExePath, filepath.Base(app.ExePath))
+		}
+	}
+	return newAppNameRedactor(names), nil
+}
+
+// newAppNameRedactor builds a redactor matching whole names, ignoring case. Paths are also
+// matched as they appear escaped in JSON.
+func newAppNameRedactor(names []string) *appNameRedactor {
+	seen := make(map[string]bool)
+	var alternatives []string
+	addName := func(name string) {
+		if len(name) < minRedactedNameLength || seen[strings.ToLower(name)] {
+			return
+		}
+		seen[strings.ToLower(name)] = true
+		alternatives = append(alternatives, regexp.QuoteMeta(name))
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		addName(name)
+		if escaped, err := json.Marshal(name); err == nil {
+			addName(strings.Trim(string(escaped), `"`))
+		}
+	}
+	if len(alternatives) == 0 {
+		return &appNameRedactor{}
+	}
+
+	// Longer names first, so a path is replaced whole rather than just its file name
+	sort.Slice(alternatives, func(i, j int) bool { return len(alternatives[i]) > len(alternatives[j]) })
+	pattern := `(?i)(^|[^\pL\pN_])(` + strings.Join(alternatives, "|") + `)($|[^\pL\pN_])`
+	return &appNameRedactor{pattern: regexp.MustCompile(pattern)}
+}
+
+// redact replaces every application name in data
+func (r *appNameRedactor) redact(data []byte) []byte {
+	if r.pattern == nil {
+		return data
+	}
+	// Matches consume the character after a name, so adjacent names need another pass
+	for i := 0; i < 2; i++ {
+		data = r.pattern.ReplaceAll(data, []byte("${1}"+redactedAppName+"${3}"))
+	}
+	return data
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// iconLookupTimeout bounds how long a single icon lookup may take
+	iconLookupTimeout = 5 * time.Second
+)
+
+// GetIcon returns the stored icon with the given hash as a data URL
+func (a *App) GetIcon(hash string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), iconLookupTimeout)
+	defer cancel()
+
+	icon, err := a.lookupIcon(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	return icon.DataURL(), nil
+}
+
+// IconHandler serves stored icons at types.IconURLPrefix so the frontend can load
+// the iconPath of an app usage record directly as an image source
+func (a *App) IconHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash, ok := types.IconHashFromURL(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		icon, err := a.lookupIcon(r.Context(), hash)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				http.NotFound(w, r)
+				return
+			}
+			a.logger.Error("Failed to serve icon", "hash", hash, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		// Icons are content-addressed, so a given URL never changes
+		w.Header().Set("Content-Type", icon.Mime)
+		w.Header().Set("Content-Length", strconv.Itoa(len(icon.Data)))
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Write(icon.Data)
+	})
+}
+
+// lookupIcon retrieves an icon from the repository when it supports icon storage
+func (a *App) lookupIcon(ctx context.Context, hash string) (*types.Icon, error) {
+	icons, ok := a.repository.(repository.IconRepository)
+	if !ok {
+		return nil, errors.NewRepositoryError("GetIcon",
+			fmt.Errorf("repository does not support icon storage"), errors.ErrCodeValidation)
+	}
+	return icons.GetIcon(ctx, hash)
+}
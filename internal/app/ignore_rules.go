@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+// GetIgnoreRules returns all rules that keep applications out of usage stats
+func (a *App) GetIgnoreRules() ([]types.IgnoreRule, error) {
+	rules, err := a.ignoreRuleRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settingsOpTimeout)
+	defer cancel()
+	return rules.ListIgnoreRules(ctx)
+}
+
+// AddIgnoreRule stores a new ignore rule and starts applying it to tracking.
+// With applyToHistory, stored usage matching the rule is removed retroactively as well.
+func (a *App) AddIgnoreRule(rule types.IgnoreRule, applyToHistory bool) (*types.IgnoreRule, error) {
+	rules, err := a.ignoreRuleRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+
+	if err := rules.CreateIgnoreRule(ctx, &rule); err != nil {
+		return nil, err
+	}
+	if err := a.loadIgnoreRules(ctx); err != nil {
+		return nil, err
+	}
+
+	if applyToHistory {
+		if _, err := a.applyIgnoreRulesToHistory(ctx, rules, []types.IgnoreRule{rule}); err != nil {
+			return nil, err
+		}
+	}
+	return &rule, nil
+}
+
+// UpdateIgnoreRule saves changes to an ignore rule; stored history is left as is
+func (a *App) UpdateIgnoreRule(rule types.IgnoreRule) (*types.IgnoreRule, error) {
+	rules, err := a.ignoreRuleRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settingsOpTimeout)
+	defer cancel()
+
+	if err := rules.UpdateIgnoreRule(ctx, &rule); err != nil {
+		return nil, err
+	}
+	if err := a.loadIgnoreRules(ctx); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteIgnoreRule removes an ignore rule; history it was applied to is not restored
+func (a *App) DeleteIgnoreRule(id int64) error {
+	rules, err := a.ignoreRuleRepository()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settingsOpTimeout)
+	defer cancel()
+
+	if err := rules.DeleteIgnoreRule(ctx, id); err != nil {
+		return err
+	}
+	return a.loadIgnoreRules(ctx)
+}
+
+// ApplyIgnoreRuleToHistory purges or hides stored usage matching an existing rule,
+// depending on its mode, and returns how many app usage rows were removed
+func (a *App) ApplyIgnoreRuleToHistory(id int64) (int64, error) {
+	rules, err := a.ignoreRuleRepository()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+
+	all, err := rules.ListIgnoreRules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, rule := range all {
+		if rule.ID == id {
+			// Applying a rule explicitly works even while it is disabled for tracking
+			rule.Enabled = true
+			return a.applyIgnoreRulesToHistory(ctx, rules, []types.IgnoreRule{rule})
+		}
+	}
+	return 0, errors.HandleNotFound("ApplyIgnoreRuleToHistory", "ignore_rule", fmt.Sprintf("%d", id))
+}
+
+// applyIgnoreRulesToHistory removes matching stored usage and refreshes today's figures
+func (a *App) applyIgnoreRulesToHistory(ctx context.Context, repo repository.IgnoreRuleRepository, rules []types.IgnoreRule) (int64, error) {
+	// Flush first so rows about to be removed aren't recreated from pending deltas
+	if err := a.tracker.SaveCurrentDataNow(); err != nil {
+		return 0, err
+	}
+
+	removed, err := repo.ApplyIgnoreRulesToHistory(ctx, rules)
+	if err != nil {
+		return 0, err
+	}
+
+	a.tracker.ReloadCurrentDay()
+	return removed, nil
+}
+
+// loadIgnoreRules hands the stored ignore rules to the tracker
+func (a *App) loadIgnoreRules(ctx context.Context) error {
+	rules, err := a.ignoreRuleRepository()
+	if err != nil {
+		return err
+	}
+
+	stored, err := rules.ListIgnoreRules(ctx)
+	if err != nil {
+		return err
+	}
+	if err := a.tracker.SetIgnoreRules(stored); err != nil {
+		return errors.NewRepositoryError("loadIgnoreRules", err, errors.ErrCodeValidation)
+	}
+	return nil
+}
+
+// ignoreRuleRepository returns the repository when it supports ignore rules
+func (a *App) ignoreRuleRepository() (repository.IgnoreRuleRepository, error) {
+	rules, ok := a.repository.(repository.IgnoreRuleRepository)
+	if !ok {
+		return nil, errors.NewRepositoryError("ignore_rules",
+			fmt.Errorf("repository does not support ignore rules"), errors.ErrCodeValidation)
+	}
+	return rules, nil
+}
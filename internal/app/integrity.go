@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"qwin/internal/database"
+)
+
+// DatabaseIntegrity tells the UI what the startup integrity check found, and how the
+// database was recovered if it was corrupt
+type DatabaseIntegrity struct {
+	CheckedAt time.Time                 `json:"checkedAt"`
+	Issues    []database.IntegrityIssue `json:"issues"`
+	Recovery  *database.RecoveryReport  `json:"recovery,omitempty"`
+	Message   string                    `json:"message"` // Empty when there is nothing to tell the user
+}
+
+// GetDatabaseIntegrity returns the outcome of the integrity check run when the app started
+func (a *App) GetDatabaseIntegrity() *DatabaseIntegrity {
+	integrity := &DatabaseIntegrity{Issues: []database.IntegrityIssue{}}
+	if a.startupCheck == nil {
+		return integrity
+	}
+
+	if report := a.startupCheck.Integrity; report != nil {
+		integrity.CheckedAt = report.CheckedAt
+		integrity.Issues = report.Issues
+	}
+	integrity.Recovery = a.startupCheck.Recovery
+	integrity.Message = integrityMessage(integrity)
+	return integrity
+}
+
+// integrityMessage explains the startup check's outcome in a sentence or two
+func integrityMessage(integrity *DatabaseIntegrity) string {
+	if recovery := integrity.Recovery; recovery != nil {
+		kept := fmt.Sprintf("The damaged file was kept at %s.", recovery.Quarantine)
+		if recovery.SalvageError != "" {
+			kept = fmt.Sprintf("Nothing could be salvaged from it: %s. ", recovery.SalvageError) + kept
+		}
+		switch recovery.Method {
+		case database.RecoveryFromBackup:
+			return fmt.Sprintf("The usage database was damaged and has been restored from a backup taken on %s. ",
+				recovery.BackupTakenAt.Format("January 2, 2006")) +
+				"Usage recorded since that backup is missing. " + kept
+		case database.RecoveryRebuilt:
+			return "The usage database was damaged and has been rebuilt from what could be salvaged. " +
+				"Some usage may be missing. " + kept
+		default:
+			return "The usage database was damaged and couldn't be salvaged, so a new one was started. " + kept
+		}
+	}
+
+	switch len(integrity.Issues) {
+	case 0:
+		return ""
+	case 1:
+		return "The database check found a problem with stored usage: " + integrity.Issues[0].Detail
+	default:
+		return fmt.Sprintf("The database check found %d problems with stored usage.", len(integrity.Issues))
+	}
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"qwin/internal/database"
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+)
+
+const (
+	// appName names the directory logs are kept in
+	appName = "qwin"
+	// logLevelEnv overrides the configured log level, e.g. QWIN_LOG_LEVEL=debug
+	logLevelEnv = "QWIN_LOG_LEVEL"
+)
+
+// newLogger creates the application's logger: entries at the configured level go to a
+// rotating file in the user's state directory, and are mirrored to the console outside
+// production. Without a usable state directory it logs to the console only.
+func newLogger(env string, config *database.Config) *logging.LeveledLogger {
+	levelName := config.LogLevel
+	if override := os.Getenv(logLevelEnv); override != "" {
+		levelName = override
+	}
+	level, levelErr := logging.ParseLevel(levelName)
+
+	loggerConfig := logging.DefaultLoggerConfig("")
+	loggerConfig.Level = level
+	loggerConfig.Console = env != "production"
+
+	if env != "test" {
+		if dir, err := logging.DefaultLogDir(appName); err != nil {
+			log.Printf("Logging to the console only, no state directory: %v", err)
+			loggerConfig.Console = true
+		} else {
+			loggerConfig.FilePath = filepath.Join(dir, logFileName(env))
+		}
+	}
+
+	logger, err := logging.NewLeveledLogger(loggerConfig)
+	if err != nil {
+		log.Printf("Logging to the console only, failed to open log file: %v", err)
+		loggerConfig.FilePath = ""
+		loggerConfig.Console = true
+		logger, _ = logging.NewLeveledLogger(loggerConfig)
+	}
+
+	if levelErr != nil {
+		logger.Warn("Ignoring invalid log level", "level", levelName, "error", levelErr)
+	}
+	return logger
+}
+
+// logFileName keeps development logs apart from the installed app's
+func logFileName(env string) string {
+	if env == "production" {
+		return appName + ".log"
+	}
+	return appName + "_" + env + ".log"
+}
+
+// SetLogLevel changes which messages are logged, e.g. to "debug" while diagnosing a
+// problem, until the app exits
+func (a *App) SetLogLevel(name string) error {
+	level, err := logging.ParseLevel(name)
+	if err != nil {
+		return errors.NewRepositoryError("SetLogLevel", err, errors.ErrCodeValidation)
+	}
+	if a.logOutput == nil {
+		return errors.NewRepositoryError("SetLogLevel", fmt.Errorf("logger does not support levels"), errors.ErrCodeValidation)
+	}
+	a.logOutput.SetLevel(level)
+	a.logger.Info("Log level changed", "level", level.String())
+	return nil
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"qwin/internal/database"
+	"qwin/internal/infrastructure/logging"
+)
+
+const (
+	// migrateCommand is the first command-line argument that selects migration commands
+	migrateCommand = "migrate"
+	// migrateCommandTimeout bounds a single migration command
+	migrateCommandTimeout = 5 * time.Minute
+)
+
+// migrateUsage describes the migration commands
+const migrateUsage = `usage: qwin migrate <command>
+
+commands:
+  status             list applied and pending migrations (default)
+  up                 apply every pending migration
+  to <version>       migrate up or down to a version; 0 rolls back everything
+  rollback [steps]   roll back the last steps migrations (default 1)
+
+The database is backed up before any migration that can lose data.`
+
+// RunMigrationCommand runs "qwin migrate ..." against the environment's database without
+// starting the UI. It reports false when args are not a migration command.
+func RunMigrationCommand(env string, args []string, out io.Writer) (bool, error) {
+	if len(args) == 0 || args[0] != migrateCommand {
+		return false, nil
+	}
+	args = args[1:]
+
+	command := "status"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateCommandTimeout)
+	defer cancel()
+
+	dbService := database.NewSQLiteService(logging.NewDefaultLogger())
+	if err := dbService.Connect(ctx, database.ConfigForEnvironment(env)); err != nil {
+		return true, err
+	}
+	defer dbService.Close()
+
+	switch {
+	case command == "status" && len(args) == 0:
+	case command == "up" && len(args) == 0:
+		if err := dbService.Migrate(ctx); err != nil {
+			return true, err
+		}
+	case command == "to" && len(args) == 1:
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("invalid version %q\n%s", args[0], migrateUsage)
+		}
+		if err := dbService.MigrateTo(ctx, version); err != nil {
+			return true, err
+		}
+	case command == "rollback" && len(args) <= 1:
+		steps := 1
+		if len(args) == 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[0]); err != nil {
+				return true, fmt.Errorf("invalid step count %q\n%s", args[0], migrateUsage)
+			}
+		}
+		if err := dbService.Rollback(ctx, steps); err != nil {
+			return true, err
+		}
+	default:
+		return true, fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+
+	status, err := dbService.MigrationStatus(ctx)
+	if err != nil {
+		return true, err
+	}
+	return true, writeMigrationStatus(out, status)
+}
+
+// writeMigrationStatus prints migration status as a table
+func writeMigrationStatus(out io.Writer, status *database.MigrationStatus) error {
+	fmt.Fprintf(out, "Schema version %d of %d\n\n", status.CurrentVersion, status.LatestVersion)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tAPPLIED AT\tMIGRATION")
+	for _, migration := range status.Applied {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.AppliedAt.Local().Format("2006-01-02 15:04:05"), migration.Name)
+	}
+	for _, migration := range status.Pending {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, "pending", migration.Name)
+	}
+	return w.Flush()
+}
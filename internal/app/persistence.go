@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// writeBehindJournalName is the file usage is journaled to while the database is unavailable
+	writeBehindJournalName = "pending-usage.jsonl"
+	// persistenceRecoveryInterval is how often a lost database connection is retried
+	persistenceRecoveryInterval = time.Minute
+)
+
+// startPersistenceRecovery keeps trying to reconnect the database after startup failed to
+// use it. Usage is queued and journaled by the tracker in the meantime, and written once
+// the database is back.
+func (a *App) startPersistenceRecovery(ctx context.Context) {
+	a.recoveryMutex.Lock()
+	defer a.recoveryMutex.Unlock()
+	if a.stopRecovery != nil {
+		return
+	}
+	stop := make(chan struct{})
+	a.stopRecovery = stop
+
+	go func() {
+		ticker := time.NewTicker(persistenceRecoveryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if a.tryResumePersistence(ctx) {
+					a.recoveryMutex.Lock()
+					a.stopRecovery = nil
+					a.recoveryMutex.Unlock()
+					return
+				}
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// stopPersistenceRecovery ends reconnection attempts, if any are running
+func (a *App) stopPersistenceRecovery() {
+	a.recoveryMutex.Lock()
+	defer a.recoveryMutex.Unlock()
+	if a.stopRecovery != nil {
+		close(a.stopRecovery)
+		a.stopRecovery = nil
+	}
+}
+
+// tryResumePersistence reconnects the database and, if that works, writes the usage queued
+// while it was unavailable. It reports whether persistence is back.
+func (a *App) tryResumePersistence(ctx context.Context) bool {
+	if err := a.reconnectDatabase(ctx); err != nil {
+		a.logger.Warn("Database is still unavailable", "error", err)
+		return false
+	}
+
+	// Rules stored in the database couldn't be applied at startup
+	if err := a.loadIgnoreRules(ctx); err != nil {
+		a.logger.Warn("Failed to load ignore rules", "error", err)
+	}
+	a.loadPrivacyPolicy(ctx)
+	a.loadWorkSchedule(ctx)
+
+	if err := a.tracker.ResumePersistence(); err != nil {
+		a.logger.Error("Reconnected to the database but failed to write queued usage", "error", err)
+		return false
+	}
+
+	if a.reportScheduler != nil {
+		a.reportScheduler.Start()
+	}
+	a.startSync(ctx)
+
+	a.logger.Info("Database persistence resumed")
+	return true
+}
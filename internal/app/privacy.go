@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+// settingPrivacyPolicy stores the privacy policy as JSON
+const settingPrivacyPolicy = "privacy_policy"
+
+// GetPrivacyPolicy returns the policy deciding what identifying detail is kept in recorded data
+func (a *App) GetPrivacyPolicy() types.PrivacyPolicy {
+	a.privacyMu.RLock()
+	defer a.privacyMu.RUnlock()
+	return a.privacyPolicy
+}
+
+// SetPrivacyPolicy saves the privacy policy and applies it to everything recorded from now on.
+// Stored history keeps its current values until RedactHistory is called.
+func (a *App) SetPrivacyPolicy(policy types.PrivacyPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return errors.NewRepositoryError("SetPrivacyPolicy", err, errors.ErrCodeValidation)
+	}
+
+	settings, err := a.settingsRepository()
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(policy)
+	if err != nil {
+		return errors.NewRepositoryError("SetPrivacyPolicy", err, errors.ErrCodeValidation)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settingsOpTimeout)
+	defer cancel()
+	if err := settings.SetSetting(ctx, settingPrivacyPolicy, string(value)); err != nil {
+		return err
+	}
+
+	a.applyPrivacyPolicy(policy)
+	return nil
+}
+
+// RedactHistory rewrites stored usage according to the current privacy policy, so exports
+// can be shared safely, and returns how many rows were changed
+func (a *App) RedactHistory() (int64, error) {
+	privacy, ok := a.repository.(repository.PrivacyRepository)
+	if !ok {
+		return 0, errors.NewRepositoryError("RedactHistory",
+			fmt.Errorf("repository does not support redacting history"), errors.ErrCodeValidation)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+
+	// Flush first so rows written after the rewrite don't carry the old values
+	if err := a.tracker.SaveCurrentDataNow(); err != nil {
+		return 0, err
+	}
+
+	rewritten, err := privacy.RedactHistory(ctx, a.privacyRedactor(a.GetPrivacyPolicy()))
+	if err != nil {
+		return 0, err
+	}
+
+	a.tracker.ReloadCurrentDay()
+	return rewritten, nil
+}
+
+// loadPrivacyPolicy applies the saved privacy policy, keeping everything when none is stored
+func (a *App) loadPrivacyPolicy(ctx context.Context) {
+	settings, err := a.settingsRepository()
+	if err != nil || !a.tracker.IsPersistenceEnabled() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settingsOpTimeout)
+	defer cancel()
+
+	value, err := settings.GetSetting(ctx, settingPrivacyPolicy)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			a.logger.Warn("Failed to load privacy policy setting", "error", err)
+		}
+		return
+	}
+
+	var policy types.PrivacyPolicy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		a.logger.Warn("Ignoring invalid privacy policy setting", "error", err)
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		a.logger.Warn("Ignoring invalid privacy policy setting", "error", err)
+		return
+	}
+	a.applyPrivacyPolicy(policy)
+}
+
+// applyPrivacyPolicy hands the policy to the tracker, which redacts before anything is recorded
+func (a *App) applyPrivacyPolicy(policy types.PrivacyPolicy) {
+	a.privacyMu.Lock()
+	a.privacyPolicy = policy
+	a.privacyMu.Unlock()
+
+	a.tracker.SetPrivacyRedactor(a.privacyRedactor(policy))
+}
+
+// privacyRedactor returns a redactor applying policy for the signed-in user
+func (a *App) privacyRedactor(policy types.PrivacyPolicy) *types.PrivacyRedactor {
+	return types.NewPrivacyRedactor(policy, currentUsername())
+}
+
+// currentUsername returns the name of the signed-in account without any domain, which is
+// how it appears in home directory paths
+func currentUsername() string {
+	name := ""
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	if name == "" {
+		name = os.Getenv("USER")
+	}
+	if name == "" {
+		name = os.Getenv("USERNAME")
+	}
+	if i := strings.LastIndex(name, `\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
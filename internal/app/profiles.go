@@ -0,0 +1,287 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"qwin/internal/database"
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+const (
+	// ProfileChangedEvent is emitted to the frontend with the new profile's name after a switch
+	ProfileChangedEvent = "profile:changed"
+
+	// profileScheduleInterval is how often scheduled profile switches are checked
+	profileScheduleInterval = time.Minute
+)
+
+// ListProfiles returns every profile and the database each one keeps its data in
+func (a *App) ListProfiles() []types.Profile {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	profiles := make([]types.Profile, 0, len(a.profiles.Profiles))
+	for _, name := range a.profiles.Profiles {
+		profiles = append(profiles, types.Profile{
+			Name:         name,
+			Active:       name == a.profiles.Active,
+			DatabasePath: a.baseConfig.ForProfile(name).Path,
+		})
+	}
+	return profiles
+}
+
+// GetActiveProfile returns the name of the profile being tracked into
+func (a *App) GetActiveProfile() string {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	return a.profiles.Active
+}
+
+// CreateProfile adds an empty profile; its database is created when it is first switched to
+func (a *App) CreateProfile(name string) error {
+	if err := types.ValidateProfileName(name); err != nil {
+		return errors.NewRepositoryError("CreateProfile", err, errors.ErrCodeValidation)
+	}
+
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	if a.profiles.Has(name) {
+		return errors.NewRepositoryErrorWithContext("CreateProfile",
+			fmt.Errorf("profile %q already exists", name), errors.ErrCodeDuplicate, map[string]string{
+				"profile": name,
+			})
+	}
+
+	updated := *a.profiles
+	updated.Profiles = append(append([]string(nil), a.profiles.Profiles...), name)
+	return a.saveProfilesLocked(&updated)
+}
+
+// GetProfileSchedule returns the times of day the active profile is switched automatically
+func (a *App) GetProfileSchedule() []types.ProfileSwitch {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	return append([]types.ProfileSwitch(nil), a.profiles.Schedule...)
+}
+
+// SetProfileSchedule replaces the automatic profile switches; an empty schedule turns them off
+func (a *App) SetProfileSchedule(schedule []types.ProfileSwitch) error {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	updated := *a.profiles
+	updated.Schedule = schedule
+	return a.saveProfilesLocked(&updated)
+}
+
+// SwitchProfile makes the named profile the active one. Tracking stops, usage tracked so
+// far is written to the current profile's database, and tracking resumes on the named
+// profile's database with its own settings and rules. If usage can't be written, or any
+// is left queued or journaled, the switch is refused so nothing is recorded in the wrong
+// profile.
+func (a *App) SwitchProfile(name string) error {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	return a.switchProfileLocked(name, time.Now())
+}
+
+// switchProfileLocked switches to the named profile, recording now as the switch time
+// Must be called with a.profileMu held
+func (a *App) switchProfileLocked(name string, now time.Time) error {
+	if !a.profiles.Has(name) {
+		return errors.NewRepositoryErrorWithContext("SwitchProfile",
+			fmt.Errorf("profile %q does not exist", name), errors.ErrCodeNotFound, map[string]string{
+				"profile": name,
+			})
+	}
+	if name == a.profiles.Active {
+		return nil
+	}
+	if !a.tracker.IsPersistenceEnabled() {
+		return errors.NewRepositoryErrorWithContext("SwitchProfile",
+			fmt.Errorf("database unavailable; usage queued for profile %q must be written first", a.profiles.Active),
+			errors.ErrCodeConnection, map[string]string{
+				"profile": name,
+			})
+	}
+
+	ctx := a.appContext()
+	previous := a.baseConfig.ForProfile(a.profiles.Active)
+	next := a.baseConfig.ForProfile(name)
+
+	// Nothing may read or write the current profile's database while it is swapped
+	a.stopSync()
+	if a.reportScheduler != nil {
+		a.reportScheduler.Stop()
+	}
+	a.tracker.Stop()
+
+	// Queued usage would otherwise be written to the next profile's database
+	if err := a.tracker.SaveAllPendingNow(); err != nil {
+		a.startProfile(ctx)
+		return errors.NewRepositoryErrorWithContext("SwitchProfile", err, errors.ClassifyError(err), map[string]string{
+			"operation": "flush",
+			"profile":   name,
+		})
+	}
+
+	// Start the next profile from a clean slate; its own state is loaded once it is connected
+	a.tracker.SetStateChangeHandler(nil)
+	a.tracker.ResetUsageData()
+	a.tracker.Resume()
+	a.tracker.SetPrivateMode(false)
+	a.applyPrivacyPolicy(types.PrivacyPolicy{})
+
+	if err := a.connectDatabase(ctx, next); err != nil {
+		if restoreErr := a.connectDatabase(ctx, previous); restoreErr != nil {
+			// Keep tracking with usage queued until the previous profile's database is back
+			a.logger.Error("Failed to reopen the previous profile's database", "profile", a.profiles.Active, "error", restoreErr)
+			a.tracker.SetPersistenceEnabled(false)
+			a.startPersistenceRecovery(ctx)
+		}
+		a.startProfile(ctx)
+		return err
+	}
+
+	updated := *a.profiles
+	updated.Active = name
+	updated.SwitchedAt = now
+	if err := a.saveProfilesLocked(&updated); err != nil {
+		// The switch happened; only the choice won't survive a restart
+		a.logger.Warn("Failed to save the active profile", "profile", name, "error", err)
+		a.profiles = &updated
+	}
+
+	a.useDataDir(next)
+	a.startProfile(ctx)
+
+	a.logger.Info("Switched profile", "from", previous.Path, "to", next.Path, "profile", name)
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, ProfileChangedEvent, name)
+	}
+	return nil
+}
+
+// startProfile applies the connected profile's settings and rules and starts tracking,
+// reports and sync on its database
+func (a *App) startProfile(ctx context.Context) {
+	a.loadDayBoundary(ctx)
+	a.loadPrivacyPolicy(ctx)
+	a.loadWorkSchedule(ctx)
+	if a.tracker.IsPersistenceEnabled() {
+		if err := a.loadIgnoreRules(ctx); err != nil {
+			a.logger.Warn("Failed to load ignore rules", "error", err)
+		}
+	}
+
+	a.restoreTrackingState(ctx)
+	a.tracker.SetStateChangeHandler(a.onTrackingStateChange)
+	a.tracker.Start()
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, TrackingStateEvent, a.tracker.TrackingState())
+	}
+
+	if a.reportScheduler != nil && a.tracker.IsPersistenceEnabled() {
+		a.reportScheduler.Start()
+	}
+	a.startSync(ctx)
+}
+
+// useDataDir points the files kept next to the database at config's directory
+func (a *App) useDataDir(config *database.Config) {
+	if config.IsInMemory() {
+		return
+	}
+	a.dataDir = filepath.Dir(config.Path)
+	a.tracker.SetWriteBehindJournal(filepath.Join(a.dataDir, writeBehindJournalName))
+	if a.reportScheduler != nil {
+		a.reportScheduler.SetDir(filepath.Join(a.dataDir, "reports"))
+	}
+}
+
+// databaseConfig returns the configuration of the active profile's database
+func (a *App) databaseConfig() *database.Config {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	return a.baseConfig.ForProfile(a.profiles.Active)
+}
+
+// saveProfilesLocked validates and stores the profile configuration, and makes it current
+// Must be called with a.profileMu held
+func (a *App) saveProfilesLocked(profiles *types.ProfileConfig) error {
+	if err := database.SaveProfiles(database.ProfilesPath(a.baseConfig), profiles); err != nil {
+		return err
+	}
+	a.profiles = profiles
+	return nil
+}
+
+// startProfileSchedule switches profiles when a scheduled switch comes due, checking
+// right away and then every minute until stopProfileSchedule
+func (a *App) startProfileSchedule(ctx context.Context) {
+	a.profileScheduleMu.Lock()
+	defer a.profileScheduleMu.Unlock()
+	if a.stopProfileScheduleCh != nil {
+		return
+	}
+	stop := make(chan struct{})
+	a.stopProfileScheduleCh = stop
+
+	go func() {
+		ticker := time.NewTicker(profileScheduleInterval)
+		defer ticker.Stop()
+
+		for {
+			a.applyProfileSchedule(time.Now())
+
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// stopProfileSchedule ends automatic profile switching
+func (a *App) stopProfileSchedule() {
+	a.profileScheduleMu.Lock()
+	defer a.profileScheduleMu.Unlock()
+	if a.stopProfileScheduleCh != nil {
+		close(a.stopProfileScheduleCh)
+		a.stopProfileScheduleCh = nil
+	}
+}
+
+// applyProfileSchedule switches to the profile the latest scheduled switch selected,
+// unless the active profile was chosen after that switch came due
+func (a *App) applyProfileSchedule(now time.Time) {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	name, at, ok := a.profiles.ScheduledProfile(now)
+	if !ok || name == a.profiles.Active || !at.After(a.profiles.SwitchedAt) {
+		return
+	}
+	if err := a.switchProfileLocked(name, now); err != nil {
+		a.logger.Warn("Scheduled profile switch failed", "profile", name, "error", err)
+	}
+}
+
+// appContext returns the context the app was started with, for work that outlives a call
+func (a *App) appContext() context.Context {
+	if a.ctx != nil {
+		return a.ctx
+	}
+	return context.Background()
+}
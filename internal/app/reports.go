@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/services"
+	"qwin/internal/types"
+)
+
+const (
+	// reportOpTimeout bounds building a report on demand
+	reportOpTimeout = 30 * time.Second
+
+	// ReportFormatMarkdown and ReportFormatHTML select how ExportReport renders a report
+	ReportFormatMarkdown = "markdown"
+	ReportFormatHTML     = "html"
+)
+
+// GetLatestReport builds the report for the last complete week or month
+func (a *App) GetLatestReport(period string) (*types.Report, error) {
+	today := a.reports.Today()
+
+	var start, end time.Time
+	switch types.ReportPeriod(period) {
+	case types.ReportPeriodWeekly:
+		start, end = services.WeeklyPeriod(today)
+	case types.ReportPeriodMonthly:
+		start, end = services.MonthlyPeriod(today)
+	default:
+		return nil, errors.NewRepositoryError("GetLatestReport",
+			fmt.Errorf("unknown report period %q", period), errors.ErrCodeValidation)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+	return a.reports.BuildReport(ctx, types.ReportPeriod(period), start, end)
+}
+
+// GetReport builds a report over a custom date range, both dates inclusive
+func (a *App) GetReport(startYear, startMonth, startDay, endYear, endMonth, endDay int) (*types.Report, error) {
+	start := time.Date(startYear, time.Month(startMonth), startDay, 0, 0, 0, 0, time.UTC)
+	end := time.Date(endYear, time.Month(endMonth), endDay, 0, 0, 0, 0, time.UTC)
+
+	a.saveIfIncludesToday(end)
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+	return a.reports.BuildReport(ctx, types.ReportPeriodCustom, start, end)
+}
+
+// ExportReport renders a custom date range report as Markdown or self-contained HTML
+func (a *App) ExportReport(startYear, startMonth, startDay, endYear, endMonth, endDay int, format string) (string, error) {
+	report, err := a.GetReport(startYear, startMonth, startDay, endYear, endMonth, endDay)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case ReportFormatMarkdown:
+		return services.RenderReportMarkdown(report)
+	case ReportFormatHTML:
+		return services.RenderReportHTML(report)
+	default:
+		return "", errors.NewRepositoryError("ExportReport",
+			fmt.Errorf("unknown report format %q", format), errors.ErrCodeValidation)
+	}
+}
+
+// GenerateDueReports writes any scheduled reports that are due and returns their paths
+func (a *App) GenerateDueReports() ([]string, error) {
+	if a.reportScheduler == nil {
+		return nil, errors.NewRepositoryError("GenerateDueReports",
+			fmt.Errorf("report directory is not available"), errors.ErrCodeValidation)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportOpTimeout)
+	defer cancel()
+	return a.reportScheduler.GenerateDue(ctx, a.reports.Today())
+}
+
+// GetReportsDirectory returns where scheduled reports are written, or "" when there is none
+func (a *App) GetReportsDirectory() string {
+	if a.reportScheduler == nil {
+		return ""
+	}
+	return a.reportScheduler.Dir()
+}
+
+// saveIfIncludesToday flushes the time tracked so far today when a query reaches today,
+// so on-demand reports and analytics include it
+func (a *App) saveIfIncludesToday(end time.Time) {
+	if end.Before(a.tracker.DayBoundary().DateOf(time.Now())) || !a.tracker.IsPersistenceEnabled() {
+		return
+	}
+	if err := a.tracker.SaveCurrentDataNow(); err != nil {
+		a.logger.Warn("Failed to save current usage before querying it", "error", err)
+	}
+}
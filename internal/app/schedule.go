@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+// settingWorkSchedule stores the working hours and holidays as JSON
+const settingWorkSchedule = "work_schedule"
+
+// GetWorkSchedule returns the working hours usage is split by
+func (a *App) GetWorkSchedule() types.WorkSchedule {
+	a.scheduleMu.RLock()
+	defer a.scheduleMu.RUnlock()
+	return a.workSchedule
+}
+
+// SetWorkSchedule saves the working hours per weekday and the holidays
+func (a *App) SetWorkSchedule(schedule types.WorkSchedule) error {
+	if err := schedule.Validate(); err != nil {
+		return errors.NewRepositoryError("SetWorkSchedule", err, errors.ErrCodeValidation)
+	}
+
+	settings, err := a.settingsRepository()
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(schedule)
+	if err != nil {
+		return errors.NewRepositoryError("SetWorkSchedule", err, errors.ErrCodeValidation)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settingsOpTimeout)
+	defer cancel()
+	if err := settings.SetSetting(ctx, settingWorkSchedule, string(value)); err != nil {
+		return err
+	}
+
+	a.applyWorkSchedule(schedule)
+	return nil
+}
+
+// IsWorkingHours reports whether now is within the scheduled working hours, so features
+// such as limits can apply only during work
+func (a *App) IsWorkingHours() bool {
+	return a.GetWorkSchedule().Contains(time.Now().In(a.tracker.DayBoundary().Location()))
+}
+
+// GetUsageSplitForDate returns a date's usage divided between working hours and the rest
+func (a *App) GetUsageSplitForDate(year, month, day int) (*types.UsageSplit, error) {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	return a.tracker.GetUsageSplitForDate(date, a.GetWorkSchedule())
+}
+
+// GetUsageSplitHistory returns the usage of each of the last days divided between working
+// hours and the rest, keyed like GetHistoricalUsage
+func (a *App) GetUsageSplitHistory(days int) (map[string]*types.UsageSplit, error) {
+	return a.tracker.GetUsageSplitHistory(days, a.GetWorkSchedule())
+}
+
+// loadWorkSchedule applies the saved work schedule, or no working hours when none is stored
+func (a *App) loadWorkSchedule(ctx context.Context) {
+	settings, err := a.settingsRepository()
+	if err != nil || !a.tracker.IsPersistenceEnabled() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settingsOpTimeout)
+	defer cancel()
+
+	var schedule types.WorkSchedule
+	value, err := settings.GetSetting(ctx, settingWorkSchedule)
+	if err != nil && !errors.IsNotFound(err) {
+		a.logger.Warn("Failed to load work schedule setting", "error", err)
+		return
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(value), &schedule); err != nil {
+			a.logger.Warn("Ignoring invalid work schedule setting", "error", err)
+			return
+		}
+		if err := schedule.Validate(); err != nil {
+			a.logger.Warn("Ignoring invalid work schedule setting", "error", err)
+			return
+		}
+	}
+	a.applyWorkSchedule(schedule)
+}
+
+// applyWorkSchedule makes schedule the one usage is split by
+func (a *App) applyWorkSchedule(schedule types.WorkSchedule) {
+	a.scheduleMu.Lock()
+	defer a.scheduleMu.Unlock()
+	a.workSchedule = schedule
+}
@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"qwin/internal/database"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+const (
+	// SystemStatusEvent is emitted to the frontend whenever the self-check finds a subsystem changed health
+	SystemStatusEvent = "system:status"
+
+	// selfCheckInterval is how often subsystem health is checked in the background
+	selfCheckInterval = time.Minute
+	// selfCheckTimeout bounds the database calls made by a status check
+	selfCheckTimeout = 5 * time.Second
+	// recentErrorCapacity is how many warnings and errors status reports keep
+	recentErrorCapacity = 20
+)
+
+// SystemStatus is a health report covering every subsystem
+type SystemStatus struct {
+	CheckedAt    time.Time               `json:"checkedAt"`
+	Healthy      bool                    `json:"healthy"`
+	Platform     PlatformStatus          `json:"platform"`
+	Tracker      TrackerStatus           `json:"tracker"`
+	Database     DatabaseStatus          `json:"database"`
+	Storage      *StorageStatus          `json:"storage"`
+	RecentErrors []logging.RecordedError `json:"recentErrors"` // newest first
+}
+
+// PlatformStatus describes the operating system integration in use
+type PlatformStatus struct {
+	OS      string `json:"os"`
+	Backend string `json:"backend"`
+}
+
+// TrackerStatus describes whether usage is being tracked and written
+type TrackerStatus struct {
+	State       types.TrackingState     `json:"state"`
+	Persistence types.PersistenceStatus `json:"persistence"`
+}
+
+// DatabaseStatus describes the database connection and its files
+type DatabaseStatus struct {
+	Connected        bool                `json:"connected"`
+	Error            string              `json:"error,omitempty"`
+	MigrationVersion int64               `json:"migrationVersion"`
+	LatestMigration  int64               `json:"latestMigration"`
+	Files            *database.FileStats `json:"files,omitempty"`
+}
+
+// GetSystemStatus checks every subsystem and reports its health
+func (a *App) GetSystemStatus() *SystemStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+	defer cancel()
+	return a.systemStatus(ctx)
+}
+
+// systemStatus builds a status report, bounding database calls by ctx
+func (a *App) systemStatus(ctx context.Context) *SystemStatus {
+	status := &SystemStatus{
+		CheckedAt: time.Now(),
+		Platform: PlatformStatus{
+			OS:      runtime.GOOS,
+			Backend: a.tracker.PlatformBackend(),
+		},
+		Tracker: TrackerStatus{
+			State:       a.tracker.TrackingState(),
+			Persistence: a.tracker.PersistenceStatus(),
+		},
+		Database:     a.databaseStatus(ctx),
+		Storage:      a.GetStorageStatus(),
+		RecentErrors: []logging.RecordedError{},
+	}
+	if a.errorLog != nil {
+		status.RecentErrors = a.errorLog.RecentErrors()
+	}
+
+	status.Healthy = status.Tracker.State.Running &&
+		status.Tracker.Persistence.Enabled &&
+		status.Database.Connected &&
+		status.Storage.Available
+	return status
+}
+
+// databaseStatus checks the connection and reads the migration version and file sizes
+func (a *App) databaseStatus(ctx context.Context) DatabaseStatus {
+	var status DatabaseStatus
+	if a.dbService == nil {
+		status.Error = "database service not initialized"
+		return status
+	}
+
+	if err := a.dbService.Health(ctx); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Connected = true
+
+	if migrations, err := a.dbService.MigrationStatus(ctx); err != nil {
+		a.logger.Warn("Failed to read migration status", "error", err)
+	} else {
+		status.MigrationVersion = migrations.CurrentVersion
+		status.LatestMigration = migrations.LatestVersion
+	}
+
+	if files, err := a.dbService.FileStats(); err != nil {
+		a.logger.Warn("Failed to read database file sizes", "error", err)
+	} else {
+		status.Files = files
+	}
+	return status
+}
+
+// startSelfCheck checks subsystem health periodically and tells the frontend when it changes
+func (a *App) startSelfCheck(ctx context.Context) {
+	a.selfCheckMutex.Lock()
+	defer a.selfCheckMutex.Unlock()
+	if a.stopSelfCheck != nil {
+		return
+	}
+	stop := make(chan struct{})
+	a.stopSelfCheck = stop
+
+	go func() {
+		ticker := time.NewTicker(selfCheckInterval)
+		defer ticker.Stop()
+
+		var last *SystemStatus
+		for {
+			select {
+			case <-ticker.C:
+				checkCtx, cancel := context.WithTimeout(ctx, selfCheckTimeout)
+				status := a.systemStatus(checkCtx)
+				cancel()
+
+				if last == nil || healthChanged(last, status) {
+					if !status.Healthy {
+						a.logger.Warn("Self-check found an unhealthy subsystem",
+							"tracker_running", status.Tracker.State.Running,
+							"persistence_enabled", status.Tracker.Persistence.Enabled,
+							"database_connected", status.Database.Connected,
+							"storage_available", status.Storage.Available)
+					}
+					wailsruntime.EventsEmit(ctx, SystemStatusEvent, status)
+				}
+				last = status
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// stopSelfChecks ends the periodic self-check, if it is running
+func (a *App) stopSelfChecks() {
+	a.selfCheckMutex.Lock()
+	defer a.selfCheckMutex.Unlock()
+	if a.stopSelfCheck != nil {
+		close(a.stopSelfCheck)
+		a.stopSelfCheck = nil
+	}
+}
+
+// healthChanged reports whether any subsystem became healthy or unhealthy between two checks.
+// Sizes, counters and timestamps change all the time and don't count.
+func healthChanged(previous, current *SystemStatus) bool {
+	return previous.Healthy != current.Healthy ||
+		previous.Tracker.State.Running != current.Tracker.State.Running ||
+		previous.Tracker.State.Paused != current.Tracker.State.Paused ||
+		previous.Tracker.Persistence.Enabled != current.Tracker.Persistence.Enabled ||
+		(previous.Tracker.Persistence.PendingDays > 0) != (current.Tracker.Persistence.PendingDays > 0) ||
+		previous.Database.Connected != current.Database.Connected ||
+		previous.Database.MigrationVersion != current.Database.MigrationVersion ||
+		previous.Storage.State != current.Storage.State
+}
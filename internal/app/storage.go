@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/repository"
+)
+
+// StorageStatusEvent is emitted to the frontend whenever storage becomes unavailable or available again
+const StorageStatusEvent = "storage:status"
+
+// StorageStatus tells the UI whether usage can currently be saved
+type StorageStatus struct {
+	Available     bool                `json:"available"`
+	State         errors.CircuitState `json:"state"`
+	ErrorCode     string              `json:"errorCode,omitempty"`
+	LastError     string              `json:"lastError,omitempty"`
+	RetryAt       *time.Time          `json:"retryAt,omitempty"`       // When saving is next attempted
+	PendingWrites int                 `json:"pendingWrites,omitempty"` // Days of usage waiting to be saved
+	Message       string              `json:"message"`                 // Empty while storage is available
+}
+
+// GetStorageStatus returns whether the usage database is currently being written to
+func (a *App) GetStorageStatus() *StorageStatus {
+	reporter, ok := a.repository.(repository.StorageHealthReporter)
+	if !ok {
+		return storageStatus(errors.CircuitStatus{State: errors.CircuitClosed})
+	}
+	status := storageStatus(reporter.StorageStatus())
+	status.PendingWrites = a.tracker.PendingWrites()
+	return status
+}
+
+// watchStorageStatus forwards storage status changes to the frontend. The handler runs on
+// whichever call changed the state, possibly with the tracker's persistence lock held, so
+// it leaves out the pending writes count.
+func (a *App) watchStorageStatus() {
+	reporter, ok := a.repository.(repository.StorageHealthReporter)
+	if !ok {
+		return
+	}
+	reporter.SetStorageStatusHandler(func(circuit errors.CircuitStatus) {
+		status := storageStatus(circuit)
+		if status.Available {
+			a.logger.Info("Storage is available again")
+		} else {
+			a.logger.Warn("Storage unavailable, usage will be queued", "error_code", status.ErrorCode, "error", status.LastError)
+		}
+
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, StorageStatusEvent, status)
+		}
+	})
+}
+
+// resetStorageStatus lets storage calls through again after the database was reconnected
+func (a *App) resetStorageStatus() {
+	if reporter, ok := a.repository.(repository.StorageHealthReporter); ok {
+		reporter.ResetStorageStatus()
+	}
+}
+
+// storageStatus describes a circuit breaker status for the UI
+func storageStatus(circuit errors.CircuitStatus) *StorageStatus {
+	status := &StorageStatus{
+		Available: circuit.State == errors.CircuitClosed,
+		State:     circuit.State,
+		ErrorCode: circuit.ErrorCode,
+		LastError: circuit.LastError,
+	}
+	if !circuit.RetryAt.IsZero() {
+		retryAt := circuit.RetryAt
+		status.RetryAt = &retryAt
+	}
+	if !status.Available {
+		status.Message = storageUnavailableMessage(circuit.ErrorCode)
+	}
+	return status
+}
+
+// storageUnavailableMessage explains why usage can't be saved, by the error that made storage unavailable
+func storageUnavailableMessage(code string) string {
+	var reason string
+	switch code {
+	case errors.ErrCodeDiskSpace.String():
+		reason = "the disk is full"
+	case errors.ErrCodeBusy.String():
+		reason = "the database is locked by another program"
+	case errors.ErrCodePermission.String():
+		reason = "the database can't be written to"
+	case errors.ErrCodeCorruption.String():
+		reason = "the database is damaged"
+	default:
+		reason = "the database can't be reached"
+	}
+	return fmt.Sprintf("Storage unavailable: %s. Usage is still tracked and will be saved once storage is back.", reason)
+}
@@ -0,0 +1,393 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/repository"
+	"qwin/internal/services"
+	"qwin/internal/types"
+)
+
+const (
+	// settingSyncFolder stores the folder usage is synced through
+	settingSyncFolder = "sync_folder"
+	// settingSyncServerURL and settingSyncServerToken store the sync server and its access token
+	settingSyncServerURL   = "sync_server_url"
+	settingSyncServerToken = "sync_server_token"
+	// settingSyncTarget stores the folder or server last synced with, to tell when it changes
+	settingSyncTarget = "sync_target"
+	// settingSyncDeviceID stores the ID this device writes its change log under
+	settingSyncDeviceID = "sync_device_id"
+
+	// syncOpTimeout bounds a sync run started on demand
+	syncOpTimeout = 2 * time.Minute
+)
+
+// usageSyncer is a way of syncing usage with other devices
+type usageSyncer interface {
+	Sync(ctx context.Context) (*types.SyncResult, error)
+	Start()
+	Stop()
+}
+
+// GetSyncFolder returns the folder usage is synced through, or "" when it isn't
+func (a *App) GetSyncFolder() string {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+	if folder, ok := a.syncer.(*services.FolderSync); ok {
+		return folder.Dir()
+	}
+	return ""
+}
+
+// SetSyncFolder syncs usage with other devices through a folder they all share, in place
+// of any sync server, or turns folder sync off for "". The folder keeps each device's
+// change log, so when moving it, move its contents too.
+func (a *App) SetSyncFolder(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return a.disableSync(settingSyncFolder)
+	}
+	if !filepath.IsAbs(path) {
+		return errors.NewRepositoryError("SetSyncFolder",
+			fmt.Errorf("sync folder %q is not an absolute path", path), errors.ErrCodeValidation)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+
+	syncer, err := a.newFolderSync(ctx, path)
+	if err != nil {
+		return err
+	}
+	return a.switchSync(ctx, "folder:"+path, syncer, map[string]string{settingSyncFolder: path})
+}
+
+// GetSyncServer returns the URL of the sync server usage is synced with, or "" when it isn't
+func (a *App) GetSyncServer() string {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+	if server, ok := a.syncer.(*services.ServerSync); ok {
+		return server.URL()
+	}
+	return ""
+}
+
+// SetSyncServer syncs usage with other devices through a qwin sync server, in place of
+// any sync folder, or turns server sync off for an empty URL. Changes made while the
+// server is unreachable are queued and uploaded once it is back.
+func (a *App) SetSyncServer(serverURL, token string) error {
+	serverURL = strings.TrimSpace(serverURL)
+	if serverURL == "" {
+		return a.disableSync(settingSyncServerURL, settingSyncServerToken)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+
+	syncer, err := a.newServerSync(ctx, serverURL, token)
+	if err != nil {
+		return err
+	}
+	return a.switchSync(ctx, "server:"+serverURL, syncer, map[string]string{
+		settingSyncServerURL:   serverURL,
+		settingSyncServerToken: token,
+	})
+}
+
+// SyncNow writes pending usage and syncs with other devices right away
+func (a *App) SyncNow() (*types.SyncResult, error) {
+	a.syncMu.Lock()
+	syncer := a.syncer
+	a.syncMu.Unlock()
+	if syncer == nil {
+		return nil, errors.NewRepositoryError("SyncNow", fmt.Errorf("neither a sync folder nor a sync server is set"), errors.ErrCodeValidation)
+	}
+
+	if err := a.tracker.SaveCurrentDataNow(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncOpTimeout)
+	defer cancel()
+	return syncer.Sync(ctx)
+}
+
+// ResetSync forgets the usage merged from other devices and what this device has shared,
+// then syncs again from the start. It recovers from a sync folder or server that lost
+// this device's changes.
+func (a *App) ResetSync() error {
+	devices, err := a.deviceSyncRepository()
+	if err != nil {
+		return err
+	}
+
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+	if a.syncer != nil {
+		a.syncer.Stop()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	err = a.resetSyncState(ctx, devices)
+	if a.syncer != nil {
+		a.syncer.Start()
+	}
+	return err
+}
+
+// ListSyncDevices returns this device and the devices whose usage was synced to it
+func (a *App) ListSyncDevices() ([]types.SyncDevice, error) {
+	devices, err := a.deviceSyncRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return devices.ListSyncDevices(ctx)
+}
+
+// GetUsageHistoryForDevice returns daily usage for the last N days of one device, by ID,
+// of this device for "local", or of all devices combined for "all"
+func (a *App) GetUsageHistoryForDevice(days int, device string) (map[string]*types.UsageData, error) {
+	devices, err := a.deviceSyncRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	a.saveIfIncludesToday(time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return devices.GetUsageHistoryForDevice(ctx, days, device)
+}
+
+// startSync starts syncing with the saved sync folder or server, if any
+func (a *App) startSync(ctx context.Context) {
+	settings, err := a.settingsRepository()
+	if err != nil || !a.tracker.IsPersistenceEnabled() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settingsOpTimeout)
+	defer cancel()
+
+	var syncer usageSyncer
+	if path, err := a.optionalSetting(ctx, settings, settingSyncFolder); err != nil || path != "" {
+		if err == nil {
+			syncer, err = a.newFolderSync(ctx, path)
+		}
+		if err != nil {
+			a.logger.Warn("Not syncing usage through folder", "dir", path, "error", err)
+			return
+		}
+	} else if serverURL, err := a.optionalSetting(ctx, settings, settingSyncServerURL); err != nil || serverURL != "" {
+		token := ""
+		if err == nil {
+			token, err = a.optionalSetting(ctx, settings, settingSyncServerToken)
+		}
+		if err == nil {
+			syncer, err = a.newServerSync(ctx, serverURL, token)
+		}
+		if err != nil {
+			a.logger.Warn("Not syncing usage with server", "server", serverURL, "error", err)
+			return
+		}
+	}
+	if syncer != nil {
+		a.replaceSyncer(syncer)
+	}
+}
+
+// stopSync stops periodic syncing
+func (a *App) stopSync() {
+	a.replaceSyncer(nil)
+}
+
+// replaceSyncer stops the running sync, if any, and starts syncer in its place
+func (a *App) replaceSyncer(syncer usageSyncer) {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+
+	if a.syncer != nil {
+		a.syncer.Stop()
+	}
+	a.syncer = syncer
+	if syncer != nil {
+		syncer.Start()
+	}
+}
+
+// switchSync saves the settings of a sync target, replacing those of the other kind, and
+// starts syncing with it. Logs are numbered per target, so a target other than the last
+// one synced with starts sync over.
+func (a *App) switchSync(ctx context.Context, target string, syncer usageSyncer, values map[string]string) error {
+	settings, err := a.settingsRepository()
+	if err != nil {
+		return err
+	}
+	devices, err := a.deviceSyncRepository()
+	if err != nil {
+		return err
+	}
+
+	a.stopSync()
+
+	previous, err := a.optionalSetting(ctx, settings, settingSyncTarget)
+	if err != nil {
+		return err
+	}
+	if previous != target {
+		if err := a.resetSyncState(ctx, devices); err != nil {
+			return err
+		}
+		if err := settings.SetSetting(ctx, settingSyncTarget, target); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range []string{settingSyncFolder, settingSyncServerURL, settingSyncServerToken} {
+		if _, keep := values[key]; keep {
+			continue
+		}
+		if err := settings.DeleteSetting(ctx, key); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	for key, value := range values {
+		if err := settings.SetSetting(ctx, key, value); err != nil {
+			return err
+		}
+	}
+
+	a.replaceSyncer(syncer)
+	return nil
+}
+
+// disableSync deletes the settings of a sync target and stops syncing if it was in use
+func (a *App) disableSync(keys ...string) error {
+	settings, err := a.settingsRepository()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settingsOpTimeout)
+	defer cancel()
+
+	wasSet := false
+	for _, key := range keys {
+		value, err := a.optionalSetting(ctx, settings, key)
+		if err != nil {
+			return err
+		}
+		wasSet = wasSet || value != ""
+		if err := settings.DeleteSetting(ctx, key); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if wasSet {
+		a.stopSync()
+	}
+	return nil
+}
+
+// resetSyncState forgets all synced devices and drops changes queued for a sync server
+func (a *App) resetSyncState(ctx context.Context, devices repository.DeviceSyncRepository) error {
+	if err := devices.ResetSync(ctx); err != nil {
+		return err
+	}
+	if a.dataDir == "" {
+		return nil
+	}
+	if err := os.Remove(services.OutboxPath(a.dataDir)); err != nil && !os.IsNotExist(err) {
+		return errors.NewRepositoryError("ResetSync", err, errors.ClassifyError(err))
+	}
+	return nil
+}
+
+// newFolderSync creates a sync through dir as this device
+func (a *App) newFolderSync(ctx context.Context, dir string) (*services.FolderSync, error) {
+	device, err := a.syncDevice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewFolderSync(a.repository, dir, device, logging.ForComponent(a.logger, "sync"))
+}
+
+// newServerSync creates a sync with the server at serverURL as this device
+func (a *App) newServerSync(ctx context.Context, serverURL, token string) (*services.ServerSync, error) {
+	if a.dataDir == "" {
+		return nil, errors.NewRepositoryError("newServerSync",
+			fmt.Errorf("sync outbox needs a data directory, which in-memory databases don't have"), errors.ErrCodeValidation)
+	}
+	device, err := a.syncDevice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewServerSync(a.repository, serverURL, token, services.OutboxPath(a.dataDir), device, logging.ForComponent(a.logger, "sync"))
+}
+
+// syncDevice returns this device as other devices see it
+func (a *App) syncDevice(ctx context.Context) (types.SyncDevice, error) {
+	id, err := a.syncDeviceID(ctx)
+	if err != nil {
+		return types.SyncDevice{}, err
+	}
+	name, _ := os.Hostname()
+	return types.SyncDevice{ID: id, Name: name}, nil
+}
+
+// optionalSetting returns a setting's value, "" when it has never been set
+func (a *App) optionalSetting(ctx context.Context, settings repository.SettingsRepository, key string) (string, error) {
+	value, err := settings.GetSetting(ctx, key)
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	return value, err
+}
+
+// syncDeviceID returns the ID of this device, choosing a random one the first time
+func (a *App) syncDeviceID(ctx context.Context) (string, error) {
+	settings, err := a.settingsRepository()
+	if err != nil {
+		return "", err
+	}
+
+	id, err := settings.GetSetting(ctx, settingSyncDeviceID)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", errors.NewRepositoryError("syncDeviceID", err, errors.ErrCodeValidation)
+	}
+	id = hex.EncodeToString(random)
+	if err := settings.SetSetting(ctx, settingSyncDeviceID, id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (a *App) deviceSyncRepository() (repository.DeviceSyncRepository, error) {
+	devices, ok := a.repository.(repository.DeviceSyncRepository)
+	if !ok {
+		return nil, errors.NewRepositoryError("sync",
+			fmt.Errorf("repository does not support device sync"), errors.ErrCodeValidation)
+	}
+	return devices, nil
+}
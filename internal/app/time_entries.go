@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+// AddTimeEntry adds manual or imported usage to a day, such as a meeting away from the
+// computer, or a correction that takes away time the tracker misattributed. It is shown
+// next to the tracked usage, which it never changes.
+func (a *App) AddTimeEntry(entry types.TimeEntry) (*types.TimeEntry, error) {
+	entries, err := a.timeEntryRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+
+	if err := entries.CreateTimeEntry(ctx, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// UpdateTimeEntry saves changes to a time entry, keeping the previous values in its history
+func (a *App) UpdateTimeEntry(entry types.TimeEntry) (*types.TimeEntry, error) {
+	entries, err := a.timeEntryRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+
+	if err := entries.UpdateTimeEntry(ctx, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// DeleteTimeEntry removes a time entry; its history is kept
+func (a *App) DeleteTimeEntry(id int64) error {
+	entries, err := a.timeEntryRepository()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return entries.DeleteTimeEntry(ctx, id)
+}
+
+// GetTimeEntries returns the time entries of a date range; both bounds are inclusive
+func (a *App) GetTimeEntries(startYear, startMonth, startDay, endYear, endMonth, endDay int) ([]types.TimeEntry, error) {
+	entries, err := a.timeEntryRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	startDate := time.Date(startYear, time.Month(startMonth), startDay, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(endYear, time.Month(endMonth), endDay, 0, 0, 0, 0, time.UTC)
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return entries.GetTimeEntries(ctx, startDate, endDate)
+}
+
+// GetTimeEntryHistory returns every change made to a time entry, oldest first
+func (a *App) GetTimeEntryHistory(id int64) ([]types.TimeEntryEdit, error) {
+	entries, err := a.timeEntryRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applicationOpTimeout)
+	defer cancel()
+	return entries.GetTimeEntryHistory(ctx, id)
+}
+
+// timeEntryRepository returns the repository when it supports time entries
+func (a *App) timeEntryRepository() (repository.TimeEntryRepository, error) {
+	entries, ok := a.repository.(repository.TimeEntryRepository)
+	if !ok {
+		return nil, errors.NewRepositoryError("time_entries",
+			fmt.Errorf("repository does not support time entries"), errors.ErrCodeValidation)
+	}
+	return entries, nil
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+const (
+	// TrackingStateEvent is emitted to the frontend whenever pause or private mode changes
+	TrackingStateEvent = "tracking:state"
+
+	// settingPausedUntil stores the end of the active pause as RFC 3339, or pauseIndefinitely
+	settingPausedUntil = "paused_until"
+	// settingPrivateMode stores whether private mode is on
+	settingPrivateMode = "private_mode"
+
+	// pauseIndefinitely marks a pause that lasts until tracking is resumed
+	pauseIndefinitely = "indefinite"
+)
+
+// GetTrackingState returns whether tracking is paused and whether private mode is on
+func (a *App) GetTrackingState() types.TrackingState {
+	return a.tracker.TrackingState()
+}
+
+// PauseTracking stops tracking for the given number of minutes; zero pauses until resumed
+func (a *App) PauseTracking(minutes int) error {
+	if minutes < 0 {
+		return errors.NewRepositoryError("PauseTracking",
+			fmt.Errorf("pause duration must not be negative: %d minutes", minutes), errors.ErrCodeValidation)
+	}
+	a.tracker.Pause(time.Duration(minutes) * time.Minute)
+	return nil
+}
+
+// ResumeTracking continues tracking after a pause
+func (a *App) ResumeTracking() {
+	a.tracker.Resume()
+}
+
+// SetPrivateMode toggles recording all time to an anonymous bucket
+func (a *App) SetPrivateMode(enabled bool) {
+	a.tracker.SetPrivateMode(enabled)
+}
+
+// onTrackingStateChange persists the new state and forwards it to the frontend
+func (a *App) onTrackingStateChange(state types.TrackingState) {
+	a.saveTrackingState(state)
+
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, TrackingStateEvent, state)
+	}
+}
+
+// saveTrackingState stores pause and private mode so they survive a restart
+func (a *App) saveTrackingState(state types.TrackingState) {
+	settings, err := a.settingsRepository()
+	if err != nil || !a.tracker.IsPersistenceEnabled() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settingsOpTimeout)
+	defer cancel()
+
+	if state.Paused {
+		value := pauseIndefinitely
+		if state.PausedUntil != nil {
+			value = state.PausedUntil.UTC().Format(time.RFC3339)
+		}
+		err = settings.SetSetting(ctx, settingPausedUntil, value)
+	} else {
+		err = settings.DeleteSetting(ctx, settingPausedUntil)
+	}
+	if err != nil {
+		a.logger.Warn("Failed to save pause state", "error", err)
+	}
+
+	if err := settings.SetSetting(ctx, settingPrivateMode, strconv.FormatBool(state.PrivateMode)); err != nil {
+		a.logger.Warn("Failed to save private mode", "error", err)
+	}
+}
+
+// restoreTrackingState re-applies a pause or private mode that was active when the app last exited
+func (a *App) restoreTrackingState(ctx context.Context) {
+	settings, err := a.settingsRepository()
+	if err != nil || !a.tracker.IsPersistenceEnabled() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settingsOpTimeout)
+	defer cancel()
+
+	if value, err := settings.GetSetting(ctx, settingPrivateMode); err == nil {
+		if enabled, err := strconv.ParseBool(value); err == nil && enabled {
+			a.tracker.SetPrivateMode(true)
+		}
+	} else if !errors.IsNotFound(err) {
+		a.logger.Warn("Failed to load private mode", "error", err)
+	}
+
+	value, err := settings.GetSetting(ctx, settingPausedUntil)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			a.logger.Warn("Failed to load pause state", "error", err)
+		}
+		return
+	}
+
+	if value == pauseIndefinitely {
+		a.tracker.Pause(0)
+		return
+	}
+
+	deadline, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		a.logger.Warn("Ignoring invalid pause setting", "value", value)
+		return
+	}
+	if remaining := time.Until(deadline); remaining > 0 {
+		a.tracker.Pause(remaining)
+		return
+	}
+
+	// The pause ran out while the app was closed
+	if err := settings.DeleteSetting(ctx, settingPausedUntil); err != nil {
+		a.logger.Warn("Failed to clear expired pause", "error", err)
+	}
+}
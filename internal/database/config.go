@@ -71,6 +71,11 @@ type Config struct {
 	BackupPath      string        `json:"backupPath" yaml:"backupPath"`           // Backup directory path
 	BackupRetention int           `json:"backupRetention" yaml:"backupRetention"` // Number of backups to retain
 
+	// Encryption settings
+	EncryptionEnabled    bool   `json:"encryptionEnabled" yaml:"encryptionEnabled"`     // Encrypt the database file at rest
+	EncryptionKeySource  string `json:"encryptionKeySource" yaml:"encryptionKeySource"` // Where the key comes from: keyring or passphrase
+	EncryptionPassphrase string `json:"-" yaml:"-"`                                     // Passphrase the key is derived from; never serialized
+
 	// Environment and runtime settings
 	Environment string `json:"environment" yaml:"environment"` // Environment (development, production, test)
 	LogLevel    string `json:"logLevel" yaml:"logLevel"`       // Log level for database operations
@@ -113,6 +118,10 @@ func DefaultConfig() *Config {
 		BackupPath:      "backups",
 		BackupRetention: 7, // Keep 7 backups
 
+		// Encryption settings
+		EncryptionEnabled:   false, // Opt-in
+		EncryptionKeySource: KeySourceKeyring,
+
 		// Environment settings
 		Environment: "production",
 		LogLevel:    "info",
@@ -272,6 +281,19 @@ func (c *Config) LoadFromEnvironment() error {
 		}
 	}
 
+	// Encryption settings
+	if encryptionEnabled, present := parseBoolEnv("QWIN_DB_ENCRYPTION"); present {
+		c.EncryptionEnabled = encryptionEnabled
+	}
+
+	if keySource := os.Getenv("QWIN_DB_ENCRYPTION_KEY_SOURCE"); keySource != "" {
+		c.EncryptionKeySource = keySource
+	}
+
+	if passphrase := os.Getenv("QWIN_DB_ENCRYPTION_PASSPHRASE"); passphrase != "" {
+		c.EncryptionPassphrase = passphrase
+	}
+
 	// Environment settings
 	if environment := os.Getenv("QWIN_ENVIRONMENT"); environment != "" {
 		c.Environment = environment
@@ -418,6 +440,19 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate encryption settings
+	if c.EncryptionEnabled && !c.IsInMemory() {
+		switch c.EncryptionKeySource {
+		case KeySourceKeyring:
+		case KeySourcePassphrase:
+			if c.EncryptionPassphrase == "" {
+				return fmt.Errorf("encryptionPassphrase cannot be empty when the key source is %s", KeySourcePassphrase)
+			}
+		default:
+			return fmt.Errorf("invalid encryptionKeySource: %s", c.EncryptionKeySource)
+		}
+	}
+
 	// Validate environment
 	validEnvironments := map[string]bool{
 		"development": true,
@@ -475,7 +510,7 @@ func (c *Config) GetConnectionString() string {
 		path = strings.ReplaceAll(path, "?", "%3F")
 		path = strings.ReplaceAll(path, "&", "%26")
 	}
-	
+
 	return path + "?" + values.Encode()
 }
 
@@ -504,6 +539,9 @@ func (c *Config) Clone() *Config {
 		BackupInterval:        c.BackupInterval,
 		BackupPath:            c.BackupPath,
 		BackupRetention:       c.BackupRetention,
+		EncryptionEnabled:     c.EncryptionEnabled,
+		EncryptionKeySource:   c.EncryptionKeySource,
+		EncryptionPassphrase:  c.EncryptionPassphrase,
 		Environment:           c.Environment,
 		LogLevel:              c.LogLevel,
 	}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	dberrors "qwin/internal/infrastructure/errors"
+)
+
+// redactedDir replaces the directories of paths in redacted configurations
+const redactedDir = "[redacted]"
+
+// Config returns a copy of the configuration the database was opened with, or nil before
+// the first Connect
+func (s *SQLiteService) Config() *Config {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	if s.config == nil {
+		return nil
+	}
+	config := *s.config
+	return &config
+}
+
+// TableRowCounts returns the number of rows in each table, including the migration table
+func (s *SQLiteService) TableRowCounts(ctx context.Context) (map[string]int64, error) {
+	s.stateMu.RLock()
+	db := s.db
+	s.stateMu.RUnlock()
+	if db == nil {
+		return nil, dberrors.HandleConnectionError("TableRowCounts", "database not connected")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, dberrors.WrapDatabaseError("TableRowCounts", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, dberrors.WrapDatabaseError("TableRowCounts", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, dberrors.WrapDatabaseError("TableRowCounts", err)
+	}
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		var count int64
+		// Table names come from sqlite_master, quoting guards against unusual names
+		query := `SELECT COUNT(*) FROM "` + strings.ReplaceAll(table, `"`, `""`) + `"`
+		if err := db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+			return nil, dberrors.WrapDatabaseErrorWithContext("TableRowCounts", err, map[string]string{
+				"table": table,
+			})
+		}
+		counts[table] = count
+	}
+	return counts, nil
+}
+
+// Redacted returns a copy of the configuration with the directories of its paths and the
+// encryption passphrase removed, so it can be shared without revealing user or folder names
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Path = RedactPath(c.Path)
+	redacted.MigrationsPath = RedactPath(c.MigrationsPath)
+	redacted.BackupPath = RedactPath(c.BackupPath)
+	redacted.EncryptionPassphrase = ""
+	return &redacted
+}
+
+// RedactPath replaces the directory of path, keeping only the file name. Empty paths and
+// in-memory databases are returned as they are.
+func RedactPath(path string) string {
+	if path == "" || path == ":memory:" {
+		return path
+	}
+	return redactedDir + "/" + filepath.Base(path)
+}
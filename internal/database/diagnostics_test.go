@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"qwin/internal/infrastructure/logging"
+)
+
+func TestSQLiteService_TableRowCounts(t *testing.T) {
+	t.Parallel()
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "test.db")
+
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	if service.Config() != nil {
+		t.Error("Expected no configuration before connecting")
+	}
+	if _, err := service.TableRowCounts(context.Background()); err == nil {
+		t.Error("Expected TableRowCounts to fail before connecting")
+	}
+
+	ctx := context.Background()
+	if err := service.Connect(ctx, config); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer service.Close()
+	if err := service.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	if got := service.Config(); got == nil || got.Path != config.Path || got == config {
+		t.Errorf("Config() = %+v, want a copy of the connected configuration", got)
+	}
+
+	if _, err := service.DB().ExecContext(ctx, "INSERT INTO settings (key, value) VALUES ('a', '1'), ('b', '2')"); err != nil {
+		t.Fatalf("Failed to insert settings: %v", err)
+	}
+	counts, err := service.TableRowCounts(ctx)
+	if err != nil {
+		t.Fatalf("TableRowCounts failed: %v", err)
+	}
+	if counts["settings"] != 2 {
+		t.Errorf("settings rows = %d, want 2", counts["settings"])
+	}
+	if _, ok := counts["app_usage"]; !ok {
+		t.Errorf("Expected every table to be counted, got %v", counts)
+	}
+	if counts["goose_db_version"] == 0 {
+		t.Error("Expected the applied migrations to be counted")
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join("home", "alice", "qwin", "qwin.db")
+	config.BackupPath = filepath.Join("home", "alice", "qwin", "backups")
+
+	redacted := config.Redacted()
+	if redacted.Path != "[redacted]/qwin.db" || redacted.BackupPath != "[redacted]/backups" {
+		t.Errorf("Redacted() paths = %q, %q", redacted.Path, redacted.BackupPath)
+	}
+	if config.Path == redacted.Path {
+		t.Error("Expected the original configuration to be left alone")
+	}
+	if redacted.BusyTimeout != config.BusyTimeout {
+		t.Error("Expected settings other than paths to be kept")
+	}
+	if RedactPath(":memory:") != ":memory:" || RedactPath("") != "" {
+		t.Error("Expected in-memory and empty paths to be kept")
+	}
+}
@@ -0,0 +1,457 @@
+package database
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Key sources for encrypted databases
+const (
+	// KeySourceKeyring keeps a random key in the operating system's keyring
+	KeySourceKeyring = "keyring"
+	// KeySourcePassphrase derives the key from Config.EncryptionPassphrase
+	KeySourcePassphrase = "passphrase"
+)
+
+// ErrDecryptionFailed is returned when an encrypted database can't be decrypted with the
+// key available, either because it is the wrong key or because the file was damaged
+var ErrDecryptionFailed = errors.New("database cannot be decrypted: wrong key or damaged file")
+
+// Encrypted databases keep SQLite's file layout with every page sealed on its own, so the
+// file stays the live store and SQLite reads and writes it through the encrypted VFS.
+// Pages are fixed at encryptedPageSize and SQLite leaves encryptedReserveSize bytes at the
+// end of each one unused; they hold the page's nonce and AES-256-GCM tag. The database
+// file's first 16 bytes, where SQLite keeps its magic string, hold the key derivation
+// instead and stay in the clear, along with the salt at the end of page 1:
+//
+//	magic "QWINENC1" | kdf (1) | argon2 threads (1) | argon2 time (2) | argon2 memory KiB (4)
+//
+// Pages of the rollback journal and the WAL are sealed the same way, so nothing SQLite
+// writes next to the database is readable either.
+var encryptedMagic = []byte("QWINENC1")
+
+// sqliteMagic starts every plaintext SQLite database file
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+const (
+	encryptionKeySize   = 32
+	encryptionSaltSize  = 16
+	encryptionNonceSize = 12
+	encryptionTagSize   = 16
+	encryptedHeaderSize = 16
+
+	encryptedPageSize    = 4096
+	encryptedReserveSize = 48
+	encryptedUsableSize  = encryptedPageSize - encryptedReserveSize
+
+	// Offsets in the reserved bytes at the end of a page
+	reserveNonceOffset = 0
+	reserveTagOffset   = reserveNonceOffset + encryptionNonceSize
+	reserveSaltOffset  = reserveTagOffset + encryptionTagSize
+
+	// kdfNone means the key is used as stored; kdfArgon2id derives it from a passphrase
+	kdfNone     byte = 0
+	kdfArgon2id byte = 1
+)
+
+// WAL and rollback journal layout, for telling page data from SQLite's own headers
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+	// journalSectorSize is the smallest sector SQLite aligns journal headers to; page
+	// records never start on one, as each is 8 bytes longer than a page
+	journalSectorSize = 512
+)
+
+// SQLite result codes returned by the VFS
+const (
+	sqliteOK             = 0
+	sqliteCorrupt        = 11
+	sqliteNotADB         = 26
+	sqliteIOErrShortRead = 10 | 2<<8
+	sqliteIOErrWrite     = 10 | 3<<8
+)
+
+// kdfParams are the argon2id parameters a passphrase key was derived with
+type kdfParams struct {
+	time      uint32
+	memoryKiB uint32
+	threads   uint8
+}
+
+// passphraseKDF follows the argon2id recommendation for interactive use
+var passphraseKDF = kdfParams{time: 3, memoryKiB: 64 * 1024, threads: 4}
+
+// encryptedHeader is the parsed header of an encrypted database file
+type encryptedHeader struct {
+	kdf    byte
+	params kdfParams
+	salt   []byte
+}
+
+// marshal encodes the header as it is written at the start of the file
+func (h *encryptedHeader) marshal() []byte {
+	buf := make([]byte, 0, encryptedHeaderSize)
+	buf = append(buf, encryptedMagic...)
+	buf = append(buf, h.kdf, h.params.threads)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(h.params.time))
+	return binary.BigEndian.AppendUint32(buf, h.params.memoryKiB)
+}
+
+// parseEncryptedHeader reads the header from page 1 of an encrypted database
+func parseEncryptedHeader(page []byte) (*encryptedHeader, error) {
+	if len(page) < encryptedPageSize || !bytes.HasPrefix(page, encryptedMagic) {
+		return nil, fmt.Errorf("not an encrypted database file")
+	}
+	rest := page[len(encryptedMagic):encryptedHeaderSize]
+	h := &encryptedHeader{kdf: rest[0]}
+	h.params.threads = rest[1]
+	h.params.time = uint32(binary.BigEndian.Uint16(rest[2:4]))
+	h.params.memoryKiB = binary.BigEndian.Uint32(rest[4:8])
+	salt := page[encryptedUsableSize+reserveSaltOffset:]
+	h.salt = append([]byte(nil), salt[:encryptionSaltSize]...)
+	if h.kdf != kdfNone && h.kdf != kdfArgon2id {
+		return nil, fmt.Errorf("unknown key derivation %d", h.kdf)
+	}
+	return h, nil
+}
+
+// readEncryptedHeader reads the header of the encrypted database at path along with its
+// first page, which proves whether a key fits
+func readEncryptedHeader(path string) (*encryptedHeader, []byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	page := make([]byte, encryptedPageSize)
+	if _, err := io.ReadFull(file, page); err != nil {
+		return nil, nil, fmt.Errorf("encrypted database is truncated: %w", err)
+	}
+	header, err := parseEncryptedHeader(page)
+	return header, page, err
+}
+
+// databaseKey is the key of one encrypted database. Passphrase keys are derived once per
+// salt, as every file written through the same cipher shares its salt.
+type databaseKey struct {
+	raw        []byte // keyring key, used as is
+	passphrase []byte
+	params     kdfParams
+	salt       []byte // salt the key was last derived for
+	derived    []byte // key derived for salt
+}
+
+// newKeyringKey returns a key for a database whose key is kept in the keyring
+func newKeyringKey(raw []byte) *databaseKey {
+	return &databaseKey{raw: raw}
+}
+
+// newPassphraseKey returns a key derived from passphrase
+func newPassphraseKey(passphrase string) *databaseKey {
+	return &databaseKey{passphrase: []byte(passphrase), params: passphraseKDF}
+}
+
+// forHeader returns the AES key for a file with the given header
+func (k *databaseKey) forHeader(h *encryptedHeader) ([]byte, error) {
+	if h.kdf == kdfNone {
+		if k.raw == nil {
+			return nil, fmt.Errorf("database was encrypted with a keyring key, not a passphrase")
+		}
+		return k.raw, nil
+	}
+	if k.passphrase == nil {
+		return nil, fmt.Errorf("database was encrypted with a passphrase, not a keyring key")
+	}
+	if k.derived != nil && bytes.Equal(k.salt, h.salt) && k.params == h.params {
+		return k.derived, nil
+	}
+	if h.params.time == 0 || h.params.threads == 0 {
+		return nil, fmt.Errorf("invalid key derivation parameters")
+	}
+	derived := argon2.IDKey(k.passphrase, h.salt, h.params.time, h.params.memoryKiB, h.params.threads, encryptionKeySize)
+	k.salt, k.params, k.derived = append([]byte(nil), h.salt...), h.params, derived
+	return derived, nil
+}
+
+// newHeader returns a header for a new encrypted database, with a fresh salt
+func (k *databaseKey) newHeader() (*encryptedHeader, error) {
+	h := &encryptedHeader{kdf: kdfArgon2id, params: k.params, salt: make([]byte, encryptionSaltSize)}
+	if k.raw != nil {
+		h.kdf, h.params = kdfNone, kdfParams{}
+	}
+	if _, err := rand.Read(h.salt); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// fileKind is the kind of file SQLite opened through the encrypted VFS. The values match
+// the QWIN_FILE_ constants in encryption_vfs.h.
+type fileKind int
+
+const (
+	fileDatabase fileKind = 1
+	fileJournal  fileKind = 2
+	fileWAL      fileKind = 3
+)
+
+// fileIO reads and writes a file underneath the encrypted VFS, returning SQLite result codes
+type fileIO interface {
+	readAt(p []byte, off int64) int
+	writeAt(p []byte, off int64) int
+}
+
+// pageCipher seals and opens the pages of one encrypted database and its journals
+type pageCipher struct {
+	aead   cipher.AEAD
+	header []byte // first bytes of the database file
+	salt   []byte
+}
+
+// newPageCipher returns the cipher for a database file with the given header
+func newPageCipher(key *databaseKey, header *encryptedHeader) (*pageCipher, error) {
+	aesKey, err := key.forHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &pageCipher{aead: aead, header: header.marshal(), salt: header.salt}, nil
+}
+
+// checkFirstPage opens page 1 of a database file, telling a wrong key from a usable one
+func (c *pageCipher) checkFirstPage(page []byte) error {
+	page = append([]byte(nil), page...)
+	if c.openPage(fileDatabase, 0, page) != sqliteOK {
+		return ErrDecryptionFailed
+	}
+	return nil
+}
+
+// segment returns the part of a file that off falls in, and whether it is page data
+// rather than one of SQLite's headers. Journals are told apart by the size of the access,
+// amt, as their headers sit at offsets that depend on the sector size.
+func (c *pageCipher) segment(kind fileKind, off int64, amt int) (start int64, size int, data bool) {
+	switch kind {
+	case fileDatabase:
+		return off - off%encryptedPageSize, encryptedPageSize, true
+	case fileWAL:
+		if off < walHeaderSize {
+			return 0, walHeaderSize, false
+		}
+		frame := int64(walFrameHeaderSize + encryptedPageSize)
+		start = off - (off-walHeaderSize)%frame
+		if off < start+walFrameHeaderSize {
+			return start, walFrameHeaderSize, false
+		}
+		return start + walFrameHeaderSize, encryptedPageSize, true
+	default:
+		return off, amt, amt == encryptedPageSize && off%journalSectorSize != 0
+	}
+}
+
+// read fills p from off in a file, opening the pages it covers
+func (c *pageCipher) read(kind fileKind, file fileIO, p []byte, off int64) int {
+	short := false
+	for len(p) > 0 {
+		start, size, data := c.segment(kind, off, len(p))
+		n := min(len(p), int(start+int64(size)-off))
+		if !data {
+			rc := file.readAt(p[:n], off)
+			if rc == sqliteIOErrShortRead {
+				short = true
+			} else if rc != sqliteOK {
+				return rc
+			}
+		} else {
+			page := make([]byte, size)
+			rc := file.readAt(page, start)
+			if rc == sqliteIOErrShortRead {
+				short = true
+			} else if rc != sqliteOK {
+				return rc
+			}
+			if rc := c.openPage(kind, start, page); rc != sqliteOK {
+				return rc
+			}
+			copy(p[:n], page[off-start:])
+		}
+		p = p[n:]
+		off += int64(n)
+	}
+	if short {
+		return sqliteIOErrShortRead
+	}
+	return sqliteOK
+}
+
+// write writes p at off in a file, sealing the pages it covers. SQLite writes pages
+// whole, so a write of part of one is refused rather than sealed on its own.
+func (c *pageCipher) write(kind fileKind, file fileIO, p []byte, off int64) int {
+	for len(p) > 0 {
+		start, size, data := c.segment(kind, off, len(p))
+		n := min(len(p), int(start+int64(size)-off))
+		if !data {
+			if rc := file.writeAt(p[:n], off); rc != sqliteOK {
+				return rc
+			}
+		} else {
+			if start != off || n != size {
+				return sqliteIOErrWrite
+			}
+			sealed, rc := c.sealPage(kind, off, p[:n])
+			if rc != sqliteOK {
+				return rc
+			}
+			if rc := file.writeAt(sealed, off); rc != sqliteOK {
+				return rc
+			}
+		}
+		p = p[n:]
+		off += int64(n)
+	}
+	return sqliteOK
+}
+
+// sealPage encrypts a page written at off, returning what goes into the file
+func (c *pageCipher) sealPage(kind fileKind, off int64, page []byte) ([]byte, int) {
+	sealed := make([]byte, encryptedPageSize)
+	copy(sealed, page)
+	// SQLite reads the reserved bytes back as zeroes and never writes to them itself; they
+	// must stay that way, as WAL checksums cover them
+	if !isZeroed(page[encryptedUsableSize:]) {
+		return nil, sqliteIOErrWrite
+	}
+	start := 0
+	if kind == fileDatabase && off == 0 {
+		// The page layout must be what the VFS expects, or sealing would overwrite data
+		if binary.BigEndian.Uint16(page[16:18]) != encryptedPageSize || page[20] != encryptedReserveSize {
+			return nil, sqliteIOErrWrite
+		}
+		copy(sealed, c.header)
+		copy(sealed[encryptedUsableSize+reserveSaltOffset:], c.salt)
+		start = encryptedHeaderSize
+	}
+
+	reserve := sealed[encryptedUsableSize:]
+	nonce := reserve[reserveNonceOffset : reserveNonceOffset+encryptionNonceSize]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, sqliteIOErrWrite
+	}
+	out := c.aead.Seal(nil, nonce, page[start:encryptedUsableSize], pageAAD(kind, off))
+	copy(sealed[start:encryptedUsableSize], out)
+	copy(reserve[reserveTagOffset:], out[encryptedUsableSize-start:])
+	return sealed, sqliteOK
+}
+
+// openPage decrypts a page read from off in place. Pages of the database that fail to
+// open are reported as corrupt; in journals they read as zeroes, so SQLite's own
+// checksums reject them like any torn write.
+func (c *pageCipher) openPage(kind fileKind, off int64, page []byte) int {
+	// Pages beyond the end of the file, or never written
+	if isZeroed(page) {
+		return sqliteOK
+	}
+
+	start := 0
+	if kind == fileDatabase && off == 0 {
+		if !bytes.Equal(page[:encryptedHeaderSize], c.header) ||
+			!bytes.Equal(page[encryptedUsableSize+reserveSaltOffset:][:encryptionSaltSize], c.salt) {
+			return sqliteNotADB
+		}
+		start = encryptedHeaderSize
+	}
+
+	reserve := page[encryptedUsableSize:]
+	nonce := reserve[reserveNonceOffset : reserveNonceOffset+encryptionNonceSize]
+	sealed := make([]byte, 0, encryptedUsableSize-start+encryptionTagSize)
+	sealed = append(sealed, page[start:encryptedUsableSize]...)
+	sealed = append(sealed, reserve[reserveTagOffset:reserveTagOffset+encryptionTagSize]...)
+	plaintext, err := c.aead.Open(sealed[:0], nonce, sealed, pageAAD(kind, off))
+	if err != nil {
+		if kind == fileDatabase {
+			return sqliteCorrupt
+		}
+		clear(page)
+		return sqliteOK
+	}
+
+	copy(page[start:], plaintext)
+	clear(reserve)
+	if start > 0 {
+		copy(page, sqliteMagic)
+	}
+	return sqliteOK
+}
+
+// pageAAD binds a sealed page to where it was written, so pages can't be moved around
+func pageAAD(kind fileKind, off int64) []byte {
+	return binary.BigEndian.AppendUint64([]byte{byte(kind)}, uint64(off))
+}
+
+// isZeroed reports whether every byte of p is zero
+func isZeroed(p []byte) bool {
+	for _, b := range p {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// databaseFileKind says what is stored at a database path
+type databaseFileKind int
+
+const (
+	databaseFileMissing databaseFileKind = iota
+	databaseFilePlaintext
+	databaseFileEncrypted
+	databaseFileUnknown
+)
+
+// detectDatabaseFile reports whether path holds a plaintext or an encrypted database.
+// Empty files count as missing, as SQLite leaves them behind before its first write.
+func detectDatabaseFile(path string) (databaseFileKind, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return databaseFileMissing, nil
+		}
+		return databaseFileUnknown, err
+	}
+	defer file.Close()
+
+	prefix := make([]byte, len(sqliteMagic))
+	n, err := io.ReadFull(file, prefix)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return databaseFileUnknown, err
+	}
+	prefix = prefix[:n]
+	switch {
+	case n == 0:
+		return databaseFileMissing, nil
+	case bytes.HasPrefix(prefix, encryptedMagic):
+		return databaseFileEncrypted, nil
+	case bytes.Equal(prefix, sqliteMagic):
+		return databaseFilePlaintext, nil
+	default:
+		return databaseFileUnknown, nil
+	}
+}
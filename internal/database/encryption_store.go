@@ -0,0 +1,250 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dberrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// sqliteFcntlReserveBytes is SQLITE_FCNTL_RESERVE_BYTES, which sets how many bytes SQLite
+// leaves unused at the end of each page
+const sqliteFcntlReserveBytes = 38
+
+// encryptedStore is an open encrypted database. The file at path stays the live store:
+// SQLite reads and writes it through an encrypted VFS that seals each page as it is
+// written, and the rollback journal and WAL with it, so nothing reaches the disk in
+// plaintext. Backups taken with VACUUM INTO go through the same VFS and share the key.
+type encryptedStore struct {
+	path string
+	vfs  *encryptedVFS
+}
+
+// openEncryptedStore prepares the encrypted database at config.Path for connections. A
+// plaintext database found there is encrypted in place first, and a missing one is
+// created empty, so connections always find an encrypted file.
+func openEncryptedStore(ctx context.Context, config *Config, keyring Keyring, logger logging.Logger) (*encryptedStore, error) {
+	kind, err := detectDatabaseFile(config.Path)
+	if err != nil {
+		return nil, dberrors.NewRepositoryErrorWithContext("Connect", err, dberrors.ClassifyError(err), map[string]string{
+			"path": config.Path,
+		})
+	}
+	if kind == databaseFileUnknown {
+		return nil, dberrors.HandleCorruptionError("Connect", config.Path, "file is neither a SQLite database nor an encrypted one")
+	}
+
+	key, err := encryptionKey(config, keyring, kind != databaseFileEncrypted)
+	if err != nil {
+		return nil, dberrors.NewRepositoryErrorWithContext("Connect", err, dberrors.ErrCodePermission, map[string]string{
+			"path":       config.Path,
+			"key_source": config.EncryptionKeySource,
+		})
+	}
+
+	var cipher *pageCipher
+	if kind == databaseFileEncrypted {
+		cipher, err = fileCipher(config.Path, key)
+	} else {
+		var header *encryptedHeader
+		if header, err = key.newHeader(); err == nil {
+			cipher, err = newPageCipher(key, header)
+		}
+	}
+	if err != nil {
+		// Not treated as corruption: with the wrong key, recovery would replace good data
+		return nil, dberrors.NewRepositoryErrorWithContext("Connect", err, dberrors.ErrCodePermission, map[string]string{
+			"path":       config.Path,
+			"key_source": config.EncryptionKeySource,
+		})
+	}
+
+	vfs, err := registerEncryptedVFS(cipher)
+	if err != nil {
+		return nil, dberrors.HandleConnectionError("Connect", err.Error())
+	}
+	store := &encryptedStore{path: config.Path, vfs: vfs}
+
+	if kind != databaseFileEncrypted {
+		if err := store.create(ctx, config, kind == databaseFilePlaintext); err != nil {
+			vfs.unregister()
+			return nil, dberrors.NewRepositoryErrorWithContext("Connect", err, dberrors.ClassifyError(err), map[string]string{
+				"path":  config.Path,
+				"phase": "encrypt",
+			})
+		}
+	}
+	if kind == databaseFilePlaintext {
+		for _, suffix := range databaseSidecars {
+			if err := os.Remove(config.Path + suffix); err != nil && !os.IsNotExist(err) {
+				logger.Warn("Failed to remove plaintext database file", "path", config.Path+suffix, "error", err)
+			}
+		}
+		logger.Info("Encrypted existing plaintext database", "path", config.Path)
+	}
+	return store, nil
+}
+
+// encryptionKey returns the key configured for the database. Keyring keys are created when
+// create is set, for databases that aren't encrypted yet.
+func encryptionKey(config *Config, keyring Keyring, create bool) (*databaseKey, error) {
+	switch config.EncryptionKeySource {
+	case KeySourcePassphrase:
+		if config.EncryptionPassphrase == "" {
+			return nil, fmt.Errorf("no passphrase configured for the encrypted database")
+		}
+		return newPassphraseKey(config.EncryptionPassphrase), nil
+	case KeySourceKeyring, "":
+		if keyring == nil {
+			keyring = OSKeyring()
+		}
+		key, err := loadKeyringKey(keyring, config.Path, create)
+		if err == ErrSecretNotFound {
+			return nil, fmt.Errorf("the keyring holds no key for this encrypted database")
+		}
+		return key, err
+	default:
+		return nil, fmt.Errorf("unknown encryption key source %q", config.EncryptionKeySource)
+	}
+}
+
+// fileCipher returns the cipher for the encrypted database file at path, checking key
+// opens its first page
+func fileCipher(path string, key *databaseKey) (*pageCipher, error) {
+	header, firstPage, err := readEncryptedHeader(path)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := newPageCipher(key, header)
+	if err != nil {
+		return nil, err
+	}
+	if err := cipher.checkFirstPage(firstPage); err != nil {
+		return nil, err
+	}
+	return cipher, nil
+}
+
+// create writes a new encrypted database to the store's path with the contents of the
+// plaintext database there, or empty when there is none. VACUUM INTO copies it through the
+// VFS, with the page size and reserved bytes the cipher needs, into a file beside it that
+// then replaces the plaintext one.
+func (s *encryptedStore) create(ctx context.Context, config *Config, fromPlaintext bool) error {
+	source := ":memory:"
+	if fromPlaintext {
+		source = config.GetConnectionString()
+	}
+	db, err := sql.Open("sqlite3", source)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	// Reserved bytes are set per connection, so VACUUM INTO must run on the same one
+	db.SetMaxOpenConns(1)
+
+	target := s.path + ".encrypting"
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	defer os.Remove(target)
+
+	// The page size comes first, as setting it resets the reserved bytes
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("PRAGMA page_size = %d", encryptedPageSize)); err != nil {
+		return err
+	}
+	err = withSQLiteConn(ctx, db, func(conn *sqlite3.SQLiteConn) error {
+		return conn.SetFileControlInt("main", sqliteFcntlReserveBytes, encryptedReserveSize)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reserve page space for encryption: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", databaseURI(target, url.Values{"vfs": {s.vfs.name}})); err != nil {
+		return fmt.Errorf("failed to write encrypted database: %w", err)
+	}
+
+	// Closing a plaintext database in WAL mode folds its log back in before it is replaced
+	if err := db.Close(); err != nil {
+		return err
+	}
+	return os.Rename(target, s.path)
+}
+
+// connectionString returns the connection string for the store's database, opened
+// through its VFS
+func (s *encryptedStore) connectionString(config *Config) string {
+	return config.GetConnectionString() + "&vfs=" + url.QueryEscape(s.vfs.name)
+}
+
+// Close drops the store's VFS. Callers close the database first.
+func (s *encryptedStore) Close() {
+	s.vfs.unregister()
+}
+
+// openDatabaseFile opens the database file at path read-only, for checking files other
+// than the live database such as backups. Encrypted files are opened through a VFS of
+// their own with key, which may be nil when no key is configured. release closes the
+// database and drops the VFS.
+func openDatabaseFile(path string, key *databaseKey) (db *sql.DB, release func(), err error) {
+	kind, err := detectDatabaseFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kind != databaseFileEncrypted {
+		db, err := sql.Open("sqlite3", databaseURI(path, url.Values{"mode": {"ro"}}))
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() { db.Close() }, nil
+	}
+
+	if key == nil {
+		return nil, nil, fmt.Errorf("%s is encrypted and no key is configured", filepath.Base(path))
+	}
+	cipher, err := fileCipher(path, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	vfs, err := registerEncryptedVFS(cipher)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err = sql.Open(encryptedDriverName, databaseURI(path, url.Values{"mode": {"ro"}, "vfs": {vfs.name}}))
+	if err != nil {
+		vfs.unregister()
+		return nil, nil, err
+	}
+	return db, func() {
+		db.Close()
+		vfs.unregister()
+	}, nil
+}
+
+// databaseURI returns a SQLite URI filename for path with the given query parameters
+func databaseURI(path string, params url.Values) string {
+	escaped := strings.NewReplacer("%", "%25", "?", "%3F", "#", "%23").Replace(filepath.ToSlash(path))
+	return "file:" + escaped + "?" + params.Encode()
+}
+
+// withSQLiteConn runs fn with the driver connection underneath db
+func withSQLiteConn(ctx context.Context, db *sql.DB, fn func(*sqlite3.SQLiteConn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn interface{}) error {
+		sqliteConn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		return fn(sqliteConn)
+	})
+}
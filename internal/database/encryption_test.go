@@ -0,0 +1,420 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	dberrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+)
+
+// secretValue is written to encrypted databases and must never show up in their files
+const secretValue = "SecretProjectTracker"
+
+// memoryKeyring keeps secrets in memory for tests
+type memoryKeyring struct {
+	mutex   sync.Mutex
+	secrets map[string][]byte
+}
+
+func newMemoryKeyring() *memoryKeyring {
+	return &memoryKeyring{secrets: make(map[string][]byte)}
+}
+
+func (k *memoryKeyring) Get(service, account string) ([]byte, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	secret, ok := k.secrets[service+"/"+account]
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	return secret, nil
+}
+
+func (k *memoryKeyring) Set(service, account string, secret []byte) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.secrets[service+"/"+account] = append([]byte(nil), secret...)
+	return nil
+}
+
+// encryptedTestConfig returns a file database configuration with encryption enabled
+func encryptedTestConfig(path, keySource string) *Config {
+	config := DefaultConfig()
+	config.Path = path
+	config.EncryptionEnabled = true
+	config.EncryptionKeySource = keySource
+	if keySource == KeySourcePassphrase {
+		config.EncryptionPassphrase = "correct horse battery staple"
+	}
+	return config
+}
+
+// openTestService connects and migrates a service, failing the test on error
+func openTestService(t *testing.T, config *Config, keyring Keyring) *SQLiteService {
+	t.Helper()
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	service.SetKeyring(keyring)
+	ctx := context.Background()
+	if err := service.Connect(ctx, config); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := service.Migrate(ctx); err != nil {
+		service.Close()
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return service
+}
+
+// assertNotReadable checks path holds an encrypted file with no trace of secretValue
+func assertNotReadable(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if !bytes.HasPrefix(data, encryptedMagic) || bytes.HasPrefix(data, sqliteMagic) {
+		t.Errorf("%s does not look encrypted", path)
+	}
+	if bytes.Contains(data, []byte(secretValue)) {
+		t.Errorf("%s contains the plaintext value", path)
+	}
+
+	// SQLite itself can't read it either
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("SELECT * FROM settings"); err == nil {
+		t.Errorf("SQLite read %s without the key", path)
+	}
+}
+
+// readSecret returns the value stored by writeSecret
+func readSecret(t *testing.T, service *SQLiteService) string {
+	t.Helper()
+	var value string
+	if err := service.DB().QueryRow("SELECT value FROM settings WHERE key = 'secret'").Scan(&value); err != nil {
+		t.Fatalf("Failed to read setting: %v", err)
+	}
+	return value
+}
+
+func writeSecret(t *testing.T, service *SQLiteService) {
+	t.Helper()
+	if _, err := service.DB().Exec("INSERT INTO settings (key, value) VALUES ('secret', ?)", secretValue); err != nil {
+		t.Fatalf("Failed to write setting: %v", err)
+	}
+}
+
+func TestEncryptedDatabase_PassphraseKey(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "qwin.db")
+	config := encryptedTestConfig(path, KeySourcePassphrase)
+
+	service := openTestService(t, config, nil)
+	writeSecret(t, service)
+
+	// Backups go through the same VFS and are encrypted with the same key
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	if _, err := service.DB().Exec("VACUUM INTO ?", backup); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	if err := service.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	assertNotReadable(t, path)
+	assertNotReadable(t, backup)
+	if err := verifyDatabaseFile(context.Background(), backup, newPassphraseKey(config.EncryptionPassphrase)); err != nil {
+		t.Errorf("Expected the backup to verify with the passphrase: %v", err)
+	}
+	if err := verifyDatabaseFile(context.Background(), backup, nil); err == nil {
+		t.Error("Expected the backup not to verify without a key")
+	}
+
+	// The same passphrase opens it again
+	service = openTestService(t, config, nil)
+	if got := readSecret(t, service); got != secretValue {
+		t.Errorf("value = %q, want %q", got, secretValue)
+	}
+	service.Close()
+
+	// A wrong passphrase is refused without being mistaken for corruption
+	wrong := encryptedTestConfig(path, KeySourcePassphrase)
+	wrong.EncryptionPassphrase = "wrong"
+	err := NewSQLiteService(logging.NewDefaultLogger()).Connect(context.Background(), wrong)
+	if err == nil {
+		t.Fatal("Expected a wrong passphrase to be refused")
+	}
+	if dberrors.IsCorruption(err) || !dberrors.IsPermission(err) {
+		t.Errorf("Expected a permission error, got %v", err)
+	}
+}
+
+func TestEncryptedDatabase_MigratesPlaintextDatabase(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "qwin.db")
+
+	plain := DefaultConfig()
+	plain.Path = path
+	service := openTestService(t, plain, nil)
+	writeSecret(t, service)
+	service.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(data, sqliteMagic) {
+		t.Fatalf("Expected a plaintext database to start with: %v", err)
+	}
+
+	keyring := newMemoryKeyring()
+	config := encryptedTestConfig(path, KeySourceKeyring)
+	service = openTestService(t, config, keyring)
+	if got := readSecret(t, service); got != secretValue {
+		t.Errorf("value = %q after encrypting, want %q", got, secretValue)
+	}
+	if err := service.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	assertNotReadable(t, path)
+	for _, suffix := range databaseSidecars {
+		if _, err := os.Stat(path + suffix); !os.IsNotExist(err) {
+			t.Errorf("Expected the plaintext %s file to be removed", suffix)
+		}
+	}
+	if _, err := keyring.Get(keyringService, keyringAccount(path)); err != nil {
+		t.Errorf("Expected the key to be stored in the keyring: %v", err)
+	}
+
+	// The key is read back from the keyring
+	service = openTestService(t, config, keyring)
+	if got := readSecret(t, service); got != secretValue {
+		t.Errorf("value = %q after reopening, want %q", got, secretValue)
+	}
+	service.Close()
+
+	// Without the key the database can't be opened
+	err = NewSQLiteService(logging.NewDefaultLogger()).Connect(context.Background(), config)
+	if err == nil {
+		t.Error("Expected connecting without the key to fail")
+	}
+	other := NewSQLiteService(logging.NewDefaultLogger())
+	other.SetKeyring(newMemoryKeyring())
+	if err := other.Connect(context.Background(), config); err == nil || dberrors.IsCorruption(err) {
+		t.Errorf("Expected a missing key to be reported, got %v", err)
+	}
+
+	// Nor with encryption turned off, which must not pass for corruption either
+	plain.EncryptionEnabled = false
+	err = NewSQLiteService(logging.NewDefaultLogger()).Connect(context.Background(), plain)
+	if err == nil || !dberrors.IsPermission(err) {
+		t.Errorf("Expected opening without encryption to be refused, got %v", err)
+	}
+}
+
+func TestEncryptedDatabase_WritesPagesInPlace(t *testing.T) {
+	t.Parallel()
+	for _, journalMode := range []string{"WAL", "DELETE"} {
+		t.Run(journalMode, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "qwin.db")
+			config := encryptedTestConfig(path, KeySourceKeyring)
+			config.JournalMode = journalMode
+			keyring := newMemoryKeyring()
+
+			service := openTestService(t, config, keyring)
+			defer service.Close()
+			writeSecret(t, service)
+
+			// A committed change is on disk while the database is open, encrypted wherever
+			// SQLite put it
+			for _, file := range append([]string{""}, databaseSidecars...) {
+				data, err := os.ReadFile(path + file)
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if bytes.Contains(data, []byte(secretValue)) {
+					t.Errorf("%s contains the plaintext value", filepath.Base(path+file))
+				}
+			}
+			assertNotReadable(t, path)
+
+			key, err := loadKeyringKey(keyring, path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db, release, err := openDatabaseFile(path, key)
+			if err != nil {
+				t.Fatalf("Failed to open the file beside the service: %v", err)
+			}
+			defer release()
+			var value string
+			if err := db.QueryRow("SELECT value FROM settings WHERE key = 'secret'").Scan(&value); err != nil || value != secretValue {
+				t.Errorf("value = %q (%v) read from the file, want %q", value, err, secretValue)
+			}
+		})
+	}
+}
+
+func TestOpenWithRecovery_RestoresEncryptedBackup(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "usage.db")
+	config := encryptedTestConfig(path, KeySourceKeyring)
+	keyring := newMemoryKeyring()
+	ctx := context.Background()
+
+	service := openTestService(t, config, keyring)
+	if _, err := service.DB().Exec(`INSERT INTO daily_usage (date, total_time) VALUES ('2024-06-01 00:00:00+00:00', 200)`); err != nil {
+		t.Fatalf("Failed to insert usage: %v", err)
+	}
+	backupDir := migrationBackupDir(config)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(backupDir, migrationBackupPrefix+"v5-to-v6-a.db")
+	if _, err := service.DB().Exec("VACUUM INTO ?", backup); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	writeSecret(t, service)
+	service.Close()
+
+	// Damage a page past the first, so the key still opens the file but a check fails
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteAt(bytes.Repeat([]byte{0xA5}, 64), 2*encryptedPageSize+100); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	service = NewSQLiteService(logging.NewDefaultLogger())
+	service.SetKeyring(keyring)
+	check, err := OpenWithRecovery(ctx, service, config, logging.NewDefaultLogger())
+	if err != nil {
+		t.Fatalf("OpenWithRecovery failed: %v", err)
+	}
+	defer service.Close()
+
+	if check.Recovery == nil || check.Recovery.Method != RecoveryFromBackup {
+		t.Fatalf("Expected recovery from the encrypted backup, got %+v", check.Recovery)
+	}
+	if check.Recovery.LatestUsageDate != "2024-06-01" {
+		t.Errorf("LatestUsageDate = %q, want it read from the encrypted database", check.Recovery.LatestUsageDate)
+	}
+	if total := storedTotalTime(t, service); total != 200 {
+		t.Errorf("total_time = %d, want the backup's 200", total)
+	}
+	assertNotReadable(t, path)
+}
+
+// memoryFile is a fileIO over a byte slice, zero-filling reads past its end like SQLite
+type memoryFile struct {
+	data []byte
+}
+
+func (f *memoryFile) readAt(p []byte, off int64) int {
+	clear(p)
+	if off >= int64(len(f.data)) {
+		return sqliteIOErrShortRead
+	}
+	if n := copy(p, f.data[off:]); n < len(p) {
+		return sqliteIOErrShortRead
+	}
+	return sqliteOK
+}
+
+func (f *memoryFile) writeAt(p []byte, off int64) int {
+	if end := int(off) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	copy(f.data[off:], p)
+	return sqliteOK
+}
+
+func TestPageCipher_JournalPages(t *testing.T) {
+	key := newKeyringKey(bytes.Repeat([]byte{1}, encryptionKeySize))
+	header, err := key.newHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := newPageCipher(key, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A journal header filling a sector, then one page record: page number, page, checksum
+	journalHeader := bytes.Repeat([]byte{0xD9}, journalSectorSize)
+	page := make([]byte, encryptedPageSize)
+	copy(page, bytes.Repeat([]byte(secretValue), encryptedUsableSize/len(secretValue)))
+	file := &memoryFile{}
+	writes := []struct {
+		data []byte
+		off  int64
+	}{
+		{journalHeader, 0},
+		{[]byte{0, 0, 0, 7}, journalSectorSize},
+		{page, journalSectorSize + 4},
+		{[]byte{1, 2, 3, 4}, journalSectorSize + 4 + encryptedPageSize},
+	}
+	for _, w := range writes {
+		if rc := cipher.write(fileJournal, file, w.data, w.off); rc != sqliteOK {
+			t.Fatalf("write at %d = %d", w.off, rc)
+		}
+	}
+	if bytes.Contains(file.data, []byte(secretValue)) {
+		t.Error("Journal holds the page in plaintext")
+	}
+
+	for _, w := range writes {
+		got := make([]byte, len(w.data))
+		if rc := cipher.read(fileJournal, file, got, w.off); rc != sqliteOK {
+			t.Fatalf("read at %d = %d", w.off, rc)
+		}
+		if !bytes.Equal(got, w.data) {
+			t.Errorf("read at %d returned other bytes than were written", w.off)
+		}
+	}
+
+	// A damaged page reads as zeroes, for SQLite's checksum to reject
+	file.data[journalSectorSize+100] ^= 0xFF
+	got := make([]byte, encryptedPageSize)
+	if rc := cipher.read(fileJournal, file, got, journalSectorSize+4); rc != sqliteOK || !isZeroed(got) {
+		t.Errorf("Expected a damaged journal page to read as zeroes, got rc %d", rc)
+	}
+}
+
+func TestConfig_ValidateEncryption(t *testing.T) {
+	config := TestConfig()
+	config.Path = filepath.Join(t.TempDir(), "qwin.db")
+	config.AutoMigrate = false
+	config.EncryptionEnabled = true
+
+	config.EncryptionKeySource = KeySourcePassphrase
+	if err := config.Validate(); err == nil {
+		t.Error("Expected a passphrase key without a passphrase to be invalid")
+	}
+	config.EncryptionPassphrase = "secret"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+
+	config.EncryptionKeySource = "usb-stick"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an unknown key source to be invalid")
+	}
+
+	if config.Clone().EncryptionPassphrase != "secret" || config.Redacted().EncryptionPassphrase != "" {
+		t.Error("Expected Clone to keep the passphrase and Redacted to drop it")
+	}
+}
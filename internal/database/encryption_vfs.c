@@ -0,0 +1,242 @@
+// The encrypted VFS passes every call through to the default VFS, except that reads and
+// writes of database, rollback journal and WAL files go through the page cipher in Go.
+
+#include <stdlib.h>
+#include <string.h>
+
+#include "encryption_vfs.h"
+#include "_cgo_export.h"
+
+#define SQLITE_OK                        0
+#define SQLITE_CANTOPEN                  14
+#define SQLITE_OPEN_MAIN_DB              0x00000100
+#define SQLITE_OPEN_MAIN_JOURNAL         0x00000800
+#define SQLITE_OPEN_WAL                  0x00080000
+#define SQLITE_IOCAP_POWERSAFE_OVERWRITE 0x00001000
+
+sqlite3_vfs *sqlite3_vfs_find(const char *zVfsName);
+int sqlite3_vfs_register(sqlite3_vfs*, int makeDflt);
+int sqlite3_vfs_unregister(sqlite3_vfs*);
+
+#define REAL_VFS(p) (((qwin_vfs*)(p))->real)
+#define REAL_FILE(p) (((qwin_file*)(p))->real)
+
+int qwin_real_read(qwin_file *file, void *buf, int amt, sqlite3_int64 offset) {
+	return file->real->pMethods->xRead(file->real, buf, amt, offset);
+}
+
+int qwin_real_write(qwin_file *file, const void *buf, int amt, sqlite3_int64 offset) {
+	return file->real->pMethods->xWrite(file->real, buf, amt, offset);
+}
+
+static int qwinClose(sqlite3_file *pFile) {
+	return REAL_FILE(pFile)->pMethods->xClose(REAL_FILE(pFile));
+}
+
+static int qwinRead(sqlite3_file *pFile, void *zBuf, int iAmt, sqlite3_int64 iOfst) {
+	return qwinFileRead((qwin_file*)pFile, zBuf, iAmt, iOfst);
+}
+
+static int qwinWrite(sqlite3_file *pFile, const void *zBuf, int iAmt, sqlite3_int64 iOfst) {
+	return qwinFileWrite((qwin_file*)pFile, (void*)zBuf, iAmt, iOfst);
+}
+
+static int qwinTruncate(sqlite3_file *pFile, sqlite3_int64 size) {
+	return REAL_FILE(pFile)->pMethods->xTruncate(REAL_FILE(pFile), size);
+}
+
+static int qwinSync(sqlite3_file *pFile, int flags) {
+	return REAL_FILE(pFile)->pMethods->xSync(REAL_FILE(pFile), flags);
+}
+
+static int qwinFileSize(sqlite3_file *pFile, sqlite3_int64 *pSize) {
+	return REAL_FILE(pFile)->pMethods->xFileSize(REAL_FILE(pFile), pSize);
+}
+
+static int qwinLock(sqlite3_file *pFile, int eLock) {
+	return REAL_FILE(pFile)->pMethods->xLock(REAL_FILE(pFile), eLock);
+}
+
+static int qwinUnlock(sqlite3_file *pFile, int eLock) {
+	return REAL_FILE(pFile)->pMethods->xUnlock(REAL_FILE(pFile), eLock);
+}
+
+static int qwinCheckReservedLock(sqlite3_file *pFile, int *pResOut) {
+	return REAL_FILE(pFile)->pMethods->xCheckReservedLock(REAL_FILE(pFile), pResOut);
+}
+
+static int qwinFileControl(sqlite3_file *pFile, int op, void *pArg) {
+	return REAL_FILE(pFile)->pMethods->xFileControl(REAL_FILE(pFile), op, pArg);
+}
+
+static int qwinSectorSize(sqlite3_file *pFile) {
+	return REAL_FILE(pFile)->pMethods->xSectorSize(REAL_FILE(pFile));
+}
+
+// Powersafe overwrite keeps SQLite from padding the WAL to sector boundaries, which would
+// split writes of page data that the cipher can only encrypt whole
+static int qwinDeviceCharacteristics(sqlite3_file *pFile) {
+	return REAL_FILE(pFile)->pMethods->xDeviceCharacteristics(REAL_FILE(pFile)) | SQLITE_IOCAP_POWERSAFE_OVERWRITE;
+}
+
+static int qwinShmMap(sqlite3_file *pFile, int iPg, int pgsz, int bExtend, void volatile **pp) {
+	return REAL_FILE(pFile)->pMethods->xShmMap(REAL_FILE(pFile), iPg, pgsz, bExtend, pp);
+}
+
+static int qwinShmLock(sqlite3_file *pFile, int offset, int n, int flags) {
+	return REAL_FILE(pFile)->pMethods->xShmLock(REAL_FILE(pFile), offset, n, flags);
+}
+
+static void qwinShmBarrier(sqlite3_file *pFile) {
+	REAL_FILE(pFile)->pMethods->xShmBarrier(REAL_FILE(pFile));
+}
+
+static int qwinShmUnmap(sqlite3_file *pFile, int deleteFlag) {
+	return REAL_FILE(pFile)->pMethods->xShmUnmap(REAL_FILE(pFile), deleteFlag);
+}
+
+// Version 1 methods for files of a default VFS without shared memory, and version 2
+// otherwise. Memory-mapped I/O (version 3) is left out, as it would bypass the cipher.
+static const sqlite3_io_methods qwinMethodsV1 = {
+	1,
+	qwinClose, qwinRead, qwinWrite, qwinTruncate, qwinSync, qwinFileSize,
+	qwinLock, qwinUnlock, qwinCheckReservedLock, qwinFileControl,
+	qwinSectorSize, qwinDeviceCharacteristics,
+	0, 0, 0, 0, 0, 0
+};
+
+static const sqlite3_io_methods qwinMethodsV2 = {
+	2,
+	qwinClose, qwinRead, qwinWrite, qwinTruncate, qwinSync, qwinFileSize,
+	qwinLock, qwinUnlock, qwinCheckReservedLock, qwinFileControl,
+	qwinSectorSize, qwinDeviceCharacteristics,
+	qwinShmMap, qwinShmLock, qwinShmBarrier, qwinShmUnmap,
+	0, 0
+};
+
+// qwinOpen opens database, journal and WAL files. Temporary files would be written in
+// plaintext, so they are refused; connections keep them in memory instead.
+static int qwinOpen(sqlite3_vfs *pVfs, const char *zName, sqlite3_file *pFile, int flags, int *pOutFlags) {
+	qwin_vfs *vfs = (qwin_vfs*)pVfs;
+	qwin_file *file = (qwin_file*)pFile;
+	int kind, rc;
+
+	if (flags & SQLITE_OPEN_MAIN_DB) {
+		kind = QWIN_FILE_DB;
+	} else if (flags & SQLITE_OPEN_MAIN_JOURNAL) {
+		kind = QWIN_FILE_JOURNAL;
+	} else if (flags & SQLITE_OPEN_WAL) {
+		kind = QWIN_FILE_WAL;
+	} else {
+		pFile->pMethods = 0;
+		return SQLITE_CANTOPEN;
+	}
+
+	memset(file, 0, sizeof(*file));
+	file->real = (sqlite3_file*)&file[1];
+	file->cipher = vfs->cipher;
+	file->kind = kind;
+
+	rc = vfs->real->xOpen(vfs->real, zName, file->real, flags, pOutFlags);
+	if (rc != SQLITE_OK) {
+		if (file->real->pMethods) {
+			file->real->pMethods->xClose(file->real);
+		}
+		pFile->pMethods = 0;
+		return rc;
+	}
+	pFile->pMethods = file->real->pMethods->iVersion >= 2 ? &qwinMethodsV2 : &qwinMethodsV1;
+	return SQLITE_OK;
+}
+
+static int qwinDelete(sqlite3_vfs *pVfs, const char *zName, int syncDir) {
+	return REAL_VFS(pVfs)->xDelete(REAL_VFS(pVfs), zName, syncDir);
+}
+
+static int qwinAccess(sqlite3_vfs *pVfs, const char *zName, int flags, int *pResOut) {
+	return REAL_VFS(pVfs)->xAccess(REAL_VFS(pVfs), zName, flags, pResOut);
+}
+
+static int qwinFullPathname(sqlite3_vfs *pVfs, const char *zName, int nOut, char *zOut) {
+	return REAL_VFS(pVfs)->xFullPathname(REAL_VFS(pVfs), zName, nOut, zOut);
+}
+
+static void *qwinDlOpen(sqlite3_vfs *pVfs, const char *zPath) {
+	return REAL_VFS(pVfs)->xDlOpen(REAL_VFS(pVfs), zPath);
+}
+
+static void qwinDlError(sqlite3_vfs *pVfs, int nByte, char *zErrMsg) {
+	REAL_VFS(pVfs)->xDlError(REAL_VFS(pVfs), nByte, zErrMsg);
+}
+
+static void (*qwinDlSym(sqlite3_vfs *pVfs, void *p, const char *zSym))(void) {
+	return REAL_VFS(pVfs)->xDlSym(REAL_VFS(pVfs), p, zSym);
+}
+
+static void qwinDlClose(sqlite3_vfs *pVfs, void *pHandle) {
+	REAL_VFS(pVfs)->xDlClose(REAL_VFS(pVfs), pHandle);
+}
+
+static int qwinRandomness(sqlite3_vfs *pVfs, int nByte, char *zBufOut) {
+	return REAL_VFS(pVfs)->xRandomness(REAL_VFS(pVfs), nByte, zBufOut);
+}
+
+static int qwinSleep(sqlite3_vfs *pVfs, int nMicro) {
+	return REAL_VFS(pVfs)->xSleep(REAL_VFS(pVfs), nMicro);
+}
+
+static int qwinCurrentTime(sqlite3_vfs *pVfs, double *pTimeOut) {
+	return REAL_VFS(pVfs)->xCurrentTime(REAL_VFS(pVfs), pTimeOut);
+}
+
+static int qwinGetLastError(sqlite3_vfs *pVfs, int nBuf, char *zBuf) {
+	return REAL_VFS(pVfs)->xGetLastError(REAL_VFS(pVfs), nBuf, zBuf);
+}
+
+static int qwinCurrentTimeInt64(sqlite3_vfs *pVfs, sqlite3_int64 *pTimeOut) {
+	return REAL_VFS(pVfs)->xCurrentTimeInt64(REAL_VFS(pVfs), pTimeOut);
+}
+
+qwin_vfs *qwin_vfs_register(const char *name, uintptr_t cipher) {
+	sqlite3_vfs *real = sqlite3_vfs_find(0);
+	qwin_vfs *vfs;
+
+	if (real == 0) {
+		return 0;
+	}
+	vfs = (qwin_vfs*)calloc(1, sizeof(qwin_vfs));
+	if (vfs == 0) {
+		return 0;
+	}
+
+	vfs->base.iVersion = 2;
+	vfs->base.szOsFile = (int)sizeof(qwin_file) + real->szOsFile;
+	vfs->base.mxPathname = real->mxPathname;
+	vfs->base.zName = name;
+	vfs->base.xOpen = qwinOpen;
+	vfs->base.xDelete = qwinDelete;
+	vfs->base.xAccess = qwinAccess;
+	vfs->base.xFullPathname = qwinFullPathname;
+	vfs->base.xDlOpen = qwinDlOpen;
+	vfs->base.xDlError = qwinDlError;
+	vfs->base.xDlSym = qwinDlSym;
+	vfs->base.xDlClose = qwinDlClose;
+	vfs->base.xRandomness = qwinRandomness;
+	vfs->base.xSleep = qwinSleep;
+	vfs->base.xCurrentTime = qwinCurrentTime;
+	vfs->base.xGetLastError = qwinGetLastError;
+	vfs->base.xCurrentTimeInt64 = real->iVersion >= 2 ? qwinCurrentTimeInt64 : 0;
+	vfs->real = real;
+	vfs->cipher = cipher;
+
+	if (sqlite3_vfs_register(&vfs->base, 0) != SQLITE_OK) {
+		free(vfs);
+		return 0;
+	}
+	return vfs;
+}
+
+void qwin_vfs_unregister(qwin_vfs *vfs) {
+	sqlite3_vfs_unregister(&vfs->base);
+	free(vfs);
+}
@@ -0,0 +1,90 @@
+package database
+
+// #include <stdlib.h>
+// #include "encryption_vfs.h"
+import "C"
+
+import (
+	"database/sql"
+	"fmt"
+	"runtime/cgo"
+	"sync/atomic"
+	"unsafe"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// encryptedDriverName is the driver encrypted databases are opened with. Its connections
+// keep temporary tables and indexes in memory, as the encrypted VFS refuses temporary files.
+const encryptedDriverName = "sqlite3_encrypted"
+
+func init() {
+	sql.Register(encryptedDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec("PRAGMA temp_store = MEMORY", nil)
+			return err
+		},
+	})
+}
+
+// vfsCounter numbers encrypted VFSes, which SQLite tells apart by name
+var vfsCounter atomic.Uint64
+
+// encryptedVFS is a SQLite VFS that encrypts the files of one database with its cipher.
+// Connections select it with the vfs parameter of their connection string.
+type encryptedVFS struct {
+	name   string
+	cName  *C.char
+	cipher cgo.Handle
+	vfs    *C.qwin_vfs
+}
+
+// registerEncryptedVFS registers a VFS that encrypts files with cipher
+func registerEncryptedVFS(cipher *pageCipher) (*encryptedVFS, error) {
+	v := &encryptedVFS{
+		name:   fmt.Sprintf("qwin-encrypted-%d", vfsCounter.Add(1)),
+		cipher: cgo.NewHandle(cipher),
+	}
+	v.cName = C.CString(v.name)
+	v.vfs = C.qwin_vfs_register(v.cName, C.uintptr_t(v.cipher))
+	if v.vfs == nil {
+		C.free(unsafe.Pointer(v.cName))
+		v.cipher.Delete()
+		return nil, fmt.Errorf("failed to register the encrypted SQLite VFS")
+	}
+	return v, nil
+}
+
+// unregister removes the VFS. Every connection using it must be closed first.
+func (v *encryptedVFS) unregister() {
+	C.qwin_vfs_unregister(v.vfs)
+	C.free(unsafe.Pointer(v.cName))
+	v.cipher.Delete()
+}
+
+// cFile is a file of the default VFS underneath an encrypted one
+type cFile struct {
+	file *C.qwin_file
+}
+
+func (f cFile) readAt(p []byte, off int64) int {
+	return int(C.qwin_real_read(f.file, unsafe.Pointer(&p[0]), C.int(len(p)), C.sqlite3_int64(off)))
+}
+
+func (f cFile) writeAt(p []byte, off int64) int {
+	return int(C.qwin_real_write(f.file, unsafe.Pointer(&p[0]), C.int(len(p)), C.sqlite3_int64(off)))
+}
+
+//export qwinFileRead
+func qwinFileRead(file *C.qwin_file, buf unsafe.Pointer, amt C.int, off C.sqlite3_int64) C.int {
+	cipher := cgo.Handle(file.cipher).Value().(*pageCipher)
+	p := unsafe.Slice((*byte)(buf), int(amt))
+	return C.int(cipher.read(fileKind(file.kind), cFile{file}, p, int64(off)))
+}
+
+//export qwinFileWrite
+func qwinFileWrite(file *C.qwin_file, buf unsafe.Pointer, amt C.int, off C.sqlite3_int64) C.int {
+	cipher := cgo.Handle(file.cipher).Value().(*pageCipher)
+	p := unsafe.Slice((*byte)(buf), int(amt))
+	return C.int(cipher.write(fileKind(file.kind), cFile{file}, p, int64(off)))
+}
@@ -0,0 +1,92 @@
+// Declarations for the encrypted SQLite VFS. The SQLite structures are declared here as
+// they are in sqlite3.h, whose copy bundled with go-sqlite3 isn't on the include path;
+// the functions resolve against the SQLite that go-sqlite3 links in.
+
+#ifndef QWIN_ENCRYPTION_VFS_H
+#define QWIN_ENCRYPTION_VFS_H
+
+#include <stdint.h>
+
+typedef long long sqlite3_int64;
+typedef struct sqlite3_file sqlite3_file;
+typedef struct sqlite3_io_methods sqlite3_io_methods;
+typedef struct sqlite3_vfs sqlite3_vfs;
+typedef void (*sqlite3_syscall_ptr)(void);
+
+struct sqlite3_file {
+	const struct sqlite3_io_methods *pMethods;
+};
+
+struct sqlite3_io_methods {
+	int iVersion;
+	int (*xClose)(sqlite3_file*);
+	int (*xRead)(sqlite3_file*, void*, int iAmt, sqlite3_int64 iOfst);
+	int (*xWrite)(sqlite3_file*, const void*, int iAmt, sqlite3_int64 iOfst);
+	int (*xTruncate)(sqlite3_file*, sqlite3_int64 size);
+	int (*xSync)(sqlite3_file*, int flags);
+	int (*xFileSize)(sqlite3_file*, sqlite3_int64 *pSize);
+	int (*xLock)(sqlite3_file*, int);
+	int (*xUnlock)(sqlite3_file*, int);
+	int (*xCheckReservedLock)(sqlite3_file*, int *pResOut);
+	int (*xFileControl)(sqlite3_file*, int op, void *pArg);
+	int (*xSectorSize)(sqlite3_file*);
+	int (*xDeviceCharacteristics)(sqlite3_file*);
+	int (*xShmMap)(sqlite3_file*, int iPg, int pgsz, int, void volatile**);
+	int (*xShmLock)(sqlite3_file*, int offset, int n, int flags);
+	void (*xShmBarrier)(sqlite3_file*);
+	int (*xShmUnmap)(sqlite3_file*, int deleteFlag);
+	int (*xFetch)(sqlite3_file*, sqlite3_int64 iOfst, int iAmt, void **pp);
+	int (*xUnfetch)(sqlite3_file*, sqlite3_int64 iOfst, void *p);
+};
+
+struct sqlite3_vfs {
+	int iVersion;
+	int szOsFile;
+	int mxPathname;
+	sqlite3_vfs *pNext;
+	const char *zName;
+	void *pAppData;
+	int (*xOpen)(sqlite3_vfs*, const char *zName, sqlite3_file*, int flags, int *pOutFlags);
+	int (*xDelete)(sqlite3_vfs*, const char *zName, int syncDir);
+	int (*xAccess)(sqlite3_vfs*, const char *zName, int flags, int *pResOut);
+	int (*xFullPathname)(sqlite3_vfs*, const char *zName, int nOut, char *zOut);
+	void *(*xDlOpen)(sqlite3_vfs*, const char *zFilename);
+	void (*xDlError)(sqlite3_vfs*, int nByte, char *zErrMsg);
+	void (*(*xDlSym)(sqlite3_vfs*, void*, const char *zSymbol))(void);
+	void (*xDlClose)(sqlite3_vfs*, void*);
+	int (*xRandomness)(sqlite3_vfs*, int nByte, char *zOut);
+	int (*xSleep)(sqlite3_vfs*, int microseconds);
+	int (*xCurrentTime)(sqlite3_vfs*, double*);
+	int (*xGetLastError)(sqlite3_vfs*, int, char *);
+	int (*xCurrentTimeInt64)(sqlite3_vfs*, sqlite3_int64*);
+	int (*xSetSystemCall)(sqlite3_vfs*, const char *zName, sqlite3_syscall_ptr);
+	sqlite3_syscall_ptr (*xGetSystemCall)(sqlite3_vfs*, const char *zName);
+	const char *(*xNextSystemCall)(sqlite3_vfs*, const char *zName);
+};
+
+// Kinds of files the VFS encrypts; they match fileKind in encryption.go
+#define QWIN_FILE_DB      1
+#define QWIN_FILE_JOURNAL 2
+#define QWIN_FILE_WAL     3
+
+// qwin_vfs wraps the default VFS, encrypting files with the cipher behind a cgo.Handle
+typedef struct qwin_vfs {
+	sqlite3_vfs base;
+	sqlite3_vfs *real;
+	uintptr_t cipher;
+} qwin_vfs;
+
+// qwin_file is a file opened through qwin_vfs; the default VFS's file follows it
+typedef struct qwin_file {
+	sqlite3_file base;
+	sqlite3_file *real;
+	uintptr_t cipher;
+	int kind;
+} qwin_file;
+
+qwin_vfs *qwin_vfs_register(const char *name, uintptr_t cipher);
+void qwin_vfs_unregister(qwin_vfs *vfs);
+int qwin_real_read(qwin_file *file, void *buf, int amt, sqlite3_int64 offset);
+int qwin_real_write(qwin_file *file, const void *buf, int amt, sqlite3_int64 offset);
+
+#endif
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"os"
+	"time"
+
+	dberrors "qwin/internal/infrastructure/errors"
+)
+
+// FileStats describes the database's files on disk
+type FileStats struct {
+	Path           string     `json:"path"`
+	SizeBytes      int64      `json:"sizeBytes"`
+	WALSizeBytes   int64      `json:"walSizeBytes"` // Zero when there is no write-ahead log
+	LastBackupPath string     `json:"lastBackupPath,omitempty"`
+	LastBackupAt   *time.Time `json:"lastBackupAt,omitempty"`
+}
+
+// FileStats returns the size of the database file and its write-ahead log, and the newest
+// backup of it. In-memory databases have no files and report zero sizes.
+func (s *SQLiteService) FileStats() (*FileStats, error) {
+	s.stateMu.RLock()
+	config := s.config
+	s.stateMu.RUnlock()
+
+	if config == nil {
+		return nil, dberrors.HandleConnectionError("FileStats", "database not connected")
+	}
+
+	stats := &FileStats{Path: config.Path}
+	if config.Path == ":memory:" {
+		return stats, nil
+	}
+
+	info, err := os.Stat(config.Path)
+	if err != nil {
+		return nil, dberrors.NewRepositoryErrorWithContext("FileStats", err, dberrors.ClassifyError(err), map[string]string{
+			"path": config.Path,
+		})
+	}
+	stats.SizeBytes = info.Size()
+
+	if wal, err := os.Stat(config.Path + "-wal"); err == nil {
+		stats.WALSizeBytes = wal.Size()
+	}
+
+	backups, err := ListMigrationBackups(migrationBackupDir(config))
+	if err != nil {
+		s.logger.Warn("Failed to list database backups", "dir", migrationBackupDir(config), "error", err)
+	}
+	if len(backups) > 0 {
+		newest := backups[len(backups)-1]
+		if info, err := os.Stat(newest); err == nil {
+			modTime := info.ModTime()
+			stats.LastBackupPath = newest
+			stats.LastBackupAt = &modTime
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+)
+
+func TestSQLiteService_FileStats(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+
+	config := DefaultConfig()
+	config.Path = filepath.Join(tempDir, "test.db")
+
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	if _, err := service.FileStats(); err == nil {
+		t.Error("Expected FileStats to fail before connecting")
+	}
+
+	ctx := context.Background()
+	if err := service.Connect(ctx, config); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer service.Close()
+	if err := service.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	stats, err := service.FileStats()
+	if err != nil {
+		t.Fatalf("FileStats failed: %v", err)
+	}
+	if stats.Path != config.Path || stats.SizeBytes == 0 {
+		t.Errorf("FileStats() = %+v, want the database file's size", stats)
+	}
+	if stats.LastBackupAt != nil {
+		t.Errorf("Expected no backup yet, got %s", stats.LastBackupPath)
+	}
+
+	// The newest backup is reported
+	backupDir := filepath.Join(tempDir, "backups")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	older := filepath.Join(backupDir, migrationBackupPrefix+"older.db")
+	newer := filepath.Join(backupDir, migrationBackupPrefix+"newer.db")
+	for i, path := range []string{older, newer} {
+		if err := os.WriteFile(path, []byte("backup"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(time.Duration(i-2) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err = service.FileStats()
+	if err != nil {
+		t.Fatalf("FileStats failed: %v", err)
+	}
+	if stats.LastBackupPath != newer || stats.LastBackupAt == nil {
+		t.Errorf("last backup = %q at %v, want %q", stats.LastBackupPath, stats.LastBackupAt, newer)
+	}
+}
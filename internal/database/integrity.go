@@ -0,0 +1,228 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	dberrors "qwin/internal/infrastructure/errors"
+)
+
+const (
+	// maxDayLength is the most usage a single day can hold, in seconds. Days are 25 hours
+	// long when daylight saving time ends, so that is the limit rather than 24.
+	maxDayLength = 25 * 60 * 60
+	// maxIntegrityIssues bounds how many problems of each kind a check reports
+	maxIntegrityIssues = 20
+)
+
+// IntegrityIssueKind classifies a problem found by an integrity check
+type IntegrityIssueKind string
+
+const (
+	// IntegrityStructure is damage to the database file itself, reported by SQLite
+	IntegrityStructure IntegrityIssueKind = "structure"
+	// IntegrityForeignKey is a row referencing a parent row that doesn't exist
+	IntegrityForeignKey IntegrityIssueKind = "foreign_key"
+	// IntegrityInvariant is stored data that breaks a rule the application relies on
+	IntegrityInvariant IntegrityIssueKind = "invariant"
+)
+
+// IntegrityIssue is a single problem found by an integrity check
+type IntegrityIssue struct {
+	Kind   IntegrityIssueKind `json:"kind"`
+	Table  string             `json:"table,omitempty"`
+	Detail string             `json:"detail"`
+}
+
+// IntegrityReport is the outcome of an integrity check
+type IntegrityReport struct {
+	CheckedAt time.Time        `json:"checkedAt"`
+	Issues    []IntegrityIssue `json:"issues"`
+}
+
+// OK reports whether the check found no problems at all
+func (r *IntegrityReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Corrupt reports whether the database file itself is damaged. Foreign key and invariant
+// problems leave the file readable and are reported without counting as corruption.
+func (r *IntegrityReport) Corrupt() bool {
+	for _, issue := range r.Issues {
+		if issue.Kind == IntegrityStructure {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIntegrity runs SQLite's quick check and foreign key check on db, then checks the
+// usage tables against invariants such as no day holding more than a day's worth of usage.
+// Corruption is reported in the result; the error is only for checks that couldn't run.
+func CheckIntegrity(ctx context.Context, db *sql.DB) (*IntegrityReport, error) {
+	report := &IntegrityReport{CheckedAt: time.Now(), Issues: []IntegrityIssue{}}
+
+	structural, err := quickCheck(ctx, db)
+	if err != nil {
+		if dberrors.ClassifyError(err) == dberrors.ErrCodeCorruption {
+			report.Issues = append(report.Issues, IntegrityIssue{Kind: IntegrityStructure, Detail: err.Error()})
+			return report, nil
+		}
+		return nil, dberrors.WrapDatabaseErrorWithContext("CheckIntegrity", err, map[string]string{
+			"phase": "quick_check",
+		})
+	}
+	report.Issues = append(report.Issues, structural...)
+
+	// Further queries can't be trusted on a damaged file
+	if report.Corrupt() {
+		return report, nil
+	}
+
+	foreignKeys, err := foreignKeyCheck(ctx, db)
+	if err != nil {
+		return nil, dberrors.WrapDatabaseErrorWithContext("CheckIntegrity", err, map[string]string{
+			"phase": "foreign_key_check",
+		})
+	}
+	report.Issues = append(report.Issues, foreignKeys...)
+
+	invariants, err := invariantCheck(ctx, db)
+	if err != nil {
+		return nil, dberrors.WrapDatabaseErrorWithContext("CheckIntegrity", err, map[string]string{
+			"phase": "invariants",
+		})
+	}
+	report.Issues = append(report.Issues, invariants...)
+
+	return report, nil
+}
+
+// quickCheck runs PRAGMA quick_check, which returns a single "ok" row for a sound file
+func quickCheck(ctx context.Context, db *sql.DB) ([]IntegrityIssue, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA quick_check(%d)", maxIntegrityIssues))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []IntegrityIssue
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, err
+		}
+		if result != "ok" {
+			detail := strings.TrimPrefix(result, "*** in database main ***\n")
+			issues = append(issues, IntegrityIssue{Kind: IntegrityStructure, Detail: detail})
+		}
+	}
+	return issues, rows.Err()
+}
+
+// foreignKeyCheck lists rows whose foreign keys point at missing parent rows
+func foreignKeyCheck(ctx context.Context, db *sql.DB) ([]IntegrityIssue, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []IntegrityIssue
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var constraint int64
+		if err := rows.Scan(&table, &rowID, &parent, &constraint); err != nil {
+			return nil, err
+		}
+		if len(issues) < maxIntegrityIssues {
+			issues = append(issues, IntegrityIssue{
+				Kind:   IntegrityForeignKey,
+				Table:  table,
+				Detail: fmt.Sprintf("row %d references a missing %s row", rowID.Int64, parent),
+			})
+		}
+	}
+	return issues, rows.Err()
+}
+
+// invariantCheck looks for days holding more usage than a day can, in the daily totals and
+// in the sum of the day's app usage. Tables not created yet by migrations are skipped.
+func invariantCheck(ctx context.Context, db *sql.DB) ([]IntegrityIssue, error) {
+	checks := []struct {
+		table  string
+		query  string
+		detail string
+	}{
+		{
+			table:  "daily_usage",
+			query:  "SELECT date, total_time FROM daily_usage WHERE total_time > ? ORDER BY date LIMIT ?",
+			detail: "total time on %s is %ds, longer than a day",
+		},
+		{
+			table:  "app_usage",
+			query:  "SELECT date, SUM(duration) FROM app_usage GROUP BY date HAVING SUM(duration) > ? ORDER BY date LIMIT ?",
+			detail: "app usage on %s adds up to %ds, longer than a day",
+		},
+	}
+
+	var issues []IntegrityIssue
+	for _, check := range checks {
+		exists, err := tableExists(ctx, db, check.table)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		found, err := func() ([]IntegrityIssue, error) {
+			rows, err := db.QueryContext(ctx, check.query, maxDayLength, maxIntegrityIssues)
+			if err != nil {
+				return nil, err
+			}
+			defer rows.Close()
+
+			var found []IntegrityIssue
+			for rows.Next() {
+				var date string
+				var seconds int64
+				if err := rows.Scan(&date, &seconds); err != nil {
+					return nil, err
+				}
+				found = append(found, IntegrityIssue{
+					Kind:   IntegrityInvariant,
+					Table:  check.table,
+					Detail: fmt.Sprintf(check.detail, formatIntegrityDate(date), seconds),
+				})
+			}
+			return found, rows.Err()
+		}()
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+	return issues, nil
+}
+
+// tableExists reports whether db has a table with the given name
+func tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	return count > 0, err
+}
+
+// formatIntegrityDate shortens a stored date key to its calendar date when it parses as one
+func formatIntegrityDate(date string) string {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return date
+}
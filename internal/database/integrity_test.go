@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"qwin/internal/infrastructure/logging"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	ctx := context.Background()
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	if err := service.Connect(ctx, TestConfig()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer service.Close()
+
+	// Before migrations there are no usage tables to check
+	report, err := service.CheckIntegrity(ctx)
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if !report.OK() || report.Corrupt() {
+		t.Fatalf("Expected an empty database to pass, got %+v", report.Issues)
+	}
+
+	if err := service.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	db := service.DB()
+	statements := []string{
+		`INSERT INTO daily_usage (date, total_time) VALUES ('2024-06-01 00:00:00+00:00', 3600)`,
+		`INSERT INTO daily_usage (date, total_time) VALUES ('2024-06-02 00:00:00+00:00', 100000)`,
+		`INSERT INTO app_usage (name, duration, date, application_id) VALUES ('Code', 50000, '2024-06-03 00:00:00+00:00', 1)`,
+		`INSERT INTO app_usage (name, duration, date, application_id) VALUES ('Discord', 50000, '2024-06-03 00:00:00+00:00', 2)`,
+		`INSERT INTO app_usage (name, duration, date, application_id) VALUES ('Code', 80000, '2024-06-04 00:00:00+00:00', 1)`,
+		// Orphan a row without tripping the foreign key constraint on insert
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO application_paths (exe_path, application_id) VALUES ('C:\Tools\gone.exe', 999)`,
+		`PRAGMA foreign_keys = ON`,
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+
+	report, err = service.CheckIntegrity(ctx)
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if report.Corrupt() {
+		t.Errorf("Expected data problems not to count as corruption, got %+v", report.Issues)
+	}
+
+	var details []string
+	kinds := make(map[IntegrityIssueKind]int)
+	for _, issue := range report.Issues {
+		kinds[issue.Kind]++
+		details = append(details, issue.Detail)
+	}
+	if kinds[IntegrityInvariant] != 2 || kinds[IntegrityForeignKey] != 1 || len(report.Issues) != 3 {
+		t.Fatalf("Expected two invariant issues and one foreign key issue, got %+v", report.Issues)
+	}
+
+	joined := strings.Join(details, "\n")
+	for _, want := range []string{"total time on 2024-06-02 is 100000s", "app usage on 2024-06-03 adds up to 100000s", "references a missing applications row"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected an issue mentioning %q, got:\n%s", want, joined)
+		}
+	}
+}
+
+func TestSQLiteService_CheckIntegrity_NotConnected(t *testing.T) {
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	if _, err := service.CheckIntegrity(context.Background()); err == nil {
+		t.Fatal("Expected an error checking a database that isn't connected")
+	}
+}
@@ -13,6 +13,8 @@ type Service interface {
 	Connect(ctx context.Context, config *Config) error
 	Close() error
 	Health(ctx context.Context) error
+	CheckIntegrity(ctx context.Context) (*IntegrityReport, error)
+	Config() *Config
 
 	// Database access
 	DB() *sql.DB
@@ -22,10 +24,15 @@ type Service interface {
 	// Migration management
 	Migrate(ctx context.Context) error
 	GetMigrationVersion(ctx context.Context) (int64, error)
+	MigrateTo(ctx context.Context, version int64) error
+	Rollback(ctx context.Context, steps int) error
+	MigrationStatus(ctx context.Context) (*MigrationStatus, error)
 
 	// Maintenance operations
 	Optimize(ctx context.Context) error
 	GetStats() sql.DBStats
+	FileStats() (*FileStats, error)
+	TableRowCounts(ctx context.Context) (map[string]int64, error)
 }
 
 // MigrationManager defines the interface for database migration operations
@@ -34,6 +41,13 @@ type MigrationManager interface {
 	// Migration execution
 	RunMigrations(ctx context.Context) error
 	GetCurrentVersion(ctx context.Context) (int64, error)
+	// MigrateTo migrates up or down until the schema is at the given version.
+	// Version 0 rolls back every migration.
+	MigrateTo(ctx context.Context, version int64) error
+	// Rollback reverts the most recently applied steps migrations.
+	Rollback(ctx context.Context, steps int) error
+	// Status reports which embedded migrations are applied and which are pending.
+	Status(ctx context.Context) (*MigrationStatus, error)
 
 	// Migration validation
 	ValidateMigrations() error
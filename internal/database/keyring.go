@@ -0,0 +1,63 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+)
+
+// keyringService names the application in keyring entries
+const keyringService = "qwin"
+
+// ErrSecretNotFound is returned by a Keyring that holds no secret for an account
+var ErrSecretNotFound = errors.New("secret not found in keyring")
+
+// Keyring stores secrets with the operating system, so they are protected by the user's login
+type Keyring interface {
+	// Get returns the secret stored for account, or ErrSecretNotFound
+	Get(service, account string) ([]byte, error)
+	// Set stores secret for account, replacing any previous one
+	Set(service, account string, secret []byte) error
+}
+
+// OSKeyring returns the keyring of the operating system: DPAPI-protected files on Windows,
+// the login keychain on macOS and the Secret Service (through secret-tool) on Linux
+func OSKeyring() Keyring {
+	return osKeyring{}
+}
+
+// keyringAccount identifies the key of the database at path, so databases don't share keys
+func keyringAccount(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(path)))
+	return "database-" + hex.EncodeToString(sum[:8])
+}
+
+// loadKeyringKey returns the key of the database at path from keyring. When create is set
+// and there is none yet, a random key is generated and stored.
+func loadKeyringKey(keyring Keyring, path string, create bool) (*databaseKey, error) {
+	account := keyringAccount(path)
+	secret, err := keyring.Get(keyringService, account)
+	if err == nil {
+		if len(secret) != encryptionKeySize {
+			return nil, errors.New("keyring holds a malformed database key")
+		}
+		return newKeyringKey(secret), nil
+	}
+	if !errors.Is(err, ErrSecretNotFound) || !create {
+		return nil, err
+	}
+
+	secret = make([]byte, encryptionKeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := keyring.Set(keyringService, account, secret); err != nil {
+		return nil, err
+	}
+	return newKeyringKey(secret), nil
+}
@@ -0,0 +1,48 @@
+//go:build darwin
+
+package database
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// osKeyring keeps secrets in the login keychain through the security tool
+type osKeyring struct{}
+
+// errItemNotFound is the exit status of security when the keychain has no matching item
+const errItemNotFound = 44
+
+func (osKeyring) Get(service, account string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == errItemNotFound {
+			return nil, ErrSecretNotFound
+		}
+		return nil, fmt.Errorf("failed to read keychain: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return hex.DecodeString(strings.TrimSpace(stdout.String()))
+}
+
+func (osKeyring) Set(service, account string, secret []byte) error {
+	var stderr bytes.Buffer
+	// With -w last and no value, security prompts for the secret, and again to confirm it,
+	// reading both from standard input so it doesn't show up in the process list
+	cmd := exec.Command("security", "add-generic-password", "-U", "-s", service, "-a", account,
+		"-l", service+" database key", "-w")
+	encoded := hex.EncodeToString(secret)
+	cmd.Stdin = strings.NewReader(encoded + "\n" + encoded + "\n")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to write keychain: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package database
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// osKeyring keeps secrets in the Secret Service (GNOME Keyring, KWallet) through secret-tool
+type osKeyring struct{}
+
+func (osKeyring) Get(service, account string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		// lookup exits with 1 and prints nothing when there is no matching secret
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return nil, ErrSecretNotFound
+		}
+		return nil, fmt.Errorf("failed to read keyring: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return hex.DecodeString(strings.TrimSpace(stdout.String()))
+}
+
+func (osKeyring) Set(service, account string, secret []byte) error {
+	var stderr bytes.Buffer
+	// The secret is read from standard input so it doesn't show up in the process list
+	cmd := exec.Command("secret-tool", "store", "--label="+service+" database key", "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(hex.EncodeToString(secret))
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to write keyring: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
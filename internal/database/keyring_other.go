@@ -0,0 +1,18 @@
+//go:build !windows && !darwin && !linux
+
+package database
+
+import "errors"
+
+// osKeyring is unavailable on this platform; use a passphrase key instead
+type osKeyring struct{}
+
+var errNoKeyring = errors.New("no keyring is available on this platform")
+
+func (osKeyring) Get(service, account string) ([]byte, error) {
+	return nil, errNoKeyring
+}
+
+func (osKeyring) Set(service, account string, secret []byte) error {
+	return errNoKeyring
+}
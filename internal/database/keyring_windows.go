@@ -0,0 +1,72 @@
+//go:build windows
+
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// osKeyring protects secrets with DPAPI, which ties them to the user's Windows login, and
+// keeps the protected blobs in the user's roaming app data
+type osKeyring struct{}
+
+// keyFile returns where the secret for account is kept
+func (osKeyring) keyFile(service, account string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, service, "keys", account+".key"), nil
+}
+
+func (k osKeyring) Get(service, account string) ([]byte, error) {
+	path, err := k.keyFile(service, account)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSecretNotFound
+		}
+		return nil, err
+	}
+	if len(protected) == 0 {
+		return nil, errors.New("keyring file is empty")
+	}
+
+	in := windows.DataBlob{Size: uint32(len(protected)), Data: &protected[0]}
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
+
+func (k osKeyring) Set(service, account string, secret []byte) error {
+	path, err := k.keyFile(service, account)
+	if err != nil {
+		return err
+	}
+	if len(secret) == 0 {
+		return errors.New("secret is empty")
+	}
+
+	in := windows.DataBlob{Size: uint32(len(secret)), Data: &secret[0]}
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileSynced(path, unsafe.Slice(out.Data, out.Size))
+}
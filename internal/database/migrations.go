@@ -4,10 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
 	"qwin/internal/infrastructure/logging"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pressly/goose/v3"
 )
@@ -27,11 +31,37 @@ var (
 	gooseConfigErr  error
 )
 
+// migrationsDir is the directory of the embedded migration files
+const migrationsDir = "migrations"
+
 // MigrationRunner handles database migration operations
 // It implements the MigrationManager interface
 type MigrationRunner struct {
 	db     *sql.DB
 	logger logging.Logger
+
+	// Backups taken before destructive migrations; disabled while backupDir is empty
+	backupDir       string
+	backupRetention int
+
+	// mutex serializes migrations run through this runner
+	mutex sync.Mutex
+}
+
+// MigrationInfo describes one embedded migration and whether it is applied
+type MigrationInfo struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"` // nil while pending
+}
+
+// MigrationStatus lists the applied and pending migrations of a database
+type MigrationStatus struct {
+	CurrentVersion int64           `json:"currentVersion"`
+	LatestVersion  int64           `json:"latestVersion"`
+	Applied        []MigrationInfo `json:"applied"` // oldest first
+	Pending        []MigrationInfo `json:"pending"` // in the order they would run
 }
 
 // Ensure MigrationRunner implements MigrationManager interface
@@ -67,18 +97,16 @@ func configureGoose() error {
 
 // RunMigrations executes all pending migrations using embedded files
 func (mr *MigrationRunner) RunMigrations(ctx context.Context) error {
-	if mr.db == nil {
-		return fmt.Errorf("database connection is nil")
+	if err := mr.checkReady(); err != nil {
+		return err
 	}
 
-	// Check if goose configuration failed during initialization
-	if gooseConfigErr != nil {
-		return fmt.Errorf("goose configuration failed: %w", gooseConfigErr)
-	}
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
 
 	mr.logger.Info("Running database migrations from embedded filesystem")
 
-	if err := goose.UpContext(ctx, mr.db, "migrations"); err != nil {
+	if err := mr.migrateTo(ctx, goose.MaxVersion); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
@@ -90,6 +118,166 @@ func (mr *MigrationRunner) RunMigrations(ctx context.Context) error {
 	return nil
 }
 
+// MigrateTo migrates up or down to the given version, backing up first if needed
+func (mr *MigrationRunner) MigrateTo(ctx context.Context, version int64) error {
+	if err := mr.checkReady(); err != nil {
+		return err
+	}
+	if version < 0 {
+		return fmt.Errorf("invalid target version %d", version)
+	}
+
+	migrations, err := goose.CollectMigrations(migrationsDir, 0, goose.MaxVersion)
+	if err != nil {
+		return fmt.Errorf("failed to collect migrations: %w", err)
+	}
+	if version != 0 {
+		if _, err := migrations.Current(version); err != nil {
+			return fmt.Errorf("no migration with version %d", version)
+		}
+	}
+
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+
+	if err := mr.migrateTo(ctx, version); err != nil {
+		return fmt.Errorf("failed to migrate to version %d: %w", version, err)
+	}
+
+	mr.logger.Info("Database migrated to version", "version", version)
+	return nil
+}
+
+// Rollback reverts the last steps applied migrations, backing up first
+func (mr *MigrationRunner) Rollback(ctx context.Context, steps int) error {
+	if err := mr.checkReady(); err != nil {
+		return err
+	}
+	if steps <= 0 {
+		return fmt.Errorf("rollback steps must be positive, got %d", steps)
+	}
+
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+
+	status, err := mr.status(ctx)
+	if err != nil {
+		return err
+	}
+	if steps > len(status.Applied) {
+		return fmt.Errorf("cannot roll back %d migrations, only %d are applied", steps, len(status.Applied))
+	}
+
+	// Roll back to the newest migration that stays applied, or to an empty schema
+	var target int64
+	if remaining := len(status.Applied) - steps; remaining > 0 {
+		target = status.Applied[remaining-1].Version
+	}
+
+	if err := mr.migrateTo(ctx, target); err != nil {
+		return fmt.Errorf("failed to roll back %d migrations: %w", steps, err)
+	}
+
+	mr.logger.Info("Rolled back database migrations", "steps", steps, "version", target)
+	return nil
+}
+
+// Status reports the applied and pending embedded migrations with when each was applied
+func (mr *MigrationRunner) Status(ctx context.Context) (*MigrationStatus, error) {
+	if err := mr.checkReady(); err != nil {
+		return nil, err
+	}
+
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	return mr.status(ctx)
+}
+
+// status builds the migration status; callers hold mr.mutex
+func (mr *MigrationRunner) status(ctx context.Context) (*MigrationStatus, error) {
+	migrations, err := goose.CollectMigrations(migrationsDir, 0, goose.MaxVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+
+	// GetDBVersionContext creates the version table on a pristine database
+	current, err := goose.GetDBVersionContext(ctx, mr.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	status := &MigrationStatus{
+		CurrentVersion: current,
+		Applied:        []MigrationInfo{},
+		Pending:        []MigrationInfo{},
+	}
+	for _, migration := range migrations {
+		info := MigrationInfo{
+			Version: migration.Version,
+			Name:    filepath.Base(migration.Source),
+		}
+
+		// The newest row for a version tells whether it is currently applied
+		var appliedAt time.Time
+		err := mr.db.QueryRowContext(ctx,
+			`SELECT is_applied, tstamp FROM `+goose.TableName()+` WHERE version_id = ? ORDER BY id DESC LIMIT 1`,
+			migration.Version).Scan(&info.Applied, &appliedAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to read status of migration %d: %w", migration.Version, err)
+		}
+
+		if info.Applied {
+			info.AppliedAt = &appliedAt
+			status.Applied = append(status.Applied, info)
+		} else {
+			status.Pending = append(status.Pending, info)
+		}
+		status.LatestVersion = migration.Version
+	}
+	return status, nil
+}
+
+// migrateTo moves the schema to version, taking a backup first when the migrations
+// it runs could lose data; callers hold mr.mutex
+func (mr *MigrationRunner) migrateTo(ctx context.Context, version int64) error {
+	current, err := goose.GetDBVersionContext(ctx, mr.db)
+	if err != nil {
+		return fmt.Errorf("failed to get version: %w", err)
+	}
+
+	if version < current {
+		if err := mr.backupBeforeMigration(ctx, current, version); err != nil {
+			return err
+		}
+		return goose.DownToContext(ctx, mr.db, migrationsDir, version)
+	}
+
+	destructive, err := hasDestructiveMigration(current, version)
+	if err != nil {
+		return err
+	}
+	// A database without any migrations applied has no data to protect
+	if destructive && current > 0 {
+		if err := mr.backupBeforeMigration(ctx, current, version); err != nil {
+			return err
+		}
+	}
+	return goose.UpToContext(ctx, mr.db, migrationsDir, version)
+}
+
+// checkReady verifies the runner has a database and goose was configured
+func (mr *MigrationRunner) checkReady() error {
+	if mr.db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	// Check if goose configuration failed during initialization
+	if gooseConfigErr != nil {
+		return fmt.Errorf("goose configuration failed: %w", gooseConfigErr)
+	}
+	return nil
+}
+
 // GetCurrentVersion returns the current migration version
 func (mr *MigrationRunner) GetCurrentVersion(ctx context.Context) (int64, error) {
 	if mr.db == nil {
@@ -109,14 +297,15 @@ func (mr *MigrationRunner) GetCurrentVersion(ctx context.Context) (int64, error)
 	return version, nil
 }
 
-// ValidateMigrations checks if embedded migration files are valid
+// ValidateMigrations checks that the embedded migrations can be collected and that
+// every one of them can be rolled back
 func (mr *MigrationRunner) ValidateMigrations() error {
 	// Check if goose configuration failed during initialization
 	if gooseConfigErr != nil {
 		return fmt.Errorf("goose configuration failed: %w", gooseConfigErr)
 	}
 
-	migrations, err := goose.CollectMigrations("migrations", 0, goose.MaxVersion)
+	migrations, err := goose.CollectMigrations(migrationsDir, 0, goose.MaxVersion)
 	if err != nil {
 		return fmt.Errorf("failed to collect migrations: %w", err)
 	}
@@ -125,6 +314,26 @@ func (mr *MigrationRunner) ValidateMigrations() error {
 		return fmt.Errorf("no migrations found in embedded filesystem")
 	}
 
+	for _, migration := range migrations {
+		name := filepath.Base(migration.Source)
+		if filepath.Ext(migration.Source) == ".go" {
+			if migration.DownFnContext == nil && migration.DownFnNoTxContext == nil {
+				return fmt.Errorf("migration %s has no down function", name)
+			}
+			continue
+		}
+
+		content, err := embedMigrations.ReadFile(migration.Source)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		for _, annotation := range []string{"-- +goose Up", "-- +goose Down"} {
+			if !strings.Contains(string(content), annotation) {
+				return fmt.Errorf("migration %s is missing its %q section", name, annotation)
+			}
+		}
+	}
+
 	mr.logger.Info("Found valid migrations in embedded filesystem", "count", len(migrations))
 	return nil
 }
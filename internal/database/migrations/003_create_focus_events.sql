@@ -0,0 +1,20 @@
+-- +goose Up
+-- Create focus_events table as an append-only log of tracker state transitions
+CREATE TABLE focus_events (
+    id INTEGER PRIMARY KEY, -- Uses rowid-backed primary key for better performance
+    event_type TEXT NOT NULL,
+    app_name TEXT,
+    exe_path TEXT,
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Replay and reconciliation always scan events in chronological order
+CREATE INDEX idx_focus_events_occurred_at ON focus_events(occurred_at);
+CREATE INDEX idx_focus_events_type_occurred_at ON focus_events(event_type, occurred_at);
+
+-- +goose Down
+-- Drop the focus_events table and its indexes
+DROP INDEX IF EXISTS idx_focus_events_type_occurred_at;
+DROP INDEX IF EXISTS idx_focus_events_occurred_at;
+DROP TABLE IF EXISTS focus_events;
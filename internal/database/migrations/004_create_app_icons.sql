@@ -0,0 +1,20 @@
+-- +goose Up
+-- Create app_icons table storing each distinct icon once, addressed by the SHA-256 of its bytes
+CREATE TABLE app_icons (
+    hash TEXT PRIMARY KEY,
+    mime TEXT NOT NULL,
+    data BLOB NOT NULL,
+    size INTEGER NOT NULL CHECK (size >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Reference icons by hash instead of embedding them in every usage row
+ALTER TABLE app_usage ADD COLUMN icon_hash TEXT;
+
+CREATE INDEX idx_app_usage_icon_hash ON app_usage(icon_hash);
+
+-- +goose Down
+-- Drop the icon reference and the app_icons table
+DROP INDEX IF EXISTS idx_app_usage_icon_hash;
+ALTER TABLE app_usage DROP COLUMN icon_hash;
+DROP TABLE IF EXISTS app_icons;
@@ -0,0 +1,50 @@
+-- +goose Up
+-- Create applications table giving each tracked program a stable identity
+CREATE TABLE applications (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL, -- executable base name as reported by the tracker
+    display_name TEXT NOT NULL,
+    alias TEXT, -- user-provided name, shown instead of display_name when set
+    publisher TEXT,
+    version TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_applications_name ON applications(name);
+
+-- Each executable path belongs to exactly one application
+CREATE TABLE application_paths (
+    exe_path TEXT PRIMARY KEY COLLATE NOCASE,
+    application_id INTEGER NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_application_paths_application ON application_paths(application_id);
+
+-- Reference the application from usage rows
+ALTER TABLE app_usage ADD COLUMN application_id INTEGER;
+
+CREATE INDEX idx_app_usage_application ON app_usage(application_id);
+
+-- Backfill one application per tracked name so existing history stays grouped as before
+INSERT INTO applications (name, display_name)
+SELECT DISTINCT name, name FROM app_usage;
+
+INSERT OR IGNORE INTO application_paths (exe_path, application_id)
+SELECT app_usage.exe_path, applications.id
+FROM app_usage
+JOIN applications ON applications.name = app_usage.name
+WHERE app_usage.exe_path IS NOT NULL AND app_usage.exe_path <> '';
+
+UPDATE app_usage
+SET application_id = (SELECT id FROM applications WHERE applications.name = app_usage.name);
+
+-- +goose Down
+-- Drop the application reference and the identity tables
+DROP INDEX IF EXISTS idx_app_usage_application;
+ALTER TABLE app_usage DROP COLUMN application_id;
+DROP INDEX IF EXISTS idx_application_paths_application;
+DROP TABLE IF EXISTS application_paths;
+DROP INDEX IF EXISTS idx_applications_name;
+DROP TABLE IF EXISTS applications;
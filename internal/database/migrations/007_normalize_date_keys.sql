@@ -0,0 +1,44 @@
+-- +goose Up
+-- Store usage dates as UTC midnight keys of the local calendar date they were recorded for.
+-- Dates were previously written with the local UTC offset, so the same day recorded in
+-- two timezones produced different values that neither matched nor sorted together.
+
+-- Fold rows that land on the same key into the oldest row
+UPDATE daily_usage
+SET total_time = (
+    SELECT SUM(d.total_time) FROM daily_usage d
+    WHERE substr(d.date, 1, 10) = substr(daily_usage.date, 1, 10)
+)
+WHERE id = (
+    SELECT MIN(d.id) FROM daily_usage d
+    WHERE substr(d.date, 1, 10) = substr(daily_usage.date, 1, 10)
+);
+
+DELETE FROM daily_usage
+WHERE id <> (
+    SELECT MIN(d.id) FROM daily_usage d
+    WHERE substr(d.date, 1, 10) = substr(daily_usage.date, 1, 10)
+);
+
+UPDATE app_usage
+SET duration = (
+    SELECT SUM(a.duration) FROM app_usage a
+    WHERE a.name = app_usage.name AND substr(a.date, 1, 10) = substr(app_usage.date, 1, 10)
+)
+WHERE id = (
+    SELECT MIN(a.id) FROM app_usage a
+    WHERE a.name = app_usage.name AND substr(a.date, 1, 10) = substr(app_usage.date, 1, 10)
+);
+
+DELETE FROM app_usage
+WHERE id <> (
+    SELECT MIN(a.id) FROM app_usage a
+    WHERE a.name = app_usage.name AND substr(a.date, 1, 10) = substr(app_usage.date, 1, 10)
+);
+
+UPDATE daily_usage SET date = substr(date, 1, 10) || ' 00:00:00+00:00';
+UPDATE app_usage SET date = substr(date, 1, 10) || ' 00:00:00+00:00';
+
+-- +goose Down
+-- UTC date keys remain valid dates for the previous schema; the original offsets are not recoverable
+SELECT 1;
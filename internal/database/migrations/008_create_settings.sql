@@ -0,0 +1,11 @@
+-- +goose Up
+-- Create settings table for user preferences stored as key/value pairs
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+-- Drop the settings table
+DROP TABLE IF EXISTS settings;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Create ignore_rules table for applications that should not show up in usage stats
+CREATE TABLE ignore_rules (
+    id INTEGER PRIMARY KEY,
+    match_type TEXT NOT NULL CHECK (match_type IN ('name', 'exe_path', 'title')),
+    pattern TEXT NOT NULL, -- exact name, exe path glob or window title regex depending on match_type
+    mode TEXT NOT NULL CHECK (mode IN ('ignore', 'hide')),
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Seed the obvious offenders: the Windows lock screen and qwin's own window
+INSERT INTO ignore_rules (match_type, pattern, mode) VALUES ('name', 'LockApp', 'ignore');
+INSERT INTO ignore_rules (match_type, pattern, mode) VALUES ('name', 'qwin', 'hide');
+
+-- +goose Down
+-- Drop the ignore_rules table
+DROP TABLE IF EXISTS ignore_rules;
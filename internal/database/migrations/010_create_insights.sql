@@ -0,0 +1,18 @@
+-- +goose Up
+-- Create insights table for days and apps whose usage stood out from the user's baseline
+CREATE TABLE insights (
+    id INTEGER PRIMARY KEY,
+    date DATE NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('daily_total', 'app_usage')),
+    app_name TEXT NOT NULL DEFAULT '', -- empty for daily_total insights
+    value INTEGER NOT NULL, -- seconds of usage on the day
+    baseline REAL NOT NULL, -- typical seconds of usage over the trailing window
+    score REAL NOT NULL, -- robust z-score of value against the baseline
+    explanation TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(date, kind, app_name) -- also serves date range lookups
+);
+
+-- +goose Down
+-- Drop the insights table
+DROP TABLE IF EXISTS insights;
@@ -0,0 +1,190 @@
+-- +goose Up
+-- Create tables for usage synced between devices through per-device change logs
+CREATE TABLE sync_devices (
+    device_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    is_local BOOLEAN NOT NULL DEFAULT 0, -- the device this database belongs to
+    last_seq INTEGER NOT NULL DEFAULT 0, -- last change log entry applied from the device
+    last_synced_at TIMESTAMP
+);
+
+-- Per-app usage of each device as merged from its change log. The local device's rows
+-- record what it has written to its own log, not its live usage, which stays in app_usage.
+CREATE TABLE device_app_usage (
+    device_id TEXT NOT NULL REFERENCES sync_devices(device_id) ON DELETE CASCADE,
+    app_name TEXT NOT NULL,
+    date DATE NOT NULL,
+    duration INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (device_id, app_name, date)
+);
+
+CREATE INDEX idx_device_app_usage_date ON device_app_usage(date);
+
+-- Daily totals of each device, kept the same way
+CREATE TABLE device_daily_usage (
+    device_id TEXT NOT NULL REFERENCES sync_devices(device_id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    total_time INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (device_id, date)
+);
+
+CREATE INDEX idx_device_daily_usage_date ON device_daily_usage(date);
+
+-- Dates whose local usage may differ from what the local device has written to its change
+-- log, so exporting compares those dates instead of all usage ever tracked. Triggers mark
+-- a date on every change to either side; version orders the marks, so a date is only
+-- forgotten if it wasn't marked again after it was compared.
+CREATE TABLE sync_dirty_dates (
+    date DATE PRIMARY KEY,
+    version INTEGER NOT NULL
+);
+
+-- Every date is compared once, after which only changed dates are
+INSERT INTO sync_dirty_dates (date, version)
+SELECT date, 1 FROM (
+    SELECT date FROM app_usage
+    UNION SELECT date FROM daily_usage
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_insert AFTER INSERT ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_update AFTER UPDATE OF name, duration, date ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_delete AFTER DELETE ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER daily_usage_sync_dirty_insert AFTER INSERT ON daily_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER daily_usage_sync_dirty_update AFTER UPDATE OF total_time, date ON daily_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER daily_usage_sync_dirty_delete AFTER DELETE ON daily_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- What the local device has written changes when its own log is merged after a restore,
+-- and when sync is reset; other devices' usage doesn't affect what is exported
+-- +goose StatementBegin
+CREATE TRIGGER device_app_usage_sync_dirty_insert AFTER INSERT ON device_app_usage
+WHEN NEW.device_id IN (SELECT device_id FROM sync_devices WHERE is_local)
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER device_app_usage_sync_dirty_update AFTER UPDATE ON device_app_usage
+WHEN NEW.device_id IN (SELECT device_id FROM sync_devices WHERE is_local)
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER device_app_usage_sync_dirty_delete AFTER DELETE ON device_app_usage
+WHEN OLD.device_id IN (SELECT device_id FROM sync_devices WHERE is_local)
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER device_daily_usage_sync_dirty_insert AFTER INSERT ON device_daily_usage
+WHEN NEW.device_id IN (SELECT device_id FROM sync_devices WHERE is_local)
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER device_daily_usage_sync_dirty_update AFTER UPDATE ON device_daily_usage
+WHEN NEW.device_id IN (SELECT device_id FROM sync_devices WHERE is_local)
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER device_daily_usage_sync_dirty_delete AFTER DELETE ON device_daily_usage
+WHEN OLD.device_id IN (SELECT device_id FROM sync_devices WHERE is_local)
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- Drop the device sync tables and dirty date tracking
+DROP TRIGGER IF EXISTS device_daily_usage_sync_dirty_delete;
+DROP TRIGGER IF EXISTS device_daily_usage_sync_dirty_update;
+DROP TRIGGER IF EXISTS device_daily_usage_sync_dirty_insert;
+DROP TRIGGER IF EXISTS device_app_usage_sync_dirty_delete;
+DROP TRIGGER IF EXISTS device_app_usage_sync_dirty_update;
+DROP TRIGGER IF EXISTS device_app_usage_sync_dirty_insert;
+DROP TRIGGER IF EXISTS daily_usage_sync_dirty_delete;
+DROP TRIGGER IF EXISTS daily_usage_sync_dirty_update;
+DROP TRIGGER IF EXISTS daily_usage_sync_dirty_insert;
+DROP TRIGGER IF EXISTS app_usage_sync_dirty_delete;
+DROP TRIGGER IF EXISTS app_usage_sync_dirty_update;
+DROP TRIGGER IF EXISTS app_usage_sync_dirty_insert;
+DROP TABLE IF EXISTS sync_dirty_dates;
+DROP INDEX IF EXISTS idx_device_daily_usage_date;
+DROP TABLE IF EXISTS device_daily_usage;
+DROP INDEX IF EXISTS idx_device_app_usage_date;
+DROP TABLE IF EXISTS device_app_usage;
+DROP TABLE IF EXISTS sync_devices;
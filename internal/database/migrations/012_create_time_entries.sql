@@ -0,0 +1,41 @@
+-- +goose Up
+-- Create time_entries for usage added by hand or imported, kept apart from tracked usage
+-- in app_usage and daily_usage so corrections never overwrite what was tracked. Correction
+-- entries change an app's usage on a day by a signed duration, so over-counted tracked
+-- time can be taken away. Ids are never reused: audit rows outlive their entry and are
+-- keyed by its id, so a reused id would hand a deleted entry's history to a new one.
+CREATE TABLE time_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date DATE NOT NULL,
+    app_name TEXT NOT NULL,
+    duration INTEGER NOT NULL CHECK (duration <> 0 AND (duration > 0 OR source = 'correction')), -- seconds
+    source TEXT NOT NULL CHECK (source IN ('manual', 'imported', 'correction')),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_time_entries_date ON time_entries(date);
+
+-- Every change to a time entry, with the entry as it was after the change, or as it was
+-- when deleted. Rows outlive their entry so deletions stay traceable.
+CREATE TABLE time_entry_audit (
+    id INTEGER PRIMARY KEY,
+    entry_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    date DATE NOT NULL,
+    app_name TEXT NOT NULL,
+    duration INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_time_entry_audit_entry ON time_entry_audit(entry_id);
+
+-- +goose Down
+-- Drop the time entry tables
+DROP INDEX IF EXISTS idx_time_entry_audit_entry;
+DROP TABLE IF EXISTS time_entry_audit;
+DROP INDEX IF EXISTS idx_time_entries_date;
+DROP TABLE IF EXISTS time_entries;
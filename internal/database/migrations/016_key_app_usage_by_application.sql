@@ -0,0 +1,157 @@
+-- +goose Up
+-- Key app usage by application instead of by name, so programs that share an executable
+-- name, e.g. after one was split off another, each keep their own usage for a day
+
+-- Rows written before they had an application get the oldest one with their name
+INSERT INTO applications (name, display_name)
+SELECT DISTINCT name, name FROM app_usage
+WHERE application_id IS NULL
+  AND name NOT IN (SELECT name FROM applications);
+
+UPDATE app_usage
+SET application_id = (SELECT MIN(id) FROM applications WHERE applications.name = app_usage.name)
+WHERE application_id IS NULL;
+
+-- Days on which one application was tracked under several names are folded below, which
+-- moves time between names, so their change log entries are compared again
+INSERT INTO sync_dirty_dates (date, version)
+SELECT date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates) FROM (
+    SELECT date FROM app_usage
+    GROUP BY application_id, date
+    HAVING COUNT(*) > 1
+) WHERE true
+ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+
+CREATE TABLE app_usage_new (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL, -- name the application was tracked under
+    duration INTEGER NOT NULL DEFAULT 0 CHECK (duration >= 0),
+    icon_path TEXT,
+    exe_path TEXT,
+    date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    icon_hash TEXT,
+    application_id INTEGER NOT NULL
+);
+
+-- One row per application and day, keeping the longest tracked row with the summed time
+INSERT INTO app_usage_new (id, name, duration, icon_path, exe_path, date, created_at, updated_at, icon_hash, application_id)
+SELECT id, name, total, icon_path, exe_path, date, created_at, updated_at, icon_hash, application_id
+FROM (
+    SELECT app_usage.*,
+        SUM(duration) OVER (PARTITION BY application_id, date) AS total,
+        ROW_NUMBER() OVER (PARTITION BY application_id, date ORDER BY duration DESC, id) AS position
+    FROM app_usage
+)
+WHERE position = 1;
+
+DROP TABLE app_usage;
+ALTER TABLE app_usage_new RENAME TO app_usage;
+
+CREATE INDEX idx_app_usage_date ON app_usage(date);
+CREATE UNIQUE INDEX idx_app_usage_unique ON app_usage(application_id, date);
+CREATE INDEX idx_app_usage_name ON app_usage(name, date);
+CREATE INDEX idx_app_usage_icon_hash ON app_usage(icon_hash);
+
+-- Dropping the table dropped its triggers; changes are still exported per name
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_insert AFTER INSERT ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_update AFTER UPDATE OF name, duration, date ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_delete AFTER DELETE ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- Key app usage by name again, folding applications that share a name on the same day
+INSERT INTO sync_dirty_dates (date, version)
+SELECT date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates) FROM (
+    SELECT date FROM app_usage
+    GROUP BY name, date
+    HAVING COUNT(*) > 1
+) WHERE true
+ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+
+CREATE TABLE app_usage_old (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    duration INTEGER NOT NULL DEFAULT 0 CHECK (duration >= 0),
+    icon_path TEXT,
+    exe_path TEXT,
+    date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    icon_hash TEXT,
+    application_id INTEGER
+);
+
+INSERT INTO app_usage_old (id, name, duration, icon_path, exe_path, date, created_at, updated_at, icon_hash, application_id)
+SELECT id, name, total, icon_path, exe_path, date, created_at, updated_at, icon_hash, application_id
+FROM (
+    SELECT app_usage.*,
+        SUM(duration) OVER (PARTITION BY name, date) AS total,
+        ROW_NUMBER() OVER (PARTITION BY name, date ORDER BY duration DESC, id) AS position
+    FROM app_usage
+)
+WHERE position = 1;
+
+DROP TABLE app_usage;
+ALTER TABLE app_usage_old RENAME TO app_usage;
+
+CREATE INDEX idx_app_usage_date ON app_usage(date);
+CREATE UNIQUE INDEX idx_app_usage_unique ON app_usage(name, date);
+CREATE INDEX idx_app_usage_icon_hash ON app_usage(icon_hash);
+CREATE INDEX idx_app_usage_application ON app_usage(application_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_insert AFTER INSERT ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_update AFTER UPDATE OF name, duration, date ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (NEW.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER app_usage_sync_dirty_delete AFTER DELETE ON app_usage
+BEGIN
+    INSERT INTO sync_dirty_dates (date, version)
+    VALUES (OLD.date, (SELECT COALESCE(MAX(version), 0) + 1 FROM sync_dirty_dates))
+    ON CONFLICT(date) DO UPDATE SET version = excluded.version;
+END;
+-- +goose StatementEnd
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// migrationBackupPrefix starts the file name of every pre-migration backup
+const migrationBackupPrefix = "pre-migration-"
+
+// destructiveStatement matches SQL statements that remove or rewrite existing data
+var destructiveStatement = regexp.MustCompile(`(?i)\b(DROP\s+(TABLE|COLUMN)|DELETE\s+FROM|UPDATE\s+\w+\s+SET|ALTER\s+TABLE\s+\w+\s+(DROP|RENAME))\b`)
+
+// EnableBackups makes the runner copy the database into dir before any destructive
+// migration, keeping the newest retention copies (all of them when retention <= 0)
+func (mr *MigrationRunner) EnableBackups(dir string, retention int) {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	mr.backupDir = dir
+	mr.backupRetention = retention
+}
+
+// backupBeforeMigration copies the database before migrating from one version to another.
+// It does nothing when backups are disabled; callers hold mr.mutex.
+func (mr *MigrationRunner) backupBeforeMigration(ctx context.Context, from, to int64) error {
+	if mr.backupDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(mr.backupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory %s: %w", mr.backupDir, err)
+	}
+
+	name := fmt.Sprintf("%sv%d-to-v%d-%s.db", migrationBackupPrefix, from, to, time.Now().Format("20060102T150405.000"))
+	path := filepath.Join(mr.backupDir, name)
+
+	// VACUUM INTO writes a consistent, compacted copy without closing the connection. An
+	// encrypted database's copy goes through its VFS, so it is encrypted with the same key.
+	if _, err := mr.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database before migration: %w", err)
+	}
+	mr.logger.Info("Backed up database before migration", "path", path, "from", from, "to", to)
+
+	mr.pruneBackups()
+	return nil
+}
+
+// pruneBackups removes the oldest pre-migration backups beyond the retention limit
+func (mr *MigrationRunner) pruneBackups() {
+	if mr.backupRetention <= 0 {
+		return
+	}
+
+	backups, err := ListMigrationBackups(mr.backupDir)
+	if err != nil {
+		mr.logger.Warn("Failed to list migration backups", "dir", mr.backupDir, "error", err)
+		return
+	}
+
+	for len(backups) > mr.backupRetention {
+		if err := os.Remove(backups[0]); err != nil {
+			mr.logger.Warn("Failed to remove old migration backup", "path", backups[0], "error", err)
+		}
+		backups = backups[1:]
+	}
+}
+
+// ListMigrationBackups returns the pre-migration backups in dir, oldest first
+func ListMigrationBackups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), migrationBackupPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{filepath.Join(dir, entry.Name()), info.ModTime()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].modTime.Before(backups[j].modTime)
+		}
+		return backups[i].path < backups[j].path
+	})
+
+	paths := make([]string, len(backups))
+	for i, b := range backups {
+		paths[i] = b.path
+	}
+	return paths, nil
+}
+
+// hasDestructiveMigration reports whether any migration after current, up to target,
+// drops or rewrites existing data. Go migrations can't be inspected and always count.
+func hasDestructiveMigration(current, target int64) (bool, error) {
+	if target <= current {
+		return false, nil
+	}
+
+	migrations, err := goose.CollectMigrations(migrationsDir, 0, goose.MaxVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= current || migration.Version > target {
+			continue
+		}
+		if filepath.Ext(migration.Source) == ".go" {
+			return true, nil
+		}
+
+		content, err := embedMigrations.ReadFile(migration.Source)
+		if err != nil {
+			return false, fmt.Errorf("failed to read migration %s: %w", migration.Source, err)
+		}
+		if destructiveStatement.MatchString(upSection(string(content))) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// upSection returns the statements of a SQL migration's up section without comments
+func upSection(content string) string {
+	if i := strings.Index(content, "-- +goose Down"); i >= 0 {
+		content = content[:i]
+	}
+
+	var statements strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+		statements.WriteString(line)
+		statements.WriteString("\n")
+	}
+	return statements.String()
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"qwin/internal/infrastructure/logging"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMigrationRunner_BackupsBeforeDestructiveMigrations(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "backups")
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(dir, "test_backup.db")+"?_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	runner := NewMigrationRunner(db, logging.NewDefaultLogger())
+	runner.EnableBackups(backupDir, 2)
+	ctx := context.Background()
+
+	countBackups := func() []string {
+		t.Helper()
+		backups, err := ListMigrationBackups(backupDir)
+		if err != nil {
+			t.Fatalf("ListMigrationBackups failed: %v", err)
+		}
+		return backups
+	}
+
+	// A fresh database has nothing to lose
+	if err := runner.MigrateTo(ctx, 6); err != nil {
+		t.Fatalf("MigrateTo(6) failed: %v", err)
+	}
+	if backups := countBackups(); len(backups) != 0 {
+		t.Fatalf("Expected no backup when migrating a fresh database, got %v", backups)
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT INTO daily_usage (date, total_time) VALUES ('2024-06-01 00:00:00+00:00', 100)`); err != nil {
+		t.Fatalf("Failed to insert usage: %v", err)
+	}
+
+	// Migration 7 rewrites date keys, so it is backed up first
+	if err := runner.MigrateTo(ctx, 7); err != nil {
+		t.Fatalf("MigrateTo(7) failed: %v", err)
+	}
+	backups := countBackups()
+	if len(backups) != 1 || !strings.Contains(filepath.Base(backups[0]), "v6-to-v7") {
+		t.Fatalf("Expected one backup before migration 7, got %v", backups)
+	}
+
+	// The backup holds the data as it was before the migration
+	backup, err := sql.Open("sqlite3", "file:"+backups[0]+"?mode=ro")
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	var total int64
+	err = backup.QueryRowContext(ctx, `SELECT total_time FROM daily_usage`).Scan(&total)
+	backup.Close()
+	if err != nil || total != 100 {
+		t.Errorf("Expected the backup to contain the usage row, got %d (%v)", total, err)
+	}
+
+	// Migration 8 only creates a table
+	if err := runner.MigrateTo(ctx, 8); err != nil {
+		t.Fatalf("MigrateTo(8) failed: %v", err)
+	}
+	if backups := countBackups(); len(backups) != 1 {
+		t.Fatalf("Expected no backup before a non-destructive migration, got %v", backups)
+	}
+
+	// Rollbacks always drop something, and only the newest two backups are kept
+	for i := 0; i < 2; i++ {
+		if err := runner.Rollback(ctx, 1); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+	}
+	backups = countBackups()
+	if len(backups) != 2 || !strings.Contains(filepath.Base(backups[1]), "v7-to-v6") {
+		t.Errorf("Expected the two rollback backups to be kept, got %v", backups)
+	}
+}
+
+func TestHasDestructiveMigration(t *testing.T) {
+	tests := []struct {
+		name            string
+		current, target int64
+		want            bool
+	}{
+		{"creating tables", 0, 4, false},
+		{"go data migration", 4, 5, true},
+		{"rewriting date keys", 6, 7, true},
+		{"creating later tables", 7, 10, false},
+		{"nothing to run", 8, 8, false},
+		{"rolling back", 8, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hasDestructiveMigration(tt.current, tt.target)
+			if err != nil {
+				t.Fatalf("hasDestructiveMigration failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("hasDestructiveMigration(%d, %d) = %v, want %v", tt.current, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpSection(t *testing.T) {
+	content := "-- +goose Up\n-- DROP TABLE in a comment\nCREATE TABLE t (id INTEGER); -- DELETE FROM t\n-- +goose Down\nDROP TABLE t;\n"
+	if destructiveStatement.MatchString(upSection(content)) {
+		t.Errorf("Comments and the down section should not count as destructive: %q", upSection(content))
+	}
+	if !destructiveStatement.MatchString(upSection("-- +goose Up\nDELETE FROM t WHERE id = 1;\n")) {
+		t.Error("Expected DELETE FROM to be destructive")
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"qwin/internal/types"
+
+	"github.com/pressly/goose/v3"
+)
+
+// Icon data migration is written in Go because decoding base64 data URLs
+// and hashing their bytes cannot be expressed in SQLite SQL.
+func init() {
+	goose.AddNamedMigrationContext("005_move_icons_to_app_icons.go", upMoveIconsToAppIcons, downMoveIconsToAppIcons)
+}
+
+// upMoveIconsToAppIcons moves data URL icons embedded in app_usage rows into app_icons
+func upMoveIconsToAppIcons(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT icon_path FROM app_usage WHERE icon_path LIKE 'data:%'`)
+	if err != nil {
+		return fmt.Errorf("failed to query embedded icons: %w", err)
+	}
+
+	var iconPaths []string
+	for rows.Next() {
+		var iconPath string
+		if err := rows.Scan(&iconPath); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan embedded icon: %w", err)
+		}
+		iconPaths = append(iconPaths, iconPath)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to read embedded icons: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read embedded icons: %w", err)
+	}
+
+	for _, iconPath := range iconPaths {
+		icon, ok := types.IconFromDataURL(iconPath)
+		if !ok {
+			// Leave malformed values in icon_path untouched
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO app_icons (hash, mime, data, size) VALUES (?, ?, ?, ?) ON CONFLICT(hash) DO NOTHING`,
+			icon.Hash, icon.Mime, icon.Data, icon.Size); err != nil {
+			return fmt.Errorf("failed to insert icon %s: %w", icon.Hash, err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE app_usage SET icon_hash = ?, icon_path = NULL WHERE icon_path = ?`,
+			icon.Hash, iconPath); err != nil {
+			return fmt.Errorf("failed to reference icon %s: %w", icon.Hash, err)
+		}
+	}
+
+	return nil
+}
+
+// downMoveIconsToAppIcons embeds stored icons back into app_usage rows as data URLs
+func downMoveIconsToAppIcons(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT hash, mime, data, size FROM app_icons`)
+	if err != nil {
+		return fmt.Errorf("failed to query stored icons: %w", err)
+	}
+
+	var icons []types.Icon
+	for rows.Next() {
+		var icon types.Icon
+		if err := rows.Scan(&icon.Hash, &icon.Mime, &icon.Data, &icon.Size); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan stored icon: %w", err)
+		}
+		icons = append(icons, icon)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to read stored icons: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read stored icons: %w", err)
+	}
+
+	for _, icon := range icons {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE app_usage SET icon_path = ?, icon_hash = NULL WHERE icon_hash = ?`,
+			icon.DataURL(), icon.Hash); err != nil {
+			return fmt.Errorf("failed to embed icon %s: %w", icon.Hash, err)
+		}
+	}
+
+	return nil
+}
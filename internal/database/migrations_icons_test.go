@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pressly/goose/v3"
+)
+
+func TestMoveIconsToAppIconsMigration(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test_icons.db")
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	runner := NewMigrationRunner(db, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	// Bring the schema up to the version before the icon data migration
+	if err := goose.UpToContext(ctx, db, "migrations", 4); err != nil {
+		t.Fatalf("Failed to migrate to version 4: %v", err)
+	}
+
+	icon := types.NewIcon("image/png", []byte("png bytes"))
+	rows := []struct {
+		name     string
+		date     string
+		iconPath string
+	}{
+		{"Chrome", "2024-01-01", icon.DataURL()},
+		{"Chrome", "2024-01-02", icon.DataURL()},
+		{"Notepad", "2024-01-01", `C:\icons\notepad.ico`},
+	}
+	for _, row := range rows {
+		if _, err := db.ExecContext(ctx, `INSERT INTO app_usage (name, duration, icon_path, date) VALUES (?, 10, ?, ?)`,
+			row.name, row.iconPath, row.date); err != nil {
+			t.Fatalf("Failed to insert app usage: %v", err)
+		}
+	}
+
+	if err := runner.RunMigrations(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	var iconCount int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM app_icons`).Scan(&iconCount); err != nil {
+		t.Fatalf("Failed to count icons: %v", err)
+	}
+	if iconCount != 1 {
+		t.Errorf("Expected identical icons to be stored once, got %d", iconCount)
+	}
+
+	var referenced int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM app_usage WHERE icon_hash = ? AND icon_path IS NULL`, icon.Hash).Scan(&referenced); err != nil {
+		t.Fatalf("Failed to count referencing rows: %v", err)
+	}
+	if referenced != 2 {
+		t.Errorf("Expected 2 rows to reference the icon by hash, got %d", referenced)
+	}
+
+	var notepadPath string
+	if err := db.QueryRowContext(ctx, `SELECT icon_path FROM app_usage WHERE name = 'Notepad'`).Scan(&notepadPath); err != nil {
+		t.Fatalf("Failed to read file icon path: %v", err)
+	}
+	if notepadPath != `C:\icons\notepad.ico` {
+		t.Errorf("File icon paths should be left as-is, got %q", notepadPath)
+	}
+
+	// Rolling back restores the embedded data URLs
+	if err := goose.DownToContext(ctx, db, "migrations", 4); err != nil {
+		t.Fatalf("Failed to roll back icon migration: %v", err)
+	}
+
+	var restored int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM app_usage WHERE icon_path = ? AND icon_hash IS NULL`, icon.DataURL()).Scan(&restored); err != nil {
+		t.Fatalf("Failed to count restored rows: %v", err)
+	}
+	if restored != 2 {
+		t.Errorf("Expected 2 rows with restored data URLs, got %d", restored)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/pressly/goose/v3"
 )
 
 func TestMigrationRunner_RunMigrations(t *testing.T) {
@@ -35,7 +36,7 @@ func TestMigrationRunner_RunMigrations(t *testing.T) {
 	}
 
 	// Verify tables were created
-	tables := []string{"daily_usage", "app_usage", "goose_db_version"}
+	tables := []string{"daily_usage", "app_usage", "focus_events", "app_icons", "applications", "application_paths", "settings", "ignore_rules", "insights", "goose_db_version"}
 	for _, table := range tables {
 		var count int
 		err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count)
@@ -281,3 +282,259 @@ func TestMigrationRunner_ConcurrentAccess(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateApplicationsMigrationBackfill(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test_applications.db")
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	runner := NewMigrationRunner(db, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	if err := goose.UpToContext(ctx, db, "migrations", 5); err != nil {
+		t.Fatalf("Failed to migrate to version 5: %v", err)
+	}
+
+	for _, stmt := range []string{
+		`INSERT INTO app_usage (name, duration, exe_path, date) VALUES ('chrome', 10, 'C:\chrome.exe', '2024-01-01')`,
+		`INSERT INTO app_usage (name, duration, exe_path, date) VALUES ('chrome', 20, 'C:\chrome.exe', '2024-01-02')`,
+		`INSERT INTO app_usage (name, duration, date) VALUES ('notepad', 5, '2024-01-01')`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Failed to insert app usage: %v", err)
+		}
+	}
+
+	if err := runner.RunMigrations(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	var applications, unreferenced, paths int
+	db.QueryRowContext(ctx, `SELECT COUNT(*) FROM applications`).Scan(&applications)
+	db.QueryRowContext(ctx, `SELECT COUNT(*) FROM app_usage WHERE application_id IS NULL`).Scan(&unreferenced)
+	db.QueryRowContext(ctx, `SELECT COUNT(*) FROM application_paths`).Scan(&paths)
+
+	if applications != 2 {
+		t.Errorf("Expected one application per name, got %d", applications)
+	}
+	if unreferenced != 0 {
+		t.Errorf("Expected every usage row to reference an application, got %d without", unreferenced)
+	}
+	if paths != 1 {
+		t.Errorf("Expected 1 executable path, got %d", paths)
+	}
+}
+
+func TestNormalizeDateKeysMigration(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test_date_keys.db")
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	runner := NewMigrationRunner(db, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	if err := goose.UpToContext(ctx, db, "migrations", 6); err != nil {
+		t.Fatalf("Failed to migrate to version 6: %v", err)
+	}
+
+	// The same calendar day recorded in two timezones, plus another day
+	for _, stmt := range []string{
+		`INSERT INTO daily_usage (date, total_time) VALUES ('2024-06-01 00:00:00+02:00', 100)`,
+		`INSERT INTO daily_usage (date, total_time) VALUES ('2024-06-01 00:00:00-05:00', 50)`,
+		`INSERT INTO daily_usage (date, total_time) VALUES ('2024-06-02 00:00:00+02:00', 10)`,
+		`INSERT INTO app_usage (name, duration, date) VALUES ('chrome', 60, '2024-06-01 00:00:00+02:00')`,
+		`INSERT INTO app_usage (name, duration, date) VALUES ('chrome', 30, '2024-06-01 00:00:00-05:00')`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+
+	if err := runner.RunMigrations(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	var days int
+	var total int64
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM daily_usage`).Scan(&days); err != nil {
+		t.Fatalf("Failed to count days: %v", err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT total_time FROM daily_usage WHERE date = '2024-06-01 00:00:00+00:00'`).Scan(&total); err != nil {
+		t.Fatalf("Failed to read folded day: %v", err)
+	}
+	if days != 2 || total != 150 {
+		t.Errorf("Expected 2 days with the first folded to 150s, got %d days and %ds", days, total)
+	}
+
+	var duration int64
+	if err := db.QueryRowContext(ctx, `SELECT duration FROM app_usage WHERE name = 'chrome' AND date = '2024-06-01 00:00:00+00:00'`).Scan(&duration); err != nil {
+		t.Fatalf("Failed to read folded app usage: %v", err)
+	}
+	if duration != 90 {
+		t.Errorf("Expected folded app duration 90, got %d", duration)
+	}
+}
+
+func TestKeyAppUsageByApplicationMigration(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test_app_usage_key.db")
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	runner := NewMigrationRunner(db, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	if err := goose.UpToContext(ctx, db, "migrations", 15); err != nil {
+		t.Fatalf("Failed to migrate to version 15: %v", err)
+	}
+
+	// One application tracked under two names on a day, and a row written before it had one
+	for _, stmt := range []string{
+		`INSERT INTO applications (id, name, display_name) VALUES (1, 'code', 'Code')`,
+		`INSERT INTO app_usage (name, duration, date, application_id) VALUES ('code', 60, '2024-06-01 00:00:00+00:00', 1)`,
+		`INSERT INTO app_usage (name, duration, date, application_id) VALUES ('code-insiders', 30, '2024-06-01 00:00:00+00:00', 1)`,
+		`INSERT INTO app_usage (name, duration, date) VALUES ('notepad', 5, '2024-06-01 00:00:00+00:00')`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+
+	if err := runner.RunMigrations(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	var name string
+	var duration int64
+	if err := db.QueryRowContext(ctx, `SELECT name, duration FROM app_usage WHERE application_id = 1`).Scan(&name, &duration); err != nil {
+		t.Fatalf("Failed to read folded app usage: %v", err)
+	}
+	if name != "code" || duration != 90 {
+		t.Errorf("Expected the day folded into code with 90s, got %s with %ds", name, duration)
+	}
+
+	var unreferenced int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM app_usage a LEFT JOIN applications p ON p.id = a.application_id WHERE p.id IS NULL`).Scan(&unreferenced); err != nil {
+		t.Fatalf("Failed to count unreferenced usage: %v", err)
+	}
+	if unreferenced != 0 {
+		t.Errorf("Expected every usage row to reference an application, got %d without", unreferenced)
+	}
+
+	// Same name, different application: both rows can exist on a day now
+	if _, err := db.ExecContext(ctx, `INSERT INTO applications (id, name, display_name) VALUES (3, 'code', 'Code (portable)')`); err != nil {
+		t.Fatalf("Failed to insert application: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO app_usage (name, duration, date, application_id) VALUES ('code', 10, '2024-06-01 00:00:00+00:00', 3)`); err != nil {
+		t.Errorf("Expected a second application named code to get its own row: %v", err)
+	}
+}
+
+func TestMigrationRunner_StatusMigrateToAndRollback(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test_status.db")
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	runner := NewMigrationRunner(db, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	status, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed on a pristine database: %v", err)
+	}
+	if status.CurrentVersion != 0 || len(status.Applied) != 0 || len(status.Pending) == 0 {
+		t.Fatalf("Expected every migration pending on a pristine database, got %+v", status)
+	}
+	latest := status.LatestVersion
+	if status.Pending[len(status.Pending)-1].Version != latest || status.Pending[0].Name != "001_create_daily_usage.sql" {
+		t.Errorf("Unexpected pending migrations: %+v", status.Pending)
+	}
+
+	before := time.Now().Add(-time.Minute)
+	if err := runner.MigrateTo(ctx, 4); err != nil {
+		t.Fatalf("MigrateTo(4) failed: %v", err)
+	}
+	status, err = runner.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.CurrentVersion != 4 || len(status.Applied) != 4 || status.Pending[0].Version != 5 {
+		t.Fatalf("Expected 4 applied migrations, got %+v", status)
+	}
+	if at := status.Applied[3].AppliedAt; at == nil || at.Before(before) {
+		t.Errorf("Expected an application timestamp, got %v", at)
+	}
+
+	if err := runner.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations failed: %v", err)
+	}
+	if err := runner.Rollback(ctx, 2); err != nil {
+		t.Fatalf("Rollback(2) failed: %v", err)
+	}
+	status, err = runner.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(status.Pending) != 2 || status.Pending[1].Version != latest || status.Pending[0].AppliedAt != nil {
+		t.Errorf("Expected the last 2 migrations pending after rollback, got %+v", status.Pending)
+	}
+
+	// Rolling back everything and migrating up again leaves a working schema
+	if err := runner.MigrateTo(ctx, 0); err != nil {
+		t.Fatalf("MigrateTo(0) failed: %v", err)
+	}
+	if version, _ := runner.GetCurrentVersion(ctx); version != 0 {
+		t.Errorf("Expected version 0 after rolling back everything, got %d", version)
+	}
+	if err := runner.MigrateTo(ctx, latest); err != nil {
+		t.Fatalf("MigrateTo(latest) failed: %v", err)
+	}
+	if version, _ := runner.GetCurrentVersion(ctx); version != latest {
+		t.Errorf("Expected version %d, got %d", latest, version)
+	}
+}
+
+func TestMigrationRunner_InvalidTargets(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test_invalid.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	runner := NewMigrationRunner(db, logging.NewDefaultLogger())
+	ctx := context.Background()
+	if err := runner.MigrateTo(ctx, 3); err != nil {
+		t.Fatalf("MigrateTo(3) failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"negative version", func() error { return runner.MigrateTo(ctx, -1) }},
+		{"unknown version", func() error { return runner.MigrateTo(ctx, 999) }},
+		{"zero steps", func() error { return runner.Rollback(ctx, 0) }},
+		{"more steps than applied", func() error { return runner.Rollback(ctx, 4) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil {
+				t.Error("Expected an error")
+			}
+			if version, _ := runner.GetCurrentVersion(ctx); version != 3 {
+				t.Errorf("Expected version to stay at 3, got %d", version)
+			}
+		})
+	}
+}
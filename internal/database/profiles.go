@@ -0,0 +1,124 @@
+package database
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	dberrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+const (
+	// profilesFileName is the file beside the default database that lists the profiles
+	profilesFileName = "profiles.json"
+	// profilesDirName is the directory beside the default database that holds the other profiles
+	profilesDirName = "profiles"
+)
+
+// ForProfile returns a copy of the configuration that opens the named profile's database.
+// The default profile uses the configured path; other profiles keep a database of the same
+// name in profiles/<name> beside it, with their own backups. In-memory databases have no
+// files to separate and are returned unchanged.
+func (c *Config) ForProfile(name string) *Config {
+	config := c.Clone()
+	if name == "" || name == types.DefaultProfile || c.IsInMemory() {
+		return config
+	}
+
+	config.Path = filepath.Join(filepath.Dir(c.Path), profilesDirName, name, filepath.Base(c.Path))
+	if filepath.IsAbs(c.BackupPath) {
+		config.BackupPath = filepath.Join(c.BackupPath, profilesDirName, name)
+	}
+	return config
+}
+
+// ProfilesPath returns the file the profile configuration is kept in, or "" for in-memory
+// databases, whose profiles aren't kept between runs
+func ProfilesPath(config *Config) string {
+	if config.IsInMemory() {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(config.Path), profilesFileName)
+}
+
+// LoadProfiles reads the profile configuration at path. A missing file, or an empty path,
+// yields the configuration of an install that only has the default profile.
+func LoadProfiles(path string) (*types.ProfileConfig, error) {
+	if path == "" {
+		return types.NewProfileConfig(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return types.NewProfileConfig(), nil
+	}
+	if err != nil {
+		return nil, dberrors.NewRepositoryErrorWithContext("LoadProfiles", err, dberrors.ClassifyError(err), map[string]string{
+			"path": path,
+		})
+	}
+
+	var config types.ProfileConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, dberrors.NewRepositoryErrorWithContext("LoadProfiles", err, dberrors.ErrCodeValidation, map[string]string{
+			"path": path,
+		})
+	}
+	if err := config.Validate(); err != nil {
+		return nil, dberrors.NewRepositoryErrorWithContext("LoadProfiles", err, dberrors.ErrCodeValidation, map[string]string{
+			"path": path,
+		})
+	}
+	return &config, nil
+}
+
+// SaveProfiles validates the profile configuration and writes it to path, replacing the
+// previous file in one step. An empty path saves nothing.
+func SaveProfiles(path string, config *types.ProfileConfig) error {
+	if err := config.Validate(); err != nil {
+		return dberrors.NewRepositoryError("SaveProfiles", err, dberrors.ErrCodeValidation)
+	}
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return dberrors.NewRepositoryError("SaveProfiles", err, dberrors.ErrCodeValidation)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return dberrors.NewRepositoryErrorWithContext("SaveProfiles", err, dberrors.ClassifyError(err), map[string]string{
+			"path": path,
+		})
+	}
+	if err := writeFileSynced(path, data); err != nil {
+		return dberrors.NewRepositoryErrorWithContext("SaveProfiles", err, dberrors.ClassifyError(err), map[string]string{
+			"path": path,
+		})
+	}
+	return nil
+}
+
+// writeFileSynced writes data to a temporary file beside path, flushes it to disk and
+// renames it over path, so path always holds either the old or the new contents
+func writeFileSynced(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func TestConfig_ForProfile(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+
+	config := DefaultConfig()
+	config.Path = filepath.Join(tempDir, "qwin.db")
+
+	if got := config.ForProfile(types.DefaultProfile); got.Path != config.Path || got == config {
+		t.Errorf("ForProfile(default) path = %q, want a copy with %q", got.Path, config.Path)
+	}
+
+	work := config.ForProfile("work")
+	if want := filepath.Join(tempDir, "profiles", "work", "qwin.db"); work.Path != want {
+		t.Errorf("ForProfile(work) path = %q, want %q", work.Path, want)
+	}
+	if work.BackupPath != config.BackupPath {
+		t.Errorf("ForProfile(work) backup path = %q, want the relative path kept", work.BackupPath)
+	}
+	if got, want := migrationBackupDir(work), filepath.Join(tempDir, "profiles", "work", "backups"); got != want {
+		t.Errorf("backup dir = %q, want %q", got, want)
+	}
+
+	config.BackupPath = filepath.Join(tempDir, "all-backups")
+	if got, want := config.ForProfile("work").BackupPath, filepath.Join(tempDir, "all-backups", "profiles", "work"); got != want {
+		t.Errorf("ForProfile(work) absolute backup path = %q, want %q", got, want)
+	}
+
+	if got := TestConfig().ForProfile("work"); !got.IsInMemory() {
+		t.Errorf("ForProfile(work) of an in-memory database = %q, want it in memory", got.Path)
+	}
+}
+
+func TestSaveAndLoadProfiles(t *testing.T) {
+	t.Parallel()
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "qwin.db")
+	path := ProfilesPath(config)
+
+	loaded, err := LoadProfiles(path)
+	if err != nil {
+		t.Fatalf("LoadProfiles() without a file error = %v", err)
+	}
+	if loaded.Active != types.DefaultProfile || len(loaded.Profiles) != 1 {
+		t.Errorf("LoadProfiles() without a file = %+v, want only the default profile", loaded)
+	}
+
+	profiles := &types.ProfileConfig{
+		Active:     "work",
+		Profiles:   []string{types.DefaultProfile, "work"},
+		Schedule:   []types.ProfileSwitch{{Profile: "work", At: "09:00", Weekdays: []time.Weekday{time.Monday}}},
+		SwitchedAt: time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC),
+	}
+	if err := SaveProfiles(path, profiles); err != nil {
+		t.Fatalf("SaveProfiles() error = %v", err)
+	}
+	loaded, err = LoadProfiles(path)
+	if err != nil {
+		t.Fatalf("LoadProfiles() error = %v", err)
+	}
+	if loaded.Active != "work" || len(loaded.Profiles) != 2 || len(loaded.Schedule) != 1 || !loaded.SwitchedAt.Equal(profiles.SwitchedAt) {
+		t.Errorf("LoadProfiles() = %+v, want what was saved", loaded)
+	}
+
+	if err := SaveProfiles(path, &types.ProfileConfig{Active: "home", Profiles: []string{"work"}}); !errors.IsValidation(err) {
+		t.Errorf("SaveProfiles() with an unknown active profile error = %v, want a validation error", err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProfiles(path); !errors.IsValidation(err) {
+		t.Errorf("LoadProfiles() of a damaged file error = %v, want a validation error", err)
+	}
+
+	if got := ProfilesPath(TestConfig()); got != "" {
+		t.Errorf("ProfilesPath() of an in-memory database = %q, want none", got)
+	}
+}
@@ -0,0 +1,18 @@
+-- App Icon Queries
+-- Icons are content-addressed, so inserting an icon that already exists is a no-op
+
+-- name: InsertAppIcon :exec
+INSERT INTO app_icons (hash, mime, data, size)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(hash) DO NOTHING;
+
+-- name: GetAppIcon :one
+SELECT * FROM app_icons
+WHERE hash = ?;
+
+-- name: DeleteUnreferencedAppIcons :exec
+DELETE FROM app_icons
+WHERE hash NOT IN (
+    SELECT DISTINCT icon_hash FROM app_usage
+    WHERE icon_hash IS NOT NULL
+);
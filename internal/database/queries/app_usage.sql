@@ -2,8 +2,8 @@
 -- These queries handle CRUD operations for individual application usage data
 
 -- name: CreateAppUsage :one
-INSERT INTO app_usage (name, duration, icon_path, exe_path, date)
-VALUES (?, ?, ?, ?, ?)
+INSERT INTO app_usage (name, duration, icon_path, exe_path, icon_hash, application_id, date)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetAppUsageByID :one
@@ -14,6 +14,10 @@ WHERE id = ?;
 SELECT * FROM app_usage
 WHERE name = ? AND date = ?;
 
+-- name: GetAppUsageByApplicationAndDate :one
+SELECT * FROM app_usage
+WHERE application_id = ? AND date = ?;
+
 -- name: GetAppUsageByDate :many
 SELECT * FROM app_usage
 WHERE date = ?
@@ -21,17 +25,18 @@ ORDER BY duration DESC, name ASC;
 
 -- name: UpdateAppUsage :one
 UPDATE app_usage
-SET duration = ?, icon_path = ?, exe_path = ?, updated_at = CURRENT_TIMESTAMP
+SET duration = ?, icon_path = ?, exe_path = ?, icon_hash = ?, updated_at = CURRENT_TIMESTAMP
 WHERE name = ? AND date = ?
 RETURNING *;
 
 -- name: UpsertAppUsage :one
-INSERT INTO app_usage (name, duration, icon_path, exe_path, date)
-VALUES (?, ?, ?, ?, ?)
-ON CONFLICT(name, date) DO UPDATE SET
+INSERT INTO app_usage (name, duration, icon_path, exe_path, icon_hash, application_id, date)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(application_id, date) DO UPDATE SET
     duration = excluded.duration,
     icon_path = excluded.icon_path,
     exe_path = excluded.exe_path,
+    icon_hash = excluded.icon_hash,
     updated_at = CURRENT_TIMESTAMP
 RETURNING *;
 
@@ -54,6 +59,11 @@ SELECT * FROM app_usage
 WHERE name = ? AND date >= ? AND date <= ?
 ORDER BY date DESC;
 
+-- name: GetAppUsageByApplicationAndDateRange :many
+SELECT * FROM app_usage
+WHERE application_id = ? AND date >= ? AND date <= ?
+ORDER BY date DESC;
+
 -- name: GetTopAppsByDate :many
 SELECT * FROM app_usage
 WHERE date = ?
@@ -61,10 +71,12 @@ ORDER BY duration DESC
 LIMIT ?;
 
 -- name: GetTopAppsByDateRange :many
-SELECT name, SUM(duration) as total_duration, COUNT(DISTINCT date) as days_used
-FROM app_usage
-WHERE date >= ? AND date <= ?
-GROUP BY name
+SELECT a.application_id, COALESCE(p.alias, p.display_name) as display_name,
+    SUM(a.duration) as total_duration, COUNT(DISTINCT a.date) as days_used
+FROM app_usage a
+JOIN applications p ON p.id = a.application_id
+WHERE a.date >= ? AND a.date <= ?
+GROUP BY a.application_id
 ORDER BY total_duration DESC
 LIMIT ?;
 
@@ -75,8 +87,8 @@ ORDER BY date DESC, duration DESC;
 
 -- Single row insert operation
 -- name: InsertAppUsage :exec
-INSERT INTO app_usage (name, duration, icon_path, exe_path, date)
-VALUES (?, ?, ?, ?, ?);
+INSERT INTO app_usage (name, duration, icon_path, exe_path, icon_hash, application_id, date)
+VALUES (?, ?, ?, ?, ?, ?, ?);
 
 -- name: BatchUpdateAppUsage :exec
 UPDATE app_usage
@@ -89,7 +101,7 @@ WHERE date = ?
 ORDER BY name;
 
 -- name: GetAppUsageHistory :many
-SELECT name, date, duration, icon_path, exe_path
+SELECT name, date, duration, icon_path, exe_path, icon_hash, application_id
 FROM app_usage
 WHERE name = ?
 ORDER BY date DESC
@@ -119,4 +131,45 @@ LIMIT ? OFFSET ?;
 
 -- name: GetAppUsageCountByDateRange :one
 SELECT COUNT(*) FROM app_usage
-WHERE date >= ? AND date <= ?;
\ No newline at end of file
+WHERE date >= ? AND date <= ?;
+
+-- name: DeleteAppUsageByDate :exec
+DELETE FROM app_usage
+WHERE date = ?;
+
+-- Adds to an application's duration, creating the row if it doesn't exist yet.
+-- Concurrent writers each add their own delta, so no tracked time is lost.
+-- name: IncrementAppUsageDuration :exec
+INSERT INTO app_usage (name, duration, application_id, date)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(application_id, date) DO UPDATE SET
+    duration = app_usage.duration + excluded.duration,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- Adds a row to an application's usage, keeping the stored icon and executable path
+-- when the application already has a row, e.g. when it was tracked under two names
+-- name: AddAppUsage :exec
+INSERT INTO app_usage (name, duration, icon_path, exe_path, icon_hash, application_id, date)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(application_id, date) DO UPDATE SET
+    duration = app_usage.duration + excluded.duration,
+    icon_path = COALESCE(app_usage.icon_path, excluded.icon_path),
+    exe_path = COALESCE(app_usage.exe_path, excluded.exe_path),
+    icon_hash = COALESCE(app_usage.icon_hash, excluded.icon_hash),
+    updated_at = CURRENT_TIMESTAMP;
+
+-- Writes icon and executable path without touching the tracked duration
+-- name: UpsertAppUsageMetadata :exec
+INSERT INTO app_usage (name, duration, icon_path, exe_path, icon_hash, application_id, date)
+VALUES (?, 0, ?, ?, ?, ?, ?)
+ON CONFLICT(application_id, date) DO UPDATE SET
+    icon_path = excluded.icon_path,
+    exe_path = excluded.exe_path,
+    icon_hash = excluded.icon_hash,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- Rewrites the identifying columns of a row, e.g. when redacting history
+-- name: UpdateAppUsageSource :exec
+UPDATE app_usage
+SET name = ?, exe_path = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
@@ -0,0 +1,106 @@
+-- Application Queries
+-- Applications give tracked programs a stable identity independent of their executable name
+
+-- name: CreateApplication :one
+INSERT INTO applications (name, display_name, publisher, version)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetApplicationByID :one
+SELECT * FROM applications
+WHERE id = ?;
+
+-- name: GetApplicationByName :one
+SELECT * FROM applications
+WHERE name = ?
+ORDER BY id
+LIMIT 1;
+
+-- name: ListApplications :many
+SELECT * FROM applications
+ORDER BY COALESCE(alias, display_name) COLLATE NOCASE, id;
+
+-- Names usage is shown under: the alias, or the display name without one
+-- name: ListApplicationDisplayNames :many
+SELECT id, COALESCE(alias, display_name) AS display_name FROM applications;
+
+-- name: UpdateApplication :one
+UPDATE applications
+SET display_name = ?, alias = ?, publisher = ?, version = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
+
+-- name: UpdateApplicationVersionInfo :exec
+UPDATE applications
+SET publisher = ?, version = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: RenameApplication :exec
+UPDATE applications
+SET name = ?, display_name = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: DeleteApplication :exec
+DELETE FROM applications
+WHERE id = ?;
+
+-- Executable path queries
+-- name: AddApplicationPath :exec
+INSERT INTO application_paths (exe_path, application_id)
+VALUES (?, ?)
+ON CONFLICT(exe_path) DO NOTHING;
+
+-- name: GetApplicationIDByExePath :one
+SELECT application_id FROM application_paths
+WHERE exe_path = ?;
+
+-- name: GetApplicationPaths :many
+SELECT exe_path FROM application_paths
+WHERE application_id = ?
+ORDER BY exe_path;
+
+-- name: ListApplicationPaths :many
+SELECT * FROM application_paths
+ORDER BY application_id, exe_path;
+
+-- name: DeleteApplicationPath :exec
+DELETE FROM application_paths
+WHERE exe_path = ?;
+
+-- name: MoveApplicationPaths :exec
+UPDATE application_paths
+SET application_id = ?
+WHERE application_id = ?;
+
+-- name: MoveApplicationPath :exec
+UPDATE application_paths
+SET application_id = ?
+WHERE exe_path = ?;
+
+-- Usage reassignment queries used by merge and split
+-- Adds the source's usage to the target's on days both were used, so the source's rows
+-- for those days can be dropped and the rest reassigned without clashing
+-- name: AddOverlappingAppUsage :exec
+UPDATE app_usage
+SET duration = duration + (
+        SELECT source.duration FROM app_usage source
+        WHERE source.application_id = sqlc.arg(source_id) AND source.date = app_usage.date
+    ),
+    updated_at = CURRENT_TIMESTAMP
+WHERE application_id = sqlc.arg(target_id)
+  AND date IN (SELECT date FROM app_usage WHERE application_id = sqlc.arg(source_id));
+
+-- name: DeleteOverlappingAppUsage :exec
+DELETE FROM app_usage
+WHERE application_id = sqlc.arg(source_id)
+  AND date IN (SELECT date FROM app_usage WHERE application_id = sqlc.arg(target_id));
+
+-- name: ReassignAppUsage :exec
+UPDATE app_usage
+SET application_id = ?, updated_at = CURRENT_TIMESTAMP
+WHERE application_id = ?;
+
+-- name: ReassignAppUsageByExePath :exec
+UPDATE app_usage
+SET application_id = ?, updated_at = CURRENT_TIMESTAMP
+WHERE application_id = ? AND exe_path = ? COLLATE NOCASE;
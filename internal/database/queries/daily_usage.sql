@@ -44,4 +44,11 @@ ORDER BY date DESC;
 
 -- name: DeleteOldDailyUsage :exec
 DELETE FROM daily_usage
-WHERE date < ?;
\ No newline at end of file
+WHERE date < ?;
+-- Adds to the day's total, creating the row if it doesn't exist yet
+-- name: IncrementDailyUsage :exec
+INSERT INTO daily_usage (date, total_time)
+VALUES (?, ?)
+ON CONFLICT(date) DO UPDATE SET
+    total_time = daily_usage.total_time + excluded.total_time,
+    updated_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,107 @@
+-- Device Sync Queries
+-- Usage of other devices is merged from their change logs, applying each entry once
+
+-- name: GetSyncDevice :one
+SELECT * FROM sync_devices
+WHERE device_id = ?;
+
+-- name: ListSyncDevices :many
+SELECT * FROM sync_devices
+ORDER BY is_local DESC, name, device_id;
+
+-- name: UpsertSyncDevice :exec
+INSERT INTO sync_devices (device_id, name, is_local, last_seq, last_synced_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(device_id) DO UPDATE SET
+    name = CASE WHEN excluded.name <> '' THEN excluded.name ELSE sync_devices.name END,
+    is_local = excluded.is_local,
+    last_seq = MAX(sync_devices.last_seq, excluded.last_seq),
+    last_synced_at = excluded.last_synced_at;
+
+-- Adds a change log delta to a device's usage of an app
+-- name: IncrementDeviceAppUsage :exec
+INSERT INTO device_app_usage (device_id, app_name, date, duration)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(device_id, app_name, date) DO UPDATE SET
+    duration = device_app_usage.duration + excluded.duration;
+
+-- name: IncrementDeviceDailyUsage :exec
+INSERT INTO device_daily_usage (device_id, date, total_time)
+VALUES (?, ?, ?)
+ON CONFLICT(device_id, date) DO UPDATE SET
+    total_time = device_daily_usage.total_time + excluded.total_time;
+
+-- name: GetDeviceAppUsageByDateRange :many
+SELECT * FROM device_app_usage
+WHERE date >= ? AND date <= ?
+ORDER BY date DESC, duration DESC;
+
+-- name: GetDeviceDailyUsageByDateRange :many
+SELECT * FROM device_daily_usage
+WHERE date >= ? AND date <= ?
+ORDER BY date DESC;
+
+-- name: DeleteOldDeviceAppUsage :exec
+DELETE FROM device_app_usage
+WHERE date < ?;
+
+-- name: DeleteOldDeviceDailyUsage :exec
+DELETE FROM device_daily_usage
+WHERE date < ?;
+
+-- Forgets everything merged and exported, so syncing starts over
+-- name: DeleteAllDeviceAppUsage :exec
+DELETE FROM device_app_usage;
+
+-- name: DeleteAllDeviceDailyUsage :exec
+DELETE FROM device_daily_usage;
+
+-- name: DeleteAllSyncDevices :exec
+DELETE FROM sync_devices;
+
+-- Replacing a device's usage with a snapshot of its change log
+-- name: DeleteDeviceAppUsage :exec
+DELETE FROM device_app_usage
+WHERE device_id = ?;
+
+-- name: DeleteDeviceDailyUsage :exec
+DELETE FROM device_daily_usage
+WHERE device_id = ?;
+
+-- name: ListDeviceAppUsage :many
+SELECT * FROM device_app_usage
+WHERE device_id = ?
+ORDER BY date, app_name;
+
+-- name: ListDeviceDailyUsage :many
+SELECT * FROM device_daily_usage
+WHERE device_id = ?
+ORDER BY date;
+
+-- Dates whose usage changed since the local device last compared it with its change log
+-- name: ListSyncDirtyDates :many
+SELECT * FROM sync_dirty_dates
+ORDER BY date;
+
+-- name: ListDirtyAppUsage :many
+SELECT a.name, a.duration, a.date FROM app_usage a
+JOIN sync_dirty_dates d ON d.date = a.date;
+
+-- name: ListDirtyDailyUsage :many
+SELECT u.date, u.total_time FROM daily_usage u
+JOIN sync_dirty_dates d ON d.date = u.date;
+
+-- name: ListDirtyDeviceAppUsage :many
+SELECT u.app_name, u.duration, u.date FROM device_app_usage u
+JOIN sync_dirty_dates d ON d.date = u.date
+WHERE u.device_id = ?;
+
+-- name: ListDirtyDeviceDailyUsage :many
+SELECT u.date, u.total_time FROM device_daily_usage u
+JOIN sync_dirty_dates d ON d.date = u.date
+WHERE u.device_id = ?;
+
+-- Forgets a dirty date unless it was marked again after being read
+-- name: ClearSyncDirtyDate :exec
+DELETE FROM sync_dirty_dates
+WHERE date = ? AND version = ?;
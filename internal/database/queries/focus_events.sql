@@ -0,0 +1,36 @@
+-- Focus Event Queries
+-- These queries handle the append-only focus event log used for crash recovery and replay
+
+-- name: InsertFocusEvent :one
+INSERT INTO focus_events (event_type, app_name, exe_path, occurred_at)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetFocusEventsByTimeRange :many
+SELECT * FROM focus_events
+WHERE occurred_at >= ? AND occurred_at <= ?
+ORDER BY occurred_at ASC, id ASC;
+
+-- name: GetLatestFocusEventByType :one
+SELECT * FROM focus_events
+WHERE event_type = ?
+ORDER BY occurred_at DESC, id DESC
+LIMIT 1;
+
+-- name: CompactFocusEvents :exec
+DELETE FROM focus_events
+WHERE event_type IN ('heartbeat', 'checkpoint') AND occurred_at < ?;
+
+-- name: DeleteOldFocusEvents :exec
+DELETE FROM focus_events
+WHERE occurred_at < ?;
+
+-- name: ListFocusEventSources :many
+SELECT id, app_name, exe_path FROM focus_events
+WHERE app_name IS NOT NULL OR exe_path IS NOT NULL
+ORDER BY id;
+
+-- name: UpdateFocusEventSource :exec
+UPDATE focus_events
+SET app_name = ?, exe_path = ?
+WHERE id = ?;
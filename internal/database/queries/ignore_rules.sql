@@ -0,0 +1,29 @@
+-- Ignore Rule Queries
+-- Ignore rules keep matching applications out of usage stats
+
+-- name: CreateIgnoreRule :one
+INSERT INTO ignore_rules (match_type, pattern, mode, enabled)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetIgnoreRule :one
+SELECT * FROM ignore_rules
+WHERE id = ?;
+
+-- name: ListIgnoreRules :many
+SELECT * FROM ignore_rules
+ORDER BY id;
+
+-- name: UpdateIgnoreRule :one
+UPDATE ignore_rules
+SET match_type = ?, pattern = ?, mode = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteIgnoreRule :exec
+DELETE FROM ignore_rules
+WHERE id = ?;
+
+-- name: ListAppUsageSources :many
+SELECT id, name, exe_path, duration, date FROM app_usage
+ORDER BY date, id;
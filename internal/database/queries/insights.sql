@@ -0,0 +1,20 @@
+-- Insight Queries
+-- Insights record usage that deviated from the user's own baseline
+
+-- name: CreateInsight :one
+INSERT INTO insights (date, kind, app_name, value, baseline, score, explanation)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetInsightsByDateRange :many
+SELECT * FROM insights
+WHERE date >= ? AND date <= ?
+ORDER BY date DESC, ABS(score) DESC, id;
+
+-- name: DeleteInsightsByDate :exec
+DELETE FROM insights
+WHERE date = ?;
+
+-- name: DeleteOldInsights :exec
+DELETE FROM insights
+WHERE date < ?;
@@ -0,0 +1,17 @@
+-- Settings Queries
+-- User preferences are stored as key/value pairs
+
+-- name: GetSetting :one
+SELECT value FROM settings
+WHERE key = ?;
+
+-- name: UpsertSetting :exec
+INSERT INTO settings (key, value)
+VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET
+    value = excluded.value,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteSetting :exec
+DELETE FROM settings
+WHERE key = ?;
@@ -0,0 +1,35 @@
+-- Time Entry Queries
+-- Time entries add manual or imported usage next to tracked usage
+
+-- name: CreateTimeEntry :one
+INSERT INTO time_entries (date, app_name, duration, source, note)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetTimeEntry :one
+SELECT * FROM time_entries
+WHERE id = ?;
+
+-- name: ListTimeEntriesByDateRange :many
+SELECT * FROM time_entries
+WHERE date >= ? AND date <= ?
+ORDER BY date, id;
+
+-- name: UpdateTimeEntry :one
+UPDATE time_entries
+SET date = ?, app_name = ?, duration = ?, source = ?, note = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteTimeEntry :exec
+DELETE FROM time_entries
+WHERE id = ?;
+
+-- name: CreateTimeEntryAudit :exec
+INSERT INTO time_entry_audit (entry_id, action, date, app_name, duration, source, note)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListTimeEntryAudit :many
+SELECT * FROM time_entry_audit
+WHERE entry_id = ?
+ORDER BY id;
@@ -0,0 +1,381 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	dberrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+)
+
+// quarantineDir is where corrupt database files are moved, next to the database
+const quarantineDir = "quarantine"
+
+// sqliteCLI is the sqlite3 command-line tool used to salvage rows with .recover. It has to
+// be installed separately on Windows; without it, recovery can only restore a backup.
+var sqliteCLI = "sqlite3"
+
+// databaseSidecars are the files SQLite keeps beside a database and that belong to it
+var databaseSidecars = []string{"-wal", "-shm", "-journal"}
+
+// RecoveryMethod says how a corrupt database was replaced
+type RecoveryMethod string
+
+const (
+	// RecoveryFromBackup means the newest valid backup was restored
+	RecoveryFromBackup RecoveryMethod = "backup"
+	// RecoveryRebuilt means the rows SQLite's .recover could salvage were rebuilt into a new file
+	RecoveryRebuilt RecoveryMethod = "recover"
+	// RecoveryFresh means nothing could be salvaged and an empty database was started
+	RecoveryFresh RecoveryMethod = "fresh"
+)
+
+// RecoveryReport describes how a corrupt database was dealt with
+type RecoveryReport struct {
+	RecoveredAt time.Time      `json:"recoveredAt"`
+	Reason      string         `json:"reason"`
+	Quarantine  string         `json:"quarantine"`
+	Method      RecoveryMethod `json:"method"`
+	Source      string         `json:"source,omitempty"` // Backup restored, or file the rows were salvaged from
+
+	// BackupTakenAt and BackupAge describe the newest valid backup that was weighed
+	// against the salvaged rows; both are zero when there was none
+	BackupTakenAt time.Time     `json:"backupTakenAt,omitempty"`
+	BackupAge     time.Duration `json:"backupAge,omitempty"`
+	// LatestUsageDate is the last day with usage in the recovered database, as YYYY-MM-DD
+	LatestUsageDate string `json:"latestUsageDate,omitempty"`
+	// SalvageError says why no rows could be salvaged from the corrupt file, such as the
+	// sqlite3 tool not being installed; empty when they were
+	SalvageError string `json:"salvageError,omitempty"`
+}
+
+// StartupCheck is the outcome of opening the database at startup
+type StartupCheck struct {
+	Integrity *IntegrityReport `json:"integrity"`
+	Recovery  *RecoveryReport  `json:"recovery,omitempty"` // Set only when the database was corrupt and replaced
+}
+
+// OpenWithRecovery connects service to the configured database and checks its integrity.
+// A corrupt database file is quarantined and replaced by RecoverDatabase before connecting
+// again, so startup only fails when the database can't be opened for other reasons.
+func OpenWithRecovery(ctx context.Context, service Service, config *Config, logger logging.Logger) (*StartupCheck, error) {
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+
+	check := &StartupCheck{}
+	reason, err := connectAndCheck(ctx, service, config, check)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return check, nil
+	}
+	if config.Path == ":memory:" {
+		service.Close()
+		return nil, dberrors.HandleCorruptionError("OpenWithRecovery", config.Path, reason)
+	}
+
+	logger.Error("Database is corrupt, recovering", "path", config.Path, "reason", reason)
+	service.Close()
+
+	var keyring Keyring
+	if sqliteService, ok := service.(*SQLiteService); ok {
+		keyring = sqliteService.encryptionKeyring()
+	}
+	recovery, err := recoverDatabase(ctx, config, keyring, reason, logger)
+	if err != nil {
+		return nil, err
+	}
+	check.Recovery = recovery
+
+	reason, err = connectAndCheck(ctx, service, config, check)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		service.Close()
+		return nil, dberrors.HandleCorruptionError("OpenWithRecovery", config.Path, reason)
+	}
+	return check, nil
+}
+
+// connectAndCheck connects service and checks the database, returning why it counts as
+// corrupt when it does. The service is left connected unless an error is returned.
+func connectAndCheck(ctx context.Context, service Service, config *Config, check *StartupCheck) (string, error) {
+	if err := service.Connect(ctx, config); err != nil {
+		if dberrors.IsCorruption(err) {
+			return err.Error(), nil
+		}
+		return "", err
+	}
+
+	report, err := service.CheckIntegrity(ctx)
+	if err != nil {
+		service.Close()
+		return "", err
+	}
+	check.Integrity = report
+
+	for _, issue := range report.Issues {
+		if issue.Kind == IntegrityStructure {
+			return issue.Detail, nil
+		}
+	}
+	return "", nil
+}
+
+// RecoverDatabase moves the corrupt database at config.Path into quarantine, then rebuilds
+// the rows SQLite's .recover can salvage from it into a new database. Backups are only
+// taken before migrations and may be months old, so the newest backup that passes an
+// integrity check is restored instead only when nothing could be salvaged or it holds
+// more recent usage than what was. Failing both, the path is left empty for a fresh
+// database. The quarantined file is always kept.
+//
+// Salvaging needs the sqlite3 command-line tool on PATH, which Windows doesn't ship with;
+// without it the report's SalvageError says so and only a backup can be restored. The
+// tool can't read encrypted databases either, so those are only restored from their
+// backups, which are encrypted with the same key; keys come from the OS keyring.
+func RecoverDatabase(ctx context.Context, config *Config, reason string, logger logging.Logger) (*RecoveryReport, error) {
+	return recoverDatabase(ctx, config, nil, reason, logger)
+}
+
+// recoverDatabase is RecoverDatabase with keys of encrypted databases held in keyring
+func recoverDatabase(ctx context.Context, config *Config, keyring Keyring, reason string, logger logging.Logger) (*RecoveryReport, error) {
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+
+	quarantined, err := quarantineDatabase(config.Path)
+	if err != nil {
+		return nil, dberrors.NewRepositoryErrorWithContext("RecoverDatabase", err, dberrors.ErrCodeCorruption, map[string]string{
+			"phase": "quarantine",
+			"path":  config.Path,
+		})
+	}
+	logger.Warn("Quarantined corrupt database", "path", config.Path, "quarantine", quarantined)
+
+	report := &RecoveryReport{
+		RecoveredAt: time.Now(),
+		Reason:      reason,
+		Quarantine:  quarantined,
+		Method:      RecoveryFresh,
+	}
+
+	var key *databaseKey
+	if config.EncryptionEnabled {
+		if key, err = encryptionKey(config, keyring, false); err != nil {
+			logger.Warn("No key to open encrypted backups with", "error", err)
+		}
+	}
+
+	rebuilt := config.Path + ".recovering"
+	salvaged := true
+	if kind, _ := detectDatabaseFile(quarantined); kind == databaseFileEncrypted {
+		logger.Warn("Rows can't be salvaged from an encrypted database, only restored from a backup")
+		salvaged = false
+		report.SalvageError = "rows can't be salvaged from an encrypted database"
+	} else if err := rebuildFromRecover(ctx, quarantined, rebuilt); err != nil {
+		logger.Warn("Could not salvage rows from the corrupt database", "error", err)
+		salvaged = false
+		report.SalvageError = err.Error()
+	}
+	defer os.Remove(rebuilt)
+
+	backup, takenAt := newestValidBackup(ctx, migrationBackupDir(config), key, logger)
+	if backup != "" {
+		report.BackupTakenAt = takenAt
+		report.BackupAge = report.RecoveredAt.Sub(takenAt)
+	}
+
+	useBackup := backup != "" && !salvaged
+	if backup != "" && salvaged {
+		salvagedLatest := latestUsageDate(ctx, rebuilt, nil)
+		backupLatest := latestUsageDate(ctx, backup, key)
+		useBackup = backupLatest > salvagedLatest
+		logger.Info("Weighed salvaged rows against the newest backup",
+			"salvaged_latest", salvagedLatest, "backup", backup, "backup_latest", backupLatest)
+	}
+
+	if useBackup {
+		if err := copyFile(backup, config.Path); err != nil {
+			logger.Warn("Failed to restore backup", "path", backup, "error", err)
+			os.Remove(config.Path)
+		} else {
+			report.Method = RecoveryFromBackup
+			report.Source = backup
+		}
+	}
+	if report.Method == RecoveryFresh && salvaged {
+		if err := os.Rename(rebuilt, config.Path); err != nil {
+			logger.Warn("Failed to move the rebuilt database into place", "error", err)
+		} else {
+			report.Method = RecoveryRebuilt
+			report.Source = quarantined
+		}
+	}
+	if report.Method == RecoveryFresh {
+		logger.Warn("Nothing could be recovered, starting a fresh database", "path", config.Path)
+	} else {
+		report.LatestUsageDate = latestUsageDate(ctx, config.Path, key)
+	}
+
+	logger.Info("Recovered corrupt database", "path", config.Path, "method", report.Method, "source", report.Source,
+		"latest_usage", report.LatestUsageDate)
+	return report, nil
+}
+
+// quarantineDatabase moves a database file and its sidecar files into the quarantine
+// directory beside it, returning the quarantined database's path
+func quarantineDatabase(path string) (string, error) {
+	dir := filepath.Join(filepath.Dir(path), quarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory %s: %w", dir, err)
+	}
+
+	quarantined := filepath.Join(dir, fmt.Sprintf("%s.corrupt-%s", filepath.Base(path), time.Now().Format("20060102T150405.000")))
+	if err := os.Rename(path, quarantined); err != nil {
+		return "", fmt.Errorf("failed to move %s into quarantine: %w", path, err)
+	}
+
+	// Sidecars keep their suffix so SQLite still pairs them with the quarantined file
+	for _, suffix := range databaseSidecars {
+		if err := os.Rename(path+suffix, quarantined+suffix); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to move %s into quarantine: %w", path+suffix, err)
+		}
+	}
+	return quarantined, nil
+}
+
+// newestValidBackup returns the newest backup in dir that passes an integrity check and
+// when it was written, or "" when there is none. Encrypted backups are opened with key.
+func newestValidBackup(ctx context.Context, dir string, key *databaseKey, logger logging.Logger) (string, time.Time) {
+	backups, err := ListMigrationBackups(dir)
+	if err != nil {
+		logger.Warn("Failed to list backups", "dir", dir, "error", err)
+		return "", time.Time{}
+	}
+
+	for i := len(backups) - 1; i >= 0; i-- {
+		if err := verifyDatabaseFile(ctx, backups[i], key); err != nil {
+			logger.Warn("Skipping unusable backup", "path", backups[i], "error", err)
+			continue
+		}
+		info, err := os.Stat(backups[i])
+		if err != nil {
+			continue
+		}
+		return backups[i], info.ModTime()
+	}
+	return "", time.Time{}
+}
+
+// latestUsageDate returns the last day with usage in a database file as YYYY-MM-DD, or ""
+// when it has none or can't be read
+func latestUsageDate(ctx context.Context, path string, key *databaseKey) string {
+	db, release, err := openDatabaseFile(path, key)
+	if err != nil {
+		return ""
+	}
+	defer release()
+
+	var latest sql.NullString
+	err = db.QueryRowContext(ctx, `
+		SELECT MAX(day) FROM (
+			SELECT MAX(substr(date, 1, 10)) AS day FROM daily_usage
+			UNION ALL
+			SELECT MAX(substr(date, 1, 10)) AS day FROM app_usage
+		)`).Scan(&latest)
+	if err != nil {
+		return ""
+	}
+	return latest.String
+}
+
+// rebuildFromRecover runs the sqlite3 tool's .recover on a corrupt file and replays its
+// output into a new database at rebuilt. The result is only kept if it is sound and still
+// records its migration version, so migrations can continue from it.
+func rebuildFromRecover(ctx context.Context, corrupt, rebuilt string) error {
+	cli, err := exec.LookPath(sqliteCLI)
+	if err != nil {
+		return fmt.Errorf("the %s command-line tool, which salvages rows, is not installed: %w", sqliteCLI, err)
+	}
+
+	var script, stderr bytes.Buffer
+	recoverCmd := exec.CommandContext(ctx, cli, corrupt, ".recover")
+	recoverCmd.Stdout = &script
+	recoverCmd.Stderr = &stderr
+	if err := recoverCmd.Run(); err != nil {
+		return fmt.Errorf(".recover failed: %w: %s", err, stderr.String())
+	}
+
+	os.Remove(rebuilt)
+
+	stderr.Reset()
+	loadCmd := exec.CommandContext(ctx, cli, rebuilt)
+	loadCmd.Stdin = &script
+	loadCmd.Stderr = &stderr
+	if err := loadCmd.Run(); err != nil {
+		os.Remove(rebuilt)
+		return fmt.Errorf("failed to load recovered rows: %w: %s", err, stderr.String())
+	}
+
+	if err := verifyDatabaseFile(ctx, rebuilt, nil); err != nil {
+		os.Remove(rebuilt)
+		return err
+	}
+	return nil
+}
+
+// verifyDatabaseFile opens a database file read-only and checks it is sound and migrated.
+// Encrypted files are opened with key.
+func verifyDatabaseFile(ctx context.Context, path string, key *databaseKey) error {
+	db, release, err := openDatabaseFile(path, key)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	issues, err := quickCheck(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("integrity check failed: %s", issues[0].Detail)
+	}
+
+	migrated, err := tableExists(ctx, db, "goose_db_version")
+	if err != nil {
+		return err
+	}
+	if !migrated {
+		return fmt.Errorf("%s has no migration version table", filepath.Base(path))
+	}
+	return nil
+}
+
+// copyFile copies src to a new file at dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+)
+
+// createUsageDatabase creates a migrated database file holding one day of usage
+func createUsageDatabase(t *testing.T, path string, totalTime int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	config := DefaultConfig()
+	config.Path = path
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	if err := service.Connect(ctx, config); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer service.Close()
+
+	if err := service.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if _, err := service.DB().ExecContext(ctx, `INSERT INTO daily_usage (date, total_time) VALUES ('2024-06-01 00:00:00+00:00', ?)`, totalTime); err != nil {
+		t.Fatalf("Failed to insert usage: %v", err)
+	}
+	for i := 0; i < 200; i++ {
+		if _, err := service.DB().ExecContext(ctx, `INSERT INTO app_usage (name, duration, date, application_id) VALUES (?, 60, '2024-06-01 00:00:00+00:00', ?)`, fmt.Sprintf("app-%d", i), i+1); err != nil {
+			t.Fatalf("Failed to insert app usage: %v", err)
+		}
+	}
+}
+
+// openRecovered opens the database at path with OpenWithRecovery and returns the service and check
+func openRecovered(t *testing.T, path string) (*SQLiteService, *StartupCheck) {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.Path = path
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	check, err := OpenWithRecovery(context.Background(), service, config, logging.NewDefaultLogger())
+	if err != nil {
+		t.Fatalf("OpenWithRecovery failed: %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+	return service, check
+}
+
+// storedTotalTime reads the day of usage written by createUsageDatabase
+func storedTotalTime(t *testing.T, service *SQLiteService) int64 {
+	t.Helper()
+	var total int64
+	if err := service.DB().QueryRowContext(context.Background(), `SELECT total_time FROM daily_usage`).Scan(&total); err != nil {
+		t.Fatalf("Failed to read usage: %v", err)
+	}
+	return total
+}
+
+// writeGarbage replaces a file with bytes that aren't a database
+func writeGarbage(t *testing.T, path string) {
+	t.Helper()
+	garbage := make([]byte, 8192)
+	for i := range garbage {
+		garbage[i] = byte(i * 7)
+	}
+	if err := os.WriteFile(path, garbage, 0644); err != nil {
+		t.Fatalf("Failed to write garbage: %v", err)
+	}
+}
+
+// damageIndexPage overwrites the start of an index page, so the file fails its check but
+// the table rows survive for .recover
+func damageIndexPage(t *testing.T, path string) {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.Path = path
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	if err := service.Connect(context.Background(), config); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	var rootPage, pageSize int64
+	if err := service.DB().QueryRow(`SELECT rootpage FROM sqlite_master WHERE name = 'idx_app_usage_unique'`).Scan(&rootPage); err != nil {
+		t.Fatalf("Failed to find the index page: %v", err)
+	}
+	if err := service.DB().QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		t.Fatalf("Failed to read the page size: %v", err)
+	}
+	service.Close()
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	garbage := make([]byte, 64)
+	for i := range garbage {
+		garbage[i] = 0xA5
+	}
+	if _, err := file.WriteAt(garbage, (rootPage-1)*pageSize); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+}
+
+// assertQuarantined checks the corrupt file was moved aside rather than deleted
+func assertQuarantined(t *testing.T, dir string, recovery *RecoveryReport) {
+	t.Helper()
+	if filepath.Dir(recovery.Quarantine) != filepath.Join(dir, quarantineDir) {
+		t.Errorf("Quarantine = %s, want a file in %s", recovery.Quarantine, filepath.Join(dir, quarantineDir))
+	}
+	if _, err := os.Stat(recovery.Quarantine); err != nil {
+		t.Errorf("Expected the quarantined file to exist: %v", err)
+	}
+}
+
+func TestOpenWithRecovery_HealthyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "healthy.db")
+	createUsageDatabase(t, path, 100)
+
+	service, check := openRecovered(t, path)
+	if check.Recovery != nil {
+		t.Fatalf("Expected no recovery, got %+v", check.Recovery)
+	}
+	if check.Integrity == nil || !check.Integrity.OK() {
+		t.Fatalf("Expected a clean integrity report, got %+v", check.Integrity)
+	}
+	if total := storedTotalTime(t, service); total != 100 {
+		t.Errorf("total_time = %d, want 100", total)
+	}
+}
+
+func TestOpenWithRecovery_RestoresNewestValidBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "usage.db")
+	backupDir := filepath.Join(dir, "backups")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// An older and a newer good backup, and a newest one that is itself damaged
+	backups := []struct {
+		name  string
+		total int64
+	}{
+		{migrationBackupPrefix + "v5-to-v6-a.db", 100},
+		{migrationBackupPrefix + "v6-to-v7-b.db", 200},
+		{migrationBackupPrefix + "v7-to-v8-c.db", 0},
+	}
+	base := time.Now().Add(-time.Hour)
+	for i, backup := range backups {
+		backupPath := filepath.Join(backupDir, backup.name)
+		if backup.total > 0 {
+			createUsageDatabase(t, backupPath, backup.total)
+		} else {
+			writeGarbage(t, backupPath)
+		}
+		modTime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(backupPath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	createUsageDatabase(t, path, 300)
+	writeGarbage(t, path)
+
+	service, check := openRecovered(t, path)
+	if check.Recovery == nil || check.Recovery.Method != RecoveryFromBackup {
+		t.Fatalf("Expected recovery from a backup, got %+v", check.Recovery)
+	}
+	if filepath.Base(check.Recovery.Source) != backups[1].name {
+		t.Errorf("Source = %s, want %s", check.Recovery.Source, backups[1].name)
+	}
+	if check.Recovery.Reason == "" {
+		t.Error("Expected the recovery to record why it happened")
+	}
+	assertQuarantined(t, dir, check.Recovery)
+
+	if !check.Integrity.OK() {
+		t.Errorf("Expected the restored database to pass, got %+v", check.Integrity.Issues)
+	}
+	if total := storedTotalTime(t, service); total != 200 {
+		t.Errorf("total_time = %d, want the newest valid backup's 200", total)
+	}
+}
+
+func TestOpenWithRecovery_RebuildsFromRecover(t *testing.T) {
+	// .recover is only in sqlite3 builds that include the recovery extension
+	if err := exec.Command(sqliteCLI, ":memory:", ".recover").Run(); err != nil {
+		t.Skipf("%s can't run .recover: %v", sqliteCLI, err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "usage.db")
+	createUsageDatabase(t, path, 300)
+	damageIndexPage(t, path)
+
+	recovered, check := openRecovered(t, path)
+	if check.Recovery == nil || check.Recovery.Method != RecoveryRebuilt {
+		t.Fatalf("Expected the database to be rebuilt, got %+v", check.Recovery)
+	}
+	if check.Recovery.Source != check.Recovery.Quarantine {
+		t.Errorf("Source = %s, want the quarantined file %s", check.Recovery.Source, check.Recovery.Quarantine)
+	}
+	assertQuarantined(t, dir, check.Recovery)
+
+	if total := storedTotalTime(t, recovered); total != 300 {
+		t.Errorf("total_time = %d, want the salvaged 300", total)
+	}
+	if err := recovered.Migrate(context.Background()); err != nil {
+		t.Errorf("Expected migrations to continue on the rebuilt database: %v", err)
+	}
+}
+
+func TestOpenWithRecovery_PrefersSalvagedRowsOverOlderBackup(t *testing.T) {
+	if err := exec.Command(sqliteCLI, ":memory:", ".recover").Run(); err != nil {
+		t.Skipf("%s can't run .recover: %v", sqliteCLI, err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "usage.db")
+	backupDir := filepath.Join(dir, "backups")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A backup from before a migration months ago, and a database with usage since
+	backupPath := filepath.Join(backupDir, migrationBackupPrefix+"v5-to-v6-a.db")
+	createUsageDatabase(t, backupPath, 100)
+	takenAt := time.Now().Add(-90 * 24 * time.Hour)
+	if err := os.Chtimes(backupPath, takenAt, takenAt); err != nil {
+		t.Fatal(err)
+	}
+
+	createUsageDatabase(t, path, 300)
+	config := DefaultConfig()
+	config.Path = path
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	if err := service.Connect(context.Background(), config); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if _, err := service.DB().Exec(`INSERT INTO daily_usage (date, total_time) VALUES ('2024-09-01 00:00:00+00:00', 50)`); err != nil {
+		t.Fatalf("Failed to insert usage: %v", err)
+	}
+	service.Close()
+	damageIndexPage(t, path)
+
+	_, check := openRecovered(t, path)
+	if check.Recovery == nil || check.Recovery.Method != RecoveryRebuilt {
+		t.Fatalf("Expected the more recent salvaged rows to win over the backup, got %+v", check.Recovery)
+	}
+	if check.Recovery.LatestUsageDate != "2024-09-01" {
+		t.Errorf("LatestUsageDate = %q, want 2024-09-01", check.Recovery.LatestUsageDate)
+	}
+	if !check.Recovery.BackupTakenAt.Equal(takenAt) || check.Recovery.BackupAge < 89*24*time.Hour {
+		t.Errorf("BackupTakenAt = %s, BackupAge = %s, want the backup's age reported",
+			check.Recovery.BackupTakenAt, check.Recovery.BackupAge)
+	}
+}
+
+func TestOpenWithRecovery_StartsFresh(t *testing.T) {
+	original := sqliteCLI
+	sqliteCLI = "qwin-test-missing-sqlite3"
+	defer func() { sqliteCLI = original }()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "usage.db")
+	writeGarbage(t, path)
+
+	service, check := openRecovered(t, path)
+	if check.Recovery == nil || check.Recovery.Method != RecoveryFresh || check.Recovery.Source != "" {
+		t.Fatalf("Expected a fresh database, got %+v", check.Recovery)
+	}
+	if !strings.Contains(check.Recovery.SalvageError, "qwin-test-missing-sqlite3") {
+		t.Errorf("SalvageError = %q, want the missing tool named", check.Recovery.SalvageError)
+	}
+	assertQuarantined(t, dir, check.Recovery)
+
+	if err := service.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate failed on the fresh database: %v", err)
+	}
+	if !check.Integrity.OK() {
+		t.Errorf("Expected the fresh database to pass, got %+v", check.Integrity.Issues)
+	}
+}
+
+func TestOpenWithRecovery_InMemoryIsNeverRecovered(t *testing.T) {
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	check, err := OpenWithRecovery(context.Background(), service, TestConfig(), nil)
+	if err != nil {
+		t.Fatalf("OpenWithRecovery failed: %v", err)
+	}
+	defer service.Close()
+
+	if check.Recovery != nil || !check.Integrity.OK() {
+		t.Errorf("Expected a clean in-memory database, got %+v", check)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"path/filepath"
 	queries "qwin/internal/database/generated"
 	dberrors "qwin/internal/infrastructure/errors"
 	"qwin/internal/infrastructure/logging"
@@ -13,6 +14,9 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Ensure SQLiteService implements Service interface
+var _ Service = (*SQLiteService)(nil)
+
 // SQLiteService implements the Service interface for SQLite
 //
 // Lifecycle:
@@ -30,6 +34,8 @@ type SQLiteService struct {
 	stateMu         sync.RWMutex     // Protects db, config, migrationRunner, queries fields
 	preparedMu      sync.RWMutex     // Protects lazy initialization of prepared statements
 	logger          logging.Logger
+	keyring         Keyring         // Holds keys of encrypted databases; nil uses the OS keyring
+	encrypted       *encryptedStore // Set while an encrypted database is open
 }
 
 // NewSQLiteService creates a new SQLite database service
@@ -72,6 +78,7 @@ func (s *SQLiteService) Connect(ctx context.Context, config *Config) error {
 			s.logger.Error("Failed to close existing database connection", "error", err)
 			// Continue with new connection even if close fails
 		}
+		s.closeEncryptedLocked()
 
 		// Clear references to prevent accidental reuse
 		s.db = nil
@@ -79,13 +86,48 @@ func (s *SQLiteService) Connect(ctx context.Context, config *Config) error {
 		s.migrationRunner = nil
 	}
 
-	// Build connection string with configuration options
-	connStr := config.GetConnectionString()
+	driverName, connStr := "sqlite3", config.GetConnectionString()
+	if config.EncryptionEnabled && !config.IsInMemory() {
+		store, err := openEncryptedStore(ctx, config, s.keyring, s.logger)
+		if err != nil {
+			return err
+		}
+		s.encrypted = store
+		driverName, connStr = encryptedDriverName, store.connectionString(config)
+	} else if kind, _ := detectDatabaseFile(config.Path); kind == databaseFileEncrypted {
+		// Opened without its key the file reads as corrupt, and recovery would replace it
+		return dberrors.NewRepositoryErrorWithContext("Connect", fmt.Errorf("database is encrypted but encryption is disabled"),
+			dberrors.ErrCodePermission, map[string]string{
+				"path": config.Path,
+			})
+	}
+
+	db, err := s.openFile(ctx, config, driverName, connStr)
+	if err != nil {
+		s.closeEncryptedLocked()
+		return err
+	}
+
+	s.db = db
+	s.queries = queries.New(db)
+
+	// Initialize migration runner, backing up file databases before destructive migrations
+	migrationRunner := NewMigrationRunner(db, s.logger)
+	if config.Path != ":memory:" {
+		migrationRunner.EnableBackups(migrationBackupDir(config), config.BackupRetention)
+	}
+	s.migrationRunner = migrationRunner
+
+	s.logger.Info("Connected to SQLite database", "path", config.Path, "encrypted", s.encrypted != nil)
+	return nil
+}
 
+// openFile opens the database file at config.Path with the given driver and connection string
+func (s *SQLiteService) openFile(ctx context.Context, config *Config, driverName, connStr string) (*sql.DB, error) {
 	// Open database connection
-	db, err := sql.Open("sqlite3", connStr)
+	db, err := sql.Open(driverName, connStr)
 	if err != nil {
-		return dberrors.HandleConnectionError("Connect", fmt.Sprintf("failed to open database: %v", err))
+		return nil, dberrors.HandleConnectionError("Connect", fmt.Sprintf("failed to open database: %v", err))
 	}
 
 	// Configure connection pool based on SQLite capabilities
@@ -94,17 +136,40 @@ func (s *SQLiteService) Connect(ctx context.Context, config *Config) error {
 	// Test the connection
 	if err := db.PingContext(ctx); err != nil {
 		db.Close()
-		return dberrors.HandleConnectionError("Connect", fmt.Sprintf("failed to ping database: %v", err))
+		// A file that isn't a database, or is damaged, fails here rather than on open
+		if dberrors.ClassifyError(err) == dberrors.ErrCodeCorruption {
+			return nil, dberrors.NewRepositoryErrorWithContext("Connect", err, dberrors.ErrCodeCorruption, map[string]string{
+				"path": config.Path,
+			})
+		}
+		return nil, dberrors.HandleConnectionError("Connect", fmt.Sprintf("failed to ping database: %v", err))
 	}
 
-	s.db = db
-	s.queries = queries.New(db)
+	return db, nil
+}
 
-	// Initialize migration runner
-	s.migrationRunner = NewMigrationRunner(db, s.logger)
+// SetKeyring sets where the keys of encrypted databases are kept, instead of the OS keyring
+func (s *SQLiteService) SetKeyring(keyring Keyring) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.keyring = keyring
+}
 
-	s.logger.Info("Connected to SQLite database", "path", config.Path)
-	return nil
+// encryptionKeyring returns the keyring set with SetKeyring, or nil for the OS keyring
+func (s *SQLiteService) encryptionKeyring() Keyring {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.keyring
+}
+
+// closeEncryptedLocked forgets the open encrypted database, if any. Callers hold stateMu
+// and have closed the database first.
+func (s *SQLiteService) closeEncryptedLocked() {
+	if s.encrypted == nil {
+		return
+	}
+	s.encrypted.Close()
+	s.encrypted = nil
 }
 
 // Close closes the database connection
@@ -130,7 +195,9 @@ func (s *SQLiteService) Close() error {
 	s.preparedMu.Unlock()
 
 	// Close database connection
-	if err := s.db.Close(); err != nil {
+	err := s.db.Close()
+	s.closeEncryptedLocked()
+	if err != nil {
 		return dberrors.HandleConnectionError("Close", fmt.Sprintf("failed to close database: %v", err))
 	}
 
@@ -175,6 +242,77 @@ func (s *SQLiteService) Migrate(ctx context.Context) error {
 	return nil
 }
 
+// MigrateTo migrates the schema up or down to the given version
+func (s *SQLiteService) MigrateTo(ctx context.Context, version int64) error {
+	migrationRunner, err := s.getMigrationRunner("MigrateTo")
+	if err != nil {
+		return err
+	}
+
+	if err := migrationRunner.MigrateTo(ctx, version); err != nil {
+		return dberrors.WrapDatabaseErrorWithContext("MigrateTo", err, map[string]string{
+			"version": fmt.Sprintf("%d", version),
+		})
+	}
+	return nil
+}
+
+// Rollback reverts the most recently applied steps migrations
+func (s *SQLiteService) Rollback(ctx context.Context, steps int) error {
+	migrationRunner, err := s.getMigrationRunner("Rollback")
+	if err != nil {
+		return err
+	}
+
+	if err := migrationRunner.Rollback(ctx, steps); err != nil {
+		return dberrors.WrapDatabaseErrorWithContext("Rollback", err, map[string]string{
+			"steps": fmt.Sprintf("%d", steps),
+		})
+	}
+	return nil
+}
+
+// MigrationStatus reports the applied and pending migrations
+func (s *SQLiteService) MigrationStatus(ctx context.Context) (*MigrationStatus, error) {
+	migrationRunner, err := s.getMigrationRunner("MigrationStatus")
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := migrationRunner.Status(ctx)
+	if err != nil {
+		return nil, dberrors.WrapDatabaseError("MigrationStatus", err)
+	}
+	return status, nil
+}
+
+// getMigrationRunner returns the migration runner of a connected database
+func (s *SQLiteService) getMigrationRunner(op string) (MigrationManager, error) {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+
+	if s.db == nil {
+		return nil, dberrors.HandleConnectionError(op, "database not connected")
+	}
+	if s.migrationRunner == nil {
+		return nil, dberrors.HandleValidationError(op, "migrationRunner", "nil", "migration runner not initialized")
+	}
+	return s.migrationRunner, nil
+}
+
+// migrationBackupDir returns where pre-migration backups of the configured database go.
+// A relative backup path is taken relative to the database file.
+func migrationBackupDir(config *Config) string {
+	dir := config.BackupPath
+	if dir == "" {
+		dir = "backups"
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(config.Path), dir)
+}
+
 // Health checks the database connection health
 func (s *SQLiteService) Health(ctx context.Context) error {
 	s.stateMu.RLock()
@@ -208,6 +346,26 @@ func (s *SQLiteService) Health(ctx context.Context) error {
 	return nil
 }
 
+// CheckIntegrity verifies the database file and the invariants of the stored usage data
+func (s *SQLiteService) CheckIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	s.stateMu.RLock()
+	if s.db == nil {
+		s.stateMu.RUnlock()
+		return nil, dberrors.HandleConnectionError("CheckIntegrity", "database not connected")
+	}
+	db := s.db
+	s.stateMu.RUnlock()
+
+	report, err := CheckIntegrity(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !report.OK() {
+		s.logger.Warn("Database integrity check found problems", "issues", len(report.Issues), "corrupt", report.Corrupt())
+	}
+	return report, nil
+}
+
 // DB returns the underlying database connection for use by repositories
 func (s *SQLiteService) DB() *sql.DB {
 	s.stateMu.RLock()
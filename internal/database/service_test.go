@@ -732,3 +732,66 @@ func TestSQLiteService_Optimize_WithNonWALMode(t *testing.T) {
 
 	t.Log("Non-WAL optimization test passed")
 }
+
+func TestSQLiteService_RollbackWithBackup(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+
+	config := DefaultConfig()
+	config.Path = filepath.Join(tempDir, "test_rollback.db")
+	config.BackupPath = filepath.Join(tempDir, "backups")
+
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	ctx := context.Background()
+	if err := service.Connect(ctx, config); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer service.Close()
+
+	if err := service.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	latest, err := service.GetMigrationVersion(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get migration version: %v", err)
+	}
+
+	if err := service.Rollback(ctx, 1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	status, err := service.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	if status.CurrentVersion >= latest || len(status.Pending) != 1 || status.Pending[0].Version != latest {
+		t.Errorf("Expected only the latest migration pending after rollback, got %+v", status)
+	}
+
+	backups, err := ListMigrationBackups(config.BackupPath)
+	if err != nil || len(backups) != 1 {
+		t.Errorf("Expected one backup in the configured backup path, got %v (%v)", backups, err)
+	}
+
+	if err := service.MigrateTo(ctx, latest); err != nil {
+		t.Fatalf("MigrateTo failed: %v", err)
+	}
+	if version, _ := service.GetMigrationVersion(ctx); version != latest {
+		t.Errorf("Expected version %d after migrating back up, got %d", latest, version)
+	}
+}
+
+func TestSQLiteService_MigrationCommands_NotConnected(t *testing.T) {
+	t.Parallel()
+	service := NewSQLiteService(logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	if err := service.MigrateTo(ctx, 1); !dberrors.IsConnection(err) {
+		t.Errorf("Expected connection error from MigrateTo, got: %v", err)
+	}
+	if err := service.Rollback(ctx, 1); !dberrors.IsConnection(err) {
+		t.Errorf("Expected connection error from Rollback, got: %v", err)
+	}
+	if _, err := service.MigrationStatus(ctx); !dberrors.IsConnection(err) {
+		t.Errorf("Expected connection error from MigrationStatus, got: %v", err)
+	}
+}
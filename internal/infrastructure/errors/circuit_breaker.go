@@ -0,0 +1,289 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState string
+
+const (
+	// CircuitClosed lets every call through
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects calls without running them until the open timeout passes
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single trial call through to decide whether to close again
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerConfig holds configuration for a circuit breaker
+type CircuitBreakerConfig struct {
+	// FailureThresholds is how many failures in a row with each code open the circuit.
+	// Codes not listed, such as not found or validation errors, say nothing about whether
+	// storage works and never open it.
+	FailureThresholds map[ErrorCode]int
+	OpenTimeout       time.Duration // How long the circuit stays open before a trial call
+	MaxOpenTimeout    time.Duration // Upper bound as the timeout doubles after failed trials
+}
+
+// DefaultCircuitBreakerConfig returns a circuit breaker configuration with sensible defaults
+func DefaultCircuitBreakerConfig() *CircuitBreakerConfig {
+	return &CircuitBreakerConfig{
+		FailureThresholds: map[ErrorCode]int{
+			ErrCodeDiskSpace:   1, // Needs the user to free space, retrying won't help
+			ErrCodeCorruption:  1,
+			ErrCodePermission:  2,
+			ErrCodeConnection:  3,
+			ErrCodeBusy:        3,
+			ErrCodeTimeout:     3,
+			ErrCodeTransaction: 5,
+		},
+		OpenTimeout:    time.Minute,
+		MaxOpenTimeout: 15 * time.Minute,
+	}
+}
+
+// CircuitStatus is a snapshot of a circuit breaker for health reporting
+type CircuitStatus struct {
+	State     CircuitState `json:"state"`
+	LastError string       `json:"lastError,omitempty"`
+	ErrorCode string       `json:"errorCode,omitempty"`
+	Failures  int          `json:"failures"`           // Failures in a row with ErrorCode
+	OpenedAt  time.Time    `json:"openedAt,omitempty"` // When the circuit last opened
+	RetryAt   time.Time    `json:"retryAt,omitempty"`  // When an open circuit allows a trial call
+	Rejected  int64        `json:"rejected"`           // Calls rejected since the circuit opened
+}
+
+// CircuitBreaker stops calling a failing dependency once the same kind of error keeps
+// happening, so retries don't hammer a full disk or a locked database. After a timeout
+// it lets one trial call through, closing again when it succeeds.
+type CircuitBreaker struct {
+	config *CircuitBreakerConfig
+	now    func() time.Time
+
+	mutex       sync.Mutex
+	state       CircuitState
+	failureCode ErrorCode
+	failures    int
+	lastError   error
+	openedAt    time.Time
+	timeout     time.Duration // current open timeout, doubled after each failed trial
+	trialActive bool
+	rejected    int64
+	onChange    func(CircuitStatus)
+	changed     *CircuitStatus // status to report to onChange once the mutex is released
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
+	if config == nil {
+		config = DefaultCircuitBreakerConfig()
+	}
+	return &CircuitBreaker{
+		config:  config,
+		now:     time.Now,
+		state:   CircuitClosed,
+		timeout: config.OpenTimeout,
+	}
+}
+
+// SetStateChangeHandler registers a function called with the new status whenever the state changes
+func (cb *CircuitBreaker) SetStateChangeHandler(handler func(CircuitStatus)) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.onChange = handler
+}
+
+// Execute runs operation unless the circuit is open, and records its outcome
+func (cb *CircuitBreaker) Execute(op string, operation func() error) error {
+	if err := cb.allow(op); err != nil {
+		return err
+	}
+	err := operation()
+	cb.record(err)
+	return err
+}
+
+// allow returns an ErrCodeUnavailable error when the call must not run
+func (cb *CircuitBreaker) allow(op string) error {
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if cb.now().Before(cb.openedAt.Add(cb.timeout)) {
+			return cb.rejectLocked(op)
+		}
+		cb.state = CircuitHalfOpen
+		cb.trialActive = true
+		cb.notifyLocked()
+		return nil
+	case CircuitHalfOpen:
+		// Only the trial call runs; the rest wait for its outcome
+		if cb.trialActive {
+			return cb.rejectLocked(op)
+		}
+		cb.trialActive = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// rejectLocked counts and builds the error for a rejected call
+// Must be called with cb.mutex held
+func (cb *CircuitBreaker) rejectLocked(op string) error {
+	cb.rejected++
+	return NewRepositoryErrorWithContext(op,
+		fmt.Errorf("storage unavailable after repeated %s errors: %w", cb.failureCode, cb.lastError),
+		ErrCodeUnavailable,
+		map[string]string{
+			"circuit":  string(cb.state),
+			"retry_at": cb.openedAt.Add(cb.timeout).Format(time.RFC3339),
+		})
+}
+
+// record updates the circuit with the outcome of a call that was allowed to run
+func (cb *CircuitBreaker) record(err error) {
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	wasTrial := cb.state == CircuitHalfOpen
+	cb.trialActive = false
+
+	// A cancelled call says nothing about storage either way
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err == nil {
+		cb.failures = 0
+		cb.lastError = nil
+		if cb.state != CircuitClosed {
+			cb.state = CircuitClosed
+			cb.timeout = cb.config.OpenTimeout
+			cb.rejected = 0
+			cb.notifyLocked()
+		}
+		return
+	}
+
+	// Errors such as not found or validation are caused by the caller, not storage, so
+	// they neither close the circuit nor break a run of failures. A half-open circuit
+	// lets the next call through as its trial instead.
+	code := circuitErrorCode(err)
+	threshold, counts := cb.config.FailureThresholds[code]
+	if !counts || threshold <= 0 {
+		return
+	}
+
+	if code != cb.failureCode {
+		cb.failureCode = code
+		cb.failures = 0
+	}
+	cb.failures++
+	cb.lastError = err
+
+	switch {
+	case wasTrial:
+		// The dependency is still failing; wait longer before the next trial
+		cb.timeout = min(cb.timeout*2, cb.config.MaxOpenTimeout)
+		cb.open()
+	case cb.failures >= threshold:
+		cb.open()
+	}
+}
+
+// open moves the circuit to open as of now
+// Must be called with cb.mutex held
+func (cb *CircuitBreaker) open() {
+	cb.state = CircuitOpen
+	cb.openedAt = cb.now()
+	cb.notifyLocked()
+}
+
+// notifyLocked queues the current status for the state change handler
+// Must be called with cb.mutex held
+func (cb *CircuitBreaker) notifyLocked() {
+	status := cb.statusLocked()
+	cb.changed = &status
+}
+
+// unlock releases the mutex, then reports a state change queued while it was held, so
+// handlers may query the breaker
+func (cb *CircuitBreaker) unlock() {
+	changed, handler := cb.changed, cb.onChange
+	cb.changed = nil
+	cb.mutex.Unlock()
+
+	if changed != nil && handler != nil {
+		handler(*changed)
+	}
+}
+
+// State returns the current state, reporting an open circuit whose timeout has passed as half-open
+func (cb *CircuitBreaker) State() CircuitState {
+	return cb.Status().State
+}
+
+// Status returns a snapshot of the circuit for health reporting
+func (cb *CircuitBreaker) Status() CircuitStatus {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.statusLocked()
+}
+
+// statusLocked builds the status snapshot
+// Must be called with cb.mutex held
+func (cb *CircuitBreaker) statusLocked() CircuitStatus {
+	status := CircuitStatus{
+		State:    cb.state,
+		Failures: cb.failures,
+		Rejected: cb.rejected,
+	}
+	if cb.lastError != nil {
+		status.LastError = cb.lastError.Error()
+		status.ErrorCode = cb.failureCode.String()
+	}
+	if cb.state != CircuitClosed {
+		status.OpenedAt = cb.openedAt
+		status.RetryAt = cb.openedAt.Add(cb.timeout)
+	}
+	if cb.state == CircuitOpen && !cb.now().Before(status.RetryAt) {
+		status.State = CircuitHalfOpen
+	}
+	return status
+}
+
+// Reset closes the circuit and forgets recorded failures, e.g. after reconnecting
+func (cb *CircuitBreaker) Reset() {
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	changed := cb.state != CircuitClosed
+	cb.state = CircuitClosed
+	cb.failures = 0
+	cb.lastError = nil
+	cb.trialActive = false
+	cb.timeout = cb.config.OpenTimeout
+	cb.rejected = 0
+	if changed {
+		cb.notifyLocked()
+	}
+}
+
+// circuitErrorCode returns the code of a repository error, classifying other errors
+func circuitErrorCode(err error) ErrorCode {
+	if err == nil {
+		return ErrCodeUnknown
+	}
+	var repoErr *RepositoryError
+	if errors.As(err, &repoErr) {
+		return repoErr.Code
+	}
+	return ClassifyError(err)
+}
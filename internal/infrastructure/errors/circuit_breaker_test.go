@@ -0,0 +1,217 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestCircuitBreaker creates a circuit breaker whose clock is advanced by the returned function
+func newTestCircuitBreaker(config *CircuitBreakerConfig) (*CircuitBreaker, func(time.Duration)) {
+	cb := NewCircuitBreaker(config)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cb.now = func() time.Time { return now }
+	return cb, func(d time.Duration) { now = now.Add(d) }
+}
+
+func failWith(code ErrorCode) func() error {
+	return func() error {
+		return NewRepositoryError("op", fmt.Errorf("%s failure", code), code)
+	}
+}
+
+func succeed() error { return nil }
+
+func TestCircuitBreaker_OpensAtThresholdPerCode(t *testing.T) {
+	cb, _ := newTestCircuitBreaker(nil)
+
+	for i := 0; i < 2; i++ {
+		cb.Execute("op", failWith(ErrCodeBusy))
+	}
+	if state := cb.State(); state != CircuitClosed {
+		t.Fatalf("State after 2 busy errors = %s, want closed", state)
+	}
+
+	// A different code starts counting again
+	cb.Execute("op", failWith(ErrCodeConnection))
+	if state := cb.State(); state != CircuitClosed {
+		t.Fatalf("State after switching error codes = %s, want closed", state)
+	}
+
+	// A full disk opens the circuit straight away
+	cb.Execute("op", failWith(ErrCodeDiskSpace))
+	if state := cb.State(); state != CircuitOpen {
+		t.Fatalf("State after a disk space error = %s, want open", state)
+	}
+
+	status := cb.Status()
+	if status.ErrorCode != "DISK_SPACE" || status.Failures != 1 || status.LastError == "" {
+		t.Errorf("Status = %+v", status)
+	}
+}
+
+func TestCircuitBreaker_IgnoresErrorsUnrelatedToStorage(t *testing.T) {
+	cb, _ := newTestCircuitBreaker(nil)
+
+	for i := 0; i < 10; i++ {
+		cb.Execute("op", failWith(ErrCodeNotFound))
+		cb.Execute("op", failWith(ErrCodeValidation))
+		cb.Execute("op", func() error { return context.Canceled })
+	}
+	if state := cb.State(); state != CircuitClosed {
+		t.Errorf("State = %s, want closed", state)
+	}
+}
+
+func TestCircuitBreaker_CallerErrorsLeaveStateUnchanged(t *testing.T) {
+	cb, advance := newTestCircuitBreaker(nil)
+
+	// Interleaved lookups don't break a run of busy errors
+	cb.Execute("op", failWith(ErrCodeBusy))
+	cb.Execute("op", failWith(ErrCodeNotFound))
+	cb.Execute("op", failWith(ErrCodeBusy))
+	cb.Execute("op", failWith(ErrCodeValidation))
+	cb.Execute("op", failWith(ErrCodeBusy))
+	if state := cb.State(); state != CircuitOpen {
+		t.Fatalf("State after 3 busy errors between lookups = %s, want open", state)
+	}
+
+	// A trial ending in a validation error neither closes nor reopens the circuit
+	advance(time.Minute)
+	cb.Execute("op", failWith(ErrCodeValidation))
+	status := cb.Status()
+	if status.State != CircuitHalfOpen || status.Failures != 3 {
+		t.Fatalf("Status after a trial with a validation error = %+v, want half_open with 3 failures", status)
+	}
+
+	// The next call becomes the trial
+	if err := cb.Execute("op", succeed); err != nil {
+		t.Fatalf("Trial call failed: %v", err)
+	}
+	if state := cb.State(); state != CircuitClosed {
+		t.Errorf("State after a successful trial = %s, want closed", state)
+	}
+}
+
+func TestCircuitBreaker_RejectsWhileOpen(t *testing.T) {
+	cb, advance := newTestCircuitBreaker(nil)
+	cb.Execute("op", failWith(ErrCodeDiskSpace))
+
+	called := false
+	err := cb.Execute("SaveAppUsage", func() error {
+		called = true
+		return nil
+	})
+	if called {
+		t.Error("Expected the operation not to run while the circuit is open")
+	}
+	if !IsUnavailable(err) {
+		t.Fatalf("Expected an unavailable error, got %v", err)
+	}
+	if IsRetryable(err) {
+		t.Error("Expected the unavailable error not to be retryable")
+	}
+	if status := cb.Status(); status.Rejected != 1 {
+		t.Errorf("Rejected = %d, want 1", status.Rejected)
+	}
+
+	advance(30 * time.Second)
+	if err := cb.Execute("op", succeed); !IsUnavailable(err) {
+		t.Errorf("Expected calls before the open timeout to be rejected, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenTrial(t *testing.T) {
+	cb, advance := newTestCircuitBreaker(nil)
+	cb.Execute("op", failWith(ErrCodeDiskSpace))
+
+	advance(time.Minute)
+	if state := cb.State(); state != CircuitHalfOpen {
+		t.Fatalf("State after the open timeout = %s, want half_open", state)
+	}
+
+	// A failed trial reopens the circuit for twice as long
+	cb.Execute("op", failWith(ErrCodeDiskSpace))
+	status := cb.Status()
+	if status.State != CircuitOpen {
+		t.Fatalf("State after a failed trial = %s, want open", status.State)
+	}
+	if wait := status.RetryAt.Sub(status.OpenedAt); wait != 2*time.Minute {
+		t.Errorf("Open timeout after a failed trial = %s, want 2m", wait)
+	}
+
+	advance(2 * time.Minute)
+	if err := cb.Execute("op", succeed); err != nil {
+		t.Fatalf("Trial call failed: %v", err)
+	}
+	status = cb.Status()
+	if status.State != CircuitClosed || status.Failures != 0 || status.LastError != "" {
+		t.Errorf("Status after a successful trial = %+v, want closed and cleared", status)
+	}
+
+	// The timeout starts over once the circuit has closed
+	cb.Execute("op", failWith(ErrCodeDiskSpace))
+	if status := cb.Status(); status.RetryAt.Sub(status.OpenedAt) != time.Minute {
+		t.Errorf("Open timeout after closing = %s, want 1m", status.RetryAt.Sub(status.OpenedAt))
+	}
+}
+
+func TestCircuitBreaker_HalfOpenAllowsOneTrial(t *testing.T) {
+	cb, advance := newTestCircuitBreaker(nil)
+	cb.Execute("op", failWith(ErrCodeCorruption))
+	advance(time.Minute)
+
+	err := cb.Execute("trial", func() error {
+		if err := cb.Execute("other", succeed); !IsUnavailable(err) {
+			t.Errorf("Expected a second call during the trial to be rejected, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Trial call failed: %v", err)
+	}
+	if state := cb.State(); state != CircuitClosed {
+		t.Errorf("State = %s, want closed", state)
+	}
+}
+
+func TestCircuitBreaker_ResetAndStateChanges(t *testing.T) {
+	cb, _ := newTestCircuitBreaker(nil)
+
+	changes := make(chan CircuitState, 4)
+	cb.SetStateChangeHandler(func(status CircuitStatus) {
+		changes <- status.State
+	})
+
+	cb.Execute("op", failWith(ErrCodeDiskSpace))
+	cb.Reset()
+
+	for _, want := range []CircuitState{CircuitOpen, CircuitClosed} {
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("state change = %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected a change to %s to be reported", want)
+		}
+	}
+	if err := cb.Execute("op", succeed); err != nil {
+		t.Errorf("Expected calls to run after Reset, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_ClassifiesPlainErrors(t *testing.T) {
+	cb, _ := newTestCircuitBreaker(&CircuitBreakerConfig{
+		FailureThresholds: map[ErrorCode]int{ErrCodeDiskSpace: 1},
+		OpenTimeout:       time.Minute,
+		MaxOpenTimeout:    time.Minute,
+	})
+
+	cb.Execute("op", func() error { return errors.New("write failed: no space left on device") })
+	if state := cb.State(); state != CircuitOpen {
+		t.Errorf("State = %s, want open", state)
+	}
+}
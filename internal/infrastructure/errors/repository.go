@@ -55,6 +55,8 @@ func ClassifyError(err error) ErrorCode {
 		return ErrCodeDiskSpace
 	case strings.Contains(errStr, "no space left"):
 		return ErrCodeDiskSpace
+	case strings.Contains(errStr, "database is closed"):
+		return ErrCodeConnection
 	case strings.Contains(errStr, "connection refused"):
 		return ErrCodeConnection
 	case strings.Contains(errStr, "network unreachable"):
@@ -28,6 +28,7 @@ const (
 	ErrCodeInternal
 	ErrCodeBusy
 	ErrCodeSchema
+	ErrCodeUnavailable
 )
 
 // String returns a string representation of the error code
@@ -63,6 +64,8 @@ func (e ErrorCode) String() string {
 		return "BUSY"
 	case ErrCodeSchema:
 		return "SCHEMA"
+	case ErrCodeUnavailable:
+		return "UNAVAILABLE"
 	default:
 		return "UNKNOWN"
 	}
@@ -238,6 +241,9 @@ func isRetryableError(code ErrorCode, err error) bool {
 		return false
 	case ErrCodeNotFound, ErrCodeDuplicate, ErrCodeConstraint, ErrCodeValidation, ErrCodePermission, ErrCodeCorruption, ErrCodeInternal, ErrCodeSchema:
 		return false
+	case ErrCodeUnavailable:
+		// Storage was given up on by a circuit breaker; retrying right away would defeat it
+		return false
 	case ErrCodeDiskSpace:
 		// Disk space errors are non-retryable by default as they require external intervention
 		// (cleanup, adding storage). Can be made retryable via configuration if needed.
@@ -348,6 +354,15 @@ func IsDiskSpace(err error) bool {
 	return false
 }
 
+// IsUnavailable checks if the error is a call rejected because storage is unavailable
+func IsUnavailable(err error) bool {
+	var repoErr *RepositoryError
+	if errors.As(err, &repoErr) {
+		return repoErr.Code == ErrCodeUnavailable
+	}
+	return false
+}
+
 // IsCorruption checks if the error is a corruption error
 func IsCorruption(err error) bool {
 	var repoErr *RepositoryError
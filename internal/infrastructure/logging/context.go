@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+)
+
+// fieldsKey is the context key holding logging fields
+type fieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying fields, given as alternating keys and
+// values, in addition to those ctx already carries. Loggers returned by FromContext add
+// them to every entry, so all entries of one operation can be correlated.
+func ContextWithFields(ctx context.Context, fields ...interface{}) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	existing := FieldsFromContext(ctx)
+	combined := make([]interface{}, 0, len(existing)+len(fields))
+	combined = append(combined, existing...)
+	combined = append(combined, fields...)
+	return context.WithValue(ctx, fieldsKey{}, combined)
+}
+
+// FieldsFromContext returns the fields carried by ctx, oldest first
+func FieldsFromContext(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	return fields
+}
+
+// contextValue returns the value ctx carries for key, if any
+func contextValue(ctx context.Context, key string) (interface{}, bool) {
+	fields := FieldsFromContext(ctx)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == key {
+			return fields[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// operationCounter makes IDs unique should the random source fail
+var operationCounter atomic.Uint64
+
+// NewOperationID returns a short random ID for correlating the entries of one operation
+func NewOperationID() string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("op-%d", operationCounter.Add(1))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// WithOperationID returns a copy of ctx carrying a new operation ID under key, e.g.
+// "persist_id", along with the ID. When ctx already carries an ID under key it is kept,
+// so nested calls log under the ID of the operation that started them.
+func WithOperationID(ctx context.Context, key string) (context.Context, string) {
+	if value, ok := contextValue(ctx, key); ok {
+		return ctx, fmt.Sprint(value)
+	}
+	id := NewOperationID()
+	return ContextWithFields(ctx, key, id), id
+}
+
+// FromContext returns a logger adding the fields carried by ctx to every entry, or logger
+// itself when ctx carries none
+func FromContext(ctx context.Context, logger Logger) Logger {
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 || logger == nil {
+		return logger
+	}
+	if slogLogger, ok := logger.(*SlogLogger); ok {
+		if _, ok := slogLogger.Handler().(*ContextHandler); ok {
+			// The handler adds the fields itself once it sees the context
+			return &slogContextLogger{logger: slogLogger, ctx: ctx}
+		}
+	}
+	return &fieldsLogger{next: logger, fields: fields}
+}
+
+// fieldsLogger adds a fixed set of fields after those of each entry
+type fieldsLogger struct {
+	next   Logger
+	fields []interface{}
+}
+
+var _ ComponentLogger = (*fieldsLogger)(nil)
+
+// Component returns a child logger for the named component, keeping the fields
+func (l *fieldsLogger) Component(name string) Logger {
+	return &fieldsLogger{next: ForComponent(l.next, name), fields: l.fields}
+}
+
+func (l *fieldsLogger) Debug(msg string, fields ...interface{}) {
+	l.next.Debug(msg, l.with(fields)...)
+}
+
+func (l *fieldsLogger) Info(msg string, fields ...interface{}) {
+	l.next.Info(msg, l.with(fields)...)
+}
+
+func (l *fieldsLogger) Warn(msg string, fields ...interface{}) {
+	l.next.Warn(msg, l.with(fields)...)
+}
+
+func (l *fieldsLogger) Error(msg string, fields ...interface{}) {
+	l.next.Error(msg, l.with(fields)...)
+}
+
+// with appends the logger's fields to an entry's, copying so callers' slices aren't modified
+func (l *fieldsLogger) with(fields []interface{}) []interface{} {
+	combined := make([]interface{}, 0, len(fields)+len(l.fields))
+	combined = append(combined, fields...)
+	return append(combined, l.fields...)
+}
+
+// slogContextLogger logs through a SlogLogger with a fixed context, for handlers that take
+// fields from the context
+type slogContextLogger struct {
+	logger *SlogLogger
+	ctx    context.Context
+}
+
+var _ ComponentLogger = (*slogContextLogger)(nil)
+
+func (l *slogContextLogger) Component(name string) Logger {
+	return &slogContextLogger{logger: l.logger.Component(name).(*SlogLogger), ctx: l.ctx}
+}
+
+func (l *slogContextLogger) Debug(msg string, fields ...interface{}) {
+	l.logger.LogContext(l.ctx, LevelDebug, msg, fields...)
+}
+
+func (l *slogContextLogger) Info(msg string, fields ...interface{}) {
+	l.logger.LogContext(l.ctx, LevelInfo, msg, fields...)
+}
+
+func (l *slogContextLogger) Warn(msg string, fields ...interface{}) {
+	l.logger.LogContext(l.ctx, LevelWarn, msg, fields...)
+}
+
+func (l *slogContextLogger) Error(msg string, fields ...interface{}) {
+	l.logger.LogContext(l.ctx, LevelError, msg, fields...)
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestWithOperationID_KeepsOuterID(t *testing.T) {
+	ctx, persistID := WithOperationID(context.Background(), "persist_id")
+	if persistID == "" {
+		t.Fatal("Expected an operation ID")
+	}
+
+	nested, nestedID := WithOperationID(ctx, "persist_id")
+	if nestedID != persistID || nested != ctx {
+		t.Errorf("nested ID = %s, want the outer %s", nestedID, persistID)
+	}
+
+	ctx, batchID := WithOperationID(ctx, "batch_id")
+	if batchID == persistID {
+		t.Error("Expected a distinct ID under another key")
+	}
+	fields := FieldsFromContext(ctx)
+	if len(fields) != 4 || fields[1] != persistID || fields[3] != batchID {
+		t.Errorf("fields = %v", fields)
+	}
+}
+
+func TestFromContext_AddsFieldsToEntries(t *testing.T) {
+	var console bytes.Buffer
+	base, err := NewLeveledLogger(&LoggerConfig{Level: LevelDebug, Console: true, ConsoleWriter: &console})
+	if err != nil {
+		t.Fatalf("NewLeveledLogger failed: %v", err)
+	}
+
+	if FromContext(context.Background(), base) != Logger(base) {
+		t.Error("Expected the logger unchanged for a context without fields")
+	}
+
+	ctx := ContextWithFields(context.Background(), "batch_id", "abc")
+	fields := []interface{}{"rows", 2}
+	ForComponent(FromContext(ctx, base), "repository").Info("saved", fields...)
+	if len(fields) != 2 {
+		t.Errorf("caller's fields were modified: %v", fields)
+	}
+
+	entries := readEntries(t, console.Bytes())
+	if len(entries) != 1 {
+		t.Fatalf("Got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Component != "repository" || entry.Fields["batch_id"] != "abc" || entry.Fields["rows"] != float64(2) {
+		t.Errorf("entry = %+v", entry)
+	}
+}
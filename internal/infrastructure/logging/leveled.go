@@ -0,0 +1,259 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ComponentLogger is implemented by loggers that can create child loggers tagging every
+// message with the component that logged it
+type ComponentLogger interface {
+	Logger
+	Component(name string) Logger
+}
+
+// ForComponent returns a child logger for the named component, or logger itself when it
+// can't create child loggers
+func ForComponent(logger Logger, name string) Logger {
+	if componentLogger, ok := logger.(ComponentLogger); ok {
+		return componentLogger.Component(name)
+	}
+	return logger
+}
+
+// LoggerConfig holds configuration for a LeveledLogger
+type LoggerConfig struct {
+	Level    Level
+	FilePath string         // Empty writes no log file
+	Rotation RotationConfig // How FilePath is rotated
+	Console  bool           // Mirror entries to the console
+	// ConsoleWriter receives console entries; nil means standard error
+	ConsoleWriter io.Writer
+}
+
+// DefaultLoggerConfig returns a logger configuration writing info and above to filePath,
+// rotated daily or at 10 MB, with two weeks of compressed history
+func DefaultLoggerConfig(filePath string) *LoggerConfig {
+	return &LoggerConfig{
+		Level:    LevelInfo,
+		FilePath: filePath,
+		Rotation: RotationConfig{
+			MaxSizeBytes: 10 * 1024 * 1024,
+			RotateDaily:  true,
+			MaxAge:       14 * 24 * time.Hour,
+			MaxBackups:   20,
+			Compress:     true,
+		},
+	}
+}
+
+// DefaultLogDir returns where logs for appName are kept: the local app data folder on
+// Windows, ~/Library/Logs on macOS, and $XDG_STATE_HOME (~/.local/state) elsewhere
+func DefaultLogDir(appName string) (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, appName, "logs"), nil
+		}
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Logs", appName), nil
+	default:
+		if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+			return filepath.Join(dir, appName, "logs"), nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "state", appName, "logs"), nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appName, "logs"), nil
+}
+
+// LeveledLogger writes structured JSON entries at or above a level that can be changed at
+// runtime, to a rotating file and optionally the console. Child loggers created with
+// Component share its outputs and level.
+type LeveledLogger struct {
+	core      *leveledCore
+	component string
+}
+
+// leveledCore is the state shared by a logger and its children
+type leveledCore struct {
+	level   atomic.Int32
+	mutex   sync.Mutex // serializes writes so entries don't interleave
+	file    *RotatingFile
+	console io.Writer
+}
+
+// leveledEntry is one line written by a LeveledLogger
+type leveledEntry struct {
+	Timestamp string                 `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Component string                 `json:"component,omitempty"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+var _ ComponentLogger = (*LeveledLogger)(nil)
+
+// NewLeveledLogger creates a logger writing to the configured file and console
+func NewLeveledLogger(config *LoggerConfig) (*LeveledLogger, error) {
+	if config == nil {
+		config = &LoggerConfig{Level: LevelInfo, Console: true}
+	}
+
+	core := &leveledCore{}
+	core.level.Store(int32(config.Level))
+	if config.FilePath != "" {
+		file, err := OpenRotatingFile(config.FilePath, config.Rotation)
+		if err != nil {
+			return nil, err
+		}
+		core.file = file
+	}
+	if config.Console {
+		core.console = config.ConsoleWriter
+		if core.console == nil {
+			core.console = os.Stderr
+		}
+	}
+	return &LeveledLogger{core: core}, nil
+}
+
+// Component returns a child logger adding a component field to its entries. Nested
+// components are joined with dots.
+func (l *LeveledLogger) Component(name string) Logger {
+	if l.component != "" {
+		name = l.component + "." + name
+	}
+	return &LeveledLogger{core: l.core, component: name}
+}
+
+// SetLevel changes the lowest level written, for this logger and all its children
+func (l *LeveledLogger) SetLevel(level Level) {
+	l.core.level.Store(int32(level))
+}
+
+// Level returns the lowest level written
+func (l *LeveledLogger) Level() Level {
+	return Level(l.core.level.Load())
+}
+
+// Enabled reports whether messages at level are written
+func (l *LeveledLogger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+// FilePath returns the active log file, or an empty string when logging to the console only
+func (l *LeveledLogger) FilePath() string {
+	if l.core.file == nil {
+		return ""
+	}
+	return l.core.file.Path()
+}
+
+// LogFiles returns the active log file followed by its rotated predecessors, newest first.
+// It returns nothing when logging to the console only.
+func (l *LeveledLogger) LogFiles() ([]string, error) {
+	if l.core.file == nil {
+		return nil, nil
+	}
+	rotated, err := l.core.file.RotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	files := []string{l.core.file.Path()}
+	for i := len(rotated) - 1; i >= 0; i-- {
+		files = append(files, rotated[i])
+	}
+	return files, nil
+}
+
+// Close closes the log file; entries logged afterwards go to the console or standard error
+func (l *LeveledLogger) Close() error {
+	if l.core.file == nil {
+		return nil
+	}
+	return l.core.file.Close()
+}
+
+func (l *LeveledLogger) Debug(msg string, fields ...interface{}) {
+	l.log(LevelDebug, msg, fields)
+}
+
+func (l *LeveledLogger) Info(msg string, fields ...interface{}) {
+	l.log(LevelInfo, msg, fields)
+}
+
+func (l *LeveledLogger) Warn(msg string, fields ...interface{}) {
+	l.log(LevelWarn, msg, fields)
+}
+
+func (l *LeveledLogger) Error(msg string, fields ...interface{}) {
+	l.log(LevelError, msg, fields)
+}
+
+// log writes an entry if its level is enabled
+func (l *LeveledLogger) log(level Level, msg string, fields []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	values := fieldsToMap(fields)
+	for key, value := range values {
+		// Errors have no exported fields and would marshal as {}
+		if err, ok := value.(error); ok {
+			values[key] = err.Error()
+		}
+	}
+
+	line, err := json.Marshal(leveledEntry{
+		Timestamp: time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Level:     level.String(),
+		Component: l.component,
+		Message:   msg,
+		Fields:    values,
+	})
+	if err != nil {
+		line, _ = json.Marshal(leveledEntry{
+			Timestamp: time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Level:     level.String(),
+			Component: l.component,
+			Message:   msg,
+			Fields: map[string]interface{}{
+				"original_fields": fmt.Sprintf("%v", fields),
+				"marshal_error":   err.Error(),
+			},
+		})
+	}
+	line = append(line, '\n')
+
+	l.core.mutex.Lock()
+	defer l.core.mutex.Unlock()
+	if l.core.file != nil {
+		if _, err := l.core.file.Write(line); err != nil && l.core.console == nil {
+			// Don't lose the entry silently when the file is the only output
+			os.Stderr.Write(line)
+		}
+	}
+	if l.core.console != nil {
+		l.core.console.Write(line)
+	}
+}
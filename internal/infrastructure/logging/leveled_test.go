@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readEntries decodes the JSON lines written by a LeveledLogger
+func readEntries(t *testing.T, data []byte) []leveledEntry {
+	t.Helper()
+	var entries []leveledEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var entry leveledEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"debug":   LevelDebug,
+		"INFO":    LevelInfo,
+		"":        LevelInfo,
+		"warning": LevelWarn,
+		" warn ":  LevelWarn,
+		"Error":   LevelError,
+	}
+	for name, want := range tests {
+		got, err := ParseLevel(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %s, %v; want %s", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestLeveledLogger_FiltersByLevelAtRuntime(t *testing.T) {
+	var console bytes.Buffer
+	logger, err := NewLeveledLogger(&LoggerConfig{Level: LevelWarn, Console: true, ConsoleWriter: &console})
+	if err != nil {
+		t.Fatalf("NewLeveledLogger failed: %v", err)
+	}
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+
+	// Children share the level, so lowering it applies to them too
+	child := logger.Component("tracker")
+	logger.SetLevel(LevelDebug)
+	child.Debug("child debug")
+
+	entries := readEntries(t, console.Bytes())
+	if len(entries) != 3 {
+		t.Fatalf("Got %d entries, want 3: %+v", len(entries), entries)
+	}
+	if entries[0].Level != "WARN" || entries[1].Level != "ERROR" || entries[2].Message != "child debug" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestLeveledLogger_ComponentsAndFields(t *testing.T) {
+	var console bytes.Buffer
+	logger, err := NewLeveledLogger(&LoggerConfig{Level: LevelDebug, Console: true, ConsoleWriter: &console})
+	if err != nil {
+		t.Fatalf("NewLeveledLogger failed: %v", err)
+	}
+
+	ForComponent(logger, "repository").Error("write failed", "error", errors.New("disk is full"), "rows", 3)
+	ForComponent(ForComponent(logger, "repository"), "batch").Info("nested")
+	logger.Info("root")
+
+	entries := readEntries(t, console.Bytes())
+	if len(entries) != 3 {
+		t.Fatalf("Got %d entries, want 3", len(entries))
+	}
+	if entries[0].Component != "repository" || entries[0].Fields["error"] != "disk is full" || entries[0].Fields["rows"] != float64(3) {
+		t.Errorf("component entry = %+v", entries[0])
+	}
+	if entries[1].Component != "repository.batch" {
+		t.Errorf("nested component = %q, want repository.batch", entries[1].Component)
+	}
+	if entries[2].Component != "" {
+		t.Errorf("root component = %q, want none", entries[2].Component)
+	}
+}
+
+func TestLeveledLogger_WritesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "qwin.log")
+	logger, err := NewLeveledLogger(&LoggerConfig{Level: LevelInfo, FilePath: path})
+	if err != nil {
+		t.Fatalf("NewLeveledLogger failed: %v", err)
+	}
+	if logger.FilePath() != path {
+		t.Errorf("FilePath() = %q, want %q", logger.FilePath(), path)
+	}
+
+	logger.Info("to file", "key", "value")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	files, err := logger.LogFiles()
+	if err != nil || len(files) != 1 || files[0] != path {
+		t.Errorf("LogFiles() = %v, %v; want only the active file", files, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	entries := readEntries(t, data)
+	if len(entries) != 1 || entries[0].Message != "to file" || entries[0].Fields["key"] != "value" {
+		t.Errorf("file entries = %+v", entries)
+	}
+}
+
+func TestForComponent_PlainLoggerIsReturnedAsIs(t *testing.T) {
+	logger := NewDefaultLogger()
+	if ForComponent(logger, "tracker") != logger {
+		t.Error("Expected a logger without components to be returned unchanged")
+	}
+}
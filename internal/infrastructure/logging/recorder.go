@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RecordedError is a warning or error logged through a RecordingLogger
+type RecordedError struct {
+	Time      time.Time         `json:"time"`
+	Level     string            `json:"level"`
+	Component string            `json:"component,omitempty"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// RecordingLogger passes everything on to another logger and keeps the most recent
+// warnings and errors, so they can be shown in status reports
+type RecordingLogger struct {
+	next      Logger
+	component string
+	buffer    *recordBuffer // shared with child loggers
+}
+
+// recordBuffer holds the most recent entries of a recording logger and its children
+type recordBuffer struct {
+	capacity int
+	mutex    sync.Mutex
+	recent   []RecordedError // oldest first, at most capacity entries
+}
+
+var _ ComponentLogger = (*RecordingLogger)(nil)
+
+// NewRecordingLogger creates a logger keeping the last capacity warnings and errors logged to next
+func NewRecordingLogger(next Logger, capacity int) *RecordingLogger {
+	if next == nil {
+		next = NewDefaultLogger()
+	}
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &RecordingLogger{next: next, buffer: &recordBuffer{capacity: capacity}}
+}
+
+// Component returns a child logger for the named component, recording into the same buffer
+func (l *RecordingLogger) Component(name string) Logger {
+	component := name
+	if l.component != "" {
+		component = l.component + "." + name
+	}
+	return &RecordingLogger{next: ForComponent(l.next, name), component: component, buffer: l.buffer}
+}
+
+func (l *RecordingLogger) Debug(msg string, fields ...interface{}) {
+	l.next.Debug(msg, fields...)
+}
+
+func (l *RecordingLogger) Info(msg string, fields ...interface{}) {
+	l.next.Info(msg, fields...)
+}
+
+func (l *RecordingLogger) Warn(msg string, fields ...interface{}) {
+	l.record("WARN", msg, fields)
+	l.next.Warn(msg, fields...)
+}
+
+func (l *RecordingLogger) Error(msg string, fields ...interface{}) {
+	l.record("ERROR", msg, fields)
+	l.next.Error(msg, fields...)
+}
+
+// RecentErrors returns the recorded warnings and errors, newest first
+func (l *RecordingLogger) RecentErrors() []RecordedError {
+	b := l.buffer
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	recent := make([]RecordedError, len(b.recent))
+	for i, entry := range b.recent {
+		recent[len(b.recent)-1-i] = entry
+	}
+	return recent
+}
+
+// record keeps an entry, dropping the oldest once capacity is reached
+func (l *RecordingLogger) record(level, msg string, fields []interface{}) {
+	entry := RecordedError{
+		Time:      time.Now(),
+		Level:     level,
+		Component: l.component,
+		Message:   msg,
+	}
+	// Values are formatted now; errors and other values may not marshal on their own
+	if values := fieldsToMap(fields); len(values) > 0 {
+		entry.Fields = make(map[string]string, len(values))
+		for key, value := range values {
+			entry.Fields[key] = fmt.Sprint(value)
+		}
+	}
+
+	b := l.buffer
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.recent) == b.capacity {
+		b.recent = append(b.recent[:0], b.recent[1:]...)
+	}
+	b.recent = append(b.recent, entry)
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// countingLogger counts the messages passed on to it per level
+type countingLogger struct {
+	counts map[string]int
+}
+
+func (c *countingLogger) Debug(msg string, fields ...interface{}) { c.counts["DEBUG"]++ }
+func (c *countingLogger) Info(msg string, fields ...interface{})  { c.counts["INFO"]++ }
+func (c *countingLogger) Warn(msg string, fields ...interface{})  { c.counts["WARN"]++ }
+func (c *countingLogger) Error(msg string, fields ...interface{}) { c.counts["ERROR"]++ }
+
+func TestRecordingLogger_KeepsRecentWarningsAndErrors(t *testing.T) {
+	next := &countingLogger{counts: make(map[string]int)}
+	logger := NewRecordingLogger(next, 2)
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("first warning")
+	logger.Error("failed to persist", "error", errors.New("disk is full"), "days", 2)
+	logger.Error("second error")
+
+	for _, level := range []string{"DEBUG", "INFO", "WARN", "ERROR"} {
+		want := 1
+		if level == "ERROR" {
+			want = 2
+		}
+		if next.counts[level] != want {
+			t.Errorf("%s messages passed on = %d, want %d", level, next.counts[level], want)
+		}
+	}
+
+	recent := logger.RecentErrors()
+	if len(recent) != 2 {
+		t.Fatalf("RecentErrors() returned %d entries, want 2", len(recent))
+	}
+	if recent[0].Message != "second error" || recent[1].Message != "failed to persist" {
+		t.Errorf("RecentErrors() = %+v, want newest first with the oldest dropped", recent)
+	}
+
+	fields := recent[1].Fields
+	if recent[1].Level != "ERROR" || fields["error"] != "disk is full" || fields["days"] != "2" {
+		t.Errorf("recorded entry = %+v", recent[1])
+	}
+}
+
+func TestRecordingLogger_RecentErrorsIsACopy(t *testing.T) {
+	logger := NewRecordingLogger(&countingLogger{counts: make(map[string]int)}, 3)
+	logger.Warn("warning")
+
+	recent := logger.RecentErrors()
+	recent[0].Message = "changed"
+	for i := 0; i < 3; i++ {
+		logger.Warn(fmt.Sprintf("warning %d", i))
+	}
+
+	if recent[0].Message != "changed" || logger.RecentErrors()[2].Message != "warning 0" {
+		t.Errorf("RecentErrors() shares storage with the logger")
+	}
+}
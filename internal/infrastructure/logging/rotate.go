@@ -0,0 +1,298 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat is the timestamp added to rotated file names; it sorts chronologically
+const rotatedTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotationConfig controls when a RotatingFile starts a new file and which old files it keeps
+type RotationConfig struct {
+	MaxSizeBytes int64         // Rotate before the file grows past this size; zero disables size rotation
+	RotateDaily  bool          // Start a new file on the first write of each day
+	MaxAge       time.Duration // Delete rotated files older than this; zero keeps them regardless of age
+	MaxBackups   int           // Number of rotated files to keep; zero keeps them all
+	Compress     bool          // Gzip rotated files
+}
+
+// RotatingFile is an append-only file that is rotated by size and age. Rotated files are
+// renamed with a timestamp next to the active one, optionally compressed in the background,
+// and pruned by age and count.
+type RotatingFile struct {
+	path   string
+	config RotationConfig
+	now    func() time.Time
+
+	mutex     sync.Mutex
+	file      *os.File
+	size      int64
+	startedAt time.Time // when the active file was started, for daily rotation
+
+	maintenance sync.Mutex     // serializes compression and pruning
+	background  sync.WaitGroup // compression and pruning still running
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory if needed
+func OpenRotatingFile(path string, config RotationConfig) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, config: config, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.openLocked(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the path of the active file
+func (f *RotatingFile) Path() string {
+	return f.path
+}
+
+// Write appends p to the active file, rotating first if p would take it past the size
+// limit or a new day has started
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotateLocked(int64(len(p))) {
+		if err := f.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate starts a new file straight away
+func (f *RotatingFile) Rotate() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotateLocked()
+}
+
+// Close closes the active file and waits for background compression to finish
+func (f *RotatingFile) Close() error {
+	f.mutex.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mutex.Unlock()
+
+	f.background.Wait()
+	return err
+}
+
+// openLocked opens the active file, rotating a leftover file from an earlier day first
+// Must be called with f.mutex held
+func (f *RotatingFile) openLocked() error {
+	if info, err := os.Stat(f.path); err == nil && info.Size() > 0 {
+		// The file was last written when its day was current
+		f.startedAt = info.ModTime()
+		f.size = info.Size()
+		if f.config.RotateDaily && !sameDay(f.startedAt, f.now()) {
+			if err := f.renameActive(); err != nil {
+				return err
+			}
+			f.size = 0
+			f.startedAt = f.now()
+		}
+	} else {
+		f.size = 0
+		f.startedAt = f.now()
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	f.file = file
+	return nil
+}
+
+// shouldRotateLocked reports whether writing n more bytes needs a new file first
+// Must be called with f.mutex held
+func (f *RotatingFile) shouldRotateLocked(n int64) bool {
+	if f.size > 0 && f.config.MaxSizeBytes > 0 && f.size+n > f.config.MaxSizeBytes {
+		return true
+	}
+	return f.config.RotateDaily && !sameDay(f.startedAt, f.now())
+}
+
+// rotateLocked closes the active file, renames it aside and opens a new one
+// Must be called with f.mutex held
+func (f *RotatingFile) rotateLocked() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	if err := f.renameActive(); err != nil {
+		// Keep writing to the old file rather than losing messages
+		file, openErr := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if openErr == nil {
+			f.file = file
+		}
+		return err
+	}
+
+	f.size = 0
+	f.startedAt = f.now()
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	f.file = file
+	return nil
+}
+
+// renameActive moves the active file aside and compresses and prunes rotated files in the background
+func (f *RotatingFile) renameActive() error {
+	now := f.now()
+	ext := filepath.Ext(f.path)
+	rotated := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(f.path, ext), now.Format(rotatedTimeFormat), ext)
+	if err := os.Rename(f.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	f.background.Add(1)
+	go func() {
+		defer f.background.Done()
+		f.maintenance.Lock()
+		defer f.maintenance.Unlock()
+
+		if f.config.Compress {
+			if err := compressFile(rotated); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to compress rotated log %s: %v\n", rotated, err)
+			}
+		}
+		f.prune(now)
+	}()
+	return nil
+}
+
+// RotatedFiles returns the rotated files kept next to the active one, oldest first
+func (f *RotatingFile) RotatedFiles() ([]string, error) {
+	dir := filepath.Dir(f.path)
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var rotated []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err == nil {
+			rotated = append(rotated, filepath.Join(dir, name))
+		}
+	}
+	// The timestamp in the name sorts chronologically
+	sort.Strings(rotated)
+	return rotated, nil
+}
+
+// prune deletes rotated files beyond the configured count, or older than the configured age as of now
+// Must be called with f.maintenance held
+func (f *RotatingFile) prune(now time.Time) {
+	rotated, err := f.RotatedFiles()
+	if err != nil {
+		return
+	}
+
+	keep := len(rotated)
+	if f.config.MaxBackups > 0 && keep > f.config.MaxBackups {
+		keep = f.config.MaxBackups
+	}
+	cutoff := now.Add(-f.config.MaxAge)
+	for i, path := range rotated {
+		expired := i < len(rotated)-keep
+		if !expired && f.config.MaxAge > 0 {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				expired = true
+			}
+		}
+		if expired {
+			os.Remove(path)
+		}
+	}
+}
+
+// compressFile gzips path into path.gz and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	gz.Name = filepath.Base(path)
+	gz.ModTime = info.ModTime()
+
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Keep the rotation time so pruning by age still works on the compressed file
+	os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// sameDay reports whether two times fall on the same local calendar day
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Local().Date()
+	by, bm, bd := b.Local().Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,184 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRotatingFile opens a rotating file whose clock is advanced by the returned function
+func newTestRotatingFile(t *testing.T, path string, config RotationConfig) (*RotatingFile, func(time.Duration)) {
+	t.Helper()
+	f, err := OpenRotatingFile(path, config)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	now := time.Now()
+	f.mutex.Lock()
+	f.now = func() time.Time { return now }
+	f.startedAt = now
+	f.mutex.Unlock()
+
+	// Rotated names are timestamped to the millisecond, so tests move the clock
+	return f, func(d time.Duration) {
+		f.mutex.Lock()
+		now = now.Add(d)
+		f.mutex.Unlock()
+	}
+}
+
+func writeString(t *testing.T, f *RotatingFile, s string) {
+	t.Helper()
+	if _, err := f.Write([]byte(s)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+}
+
+func TestRotatingFile_RotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qwin.log")
+	f, advance := newTestRotatingFile(t, path, RotationConfig{MaxSizeBytes: 10})
+
+	writeString(t, f, "12345678\n")
+	advance(time.Second)
+	writeString(t, f, "second\n") // would pass 10 bytes, so goes to a new file
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := f.RotatedFiles()
+	if err != nil || len(rotated) != 1 {
+		t.Fatalf("RotatedFiles() = %v, %v; want one file", rotated, err)
+	}
+	if data, _ := os.ReadFile(rotated[0]); string(data) != "12345678\n" {
+		t.Errorf("rotated file = %q", data)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "second\n" {
+		t.Errorf("active file = %q", data)
+	}
+}
+
+func TestRotatingFile_RotatesDaily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qwin.log")
+	f, advance := newTestRotatingFile(t, path, RotationConfig{RotateDaily: true})
+
+	writeString(t, f, "today\n")
+	advance(time.Hour)
+	writeString(t, f, "still today\n")
+	advance(24 * time.Hour)
+	writeString(t, f, "tomorrow\n")
+	f.Close()
+
+	rotated, _ := f.RotatedFiles()
+	if len(rotated) != 1 {
+		t.Fatalf("RotatedFiles() = %v, want one file", rotated)
+	}
+	if data, _ := os.ReadFile(rotated[0]); string(data) != "today\nstill today\n" {
+		t.Errorf("rotated file = %q", data)
+	}
+}
+
+func TestRotatingFile_RotatesLeftoverFileFromEarlierDay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qwin.log")
+	if err := os.WriteFile(path, []byte("yesterday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	yesterday := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(path, yesterday, yesterday); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenRotatingFile(path, RotationConfig{RotateDaily: true})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	writeString(t, f, "today\n")
+	f.Close()
+
+	if data, _ := os.ReadFile(path); string(data) != "today\n" {
+		t.Errorf("active file = %q, want only today's entries", data)
+	}
+	if rotated, _ := f.RotatedFiles(); len(rotated) != 1 {
+		t.Errorf("RotatedFiles() = %v, want yesterday's file", rotated)
+	}
+}
+
+func TestRotatingFile_CompressesAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "qwin.log")
+	f, advance := newTestRotatingFile(t, path, RotationConfig{Compress: true, MaxBackups: 2})
+
+	// Unrelated files next to the log are left alone
+	other := filepath.Join(dir, "qwin-notes.log")
+	if err := os.WriteFile(other, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		writeString(t, f, strings.Repeat("x", i+1)+"\n")
+		advance(time.Second)
+		if err := f.Rotate(); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+	}
+	f.Close()
+
+	rotated, _ := f.RotatedFiles()
+	if len(rotated) != 2 {
+		t.Fatalf("RotatedFiles() = %v, want the 2 newest", rotated)
+	}
+	for _, path := range rotated {
+		if !strings.HasSuffix(path, ".log.gz") {
+			t.Errorf("%s was not compressed", path)
+		}
+	}
+
+	// The newest rotated file holds the last write
+	file, err := os.Open(rotated[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Invalid gzip file: %v", err)
+	}
+	if data, _ := io.ReadAll(gz); string(data) != "xxxx\n" {
+		t.Errorf("newest rotated file = %q", data)
+	}
+
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Expected unrelated file to be kept: %v", err)
+	}
+}
+
+func TestRotatingFile_PrunesByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "qwin.log")
+
+	old := filepath.Join(dir, "qwin-2020-01-01T00-00-00.000.log")
+	if err := os.WriteFile(old, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	longAgo := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(old, longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ := newTestRotatingFile(t, path, RotationConfig{MaxAge: 7 * 24 * time.Hour})
+	writeString(t, f, "entry\n")
+	if err := f.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	f.Close()
+
+	rotated, _ := f.RotatedFiles()
+	if len(rotated) != 1 || rotated[0] == old {
+		t.Errorf("RotatedFiles() = %v, want only the new file", rotated)
+	}
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger implements Logger on top of a log/slog handler, so entries can go to any
+// slog backend. Fields are passed to slog as alternating keys and values.
+type SlogLogger struct {
+	logger    *slog.Logger
+	component string
+}
+
+var _ ComponentLogger = (*SlogLogger)(nil)
+
+// NewSlogLogger creates a logger writing to handler. Wrap the handler with
+// NewContextHandler to also receive the fields carried by contexts.
+func NewSlogLogger(handler slog.Handler) *SlogLogger {
+	if handler == nil {
+		handler = slog.Default().Handler()
+	}
+	return &SlogLogger{logger: slog.New(handler)}
+}
+
+// Component returns a child logger adding a component attribute to its records. Nested
+// components are joined with dots.
+func (l *SlogLogger) Component(name string) Logger {
+	if l.component != "" {
+		name = l.component + "." + name
+	}
+	return &SlogLogger{logger: l.logger, component: name}
+}
+
+// Handler returns the handler records are written to
+func (l *SlogLogger) Handler() slog.Handler {
+	return l.logger.Handler()
+}
+
+func (l *SlogLogger) Debug(msg string, fields ...interface{}) {
+	l.log(context.Background(), slog.LevelDebug, msg, fields)
+}
+
+func (l *SlogLogger) Info(msg string, fields ...interface{}) {
+	l.log(context.Background(), slog.LevelInfo, msg, fields)
+}
+
+func (l *SlogLogger) Warn(msg string, fields ...interface{}) {
+	l.log(context.Background(), slog.LevelWarn, msg, fields)
+}
+
+func (l *SlogLogger) Error(msg string, fields ...interface{}) {
+	l.log(context.Background(), slog.LevelError, msg, fields)
+}
+
+// LogContext logs at level with ctx passed to the handler, which NewContextHandler uses
+// to add the context's fields
+func (l *SlogLogger) LogContext(ctx context.Context, level Level, msg string, fields ...interface{}) {
+	l.log(ctx, slogLevel(level), msg, fields)
+}
+
+// log writes a record, with the component first so it reads like LeveledLogger entries
+func (l *SlogLogger) log(ctx context.Context, level slog.Level, msg string, fields []interface{}) {
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	if l.component != "" {
+		fields = append([]interface{}{"component", l.component}, fields...)
+	}
+	l.logger.Log(ctx, level, msg, fields...)
+}
+
+// slogLevel maps a Level to the matching slog level
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// ContextHandler adds the fields carried by a record's context to the record before
+// passing it on, for code logging through slog directly with the *Context methods
+type ContextHandler struct {
+	next slog.Handler
+}
+
+var _ slog.Handler = (*ContextHandler)(nil)
+
+// NewContextHandler wraps next so records logged with a context include its fields
+func NewContextHandler(next slog.Handler) *ContextHandler {
+	return &ContextHandler{next: next}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if fields := FieldsFromContext(ctx); len(fields) > 0 {
+		record = record.Clone()
+		record.Add(fields...)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// readSlogRecords decodes the lines written by a slog JSON handler
+func readSlogRecords(t *testing.T, data []byte) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSlogLogger_LevelsComponentsAndFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewSlogLogger(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	logger.Debug("hidden")
+	ForComponent(ForComponent(logger, "repository"), "batch").Warn("slow", "rows", 3, "error", errors.New("busy"))
+	logger.Error("failed")
+
+	records := readSlogRecords(t, out.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("Got %d records, want 2: %v", len(records), records)
+	}
+	if records[0]["level"] != "WARN" || records[0]["component"] != "repository.batch" ||
+		records[0]["rows"] != float64(3) || records[0]["error"] != "busy" {
+		t.Errorf("component record = %v", records[0])
+	}
+	if records[1]["level"] != "ERROR" || records[1]["component"] != nil {
+		t.Errorf("root record = %v", records[1])
+	}
+}
+
+func TestContextHandler_AddsContextFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewSlogLogger(NewContextHandler(slog.NewJSONHandler(&out, nil)))
+
+	ctx, id := WithOperationID(context.Background(), "persist_id")
+	FromContext(ctx, ForComponent(logger, "tracker")).Info("flushed", "days", 1)
+	slog.New(logger.Handler()).InfoContext(ctx, "direct")
+
+	records := readSlogRecords(t, out.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("Got %d records, want 2", len(records))
+	}
+	for _, record := range records {
+		if record["persist_id"] != id {
+			t.Errorf("record %v lacks persist_id %s", record, id)
+		}
+	}
+	if records[0]["component"] != "tracker" {
+		t.Errorf("component = %v, want tracker", records[0]["component"])
+	}
+}
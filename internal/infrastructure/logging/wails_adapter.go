@@ -5,13 +5,14 @@ type WailsLoggerAdapter struct {
 	logger Logger
 }
 
-// NewWailsLoggerAdapter creates a new Wails logger adapter using our structured logger
+// NewWailsLoggerAdapter creates a new Wails logger adapter using our structured logger.
+// Loggers that support components get a "wails" child logger.
 func NewWailsLoggerAdapter(logger Logger) *WailsLoggerAdapter {
 	if logger == nil {
 		logger = NewDefaultLogger()
 	}
 	return &WailsLoggerAdapter{
-		logger: logger,
+		logger: ForComponent(logger, "wails"),
 	}
 }
 
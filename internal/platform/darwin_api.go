@@ -15,6 +15,11 @@ func NewWindowAPI() WindowAPI {
 	return NewDarwinAPI()
 }
 
+// Backend reports this is a placeholder until AppKit support lands
+func (d *DarwinAPI) Backend() string {
+	return "macos-placeholder"
+}
+
 // GetCurrentAppName gets the name of the currently active application on macOS
 func (d *DarwinAPI) GetCurrentAppName() string {
 	// TODO: Implement using Cocoa/AppKit APIs
@@ -1,14 +1,35 @@
 package platform
 
+import "time"
+
 // WindowAPI defines the interface for platform-specific window operations
 type WindowAPI interface {
 	GetCurrentAppName() string
 	GetCurrentAppInfo() *AppInfo
 }
 
+// ActivityMonitor is optionally implemented by a WindowAPI that can report user presence.
+// Platforms that cannot detect idleness or a locked session simply don't implement it.
+type ActivityMonitor interface {
+	// IdleDuration returns how long it has been since the last user input
+	IdleDuration() time.Duration
+	// IsSessionLocked reports whether the interactive session is currently locked
+	IsSessionLocked() bool
+}
+
+// BackendDescriber is optionally implemented by a WindowAPI to name how it finds the
+// focused application, for status reports
+type BackendDescriber interface {
+	Backend() string
+}
+
 // AppInfo contains information about an application
 type AppInfo struct {
-	Name     string `json:"name"`
-	IconPath string `json:"iconPath"`
-	ExePath  string `json:"exePath"`
+	Name      string `json:"name"`
+	IconPath  string `json:"iconPath"`
+	ExePath   string `json:"exePath"`
+	Publisher string `json:"publisher,omitempty"`
+	Version   string `json:"version,omitempty"`
+	// WindowTitle is the title of the focused window; it is used for ignore rules and never stored
+	WindowTitle string `json:"windowTitle,omitempty"`
 }
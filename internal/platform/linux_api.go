@@ -15,6 +15,11 @@ func NewWindowAPI() WindowAPI {
 	return NewLinuxAPI()
 }
 
+// Backend reports this is a placeholder until X11/Wayland support lands
+func (l *LinuxAPI) Backend() string {
+	return "linux-placeholder"
+}
+
 // GetCurrentAppName gets the name of the currently active application on Linux
 func (l *LinuxAPI) GetCurrentAppName() string {
 	// TODO: Implement using X11/Wayland APIs
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
@@ -24,6 +25,8 @@ var (
 	gdi32                        = windows.NewLazySystemDLL("gdi32.dll")
 	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
 	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	procGetWindowTextW           = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW     = user32.NewProc("GetWindowTextLengthW")
 	procOpenProcess              = kernel32.NewProc("OpenProcess")
 	procCloseHandle              = kernel32.NewProc("CloseHandle")
 	procGetModuleFileNameExW     = psapi.NewProc("GetModuleFileNameExW")
@@ -34,8 +37,15 @@ var (
 	procCreateCompatibleDC       = gdi32.NewProc("CreateCompatibleDC")
 	procDeleteDC                 = gdi32.NewProc("DeleteDC")
 	procDeleteObject             = gdi32.NewProc("DeleteObject")
+	procGetLastInputInfo         = user32.NewProc("GetLastInputInfo")
+	procGetTickCount             = kernel32.NewProc("GetTickCount")
+	procOpenInputDesktop         = user32.NewProc("OpenInputDesktop")
+	procCloseDesktop             = user32.NewProc("CloseDesktop")
 )
 
+// Ensure WindowsAPI implements ActivityMonitor interface
+var _ ActivityMonitor = (*WindowsAPI)(nil)
+
 type ICONINFO struct {
 	fIcon    uint32
 	xHotspot uint32
@@ -58,6 +68,11 @@ type BITMAPINFOHEADER struct {
 	biClrImportant  uint32
 }
 
+type LASTINPUTINFO struct {
+	cbSize uint32
+	dwTime uint32
+}
+
 // WindowsAPI implements WindowAPI for Windows platform
 type WindowsAPI struct{}
 
@@ -71,6 +86,11 @@ func NewWindowAPI() WindowAPI {
 	return NewWindowsAPI()
 }
 
+// Backend names the Win32 foreground window API
+func (w *WindowsAPI) Backend() string {
+	return "win32"
+}
+
 // GetCurrentAppName gets the name of the currently active application
 func (w *WindowsAPI) GetCurrentAppName() string {
 	appInfo := w.GetCurrentAppInfo()
@@ -119,11 +139,65 @@ func (w *WindowsAPI) GetCurrentAppInfo() *AppInfo {
 	// Get icon path (for now, we'll use the exe path as icon source)
 	iconPath := w.extractIconToTemp(exePath)
 
+	publisher, version := w.readVersionInfo(exePath)
+
 	return &AppInfo{
-		Name:     name,
-		IconPath: iconPath,
-		ExePath:  exePath,
+		Name:        name,
+		IconPath:    iconPath,
+		ExePath:     exePath,
+		Publisher:   publisher,
+		Version:     version,
+		WindowTitle: w.windowTitle(hwnd),
+	}
+}
+
+// windowTitle reads the title bar text of a window
+func (w *WindowsAPI) windowTitle(hwnd uintptr) string {
+	length, _, _ := procGetWindowTextLengthW.Call(hwnd)
+	if length == 0 {
+		return ""
+	}
+
+	buffer := make([]uint16, length+1)
+	copied, _, _ := procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)))
+	if copied == 0 {
+		return ""
 	}
+	return windows.UTF16ToString(buffer[:copied])
+}
+
+// readVersionInfo reads the company name and product version from an executable's version resource
+func (w *WindowsAPI) readVersionInfo(exePath string) (publisher, version string) {
+	size, err := windows.GetFileVersionInfoSize(exePath, nil)
+	if err != nil || size == 0 {
+		return "", ""
+	}
+
+	data := make([]byte, size)
+	if err := windows.GetFileVersionInfo(exePath, 0, size, unsafe.Pointer(&data[0])); err != nil {
+		return "", ""
+	}
+
+	// String values are keyed by the first language/code page the resource declares
+	var translation *[2]uint16
+	var translationLen uint32
+	if err := windows.VerQueryValue(unsafe.Pointer(&data[0]), `\VarFileInfo\Translation`,
+		unsafe.Pointer(&translation), &translationLen); err != nil || translationLen < 4 {
+		return "", ""
+	}
+	prefix := fmt.Sprintf(`\StringFileInfo\%04x%04x\`, translation[0], translation[1])
+
+	queryString := func(name string) string {
+		var value *uint16
+		var valueLen uint32
+		if err := windows.VerQueryValue(unsafe.Pointer(&data[0]), prefix+name,
+			unsafe.Pointer(&value), &valueLen); err != nil || valueLen == 0 {
+			return ""
+		}
+		return strings.TrimSpace(windows.UTF16PtrToString(value))
+	}
+
+	return queryString("CompanyName"), queryString("ProductVersion")
 }
 
 // extractIconToTemp extracts the icon from an executable and returns it as base64 data URL
@@ -257,3 +331,30 @@ func (w *WindowsAPI) createImageFromBGRA(data []byte, width, height int) image.I
 
 	return img
 }
+
+// IdleDuration returns the time elapsed since the last keyboard or mouse input
+func (w *WindowsAPI) IdleDuration() time.Duration {
+	var info LASTINPUTINFO
+	info.cbSize = uint32(unsafe.Sizeof(info))
+	ret, _, _ := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		return 0
+	}
+
+	// Both values are 32-bit tick counts, so unsigned subtraction handles wraparound
+	now, _, _ := procGetTickCount.Call()
+	idleMillis := uint32(now) - info.dwTime
+	return time.Duration(idleMillis) * time.Millisecond
+}
+
+// IsSessionLocked reports whether the workstation is locked
+// The input desktop cannot be opened by user processes while the secure desktop is active
+func (w *WindowsAPI) IsSessionLocked() bool {
+	// DESKTOP_SWITCHDESKTOP access right
+	hDesk, _, _ := procOpenInputDesktop.Call(0, 0, 0x0100)
+	if hDesk == 0 {
+		return true
+	}
+	procCloseDesktop.Call(hDesk)
+	return false
+}
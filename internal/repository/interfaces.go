@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	repoerrors "qwin/internal/infrastructure/errors"
 	"qwin/internal/types"
 )
 
@@ -11,6 +12,8 @@ import (
 type UsageRepository interface {
 	// Daily usage operations
 	SaveDailyUsage(ctx context.Context, date time.Time, usage *types.UsageData) error
+	// IncrementDailyUsage adds to the stored daily total, creating the record if needed.
+	IncrementDailyUsage(ctx context.Context, date time.Time, additionalTime int64) error
 	GetDailyUsage(ctx context.Context, date time.Time) (*types.UsageData, error)
 
 	// Application usage operations
@@ -33,7 +36,14 @@ type UsageRepository interface {
 	// - BatchStrategyInsertOnly: insert-only operations, failing on conflicts
 	// - BatchStrategyUpsert: upsert operations, updating existing records on conflicts
 	BatchProcessAppUsage(ctx context.Context, date time.Time, appUsages []types.AppUsage, strategy types.BatchStrategy) error
+	// BatchIncrementAppUsageDurations adds to stored durations, creating missing records.
+	// Increments are applied atomically per app, so concurrent writers don't lose time.
 	BatchIncrementAppUsageDurations(ctx context.Context, date time.Time, increments map[string]int64) error
+	// BatchIncrementAppUsage adds each app's duration like BatchIncrementAppUsageDurations,
+	// attributing it to the application its executable path belongs to before its name.
+	BatchIncrementAppUsage(ctx context.Context, date time.Time, appUsages []types.AppUsage) error
+	// BatchUpdateAppUsageMetadata writes icon and executable paths without changing durations.
+	BatchUpdateAppUsageMetadata(ctx context.Context, date time.Time, appUsages []types.AppUsage) error
 
 	// Pagination for large datasets with metadata
 	// Returns paginated results along with total count for UI rendering without additional queries
@@ -42,3 +52,143 @@ type UsageRepository interface {
 	// Filtered queries for efficiency
 	GetAppUsageByNameAndDateRange(ctx context.Context, appName string, startDate, endDate time.Time) ([]types.AppUsage, error)
 }
+
+// FocusEventRepository defines the interface for the append-only focus event log.
+// The log records tracker state transitions as they happen so that usage can be
+// reconciled after a crash or rebuilt for any day by replaying it.
+type FocusEventRepository interface {
+	AppendFocusEvent(ctx context.Context, event *types.FocusEvent) error
+	// GetFocusEvents retrieves events whose OccurredAt is within [start, end], oldest first.
+	GetFocusEvents(ctx context.Context, start, end time.Time) ([]types.FocusEvent, error)
+	// GetLatestFocusEvent retrieves the most recent event of the given type.
+	// Returns a not found error when no such event has been recorded.
+	GetLatestFocusEvent(ctx context.Context, eventType types.FocusEventType) (*types.FocusEvent, error)
+	// CompactFocusEvents removes heartbeat and checkpoint events older than before.
+	// These events carry no attribution once a newer checkpoint exists, so replay is unaffected.
+	CompactFocusEvents(ctx context.Context, before time.Time) error
+
+	// ReplaceUsageForDate overwrites the daily summary and every app usage row for a date.
+	// Apps not present in usage.Apps are removed.
+	ReplaceUsageForDate(ctx context.Context, date time.Time, usage *types.UsageData) error
+}
+
+// IconRepository defines the interface for content-addressed application icons.
+// Icons are written implicitly when app usage is saved with a data URL icon path.
+type IconRepository interface {
+	// GetIcon retrieves the icon with the given content hash.
+	// Returns a not found error when no such icon is stored.
+	GetIcon(ctx context.Context, hash string) (*types.Icon, error)
+}
+
+// ApplicationRepository defines the interface for canonical application identities.
+// Usage rows reference an application, which can be renamed, merged with another
+// application or split apart to correct misidentified programs retroactively.
+type ApplicationRepository interface {
+	ListApplications(ctx context.Context) ([]types.Application, error)
+	// GetApplication returns a not found error when no application has the given ID.
+	GetApplication(ctx context.Context, id int64) (*types.Application, error)
+	// UpdateApplication saves the display name, alias, publisher and version of an application.
+	UpdateApplication(ctx context.Context, app *types.Application) error
+	// MergeApplications moves the paths and usage history of sourceIDs to targetID and removes the sources.
+	MergeApplications(ctx context.Context, targetID int64, sourceIDs []int64) error
+	// SplitApplication moves the given executable paths, and usage recorded with them, to a new application.
+	SplitApplication(ctx context.Context, id int64, exePaths []string) (*types.Application, error)
+	// GetAppUsageByApplicationAndDateRange retrieves an application's usage within a date range,
+	// newest first, whatever names it was tracked under.
+	GetAppUsageByApplicationAndDateRange(ctx context.Context, applicationID int64, startDate, endDate time.Time) ([]types.AppUsage, error)
+}
+
+// DayBoundaryConfigurable is implemented by repositories that resolve relative dates,
+// such as the last N days of history, so they can follow the tracker's day boundary.
+type DayBoundaryConfigurable interface {
+	SetDayBoundary(boundary types.DayBoundary)
+}
+
+// StorageHealthReporter is implemented by repositories that stop calling storage after it
+// fails repeatedly, so callers can tell the user storage is unavailable.
+type StorageHealthReporter interface {
+	StorageStatus() repoerrors.CircuitStatus
+	// SetStorageStatusHandler registers a function called whenever the status changes state.
+	SetStorageStatusHandler(handler func(repoerrors.CircuitStatus))
+	// ResetStorageStatus treats storage as available again, e.g. after reconnecting.
+	ResetStorageStatus()
+}
+
+// SettingsRepository defines the interface for persisted user preferences
+type SettingsRepository interface {
+	// GetSetting returns a not found error when the key has never been set.
+	GetSetting(ctx context.Context, key string) (string, error)
+	SetSetting(ctx context.Context, key, value string) error
+	DeleteSetting(ctx context.Context, key string) error
+}
+
+// IgnoreRuleRepository defines the interface for rules that keep applications out of usage stats
+type IgnoreRuleRepository interface {
+	ListIgnoreRules(ctx context.Context) ([]types.IgnoreRule, error)
+	// CreateIgnoreRule validates and stores a new rule, filling in its ID and timestamps.
+	CreateIgnoreRule(ctx context.Context, rule *types.IgnoreRule) error
+	// UpdateIgnoreRule returns a not found error when no rule has the rule's ID.
+	UpdateIgnoreRule(ctx context.Context, rule *types.IgnoreRule) error
+	DeleteIgnoreRule(ctx context.Context, id int64) error
+	// ApplyIgnoreRulesToHistory removes stored app usage matching the rules and returns how many rows it removed.
+	// Rows matching an ignore rule also have their time taken off the daily totals; hidden rows keep it.
+	ApplyIgnoreRulesToHistory(ctx context.Context, rules []types.IgnoreRule) (int64, error)
+}
+
+// PrivacyRepository defines the interface for applying a privacy policy to stored history
+type PrivacyRepository interface {
+	// RedactHistory rewrites stored names and executable paths as the redactor would have
+	// recorded them and returns how many rows it changed.
+	RedactHistory(ctx context.Context, redactor *types.PrivacyRedactor) (int64, error)
+}
+
+// DeviceSyncRepository defines the interface for usage synced between devices.
+// Each device appends its usage changes to its own change log; logs read from other
+// devices are merged into a per-device copy of their usage, kept apart from local usage.
+type DeviceSyncRepository interface {
+	// ListSyncDevices returns the local device first, then the devices merged from.
+	ListSyncDevices(ctx context.Context) ([]types.SyncDevice, error)
+	// ApplySyncEntries applies the entries of a device's change log not applied yet and
+	// returns how many it applied. Entries must be ordered by their sequence number.
+	ApplySyncEntries(ctx context.Context, device types.SyncDevice, entries []types.SyncLogEntry) (int, error)
+	// PendingSyncEntries returns the local changes the local device hasn't written to its change log.
+	PendingSyncEntries(ctx context.Context, device types.SyncDevice) ([]types.SyncLogEntry, error)
+	// SyncSnapshot returns a device's usage as of the last change log entry applied from it.
+	SyncSnapshot(ctx context.Context, device types.SyncDevice) (*types.SyncSnapshot, error)
+	// ApplySyncSnapshot replaces a device's usage with a snapshot that is ahead of the
+	// entries applied from it, and reports whether it did.
+	ApplySyncSnapshot(ctx context.Context, device types.SyncDevice, snapshot *types.SyncSnapshot) (bool, error)
+	// ResetSync forgets all synced devices and what the local device has exported.
+	ResetSync(ctx context.Context) error
+
+	// GetAppUsageByDateRangeForDevice and GetUsageHistoryForDevice read the usage of a
+	// device ID, types.DeviceScopeLocal, or types.DeviceScopeAll for all devices combined.
+	GetAppUsageByDateRangeForDevice(ctx context.Context, startDate, endDate time.Time, device string) ([]types.AppUsage, error)
+	GetUsageHistoryForDevice(ctx context.Context, days int, device string) (map[string]*types.UsageData, error)
+}
+
+// InsightRepository defines the interface for stored usage anomaly insights
+type InsightRepository interface {
+	// ReplaceInsightsForDate overwrites every insight stored for a date with the given ones.
+	ReplaceInsightsForDate(ctx context.Context, date time.Time, insights []types.Insight) error
+	// GetInsights retrieves insights dated within [startDate, endDate], newest first and
+	// most significant first within a day.
+	GetInsights(ctx context.Context, startDate, endDate time.Time) ([]types.Insight, error)
+}
+
+// TimeEntryRepository defines the interface for usage added by hand or imported. Entries
+// are stored apart from tracked usage, which they never change, and every change to them
+// is kept in an audit trail.
+type TimeEntryRepository interface {
+	// CreateTimeEntry validates and stores a new entry, filling in its ID and timestamps.
+	CreateTimeEntry(ctx context.Context, entry *types.TimeEntry) error
+	// UpdateTimeEntry returns a not found error when no entry has the entry's ID.
+	UpdateTimeEntry(ctx context.Context, entry *types.TimeEntry) error
+	// DeleteTimeEntry returns a not found error when no entry has the ID.
+	DeleteTimeEntry(ctx context.Context, id int64) error
+	// GetTimeEntries retrieves entries dated within [startDate, endDate], oldest first.
+	GetTimeEntries(ctx context.Context, startDate, endDate time.Time) ([]types.TimeEntry, error)
+	// GetTimeEntryHistory retrieves the changes made to an entry, oldest first, including
+	// those made before it was deleted.
+	GetTimeEntryHistory(ctx context.Context, id int64) ([]types.TimeEntryEdit, error)
+}
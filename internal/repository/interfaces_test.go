@@ -21,6 +21,10 @@ func (m *mockRepository) SaveDailyUsage(ctx context.Context, date time.Time, usa
 	return nil
 }
 
+func (m *mockRepository) IncrementDailyUsage(ctx context.Context, date time.Time, additionalTime int64) error {
+	return nil
+}
+
 func (m *mockRepository) GetDailyUsage(ctx context.Context, date time.Time) (*types.UsageData, error) {
 	return &types.UsageData{}, nil
 }
@@ -57,6 +61,14 @@ func (m *mockRepository) BatchIncrementAppUsageDurations(ctx context.Context, da
 	return nil
 }
 
+func (m *mockRepository) BatchIncrementAppUsage(ctx context.Context, date time.Time, appUsages []types.AppUsage) error {
+	return nil
+}
+
+func (m *mockRepository) BatchUpdateAppUsageMetadata(ctx context.Context, date time.Time, appUsages []types.AppUsage) error {
+	return nil
+}
+
 func (m *mockRepository) GetAppUsageByDateRangePaginated(ctx context.Context, startDate, endDate time.Time, limit, offset int) (*types.PaginatedAppUsageResult, error) {
 	return &types.PaginatedAppUsageResult{
 		Results: []types.AppUsage{},
@@ -18,7 +18,7 @@ func (r *SQLiteRepository) SaveAppUsage(ctx context.Context, date time.Time, app
 
 	if appUsage == nil {
 		err := repoerrors.NewRepositoryError("SaveAppUsage", fmt.Errorf("app usage data is nil"), repoerrors.ErrCodeValidation)
-		logging.LogError(r.logger, err, "SaveAppUsage", map[string]interface{}{
+		logging.LogError(r.loggerFor(ctx), err, "SaveAppUsage", map[string]interface{}{
 			"date": date.Format("2006-01-02"),
 		})
 		return err
@@ -39,7 +39,7 @@ func (r *SQLiteRepository) SaveAppUsage(ctx context.Context, date time.Time, app
 		for k, v := range validationContext {
 			logContext[k] = v
 		}
-		logging.LogError(r.logger, err, "SaveAppUsage", logContext)
+		logging.LogError(r.loggerFor(ctx), err, "SaveAppUsage", logContext)
 		return err
 	}
 
@@ -50,22 +50,27 @@ func (r *SQLiteRepository) SaveAppUsage(ctx context.Context, date time.Time, app
 		for k, v := range validationContext {
 			logContext[k] = v
 		}
-		logging.LogError(r.logger, err, "SaveAppUsage", logContext)
+		logging.LogError(r.loggerFor(ctx), err, "SaveAppUsage", logContext)
 		return err
 	}
 
-	// Normalize date to start of day
-	normalizedDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
 
 	// Execute with retry logic
-	err := repoerrors.WithRetry(ctx, r.retryConfig, func() error {
-		_, err := r.queries.UpsertAppUsage(ctx, queries.UpsertAppUsageParams{
-			Name:     appUsage.Name,
-			Duration: appUsage.Duration,
-			IconPath: r.nullStringFromString(appUsage.IconPath),
-			ExePath:  r.nullStringFromString(appUsage.ExePath),
-			Date:     normalizedDate,
-		})
+	err := r.withRetry(ctx, "SaveAppUsage", func() error {
+		cols, err := r.resolveAppUsageColumns(ctx, r.queries, appUsage)
+		if err == nil {
+			_, err = r.queries.UpsertAppUsage(ctx, queries.UpsertAppUsageParams{
+				Name:          appUsage.Name,
+				Duration:      appUsage.Duration,
+				IconPath:      cols.iconPath,
+				ExePath:       cols.exePath,
+				IconHash:      cols.iconHash,
+				ApplicationID: cols.applicationID,
+				Date:          normalizedDate,
+			})
+		}
 
 		if err != nil {
 			repoErr := repoerrors.NewRepositoryErrorWithContext("SaveAppUsage", err, r.classifyError(err), map[string]string{
@@ -76,9 +81,9 @@ func (r *SQLiteRepository) SaveAppUsage(ctx context.Context, date time.Time, app
 
 			// Log retryable errors at debug level, non-retryable at error level
 			if repoErr.IsRetryable() {
-				r.logger.Debug("Retryable error in SaveAppUsage", "error", err, "app", appUsage.Name)
+				r.loggerFor(ctx).Debug("Retryable error in SaveAppUsage", "error", err, "app", appUsage.Name)
 			} else {
-				logging.LogError(r.logger, repoErr, "SaveAppUsage", map[string]interface{}{
+				logging.LogError(r.loggerFor(ctx), repoErr, "SaveAppUsage", map[string]interface{}{
 					"app_name": appUsage.Name,
 					"date":     normalizedDate.Format("2006-01-02"),
 					"duration": appUsage.Duration,
@@ -93,7 +98,7 @@ func (r *SQLiteRepository) SaveAppUsage(ctx context.Context, date time.Time, app
 
 	// Log successful operation
 	if err == nil {
-		logging.LogOperation(r.logger, "SaveAppUsage", time.Since(start), map[string]interface{}{
+		logging.LogOperation(r.loggerFor(ctx), "SaveAppUsage", time.Since(start), map[string]interface{}{
 			"app_name": appUsage.Name,
 			"date":     normalizedDate.Format("2006-01-02"),
 			"duration": appUsage.Duration,
@@ -105,17 +110,17 @@ func (r *SQLiteRepository) SaveAppUsage(ctx context.Context, date time.Time, app
 
 // GetAppUsageByDate retrieves all application usage data for a specific date
 func (r *SQLiteRepository) GetAppUsageByDate(ctx context.Context, date time.Time) ([]types.AppUsage, error) {
-	// Normalize date to start of day
-	normalizedDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
 
 	rows, err := r.queries.GetAppUsageByDate(ctx, normalizedDate)
 	if err != nil {
 		return nil, repoerrors.NewRepositoryError("GetAppUsageByDate", err, r.classifyError(err))
 	}
 
-	apps := make([]types.AppUsage, len(rows))
-	for i, row := range rows {
-		apps[i] = r.convertAppUsageFromDB(row)
+	apps, err := r.convertAppUsageRows(ctx, rows)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("GetAppUsageByDate", err, r.classifyError(err))
 	}
 
 	return apps, nil
@@ -125,9 +130,9 @@ func (r *SQLiteRepository) GetAppUsageByDate(ctx context.Context, date time.Time
 // Results are ordered by date descending (newest first) and then by duration descending.
 // Both start and end date bounds are inclusive.
 func (r *SQLiteRepository) GetAppUsageByDateRange(ctx context.Context, startDate, endDate time.Time) ([]types.AppUsage, error) {
-	// Normalize dates
-	normalizedStart := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
-	normalizedEnd := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
+	// Normalize to stored date keys
+	normalizedStart := types.DateKey(startDate)
+	normalizedEnd := types.DateKeyEnd(endDate)
 
 	rows, err := r.queries.GetAppUsageByDateRange(ctx, queries.GetAppUsageByDateRangeParams{
 		Date:   normalizedStart,
@@ -138,9 +143,9 @@ func (r *SQLiteRepository) GetAppUsageByDateRange(ctx context.Context, startDate
 		return nil, repoerrors.NewRepositoryError("GetAppUsageByDateRange", err, r.classifyError(err))
 	}
 
-	apps := make([]types.AppUsage, len(rows))
-	for i, row := range rows {
-		apps[i] = r.convertAppUsageFromDB(row)
+	apps, err := r.convertAppUsageRows(ctx, rows)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("GetAppUsageByDateRange", err, r.classifyError(err))
 	}
 
 	return apps, nil
@@ -148,9 +153,9 @@ func (r *SQLiteRepository) GetAppUsageByDateRange(ctx context.Context, startDate
 
 // GetAppUsageByNameAndDateRange retrieves application usage data for a specific app within a date range
 func (r *SQLiteRepository) GetAppUsageByNameAndDateRange(ctx context.Context, appName string, startDate, endDate time.Time) ([]types.AppUsage, error) {
-	// Normalize dates
-	normalizedStart := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
-	normalizedEnd := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
+	// Normalize to stored date keys
+	normalizedStart := types.DateKey(startDate)
+	normalizedEnd := types.DateKeyEnd(endDate)
 
 	rows, err := r.queries.GetAppUsageByNameAndDateRange(ctx, queries.GetAppUsageByNameAndDateRangeParams{
 		Name:   appName,
@@ -162,10 +167,60 @@ func (r *SQLiteRepository) GetAppUsageByNameAndDateRange(ctx context.Context, ap
 		return nil, repoerrors.NewRepositoryError("GetAppUsageByNameAndDateRange", err, r.classifyError(err))
 	}
 
+	apps, err := r.convertAppUsageRows(ctx, rows)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("GetAppUsageByNameAndDateRange", err, r.classifyError(err))
+	}
+
+	return apps, nil
+}
+
+// GetAppUsageByApplicationAndDateRange retrieves an application's usage within a date range,
+// whatever names it was tracked under
+func (r *SQLiteRepository) GetAppUsageByApplicationAndDateRange(ctx context.Context, applicationID int64, startDate, endDate time.Time) ([]types.AppUsage, error) {
+	// Normalize to stored date keys
+	normalizedStart := types.DateKey(startDate)
+	normalizedEnd := types.DateKeyEnd(endDate)
+
+	rows, err := r.queries.GetAppUsageByApplicationAndDateRange(ctx, queries.GetAppUsageByApplicationAndDateRangeParams{
+		ApplicationID: applicationID,
+		Date:          normalizedStart,
+		Date_2:        normalizedEnd,
+	})
+	if err != nil {
+		return nil, repoerrors.NewRepositoryErrorWithContext("GetAppUsageByApplicationAndDateRange", err, r.classifyError(err), map[string]string{
+			"application_id": fmt.Sprintf("%d", applicationID),
+		})
+	}
+
+	apps, err := r.convertAppUsageRows(ctx, rows)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("GetAppUsageByApplicationAndDateRange", err, r.classifyError(err))
+	}
+
+	return apps, nil
+}
+
+// convertAppUsageRows converts app usage rows, labelling each with its application's
+// alias or display name
+func (r *SQLiteRepository) convertAppUsageRows(ctx context.Context, rows []queries.AppUsage) ([]types.AppUsage, error) {
 	apps := make([]types.AppUsage, len(rows))
+	if len(rows) == 0 {
+		return apps, nil
+	}
+
+	names, err := r.queries.ListApplicationDisplayNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[int64]string, len(names))
+	for _, name := range names {
+		labels[name.ID] = name.DisplayName
+	}
+
 	for i, row := range rows {
 		apps[i] = r.convertAppUsageFromDB(row)
+		apps[i].DisplayName = labels[row.ApplicationID]
 	}
-
 	return apps, nil
 }
@@ -0,0 +1,384 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// Ensure SQLiteRepository implements ApplicationRepository interface
+var _ ApplicationRepository = (*SQLiteRepository)(nil)
+
+// appUsageColumns holds the column values written for an app usage row
+type appUsageColumns struct {
+	iconPath      sql.NullString
+	iconHash      sql.NullString
+	exePath       sql.NullString
+	applicationID int64
+}
+
+// resolveAppUsageColumns stores the icon and resolves the application for an app usage row
+func (r *SQLiteRepository) resolveAppUsageColumns(ctx context.Context, q *queries.Queries, appUsage *types.AppUsage) (appUsageColumns, error) {
+	iconPath, iconHash, err := r.iconColumns(ctx, q, appUsage.IconPath)
+	if err != nil {
+		return appUsageColumns{}, err
+	}
+
+	applicationID, err := r.resolveApplicationID(ctx, q, appUsage)
+	if err != nil {
+		return appUsageColumns{}, err
+	}
+
+	return appUsageColumns{
+		iconPath:      iconPath,
+		iconHash:      iconHash,
+		exePath:       r.nullStringFromString(appUsage.ExePath),
+		applicationID: applicationID,
+	}, nil
+}
+
+// resolveApplicationID finds the application an app usage row belongs to, creating it if needed.
+// A known executable path wins, so paths moved by a merge or split keep their new owner.
+// Otherwise the oldest application with the same name is used and the path is attached to it.
+func (r *SQLiteRepository) resolveApplicationID(ctx context.Context, q *queries.Queries, appUsage *types.AppUsage) (int64, error) {
+	if appUsage.ExePath != "" {
+		id, err := q.GetApplicationIDByExePath(ctx, appUsage.ExePath)
+		if err == nil {
+			return id, r.refreshApplicationVersionInfo(ctx, q, id, appUsage)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+	}
+
+	app, err := q.GetApplicationByName(ctx, appUsage.Name)
+	switch {
+	case err == nil:
+		if err := r.refreshApplicationVersionInfo(ctx, q, app.ID, appUsage); err != nil {
+			return 0, err
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		app, err = q.CreateApplication(ctx, queries.CreateApplicationParams{
+			Name:        appUsage.Name,
+			DisplayName: appUsage.Name,
+			Publisher:   r.nullStringFromString(appUsage.Publisher),
+			Version:     r.nullStringFromString(appUsage.Version),
+		})
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, err
+	}
+
+	if appUsage.ExePath != "" {
+		if err := q.AddApplicationPath(ctx, queries.AddApplicationPathParams{
+			ExePath:       appUsage.ExePath,
+			ApplicationID: app.ID,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	return app.ID, nil
+}
+
+// refreshApplicationVersionInfo records publisher and version reported by the platform
+// when they differ from what is stored. Empty values never overwrite stored ones.
+func (r *SQLiteRepository) refreshApplicationVersionInfo(ctx context.Context, q *queries.Queries, id int64, appUsage *types.AppUsage) error {
+	if appUsage.Publisher == "" && appUsage.Version == "" {
+		return nil
+	}
+
+	app, err := q.GetApplicationByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	publisher, version := app.Publisher, app.Version
+	if appUsage.Publisher != "" {
+		publisher = r.nullStringFromString(appUsage.Publisher)
+	}
+	if appUsage.Version != "" {
+		version = r.nullStringFromString(appUsage.Version)
+	}
+	if publisher == app.Publisher && version == app.Version {
+		return nil
+	}
+
+	return q.UpdateApplicationVersionInfo(ctx, queries.UpdateApplicationVersionInfoParams{
+		Publisher: publisher,
+		Version:   version,
+		ID:        id,
+	})
+}
+
+// ListApplications retrieves all applications with their executable paths
+func (r *SQLiteRepository) ListApplications(ctx context.Context) ([]types.Application, error) {
+	rows, err := r.queries.ListApplications(ctx)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("ListApplications", err, r.classifyError(err))
+	}
+
+	paths, err := r.queries.ListApplicationPaths(ctx)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("ListApplications", err, r.classifyError(err))
+	}
+
+	pathsByApp := make(map[int64][]string)
+	for _, path := range paths {
+		pathsByApp[path.ApplicationID] = append(pathsByApp[path.ApplicationID], path.ExePath)
+	}
+
+	apps := make([]types.Application, len(rows))
+	for i, row := range rows {
+		apps[i] = r.convertApplicationFromDB(row, pathsByApp[row.ID])
+	}
+
+	return apps, nil
+}
+
+// GetApplication retrieves an application and its executable paths
+func (r *SQLiteRepository) GetApplication(ctx context.Context, id int64) (*types.Application, error) {
+	return r.getApplication(ctx, r.queries, "GetApplication", id)
+}
+
+// UpdateApplication saves the user-editable fields of an application
+func (r *SQLiteRepository) UpdateApplication(ctx context.Context, app *types.Application) error {
+	if app == nil {
+		return repoerrors.NewRepositoryError("UpdateApplication", fmt.Errorf("application is nil"), repoerrors.ErrCodeValidation)
+	}
+
+	displayName := strings.TrimSpace(app.DisplayName)
+	if displayName == "" {
+		return repoerrors.NewRepositoryErrorWithContext("UpdateApplication", fmt.Errorf("display name is empty or whitespace"), repoerrors.ErrCodeValidation, map[string]string{
+			"application_id": fmt.Sprintf("%d", app.ID),
+		})
+	}
+
+	row, err := r.queries.UpdateApplication(ctx, queries.UpdateApplicationParams{
+		DisplayName: displayName,
+		Alias:       r.nullStringFromString(strings.TrimSpace(app.Alias)),
+		Publisher:   r.nullStringFromString(app.Publisher),
+		Version:     r.nullStringFromString(app.Version),
+		ID:          app.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repoerrors.HandleNotFound("UpdateApplication", "application", fmt.Sprintf("%d", app.ID))
+		}
+		return repoerrors.NewRepositoryErrorWithContext("UpdateApplication", err, r.classifyError(err), map[string]string{
+			"application_id": fmt.Sprintf("%d", app.ID),
+		})
+	}
+
+	updated := r.convertApplicationFromDB(row, app.ExePaths)
+	*app = updated
+	return nil
+}
+
+// MergeApplications folds the source applications into the target.
+// Their executable paths and all of their usage history move to the target,
+// and the source applications are removed.
+func (r *SQLiteRepository) MergeApplications(ctx context.Context, targetID int64, sourceIDs []int64) error {
+	start := time.Now()
+
+	if len(sourceIDs) == 0 {
+		return repoerrors.NewRepositoryError("MergeApplications", fmt.Errorf("no source applications given"), repoerrors.ErrCodeValidation)
+	}
+
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+
+		if _, err := txRepo.getApplication(ctx, txRepo.queries, "MergeApplications", targetID); err != nil {
+			return err
+		}
+
+		for _, sourceID := range sourceIDs {
+			errContext := map[string]string{
+				"target_id": fmt.Sprintf("%d", targetID),
+				"source_id": fmt.Sprintf("%d", sourceID),
+			}
+
+			if sourceID == targetID {
+				return repoerrors.NewRepositoryErrorWithContext("MergeApplications",
+					fmt.Errorf("cannot merge an application into itself"), repoerrors.ErrCodeValidation, errContext)
+			}
+			if _, err := txRepo.getApplication(ctx, txRepo.queries, "MergeApplications", sourceID); err != nil {
+				return err
+			}
+
+			if err := txRepo.queries.MoveApplicationPaths(ctx, queries.MoveApplicationPathsParams{
+				ApplicationID:   targetID,
+				ApplicationID_2: sourceID,
+			}); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("MergeApplications", err, r.classifyError(err), errContext)
+			}
+
+			// Days both were used on are added into the target's rows before the rest move over
+			overlap := queries.AddOverlappingAppUsageParams{SourceID: sourceID, TargetID: targetID}
+			if err := txRepo.queries.AddOverlappingAppUsage(ctx, overlap); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("MergeApplications", err, r.classifyError(err), errContext)
+			}
+			if err := txRepo.queries.DeleteOverlappingAppUsage(ctx, queries.DeleteOverlappingAppUsageParams(overlap)); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("MergeApplications", err, r.classifyError(err), errContext)
+			}
+
+			if err := txRepo.queries.ReassignAppUsage(ctx, queries.ReassignAppUsageParams{
+				ApplicationID:   targetID,
+				ApplicationID_2: sourceID,
+			}); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("MergeApplications", err, r.classifyError(err), errContext)
+			}
+
+			if err := txRepo.queries.DeleteApplication(ctx, sourceID); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("MergeApplications", err, r.classifyError(err), errContext)
+			}
+		}
+
+		return nil
+	})
+
+	if err == nil {
+		logging.LogOperation(r.loggerFor(ctx), "MergeApplications", time.Since(start), map[string]interface{}{
+			"target_id":    targetID,
+			"source_count": len(sourceIDs),
+		})
+	}
+
+	return err
+}
+
+// SplitApplication moves the given executable paths of an application to a new application.
+// Usage rows recorded with one of those paths move along with them.
+func (r *SQLiteRepository) SplitApplication(ctx context.Context, id int64, exePaths []string) (*types.Application, error) {
+	start := time.Now()
+
+	if len(exePaths) == 0 {
+		return nil, repoerrors.NewRepositoryError("SplitApplication", fmt.Errorf("no executable paths given"), repoerrors.ErrCodeValidation)
+	}
+
+	var split *types.Application
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+
+		source, err := txRepo.getApplication(ctx, txRepo.queries, "SplitApplication", id)
+		if err != nil {
+			return err
+		}
+
+		// Resolve each requested path to the stored spelling, rejecting paths owned elsewhere
+		var moved []string
+		for _, exePath := range exePaths {
+			owned := ""
+			for _, path := range source.ExePaths {
+				if strings.EqualFold(path, exePath) {
+					owned = path
+					break
+				}
+			}
+			if owned == "" {
+				return repoerrors.NewRepositoryErrorWithContext("SplitApplication",
+					fmt.Errorf("executable path does not belong to the application"), repoerrors.ErrCodeValidation, map[string]string{
+						"application_id": fmt.Sprintf("%d", id),
+						"exe_path":       exePath,
+					})
+			}
+			moved = append(moved, owned)
+		}
+
+		row, err := txRepo.queries.CreateApplication(ctx, queries.CreateApplicationParams{
+			Name:        source.Name,
+			DisplayName: source.Name,
+		})
+		if err != nil {
+			return repoerrors.NewRepositoryError("SplitApplication", err, r.classifyError(err))
+		}
+
+		for _, exePath := range moved {
+			errContext := map[string]string{
+				"application_id": fmt.Sprintf("%d", id),
+				"exe_path":       exePath,
+			}
+
+			if err := txRepo.queries.MoveApplicationPath(ctx, queries.MoveApplicationPathParams{
+				ApplicationID: row.ID,
+				ExePath:       exePath,
+			}); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("SplitApplication", err, r.classifyError(err), errContext)
+			}
+
+			if err := txRepo.queries.ReassignAppUsageByExePath(ctx, queries.ReassignAppUsageByExePathParams{
+				ApplicationID:   row.ID,
+				ApplicationID_2: id,
+				ExePath:         r.nullStringFromString(exePath),
+			}); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("SplitApplication", err, r.classifyError(err), errContext)
+			}
+		}
+
+		app := r.convertApplicationFromDB(row, moved)
+		split = &app
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "SplitApplication", time.Since(start), map[string]interface{}{
+		"application_id": id,
+		"new_id":         split.ID,
+		"path_count":     len(split.ExePaths),
+	})
+
+	return split, nil
+}
+
+// getApplication retrieves an application with its paths using the given queries
+func (r *SQLiteRepository) getApplication(ctx context.Context, q *queries.Queries, operation string, id int64) (*types.Application, error) {
+	row, err := q.GetApplicationByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repoerrors.HandleNotFound(operation, "application", fmt.Sprintf("%d", id))
+		}
+		return nil, repoerrors.NewRepositoryErrorWithContext(operation, err, r.classifyError(err), map[string]string{
+			"application_id": fmt.Sprintf("%d", id),
+		})
+	}
+
+	paths, err := q.GetApplicationPaths(ctx, id)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryErrorWithContext(operation, err, r.classifyError(err), map[string]string{
+			"application_id": fmt.Sprintf("%d", id),
+		})
+	}
+
+	app := r.convertApplicationFromDB(row, paths)
+	return &app, nil
+}
+
+// convertApplicationFromDB converts a database Application to types.Application
+func (r *SQLiteRepository) convertApplicationFromDB(row queries.Application, exePaths []string) types.Application {
+	if exePaths == nil {
+		exePaths = []string{}
+	}
+	return types.Application{
+		ID:          row.ID,
+		Name:        row.Name,
+		DisplayName: row.DisplayName,
+		Alias:       r.stringFromNullString(row.Alias),
+		Publisher:   r.stringFromNullString(row.Publisher),
+		Version:     r.stringFromNullString(row.Version),
+		ExePaths:    exePaths,
+		CreatedAt:   r.timeFromNullTime(row.CreatedAt),
+		UpdatedAt:   r.timeFromNullTime(row.UpdatedAt),
+	}
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+// appUsageFor returns the stored usage row for an app on a date
+func appUsageFor(t *testing.T, repo *SQLiteRepository, date time.Time, name string) types.AppUsage {
+	t.Helper()
+	apps, err := repo.GetAppUsageByDate(context.Background(), date)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	for _, app := range apps {
+		if app.Name == name {
+			return app
+		}
+	}
+	t.Fatalf("no usage for %s on %s", name, date.Format("2006-01-02"))
+	return types.AppUsage{}
+}
+
+func TestSQLiteRepository_ApplicationResolution(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	day1 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+
+	if err := repo.SaveAppUsage(ctx, day1, &types.AppUsage{
+		Name: "setup", Duration: 10, ExePath: `C:\Downloads\a\setup.exe`, Publisher: "Vendor A", Version: "1.0",
+	}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if err := repo.BatchUpdateAppUsageMetadata(ctx, day2, []types.AppUsage{
+		{Name: "setup", ExePath: `C:\Downloads\b\setup.exe`},
+	}); err != nil {
+		t.Fatalf("BatchUpdateAppUsageMetadata() error = %v", err)
+	}
+	// Increments without metadata resolve by name
+	if err := repo.BatchIncrementAppUsageDurations(ctx, day2, map[string]int64{"notepad": 5}); err != nil {
+		t.Fatalf("BatchIncrementAppUsageDurations() error = %v", err)
+	}
+
+	first := appUsageFor(t, repo, day1, "setup")
+	second := appUsageFor(t, repo, day2, "setup")
+	notepad := appUsageFor(t, repo, day2, "notepad")
+
+	if first.ApplicationID == 0 || notepad.ApplicationID == 0 {
+		t.Fatalf("expected usage rows to reference applications, got %d and %d", first.ApplicationID, notepad.ApplicationID)
+	}
+	if second.ApplicationID != first.ApplicationID {
+		t.Errorf("same name should resolve to the same application by default, got %d and %d", first.ApplicationID, second.ApplicationID)
+	}
+
+	app, err := repo.GetApplication(ctx, first.ApplicationID)
+	if err != nil {
+		t.Fatalf("GetApplication() error = %v", err)
+	}
+	if app.Name != "setup" || app.DisplayName != "setup" || app.Publisher != "Vendor A" || app.Version != "1.0" {
+		t.Errorf("unexpected application: %+v", app)
+	}
+	if len(app.ExePaths) != 2 {
+		t.Errorf("expected both executable paths to be recorded, got %v", app.ExePaths)
+	}
+
+	if _, err := repo.GetApplication(ctx, 9999); !repoerrors.IsNotFound(err) {
+		t.Errorf("GetApplication() for a missing ID should be not found, got %v", err)
+	}
+
+	apps, err := repo.ListApplications(ctx)
+	if err != nil {
+		t.Fatalf("ListApplications() error = %v", err)
+	}
+	if len(apps) != 2 {
+		t.Errorf("expected 2 applications, got %+v", apps)
+	}
+}
+
+func TestSQLiteRepository_SplitApplication(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	day1 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	pathA, pathB := `C:\Downloads\a\setup.exe`, `C:\Downloads\b\setup.exe`
+
+	repo.SaveAppUsage(ctx, day1, &types.AppUsage{Name: "setup", Duration: 10, ExePath: pathA})
+	repo.SaveAppUsage(ctx, day2, &types.AppUsage{Name: "setup", Duration: 20, ExePath: pathB})
+	original := appUsageFor(t, repo, day1, "setup").ApplicationID
+
+	split, err := repo.SplitApplication(ctx, original, []string{`c:\downloads\b\SETUP.exe`})
+	if err != nil {
+		t.Fatalf("SplitApplication() error = %v", err)
+	}
+	if split.ID == original || len(split.ExePaths) != 1 || split.ExePaths[0] != pathB {
+		t.Errorf("unexpected split application: %+v", split)
+	}
+
+	if got := appUsageFor(t, repo, day1, "setup").ApplicationID; got != original {
+		t.Errorf("history for the remaining path should stay with the original, got %d", got)
+	}
+	if got := appUsageFor(t, repo, day2, "setup").ApplicationID; got != split.ID {
+		t.Errorf("history for the split path should move, got %d want %d", got, split.ID)
+	}
+
+	// New usage from the split path keeps resolving to the new application
+	day3 := day2.AddDate(0, 0, 1)
+	repo.SaveAppUsage(ctx, day3, &types.AppUsage{Name: "setup", Duration: 5, ExePath: pathB})
+	if got := appUsageFor(t, repo, day3, "setup").ApplicationID; got != split.ID {
+		t.Errorf("split path resolved to %d, want %d", got, split.ID)
+	}
+
+	if _, err := repo.SplitApplication(ctx, original, []string{`C:\elsewhere\setup.exe`}); !repoerrors.IsValidation(err) {
+		t.Errorf("splitting a path the application doesn't own should be a validation error, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_MergeApplications(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	nextDay := day.AddDate(0, 0, 1)
+	repo.SaveAppUsage(ctx, day, &types.AppUsage{Name: "code", Duration: 30, ExePath: `C:\VSCode\code.exe`})
+	repo.SaveAppUsage(ctx, day, &types.AppUsage{Name: "code-renamed", Duration: 15, ExePath: `C:\VSCode\code-renamed.exe`})
+	repo.SaveAppUsage(ctx, nextDay, &types.AppUsage{Name: "code-renamed", Duration: 20, ExePath: `C:\VSCode\code-renamed.exe`})
+
+	target := appUsageFor(t, repo, day, "code").ApplicationID
+	source := appUsageFor(t, repo, day, "code-renamed").ApplicationID
+
+	if err := repo.MergeApplications(ctx, target, []int64{source}); err != nil {
+		t.Fatalf("MergeApplications() error = %v", err)
+	}
+
+	// A day both were used on is folded into one row for the target
+	apps, err := repo.GetAppUsageByDate(ctx, day)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	if len(apps) != 1 || apps[0].Name != "code" || apps[0].Duration != 45 || apps[0].ApplicationID != target {
+		t.Errorf("expected one 45s row for the target, got %+v", apps)
+	}
+	if got := appUsageFor(t, repo, nextDay, "code-renamed").ApplicationID; got != target {
+		t.Errorf("merged history references %d, want %d", got, target)
+	}
+	if _, err := repo.GetApplication(ctx, source); !repoerrors.IsNotFound(err) {
+		t.Errorf("merged source should be removed, got %v", err)
+	}
+
+	merged, err := repo.GetApplication(ctx, target)
+	if err != nil {
+		t.Fatalf("GetApplication() error = %v", err)
+	}
+	if len(merged.ExePaths) != 2 {
+		t.Errorf("merged application should own both paths, got %v", merged.ExePaths)
+	}
+
+	// The alias is what users see after fixing up a merge
+	merged.Alias = "  Visual Studio Code  "
+	if err := repo.UpdateApplication(ctx, merged); err != nil {
+		t.Fatalf("UpdateApplication() error = %v", err)
+	}
+	if merged.Label() != "Visual Studio Code" {
+		t.Errorf("Label() = %q, want alias", merged.Label())
+	}
+
+	history, err := repo.GetAppUsageByApplicationAndDateRange(ctx, target, day, nextDay)
+	if err != nil {
+		t.Fatalf("GetAppUsageByApplicationAndDateRange() error = %v", err)
+	}
+	if len(history) != 2 || history[0].Duration != 20 || history[1].Duration != 45 {
+		t.Fatalf("expected both days newest first, got %+v", history)
+	}
+	for _, row := range history {
+		if row.Label() != "Visual Studio Code" {
+			t.Errorf("history row for %s labelled %q, want the alias", row.Name, row.Label())
+		}
+	}
+
+	if err := repo.MergeApplications(ctx, target, []int64{target}); !repoerrors.IsValidation(err) {
+		t.Errorf("merging an application into itself should be a validation error, got %v", err)
+	}
+	if err := repo.MergeApplications(ctx, target, []int64{9999}); !repoerrors.IsNotFound(err) {
+		t.Errorf("merging a missing application should be not found, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_BatchIncrementAppUsageByPath(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	pathA, pathB := `C:\Downloads\a\setup.exe`, `C:\Downloads\b\setup.exe`
+
+	repo.SaveAppUsage(ctx, day, &types.AppUsage{Name: "setup", Duration: 10, ExePath: pathA})
+	original := appUsageFor(t, repo, day, "setup").ApplicationID
+	repo.BatchUpdateAppUsageMetadata(ctx, day.AddDate(0, 0, -1), []types.AppUsage{{Name: "setup", ExePath: pathB}})
+	split, err := repo.SplitApplication(ctx, original, []string{pathB})
+	if err != nil {
+		t.Fatalf("SplitApplication() error = %v", err)
+	}
+
+	// Both programs share a name but keep their own time for the day
+	if err := repo.BatchIncrementAppUsage(ctx, day, []types.AppUsage{
+		{Name: "setup", Duration: 5, ExePath: pathA},
+		{Name: "setup", Duration: 7, ExePath: pathB},
+	}); err != nil {
+		t.Fatalf("BatchIncrementAppUsage() error = %v", err)
+	}
+
+	apps, err := repo.GetAppUsageByDate(ctx, day)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	durations := make(map[int64]int64)
+	for _, app := range apps {
+		durations[app.ApplicationID] += app.Duration
+	}
+	if len(apps) != 2 || durations[original] != 15 || durations[split.ID] != 7 {
+		t.Errorf("expected 15s for the original and 7s for the split application, got %+v", apps)
+	}
+
+	if err := repo.BatchIncrementAppUsage(ctx, day, []types.AppUsage{{Name: "setup", Duration: -1}}); !repoerrors.IsValidation(err) {
+		t.Errorf("a negative duration should be a validation error, got %v", err)
+	}
+}
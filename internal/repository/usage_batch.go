@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	queries "qwin/internal/database/generated"
@@ -28,11 +29,13 @@ func (r *SQLiteRepository) BatchProcessAppUsage(ctx context.Context, date time.T
 // If batchSize is 0, uses the configured default batch size calculation
 func (r *SQLiteRepository) BatchProcessAppUsageWithBatchSize(ctx context.Context, date time.Time, appUsages []types.AppUsage, strategy types.BatchStrategy, batchSize int) error {
 	start := time.Now()
+	// Entries logged by the chunk transactions carry the batch's ID
+	ctx, _ = logging.WithOperationID(ctx, "batch_id")
 
 	// Validate batch size to prevent undefined behavior/infinite loops
 	if batchSize < 0 {
 		err := repoerrors.NewRepositoryError("BatchProcessAppUsageWithBatchSize", ErrInvalidBatchSize, repoerrors.ErrCodeValidation)
-		logging.LogError(r.logger, err, "BatchProcessAppUsageWithBatchSize", map[string]interface{}{
+		logging.LogError(r.loggerFor(ctx), err, "BatchProcessAppUsageWithBatchSize", map[string]interface{}{
 			"batch_size": batchSize,
 			"date":       date.Format("2006-01-02"),
 		})
@@ -43,8 +46,8 @@ func (r *SQLiteRepository) BatchProcessAppUsageWithBatchSize(ctx context.Context
 		return nil
 	}
 
-	// Normalize date to start of day
-	normalizedDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
 
 	strategyName := "upsert"
 	if strategy == types.BatchStrategyInsertOnly {
@@ -69,24 +72,30 @@ func (r *SQLiteRepository) BatchProcessAppUsageWithBatchSize(ctx context.Context
 			txRepo := repo.(*SQLiteRepository)
 
 			for j, appUsage := range batch {
-				var err error
+				cols, err := r.resolveAppUsageColumns(ctx, txRepo.queries, &appUsage)
 
-				switch strategy {
-				case types.BatchStrategyUpsert:
+				switch {
+				case err != nil:
+					// Reported below with the rest of the batch context
+				case strategy == types.BatchStrategyUpsert:
 					_, err = txRepo.queries.UpsertAppUsage(ctx, queries.UpsertAppUsageParams{
-						Name:     appUsage.Name,
-						Duration: appUsage.Duration,
-						IconPath: r.nullStringFromString(appUsage.IconPath),
-						ExePath:  r.nullStringFromString(appUsage.ExePath),
-						Date:     normalizedDate,
+						Name:          appUsage.Name,
+						Duration:      appUsage.Duration,
+						IconPath:      cols.iconPath,
+						ExePath:       cols.exePath,
+						IconHash:      cols.iconHash,
+						ApplicationID: cols.applicationID,
+						Date:          normalizedDate,
 					})
-				case types.BatchStrategyInsertOnly:
+				case strategy == types.BatchStrategyInsertOnly:
 					err = txRepo.queries.InsertAppUsage(ctx, queries.InsertAppUsageParams{
-						Name:     appUsage.Name,
-						Duration: appUsage.Duration,
-						IconPath: r.nullStringFromString(appUsage.IconPath),
-						ExePath:  r.nullStringFromString(appUsage.ExePath),
-						Date:     normalizedDate,
+						Name:          appUsage.Name,
+						Duration:      appUsage.Duration,
+						IconPath:      cols.iconPath,
+						ExePath:       cols.exePath,
+						IconHash:      cols.iconHash,
+						ApplicationID: cols.applicationID,
+						Date:          normalizedDate,
 					})
 				default:
 					return repoerrors.NewRepositoryErrorWithContext("BatchProcessAppUsage",
@@ -106,7 +115,7 @@ func (r *SQLiteRepository) BatchProcessAppUsageWithBatchSize(ctx context.Context
 						"strategy":    strategyName,
 					})
 
-					logging.LogError(r.logger, repoErr, "BatchProcessAppUsage", map[string]any{
+					logging.LogError(r.loggerFor(ctx), repoErr, "BatchProcessAppUsage", map[string]any{
 						"app_name":    appUsage.Name,
 						"date":        normalizedDate.Format("2006-01-02"),
 						"batch_index": i + j,
@@ -127,7 +136,7 @@ func (r *SQLiteRepository) BatchProcessAppUsageWithBatchSize(ctx context.Context
 	}
 
 	// Log successful batch operation
-	logging.LogOperation(r.logger, "BatchProcessAppUsage", time.Since(start), map[string]any{
+	logging.LogOperation(r.loggerFor(ctx), "BatchProcessAppUsage", time.Since(start), map[string]any{
 		"date":       normalizedDate.Format("2006-01-02"),
 		"total_size": len(appUsages),
 		"batch_size": effectiveBatchSize,
@@ -140,107 +149,162 @@ func (r *SQLiteRepository) BatchProcessAppUsageWithBatchSize(ctx context.Context
 // BatchIncrementAppUsageDurations increments multiple app usage durations efficiently
 // additionalDuration values must be non-negative to prevent data corruption
 func (r *SQLiteRepository) BatchIncrementAppUsageDurations(ctx context.Context, date time.Time, increments map[string]int64) error {
-	if len(increments) == 0 {
+	appUsages := make([]types.AppUsage, 0, len(increments))
+	for appName, additionalDuration := range increments {
+		appUsages = append(appUsages, types.AppUsage{Name: appName, Duration: additionalDuration})
+	}
+	return r.BatchIncrementAppUsage(ctx, date, appUsages)
+}
+
+// BatchIncrementAppUsage adds each app's duration to the usage of the application it resolves
+// to, by executable path first and by name otherwise. Durations must be non-negative.
+func (r *SQLiteRepository) BatchIncrementAppUsage(ctx context.Context, date time.Time, appUsages []types.AppUsage) error {
+	if len(appUsages) == 0 {
 		return nil
 	}
 
 	// Validate all increments before performing any updates
-	for appName, additionalDuration := range increments {
-		if additionalDuration < 0 {
+	for _, appUsage := range appUsages {
+		if appUsage.Duration < 0 {
 			return repoerrors.NewRepositoryErrorWithContext(
-				"BatchIncrementAppUsageDurations",
+				"BatchIncrementAppUsage",
 				errors.New("negative increment not allowed"),
 				repoerrors.ErrCodeValidation,
 				map[string]string{
-					"app_name":            appName,
-					"additional_duration": fmt.Sprintf("%d", additionalDuration),
+					"app_name":            appUsage.Name,
+					"additional_duration": fmt.Sprintf("%d", appUsage.Duration),
 				},
 			)
 		}
 	}
 
-	// Normalize date to start of day
-	normalizedDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
 
 	return r.WithTransaction(ctx, func(repo UsageRepository) error {
 		txRepo := repo.(*SQLiteRepository)
 
-		for appName, additionalDuration := range increments {
+		for _, appUsage := range appUsages {
+			applicationID, err := txRepo.resolveApplicationID(ctx, txRepo.queries, &appUsage)
+			if err != nil {
+				return repoerrors.NewRepositoryErrorWithContext(
+					"BatchIncrementAppUsage",
+					err,
+					r.classifyError(err),
+					map[string]string{
+						"app_name":  appUsage.Name,
+						"exe_path":  appUsage.ExePath,
+						"date":      normalizedDate.Format("2006-01-02"),
+						"operation": "ResolveApplication",
+					},
+				)
+			}
+
 			// Get current duration to check for overflow
-			currentApp, err := txRepo.queries.GetAppUsageByNameAndDate(ctx, queries.GetAppUsageByNameAndDateParams{
-				Name: appName,
-				Date: normalizedDate,
+			currentApp, err := txRepo.queries.GetAppUsageByApplicationAndDate(ctx, queries.GetAppUsageByApplicationAndDateParams{
+				ApplicationID: applicationID,
+				Date:          normalizedDate,
 			})
 
 			if err != nil && !errors.Is(err, sql.ErrNoRows) {
 				return repoerrors.NewRepositoryErrorWithContext(
-					"BatchIncrementAppUsageDurations",
+					"BatchIncrementAppUsage",
 					err,
 					r.classifyError(err),
 					map[string]string{
-						"app_name":  appName,
+						"app_name":  appUsage.Name,
 						"date":      normalizedDate.Format("2006-01-02"),
 						"operation": "GetCurrentDuration",
 					},
 				)
 			}
 
-			// If record is missing, insert instead of dropping the increment
-			if errors.Is(err, sql.ErrNoRows) {
-				if insertErr := txRepo.queries.InsertAppUsage(ctx, queries.InsertAppUsageParams{
-					Name:     appName,
-					Duration: additionalDuration,
-					IconPath: txRepo.nullStringFromString(""),
-					ExePath:  txRepo.nullStringFromString(""),
-					Date:     normalizedDate,
-				}); insertErr != nil {
-					return repoerrors.NewRepositoryErrorWithContext(
-						"BatchIncrementAppUsageDurations",
-						insertErr,
-						r.classifyError(insertErr),
-						map[string]string{
-							"app_name":            appName,
-							"date":                normalizedDate.Format("2006-01-02"),
-							"additional_duration": fmt.Sprintf("%d", additionalDuration),
-							"operation":           "InsertOnMissing",
-						},
-					)
-				}
-				continue
-			}
-
-			// Check for overflow if record exists
+			// Check for overflow if record exists; missing records are created by the increment
 			if err == nil {
-				if currentApp.Duration > math.MaxInt64-additionalDuration {
+				if currentApp.Duration > math.MaxInt64-appUsage.Duration {
 					return repoerrors.NewRepositoryErrorWithContext(
-						"BatchIncrementAppUsageDurations",
+						"BatchIncrementAppUsage",
 						errors.New("duration increment would cause integer overflow"),
 						repoerrors.ErrCodeValidation,
 						map[string]string{
-							"app_name":            appName,
+							"app_name":            appUsage.Name,
 							"current_duration":    fmt.Sprintf("%d", currentApp.Duration),
-							"additional_duration": fmt.Sprintf("%d", additionalDuration),
+							"additional_duration": fmt.Sprintf("%d", appUsage.Duration),
 							"max_int64":           fmt.Sprintf("%d", int64(math.MaxInt64)),
 						},
 					)
 				}
 			}
 
-			// Perform the increment
-			err = txRepo.queries.BatchUpdateAppUsage(ctx, queries.BatchUpdateAppUsageParams{
-				Duration: additionalDuration,
-				Name:     appName,
-				Date:     normalizedDate,
+			// Perform the increment as a single upsert so concurrent writers can't
+			// lose each other's time or race on inserting a missing row
+			err = txRepo.queries.IncrementAppUsageDuration(ctx, queries.IncrementAppUsageDurationParams{
+				Name:          appUsage.Name,
+				Duration:      appUsage.Duration,
+				ApplicationID: applicationID,
+				Date:          normalizedDate,
 			})
 			if err != nil {
 				return repoerrors.NewRepositoryErrorWithContext(
-					"BatchIncrementAppUsageDurations",
+					"BatchIncrementAppUsage",
 					err,
 					r.classifyError(err),
 					map[string]string{
-						"app_name":            appName,
+						"app_name":            appUsage.Name,
 						"date":                normalizedDate.Format("2006-01-02"),
-						"additional_duration": fmt.Sprintf("%d", additionalDuration),
+						"additional_duration": fmt.Sprintf("%d", appUsage.Duration),
+					},
+				)
+			}
+		}
+		return nil
+	})
+}
+
+// BatchUpdateAppUsageMetadata writes icon and executable paths for multiple apps without changing durations
+// Apps that have no record for the date yet are created with zero duration
+func (r *SQLiteRepository) BatchUpdateAppUsageMetadata(ctx context.Context, date time.Time, appUsages []types.AppUsage) error {
+	if len(appUsages) == 0 {
+		return nil
+	}
+
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
+
+	return r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+
+		for _, appUsage := range appUsages {
+			if strings.TrimSpace(appUsage.Name) == "" {
+				return repoerrors.NewRepositoryErrorWithContext(
+					"BatchUpdateAppUsageMetadata",
+					errors.New("app name is empty or whitespace"),
+					repoerrors.ErrCodeValidation,
+					map[string]string{
+						"date": normalizedDate.Format("2006-01-02"),
+					},
+				)
+			}
+
+			cols, err := r.resolveAppUsageColumns(ctx, txRepo.queries, &appUsage)
+			if err == nil {
+				err = txRepo.queries.UpsertAppUsageMetadata(ctx, queries.UpsertAppUsageMetadataParams{
+					Name:          appUsage.Name,
+					IconPath:      cols.iconPath,
+					ExePath:       cols.exePath,
+					IconHash:      cols.iconHash,
+					ApplicationID: cols.applicationID,
+					Date:          normalizedDate,
+				})
+			}
+			if err != nil {
+				return repoerrors.NewRepositoryErrorWithContext(
+					"BatchUpdateAppUsageMetadata",
+					err,
+					r.classifyError(err),
+					map[string]string{
+						"app_name": appUsage.Name,
+						"date":     normalizedDate.Format("2006-01-02"),
 					},
 				)
 			}
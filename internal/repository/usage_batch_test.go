@@ -1,14 +1,20 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"math"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
 	"qwin/internal/types"
 )
 
@@ -169,6 +175,46 @@ func TestSQLiteRepository_BatchProcessAppUsageWithBatchSize_Validation(t *testin
 	}
 }
 
+func TestSQLiteRepository_BatchProcessAppUsage_LogsCarryOperationIDs(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepository(t)
+
+	var out bytes.Buffer
+	repo.SetLogger(logging.NewSlogLogger(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	ctx := logging.ContextWithFields(context.Background(), "persist_id", "flush-1")
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	appUsages := []types.AppUsage{
+		{Name: "CorrelatedApp1", Duration: 60},
+		{Name: "CorrelatedApp2", Duration: 120},
+	}
+	if err := repo.BatchProcessAppUsageWithBatchSize(ctx, date, appUsages, types.BatchStrategyUpsert, 1); err != nil {
+		t.Fatalf("BatchProcessAppUsageWithBatchSize failed: %v", err)
+	}
+
+	// Each chunk's transaction and the batch summary are all logged under one batch ID
+	var operations []string
+	var batchID interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		var record map[string]interface{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		if record["persist_id"] != "flush-1" || record["batch_id"] == nil {
+			t.Errorf("record lacks operation IDs: %v", record)
+		}
+		if batchID == nil {
+			batchID = record["batch_id"]
+		} else if record["batch_id"] != batchID {
+			t.Errorf("batch_id = %v, want %v", record["batch_id"], batchID)
+		}
+		operations = append(operations, fmt.Sprint(record["operation"]))
+	}
+	if want := []string{"WithTransaction", "WithTransaction", "BatchProcessAppUsage"}; fmt.Sprint(operations) != fmt.Sprint(want) {
+		t.Errorf("logged operations = %v, want %v", operations, want)
+	}
+}
+
 func TestSQLiteRepository_BatchIncrementAppUsageDurations(t *testing.T) {
 	repo := setupTestRepository(t)
 	ctx := context.Background()
@@ -288,3 +334,98 @@ func TestSQLiteRepository_BatchIncrementAppUsageDurations(t *testing.T) {
 		t.Errorf("Expected app duration 1800, got %d", retrievedApps2[0].Duration)
 	}
 }
+
+func TestSQLiteRepository_BatchUpdateAppUsageMetadata(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	if err := repo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Tracked", Duration: 600, IconPath: "old.png"}); err != nil {
+		t.Fatalf("SaveAppUsage failed: %v", err)
+	}
+
+	err := repo.BatchUpdateAppUsageMetadata(ctx, date, []types.AppUsage{
+		{Name: "Tracked", IconPath: "new.png", ExePath: `C:\tracked.exe`},
+		{Name: "Untracked", IconPath: "other.png"},
+	})
+	if err != nil {
+		t.Fatalf("BatchUpdateAppUsageMetadata failed: %v", err)
+	}
+
+	apps, err := repo.GetAppUsageByDate(ctx, date)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate failed: %v", err)
+	}
+
+	byName := make(map[string]types.AppUsage, len(apps))
+	for _, app := range apps {
+		byName[app.Name] = app
+	}
+
+	tracked := byName["Tracked"]
+	if tracked.Duration != 600 {
+		t.Errorf("metadata update changed duration to %d, want 600", tracked.Duration)
+	}
+	if tracked.IconPath != "new.png" || tracked.ExePath != `C:\tracked.exe` {
+		t.Errorf("metadata not updated: %+v", tracked)
+	}
+
+	untracked, exists := byName["Untracked"]
+	if !exists || untracked.Duration != 0 || untracked.IconPath != "other.png" {
+		t.Errorf("missing app should be created with zero duration, got %+v", untracked)
+	}
+
+	if err := repo.BatchUpdateAppUsageMetadata(ctx, date, []types.AppUsage{{Name: "  "}}); !repoerrors.IsValidation(err) {
+		t.Errorf("expected validation error for empty name, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_ConcurrentIncrements(t *testing.T) {
+	// Two repositories on the same file behave like two tracker processes
+	dbPath := filepath.Join(t.TempDir(), "concurrent.db")
+	repoA := setupFileRepository(t, dbPath)
+	repoB := setupFileRepository(t, dbPath)
+	ctx := context.Background()
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	const rounds = 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*rounds)
+	for _, repo := range []*SQLiteRepository{repoA, repoB} {
+		wg.Add(1)
+		go func(repo *SQLiteRepository) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				err := repo.WithTransaction(ctx, func(txRepo UsageRepository) error {
+					if err := txRepo.IncrementDailyUsage(ctx, date, 2); err != nil {
+						return err
+					}
+					return txRepo.BatchIncrementAppUsageDurations(ctx, date, map[string]int64{"Shared": 1})
+				})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(repo)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("concurrent increment failed: %v", err)
+	}
+
+	daily, err := repoA.GetDailyUsage(ctx, date)
+	if err != nil {
+		t.Fatalf("GetDailyUsage failed: %v", err)
+	}
+	if daily.TotalTime != 4*rounds {
+		t.Errorf("TotalTime = %d, want %d", daily.TotalTime, 4*rounds)
+	}
+	if len(daily.Apps) != 1 || daily.Apps[0].Duration != 2*rounds {
+		t.Errorf("Shared duration = %+v, want %d", daily.Apps, 2*rounds)
+	}
+}
@@ -8,8 +8,11 @@ import (
 
 	repoerrors "qwin/internal/infrastructure/errors"
 	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
 )
 
+var _ StorageHealthReporter = (*SQLiteRepository)(nil)
+
 // Configuration methods
 
 // SetRetryConfig updates the retry configuration for the repository
@@ -19,6 +22,13 @@ func (r *SQLiteRepository) SetRetryConfig(config *repoerrors.RetryConfig) {
 	}
 }
 
+// SetCircuitBreaker replaces the circuit breaker guarding storage operations
+func (r *SQLiteRepository) SetCircuitBreaker(breaker *repoerrors.CircuitBreaker) {
+	if breaker != nil {
+		r.breaker = breaker
+	}
+}
+
 // SetLogger updates the logger for the repository
 func (r *SQLiteRepository) SetLogger(logger logging.Logger) {
 	if logger != nil {
@@ -33,6 +43,11 @@ func (r *SQLiteRepository) SetBatchConfig(config *BatchConfig) {
 	}
 }
 
+// SetDayBoundary updates the day boundary used to decide which date is today
+func (r *SQLiteRepository) SetDayBoundary(boundary types.DayBoundary) {
+	r.dayBoundary = boundary
+}
+
 // GetBatchConfig returns the current batch configuration
 func (r *SQLiteRepository) GetBatchConfig() *BatchConfig {
 	return r.batchConfig
@@ -43,6 +58,28 @@ func (r *SQLiteRepository) GetRetryConfig() *repoerrors.RetryConfig {
 	return r.retryConfig
 }
 
+// StorageStatus returns the state of the circuit breaker guarding storage operations
+func (r *SQLiteRepository) StorageStatus() repoerrors.CircuitStatus {
+	if r.breaker == nil {
+		return repoerrors.CircuitStatus{State: repoerrors.CircuitClosed}
+	}
+	return r.breaker.Status()
+}
+
+// SetStorageStatusHandler registers a function called whenever storage becomes unavailable or available again
+func (r *SQLiteRepository) SetStorageStatusHandler(handler func(repoerrors.CircuitStatus)) {
+	if r.breaker != nil {
+		r.breaker.SetStateChangeHandler(handler)
+	}
+}
+
+// ResetStorageStatus closes the circuit breaker, e.g. once the database has been reconnected
+func (r *SQLiteRepository) ResetStorageStatus() {
+	if r.breaker != nil {
+		r.breaker.Reset()
+	}
+}
+
 // SetDynamicBatchSize updates batch size configuration at runtime based on operation type
 func (r *SQLiteRepository) SetDynamicBatchSize(operationType string, batchSize int) error {
 	if r.batchConfig == nil {
@@ -78,13 +115,13 @@ func (r *SQLiteRepository) HealthCheck(ctx context.Context) error {
 	start := time.Now()
 
 	// Test basic connectivity
-	err := repoerrors.WithRetry(ctx, r.retryConfig, func() error {
+	err := r.withRetry(ctx, "HealthCheck.Ping", func() error {
 		if err := r.db.PingContext(ctx); err != nil {
 			repoErr := repoerrors.NewRepositoryError("HealthCheck.Ping", err, r.classifyError(err))
 			if repoErr.IsRetryable() {
-				r.logger.Debug("Retryable error in health check ping", "error", err)
+				r.loggerFor(ctx).Debug("Retryable error in health check ping", "error", err)
 			} else {
-				logging.LogError(r.logger, repoErr, "HealthCheck.Ping", nil)
+				logging.LogError(r.loggerFor(ctx), repoErr, "HealthCheck.Ping", nil)
 			}
 			return repoErr
 		}
@@ -96,15 +133,15 @@ func (r *SQLiteRepository) HealthCheck(ctx context.Context) error {
 	}
 
 	// Test a simple query
-	err = repoerrors.WithRetry(ctx, r.retryConfig, func() error {
+	err = r.withRetry(ctx, "HealthCheck.Query", func() error {
 		var count int
 		err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type='table'").Scan(&count)
 		if err != nil {
 			repoErr := repoerrors.NewRepositoryError("HealthCheck.Query", err, r.classifyError(err))
 			if repoErr.IsRetryable() {
-				r.logger.Debug("Retryable error in health check query", "error", err)
+				r.loggerFor(ctx).Debug("Retryable error in health check query", "error", err)
 			} else {
-				logging.LogError(r.logger, repoErr, "HealthCheck.Query", nil)
+				logging.LogError(r.loggerFor(ctx), repoErr, "HealthCheck.Query", nil)
 			}
 			return repoErr
 		}
@@ -113,7 +150,7 @@ func (r *SQLiteRepository) HealthCheck(ctx context.Context) error {
 
 	// Log successful health check
 	if err == nil {
-		logging.LogOperation(r.logger, "HealthCheck", time.Since(start), nil)
+		logging.LogOperation(r.loggerFor(ctx), "HealthCheck", time.Since(start), nil)
 	}
 
 	return err
@@ -120,3 +120,49 @@ func TestSQLiteRepository_ConfigurationMethods(t *testing.T) {
 		t.Errorf("Expected validation error for batch size exceeding maximum, got: %v", err)
 	}
 }
+
+func TestSQLiteRepository_CircuitBreakerReportsUnavailableStorage(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	repo.SetRetryConfig(&repoerrors.RetryConfig{MaxAttempts: 1})
+
+	changes := make(chan repoerrors.CircuitStatus, 4)
+	repo.SetStorageStatusHandler(func(status repoerrors.CircuitStatus) {
+		changes <- status
+	})
+
+	if status := repo.StorageStatus(); status.State != repoerrors.CircuitClosed {
+		t.Fatalf("StorageStatus() = %s before any failure, want closed", status.State)
+	}
+
+	// Calls keep failing once the connection is gone, until the breaker stops making them
+	repo.db.current().Close()
+	threshold := repoerrors.DefaultCircuitBreakerConfig().FailureThresholds[repoerrors.ErrCodeConnection]
+	for i := 0; i < threshold; i++ {
+		if err := repo.SetSetting(ctx, "theme", "dark"); err == nil || repoerrors.IsUnavailable(err) {
+			t.Fatalf("call %d: expected the connection error, got %v", i+1, err)
+		}
+	}
+
+	err := repo.HealthCheck(ctx)
+	if !repoerrors.IsUnavailable(err) {
+		t.Fatalf("HealthCheck() = %v, want an unavailable error", err)
+	}
+	status := repo.StorageStatus()
+	if status.State != repoerrors.CircuitOpen || status.ErrorCode != "CONNECTION" || status.Rejected != 1 {
+		t.Errorf("StorageStatus() = %+v", status)
+	}
+	select {
+	case change := <-changes:
+		if change.State != repoerrors.CircuitOpen {
+			t.Errorf("reported state = %s, want open", change.State)
+		}
+	default:
+		t.Error("Expected the circuit opening to be reported")
+	}
+
+	repo.ResetStorageStatus()
+	if status := repo.StorageStatus(); status.State != repoerrors.CircuitClosed {
+		t.Errorf("StorageStatus() = %s after reset, want closed", status.State)
+	}
+}
@@ -19,7 +19,7 @@ func (r *SQLiteRepository) SaveDailyUsage(ctx context.Context, date time.Time, u
 
 	if usage == nil {
 		err := repoerrors.NewRepositoryError("SaveDailyUsage", errors.New("usage data is nil"), repoerrors.ErrCodeValidation)
-		logging.LogError(r.logger, err, "SaveDailyUsage", map[string]any{
+		logging.LogError(r.loggerFor(ctx), err, "SaveDailyUsage", map[string]any{
 			"date": date.Format("2006-01-02"),
 		})
 		return err
@@ -28,18 +28,18 @@ func (r *SQLiteRepository) SaveDailyUsage(ctx context.Context, date time.Time, u
 	// Validate usage data fields
 	if usage.TotalTime < 0 {
 		err := repoerrors.NewRepositoryError("SaveDailyUsage", fmt.Errorf("total time is negative: %d", usage.TotalTime), repoerrors.ErrCodeValidation)
-		logging.LogError(r.logger, err, "SaveDailyUsage", map[string]any{
+		logging.LogError(r.loggerFor(ctx), err, "SaveDailyUsage", map[string]any{
 			"date":       date.Format("2006-01-02"),
 			"total_time": usage.TotalTime,
 		})
 		return err
 	}
 
-	// Normalize date to start of day
-	normalizedDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
 
 	// Execute with retry logic
-	err := repoerrors.WithRetry(ctx, r.retryConfig, func() error {
+	err := r.withRetry(ctx, "SaveDailyUsage", func() error {
 		_, err := r.queries.UpsertDailyUsage(ctx, queries.UpsertDailyUsageParams{
 			Date:      normalizedDate,
 			TotalTime: usage.TotalTime,
@@ -53,9 +53,9 @@ func (r *SQLiteRepository) SaveDailyUsage(ctx context.Context, date time.Time, u
 
 			// Log retryable errors at debug level, non-retryable at error level
 			if repoErr.IsRetryable() {
-				r.logger.Debug("Retryable error in SaveDailyUsage", "error", err, "date", normalizedDate)
+				r.loggerFor(ctx).Debug("Retryable error in SaveDailyUsage", "error", err, "date", normalizedDate)
 			} else {
-				logging.LogError(r.logger, repoErr, "SaveDailyUsage", map[string]any{
+				logging.LogError(r.loggerFor(ctx), repoErr, "SaveDailyUsage", map[string]any{
 					"date":       normalizedDate.Format("2006-01-02"),
 					"total_time": usage.TotalTime,
 				})
@@ -69,7 +69,7 @@ func (r *SQLiteRepository) SaveDailyUsage(ctx context.Context, date time.Time, u
 
 	// Log successful operation
 	if err == nil {
-		logging.LogOperation(r.logger, "SaveDailyUsage", time.Since(start), map[string]any{
+		logging.LogOperation(r.loggerFor(ctx), "SaveDailyUsage", time.Since(start), map[string]any{
 			"date":       normalizedDate.Format("2006-01-02"),
 			"total_time": usage.TotalTime,
 		})
@@ -78,17 +78,74 @@ func (r *SQLiteRepository) SaveDailyUsage(ctx context.Context, date time.Time, u
 	return err
 }
 
+// IncrementDailyUsage adds additionalTime seconds to the daily total with retry logic
+// The record is created if it doesn't exist, so concurrent writers each contribute their own time
+func (r *SQLiteRepository) IncrementDailyUsage(ctx context.Context, date time.Time, additionalTime int64) error {
+	start := time.Now()
+
+	if additionalTime < 0 {
+		err := repoerrors.NewRepositoryErrorWithContext("IncrementDailyUsage", errors.New("negative increment not allowed"), repoerrors.ErrCodeValidation, map[string]string{
+			"date":            date.Format("2006-01-02"),
+			"additional_time": fmt.Sprintf("%d", additionalTime),
+		})
+		logging.LogError(r.loggerFor(ctx), err, "IncrementDailyUsage", map[string]any{
+			"date":            date.Format("2006-01-02"),
+			"additional_time": additionalTime,
+		})
+		return err
+	}
+
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
+
+	err := r.withRetry(ctx, "IncrementDailyUsage", func() error {
+		err := r.queries.IncrementDailyUsage(ctx, queries.IncrementDailyUsageParams{
+			Date:      normalizedDate,
+			TotalTime: additionalTime,
+		})
+
+		if err != nil {
+			repoErr := repoerrors.NewRepositoryErrorWithContext("IncrementDailyUsage", err, r.classifyError(err), map[string]string{
+				"date":            normalizedDate.Format("2006-01-02"),
+				"additional_time": fmt.Sprintf("%d", additionalTime),
+			})
+
+			if repoErr.IsRetryable() {
+				r.loggerFor(ctx).Debug("Retryable error in IncrementDailyUsage", "error", err, "date", normalizedDate)
+			} else {
+				logging.LogError(r.loggerFor(ctx), repoErr, "IncrementDailyUsage", map[string]any{
+					"date":            normalizedDate.Format("2006-01-02"),
+					"additional_time": additionalTime,
+				})
+			}
+
+			return repoErr
+		}
+
+		return nil
+	})
+
+	if err == nil {
+		logging.LogOperation(r.loggerFor(ctx), "IncrementDailyUsage", time.Since(start), map[string]any{
+			"date":            normalizedDate.Format("2006-01-02"),
+			"additional_time": additionalTime,
+		})
+	}
+
+	return err
+}
+
 // GetDailyUsage retrieves daily usage data for a specific date with enhanced error handling
 func (r *SQLiteRepository) GetDailyUsage(ctx context.Context, date time.Time) (*types.UsageData, error) {
 	start := time.Now()
 
-	// Normalize date to start of day
-	normalizedDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
 
 	var result *types.UsageData
 
 	// Execute with retry logic for transient errors
-	err := repoerrors.WithRetry(ctx, r.retryConfig, func() error {
+	err := r.withRetry(ctx, "GetDailyUsage", func() error {
 		// Get daily usage summary
 		dailyUsage, err := r.queries.GetDailyUsageByDate(ctx, normalizedDate)
 		if err != nil {
@@ -105,9 +162,9 @@ func (r *SQLiteRepository) GetDailyUsage(ctx context.Context, date time.Time) (*
 			})
 
 			if repoErr.IsRetryable() {
-				r.logger.Debug("Retryable error in GetDailyUsage", "error", err, "date", normalizedDate)
+				r.loggerFor(ctx).Debug("Retryable error in GetDailyUsage", "error", err, "date", normalizedDate)
 			} else {
-				logging.LogError(r.logger, repoErr, "GetDailyUsage", map[string]any{
+				logging.LogError(r.loggerFor(ctx), repoErr, "GetDailyUsage", map[string]any{
 					"date":      normalizedDate.Format("2006-01-02"),
 					"operation": "GetDailyUsageByDate",
 				})
@@ -125,9 +182,9 @@ func (r *SQLiteRepository) GetDailyUsage(ctx context.Context, date time.Time) (*
 			})
 
 			if repoErr.IsRetryable() {
-				r.logger.Debug("Retryable error in GetDailyUsage", "error", err, "date", normalizedDate)
+				r.loggerFor(ctx).Debug("Retryable error in GetDailyUsage", "error", err, "date", normalizedDate)
 			} else {
-				logging.LogError(r.logger, repoErr, "GetDailyUsage", map[string]interface{}{
+				logging.LogError(r.loggerFor(ctx), repoErr, "GetDailyUsage", map[string]interface{}{
 					"date":      normalizedDate.Format("2006-01-02"),
 					"operation": "GetAppUsageByDate",
 				})
@@ -137,9 +194,12 @@ func (r *SQLiteRepository) GetDailyUsage(ctx context.Context, date time.Time) (*
 		}
 
 		// Convert to types.AppUsage
-		apps := make([]types.AppUsage, len(appUsageRows))
-		for i, row := range appUsageRows {
-			apps[i] = r.convertAppUsageFromDB(row)
+		apps, err := r.convertAppUsageRows(ctx, appUsageRows)
+		if err != nil {
+			return repoerrors.NewRepositoryErrorWithContext("GetDailyUsage", err, r.classifyError(err), map[string]string{
+				"date":      normalizedDate.Format("2006-01-02"),
+				"operation": "ListApplicationDisplayNames",
+			})
 		}
 
 		result = &types.UsageData{
@@ -152,7 +212,7 @@ func (r *SQLiteRepository) GetDailyUsage(ctx context.Context, date time.Time) (*
 
 	// Log successful operation
 	if err == nil {
-		logging.LogOperation(r.logger, "GetDailyUsage", time.Since(start), map[string]interface{}{
+		logging.LogOperation(r.loggerFor(ctx), "GetDailyUsage", time.Since(start), map[string]interface{}{
 			"date":      normalizedDate.Format("2006-01-02"),
 			"app_count": len(result.Apps),
 		})
@@ -83,3 +83,30 @@ func TestSQLiteRepository_GetDailyUsage_NotFound(t *testing.T) {
 		t.Errorf("Expected NotFound error, got: %v", err)
 	}
 }
+
+func TestSQLiteRepository_IncrementDailyUsage(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// Creates the record when missing
+	if err := repo.IncrementDailyUsage(ctx, date, 300); err != nil {
+		t.Fatalf("IncrementDailyUsage failed: %v", err)
+	}
+	if err := repo.IncrementDailyUsage(ctx, date, 200); err != nil {
+		t.Fatalf("IncrementDailyUsage failed: %v", err)
+	}
+
+	usage, err := repo.GetDailyUsage(ctx, date)
+	if err != nil {
+		t.Fatalf("GetDailyUsage failed: %v", err)
+	}
+	if usage.TotalTime != 500 {
+		t.Errorf("TotalTime = %d, want 500", usage.TotalTime)
+	}
+
+	if err := repo.IncrementDailyUsage(ctx, date, -1); !repoerrors.IsValidation(err) {
+		t.Errorf("expected validation error for negative increment, got %v", err)
+	}
+}
@@ -0,0 +1,626 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// Ensure SQLiteRepository implements DeviceSyncRepository interface
+var _ DeviceSyncRepository = (*SQLiteRepository)(nil)
+
+// usageKey identifies usage of one app, or the total when app is empty, on one date
+type usageKey struct {
+	app  string
+	date time.Time
+}
+
+// ListSyncDevices retrieves every device usage has been synced with, the local device first
+func (r *SQLiteRepository) ListSyncDevices(ctx context.Context) ([]types.SyncDevice, error) {
+	rows, err := r.queries.ListSyncDevices(ctx)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("ListSyncDevices", err, r.classifyError(err))
+	}
+
+	devices := make([]types.SyncDevice, len(rows))
+	for i, row := range rows {
+		devices[i] = r.convertSyncDeviceFromDB(row)
+	}
+	return devices, nil
+}
+
+// ApplySyncEntries applies the entries of a device's change log that follow the last one
+// applied from it. Entries already applied are skipped, so the whole log can be passed
+// every time. Applying stops at a gap in the numbering or an invalid entry; the entries
+// before it are kept and a constraint error reports where it stopped.
+func (r *SQLiteRepository) ApplySyncEntries(ctx context.Context, device types.SyncDevice, entries []types.SyncLogEntry) (int, error) {
+	start := time.Now()
+	if err := types.ValidateDeviceID(device.ID); err != nil {
+		return 0, repoerrors.NewRepositoryError("ApplySyncEntries", err, repoerrors.ErrCodeValidation)
+	}
+
+	var applied int
+	var stopErr error
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+		applied, stopErr = 0, nil
+
+		lastSeq, err := txRepo.lastSyncSeq(ctx, device.ID)
+		if err != nil {
+			return err
+		}
+
+		// Usage rows reference the device, so it is registered before anything is applied
+		if err := txRepo.upsertSyncDevice(ctx, device, device.Name, lastSeq); err != nil {
+			return err
+		}
+
+		name := device.Name
+		for _, entry := range entries {
+			if entry.Seq <= lastSeq {
+				continue
+			}
+			if stopErr = validateSyncEntry(device.ID, lastSeq, entry); stopErr != nil {
+				break
+			}
+			if err := txRepo.applySyncEntry(ctx, entry); err != nil {
+				return err
+			}
+			lastSeq = entry.Seq
+			applied++
+			if entry.DeviceName != "" {
+				name = entry.DeviceName
+			}
+		}
+
+		return txRepo.upsertSyncDevice(ctx, device, name, lastSeq)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "ApplySyncEntries", time.Since(start), map[string]interface{}{
+		"device_id": device.ID,
+		"applied":   applied,
+	})
+	if stopErr != nil {
+		return applied, repoerrors.NewRepositoryErrorWithContext("ApplySyncEntries", stopErr, repoerrors.ErrCodeConstraint, map[string]string{
+			"device_id": device.ID,
+		})
+	}
+	return applied, nil
+}
+
+// upsertSyncDevice records a device as synced now, up to the entry lastSeq
+func (r *SQLiteRepository) upsertSyncDevice(ctx context.Context, device types.SyncDevice, name string, lastSeq int64) error {
+	if err := r.queries.UpsertSyncDevice(ctx, queries.UpsertSyncDeviceParams{
+		DeviceID:     device.ID,
+		Name:         name,
+		IsLocal:      device.Local,
+		LastSeq:      lastSeq,
+		LastSyncedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	}); err != nil {
+		return repoerrors.NewRepositoryErrorWithContext("ApplySyncEntries", err, r.classifyError(err), map[string]string{
+			"device_id": device.ID,
+		})
+	}
+	return nil
+}
+
+// validateSyncEntry checks that entry is valid, belongs to deviceID and directly follows lastSeq
+func validateSyncEntry(deviceID string, lastSeq int64, entry types.SyncLogEntry) error {
+	if entry.DeviceID != deviceID {
+		return fmt.Errorf("change log entry %d belongs to device %q", entry.Seq, entry.DeviceID)
+	}
+	if entry.Seq != lastSeq+1 {
+		return fmt.Errorf("change log entries %d to %d are missing", lastSeq+1, entry.Seq-1)
+	}
+	return entry.Validate()
+}
+
+// applySyncEntry adds an entry's delta to its device's usage
+func (r *SQLiteRepository) applySyncEntry(ctx context.Context, entry types.SyncLogEntry) error {
+	date, err := entry.DateKey()
+	if err != nil {
+		return repoerrors.NewRepositoryError("ApplySyncEntries", err, repoerrors.ErrCodeValidation)
+	}
+
+	if entry.Kind == types.SyncEntryTotal {
+		err = r.queries.IncrementDeviceDailyUsage(ctx, queries.IncrementDeviceDailyUsageParams{
+			DeviceID:  entry.DeviceID,
+			Date:      date,
+			TotalTime: entry.Delta,
+		})
+	} else {
+		err = r.queries.IncrementDeviceAppUsage(ctx, queries.IncrementDeviceAppUsageParams{
+			DeviceID: entry.DeviceID,
+			AppName:  entry.App,
+			Date:     date,
+			Duration: entry.Delta,
+		})
+	}
+	if err != nil {
+		return repoerrors.NewRepositoryErrorWithContext("ApplySyncEntries", err, r.classifyError(err), map[string]string{
+			"device_id": entry.DeviceID,
+			"seq":       fmt.Sprintf("%d", entry.Seq),
+		})
+	}
+	return nil
+}
+
+// lastSyncSeq returns the last change log entry applied from a device, 0 for a new device
+func (r *SQLiteRepository) lastSyncSeq(ctx context.Context, deviceID string) (int64, error) {
+	row, err := r.queries.GetSyncDevice(ctx, deviceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, repoerrors.NewRepositoryErrorWithContext("lastSyncSeq", err, r.classifyError(err), map[string]string{
+			"device_id": deviceID,
+		})
+	}
+	return row.LastSeq, nil
+}
+
+// ResetSync forgets every device and the usage merged from them, along with what the
+// local device has exported, so the next sync exports all local usage again
+func (r *SQLiteRepository) ResetSync(ctx context.Context) error {
+	start := time.Now()
+
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+		steps := []func(context.Context) error{
+			txRepo.queries.DeleteAllDeviceAppUsage,
+			txRepo.queries.DeleteAllDeviceDailyUsage,
+			txRepo.queries.DeleteAllSyncDevices,
+		}
+		for _, step := range steps {
+			if err := step(ctx); err != nil {
+				return repoerrors.NewRepositoryError("ResetSync", err, r.classifyError(err))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "ResetSync", time.Since(start), nil)
+	return nil
+}
+
+// PendingSyncEntries compares local usage with what the local device has written to its
+// change log and returns entries for the differences, numbered after its last entry.
+// Removed usage, e.g. by ignore rules applied to history, yields negative deltas. Only
+// dates changed since they were last compared are read; dates found to match are
+// forgotten until they change again.
+func (r *SQLiteRepository) PendingSyncEntries(ctx context.Context, device types.SyncDevice) ([]types.SyncLogEntry, error) {
+	if err := types.ValidateDeviceID(device.ID); err != nil {
+		return nil, repoerrors.NewRepositoryError("PendingSyncEntries", err, repoerrors.ErrCodeValidation)
+	}
+
+	lastSeq, err := r.lastSyncSeq(ctx, device.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	dirty, err := r.queries.ListSyncDirtyDates(ctx)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("PendingSyncEntries", err, r.classifyError(err))
+	}
+	if len(dirty) == 0 {
+		return nil, nil
+	}
+
+	// Local usage on those dates, keyed the way the change log records it
+	current := make(map[usageKey]int64)
+	appRows, err := r.queries.ListDirtyAppUsage(ctx)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("PendingSyncEntries", err, r.classifyError(err))
+	}
+	for _, row := range appRows {
+		current[usageKey{app: row.Name, date: types.DateKey(row.Date)}] += row.Duration
+	}
+	dailyRows, err := r.queries.ListDirtyDailyUsage(ctx)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("PendingSyncEntries", err, r.classifyError(err))
+	}
+	for _, row := range dailyRows {
+		current[usageKey{date: types.DateKey(row.Date)}] += row.TotalTime
+	}
+
+	// What the change log already says for them
+	written := make(map[usageKey]int64)
+	writtenApps, err := r.queries.ListDirtyDeviceAppUsage(ctx, device.ID)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("PendingSyncEntries", err, r.classifyError(err))
+	}
+	for _, row := range writtenApps {
+		written[usageKey{app: row.AppName, date: types.DateKey(row.Date)}] += row.Duration
+	}
+	writtenDaily, err := r.queries.ListDirtyDeviceDailyUsage(ctx, device.ID)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("PendingSyncEntries", err, r.classifyError(err))
+	}
+	for _, row := range writtenDaily {
+		written[usageKey{date: types.DateKey(row.Date)}] += row.TotalTime
+	}
+
+	deltas := make(map[usageKey]int64)
+	for key, value := range current {
+		if delta := value - written[key]; delta != 0 {
+			deltas[key] = delta
+		}
+	}
+	for key, value := range written {
+		if _, exists := current[key]; !exists && value != 0 {
+			deltas[key] = -value
+		}
+	}
+
+	// Dates already in step are dropped now; the rest once the entries have been written
+	changed := make(map[time.Time]bool)
+	for key := range deltas {
+		changed[key.date] = true
+	}
+	for _, row := range dirty {
+		if changed[types.DateKey(row.Date)] {
+			continue
+		}
+		if err := r.queries.ClearSyncDirtyDate(ctx, queries.ClearSyncDirtyDateParams{Date: row.Date, Version: row.Version}); err != nil {
+			return nil, repoerrors.NewRepositoryError("PendingSyncEntries", err, r.classifyError(err))
+		}
+	}
+
+	keys := make([]usageKey, 0, len(deltas))
+	for key := range deltas {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].date.Equal(keys[j].date) {
+			return keys[i].date.Before(keys[j].date)
+		}
+		return keys[i].app < keys[j].app
+	})
+
+	entries := make([]types.SyncLogEntry, len(keys))
+	for i, key := range keys {
+		kind := types.SyncEntryApp
+		if key.app == "" {
+			kind = types.SyncEntryTotal
+		}
+		entries[i] = types.NewSyncLogEntry(device, lastSeq+int64(i)+1, kind, key.app, key.date, deltas[key])
+	}
+	return entries, nil
+}
+
+// SyncSnapshot returns the usage merged from a device, or written by the local device,
+// as of the last change log entry applied from it
+func (r *SQLiteRepository) SyncSnapshot(ctx context.Context, device types.SyncDevice) (*types.SyncSnapshot, error) {
+	if err := types.ValidateDeviceID(device.ID); err != nil {
+		return nil, repoerrors.NewRepositoryError("SyncSnapshot", err, repoerrors.ErrCodeValidation)
+	}
+
+	snapshot := &types.SyncSnapshot{DeviceID: device.ID, DeviceName: device.Name}
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+		snapshot.Usage = nil
+
+		var err error
+		if snapshot.Seq, err = txRepo.lastSyncSeq(ctx, device.ID); err != nil {
+			return err
+		}
+		appRows, err := txRepo.queries.ListDeviceAppUsage(ctx, device.ID)
+		if err != nil {
+			return repoerrors.NewRepositoryError("SyncSnapshot", err, r.classifyError(err))
+		}
+		dailyRows, err := txRepo.queries.ListDeviceDailyUsage(ctx, device.ID)
+		if err != nil {
+			return repoerrors.NewRepositoryError("SyncSnapshot", err, r.classifyError(err))
+		}
+
+		for _, row := range appRows {
+			if row.Duration != 0 {
+				snapshot.Usage = append(snapshot.Usage, newSyncUsage(types.SyncEntryApp, row.AppName, row.Date, row.Duration))
+			}
+		}
+		for _, row := range dailyRows {
+			if row.TotalTime != 0 {
+				snapshot.Usage = append(snapshot.Usage, newSyncUsage(types.SyncEntryTotal, "", row.Date, row.TotalTime))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// newSyncUsage creates snapshot usage for the day with the given date key
+func newSyncUsage(kind types.SyncEntryKind, app string, date time.Time, duration int64) types.SyncUsage {
+	entry := types.NewSyncLogEntry(types.SyncDevice{}, 0, kind, app, date, duration)
+	return types.SyncUsage{Kind: entry.Kind, App: entry.App, Date: entry.Date, Duration: entry.Delta}
+}
+
+// ApplySyncSnapshot replaces a device's usage with a snapshot of its change log, unless
+// the entries the snapshot covers have all been applied already. Entries after it are
+// then applied with ApplySyncEntries as usual.
+func (r *SQLiteRepository) ApplySyncSnapshot(ctx context.Context, device types.SyncDevice, snapshot *types.SyncSnapshot) (bool, error) {
+	start := time.Now()
+	if err := types.ValidateDeviceID(device.ID); err != nil {
+		return false, repoerrors.NewRepositoryError("ApplySyncSnapshot", err, repoerrors.ErrCodeValidation)
+	}
+	if snapshot.DeviceID != device.ID {
+		return false, repoerrors.NewRepositoryErrorWithContext("ApplySyncSnapshot",
+			fmt.Errorf("snapshot belongs to device %q", snapshot.DeviceID), repoerrors.ErrCodeConstraint, map[string]string{
+				"device_id": device.ID,
+			})
+	}
+	if err := snapshot.Validate(); err != nil {
+		return false, repoerrors.NewRepositoryErrorWithContext("ApplySyncSnapshot", err, repoerrors.ErrCodeValidation, map[string]string{
+			"device_id": device.ID,
+		})
+	}
+
+	var applied bool
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+		applied = false
+
+		lastSeq, err := txRepo.lastSyncSeq(ctx, device.ID)
+		if err != nil || snapshot.Seq <= lastSeq {
+			return err
+		}
+
+		name := device.Name
+		if snapshot.DeviceName != "" {
+			name = snapshot.DeviceName
+		}
+		if err := txRepo.upsertSyncDevice(ctx, device, name, lastSeq); err != nil {
+			return err
+		}
+		if err := txRepo.queries.DeleteDeviceAppUsage(ctx, device.ID); err != nil {
+			return repoerrors.NewRepositoryError("ApplySyncSnapshot", err, r.classifyError(err))
+		}
+		if err := txRepo.queries.DeleteDeviceDailyUsage(ctx, device.ID); err != nil {
+			return repoerrors.NewRepositoryError("ApplySyncSnapshot", err, r.classifyError(err))
+		}
+
+		// Each usage is applied as the entry adding it to nothing
+		for _, usage := range snapshot.Usage {
+			entry := types.SyncLogEntry{
+				Seq:      snapshot.Seq,
+				DeviceID: device.ID,
+				Kind:     usage.Kind,
+				App:      usage.App,
+				Date:     usage.Date,
+				Delta:    usage.Duration,
+			}
+			if err := txRepo.applySyncEntry(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		applied = true
+		return txRepo.upsertSyncDevice(ctx, device, name, snapshot.Seq)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "ApplySyncSnapshot", time.Since(start), map[string]interface{}{
+		"device_id": device.ID,
+		"seq":       snapshot.Seq,
+		"applied":   applied,
+	})
+	return applied, nil
+}
+
+// deviceUsage sums the merged usage of the devices include accepts within a date range.
+// Totals are keyed with an empty app name.
+func (r *SQLiteRepository) deviceUsage(ctx context.Context, startDate, endDate time.Time, include func(deviceID string) bool) (map[usageKey]int64, error) {
+	appRows, err := r.queries.GetDeviceAppUsageByDateRange(ctx, queries.GetDeviceAppUsageByDateRangeParams{
+		Date:   types.DateKey(startDate),
+		Date_2: types.DateKeyEnd(endDate),
+	})
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("deviceUsage", err, r.classifyError(err))
+	}
+	dailyRows, err := r.queries.GetDeviceDailyUsageByDateRange(ctx, queries.GetDeviceDailyUsageByDateRangeParams{
+		Date:   types.DateKey(startDate),
+		Date_2: types.DateKeyEnd(endDate),
+	})
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("deviceUsage", err, r.classifyError(err))
+	}
+
+	usage := make(map[usageKey]int64)
+	for _, row := range appRows {
+		if include(row.DeviceID) {
+			usage[usageKey{app: row.AppName, date: types.DateKey(row.Date)}] += row.Duration
+		}
+	}
+	for _, row := range dailyRows {
+		if include(row.DeviceID) {
+			usage[usageKey{date: types.DateKey(row.Date)}] += row.TotalTime
+		}
+	}
+	return usage, nil
+}
+
+// GetAppUsageByDateRangeForDevice retrieves app usage of one device, or of all devices
+// combined with types.DeviceScopeAll, ordered like GetAppUsageByDateRange. Combined rows
+// keep the icon and executable path of the local device where it used the app.
+func (r *SQLiteRepository) GetAppUsageByDateRangeForDevice(ctx context.Context, startDate, endDate time.Time, device string) ([]types.AppUsage, error) {
+	local, include, err := r.deviceScope(ctx, "GetAppUsageByDateRangeForDevice", device)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []types.AppUsage
+	if local {
+		if apps, err = r.GetAppUsageByDateRange(ctx, startDate, endDate); err != nil {
+			return nil, err
+		}
+	}
+	if include == nil {
+		return apps, nil
+	}
+
+	remote, err := r.deviceUsage(ctx, startDate, endDate, include)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[usageKey]int, len(apps))
+	for i, app := range apps {
+		index[usageKey{app: app.Name, date: types.DateKey(app.Date)}] = i
+	}
+	for key, duration := range remote {
+		if key.app == "" {
+			continue
+		}
+		if i, exists := index[key]; exists {
+			apps[i].Duration += duration
+			continue
+		}
+		index[key] = len(apps)
+		apps = append(apps, types.AppUsage{Name: key.app, Duration: duration, Date: key.date})
+	}
+
+	sort.SliceStable(apps, func(i, j int) bool {
+		if !apps[i].Date.Equal(apps[j].Date) {
+			return apps[i].Date.After(apps[j].Date)
+		}
+		return apps[i].Duration > apps[j].Duration
+	})
+	return apps, nil
+}
+
+// GetUsageHistoryForDevice retrieves usage history like GetUsageHistory for one device,
+// or for all devices combined with types.DeviceScopeAll
+func (r *SQLiteRepository) GetUsageHistoryForDevice(ctx context.Context, days int, device string) (map[string]*types.UsageData, error) {
+	local, include, err := r.deviceScope(ctx, "GetUsageHistoryForDevice", device)
+	if err != nil {
+		return nil, err
+	}
+	if include == nil {
+		return r.GetUsageHistory(ctx, days)
+	}
+	if days <= 0 {
+		return nil, repoerrors.NewRepositoryError("GetUsageHistoryForDevice", errors.New("days must be positive"), repoerrors.ErrCodeConstraint)
+	}
+
+	endDate := r.dayBoundary.DateOf(time.Now())
+	startDate := endDate.AddDate(0, 0, -days+1)
+
+	result := make(map[string]*types.UsageData)
+	if local {
+		if result, err = r.GetUsageHistory(ctx, days); err != nil {
+			return nil, err
+		}
+	}
+
+	remote, err := r.deviceUsage(ctx, startDate, endDate, include)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge each remote device's apps and totals into the local days, which may also list
+	// time entries, adding to the local tracked row of the same app where there is one
+	merged := make(map[string]bool)
+	for key, duration := range remote {
+		dateKey := key.date.Format("2006-01-02")
+		day, exists := result[dateKey]
+		if !exists {
+			day = &types.UsageData{Apps: []types.AppUsage{}}
+			result[dateKey] = day
+		}
+
+		if key.app == "" {
+			day.TotalTime += duration
+			if day.BySource != nil {
+				day.BySource[types.TimeSourceTracked] += duration
+			}
+			continue
+		}
+
+		merged[dateKey] = true
+		if i := trackedAppIndex(day.Apps, key.app); i >= 0 {
+			day.Apps[i].Duration += duration
+			continue
+		}
+		app := types.AppUsage{Name: key.app, Duration: duration, Date: key.date}
+		if day.BySource != nil {
+			app.Source = types.TimeSourceTracked
+		}
+		day.Apps = append(day.Apps, app)
+	}
+	for dateKey := range merged {
+		apps := result[dateKey].Apps
+		sort.SliceStable(apps, func(i, j int) bool { return apps[i].Duration > apps[j].Duration })
+	}
+	return result, nil
+}
+
+// trackedAppIndex returns the index of the tracked row of the named app, or -1
+func trackedAppIndex(apps []types.AppUsage, name string) int {
+	for i, app := range apps {
+		if app.Name == name && (app.Source == "" || app.Source == types.TimeSourceTracked) {
+			return i
+		}
+	}
+	return -1
+}
+
+// deviceScope resolves a device scope. local reports whether the local device's own usage
+// is included; include selects the synced devices whose merged usage is, nil for none.
+func (r *SQLiteRepository) deviceScope(ctx context.Context, operation, device string) (bool, func(string) bool, error) {
+	if device == "" || device == types.DeviceScopeLocal {
+		return true, nil, nil
+	}
+
+	rows, err := r.queries.ListSyncDevices(ctx)
+	if err != nil {
+		return false, nil, repoerrors.NewRepositoryError(operation, err, r.classifyError(err))
+	}
+	localIDs := make(map[string]bool)
+	known := false
+	for _, row := range rows {
+		if row.IsLocal {
+			localIDs[row.DeviceID] = true
+		}
+		known = known || row.DeviceID == device
+	}
+
+	switch {
+	case device == types.DeviceScopeAll:
+		return true, func(id string) bool { return !localIDs[id] }, nil
+	case localIDs[device]:
+		return true, nil, nil
+	case known:
+		return false, func(id string) bool { return id == device }, nil
+	default:
+		return false, nil, repoerrors.HandleNotFound(operation, "sync_device", device)
+	}
+}
+
+// convertSyncDeviceFromDB converts a database row to a sync device
+func (r *SQLiteRepository) convertSyncDeviceFromDB(row queries.SyncDevice) types.SyncDevice {
+	return types.SyncDevice{
+		ID:           row.DeviceID,
+		Name:         row.Name,
+		Local:        row.IsLocal,
+		LastSeq:      row.LastSeq,
+		LastSyncedAt: r.timeFromNullTime(row.LastSyncedAt),
+	}
+}
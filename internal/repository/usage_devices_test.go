@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func TestSQLiteRepository_ApplySyncEntries(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	date := types.DateKey(time.Now())
+	laptop := types.SyncDevice{ID: "laptop", Name: "Laptop"}
+
+	entries := []types.SyncLogEntry{
+		types.NewSyncLogEntry(laptop, 1, types.SyncEntryApp, "Code", date, 300),
+		types.NewSyncLogEntry(laptop, 2, types.SyncEntryTotal, "", date, 400),
+		types.NewSyncLogEntry(laptop, 3, types.SyncEntryApp, "Code", date, -100),
+	}
+	applied, err := repo.ApplySyncEntries(ctx, laptop, entries)
+	if err != nil || applied != 3 {
+		t.Fatalf("ApplySyncEntries() = (%d, %v), want 3 applied", applied, err)
+	}
+
+	// Passing the whole log again applies only what is new
+	entries = append(entries, types.NewSyncLogEntry(laptop, 4, types.SyncEntryApp, "Slack", date, 60))
+	if applied, err := repo.ApplySyncEntries(ctx, laptop, entries); err != nil || applied != 1 {
+		t.Fatalf("ApplySyncEntries() again = (%d, %v), want 1 applied", applied, err)
+	}
+
+	// A gap stops applying, keeping the entries before it
+	gapped := []types.SyncLogEntry{
+		types.NewSyncLogEntry(laptop, 5, types.SyncEntryApp, "Slack", date, 10),
+		types.NewSyncLogEntry(laptop, 7, types.SyncEntryApp, "Slack", date, 1000),
+	}
+	applied, err = repo.ApplySyncEntries(ctx, laptop, gapped)
+	if applied != 1 || !repoerrors.IsConstraint(err) {
+		t.Errorf("ApplySyncEntries() across a gap = (%d, %v), want 1 applied and a constraint error", applied, err)
+	}
+
+	devices, err := repo.ListSyncDevices(ctx)
+	if err != nil {
+		t.Fatalf("ListSyncDevices() error = %v", err)
+	}
+	if len(devices) != 1 || devices[0].Name != "Laptop" || devices[0].LastSeq != 5 {
+		t.Errorf("ListSyncDevices() = %+v, want the laptop at entry 5", devices)
+	}
+
+	apps, err := repo.GetAppUsageByDateRangeForDevice(ctx, date, date, "laptop")
+	if err != nil {
+		t.Fatalf("GetAppUsageByDateRangeForDevice() error = %v", err)
+	}
+	got := make(map[string]int64)
+	for _, app := range apps {
+		got[app.Name] = app.Duration
+	}
+	if got["Code"] != 200 || got["Slack"] != 70 {
+		t.Errorf("laptop usage = %v, want Code 200 and Slack 70", got)
+	}
+
+	if _, err := repo.ApplySyncEntries(ctx, types.SyncDevice{ID: "all"}, nil); !repoerrors.IsValidation(err) {
+		t.Errorf("ApplySyncEntries() with a reserved device ID should fail validation, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_PendingSyncEntriesAndCombinedUsage(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	date := types.DateKey(time.Now())
+	desktop := types.SyncDevice{ID: "desktop", Name: "Desktop", Local: true}
+
+	if err := repo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 600, ExePath: "/usr/bin/code"}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if err := repo.IncrementDailyUsage(ctx, date, 700); err != nil {
+		t.Fatalf("IncrementDailyUsage() error = %v", err)
+	}
+
+	pending, err := repo.PendingSyncEntries(ctx, desktop)
+	if err != nil {
+		t.Fatalf("PendingSyncEntries() error = %v", err)
+	}
+	if len(pending) != 2 || pending[0].Seq != 1 || pending[1].Seq != 2 {
+		t.Fatalf("PendingSyncEntries() = %+v, want the app and the total numbered from 1", pending)
+	}
+	if _, err := repo.ApplySyncEntries(ctx, desktop, pending); err != nil {
+		t.Fatalf("ApplySyncEntries() error = %v", err)
+	}
+	if pending, _ := repo.PendingSyncEntries(ctx, desktop); len(pending) != 0 {
+		t.Errorf("PendingSyncEntries() after writing = %+v, want none", pending)
+	}
+
+	// Only the difference is pending after more usage
+	if err := repo.BatchIncrementAppUsageDurations(ctx, date, map[string]int64{"Code": 60}); err != nil {
+		t.Fatalf("BatchIncrementAppUsageDurations() error = %v", err)
+	}
+	pending, err = repo.PendingSyncEntries(ctx, desktop)
+	if err != nil {
+		t.Fatalf("PendingSyncEntries() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].Seq != 3 || pending[0].App != "Code" || pending[0].Delta != 60 {
+		t.Errorf("PendingSyncEntries() = %+v, want Code +60 as entry 3", pending)
+	}
+
+	// Merge a second device and read the figures per device and combined
+	laptop := types.SyncDevice{ID: "laptop", Name: "Laptop"}
+	if _, err := repo.ApplySyncEntries(ctx, laptop, []types.SyncLogEntry{
+		types.NewSyncLogEntry(laptop, 1, types.SyncEntryApp, "Code", date, 100),
+		types.NewSyncLogEntry(laptop, 2, types.SyncEntryApp, "Mail", date, 50),
+		types.NewSyncLogEntry(laptop, 3, types.SyncEntryTotal, "", date, 200),
+	}); err != nil {
+		t.Fatalf("ApplySyncEntries() error = %v", err)
+	}
+
+	tests := []struct {
+		device    string
+		wantCode  int64
+		wantMail  int64
+		wantTotal int64
+	}{
+		{types.DeviceScopeLocal, 660, 0, 700},
+		{"desktop", 660, 0, 700},
+		{"laptop", 100, 50, 200},
+		{types.DeviceScopeAll, 760, 50, 900},
+	}
+	for _, tt := range tests {
+		t.Run(tt.device, func(t *testing.T) {
+			history, err := repo.GetUsageHistoryForDevice(ctx, 1, tt.device)
+			if err != nil {
+				t.Fatalf("GetUsageHistoryForDevice() error = %v", err)
+			}
+			day := history[date.Format("2006-01-02")]
+			if day == nil {
+				t.Fatalf("GetUsageHistoryForDevice() = %v, want today", history)
+			}
+			got := make(map[string]int64)
+			for _, app := range day.Apps {
+				got[app.Name] = app.Duration
+			}
+			if got["Code"] != tt.wantCode || got["Mail"] != tt.wantMail || day.TotalTime != tt.wantTotal {
+				t.Errorf("usage = %v total %d, want Code %d, Mail %d, total %d",
+					got, day.TotalTime, tt.wantCode, tt.wantMail, tt.wantTotal)
+			}
+		})
+	}
+
+	combined, err := repo.GetAppUsageByDateRangeForDevice(ctx, date, date, types.DeviceScopeAll)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDateRangeForDevice() error = %v", err)
+	}
+	if len(combined) != 2 || combined[0].Name != "Code" || combined[0].ExePath != "/usr/bin/code" {
+		t.Errorf("combined usage = %+v, want Code first with its local path", combined)
+	}
+
+	if _, err := repo.GetUsageHistoryForDevice(ctx, 1, "tablet"); !repoerrors.IsNotFound(err) {
+		t.Errorf("GetUsageHistoryForDevice() for an unknown device should be not found, got %v", err)
+	}
+
+	// After a reset, all local usage is pending again and other devices are forgotten
+	if err := repo.ResetSync(ctx); err != nil {
+		t.Fatalf("ResetSync() error = %v", err)
+	}
+	if devices, err := repo.ListSyncDevices(ctx); err != nil || len(devices) != 0 {
+		t.Errorf("ListSyncDevices() after reset = (%+v, %v), want none", devices, err)
+	}
+	pending, err = repo.PendingSyncEntries(ctx, desktop)
+	if err != nil || len(pending) != 2 || pending[0].Seq != 1 || pending[1].App != "Code" || pending[1].Delta != 660 {
+		t.Errorf("PendingSyncEntries() after reset = (%+v, %v), want all usage from entry 1", pending, err)
+	}
+}
+
+func TestSQLiteRepository_CombinedUsageKeepsTimeEntries(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	date := types.DateKey(time.Now())
+
+	if err := repo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 600}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if err := repo.IncrementDailyUsage(ctx, date, 600); err != nil {
+		t.Fatalf("IncrementDailyUsage() error = %v", err)
+	}
+	if err := repo.CreateTimeEntry(ctx, &types.TimeEntry{Date: date, AppName: "Meeting", Duration: 1800, Source: types.TimeSourceManual}); err != nil {
+		t.Fatalf("CreateTimeEntry() error = %v", err)
+	}
+
+	laptop := types.SyncDevice{ID: "laptop", Name: "Laptop"}
+	if _, err := repo.ApplySyncEntries(ctx, laptop, []types.SyncLogEntry{
+		types.NewSyncLogEntry(laptop, 1, types.SyncEntryApp, "Code", date, 100),
+		types.NewSyncLogEntry(laptop, 2, types.SyncEntryApp, "Mail", date, 50),
+		types.NewSyncLogEntry(laptop, 3, types.SyncEntryTotal, "", date, 150),
+	}); err != nil {
+		t.Fatalf("ApplySyncEntries() error = %v", err)
+	}
+
+	history, err := repo.GetUsageHistoryForDevice(ctx, 1, types.DeviceScopeAll)
+	if err != nil {
+		t.Fatalf("GetUsageHistoryForDevice() error = %v", err)
+	}
+	day := history[date.Format("2006-01-02")]
+	if day == nil || day.TotalTime != 2550 {
+		t.Fatalf("today = %+v, want local, manual and laptop time", day)
+	}
+
+	got := make(map[types.TimeSource]map[string]int64)
+	for _, app := range day.Apps {
+		if got[app.Source] == nil {
+			got[app.Source] = make(map[string]int64)
+		}
+		got[app.Source][app.Name] += app.Duration
+	}
+	if got[types.TimeSourceTracked]["Code"] != 700 || got[types.TimeSourceTracked]["Mail"] != 50 {
+		t.Errorf("tracked apps = %v, want Code 700 and Mail 50", got[types.TimeSourceTracked])
+	}
+	if got[types.TimeSourceManual]["Meeting"] != 1800 {
+		t.Errorf("manual apps = %v, want the meeting kept", got[types.TimeSourceManual])
+	}
+	if day.BySource[types.TimeSourceTracked] != 750 || day.BySource[types.TimeSourceManual] != 1800 {
+		t.Errorf("BySource = %v", day.BySource)
+	}
+}
+
+func TestSQLiteRepository_PendingSyncEntriesComparesChangedDates(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	today := types.DateKey(time.Now())
+	yesterday := today.AddDate(0, 0, -1)
+	desktop := types.SyncDevice{ID: "desktop", Name: "Desktop", Local: true}
+
+	for _, date := range []time.Time{yesterday, today} {
+		if err := repo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 600}); err != nil {
+			t.Fatalf("SaveAppUsage() error = %v", err)
+		}
+	}
+	pending, err := repo.PendingSyncEntries(ctx, desktop)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("PendingSyncEntries() = (%+v, %v), want both days", pending, err)
+	}
+	if _, err := repo.ApplySyncEntries(ctx, desktop, pending); err != nil {
+		t.Fatalf("ApplySyncEntries() error = %v", err)
+	}
+
+	// Comparing again finds both days in step and stops tracking them
+	if pending, err := repo.PendingSyncEntries(ctx, desktop); err != nil || len(pending) != 0 {
+		t.Fatalf("PendingSyncEntries() after writing = (%+v, %v), want none", pending, err)
+	}
+	if dirty, err := repo.queries.ListSyncDirtyDates(ctx); err != nil || len(dirty) != 0 {
+		t.Fatalf("dirty dates = (%+v, %v), want none once compared", dirty, err)
+	}
+
+	// Only the day that changed is compared again
+	if err := repo.BatchIncrementAppUsageDurations(ctx, today, map[string]int64{"Code": 60}); err != nil {
+		t.Fatalf("BatchIncrementAppUsageDurations() error = %v", err)
+	}
+	dirty, err := repo.queries.ListSyncDirtyDates(ctx)
+	if err != nil || len(dirty) != 1 || !types.DateKey(dirty[0].Date).Equal(today) {
+		t.Fatalf("dirty dates = (%+v, %v), want today only", dirty, err)
+	}
+	pending, err = repo.PendingSyncEntries(ctx, desktop)
+	if err != nil || len(pending) != 1 || pending[0].Delta != 60 {
+		t.Errorf("PendingSyncEntries() = (%+v, %v), want Code +60 today", pending, err)
+	}
+}
+
+func TestSQLiteRepository_SyncSnapshot(t *testing.T) {
+	source, target := setupTestRepository(t), setupTestRepository(t)
+	ctx := context.Background()
+	date := types.DateKey(time.Now())
+	laptop := types.SyncDevice{ID: "laptop", Name: "Laptop"}
+
+	if _, err := source.ApplySyncEntries(ctx, laptop, []types.SyncLogEntry{
+		types.NewSyncLogEntry(laptop, 1, types.SyncEntryApp, "Code", date, 100),
+		types.NewSyncLogEntry(laptop, 2, types.SyncEntryApp, "Code", date, -100),
+		types.NewSyncLogEntry(laptop, 3, types.SyncEntryApp, "Mail", date, 50),
+		types.NewSyncLogEntry(laptop, 4, types.SyncEntryTotal, "", date, 50),
+	}); err != nil {
+		t.Fatalf("ApplySyncEntries() error = %v", err)
+	}
+	snapshot, err := source.SyncSnapshot(ctx, laptop)
+	if err != nil || snapshot.Seq != 4 || len(snapshot.Usage) != 2 {
+		t.Fatalf("SyncSnapshot() = (%+v, %v), want Mail and the total as of entry 4", snapshot, err)
+	}
+
+	// The snapshot replaces what the target had merged so far
+	if _, err := target.ApplySyncEntries(ctx, laptop, []types.SyncLogEntry{
+		types.NewSyncLogEntry(laptop, 1, types.SyncEntryApp, "Code", date, 100),
+	}); err != nil {
+		t.Fatalf("ApplySyncEntries() error = %v", err)
+	}
+	if applied, err := target.ApplySyncSnapshot(ctx, laptop, snapshot); err != nil || !applied {
+		t.Fatalf("ApplySyncSnapshot() = (%v, %v), want applied", applied, err)
+	}
+	apps, err := target.GetAppUsageByDateRangeForDevice(ctx, date, date, "laptop")
+	if err != nil || len(apps) != 1 || apps[0].Name != "Mail" || apps[0].Duration != 50 {
+		t.Errorf("laptop usage after the snapshot = (%+v, %v), want Mail at 50 seconds", apps, err)
+	}
+
+	// Entries continue after it, and a snapshot that isn't ahead is ignored
+	if _, err := target.ApplySyncEntries(ctx, laptop, []types.SyncLogEntry{
+		types.NewSyncLogEntry(laptop, 5, types.SyncEntryApp, "Mail", date, 10),
+	}); err != nil {
+		t.Fatalf("ApplySyncEntries() after the snapshot error = %v", err)
+	}
+	if applied, err := target.ApplySyncSnapshot(ctx, laptop, snapshot); err != nil || applied {
+		t.Errorf("ApplySyncSnapshot() again = (%v, %v), want it skipped", applied, err)
+	}
+
+	snapshot.DeviceID = "tablet"
+	if _, err := target.ApplySyncSnapshot(ctx, laptop, snapshot); !repoerrors.IsConstraint(err) {
+		t.Errorf("ApplySyncSnapshot() of another device should be a constraint error, got %v", err)
+	}
+}
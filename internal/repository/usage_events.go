@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// Ensure SQLiteRepository implements FocusEventRepository interface
+var _ FocusEventRepository = (*SQLiteRepository)(nil)
+
+// AppendFocusEvent appends a single event to the focus event log with retry logic
+func (r *SQLiteRepository) AppendFocusEvent(ctx context.Context, event *types.FocusEvent) error {
+	if event == nil {
+		return repoerrors.NewRepositoryError("AppendFocusEvent", errors.New("focus event is nil"), repoerrors.ErrCodeValidation)
+	}
+
+	if strings.TrimSpace(string(event.Type)) == "" {
+		return repoerrors.NewRepositoryError("AppendFocusEvent", errors.New("focus event type is empty"), repoerrors.ErrCodeValidation)
+	}
+
+	if event.OccurredAt.IsZero() {
+		return repoerrors.NewRepositoryErrorWithContext("AppendFocusEvent", errors.New("focus event timestamp is zero"), repoerrors.ErrCodeValidation, map[string]string{
+			"event_type": string(event.Type),
+		})
+	}
+
+	// Store timestamps in UTC so that lexical ordering in SQLite matches chronological ordering
+	occurredAt := event.OccurredAt.UTC()
+
+	return r.withRetry(ctx, "AppendFocusEvent", func() error {
+		row, err := r.queries.InsertFocusEvent(ctx, queries.InsertFocusEventParams{
+			EventType:  string(event.Type),
+			AppName:    r.nullStringFromString(event.AppName),
+			ExePath:    r.nullStringFromString(event.ExePath),
+			OccurredAt: occurredAt,
+		})
+		if err != nil {
+			repoErr := repoerrors.NewRepositoryErrorWithContext("AppendFocusEvent", err, r.classifyError(err), map[string]string{
+				"event_type":  string(event.Type),
+				"app_name":    event.AppName,
+				"occurred_at": occurredAt.Format(time.RFC3339),
+			})
+
+			if repoErr.IsRetryable() {
+				r.loggerFor(ctx).Debug("Retryable error in AppendFocusEvent", "error", err, "event_type", event.Type)
+			} else {
+				logging.LogError(r.loggerFor(ctx), repoErr, "AppendFocusEvent", map[string]any{
+					"event_type": string(event.Type),
+					"app_name":   event.AppName,
+				})
+			}
+
+			return repoErr
+		}
+
+		event.ID = row.ID
+		return nil
+	})
+}
+
+// GetFocusEvents retrieves focus events within [start, end], oldest first
+func (r *SQLiteRepository) GetFocusEvents(ctx context.Context, start, end time.Time) ([]types.FocusEvent, error) {
+	if end.Before(start) {
+		return nil, repoerrors.NewRepositoryErrorWithContext("GetFocusEvents", errors.New("end is before start"), repoerrors.ErrCodeValidation, map[string]string{
+			"start": start.Format(time.RFC3339),
+			"end":   end.Format(time.RFC3339),
+		})
+	}
+
+	rows, err := r.queries.GetFocusEventsByTimeRange(ctx, queries.GetFocusEventsByTimeRangeParams{
+		OccurredAt:   start.UTC(),
+		OccurredAt_2: end.UTC(),
+	})
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("GetFocusEvents", err, r.classifyError(err))
+	}
+
+	events := make([]types.FocusEvent, len(rows))
+	for i, row := range rows {
+		events[i] = r.convertFocusEventFromDB(row)
+	}
+
+	return events, nil
+}
+
+// GetLatestFocusEvent retrieves the most recent focus event of the given type
+func (r *SQLiteRepository) GetLatestFocusEvent(ctx context.Context, eventType types.FocusEventType) (*types.FocusEvent, error) {
+	row, err := r.queries.GetLatestFocusEventByType(ctx, string(eventType))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repoerrors.HandleNotFound("GetLatestFocusEvent", "focus_event", string(eventType))
+		}
+		return nil, repoerrors.NewRepositoryError("GetLatestFocusEvent", err, r.classifyError(err))
+	}
+
+	event := r.convertFocusEventFromDB(row)
+	return &event, nil
+}
+
+// CompactFocusEvents removes heartbeat and checkpoint events older than before
+func (r *SQLiteRepository) CompactFocusEvents(ctx context.Context, before time.Time) error {
+	if err := r.queries.CompactFocusEvents(ctx, before.UTC()); err != nil {
+		return repoerrors.NewRepositoryErrorWithContext("CompactFocusEvents", err, r.classifyError(err), map[string]string{
+			"before": before.Format(time.RFC3339),
+		})
+	}
+	return nil
+}
+
+// ReplaceUsageForDate overwrites the daily summary and all app usage rows for a date in one transaction
+func (r *SQLiteRepository) ReplaceUsageForDate(ctx context.Context, date time.Time, usage *types.UsageData) error {
+	if usage == nil {
+		return repoerrors.NewRepositoryError("ReplaceUsageForDate", errors.New("usage data is nil"), repoerrors.ErrCodeValidation)
+	}
+
+	// Normalize to the stored date key
+	normalizedDate := types.DateKey(date)
+
+	return r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+
+		if err := txRepo.queries.DeleteAppUsageByDate(ctx, normalizedDate); err != nil {
+			return repoerrors.NewRepositoryErrorWithContext("ReplaceUsageForDate", err, r.classifyError(err), map[string]string{
+				"date":      normalizedDate.Format("2006-01-02"),
+				"operation": "DeleteAppUsageByDate",
+			})
+		}
+
+		if err := txRepo.SaveDailyUsage(ctx, normalizedDate, usage); err != nil {
+			return err
+		}
+
+		if len(usage.Apps) == 0 {
+			return nil
+		}
+
+		// Apps that resolve to the same application, e.g. tracked under two names, add up
+		for _, app := range usage.Apps {
+			cols, err := txRepo.resolveAppUsageColumns(ctx, txRepo.queries, &app)
+			if err == nil {
+				err = txRepo.queries.AddAppUsage(ctx, queries.AddAppUsageParams{
+					Name:          app.Name,
+					Duration:      app.Duration,
+					IconPath:      cols.iconPath,
+					ExePath:       cols.exePath,
+					IconHash:      cols.iconHash,
+					ApplicationID: cols.applicationID,
+					Date:          normalizedDate,
+				})
+			}
+			if err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("ReplaceUsageForDate", err, r.classifyError(err), map[string]string{
+					"date":      normalizedDate.Format("2006-01-02"),
+					"app_name":  app.Name,
+					"duration":  fmt.Sprintf("%d", app.Duration),
+					"operation": "AddAppUsage",
+				})
+			}
+		}
+
+		return nil
+	})
+}
+
+// convertFocusEventFromDB converts database FocusEvent to types.FocusEvent
+func (r *SQLiteRepository) convertFocusEventFromDB(dbEvent queries.FocusEvent) types.FocusEvent {
+	return types.FocusEvent{
+		ID:         dbEvent.ID,
+		Type:       types.FocusEventType(dbEvent.EventType),
+		AppName:    r.stringFromNullString(dbEvent.AppName),
+		ExePath:    r.stringFromNullString(dbEvent.ExePath),
+		OccurredAt: dbEvent.OccurredAt,
+	}
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func TestSQLiteRepository_FocusEvents(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	base := time.Date(2024, 2, 1, 9, 0, 0, 0, time.Local)
+	events := []*types.FocusEvent{
+		{Type: types.FocusEventTrackingStarted, OccurredAt: base},
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", ExePath: `C:\chrome.exe`, OccurredAt: base},
+		{Type: types.FocusEventHeartbeat, AppName: "Chrome", OccurredAt: base.Add(10 * time.Second)},
+		{Type: types.FocusEventCheckpoint, OccurredAt: base.Add(15 * time.Second)},
+		{Type: types.FocusEventHeartbeat, AppName: "Chrome", OccurredAt: base.Add(20 * time.Second)},
+	}
+	for _, event := range events {
+		if err := repo.AppendFocusEvent(ctx, event); err != nil {
+			t.Fatalf("AppendFocusEvent() error = %v", err)
+		}
+		if event.ID == 0 {
+			t.Error("AppendFocusEvent() should assign an ID")
+		}
+	}
+
+	got, err := repo.GetFocusEvents(ctx, base, base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GetFocusEvents() error = %v", err)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("GetFocusEvents() returned %d events, want %d", len(got), len(events))
+	}
+	if got[1].AppName != "Chrome" || got[1].ExePath != `C:\chrome.exe` {
+		t.Errorf("unexpected app switch event: %+v", got[1])
+	}
+	if !got[1].OccurredAt.Equal(base) {
+		t.Errorf("OccurredAt = %v, want %v", got[1].OccurredAt, base)
+	}
+
+	latest, err := repo.GetLatestFocusEvent(ctx, types.FocusEventCheckpoint)
+	if err != nil {
+		t.Fatalf("GetLatestFocusEvent() error = %v", err)
+	}
+	if !latest.OccurredAt.Equal(base.Add(15 * time.Second)) {
+		t.Errorf("latest checkpoint at %v, want %v", latest.OccurredAt, base.Add(15*time.Second))
+	}
+
+	if _, err := repo.GetLatestFocusEvent(ctx, types.FocusEventLocked); !repoerrors.IsNotFound(err) {
+		t.Errorf("GetLatestFocusEvent() for missing type should be not found, got %v", err)
+	}
+
+	// Compaction drops heartbeats and checkpoints before the cutoff but keeps state changes
+	if err := repo.CompactFocusEvents(ctx, base.Add(15*time.Second)); err != nil {
+		t.Fatalf("CompactFocusEvents() error = %v", err)
+	}
+	got, _ = repo.GetFocusEvents(ctx, base, base.Add(time.Minute))
+	if len(got) != 4 {
+		t.Fatalf("after compaction got %d events, want 4: %+v", len(got), got)
+	}
+	for _, event := range got {
+		if event.Type == types.FocusEventHeartbeat && event.OccurredAt.Before(base.Add(15*time.Second)) {
+			t.Errorf("heartbeat before cutoff survived compaction: %+v", event)
+		}
+	}
+
+	// Retention cleanup removes the event log too
+	if err := repo.DeleteOldData(ctx, base.Add(time.Hour)); err != nil {
+		t.Fatalf("DeleteOldData() error = %v", err)
+	}
+	got, _ = repo.GetFocusEvents(ctx, base, base.Add(time.Minute))
+	if len(got) != 0 {
+		t.Errorf("expected no events after DeleteOldData, got %d", len(got))
+	}
+}
+
+func TestSQLiteRepository_AppendFocusEventValidation(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		event *types.FocusEvent
+	}{
+		{"nil event", nil},
+		{"empty type", &types.FocusEvent{OccurredAt: time.Now()}},
+		{"zero timestamp", &types.FocusEvent{Type: types.FocusEventHeartbeat}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.AppendFocusEvent(ctx, tt.event)
+			if !repoerrors.IsValidation(err) {
+				t.Errorf("AppendFocusEvent() error = %v, want validation error", err)
+			}
+		})
+	}
+}
+
+func TestSQLiteRepository_ReplaceUsageForDate(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)
+	other := date.AddDate(0, 0, 1)
+
+	repo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Stale", Duration: 500})
+	repo.SaveAppUsage(ctx, other, &types.AppUsage{Name: "Untouched", Duration: 42})
+
+	err := repo.ReplaceUsageForDate(ctx, date, &types.UsageData{
+		TotalTime: 100,
+		Apps: []types.AppUsage{
+			{Name: "Chrome", Duration: 70},
+			{Name: "Slack", Duration: 20},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceUsageForDate() error = %v", err)
+	}
+
+	apps, err := repo.GetAppUsageByDate(ctx, date)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	if len(apps) != 2 {
+		t.Fatalf("got %d apps, want 2: %+v", len(apps), apps)
+	}
+	for _, app := range apps {
+		if app.Name == "Stale" {
+			t.Error("ReplaceUsageForDate() should remove apps not in the replacement")
+		}
+	}
+
+	daily, err := repo.GetDailyUsage(ctx, date)
+	if err != nil {
+		t.Fatalf("GetDailyUsage() error = %v", err)
+	}
+	if daily.TotalTime != 100 {
+		t.Errorf("TotalTime = %d, want 100", daily.TotalTime)
+	}
+
+	otherApps, _ := repo.GetAppUsageByDate(ctx, other)
+	if len(otherApps) != 1 || otherApps[0].Name != "Untouched" {
+		t.Errorf("other days should be left alone, got %+v", otherApps)
+	}
+}
+
+func TestSQLiteRepository_NestedTransactionJoinsOuter(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)
+
+	err := repo.WithTransaction(ctx, func(txRepo UsageRepository) error {
+		if err := txRepo.SaveDailyUsage(ctx, date, &types.UsageData{TotalTime: 10}); err != nil {
+			return err
+		}
+		return txRepo.BatchProcessAppUsage(ctx, date, []types.AppUsage{{Name: "Nested", Duration: 10}}, types.BatchStrategyUpsert)
+	})
+	if err != nil {
+		t.Fatalf("nested transaction error = %v", err)
+	}
+
+	apps, err := repo.GetAppUsageByDate(ctx, date)
+	if err != nil || len(apps) != 1 {
+		t.Fatalf("expected nested write to be committed, got %+v (err %v)", apps, err)
+	}
+}
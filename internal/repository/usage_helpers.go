@@ -30,12 +30,24 @@ func (r *SQLiteRepository) convertAppUsageFromDB(dbApp queries.AppUsage) types.A
 		ID:        dbApp.ID,
 		Name:      dbApp.Name,
 		Duration:  dbApp.Duration,
-		IconPath:  r.stringFromNullString(dbApp.IconPath),
+		IconPath:  r.iconPathFromDB(dbApp.IconPath, dbApp.IconHash),
+		IconHash:  r.stringFromNullString(dbApp.IconHash),
 		ExePath:   r.stringFromNullString(dbApp.ExePath),
 		Date:      dbApp.Date,
 		CreatedAt: r.timeFromNullTime(dbApp.CreatedAt),
 		UpdatedAt: r.timeFromNullTime(dbApp.UpdatedAt),
+
+		ApplicationID: dbApp.ApplicationID,
+	}
+}
+
+// iconPathFromDB returns the icon path exposed to callers
+// Icons stored in app_icons are exposed as URLs served by the icon handler
+func (r *SQLiteRepository) iconPathFromDB(iconPath, iconHash sql.NullString) string {
+	if iconHash.Valid && iconHash.String != "" {
+		return types.IconURL(iconHash.String)
 	}
+	return r.stringFromNullString(iconPath)
 }
 
 // nullStringFromString converts string to sql.NullString
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+// Ensure SQLiteRepository implements IconRepository interface
+var _ IconRepository = (*SQLiteRepository)(nil)
+
+// GetIcon retrieves an icon by its content hash
+func (r *SQLiteRepository) GetIcon(ctx context.Context, hash string) (*types.Icon, error) {
+	row, err := r.queries.GetAppIcon(ctx, hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repoerrors.HandleNotFound("GetIcon", "app_icon", hash)
+		}
+		return nil, repoerrors.NewRepositoryErrorWithContext("GetIcon", err, r.classifyError(err), map[string]string{
+			"hash": hash,
+		})
+	}
+
+	return &types.Icon{
+		Hash: row.Hash,
+		Mime: row.Mime,
+		Data: row.Data,
+		Size: row.Size,
+	}, nil
+}
+
+// iconColumns maps an app icon path to the icon_path and icon_hash columns.
+// Data URLs are stored once in app_icons and referenced by hash, URLs returned by
+// types.IconURL are mapped back to their hash, and any other path is kept as-is.
+func (r *SQLiteRepository) iconColumns(ctx context.Context, q *queries.Queries, iconPath string) (sql.NullString, sql.NullString, error) {
+	if hash, ok := types.IconHashFromURL(iconPath); ok {
+		return sql.NullString{}, r.nullStringFromString(hash), nil
+	}
+
+	icon, ok := types.IconFromDataURL(iconPath)
+	if !ok {
+		return r.nullStringFromString(iconPath), sql.NullString{}, nil
+	}
+
+	err := q.InsertAppIcon(ctx, queries.InsertAppIconParams{
+		Hash: icon.Hash,
+		Mime: icon.Mime,
+		Data: icon.Data,
+		Size: icon.Size,
+	})
+	if err != nil {
+		return sql.NullString{}, sql.NullString{}, err
+	}
+
+	return sql.NullString{}, r.nullStringFromString(icon.Hash), nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func TestSQLiteRepository_IconsStoredOnce(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	icon := types.NewIcon("image/png", []byte("chrome icon bytes"))
+	day1 := time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+
+	if err := repo.SaveAppUsage(ctx, day1, &types.AppUsage{Name: "Chrome", Duration: 10, IconPath: icon.DataURL()}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if err := repo.BatchProcessAppUsage(ctx, day2, []types.AppUsage{
+		{Name: "Chrome", Duration: 20, IconPath: icon.DataURL()},
+		{Name: "Notepad", Duration: 5, IconPath: `C:\icons\notepad.ico`},
+	}, types.BatchStrategyUpsert); err != nil {
+		t.Fatalf("BatchProcessAppUsage() error = %v", err)
+	}
+
+	var iconCount int
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM app_icons").Scan(&iconCount); err != nil {
+		t.Fatalf("failed to count icons: %v", err)
+	}
+	if iconCount != 1 {
+		t.Errorf("expected the icon to be stored once, got %d rows", iconCount)
+	}
+
+	apps, err := repo.GetAppUsageByDate(ctx, day2)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	for _, app := range apps {
+		switch app.Name {
+		case "Chrome":
+			if app.IconHash != icon.Hash || app.IconPath != types.IconURL(icon.Hash) {
+				t.Errorf("Chrome icon = (%q, %q), want hash %q served at %q", app.IconHash, app.IconPath, icon.Hash, types.IconURL(icon.Hash))
+			}
+		case "Notepad":
+			if app.IconHash != "" || app.IconPath != `C:\icons\notepad.ico` {
+				t.Errorf("file icon paths should be stored as-is, got %+v", app)
+			}
+		}
+	}
+
+	// Writing back the served URL keeps the reference instead of treating it as a path
+	if err := repo.SaveAppUsage(ctx, day2, &types.AppUsage{Name: "Chrome", Duration: 30, IconPath: types.IconURL(icon.Hash)}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	apps, err = repo.GetAppUsageByDate(ctx, day2)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	for _, app := range apps {
+		if app.Name == "Chrome" && app.IconHash != icon.Hash {
+			t.Errorf("IconHash = %q, want %q", app.IconHash, icon.Hash)
+		}
+	}
+
+	stored, err := repo.GetIcon(ctx, icon.Hash)
+	if err != nil {
+		t.Fatalf("GetIcon() error = %v", err)
+	}
+	if stored.Mime != "image/png" || !bytes.Equal(stored.Data, icon.Data) || stored.Size != icon.Size {
+		t.Errorf("GetIcon() = %+v, want %+v", stored, icon)
+	}
+
+	if _, err := repo.GetIcon(ctx, "missing"); !repoerrors.IsNotFound(err) {
+		t.Errorf("GetIcon() for a missing hash should be not found, got %v", err)
+	}
+
+	// Icons are pruned once no usage row references them
+	if err := repo.DeleteOldData(ctx, day2.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("DeleteOldData() error = %v", err)
+	}
+	if _, err := repo.GetIcon(ctx, icon.Hash); !repoerrors.IsNotFound(err) {
+		t.Errorf("expected unreferenced icon to be pruned, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// Ensure SQLiteRepository implements IgnoreRuleRepository interface
+var _ IgnoreRuleRepository = (*SQLiteRepository)(nil)
+
+// ListIgnoreRules retrieves all ignore rules, enabled or not
+func (r *SQLiteRepository) ListIgnoreRules(ctx context.Context) ([]types.IgnoreRule, error) {
+	rows, err := r.queries.ListIgnoreRules(ctx)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("ListIgnoreRules", err, r.classifyError(err))
+	}
+
+	rules := make([]types.IgnoreRule, len(rows))
+	for i, row := range rows {
+		rules[i] = r.convertIgnoreRuleFromDB(row)
+	}
+	return rules, nil
+}
+
+// CreateIgnoreRule stores a new ignore rule
+func (r *SQLiteRepository) CreateIgnoreRule(ctx context.Context, rule *types.IgnoreRule) error {
+	if err := r.validateIgnoreRule("CreateIgnoreRule", rule); err != nil {
+		return err
+	}
+
+	row, err := r.queries.CreateIgnoreRule(ctx, queries.CreateIgnoreRuleParams{
+		MatchType: string(rule.MatchType),
+		Pattern:   rule.Pattern,
+		Mode:      string(rule.Mode),
+		Enabled:   rule.Enabled,
+	})
+	if err != nil {
+		return repoerrors.NewRepositoryError("CreateIgnoreRule", err, r.classifyError(err))
+	}
+
+	*rule = r.convertIgnoreRuleFromDB(row)
+	return nil
+}
+
+// UpdateIgnoreRule saves changes to an existing ignore rule
+func (r *SQLiteRepository) UpdateIgnoreRule(ctx context.Context, rule *types.IgnoreRule) error {
+	if err := r.validateIgnoreRule("UpdateIgnoreRule", rule); err != nil {
+		return err
+	}
+
+	row, err := r.queries.UpdateIgnoreRule(ctx, queries.UpdateIgnoreRuleParams{
+		MatchType: string(rule.MatchType),
+		Pattern:   rule.Pattern,
+		Mode:      string(rule.Mode),
+		Enabled:   rule.Enabled,
+		ID:        rule.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repoerrors.HandleNotFound("UpdateIgnoreRule", "ignore_rule", fmt.Sprintf("%d", rule.ID))
+		}
+		return repoerrors.NewRepositoryErrorWithContext("UpdateIgnoreRule", err, r.classifyError(err), map[string]string{
+			"rule_id": fmt.Sprintf("%d", rule.ID),
+		})
+	}
+
+	*rule = r.convertIgnoreRuleFromDB(row)
+	return nil
+}
+
+// DeleteIgnoreRule removes an ignore rule; history it was applied to stays removed
+func (r *SQLiteRepository) DeleteIgnoreRule(ctx context.Context, id int64) error {
+	if err := r.queries.DeleteIgnoreRule(ctx, id); err != nil {
+		return repoerrors.NewRepositoryErrorWithContext("DeleteIgnoreRule", err, r.classifyError(err), map[string]string{
+			"rule_id": fmt.Sprintf("%d", id),
+		})
+	}
+	return nil
+}
+
+// ApplyIgnoreRulesToHistory retroactively applies rules to stored app usage.
+// Matching rows are deleted; for rules in ignore mode their duration is also
+// subtracted from the daily total, while hidden rows leave the total untouched.
+// Window titles are not stored, so title rules cannot match historic rows.
+func (r *SQLiteRepository) ApplyIgnoreRulesToHistory(ctx context.Context, rules []types.IgnoreRule) (int64, error) {
+	start := time.Now()
+
+	ruleSet, err := types.CompileIgnoreRules(rules)
+	if err != nil {
+		return 0, repoerrors.NewRepositoryError("ApplyIgnoreRulesToHistory", err, repoerrors.ErrCodeValidation)
+	}
+
+	var removed int64
+	err = r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+		removed = 0
+
+		rows, err := txRepo.queries.ListAppUsageSources(ctx)
+		if err != nil {
+			return repoerrors.NewRepositoryError("ApplyIgnoreRulesToHistory", err, r.classifyError(err))
+		}
+
+		ignoredByDate := make(map[time.Time]int64)
+		for _, row := range rows {
+			mode, matched := ruleSet.Match(types.IgnoreTarget{
+				Name:    row.Name,
+				ExePath: r.stringFromNullString(row.ExePath),
+			})
+			if !matched {
+				continue
+			}
+
+			if err := txRepo.queries.DeleteAppUsageByID(ctx, row.ID); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("ApplyIgnoreRulesToHistory", err, r.classifyError(err), map[string]string{
+					"app_name": row.Name,
+					"date":     row.Date.Format("2006-01-02"),
+				})
+			}
+			removed++
+
+			if mode == types.IgnoreModeIgnore {
+				ignoredByDate[types.DateKey(row.Date)] += row.Duration
+			}
+		}
+
+		for date, ignored := range ignoredByDate {
+			if err := txRepo.subtractDailyUsage(ctx, date, ignored); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "ApplyIgnoreRulesToHistory", time.Since(start), map[string]interface{}{
+		"rule_count":   len(rules),
+		"rows_removed": removed,
+	})
+	return removed, nil
+}
+
+// subtractDailyUsage lowers a day's stored total without letting it go negative
+func (r *SQLiteRepository) subtractDailyUsage(ctx context.Context, date time.Time, seconds int64) error {
+	row, err := r.queries.GetDailyUsageByDate(ctx, date)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return repoerrors.NewRepositoryErrorWithContext("subtractDailyUsage", err, r.classifyError(err), map[string]string{
+			"date": date.Format("2006-01-02"),
+		})
+	}
+
+	total := row.TotalTime - seconds
+	if total < 0 {
+		total = 0
+	}
+
+	if _, err := r.queries.UpsertDailyUsage(ctx, queries.UpsertDailyUsageParams{
+		Date:      date,
+		TotalTime: total,
+	}); err != nil {
+		return repoerrors.NewRepositoryErrorWithContext("subtractDailyUsage", err, r.classifyError(err), map[string]string{
+			"date": date.Format("2006-01-02"),
+		})
+	}
+	return nil
+}
+
+// validateIgnoreRule rejects nil rules and rules whose pattern, match type or mode is invalid
+func (r *SQLiteRepository) validateIgnoreRule(operation string, rule *types.IgnoreRule) error {
+	if rule == nil {
+		return repoerrors.NewRepositoryError(operation, fmt.Errorf("ignore rule is nil"), repoerrors.ErrCodeValidation)
+	}
+	if err := rule.Validate(); err != nil {
+		return repoerrors.NewRepositoryErrorWithContext(operation, err, repoerrors.ErrCodeValidation, map[string]string{
+			"pattern": rule.Pattern,
+		})
+	}
+	return nil
+}
+
+// convertIgnoreRuleFromDB converts a database row to an ignore rule
+func (r *SQLiteRepository) convertIgnoreRuleFromDB(row queries.IgnoreRule) types.IgnoreRule {
+	return types.IgnoreRule{
+		ID:        row.ID,
+		MatchType: types.IgnoreMatchType(row.MatchType),
+		Pattern:   row.Pattern,
+		Mode:      types.IgnoreMode(row.Mode),
+		Enabled:   row.Enabled,
+		CreatedAt: r.timeFromNullTime(row.CreatedAt),
+		UpdatedAt: r.timeFromNullTime(row.UpdatedAt),
+	}
+}
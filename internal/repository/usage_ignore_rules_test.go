@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func TestSQLiteRepository_IgnoreRules(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	// The migration seeds rules for the lock screen and qwin itself
+	seeded, err := repo.ListIgnoreRules(ctx)
+	if err != nil {
+		t.Fatalf("ListIgnoreRules() error = %v", err)
+	}
+	if len(seeded) != 2 {
+		t.Fatalf("expected 2 seeded rules, got %+v", seeded)
+	}
+
+	rule := &types.IgnoreRule{MatchType: types.IgnoreMatchExePath, Pattern: "explorer.exe", Mode: types.IgnoreModeHide, Enabled: true}
+	if err := repo.CreateIgnoreRule(ctx, rule); err != nil {
+		t.Fatalf("CreateIgnoreRule() error = %v", err)
+	}
+	if rule.ID == 0 || rule.CreatedAt.IsZero() {
+		t.Errorf("CreateIgnoreRule() did not fill in the stored rule: %+v", rule)
+	}
+
+	rule.Mode = types.IgnoreModeIgnore
+	rule.Enabled = false
+	if err := repo.UpdateIgnoreRule(ctx, rule); err != nil {
+		t.Fatalf("UpdateIgnoreRule() error = %v", err)
+	}
+	if rule.Mode != types.IgnoreModeIgnore || rule.Enabled {
+		t.Errorf("UpdateIgnoreRule() returned %+v", rule)
+	}
+
+	missing := *rule
+	missing.ID = 9999
+	if err := repo.UpdateIgnoreRule(ctx, &missing); !repoerrors.IsNotFound(err) {
+		t.Errorf("UpdateIgnoreRule() for a missing rule should be not found, got %v", err)
+	}
+
+	invalid := &types.IgnoreRule{MatchType: types.IgnoreMatchTitle, Pattern: "(", Mode: types.IgnoreModeIgnore}
+	if err := repo.CreateIgnoreRule(ctx, invalid); !repoerrors.IsValidation(err) {
+		t.Errorf("CreateIgnoreRule() with a bad regex should fail validation, got %v", err)
+	}
+
+	if err := repo.DeleteIgnoreRule(ctx, rule.ID); err != nil {
+		t.Fatalf("DeleteIgnoreRule() error = %v", err)
+	}
+	rules, err := repo.ListIgnoreRules(ctx)
+	if err != nil {
+		t.Fatalf("ListIgnoreRules() error = %v", err)
+	}
+	if len(rules) != len(seeded) {
+		t.Errorf("expected %d rules after delete, got %d", len(seeded), len(rules))
+	}
+}
+
+func TestSQLiteRepository_ApplyIgnoreRulesToHistory(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	date := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := repo.SaveDailyUsage(ctx, date, &types.UsageData{TotalTime: 1000}); err != nil {
+		t.Fatalf("SaveDailyUsage() error = %v", err)
+	}
+	for _, app := range []types.AppUsage{
+		{Name: "LockApp", Duration: 300, ExePath: `C:\Windows\SystemApps\LockApp.exe`},
+		{Name: "explorer", Duration: 200, ExePath: `C:\Windows\explorer.exe`},
+		{Name: "Code", Duration: 500, ExePath: `C:\Code\Code.exe`},
+	} {
+		app := app
+		app.Date = date
+		if err := repo.SaveAppUsage(ctx, date, &app); err != nil {
+			t.Fatalf("SaveAppUsage(%s) error = %v", app.Name, err)
+		}
+	}
+
+	removed, err := repo.ApplyIgnoreRulesToHistory(ctx, []types.IgnoreRule{
+		{MatchType: types.IgnoreMatchName, Pattern: "lockapp", Mode: types.IgnoreModeIgnore, Enabled: true},
+		{MatchType: types.IgnoreMatchExePath, Pattern: "EXPLORER.EXE", Mode: types.IgnoreModeHide, Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("ApplyIgnoreRulesToHistory() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("ApplyIgnoreRulesToHistory() removed %d rows, want 2", removed)
+	}
+
+	apps, err := repo.GetAppUsageByDate(ctx, date)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	if len(apps) != 1 || apps[0].Name != "Code" {
+		t.Errorf("expected only Code to remain, got %+v", apps)
+	}
+
+	// Ignored time leaves the total, hidden time stays in it
+	daily, err := repo.GetDailyUsage(ctx, date)
+	if err != nil {
+		t.Fatalf("GetDailyUsage() error = %v", err)
+	}
+	if daily.TotalTime != 700 {
+		t.Errorf("daily total = %d, want 700", daily.TotalTime)
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// Ensure SQLiteRepository implements InsightRepository interface
+var _ InsightRepository = (*SQLiteRepository)(nil)
+
+// ReplaceInsightsForDate overwrites the insights stored for a date in a single transaction
+func (r *SQLiteRepository) ReplaceInsightsForDate(ctx context.Context, date time.Time, insights []types.Insight) error {
+	start := time.Now()
+	dateKey := types.DateKey(date)
+
+	for _, insight := range insights {
+		if insight.Kind != types.InsightDailyTotal && insight.Kind != types.InsightAppUsage {
+			return repoerrors.NewRepositoryErrorWithContext("ReplaceInsightsForDate",
+				fmt.Errorf("unknown insight kind %q", insight.Kind), repoerrors.ErrCodeValidation, map[string]string{
+					"date": dateKey.Format("2006-01-02"),
+				})
+		}
+	}
+
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+
+		if err := txRepo.queries.DeleteInsightsByDate(ctx, dateKey); err != nil {
+			return repoerrors.NewRepositoryErrorWithContext("ReplaceInsightsForDate", err, r.classifyError(err), map[string]string{
+				"date": dateKey.Format("2006-01-02"),
+			})
+		}
+
+		for _, insight := range insights {
+			if _, err := txRepo.queries.CreateInsight(ctx, queries.CreateInsightParams{
+				Date:        dateKey,
+				Kind:        string(insight.Kind),
+				AppName:     insight.AppName,
+				Value:       insight.Value,
+				Baseline:    insight.Baseline,
+				Score:       insight.Score,
+				Explanation: insight.Explanation,
+			}); err != nil {
+				return repoerrors.NewRepositoryErrorWithContext("ReplaceInsightsForDate", err, r.classifyError(err), map[string]string{
+					"date":     dateKey.Format("2006-01-02"),
+					"kind":     string(insight.Kind),
+					"app_name": insight.AppName,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "ReplaceInsightsForDate", time.Since(start), map[string]interface{}{
+		"date":          dateKey.Format("2006-01-02"),
+		"insight_count": len(insights),
+	})
+	return nil
+}
+
+// GetInsights retrieves stored insights for an inclusive date range
+func (r *SQLiteRepository) GetInsights(ctx context.Context, startDate, endDate time.Time) ([]types.Insight, error) {
+	rows, err := r.queries.GetInsightsByDateRange(ctx, queries.GetInsightsByDateRangeParams{
+		Date:   types.DateKey(startDate),
+		Date_2: types.DateKeyEnd(endDate),
+	})
+	if err != nil {
+		return nil, repoerrors.NewRepositoryErrorWithContext("GetInsights", err, r.classifyError(err), map[string]string{
+			"start_date": startDate.Format("2006-01-02"),
+			"end_date":   endDate.Format("2006-01-02"),
+		})
+	}
+
+	insights := make([]types.Insight, len(rows))
+	for i, row := range rows {
+		insights[i] = types.Insight{
+			ID:          row.ID,
+			Date:        row.Date,
+			Kind:        types.InsightKind(row.Kind),
+			AppName:     row.AppName,
+			Value:       row.Value,
+			Baseline:    row.Baseline,
+			Score:       row.Score,
+			Explanation: row.Explanation,
+			CreatedAt:   r.timeFromNullTime(row.CreatedAt),
+		}
+	}
+	return insights, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func TestSQLiteRepository_Insights(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	day1 := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	if err := repo.ReplaceInsightsForDate(ctx, day1, []types.Insight{
+		{Kind: types.InsightAppUsage, AppName: "Discord", Value: 5400, Baseline: 1800, Score: 10, Explanation: "three times"},
+		{Kind: types.InsightDailyTotal, Value: 3000, Baseline: 9000, Score: -4, Explanation: "quiet day"},
+	}); err != nil {
+		t.Fatalf("ReplaceInsightsForDate() error = %v", err)
+	}
+	// Times within the day map onto the same date key
+	if err := repo.ReplaceInsightsForDate(ctx, day2.Add(15*time.Hour), []types.Insight{
+		{Kind: types.InsightAppUsage, AppName: "Slack", Value: 0, Baseline: 3600, Score: -10, Explanation: "unused"},
+	}); err != nil {
+		t.Fatalf("ReplaceInsightsForDate() error = %v", err)
+	}
+
+	insights, err := repo.GetInsights(ctx, day1, day2)
+	if err != nil {
+		t.Fatalf("GetInsights() error = %v", err)
+	}
+	want := []string{"Slack", "Discord", ""}
+	if len(insights) != len(want) {
+		t.Fatalf("GetInsights() returned %d insights, want %d", len(insights), len(want))
+	}
+	for i, name := range want {
+		if insights[i].AppName != name {
+			t.Errorf("insight %d is for %q, want %q (newest day first, then by significance)", i, insights[i].AppName, name)
+		}
+	}
+	if insights[1].Value != 5400 || insights[1].Baseline != 1800 || insights[1].Explanation != "three times" || insights[1].ID == 0 {
+		t.Errorf("insight was not stored faithfully: %+v", insights[1])
+	}
+
+	// Replacing a day's insights drops the old ones, including with an empty set
+	if err := repo.ReplaceInsightsForDate(ctx, day1, nil); err != nil {
+		t.Fatalf("ReplaceInsightsForDate() error = %v", err)
+	}
+	insights, err = repo.GetInsights(ctx, day1, day1)
+	if err != nil {
+		t.Fatalf("GetInsights() error = %v", err)
+	}
+	if len(insights) != 0 {
+		t.Errorf("expected no insights after replacing with none, got %+v", insights)
+	}
+
+	err = repo.ReplaceInsightsForDate(ctx, day1, []types.Insight{{Kind: "weekly"}})
+	if !repoerrors.IsValidation(err) {
+		t.Errorf("expected a validation error for an unknown kind, got %v", err)
+	}
+
+	// Old insights are removed along with the usage they describe
+	if err := repo.DeleteOldData(ctx, day2.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("DeleteOldData() error = %v", err)
+	}
+	insights, err = repo.GetInsights(ctx, day1, day2)
+	if err != nil {
+		t.Fatalf("GetInsights() error = %v", err)
+	}
+	if len(insights) != 0 {
+		t.Errorf("expected DeleteOldData to remove old insights, got %+v", insights)
+	}
+}
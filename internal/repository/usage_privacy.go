@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// Ensure SQLiteRepository implements PrivacyRepository interface
+var _ PrivacyRepository = (*SQLiteRepository)(nil)
+
+// RedactHistory rewrites the names and executable paths stored in app usage, the focus
+// event log and applications according to the redactor. Applications whose redacted name
+// another one already has are merged into it. Window titles are not stored, so there are
+// none to rewrite.
+func (r *SQLiteRepository) RedactHistory(ctx context.Context, redactor *types.PrivacyRedactor) (int64, error) {
+	start := time.Now()
+	if redactor == nil {
+		return 0, repoerrors.NewRepositoryError("RedactHistory", fmt.Errorf("privacy redactor is nil"), repoerrors.ErrCodeValidation)
+	}
+
+	var rewritten int64
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+		rewritten = 0
+
+		steps := []func(context.Context, *types.PrivacyRedactor) (int64, error){
+			txRepo.redactAppUsage,
+			txRepo.redactFocusEvents,
+			txRepo.redactApplications,
+		}
+		for _, step := range steps {
+			n, err := step(ctx, redactor)
+			if err != nil {
+				return err
+			}
+			rewritten += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "RedactHistory", time.Since(start), map[string]interface{}{
+		"rows_rewritten": rewritten,
+	})
+	return rewritten, nil
+}
+
+// redactAppUsage rewrites the names and executable paths of app usage rows. Rows stay
+// with their application; applications whose redacted names meet are merged afterwards.
+func (r *SQLiteRepository) redactAppUsage(ctx context.Context, redactor *types.PrivacyRedactor) (int64, error) {
+	rows, err := r.queries.ListAppUsageSources(ctx)
+	if err != nil {
+		return 0, repoerrors.NewRepositoryError("RedactHistory", err, r.classifyError(err))
+	}
+
+	var rewritten int64
+	for _, row := range rows {
+		exePath := r.stringFromNullString(row.ExePath)
+		name, redactedPath := redactor.Name(row.Name), redactor.ExePath(exePath)
+		if name == row.Name && redactedPath == exePath {
+			continue
+		}
+		if err := r.queries.UpdateAppUsageSource(ctx, queries.UpdateAppUsageSourceParams{
+			Name:    name,
+			ExePath: r.nullStringFromString(redactedPath),
+			ID:      row.ID,
+		}); err != nil {
+			return 0, repoerrors.NewRepositoryErrorWithContext("RedactHistory", err, r.classifyError(err), map[string]string{
+				"app_name": row.Name,
+				"date":     row.Date.Format("2006-01-02"),
+			})
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+// redactFocusEvents rewrites the app names and paths recorded in the focus event log
+func (r *SQLiteRepository) redactFocusEvents(ctx context.Context, redactor *types.PrivacyRedactor) (int64, error) {
+	rows, err := r.queries.ListFocusEventSources(ctx)
+	if err != nil {
+		return 0, repoerrors.NewRepositoryError("RedactHistory", err, r.classifyError(err))
+	}
+
+	var rewritten int64
+	for _, row := range rows {
+		name, exePath := r.stringFromNullString(row.AppName), r.stringFromNullString(row.ExePath)
+		redactedName, redactedPath := redactor.Name(name), redactor.ExePath(exePath)
+		if redactedName == name && redactedPath == exePath {
+			continue
+		}
+		if err := r.queries.UpdateFocusEventSource(ctx, queries.UpdateFocusEventSourceParams{
+			AppName: r.nullStringFromString(redactedName),
+			ExePath: r.nullStringFromString(redactedPath),
+			ID:      row.ID,
+		}); err != nil {
+			return 0, repoerrors.NewRepositoryErrorWithContext("RedactHistory", err, r.classifyError(err), map[string]string{
+				"event_id": fmt.Sprintf("%d", row.ID),
+			})
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+// redactApplications rewrites application names and executable paths. A redacted path
+// that another path already became keeps the application it was assigned first.
+func (r *SQLiteRepository) redactApplications(ctx context.Context, redactor *types.PrivacyRedactor) (int64, error) {
+	var rewritten int64
+
+	apps, err := r.queries.ListApplications(ctx)
+	if err != nil {
+		return 0, repoerrors.NewRepositoryError("RedactHistory", err, r.classifyError(err))
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].ID < apps[j].ID })
+
+	// An application renamed to a name another one already has is merged into the oldest
+	// holder of that name, as its usage would have been recorded there from the start
+	owners := make(map[string]int64, len(apps))
+	for _, app := range apps {
+		if _, taken := owners[app.Name]; !taken && redactor.Name(app.Name) == app.Name {
+			owners[app.Name] = app.ID
+		}
+	}
+	for _, app := range apps {
+		name, displayName := redactor.Name(app.Name), redactor.Name(app.DisplayName)
+		if name == app.Name && displayName == app.DisplayName {
+			continue
+		}
+		if owner, taken := owners[name]; taken && owner != app.ID {
+			if err := r.MergeApplications(ctx, owner, []int64{app.ID}); err != nil {
+				return 0, err
+			}
+			rewritten++
+			continue
+		}
+		owners[name] = app.ID
+		if err := r.queries.RenameApplication(ctx, queries.RenameApplicationParams{
+			Name:        name,
+			DisplayName: displayName,
+			ID:          app.ID,
+		}); err != nil {
+			return 0, repoerrors.NewRepositoryErrorWithContext("RedactHistory", err, r.classifyError(err), map[string]string{
+				"application_id": fmt.Sprintf("%d", app.ID),
+			})
+		}
+		rewritten++
+	}
+
+	paths, err := r.queries.ListApplicationPaths(ctx)
+	if err != nil {
+		return 0, repoerrors.NewRepositoryError("RedactHistory", err, r.classifyError(err))
+	}
+	for _, path := range paths {
+		redacted := redactor.ExePath(path.ExePath)
+		if redacted == path.ExePath {
+			continue
+		}
+		if err := r.queries.DeleteApplicationPath(ctx, path.ExePath); err != nil {
+			return 0, repoerrors.NewRepositoryErrorWithContext("RedactHistory", err, r.classifyError(err), map[string]string{
+				"application_id": fmt.Sprintf("%d", path.ApplicationID),
+			})
+		}
+		if err := r.queries.AddApplicationPath(ctx, queries.AddApplicationPathParams{
+			ExePath:       redacted,
+			ApplicationID: path.ApplicationID,
+		}); err != nil {
+			return 0, repoerrors.NewRepositoryErrorWithContext("RedactHistory", err, r.classifyError(err), map[string]string{
+				"application_id": fmt.Sprintf("%d", path.ApplicationID),
+			})
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func TestSQLiteRepository_RedactHistory(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	date := types.DateKey(time.Now())
+
+	usages := []types.AppUsage{
+		{Name: "Code", Duration: 600, ExePath: `C:\Users\alice\AppData\Local\Programs\Code\Code.exe`},
+		{Name: "git", Duration: 60, ExePath: `C:\Program Files\Git\git.exe`},
+		{Name: "https://docs.example.com/page", Duration: 100},
+		{Name: "https://docs.example.com/page?session=42", Duration: 50},
+	}
+	for i := range usages {
+		if err := repo.SaveAppUsage(ctx, date, &usages[i]); err != nil {
+			t.Fatalf("SaveAppUsage() error = %v", err)
+		}
+	}
+	if err := repo.AppendFocusEvent(ctx, &types.FocusEvent{
+		Type:       types.FocusEventAppSwitched,
+		AppName:    "Code",
+		ExePath:    usages[0].ExePath,
+		OccurredAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("AppendFocusEvent() error = %v", err)
+	}
+
+	redactor := types.NewPrivacyRedactor(types.PrivacyPolicy{StripURLQueries: true, DropUserPaths: true}, "alice")
+	rewritten, err := repo.RedactHistory(ctx, redactor)
+	if err != nil {
+		t.Fatalf("RedactHistory() error = %v", err)
+	}
+	if rewritten == 0 {
+		t.Error("RedactHistory() reported no rewritten rows")
+	}
+
+	stored, err := repo.GetAppUsageByDate(ctx, date)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	got := make(map[string]types.AppUsage, len(stored))
+	for _, usage := range stored {
+		got[usage.Name] = usage
+	}
+	if len(got) != 3 {
+		t.Errorf("expected the two URL rows to be merged, got %+v", stored)
+	}
+	if got["Code"].ExePath != "Code.exe" {
+		t.Errorf("Code ExePath = %q, want the file name only", got["Code"].ExePath)
+	}
+	if got["git"].ExePath != usages[1].ExePath {
+		t.Errorf("git ExePath = %q, paths outside the user's directories should be kept", got["git"].ExePath)
+	}
+	if page := got["https://docs.example.com/page"]; page.Duration != 150 {
+		t.Errorf("merged URL row duration = %d, want 150", page.Duration)
+	}
+
+	events, err := repo.GetFocusEvents(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetFocusEvents() error = %v", err)
+	}
+	if len(events) != 1 || events[0].ExePath != "Code.exe" {
+		t.Errorf("focus events = %+v, want the path redacted", events)
+	}
+
+	apps, err := repo.ListApplications(ctx)
+	if err != nil {
+		t.Fatalf("ListApplications() error = %v", err)
+	}
+	for _, app := range apps {
+		for _, path := range app.ExePaths {
+			if path == usages[0].ExePath {
+				t.Errorf("application %q still has the user's path", app.Name)
+			}
+		}
+	}
+
+	// Running it again changes nothing
+	if rewritten, err := repo.RedactHistory(ctx, redactor); err != nil || rewritten != 0 {
+		t.Errorf("second RedactHistory() = (%d, %v), want nothing left to rewrite", rewritten, err)
+	}
+
+	if _, err := repo.RedactHistory(ctx, nil); !repoerrors.IsValidation(err) {
+		t.Errorf("RedactHistory(nil) should fail validation, got %v", err)
+	}
+}
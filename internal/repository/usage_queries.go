@@ -17,13 +17,13 @@ func (r *SQLiteRepository) GetUsageHistory(ctx context.Context, days int) (map[s
 		return nil, repoerrors.NewRepositoryError("GetUsageHistory", errors.New("days must be positive"), repoerrors.ErrCodeConstraint)
 	}
 
-	// Calculate date range
-	endDate := time.Now()
+	// Calculate date range, with today decided by the configured day boundary
+	endDate := r.dayBoundary.DateOf(time.Now())
 	startDate := endDate.AddDate(0, 0, -days+1) // Include today
 
-	// Normalize dates
-	normalizedStart := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
-	normalizedEnd := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
+	// Normalize to stored date keys
+	normalizedStart := types.DateKey(startDate)
+	normalizedEnd := types.DateKeyEnd(endDate)
 
 	// Get daily usage data
 	dailyUsageRows, err := r.queries.GetDailyUsageByDateRange(ctx, queries.GetDailyUsageByDateRangeParams{
@@ -55,11 +55,16 @@ func (r *SQLiteRepository) GetUsageHistory(ctx context.Context, days int) (map[s
 		}
 	}
 
+	appUsages, err := r.convertAppUsageRows(ctx, appUsageRows)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("GetUsageHistory", err, r.classifyError(err))
+	}
+
 	// Group app usage by date
 	appsByDate := make(map[string][]types.AppUsage)
-	for _, appRow := range appUsageRows {
-		dateKey := appRow.Date.Format("2006-01-02")
-		appsByDate[dateKey] = append(appsByDate[dateKey], r.convertAppUsageFromDB(appRow))
+	for _, app := range appUsages {
+		dateKey := app.Date.Format("2006-01-02")
+		appsByDate[dateKey] = append(appsByDate[dateKey], app)
 	}
 
 	// Merge app usage into result
@@ -79,6 +84,11 @@ func (r *SQLiteRepository) GetUsageHistory(ctx context.Context, days int) (map[s
 		}
 	}
 
+	// Add manual and imported time entries next to the tracked apps
+	if err := r.addTimeEntries(ctx, result, startDate, endDate); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
@@ -94,7 +104,7 @@ func (r *SQLiteRepository) DeleteOldData(ctx context.Context, olderThan time.Tim
 	defer func(ctx context.Context, olderThan time.Time) {
 		if !committed && tx != nil {
 			if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
-				r.logger.Debug("Failed to rollback transaction in DeleteOldData",
+				r.loggerFor(ctx).Debug("Failed to rollback transaction in DeleteOldData",
 					"rollback_error", rollbackErr,
 					"context", "cleanup_after_error",
 					"older_than", olderThan.Format("2006-01-02"))
@@ -103,14 +113,39 @@ func (r *SQLiteRepository) DeleteOldData(ctx context.Context, olderThan time.Tim
 	}(ctx, olderThan)
 
 	txQueries := r.queries.WithTx(tx)
+	cutoffDate := types.DateKey(olderThan)
 
 	// Delete old app usage data
-	if err := txQueries.DeleteOldAppUsage(ctx, olderThan); err != nil {
+	if err := txQueries.DeleteOldAppUsage(ctx, cutoffDate); err != nil {
 		return repoerrors.NewRepositoryError("DeleteOldData", err, r.classifyError(err))
 	}
 
 	// Delete old daily usage data
-	if err := txQueries.DeleteOldDailyUsage(ctx, olderThan); err != nil {
+	if err := txQueries.DeleteOldDailyUsage(ctx, cutoffDate); err != nil {
+		return repoerrors.NewRepositoryError("DeleteOldData", err, r.classifyError(err))
+	}
+
+	// Synced usage of other devices, and what this one wrote to its change log, is kept
+	// for the same window; removing only local rows would sync the removal to other devices
+	if err := txQueries.DeleteOldDeviceAppUsage(ctx, cutoffDate); err != nil {
+		return repoerrors.NewRepositoryError("DeleteOldData", err, r.classifyError(err))
+	}
+	if err := txQueries.DeleteOldDeviceDailyUsage(ctx, cutoffDate); err != nil {
+		return repoerrors.NewRepositoryError("DeleteOldData", err, r.classifyError(err))
+	}
+
+	// Delete focus events for the same retention window
+	if err := txQueries.DeleteOldFocusEvents(ctx, olderThan.UTC()); err != nil {
+		return repoerrors.NewRepositoryError("DeleteOldData", err, r.classifyError(err))
+	}
+
+	// Insights about deleted days no longer have usage to refer to
+	if err := txQueries.DeleteOldInsights(ctx, cutoffDate); err != nil {
+		return repoerrors.NewRepositoryError("DeleteOldData", err, r.classifyError(err))
+	}
+
+	// Drop icons no longer referenced by any remaining app usage row
+	if err := txQueries.DeleteUnreferencedAppIcons(ctx); err != nil {
 		return repoerrors.NewRepositoryError("DeleteOldData", err, r.classifyError(err))
 	}
 
@@ -125,9 +160,9 @@ func (r *SQLiteRepository) DeleteOldData(ctx context.Context, olderThan time.Tim
 
 // GetAppUsageByDateRangePaginated retrieves application usage data with pagination metadata for large datasets
 func (r *SQLiteRepository) GetAppUsageByDateRangePaginated(ctx context.Context, startDate, endDate time.Time, limit, offset int) (*types.PaginatedAppUsageResult, error) {
-	// Normalize dates
-	normalizedStart := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
-	normalizedEnd := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
+	// Normalize to stored date keys
+	normalizedStart := types.DateKey(startDate)
+	normalizedEnd := types.DateKeyEnd(endDate)
 
 	// Validate and clamp pagination parameters
 	// Ensure offset is not negative
@@ -186,9 +221,9 @@ func (r *SQLiteRepository) GetAppUsageByDateRangePaginated(ctx context.Context,
 	}
 
 	// Convert results
-	apps := make([]types.AppUsage, len(rows))
-	for i, row := range rows {
-		apps[i] = r.convertAppUsageFromDB(row)
+	apps, err := r.convertAppUsageRows(ctx, rows)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryError("GetAppUsageByDateRangePaginated", err, r.classifyError(err))
 	}
 
 	return &types.PaginatedAppUsageResult{
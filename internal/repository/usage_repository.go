@@ -9,6 +9,7 @@ import (
 	queries "qwin/internal/database/generated"
 	repoerrors "qwin/internal/infrastructure/errors"
 	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
 )
 
 // BatchConfig holds configuration for batch operations
@@ -27,12 +28,60 @@ func DefaultBatchConfig() *BatchConfig {
 
 // SQLiteRepository implements the UsageRepository interface using SQLite
 type SQLiteRepository struct {
-	db          *sql.DB
+	db          serviceConn
 	queries     *queries.Queries
 	dbService   database.Service
 	retryConfig *repoerrors.RetryConfig
+	breaker     *repoerrors.CircuitBreaker // stops calling storage while it keeps failing
 	batchConfig *BatchConfig
 	logger      logging.Logger
+	dayBoundary types.DayBoundary // decides which date is "today" for relative queries
+	inTx        bool              // true for repositories bound to an open transaction
+}
+
+// closedDB stands in for the service's connection while it has none, failing every call
+// the way a closed connection does
+var closedDB = func() *sql.DB {
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.Close()
+	return db
+}()
+
+// serviceConn runs statements on the database service's current connection, so a
+// repository keeps working after the service reconnects, e.g. to another profile's database
+type serviceConn struct {
+	service database.Service
+}
+
+func (c serviceConn) current() *sql.DB {
+	if db := c.service.DB(); db != nil {
+		return db
+	}
+	return closedDB
+}
+
+func (c serviceConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.current().ExecContext(ctx, query, args...)
+}
+
+func (c serviceConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.current().PrepareContext(ctx, query)
+}
+
+func (c serviceConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.current().QueryContext(ctx, query, args...)
+}
+
+func (c serviceConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.current().QueryRowContext(ctx, query, args...)
+}
+
+func (c serviceConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.current().BeginTx(ctx, opts)
+}
+
+func (c serviceConn) PingContext(ctx context.Context) error {
+	return c.current().PingContext(ctx)
 }
 
 // NewSQLiteRepository creates a new SQLite repository instance
@@ -42,10 +91,11 @@ func NewSQLiteRepository(dbService database.Service, logger logging.Logger) *SQL
 	}
 
 	return &SQLiteRepository{
-		db:          dbService.DB(),
-		queries:     dbService.GetQueries(),
+		db:          serviceConn{service: dbService},
+		queries:     queries.New(serviceConn{service: dbService}),
 		dbService:   dbService,
 		retryConfig: repoerrors.DefaultRetryConfig(),
+		breaker:     repoerrors.NewCircuitBreaker(nil),
 		batchConfig: DefaultBatchConfig(),
 		logger:      logger,
 	}
@@ -64,16 +114,19 @@ func NewSQLiteRepositoryWithConfig(dbService database.Service, retryConfig *repo
 	}
 
 	return &SQLiteRepository{
-		db:          dbService.DB(),
-		queries:     dbService.GetQueries(),
+		db:          serviceConn{service: dbService},
+		queries:     queries.New(serviceConn{service: dbService}),
 		dbService:   dbService,
 		retryConfig: retryConfig,
+		breaker:     repoerrors.NewCircuitBreaker(nil),
 		batchConfig: batchConfig,
 		logger:      logger,
 	}
 }
 
-// NewSQLiteRepositoryWithPreparedQueries creates a repository with prepared statements for better performance
+// NewSQLiteRepositoryWithPreparedQueries creates a repository with prepared statements for better performance.
+// Prepared statements belong to the connection they were prepared on, so the repository
+// has to be recreated when the service reconnects.
 func NewSQLiteRepositoryWithPreparedQueries(ctx context.Context, dbService database.Service, logger logging.Logger) (*SQLiteRepository, error) {
 	if logger == nil {
 		logger = logging.NewDefaultLogger()
@@ -85,11 +138,30 @@ func NewSQLiteRepositoryWithPreparedQueries(ctx context.Context, dbService datab
 	}
 
 	return &SQLiteRepository{
-		db:          dbService.DB(),
+		db:          serviceConn{service: dbService},
 		queries:     preparedQueries,
 		dbService:   dbService,
 		retryConfig: repoerrors.DefaultRetryConfig(),
+		breaker:     repoerrors.NewCircuitBreaker(nil),
 		batchConfig: DefaultBatchConfig(),
 		logger:      logger,
 	}, nil
 }
+
+// loggerFor returns the repository's logger adding the fields carried by ctx, such as the
+// ID of the flush or batch a call belongs to
+func (r *SQLiteRepository) loggerFor(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+// withRetry runs a storage operation with the repository's retry policy behind its circuit
+// breaker, so storage that keeps failing is left alone for a while instead of being retried
+// on every call. Repositories bound to a transaction leave the breaker to WithTransaction.
+func (r *SQLiteRepository) withRetry(ctx context.Context, op string, operation repoerrors.RetryableOperation) error {
+	if r.inTx || r.breaker == nil {
+		return repoerrors.WithRetry(ctx, r.retryConfig, operation)
+	}
+	return r.breaker.Execute(op, func() error {
+		return repoerrors.WithRetry(ctx, r.retryConfig, operation)
+	})
+}
@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"qwin/internal/database"
 	repoerrors "qwin/internal/infrastructure/errors"
 	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
 )
 
 func TestNewSQLiteRepository(t *testing.T) {
@@ -17,7 +19,7 @@ func TestNewSQLiteRepository(t *testing.T) {
 		t.Fatal("NewSQLiteRepository returned nil")
 	}
 
-	if repo.db == nil {
+	if repo.db.service == nil {
 		t.Error("Repository db is nil")
 	}
 
@@ -82,6 +84,35 @@ func TestNewSQLiteRepositoryWithConfig(t *testing.T) {
 	}
 }
 
+func TestSQLiteRepository_FollowsReconnect(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := setupFileRepository(t, filepath.Join(dir, "default.db"))
+	date := types.DateKey(time.Now())
+
+	if err := repo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 60}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+
+	// Reconnecting the service to another database, as switching profiles does, moves the repository with it
+	config := database.DefaultConfig()
+	config.Path = filepath.Join(dir, "work.db")
+	if err := repo.dbService.Connect(ctx, config); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := repo.dbService.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	apps, err := repo.GetAppUsageByDate(ctx, date)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	if len(apps) != 0 {
+		t.Errorf("GetAppUsageByDate() after reconnecting = %+v, want the other database's empty usage", apps)
+	}
+}
+
 // Helper function to set up a test repository
 func setupTestRepository(t *testing.T) *SQLiteRepository {
 	t.Helper()
@@ -113,3 +144,34 @@ func setupTestRepository(t *testing.T) *SQLiteRepository {
 
 	return repo
 }
+
+// Helper function to set up a repository backed by a database file, so that
+// several repositories can share the same database like separate processes would
+func setupFileRepository(t *testing.T, path string) *SQLiteRepository {
+	t.Helper()
+
+	config := database.DefaultConfig()
+	config.Path = path
+	config.BackupEnabled = false
+	config.EnableCleanup = false
+	config.VacuumInterval = 0
+	config.AnalyzeInterval = 0
+
+	logger := logging.NewDefaultLogger()
+	dbService := database.NewSQLiteService(logger)
+
+	ctx := context.Background()
+	if err := dbService.Connect(ctx, config); err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	if err := dbService.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	t.Cleanup(func() {
+		dbService.Close()
+	})
+
+	return NewSQLiteRepository(dbService, logger)
+}
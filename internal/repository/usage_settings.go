@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+)
+
+// Ensure SQLiteRepository implements SettingsRepository interface
+var _ SettingsRepository = (*SQLiteRepository)(nil)
+
+// GetSetting retrieves the stored value of a setting
+func (r *SQLiteRepository) GetSetting(ctx context.Context, key string) (string, error) {
+	value, err := r.queries.GetSetting(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", repoerrors.HandleNotFound("GetSetting", "setting", key)
+		}
+		return "", repoerrors.NewRepositoryErrorWithContext("GetSetting", err, r.classifyError(err), map[string]string{
+			"key": key,
+		})
+	}
+	return value, nil
+}
+
+// SetSetting stores the value of a setting, replacing any previous value
+func (r *SQLiteRepository) SetSetting(ctx context.Context, key, value string) error {
+	if strings.TrimSpace(key) == "" {
+		return repoerrors.NewRepositoryError("SetSetting", errors.New("setting key is empty or whitespace"), repoerrors.ErrCodeValidation)
+	}
+
+	return r.withRetry(ctx, "SetSetting", func() error {
+		if err := r.queries.UpsertSetting(ctx, queries.UpsertSettingParams{Key: key, Value: value}); err != nil {
+			return repoerrors.NewRepositoryErrorWithContext("SetSetting", err, r.classifyError(err), map[string]string{
+				"key": key,
+			})
+		}
+		return nil
+	})
+}
+
+// DeleteSetting removes a setting so its default applies again
+func (r *SQLiteRepository) DeleteSetting(ctx context.Context, key string) error {
+	if err := r.queries.DeleteSetting(ctx, key); err != nil {
+		return repoerrors.NewRepositoryErrorWithContext("DeleteSetting", err, r.classifyError(err), map[string]string{
+			"key": key,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+)
+
+func TestSQLiteRepository_Settings(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	if _, err := repo.GetSetting(ctx, "day_start_hour"); !repoerrors.IsNotFound(err) {
+		t.Errorf("GetSetting() for an unset key should be not found, got %v", err)
+	}
+
+	if err := repo.SetSetting(ctx, "day_start_hour", "4"); err != nil {
+		t.Fatalf("SetSetting() error = %v", err)
+	}
+	if err := repo.SetSetting(ctx, "day_start_hour", "5"); err != nil {
+		t.Fatalf("SetSetting() overwrite error = %v", err)
+	}
+
+	value, err := repo.GetSetting(ctx, "day_start_hour")
+	if err != nil {
+		t.Fatalf("GetSetting() error = %v", err)
+	}
+	if value != "5" {
+		t.Errorf("GetSetting() = %q, want %q", value, "5")
+	}
+
+	if err := repo.DeleteSetting(ctx, "day_start_hour"); err != nil {
+		t.Fatalf("DeleteSetting() error = %v", err)
+	}
+	if _, err := repo.GetSetting(ctx, "day_start_hour"); !repoerrors.IsNotFound(err) {
+		t.Errorf("GetSetting() after delete should be not found, got %v", err)
+	}
+
+	if err := repo.SetSetting(ctx, " ", "x"); !repoerrors.IsValidation(err) {
+		t.Errorf("SetSetting() with an empty key should be a validation error, got %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	queries "qwin/internal/database/generated"
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// Ensure SQLiteRepository implements TimeEntryRepository interface
+var _ TimeEntryRepository = (*SQLiteRepository)(nil)
+
+// CreateTimeEntry stores a new manual, imported or correction entry and records its creation
+func (r *SQLiteRepository) CreateTimeEntry(ctx context.Context, entry *types.TimeEntry) error {
+	start := time.Now()
+	if err := r.validateTimeEntry("CreateTimeEntry", entry); err != nil {
+		return err
+	}
+
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+		if err := txRepo.checkCorrection(ctx, "CreateTimeEntry", entry); err != nil {
+			return err
+		}
+
+		row, err := txRepo.queries.CreateTimeEntry(ctx, queries.CreateTimeEntryParams{
+			Date:     types.DateKey(entry.Date),
+			AppName:  entry.AppName,
+			Duration: entry.Duration,
+			Source:   string(entry.Source),
+			Note:     entry.Note,
+		})
+		if err != nil {
+			return repoerrors.NewRepositoryErrorWithContext("CreateTimeEntry", err, r.classifyError(err), map[string]string{
+				"app_name": entry.AppName,
+				"date":     entry.Date.Format("2006-01-02"),
+			})
+		}
+
+		*entry = r.convertTimeEntryFromDB(row)
+		return txRepo.auditTimeEntry(ctx, types.TimeEntryCreated, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "CreateTimeEntry", time.Since(start), map[string]interface{}{
+		"entry_id": entry.ID,
+		"source":   entry.Source,
+		"duration": entry.Duration,
+	})
+	return nil
+}
+
+// UpdateTimeEntry saves changes to an existing time entry and records them
+func (r *SQLiteRepository) UpdateTimeEntry(ctx context.Context, entry *types.TimeEntry) error {
+	start := time.Now()
+	if err := r.validateTimeEntry("UpdateTimeEntry", entry); err != nil {
+		return err
+	}
+
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+		if err := txRepo.checkCorrection(ctx, "UpdateTimeEntry", entry); err != nil {
+			return err
+		}
+
+		row, err := txRepo.queries.UpdateTimeEntry(ctx, queries.UpdateTimeEntryParams{
+			Date:     types.DateKey(entry.Date),
+			AppName:  entry.AppName,
+			Duration: entry.Duration,
+			Source:   string(entry.Source),
+			Note:     entry.Note,
+			ID:       entry.ID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return repoerrors.HandleNotFound("UpdateTimeEntry", "time_entry", fmt.Sprintf("%d", entry.ID))
+			}
+			return repoerrors.NewRepositoryErrorWithContext("UpdateTimeEntry", err, r.classifyError(err), map[string]string{
+				"entry_id": fmt.Sprintf("%d", entry.ID),
+			})
+		}
+
+		*entry = r.convertTimeEntryFromDB(row)
+		return txRepo.auditTimeEntry(ctx, types.TimeEntryUpdated, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "UpdateTimeEntry", time.Since(start), map[string]interface{}{
+		"entry_id": entry.ID,
+		"duration": entry.Duration,
+	})
+	return nil
+}
+
+// DeleteTimeEntry removes a time entry, keeping what it held in the audit trail
+func (r *SQLiteRepository) DeleteTimeEntry(ctx context.Context, id int64) error {
+	start := time.Now()
+
+	err := r.WithTransaction(ctx, func(repo UsageRepository) error {
+		txRepo := repo.(*SQLiteRepository)
+
+		row, err := txRepo.queries.GetTimeEntry(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return repoerrors.HandleNotFound("DeleteTimeEntry", "time_entry", fmt.Sprintf("%d", id))
+			}
+			return repoerrors.NewRepositoryErrorWithContext("DeleteTimeEntry", err, r.classifyError(err), map[string]string{
+				"entry_id": fmt.Sprintf("%d", id),
+			})
+		}
+
+		if err := txRepo.queries.DeleteTimeEntry(ctx, id); err != nil {
+			return repoerrors.NewRepositoryErrorWithContext("DeleteTimeEntry", err, r.classifyError(err), map[string]string{
+				"entry_id": fmt.Sprintf("%d", id),
+			})
+		}
+
+		entry := r.convertTimeEntryFromDB(row)
+		return txRepo.auditTimeEntry(ctx, types.TimeEntryDeleted, &entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.LogOperation(r.loggerFor(ctx), "DeleteTimeEntry", time.Since(start), map[string]interface{}{
+		"entry_id": id,
+	})
+	return nil
+}
+
+// GetTimeEntries retrieves the time entries dated within [startDate, endDate]
+func (r *SQLiteRepository) GetTimeEntries(ctx context.Context, startDate, endDate time.Time) ([]types.TimeEntry, error) {
+	rows, err := r.queries.ListTimeEntriesByDateRange(ctx, queries.ListTimeEntriesByDateRangeParams{
+		Date:   types.DateKey(startDate),
+		Date_2: types.DateKeyEnd(endDate),
+	})
+	if err != nil {
+		return nil, repoerrors.NewRepositoryErrorWithContext("GetTimeEntries", err, r.classifyError(err), map[string]string{
+			"start_date": startDate.Format("2006-01-02"),
+			"end_date":   endDate.Format("2006-01-02"),
+		})
+	}
+
+	entries := make([]types.TimeEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = r.convertTimeEntryFromDB(row)
+	}
+	return entries, nil
+}
+
+// GetTimeEntryHistory retrieves the audit trail of a time entry
+func (r *SQLiteRepository) GetTimeEntryHistory(ctx context.Context, id int64) ([]types.TimeEntryEdit, error) {
+	rows, err := r.queries.ListTimeEntryAudit(ctx, id)
+	if err != nil {
+		return nil, repoerrors.NewRepositoryErrorWithContext("GetTimeEntryHistory", err, r.classifyError(err), map[string]string{
+			"entry_id": fmt.Sprintf("%d", id),
+		})
+	}
+
+	edits := make([]types.TimeEntryEdit, len(rows))
+	for i, row := range rows {
+		edits[i] = types.TimeEntryEdit{
+			ID:        row.ID,
+			EntryID:   row.EntryID,
+			Action:    types.TimeEntryAction(row.Action),
+			Date:      row.Date,
+			AppName:   row.AppName,
+			Duration:  row.Duration,
+			Source:    types.TimeSource(row.Source),
+			Note:      row.Note,
+			ChangedAt: r.timeFromNullTime(row.ChangedAt),
+		}
+	}
+	return edits, nil
+}
+
+// addTimeEntries adds the time entries dated within [startDate, endDate] to the usage of
+// their days, creating days that only have entries
+func (r *SQLiteRepository) addTimeEntries(ctx context.Context, usage map[string]*types.UsageData, startDate, endDate time.Time) error {
+	entries, err := r.GetTimeEntries(ctx, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	byDate := make(map[string][]types.TimeEntry)
+	for _, entry := range entries {
+		dateKey := entry.Date.Format("2006-01-02")
+		byDate[dateKey] = append(byDate[dateKey], entry)
+	}
+	for dateKey, dayEntries := range byDate {
+		day, exists := usage[dateKey]
+		if !exists {
+			day = &types.UsageData{Apps: []types.AppUsage{}}
+			usage[dateKey] = day
+		}
+		day.AddTimeEntries(dayEntries)
+	}
+	return nil
+}
+
+// auditTimeEntry records a change to a time entry
+func (r *SQLiteRepository) auditTimeEntry(ctx context.Context, action types.TimeEntryAction, entry *types.TimeEntry) error {
+	if err := r.queries.CreateTimeEntryAudit(ctx, queries.CreateTimeEntryAuditParams{
+		EntryID:  entry.ID,
+		Action:   string(action),
+		Date:     entry.Date,
+		AppName:  entry.AppName,
+		Duration: entry.Duration,
+		Source:   string(entry.Source),
+		Note:     entry.Note,
+	}); err != nil {
+		return repoerrors.NewRepositoryErrorWithContext("auditTimeEntry", err, r.classifyError(err), map[string]string{
+			"entry_id": fmt.Sprintf("%d", entry.ID),
+			"action":   string(action),
+		})
+	}
+	return nil
+}
+
+// checkCorrection rejects a correction that would take away more time than the app has on
+// its date, counting tracked usage and the app's other time entries
+func (r *SQLiteRepository) checkCorrection(ctx context.Context, operation string, entry *types.TimeEntry) error {
+	if entry.Source != types.TimeSourceCorrection || entry.Duration >= 0 {
+		return nil
+	}
+
+	tracked, err := r.GetAppUsageByDate(ctx, entry.Date)
+	if err != nil {
+		return err
+	}
+	var available int64
+	for _, app := range tracked {
+		if app.Name == entry.AppName {
+			available += app.Duration
+		}
+	}
+
+	others, err := r.GetTimeEntries(ctx, entry.Date, entry.Date)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if other.AppName == entry.AppName && other.ID != entry.ID {
+			available += other.Duration
+		}
+	}
+
+	if available+entry.Duration < 0 {
+		return repoerrors.NewRepositoryErrorWithContext(operation,
+			fmt.Errorf("correction of %d seconds exceeds the %d seconds %s has on %s",
+				entry.Duration, available, entry.AppName, entry.Date.Format("2006-01-02")),
+			repoerrors.ErrCodeValidation, map[string]string{
+				"app_name": entry.AppName,
+				"date":     entry.Date.Format("2006-01-02"),
+			})
+	}
+	return nil
+}
+
+// validateTimeEntry rejects nil entries and entries that fail validation
+func (r *SQLiteRepository) validateTimeEntry(operation string, entry *types.TimeEntry) error {
+	if entry == nil {
+		return repoerrors.NewRepositoryError(operation, fmt.Errorf("time entry is nil"), repoerrors.ErrCodeValidation)
+	}
+	if err := entry.Validate(); err != nil {
+		return repoerrors.NewRepositoryErrorWithContext(operation, err, repoerrors.ErrCodeValidation, map[string]string{
+			"app_name": entry.AppName,
+		})
+	}
+	return nil
+}
+
+// convertTimeEntryFromDB converts a database row to a time entry
+func (r *SQLiteRepository) convertTimeEntryFromDB(row queries.TimeEntry) types.TimeEntry {
+	return types.TimeEntry{
+		ID:        row.ID,
+		Date:      row.Date,
+		AppName:   row.AppName,
+		Duration:  row.Duration,
+		Source:    types.TimeSource(row.Source),
+		Note:      row.Note,
+		CreatedAt: r.timeFromNullTime(row.CreatedAt),
+		UpdatedAt: r.timeFromNullTime(row.UpdatedAt),
+	}
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	repoerrors "qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func TestSQLiteRepository_TimeEntries(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	date := time.Date(2024, 5, 6, 15, 30, 0, 0, time.UTC)
+
+	entry := &types.TimeEntry{Date: date, AppName: "Meeting", Duration: 1800, Source: types.TimeSourceManual, Note: "standup"}
+	if err := repo.CreateTimeEntry(ctx, entry); err != nil {
+		t.Fatalf("CreateTimeEntry() error = %v", err)
+	}
+	if entry.ID == 0 || entry.CreatedAt.IsZero() || !entry.Date.Equal(types.DateKey(date)) {
+		t.Errorf("CreateTimeEntry() did not fill in the stored entry: %+v", entry)
+	}
+
+	entry.Duration = 2700
+	entry.Note = "standup and planning"
+	if err := repo.UpdateTimeEntry(ctx, entry); err != nil {
+		t.Fatalf("UpdateTimeEntry() error = %v", err)
+	}
+	if entry.Duration != 2700 {
+		t.Errorf("UpdateTimeEntry() returned %+v", entry)
+	}
+
+	missing := *entry
+	missing.ID = 9999
+	if err := repo.UpdateTimeEntry(ctx, &missing); !repoerrors.IsNotFound(err) {
+		t.Errorf("UpdateTimeEntry() for a missing entry should be not found, got %v", err)
+	}
+	if err := repo.DeleteTimeEntry(ctx, 9999); !repoerrors.IsNotFound(err) {
+		t.Errorf("DeleteTimeEntry() for a missing entry should be not found, got %v", err)
+	}
+
+	tracked := &types.TimeEntry{Date: date, AppName: "Editor", Duration: 60, Source: types.TimeSourceTracked}
+	if err := repo.CreateTimeEntry(ctx, tracked); !repoerrors.IsValidation(err) {
+		t.Errorf("CreateTimeEntry() with the tracked source should fail validation, got %v", err)
+	}
+
+	entries, err := repo.GetTimeEntries(ctx, date, date)
+	if err != nil {
+		t.Fatalf("GetTimeEntries() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Note != "standup and planning" {
+		t.Errorf("GetTimeEntries() = %+v", entries)
+	}
+
+	if err := repo.DeleteTimeEntry(ctx, entry.ID); err != nil {
+		t.Fatalf("DeleteTimeEntry() error = %v", err)
+	}
+	entries, err = repo.GetTimeEntries(ctx, date, date)
+	if err != nil {
+		t.Fatalf("GetTimeEntries() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("DeleteTimeEntry() left %+v", entries)
+	}
+
+	// Every change is kept, including what the entry held when it was deleted
+	history, err := repo.GetTimeEntryHistory(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("GetTimeEntryHistory() error = %v", err)
+	}
+	wantActions := []types.TimeEntryAction{types.TimeEntryCreated, types.TimeEntryUpdated, types.TimeEntryDeleted}
+	if len(history) != len(wantActions) {
+		t.Fatalf("GetTimeEntryHistory() = %+v", history)
+	}
+	for i, action := range wantActions {
+		if history[i].Action != action || history[i].EntryID != entry.ID {
+			t.Errorf("history[%d] = %+v, want action %q", i, history[i], action)
+		}
+	}
+	if history[0].Duration != 1800 || history[2].Duration != 2700 {
+		t.Errorf("history should record each version of the entry, got %+v", history)
+	}
+}
+
+func TestSQLiteRepository_TimeEntryIDsAreNotReused(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	date := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	deleted := &types.TimeEntry{Date: date, AppName: "Meeting", Duration: 1800, Source: types.TimeSourceManual}
+	if err := repo.CreateTimeEntry(ctx, deleted); err != nil {
+		t.Fatalf("CreateTimeEntry() error = %v", err)
+	}
+	if err := repo.DeleteTimeEntry(ctx, deleted.ID); err != nil {
+		t.Fatalf("DeleteTimeEntry() error = %v", err)
+	}
+
+	entry := &types.TimeEntry{Date: date, AppName: "Call", Duration: 600, Source: types.TimeSourceManual}
+	if err := repo.CreateTimeEntry(ctx, entry); err != nil {
+		t.Fatalf("CreateTimeEntry() error = %v", err)
+	}
+	if entry.ID == deleted.ID {
+		t.Fatalf("new entry reused the deleted entry's id %d", entry.ID)
+	}
+
+	// The new entry's history starts with its own creation
+	history, err := repo.GetTimeEntryHistory(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("GetTimeEntryHistory() error = %v", err)
+	}
+	if len(history) != 1 || history[0].Action != types.TimeEntryCreated || history[0].AppName != "Call" {
+		t.Errorf("GetTimeEntryHistory() = %+v, want only the new entry's creation", history)
+	}
+}
+
+func TestSQLiteRepository_TimeEntryCorrections(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	today := types.DateKey(time.Now())
+
+	if err := repo.SaveDailyUsage(ctx, today, &types.UsageData{TotalTime: 3600}); err != nil {
+		t.Fatalf("SaveDailyUsage() error = %v", err)
+	}
+	if err := repo.SaveAppUsage(ctx, today, &types.AppUsage{Name: "Browser", Duration: 3600}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+
+	// Take away half an hour the tracker counted while the browser only looked focused
+	correction := &types.TimeEntry{Date: today, AppName: "Browser", Duration: -1800, Source: types.TimeSourceCorrection, Note: "left running"}
+	if err := repo.CreateTimeEntry(ctx, correction); err != nil {
+		t.Fatalf("CreateTimeEntry() error = %v", err)
+	}
+
+	tooMuch := &types.TimeEntry{Date: today, AppName: "Browser", Duration: -2000, Source: types.TimeSourceCorrection}
+	if err := repo.CreateTimeEntry(ctx, tooMuch); !repoerrors.IsValidation(err) {
+		t.Errorf("a correction below zero should fail validation, got %v", err)
+	}
+	correction.Duration = -3600
+	if err := repo.UpdateTimeEntry(ctx, correction); err != nil {
+		t.Errorf("UpdateTimeEntry() should allow correcting all tracked time, got %v", err)
+	}
+
+	history, err := repo.GetUsageHistory(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetUsageHistory() error = %v", err)
+	}
+	day := history[today.Format("2006-01-02")]
+	if day == nil || day.TotalTime != 0 {
+		t.Fatalf("today = %+v, want the tracked time corrected away", day)
+	}
+	if day.BySource[types.TimeSourceTracked] != 3600 || day.BySource[types.TimeSourceCorrection] != -3600 {
+		t.Errorf("BySource = %v, want tracked and correction apart", day.BySource)
+	}
+
+	// The stored tracked usage is untouched
+	apps, err := repo.GetAppUsageByDate(ctx, today)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDate() error = %v", err)
+	}
+	if len(apps) != 1 || apps[0].Duration != 3600 {
+		t.Errorf("tracked apps = %+v, want 3600s of Browser", apps)
+	}
+}
+
+func TestSQLiteRepository_GetUsageHistoryWithTimeEntries(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	today := types.DateKey(time.Now())
+	yesterday := today.AddDate(0, 0, -1)
+
+	if err := repo.SaveDailyUsage(ctx, today, &types.UsageData{TotalTime: 3600}); err != nil {
+		t.Fatalf("SaveDailyUsage() error = %v", err)
+	}
+	if err := repo.SaveAppUsage(ctx, today, &types.AppUsage{Name: "Editor", Duration: 3600}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	for _, entry := range []*types.TimeEntry{
+		{Date: today, AppName: "Editor", Duration: 600, Source: types.TimeSourceManual},
+		{Date: yesterday, AppName: "Meeting", Duration: 1200, Source: types.TimeSourceImported},
+	} {
+		if err := repo.CreateTimeEntry(ctx, entry); err != nil {
+			t.Fatalf("CreateTimeEntry() error = %v", err)
+		}
+	}
+
+	history, err := repo.GetUsageHistory(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetUsageHistory() error = %v", err)
+	}
+
+	day := history[today.Format("2006-01-02")]
+	if day == nil || day.TotalTime != 4200 || len(day.Apps) != 2 {
+		t.Fatalf("today = %+v, want tracked and manual time", day)
+	}
+	if day.BySource[types.TimeSourceTracked] != 3600 || day.BySource[types.TimeSourceManual] != 600 {
+		t.Errorf("today BySource = %v", day.BySource)
+	}
+	for _, app := range day.Apps {
+		if app.Source == types.TimeSourceTracked && app.Duration != 3600 {
+			t.Errorf("tracked usage changed: %+v", app)
+		}
+	}
+
+	day = history[yesterday.Format("2006-01-02")]
+	if day == nil || day.TotalTime != 1200 || day.BySource[types.TimeSourceImported] != 1200 {
+		t.Errorf("yesterday = %+v, want only imported time", day)
+	}
+
+	// The stored tracked usage is untouched
+	daily, err := repo.GetDailyUsage(ctx, today)
+	if err != nil {
+		t.Fatalf("GetDailyUsage() error = %v", err)
+	}
+	if daily.TotalTime != 3600 {
+		t.Errorf("tracked daily total = %d, want 3600", daily.TotalTime)
+	}
+}
@@ -11,18 +11,24 @@ import (
 )
 
 // WithTransaction executes a function within a database transaction with retry logic
+// When called on a repository that is already bound to a transaction, fn joins that
+// transaction instead of opening a second one, which SQLite would block on
 func (r *SQLiteRepository) WithTransaction(ctx context.Context, fn func(repo UsageRepository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
 	start := time.Now()
 
 	// Execute transaction with retry logic
-	err := repoerrors.WithRetry(ctx, r.retryConfig, func() error {
+	err := r.withRetry(ctx, "WithTransaction", func() error {
 		tx, err := r.db.BeginTx(ctx, nil)
 		if err != nil {
 			repoErr := repoerrors.NewRepositoryError("WithTransaction.Begin", err, r.classifyError(err))
 			if repoErr.IsRetryable() {
-				r.logger.Debug("Retryable error beginning transaction", "error", err)
+				r.loggerFor(ctx).Debug("Retryable error beginning transaction", "error", err)
 			} else {
-				logging.LogError(r.logger, repoErr, "WithTransaction.Begin", nil)
+				logging.LogError(r.loggerFor(ctx), repoErr, "WithTransaction.Begin", nil)
 			}
 			return repoErr
 		}
@@ -32,7 +38,7 @@ func (r *SQLiteRepository) WithTransaction(ctx context.Context, fn func(repo Usa
 		defer func(ctx context.Context) {
 			if !committed && tx != nil {
 				if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
-					r.logger.Debug("Failed to rollback transaction in WithTransaction",
+					r.loggerFor(ctx).Debug("Failed to rollback transaction in WithTransaction",
 						"rollback_error", rollbackErr,
 						"original_error", originalErr,
 						"context", "transaction_cleanup")
@@ -46,8 +52,11 @@ func (r *SQLiteRepository) WithTransaction(ctx context.Context, fn func(repo Usa
 			queries:     r.queries.WithTx(tx),
 			dbService:   r.dbService,
 			retryConfig: r.retryConfig,
+			breaker:     r.breaker,
 			batchConfig: r.batchConfig,
 			logger:      r.logger,
+			dayBoundary: r.dayBoundary,
+			inTx:        true,
 		}
 
 		// Execute the function with the transaction repository
@@ -55,7 +64,7 @@ func (r *SQLiteRepository) WithTransaction(ctx context.Context, fn func(repo Usa
 			// Log transaction function error but don't wrap it
 			// The function should return proper repository errors
 			originalErr = err
-			r.logger.Debug("Transaction function failed", "error", err)
+			r.loggerFor(ctx).Debug("Transaction function failed", "error", err)
 			return err
 		}
 
@@ -64,9 +73,9 @@ func (r *SQLiteRepository) WithTransaction(ctx context.Context, fn func(repo Usa
 			originalErr = err
 			repoErr := repoerrors.NewRepositoryError("WithTransaction.Commit", err, r.classifyError(err))
 			if repoErr.IsRetryable() {
-				r.logger.Debug("Retryable error committing transaction", "error", err)
+				r.loggerFor(ctx).Debug("Retryable error committing transaction", "error", err)
 			} else {
-				logging.LogError(r.logger, repoErr, "WithTransaction.Commit", nil)
+				logging.LogError(r.loggerFor(ctx), repoErr, "WithTransaction.Commit", nil)
 			}
 			return repoErr
 		}
@@ -77,7 +86,7 @@ func (r *SQLiteRepository) WithTransaction(ctx context.Context, fn func(repo Usa
 
 	// Log successful transaction
 	if err == nil {
-		logging.LogOperation(r.logger, "WithTransaction", time.Since(start), nil)
+		logging.LogOperation(r.loggerFor(ctx), "WithTransaction", time.Since(start), nil)
 	}
 
 	return err
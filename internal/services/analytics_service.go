@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// trendFlatThreshold is the slope, in seconds per day, below which a trend counts as flat
+	trendFlatThreshold = 60.0
+	// maxRollingWindow bounds the number of days a rolling average may span
+	maxRollingWindow = 90
+)
+
+// AnalyticsService computes comparisons and trends over stored usage
+type AnalyticsService struct {
+	repository repository.UsageRepository
+	logger     logging.Logger
+
+	mutex       sync.RWMutex
+	dayBoundary types.DayBoundary
+}
+
+// NewAnalyticsService creates an analytics service reading from the given repository
+func NewAnalyticsService(repo repository.UsageRepository, logger logging.Logger) *AnalyticsService {
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+	return &AnalyticsService{
+		repository: repo,
+		logger:     logger,
+	}
+}
+
+// SetDayBoundary sets the day boundary used to decide which usage day is today
+func (s *AnalyticsService) SetDayBoundary(boundary types.DayBoundary) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.dayBoundary = boundary
+}
+
+// Today returns the date key of the current usage day
+func (s *AnalyticsService) Today() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.dayBoundary.DateOf(time.Now())
+}
+
+// WeekToDatePeriods returns the days of date's week up to and including date,
+// and the same weekdays of the week before, so partial weeks compare like for like
+func WeekToDatePeriods(date time.Time) (currentStart, currentEnd, previousStart, previousEnd time.Time) {
+	currentEnd = types.DateKey(date)
+	sinceMonday := (int(currentEnd.Weekday()) + 6) % 7
+	currentStart = currentEnd.AddDate(0, 0, -sinceMonday)
+	return currentStart, currentEnd, currentStart.AddDate(0, 0, -7), currentEnd.AddDate(0, 0, -7)
+}
+
+// ComparePeriods compares usage from start to end with the equally long period right before it
+func (s *AnalyticsService) ComparePeriods(ctx context.Context, start, end time.Time) (*types.PeriodComparison, error) {
+	start, end = types.DateKey(start), types.DateKey(end)
+	dayCount, err := periodDays("ComparePeriods", start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	previousEnd := start.AddDate(0, 0, -1)
+	previousStart := previousEnd.AddDate(0, 0, -(dayCount - 1))
+	return s.compare(ctx, start, end, previousStart, previousEnd, dayCount)
+}
+
+// CompareWeekToDate compares this week so far with the same days of last week
+func (s *AnalyticsService) CompareWeekToDate(ctx context.Context, date time.Time) (*types.PeriodComparison, error) {
+	currentStart, currentEnd, previousStart, previousEnd := WeekToDatePeriods(date)
+	dayCount := int(currentEnd.Sub(currentStart).Hours()/24) + 1
+	return s.compare(ctx, currentStart, currentEnd, previousStart, previousEnd, dayCount)
+}
+
+// compare builds the comparison of two periods that both span dayCount days
+func (s *AnalyticsService) compare(ctx context.Context, start, end, previousStart, previousEnd time.Time, dayCount int) (*types.PeriodComparison, error) {
+	current, err := s.summarize(ctx, start, end, dayCount)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.summarize(ctx, previousStart, previousEnd, dayCount)
+	if err != nil {
+		return nil, err
+	}
+
+	currentApps, err := s.appTotals(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	previousApps, err := s.appTotals(ctx, previousStart, previousEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &types.PeriodComparison{
+		Current:  *current,
+		Previous: *previous,
+		Change:   current.TotalTime - previous.TotalTime,
+		Apps:     compareApps(currentApps, previousApps),
+	}
+	comparison.ChangePercent = percentChange(comparison.Change, previous.TotalTime)
+	return comparison, nil
+}
+
+// summarize totals the stored daily usage of a period
+func (s *AnalyticsService) summarize(ctx context.Context, start, end time.Time, dayCount int) (*types.PeriodSummary, error) {
+	totals, err := storedDailyTotals(ctx, s.repository, start, dayCount)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &types.PeriodSummary{Start: start, End: end}
+	for _, total := range totals {
+		summary.TotalTime += total
+		if total > 0 {
+			summary.ActiveDays++
+		}
+	}
+	summary.DailyAverage = summary.TotalTime / int64(dayCount)
+	return summary, nil
+}
+
+// appTotals sums each app's usage over a period, by the name it is shown under
+func (s *AnalyticsService) appTotals(ctx context.Context, start, end time.Time) (map[string]int64, error) {
+	apps, err := s.repository.GetAppUsageByDateRange(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]int64)
+	for _, app := range apps {
+		totals[app.Label()] += app.Duration
+	}
+	return totals, nil
+}
+
+// compareApps pairs up app totals from two periods, largest current usage first
+func compareApps(current, previous map[string]int64) []types.AppComparison {
+	comparisons := make([]types.AppComparison, 0, len(current))
+	seen := make(map[string]bool, len(current))
+	add := func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		change := current[name] - previous[name]
+		comparisons = append(comparisons, types.AppComparison{
+			Name:          name,
+			Current:       current[name],
+			Previous:      previous[name],
+			Change:        change,
+			ChangePercent: percentChange(change, previous[name]),
+		})
+	}
+	for name := range current {
+		add(name)
+	}
+	for name := range previous {
+		add(name)
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		if comparisons[i].Current != comparisons[j].Current {
+			return comparisons[i].Current > comparisons[j].Current
+		}
+		if comparisons[i].Previous != comparisons[j].Previous {
+			return comparisons[i].Previous > comparisons[j].Previous
+		}
+		return comparisons[i].Name < comparisons[j].Name
+	})
+	return comparisons
+}
+
+// TopMovers returns up to limit apps whose usage from start to end grew or shrank the most
+// compared with the equally long period before it
+func (s *AnalyticsService) TopMovers(ctx context.Context, start, end time.Time, limit int) (*types.AppMovers, error) {
+	if limit <= 0 {
+		return nil, errors.NewRepositoryError("TopMovers",
+			fmt.Errorf("limit must be positive, got %d", limit), errors.ErrCodeValidation)
+	}
+
+	comparison, err := s.ComparePeriods(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return splitMovers(comparison.Apps, limit), nil
+}
+
+// splitMovers picks the largest increases and decreases from a set of app comparisons
+func splitMovers(apps []types.AppComparison, limit int) *types.AppMovers {
+	movers := &types.AppMovers{
+		Risers:  []types.AppComparison{},
+		Fallers: []types.AppComparison{},
+	}
+	for _, app := range apps {
+		switch {
+		case app.Change > 0:
+			movers.Risers = append(movers.Risers, app)
+		case app.Change < 0:
+			movers.Fallers = append(movers.Fallers, app)
+		}
+	}
+
+	sort.SliceStable(movers.Risers, func(i, j int) bool {
+		return movers.Risers[i].Change > movers.Risers[j].Change
+	})
+	sort.SliceStable(movers.Fallers, func(i, j int) bool {
+		return movers.Fallers[i].Change < movers.Fallers[j].Change
+	})
+
+	if len(movers.Risers) > limit {
+		movers.Risers = movers.Risers[:limit]
+	}
+	if len(movers.Fallers) > limit {
+		movers.Fallers = movers.Fallers[:limit]
+	}
+	return movers
+}
+
+// RollingAverages returns each day's total from start to end with the mean daily total
+// over the window days ending on it. Days before start are read so the first points
+// average over a full window too.
+func (s *AnalyticsService) RollingAverages(ctx context.Context, start, end time.Time, window int) ([]types.RollingAveragePoint, error) {
+	if window <= 0 || window > maxRollingWindow {
+		return nil, errors.NewRepositoryError("RollingAverages",
+			fmt.Errorf("window must be between 1 and %d days, got %d", maxRollingWindow, window), errors.ErrCodeValidation)
+	}
+
+	start, end = types.DateKey(start), types.DateKey(end)
+	dayCount, err := periodDays("RollingAverages", start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	lead := window - 1
+	totals, err := storedDailyTotals(ctx, s.repository, start.AddDate(0, 0, -lead), dayCount+lead)
+	if err != nil {
+		return nil, err
+	}
+	return rollingAverages(start, totals, window), nil
+}
+
+// rollingAverages turns daily totals, starting window-1 days before start, into rolling average points
+func rollingAverages(start time.Time, totals []int64, window int) []types.RollingAveragePoint {
+	lead := window - 1
+	points := make([]types.RollingAveragePoint, 0, len(totals)-lead)
+
+	var sum int64
+	for i, total := range totals {
+		sum += total
+		if i >= window {
+			sum -= totals[i-window]
+		}
+		if i < lead {
+			continue
+		}
+		points = append(points, types.RollingAveragePoint{
+			Date:      start.AddDate(0, 0, i-lead),
+			TotalTime: total,
+			Average:   float64(sum) / float64(window),
+		})
+	}
+	return points
+}
+
+// AppTrends fits a line through each app's daily usage from start to end and returns
+// the trends with the steepest rise first and the steepest fall last
+func (s *AnalyticsService) AppTrends(ctx context.Context, start, end time.Time) ([]types.AppTrend, error) {
+	start, end = types.DateKey(start), types.DateKey(end)
+	dayCount, err := periodDays("AppTrends", start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := s.repository.GetAppUsageByDateRange(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Days an app wasn't used count as zero, so each series covers the whole period
+	series := make(map[string][]float64)
+	for _, app := range apps {
+		day := int(types.DateKey(app.Date).Sub(start).Hours() / 24)
+		if day < 0 || day >= dayCount {
+			continue
+		}
+		label := app.Label()
+		if series[label] == nil {
+			series[label] = make([]float64, dayCount)
+		}
+		series[label][day] += float64(app.Duration)
+	}
+
+	trends := make([]types.AppTrend, 0, len(series))
+	for name, values := range series {
+		var total float64
+		for _, value := range values {
+			total += value
+		}
+		slope := trendSlope(values)
+		trends = append(trends, types.AppTrend{
+			Name:         name,
+			TotalTime:    int64(total),
+			DailyAverage: total / float64(dayCount),
+			Slope:        slope,
+			Direction:    trendDirection(slope),
+		})
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Slope != trends[j].Slope {
+			return trends[i].Slope > trends[j].Slope
+		}
+		return trends[i].Name < trends[j].Name
+	})
+	return trends, nil
+}
+
+// trendSlope returns the least-squares slope of values over their index, in units per step
+func trendSlope(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+
+	meanX := (n - 1) / 2
+	var meanY float64
+	for _, value := range values {
+		meanY += value
+	}
+	meanY /= n
+
+	var covariance, variance float64
+	for i, value := range values {
+		dx := float64(i) - meanX
+		covariance += dx * (value - meanY)
+		variance += dx * dx
+	}
+	return covariance / variance
+}
+
+// trendDirection classifies a slope in seconds per day
+func trendDirection(slope float64) types.TrendDirection {
+	switch {
+	case math.Abs(slope) < trendFlatThreshold:
+		return types.TrendFlat
+	case slope > 0:
+		return types.TrendRising
+	default:
+		return types.TrendFalling
+	}
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"qwin/internal/database"
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+// analyticsSeedStart is the Monday the seeded analytics data begins on
+var analyticsSeedStart = time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+
+// setupAnalyticsService returns an analytics service over an in-memory database seeded
+// with two weeks of usage: Code grows by 10 minutes a day, Slack shrinks by 5 minutes
+// a day, and Spotify is only used in the second week
+func setupAnalyticsService(t *testing.T) *AnalyticsService {
+	t.Helper()
+
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	for day := 0; day < 14; day++ {
+		date := analyticsSeedStart.AddDate(0, 0, day)
+		apps := []types.AppUsage{
+			{Name: "Code", Duration: int64(3600 + day*600)},
+			{Name: "Slack", Duration: int64(5400 - day*300)},
+		}
+		if day >= 7 {
+			apps = append(apps, types.AppUsage{Name: "Spotify", Duration: 1800})
+		}
+
+		var total int64
+		for _, app := range apps {
+			total += app.Duration
+		}
+		if err := repo.SaveDailyUsage(ctx, date, &types.UsageData{TotalTime: total}); err != nil {
+			t.Fatalf("SaveDailyUsage failed: %v", err)
+		}
+		if err := repo.BatchProcessAppUsage(ctx, date, apps, types.BatchStrategyUpsert); err != nil {
+			t.Fatalf("BatchProcessAppUsage failed: %v", err)
+		}
+	}
+
+	service := NewAnalyticsService(repo, logging.NewDefaultLogger())
+	service.SetDayBoundary(utcMidnight(t))
+	return service
+}
+
+// setupSQLiteRepository returns a repository over a migrated in-memory database
+func setupSQLiteRepository(t *testing.T) *repository.SQLiteRepository {
+	t.Helper()
+
+	logger := logging.NewDefaultLogger()
+	dbService := database.NewSQLiteService(logger)
+	ctx := context.Background()
+	if err := dbService.Connect(ctx, database.TestConfig()); err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	t.Cleanup(func() { dbService.Close() })
+	if err := dbService.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	return repository.NewSQLiteRepository(dbService, logger)
+}
+
+func TestAnalyticsService_ComparePeriods(t *testing.T) {
+	service := setupAnalyticsService(t)
+	secondWeek := analyticsSeedStart.AddDate(0, 0, 7)
+
+	tests := []struct {
+		name            string
+		start, end      time.Time
+		currentTotal    int64
+		previousTotal   int64
+		changePercent   float64
+		firstApp        string
+		firstAppPrev    int64
+		previousStarted time.Time
+	}{
+		{
+			name:            "second week against first",
+			start:           secondWeek,
+			end:             secondWeek.AddDate(0, 0, 6),
+			currentTotal:    7*(3600+5400+1800) + 600*(7+8+9+10+11+12+13) - 300*(7+8+9+10+11+12+13),
+			previousTotal:   7*(3600+5400) + 600*21 - 300*21,
+			changePercent:   float64(7*1800+300*49) / float64(7*9000+300*21) * 100,
+			firstApp:        "Code",
+			firstAppPrev:    7*3600 + 600*21,
+			previousStarted: analyticsSeedStart,
+		},
+		{
+			name:            "first week against nothing",
+			start:           analyticsSeedStart,
+			end:             analyticsSeedStart.AddDate(0, 0, 6),
+			currentTotal:    7*9000 + 300*21,
+			previousTotal:   0,
+			changePercent:   0,
+			firstApp:        "Code",
+			firstAppPrev:    0,
+			previousStarted: analyticsSeedStart.AddDate(0, 0, -7),
+		},
+		{
+			name:            "single day",
+			start:           secondWeek,
+			end:             secondWeek,
+			currentTotal:    (3600 + 4200) + (5400 - 2100) + 1800,
+			previousTotal:   (3600 + 3600) + (5400 - 1800),
+			changePercent:   float64(1800+300) / float64(10800) * 100,
+			firstApp:        "Code",
+			firstAppPrev:    7200,
+			previousStarted: secondWeek.AddDate(0, 0, -1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison, err := service.ComparePeriods(context.Background(), tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("ComparePeriods failed: %v", err)
+			}
+			if comparison.Current.TotalTime != tt.currentTotal || comparison.Previous.TotalTime != tt.previousTotal {
+				t.Errorf("totals = %d vs %d, want %d vs %d",
+					comparison.Current.TotalTime, comparison.Previous.TotalTime, tt.currentTotal, tt.previousTotal)
+			}
+			if comparison.Change != tt.currentTotal-tt.previousTotal {
+				t.Errorf("Change = %d, want %d", comparison.Change, tt.currentTotal-tt.previousTotal)
+			}
+			if math.Abs(comparison.ChangePercent-tt.changePercent) > 1e-9 {
+				t.Errorf("ChangePercent = %f, want %f", comparison.ChangePercent, tt.changePercent)
+			}
+			if !comparison.Previous.Start.Equal(tt.previousStarted) {
+				t.Errorf("Previous.Start = %s, want %s", comparison.Previous.Start, tt.previousStarted)
+			}
+			if len(comparison.Apps) == 0 || comparison.Apps[0].Name != tt.firstApp || comparison.Apps[0].Previous != tt.firstAppPrev {
+				t.Errorf("Apps = %+v, want %s first with previous %d", comparison.Apps, tt.firstApp, tt.firstAppPrev)
+			}
+		})
+	}
+
+	if _, err := service.ComparePeriods(context.Background(), secondWeek, analyticsSeedStart); !errors.IsValidation(err) {
+		t.Errorf("expected a validation error for an inverted period, got %v", err)
+	}
+}
+
+func TestAnalyticsService_CompareWeekToDate(t *testing.T) {
+	service := setupAnalyticsService(t)
+
+	// Wednesday of the second week: Mon-Wed against Mon-Wed of the first
+	comparison, err := service.CompareWeekToDate(context.Background(), analyticsSeedStart.AddDate(0, 0, 9))
+	if err != nil {
+		t.Fatalf("CompareWeekToDate failed: %v", err)
+	}
+
+	if !comparison.Current.Start.Equal(analyticsSeedStart.AddDate(0, 0, 7)) || !comparison.Previous.End.Equal(analyticsSeedStart.AddDate(0, 0, 2)) {
+		t.Errorf("periods = %+v / %+v", comparison.Current, comparison.Previous)
+	}
+	wantPrevious := int64(3*9000 + 300*(0+1+2))
+	if comparison.Previous.TotalTime != wantPrevious || comparison.Previous.DailyAverage != wantPrevious/3 {
+		t.Errorf("previous = %+v, want total %d", comparison.Previous, wantPrevious)
+	}
+}
+
+func TestAnalyticsService_TopMovers(t *testing.T) {
+	service := setupAnalyticsService(t)
+	secondWeek := analyticsSeedStart.AddDate(0, 0, 7)
+
+	tests := []struct {
+		name    string
+		limit   int
+		risers  []string
+		fallers []string
+	}{
+		{"all movers", 5, []string{"Code", "Spotify"}, []string{"Slack"}},
+		{"limited", 1, []string{"Code"}, []string{"Slack"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movers, err := service.TopMovers(context.Background(), secondWeek, secondWeek.AddDate(0, 0, 6), tt.limit)
+			if err != nil {
+				t.Fatalf("TopMovers failed: %v", err)
+			}
+			if got := comparisonNames(movers.Risers); !equalStrings(got, tt.risers) {
+				t.Errorf("Risers = %v, want %v", got, tt.risers)
+			}
+			if got := comparisonNames(movers.Fallers); !equalStrings(got, tt.fallers) {
+				t.Errorf("Fallers = %v, want %v", got, tt.fallers)
+			}
+		})
+	}
+
+	if _, err := service.TopMovers(context.Background(), secondWeek, secondWeek, 0); !errors.IsValidation(err) {
+		t.Errorf("expected a validation error for a zero limit, got %v", err)
+	}
+}
+
+func TestAnalyticsService_RollingAverages(t *testing.T) {
+	service := setupAnalyticsService(t)
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		window   int
+		averages []float64
+	}{
+		{
+			name:     "window of one is the daily total",
+			start:    analyticsSeedStart,
+			window:   1,
+			averages: []float64{9000, 9300, 9600},
+		},
+		{
+			name:     "window reaching before the seeded data",
+			start:    analyticsSeedStart,
+			window:   3,
+			averages: []float64{3000, 6100, 9300},
+		},
+		{
+			name:     "window inside the seeded data",
+			start:    analyticsSeedStart.AddDate(0, 0, 2),
+			window:   2,
+			averages: []float64{9450, 9750, 10050},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := service.RollingAverages(context.Background(), tt.start, tt.start.AddDate(0, 0, len(tt.averages)-1), tt.window)
+			if err != nil {
+				t.Fatalf("RollingAverages failed: %v", err)
+			}
+			if len(points) != len(tt.averages) {
+				t.Fatalf("got %d points, want %d", len(points), len(tt.averages))
+			}
+			for i, point := range points {
+				if !point.Date.Equal(tt.start.AddDate(0, 0, i)) || math.Abs(point.Average-tt.averages[i]) > 1e-9 {
+					t.Errorf("point %d = %+v, want average %f", i, point, tt.averages[i])
+				}
+			}
+		})
+	}
+
+	if _, err := service.RollingAverages(context.Background(), analyticsSeedStart, analyticsSeedStart, 0); !errors.IsValidation(err) {
+		t.Errorf("expected a validation error for a zero window, got %v", err)
+	}
+}
+
+func TestAnalyticsService_AppTrends(t *testing.T) {
+	service := setupAnalyticsService(t)
+
+	trends, err := service.AppTrends(context.Background(), analyticsSeedStart, analyticsSeedStart.AddDate(0, 0, 13))
+	if err != nil {
+		t.Fatalf("AppTrends failed: %v", err)
+	}
+
+	want := []struct {
+		name      string
+		slope     float64
+		direction types.TrendDirection
+	}{
+		{"Code", 600, types.TrendRising},
+		{"Spotify", 1800 * 24.5 / 227.5, types.TrendRising},
+		{"Slack", -300, types.TrendFalling},
+	}
+	if len(trends) != len(want) {
+		t.Fatalf("trends = %+v, want %d entries", trends, len(want))
+	}
+	for i, w := range want {
+		if trends[i].Name != w.name || math.Abs(trends[i].Slope-w.slope) > 1e-6 || trends[i].Direction != w.direction {
+			t.Errorf("trend %d = %+v, want %s with slope %f (%s)", i, trends[i], w.name, w.slope, w.direction)
+		}
+	}
+}
+
+func TestTrendSlope(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []float64
+		slope     float64
+		direction types.TrendDirection
+	}{
+		{"empty", nil, 0, types.TrendFlat},
+		{"single value", []float64{500}, 0, types.TrendFlat},
+		{"constant", []float64{300, 300, 300}, 0, types.TrendFlat},
+		{"rising", []float64{0, 100, 200, 300}, 100, types.TrendRising},
+		{"falling", []float64{900, 600, 300}, -300, types.TrendFalling},
+		{"noisy but flat", []float64{100, 130, 90, 120}, 2, types.TrendFlat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slope := trendSlope(tt.values)
+			if math.Abs(slope-tt.slope) > 1e-9 {
+				t.Errorf("trendSlope(%v) = %f, want %f", tt.values, slope, tt.slope)
+			}
+			if direction := trendDirection(slope); direction != tt.direction {
+				t.Errorf("trendDirection(%f) = %s, want %s", slope, direction, tt.direction)
+			}
+		})
+	}
+}
+
+func comparisonNames(comparisons []types.AppComparison) []string {
+	names := make([]string, 0, len(comparisons))
+	for _, comparison := range comparisons {
+		names = append(names, comparison.Name)
+	}
+	return names
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// anomalyBaselineDays is how many days before a date make up its baseline
+	anomalyBaselineDays = 28
+	// anomalyMinActiveDays is how many days with usage a baseline needs before it is trusted
+	anomalyMinActiveDays = 7
+	// anomalyScoreThreshold is the robust z-score beyond which usage is flagged
+	anomalyScoreThreshold = 3.5
+	// anomalyMinDeviation keeps small absolute differences from being flagged, in seconds
+	anomalyMinDeviation = 15 * 60
+	// anomalyMaxScore caps scores, and is used when the baseline doesn't vary at all
+	anomalyMaxScore = 10.0
+
+	// madScale makes the median absolute deviation comparable to a standard deviation
+	madScale = 1.4826
+	// meanADScale does the same for the mean absolute deviation
+	meanADScale = 1.2533
+)
+
+// AnomalyDetector flags days and apps whose usage deviates from the user's own recent baseline.
+// The baseline is the median of the trailing anomalyBaselineDays days that had any usage,
+// and deviations are scored against the median absolute deviation, so a few unusual days in
+// the window don't shift what counts as normal.
+type AnomalyDetector struct {
+	repository repository.UsageRepository
+	insights   repository.InsightRepository // nil when the repository can't store insights
+	logger     logging.Logger
+
+	mutex       sync.RWMutex
+	dayBoundary types.DayBoundary
+	// completedThrough is the last finished day detection has run for in this session
+	completedThrough time.Time
+}
+
+// NewAnomalyDetector creates a detector reading usage from, and storing insights in, the repository
+func NewAnomalyDetector(repo repository.UsageRepository, logger logging.Logger) *AnomalyDetector {
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+
+	insights, _ := repo.(repository.InsightRepository)
+
+	return &AnomalyDetector{
+		repository: repo,
+		insights:   insights,
+		logger:     logger,
+	}
+}
+
+// SetDayBoundary sets the day boundary used to decide which usage day is today
+func (d *AnomalyDetector) SetDayBoundary(boundary types.DayBoundary) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.dayBoundary = boundary
+}
+
+// Today returns the date key of the current usage day
+func (d *AnomalyDetector) Today() time.Time {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.dayBoundary.DateOf(time.Now())
+}
+
+// Detect compares a day's usage with its baseline, replaces the insights stored for the day
+// and returns them. While a day is still in progress (dayComplete false) only usage above
+// the baseline is flagged, since the day can't yet be said to fall short of it.
+func (d *AnomalyDetector) Detect(ctx context.Context, date time.Time, dayComplete bool) ([]types.Insight, error) {
+	date = types.DateKey(date)
+	baselineStart := date.AddDate(0, 0, -anomalyBaselineDays)
+
+	totals, err := storedDailyTotals(ctx, d.repository, baselineStart, anomalyBaselineDays+1)
+	if err != nil {
+		return nil, err
+	}
+	baselineTotals, todayTotal := totals[:anomalyBaselineDays], totals[anomalyBaselineDays]
+
+	// Days without any usage are days the computer wasn't used, not days of restraint
+	var activeDays []int
+	for i, total := range baselineTotals {
+		if total > 0 {
+			activeDays = append(activeDays, i)
+		}
+	}
+
+	var insights []types.Insight
+	if len(activeDays) >= anomalyMinActiveDays {
+		window := baselineWindow{start: baselineStart, end: date.AddDate(0, 0, -1), activeDays: len(activeDays)}
+
+		values := make([]float64, len(activeDays))
+		for i, day := range activeDays {
+			values[i] = float64(baselineTotals[day])
+		}
+		if insight, ok := scoreUsage(types.InsightDailyTotal, "", todayTotal, values, dayComplete, window); ok {
+			insights = append(insights, insight)
+		}
+
+		appInsights, err := d.detectApps(ctx, date, baselineStart, activeDays, dayComplete, window)
+		if err != nil {
+			return nil, err
+		}
+		insights = append(insights, appInsights...)
+	}
+
+	for i := range insights {
+		insights[i].Date = date
+		insights[i].Message = insightMessage(insights[i], d.Today())
+	}
+
+	if d.insights != nil {
+		if err := d.insights.ReplaceInsightsForDate(ctx, date, insights); err != nil {
+			return nil, err
+		}
+	}
+	return insights, nil
+}
+
+// Refresh re-runs detection for today so far and, once per session and day, for yesterday
+// now that it is complete, so partial-day insights are replaced by final ones
+func (d *AnomalyDetector) Refresh(ctx context.Context) error {
+	today := d.Today()
+	yesterday := today.AddDate(0, 0, -1)
+
+	d.mutex.RLock()
+	yesterdayDone := d.completedThrough.Equal(yesterday)
+	d.mutex.RUnlock()
+
+	if !yesterdayDone {
+		if _, err := d.Detect(ctx, yesterday, true); err != nil {
+			return err
+		}
+		d.mutex.Lock()
+		d.completedThrough = yesterday
+		d.mutex.Unlock()
+	}
+
+	_, err := d.Detect(ctx, today, false)
+	return err
+}
+
+// detectApps scores each app used on the date or during the baseline window
+func (d *AnomalyDetector) detectApps(ctx context.Context, date, baselineStart time.Time, activeDays []int, dayComplete bool, window baselineWindow) ([]types.Insight, error) {
+	rows, err := d.repository.GetAppUsageByDateRange(ctx, baselineStart, date)
+	if err != nil {
+		return nil, err
+	}
+
+	// Per-app usage on each baseline day, and on the date itself
+	daily := make(map[string][]int64)
+	for _, row := range rows {
+		if row.Name == types.PrivateAppName {
+			continue
+		}
+		day := int(types.DateKey(row.Date).Sub(baselineStart).Hours() / 24)
+		if day < 0 || day > anomalyBaselineDays {
+			continue
+		}
+		label := row.Label()
+		if daily[label] == nil {
+			daily[label] = make([]int64, anomalyBaselineDays+1)
+		}
+		daily[label][day] += row.Duration
+	}
+
+	var insights []types.Insight
+	for name, days := range daily {
+		// Baselines only cover active days, counting those the app wasn't used on as zero
+		values := make([]float64, len(activeDays))
+		for i, day := range activeDays {
+			values[i] = float64(days[day])
+		}
+		if insight, ok := scoreUsage(types.InsightAppUsage, name, days[anomalyBaselineDays], values, dayComplete, window); ok {
+			insights = append(insights, insight)
+		}
+	}
+
+	sort.Slice(insights, func(i, j int) bool {
+		if math.Abs(insights[i].Score) != math.Abs(insights[j].Score) {
+			return math.Abs(insights[i].Score) > math.Abs(insights[j].Score)
+		}
+		return insights[i].AppName < insights[j].AppName
+	})
+	return insights, nil
+}
+
+// GetInsights returns the stored insights dated within [start, end], with messages phrased for today
+func (d *AnomalyDetector) GetInsights(ctx context.Context, start, end time.Time) ([]types.Insight, error) {
+	if d.insights == nil {
+		return []types.Insight{}, nil
+	}
+
+	insights, err := d.insights.GetInsights(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	today := d.Today()
+	for i := range insights {
+		insights[i].Message = insightMessage(insights[i], today)
+	}
+	return insights, nil
+}
+
+// baselineWindow describes the days a baseline was computed from, for explanations
+type baselineWindow struct {
+	start, end time.Time
+	activeDays int
+}
+
+// scoreUsage builds an insight when value deviates significantly from the baseline values
+func scoreUsage(kind types.InsightKind, appName string, value int64, baseline []float64, dayComplete bool, window baselineWindow) (types.Insight, bool) {
+	median, spread, score := robustScore(float64(value), baseline)
+
+	if math.Abs(score) < anomalyScoreThreshold || math.Abs(float64(value)-median) < anomalyMinDeviation {
+		return types.Insight{}, false
+	}
+	if score < 0 && !dayComplete {
+		return types.Insight{}, false
+	}
+
+	return types.Insight{
+		Kind:     kind,
+		AppName:  appName,
+		Value:    value,
+		Baseline: median,
+		Score:    score,
+		Explanation: fmt.Sprintf("Usual is the median of %d active days from %s: %s a day, typically within ±%s. This day had %s, a robust z-score of %.1f.",
+			window.activeDays, formatDateSpan(window.start, window.end), formatReportDuration(int64(median)),
+			formatReportDuration(int64(spread)), formatReportDuration(value), score),
+	}, true
+}
+
+// robustScore returns the median of baseline, its spread and value's robust z-score.
+// The spread is the scaled median absolute deviation, falling back to the scaled mean
+// absolute deviation when more than half the baseline is identical.
+func robustScore(value float64, baseline []float64) (median, spread, score float64) {
+	if len(baseline) == 0 {
+		return 0, 0, 0
+	}
+
+	median = medianOf(baseline)
+	deviations := make([]float64, len(baseline))
+	var meanDeviation float64
+	for i, v := range baseline {
+		deviations[i] = math.Abs(v - median)
+		meanDeviation += deviations[i]
+	}
+	meanDeviation /= float64(len(baseline))
+
+	spread = medianOf(deviations) * madScale
+	if spread == 0 {
+		spread = meanDeviation * meanADScale
+	}
+
+	switch {
+	case value == median:
+		score = 0
+	case spread == 0:
+		score = math.Copysign(anomalyMaxScore, value-median)
+	default:
+		score = (value - median) / spread
+	}
+	return median, spread, math.Max(-anomalyMaxScore, math.Min(anomalyMaxScore, score))
+}
+
+// medianOf returns the median of values without reordering them
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+// insightMessage phrases an insight as a short sentence relative to today,
+// e.g. "You spent 3x your usual time in Discord today"
+func insightMessage(insight types.Insight, today time.Time) string {
+	when := relativeDay(insight.Date, today)
+	subject := "on screen"
+	if insight.Kind == types.InsightAppUsage {
+		subject = "in " + insight.AppName
+	}
+
+	ratio := insight.Ratio()
+	switch {
+	case insight.Score < 0 && ratio > 0:
+		return fmt.Sprintf("You spent %.0f%% less time %s than usual %s", (1-ratio)*100, subject, when)
+	case insight.Score < 0:
+		return fmt.Sprintf("You spent %s less time %s than usual %s", formatReportDuration(int64(insight.Baseline)-insight.Value), subject, when)
+	case ratio >= 2:
+		return fmt.Sprintf("You spent %.0fx your usual time %s %s", math.Floor(ratio), subject, when)
+	case ratio >= 1.5:
+		return fmt.Sprintf("You spent %.1fx your usual time %s %s", math.Floor(ratio*10)/10, subject, when)
+	case ratio == 0:
+		return fmt.Sprintf("You spent %s %s %s, more than you usually do", formatReportDuration(insight.Value), subject, when)
+	default:
+		return fmt.Sprintf("You spent %s more time %s than usual %s", formatReportDuration(insight.Value-int64(insight.Baseline)), subject, when)
+	}
+}
+
+// relativeDay names a date relative to today: "today", "yesterday" or "on Mon, Jun 3"
+func relativeDay(date, today time.Time) string {
+	switch types.DateKey(today).Sub(types.DateKey(date)) {
+	case 0:
+		return "today"
+	case 24 * time.Hour:
+		return "yesterday"
+	default:
+		return "on " + date.Format("Mon, Jan 2")
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// anomalyDate is the day the seeded anomaly data is checked on
+var anomalyDate = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+// setupAnomalyDetector seeds four weeks of steady usage with one day off a week, then a day
+// with three times the usual Discord time and no Slack at all
+func setupAnomalyDetector(t *testing.T) *AnomalyDetector {
+	t.Helper()
+
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	save := func(date time.Time, apps []types.AppUsage) {
+		var total int64
+		for _, app := range apps {
+			total += app.Duration
+		}
+		if err := repo.SaveDailyUsage(ctx, date, &types.UsageData{TotalTime: total}); err != nil {
+			t.Fatalf("SaveDailyUsage failed: %v", err)
+		}
+		if err := repo.BatchProcessAppUsage(ctx, date, apps, types.BatchStrategyUpsert); err != nil {
+			t.Fatalf("BatchProcessAppUsage failed: %v", err)
+		}
+	}
+
+	for day := 0; day < anomalyBaselineDays; day++ {
+		if day%7 == 6 {
+			continue
+		}
+		save(anomalyDate.AddDate(0, 0, day-anomalyBaselineDays), []types.AppUsage{
+			{Name: "Code", Duration: int64(7200 + (day%5)*120)},
+			{Name: "Discord", Duration: int64(1750 + (day%3)*50)},
+			{Name: "Slack", Duration: 3600},
+		})
+	}
+	save(anomalyDate, []types.AppUsage{
+		{Name: "Code", Duration: 7440},
+		{Name: "Discord", Duration: 5400},
+	})
+
+	detector := NewAnomalyDetector(repo, logging.NewDefaultLogger())
+	detector.SetDayBoundary(utcMidnight(t))
+	return detector
+}
+
+func TestAnomalyDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		date        time.Time
+		dayComplete bool
+		apps        []string
+		scores      []float64
+	}{
+		{"day in progress flags only increases", anomalyDate, false, []string{"Discord"}, []float64{anomalyMaxScore}},
+		{"complete day flags decreases too", anomalyDate, true, []string{"Discord", "Slack"}, []float64{anomalyMaxScore, -anomalyMaxScore}},
+		{"too little history", anomalyDate.AddDate(0, 0, -22), true, nil, nil},
+		{"ordinary day", anomalyDate.AddDate(0, 0, -2), true, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := setupAnomalyDetector(t)
+
+			insights, err := detector.Detect(context.Background(), tt.date, tt.dayComplete)
+			if err != nil {
+				t.Fatalf("Detect failed: %v", err)
+			}
+			if len(insights) != len(tt.apps) {
+				t.Fatalf("got %d insights (%+v), want %d", len(insights), insights, len(tt.apps))
+			}
+			for i, insight := range insights {
+				if insight.Kind != types.InsightAppUsage || insight.AppName != tt.apps[i] || insight.Score != tt.scores[i] {
+					t.Errorf("insight %d = %s %q score %.1f, want app %q score %.1f", i, insight.Kind, insight.AppName, insight.Score, tt.apps[i], tt.scores[i])
+				}
+				if insight.Explanation == "" || !insight.Date.Equal(types.DateKey(tt.date)) {
+					t.Errorf("insight %d is missing its explanation or date: %+v", i, insight)
+				}
+			}
+
+			stored, err := detector.GetInsights(context.Background(), tt.date, tt.date)
+			if err != nil {
+				t.Fatalf("GetInsights failed: %v", err)
+			}
+			if len(stored) != len(insights) {
+				t.Errorf("stored %d insights, want %d", len(stored), len(insights))
+			}
+		})
+	}
+}
+
+func TestAnomalyDetector_DetectReplacesInsights(t *testing.T) {
+	detector := setupAnomalyDetector(t)
+	ctx := context.Background()
+
+	if _, err := detector.Detect(ctx, anomalyDate, true); err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	if _, err := detector.Detect(ctx, anomalyDate, false); err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+
+	stored, err := detector.GetInsights(ctx, anomalyDate, anomalyDate)
+	if err != nil {
+		t.Fatalf("GetInsights failed: %v", err)
+	}
+	if len(stored) != 1 || stored[0].AppName != "Discord" {
+		t.Fatalf("stored = %+v, want only the Discord insight", stored)
+	}
+
+	insight := stored[0]
+	if insight.Value != 5400 || insight.Baseline != 1800 || insight.Ratio() != 3 {
+		t.Errorf("insight = %+v, want 5400s against a 1800s baseline", insight)
+	}
+	if insight.Message != "You spent 3x your usual time in Discord on Mon, Jul 1" {
+		t.Errorf("Message = %q", insight.Message)
+	}
+	if !strings.Contains(insight.Explanation, "median of 24 active days") {
+		t.Errorf("Explanation = %q", insight.Explanation)
+	}
+}
+
+func TestRobustScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		baseline []float64
+		median   float64
+		score    float64
+	}{
+		{"no baseline", 100, nil, 0, 0},
+		{"at the median", 50, []float64{40, 50, 60}, 50, 0},
+		{"scored against the MAD", 80, []float64{40, 50, 60}, 50, 30 / (10 * madScale)},
+		{"outliers in the baseline", 80, []float64{50, 50, 60, 40, 5000}, 50, 30 / (10 * madScale)},
+		{"constant baseline", 51, []float64{50, 50, 50}, 50, anomalyMaxScore},
+		{"constant baseline below", 0, []float64{50, 50, 50}, 50, -anomalyMaxScore},
+		{"mostly constant baseline", 70, []float64{50, 50, 50, 90}, 50, 20 / (10 * meanADScale)},
+		{"capped", 1e6, []float64{40, 50, 60}, 50, anomalyMaxScore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			median, _, score := robustScore(tt.value, tt.baseline)
+			if median != tt.median || math.Abs(score-tt.score) > 1e-9 {
+				t.Errorf("robustScore(%v, %v) = median %f score %f, want %f and %f", tt.value, tt.baseline, median, score, tt.median, tt.score)
+			}
+		})
+	}
+}
+
+func TestInsightMessage(t *testing.T) {
+	today := time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		insight types.Insight
+		want    string
+	}{
+		{
+			name:    "app multiple",
+			insight: types.Insight{Date: today, Kind: types.InsightAppUsage, AppName: "Discord", Value: 5400, Baseline: 1800, Score: 10},
+			want:    "You spent 3x your usual time in Discord today",
+		},
+		{
+			name:    "daily total fraction",
+			insight: types.Insight{Date: today.AddDate(0, 0, -1), Kind: types.InsightDailyTotal, Value: 16200, Baseline: 9000, Score: 5},
+			want:    "You spent 1.8x your usual time on screen yesterday",
+		},
+		{
+			name:    "small increase",
+			insight: types.Insight{Date: today, Kind: types.InsightDailyTotal, Value: 10800, Baseline: 9000, Score: 4},
+			want:    "You spent 30m more time on screen than usual today",
+		},
+		{
+			name:    "no baseline",
+			insight: types.Insight{Date: today, Kind: types.InsightAppUsage, AppName: "Steam", Value: 2700, Score: 10},
+			want:    "You spent 45m in Steam today, more than you usually do",
+		},
+		{
+			name:    "decrease",
+			insight: types.Insight{Date: time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC), Kind: types.InsightAppUsage, AppName: "Slack", Value: 900, Baseline: 3600, Score: -6},
+			want:    "You spent 75% less time in Slack than usual on Fri, Jun 28",
+		},
+		{
+			name:    "unused",
+			insight: types.Insight{Date: today, Kind: types.InsightAppUsage, AppName: "Slack", Value: 0, Baseline: 3600, Score: -10},
+			want:    "You spent 1h 00m less time in Slack than usual today",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := insightMessage(tt.insight, today); got != tt.want {
+				t.Errorf("insightMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
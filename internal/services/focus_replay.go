@@ -0,0 +1,148 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"qwin/internal/types"
+)
+
+// replayedDay holds usage reconstructed from the focus event log for a single day
+type replayedDay struct {
+	date      time.Time
+	totalTime float64
+	apps      map[string]float64 // seconds per app key
+}
+
+// focusReplayState is the tracker state implied by the events replayed so far
+type focusReplayState struct {
+	active     bool
+	currentApp string
+	exePath    string
+}
+
+// replayFocusEvents walks events in chronological order and attributes each interval
+// between consecutive events to the application that had focus during it.
+//
+// Only the portion of each interval after countFrom is attributed, so events before
+// countFrom are used purely to establish state. Nothing is attributed after the last
+// event of a session, which keeps replay conservative when the tracker crashed.
+// Intervals that cross a day boundary are split between the affected days.
+func replayFocusEvents(events []types.FocusEvent, countFrom time.Time, boundary types.DayBoundary) (map[string]*replayedDay, focusReplayState) {
+	days := make(map[string]*replayedDay)
+	state := walkFocusIntervals(events, countFrom, func(start, end time.Time, state focusReplayState) {
+		attributeInterval(days, start, end, state, boundary)
+	})
+	return days, state
+}
+
+// walkFocusIntervals calls visit for every tracked interval after countFrom with the state
+// that was in effect during it, and returns the state after the last event
+func walkFocusIntervals(events []types.FocusEvent, countFrom time.Time, visit func(start, end time.Time, state focusReplayState)) focusReplayState {
+	var state focusReplayState
+
+	for i, event := range events {
+		// A new session means the previous one ended, possibly by crashing, at its last event
+		if i > 0 && state.active && event.Type != types.FocusEventTrackingStarted {
+			start := events[i-1].OccurredAt
+			end := event.OccurredAt
+			if start.Before(countFrom) {
+				start = countFrom
+			}
+			if end.After(start) {
+				visit(start, end, state)
+			}
+		}
+
+		state = applyFocusEvent(state, event)
+	}
+
+	return state
+}
+
+// applyFocusEvent returns the tracker state after the given event
+func applyFocusEvent(state focusReplayState, event types.FocusEvent) focusReplayState {
+	switch event.Type {
+	case types.FocusEventTrackingStarted:
+		return focusReplayState{active: true}
+	case types.FocusEventTrackingStopped, types.FocusEventPaused, types.FocusEventAppIgnored:
+		// Paused and ignored intervals count neither toward the session nor any app
+		return focusReplayState{}
+	case types.FocusEventResumed:
+		return focusReplayState{active: true}
+	case types.FocusEventAppSwitched, types.FocusEventHeartbeat:
+		// Heartbeats snapshot the current app, so they also re-establish state
+		// when the replay window starts in the middle of a session
+		return focusReplayState{active: true, currentApp: event.AppName, exePath: event.ExePath}
+	case types.FocusEventAppHidden:
+		// Hidden apps keep the session going without attributing to any app
+		return focusReplayState{active: true}
+	case types.FocusEventIdleStarted, types.FocusEventLocked:
+		return focusReplayState{active: state.active}
+	default:
+		// Day rollovers and checkpoints are markers and don't change attribution
+		return state
+	}
+}
+
+// attributeInterval adds [start, end) to the session total and to the current app, split by day
+func attributeInterval(days map[string]*replayedDay, start, end time.Time, state focusReplayState, boundary types.DayBoundary) {
+	for start.Before(end) {
+		date := boundary.DateOf(start)
+		dayEnd := boundary.End(date)
+
+		segmentEnd := end
+		if segmentEnd.After(dayEnd) && dayEnd.After(start) {
+			segmentEnd = dayEnd
+		}
+
+		dateKey := date.Format("2006-01-02")
+		day, exists := days[dateKey]
+		if !exists {
+			day = &replayedDay{date: date, apps: make(map[string]float64)}
+			days[dateKey] = day
+		}
+
+		seconds := segmentEnd.Sub(start).Seconds()
+		day.totalTime += seconds
+		if state.currentApp != "" {
+			day.apps[appKey(state.currentApp, state.exePath)] += seconds
+		}
+
+		start = segmentEnd
+	}
+}
+
+// usageData converts the replayed day into the shape stored by the repository
+func (d *replayedDay) usageData() *types.UsageData {
+	apps := make([]types.AppUsage, 0, len(d.apps))
+	for key, seconds := range d.apps {
+		duration := int64(math.Round(seconds))
+		if duration <= 0 {
+			continue
+		}
+		name, exePath := splitAppKey(key)
+		apps = append(apps, types.AppUsage{
+			Name:     name,
+			Duration: duration,
+			ExePath:  exePath,
+			Date:     d.date,
+		})
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Duration != apps[j].Duration {
+			return apps[i].Duration > apps[j].Duration
+		}
+		if apps[i].Name != apps[j].Name {
+			return apps[i].Name < apps[j].Name
+		}
+		return apps[i].ExePath < apps[j].ExePath
+	})
+
+	return &types.UsageData{
+		TotalTime: int64(math.Round(d.totalTime)),
+		Apps:      apps,
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"qwin/internal/types"
+)
+
+// utcMidnight returns a day boundary that starts days at midnight UTC
+func utcMidnight(t *testing.T) types.DayBoundary {
+	t.Helper()
+	boundary, err := types.NewDayBoundary(0, "UTC")
+	if err != nil {
+		t.Fatalf("NewDayBoundary failed: %v", err)
+	}
+	return boundary
+}
+
+func TestReplayFocusEvents(t *testing.T) {
+	base := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return base.Add(time.Duration(seconds) * time.Second) }
+
+	tests := []struct {
+		name      string
+		events    []types.FocusEvent
+		countFrom time.Time
+		wantTotal int64
+		wantApps  map[string]int64
+	}{
+		{
+			name: "app switches attribute intervals to the focused app",
+			events: []types.FocusEvent{
+				{Type: types.FocusEventTrackingStarted, OccurredAt: at(0)},
+				{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(0)},
+				{Type: types.FocusEventAppSwitched, AppName: "VSCode", OccurredAt: at(60)},
+				{Type: types.FocusEventTrackingStopped, OccurredAt: at(90)},
+			},
+			wantTotal: 90,
+			wantApps:  map[string]int64{"Chrome": 60, "VSCode": 30},
+		},
+		{
+			name: "idle time counts toward the session but not the app",
+			events: []types.FocusEvent{
+				{Type: types.FocusEventTrackingStarted, OccurredAt: at(0)},
+				{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(0)},
+				{Type: types.FocusEventIdleStarted, OccurredAt: at(30)},
+				{Type: types.FocusEventHeartbeat, OccurredAt: at(40)},
+				{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(100)},
+				{Type: types.FocusEventTrackingStopped, OccurredAt: at(110)},
+			},
+			wantTotal: 110,
+			wantApps:  map[string]int64{"Chrome": 40},
+		},
+		{
+			name: "crash gap before a new session is not attributed",
+			events: []types.FocusEvent{
+				{Type: types.FocusEventTrackingStarted, OccurredAt: at(0)},
+				{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(0)},
+				{Type: types.FocusEventHeartbeat, AppName: "Chrome", OccurredAt: at(10)},
+				{Type: types.FocusEventTrackingStarted, OccurredAt: at(3600)},
+				{Type: types.FocusEventAppSwitched, AppName: "Slack", OccurredAt: at(3600)},
+				{Type: types.FocusEventHeartbeat, AppName: "Slack", OccurredAt: at(3620)},
+			},
+			wantTotal: 30,
+			wantApps:  map[string]int64{"Chrome": 10, "Slack": 20},
+		},
+		{
+			name: "events before countFrom only establish state",
+			events: []types.FocusEvent{
+				{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(0)},
+				{Type: types.FocusEventCheckpoint, OccurredAt: at(50)},
+				{Type: types.FocusEventAppSwitched, AppName: "VSCode", OccurredAt: at(70)},
+				{Type: types.FocusEventHeartbeat, AppName: "VSCode", OccurredAt: at(80)},
+			},
+			countFrom: at(50),
+			wantTotal: 30,
+			wantApps:  map[string]int64{"Chrome": 20, "VSCode": 10},
+		},
+		{
+			name: "heartbeat re-establishes state when the window starts mid-session",
+			events: []types.FocusEvent{
+				{Type: types.FocusEventHeartbeat, AppName: "Terminal", OccurredAt: at(0)},
+				{Type: types.FocusEventHeartbeat, AppName: "Terminal", OccurredAt: at(10)},
+			},
+			wantTotal: 10,
+			wantApps:  map[string]int64{"Terminal": 10},
+		},
+		{
+			name: "apps sharing a name are kept apart by executable path",
+			events: []types.FocusEvent{
+				{Type: types.FocusEventTrackingStarted, OccurredAt: at(0)},
+				{Type: types.FocusEventAppSwitched, AppName: "setup", ExePath: `C:\a\setup.exe`, OccurredAt: at(0)},
+				{Type: types.FocusEventAppSwitched, AppName: "setup", ExePath: `C:\b\setup.exe`, OccurredAt: at(40)},
+				{Type: types.FocusEventHeartbeat, AppName: "setup", ExePath: `C:\b\setup.exe`, OccurredAt: at(50)},
+				{Type: types.FocusEventTrackingStopped, OccurredAt: at(60)},
+			},
+			wantTotal: 60,
+			wantApps:  map[string]int64{appKey("setup", `C:\a\setup.exe`): 40, appKey("setup", `C:\b\setup.exe`): 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, _ := replayFocusEvents(tt.events, tt.countFrom, utcMidnight(t))
+
+			day, exists := days[base.Format("2006-01-02")]
+			if !exists {
+				t.Fatalf("expected replay to produce usage for %s", base.Format("2006-01-02"))
+			}
+
+			usage := day.usageData()
+			if usage.TotalTime != tt.wantTotal {
+				t.Errorf("TotalTime = %d, want %d", usage.TotalTime, tt.wantTotal)
+			}
+
+			if len(usage.Apps) != len(tt.wantApps) {
+				t.Fatalf("got %d apps, want %d: %+v", len(usage.Apps), len(tt.wantApps), usage.Apps)
+			}
+			for _, app := range usage.Apps {
+				if want := tt.wantApps[appKey(app.Name, app.ExePath)]; app.Duration != want {
+					t.Errorf("%s duration = %d, want %d", app.Name, app.Duration, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayFocusEvents_SplitsAtMidnight(t *testing.T) {
+	beforeMidnight := time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)
+	events := []types.FocusEvent{
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: beforeMidnight},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: beforeMidnight.Add(3 * time.Minute)},
+	}
+
+	days, state := replayFocusEvents(events, time.Time{}, utcMidnight(t))
+
+	if state.active {
+		t.Error("expected replay to end in an inactive state after tracking_stopped")
+	}
+
+	first := days["2024-03-10"]
+	second := days["2024-03-11"]
+	if first == nil || second == nil {
+		t.Fatalf("expected usage on both days, got %v", days)
+	}
+
+	if got := first.usageData().Apps[0].Duration; got != 60 {
+		t.Errorf("first day Chrome = %d, want 60", got)
+	}
+	if got := second.usageData().Apps[0].Duration; got != 120 {
+		t.Errorf("second day Chrome = %d, want 120", got)
+	}
+}
+
+func TestReplayFocusEvents_UsesConfiguredDayStart(t *testing.T) {
+	boundary, err := types.NewDayBoundary(4, "UTC")
+	if err != nil {
+		t.Fatalf("NewDayBoundary failed: %v", err)
+	}
+
+	// A late session from 02:00 to 05:00 belongs to the previous day until 04:00
+	start := time.Date(2024, 3, 11, 2, 0, 0, 0, time.UTC)
+	events := []types.FocusEvent{
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: start},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: start.Add(3 * time.Hour)},
+	}
+
+	days, _ := replayFocusEvents(events, time.Time{}, boundary)
+
+	first := days["2024-03-10"]
+	second := days["2024-03-11"]
+	if first == nil || second == nil {
+		t.Fatalf("expected usage on both days, got %v", days)
+	}
+
+	if got := first.usageData().TotalTime; got != 2*3600 {
+		t.Errorf("first day total = %d, want %d", got, 2*3600)
+	}
+	if got := second.usageData().TotalTime; got != 3600 {
+		t.Errorf("second day total = %d, want %d", got, 3600)
+	}
+	if !first.date.Equal(types.DateKey(first.date)) {
+		t.Errorf("replayed day date %v is not a date key", first.date)
+	}
+}
+
+func TestReplayFocusEvents_SkipsPausedIntervals(t *testing.T) {
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	events := []types.FocusEvent{
+		{Type: types.FocusEventTrackingStarted, OccurredAt: at(0)},
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(0)},
+		{Type: types.FocusEventPaused, OccurredAt: at(60)},
+		{Type: types.FocusEventResumed, OccurredAt: at(600)},
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(601)},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: at(661)},
+	}
+
+	days, _ := replayFocusEvents(events, time.Time{}, utcMidnight(t))
+
+	usage := days["2024-03-10"].usageData()
+	if usage.TotalTime != 121 {
+		t.Errorf("TotalTime = %d, want 121 without the paused interval", usage.TotalTime)
+	}
+	if got := usage.Apps[0].Duration; got != 120 {
+		t.Errorf("Chrome = %d, want 120", got)
+	}
+}
+
+func TestReplayFocusEvents_IgnoredAndHiddenApps(t *testing.T) {
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	events := []types.FocusEvent{
+		{Type: types.FocusEventTrackingStarted, OccurredAt: at(0)},
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(0)},
+		{Type: types.FocusEventAppIgnored, OccurredAt: at(60)},
+		{Type: types.FocusEventAppHidden, OccurredAt: at(300)},
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: at(330)},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: at(390)},
+	}
+
+	days, _ := replayFocusEvents(events, time.Time{}, utcMidnight(t))
+
+	usage := days["2024-03-10"].usageData()
+	if usage.TotalTime != 150 {
+		t.Errorf("TotalTime = %d, want 150 with hidden but without ignored time", usage.TotalTime)
+	}
+	if len(usage.Apps) != 1 || usage.Apps[0].Duration != 120 {
+		t.Errorf("apps = %+v, want only Chrome with 120s", usage.Apps)
+	}
+}
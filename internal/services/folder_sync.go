@@ -0,0 +1,608 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// folderSyncInterval is how often the sync folder is read and written while running
+	folderSyncInterval = 5 * time.Minute
+	// folderSyncTimeout bounds a single periodic sync run
+	folderSyncTimeout = 2 * time.Minute
+
+	// syncLogExt is the extension of change log files; the file name is the device ID
+	syncLogExt = ".jsonl"
+	// syncSnapshotExt is the extension of the snapshot a device compacted its log into
+	syncSnapshotExt = ".snapshot"
+	// maxSyncLogLine bounds the length of one change log entry
+	maxSyncLogLine = 64 * 1024
+)
+
+// compactSyncLogSize is the size past which a device replaces its log with a snapshot, so
+// devices joining later and full rereads don't read every change ever made
+var compactSyncLogSize int64 = 1 << 20
+
+// logPosition is how far a device's change log has been read and applied
+type logPosition struct {
+	offset int64 // length of the complete entries read
+	seq    int64 // last entry before offset
+}
+
+// FolderSync combines usage across devices through a folder they all share, such as one
+// kept in sync by Syncthing or Dropbox or mounted over NFS. Each device appends its usage
+// changes to its own change log in the folder and merges every other device's log into
+// the database. As no file has more than one writer, the sync tool never has to resolve
+// a conflict, and merging gives the same result on every device in any order.
+type FolderSync struct {
+	repo   repository.DeviceSyncRepository
+	dir    string
+	device types.SyncDevice
+	logger logging.Logger
+
+	runMutex  sync.Mutex             // serializes sync runs
+	positions map[string]logPosition // per device ID, guarded by runMutex
+
+	mutex   sync.Mutex
+	stopCh  chan struct{}
+	running bool
+}
+
+// NewFolderSync creates a sync of the local device's usage through dir.
+// The repository must support device sync.
+func NewFolderSync(repo repository.UsageRepository, dir string, device types.SyncDevice, logger logging.Logger) (*FolderSync, error) {
+	devices, ok := repo.(repository.DeviceSyncRepository)
+	if !ok {
+		return nil, errors.NewRepositoryError("NewFolderSync",
+			fmt.Errorf("repository does not support device sync"), errors.ErrCodeValidation)
+	}
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.NewRepositoryError("NewFolderSync", fmt.Errorf("sync folder is empty"), errors.ErrCodeValidation)
+	}
+	if err := types.ValidateDeviceID(device.ID); err != nil {
+		return nil, errors.NewRepositoryError("NewFolderSync", err, errors.ErrCodeValidation)
+	}
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+
+	device.Local = true
+	return &FolderSync{
+		repo:      devices,
+		dir:       dir,
+		device:    device,
+		logger:    logger,
+		positions: make(map[string]logPosition),
+	}, nil
+}
+
+// Dir returns the sync folder
+func (fs *FolderSync) Dir() string {
+	return fs.dir
+}
+
+// Start syncs right away and then periodically until Stop
+func (fs *FolderSync) Start() {
+	fs.mutex.Lock()
+	if fs.running {
+		fs.mutex.Unlock()
+		return
+	}
+	fs.running = true
+	fs.stopCh = make(chan struct{})
+	stopCh := fs.stopCh
+	fs.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(folderSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), folderSyncTimeout)
+			if _, err := fs.Sync(ctx); err != nil {
+				fs.logger.Error("Failed to sync usage through folder", "dir", fs.dir, "error", err)
+			}
+			cancel()
+
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends periodic syncing
+func (fs *FolderSync) Stop() {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if !fs.running {
+		return
+	}
+	fs.running = false
+	close(fs.stopCh)
+}
+
+// Sync merges the change logs of other devices in the folder and appends local usage
+// changes to this device's log. Logs that can't be read fully are reported in the
+// result; the entries before the problem are still merged. Each log is read on from
+// where the previous run stopped, and this device's log is compacted into a snapshot
+// once it grows past compactSyncLogSize.
+func (fs *FolderSync) Sync(ctx context.Context) (*types.SyncResult, error) {
+	fs.runMutex.Lock()
+	defer fs.runMutex.Unlock()
+	start := time.Now()
+
+	if err := os.MkdirAll(fs.dir, 0o755); err != nil {
+		return nil, errors.NewRepositoryErrorWithContext("FolderSync", err, errors.ClassifyError(err), map[string]string{
+			"dir": fs.dir,
+		})
+	}
+
+	devices, err := fs.repo.ListSyncDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lastSeqs := make(map[string]int64, len(devices))
+	for _, device := range devices {
+		lastSeqs[device.ID] = device.LastSeq
+	}
+
+	result := &types.SyncResult{}
+
+	// This device's own log first: after a restore from backup it may hold entries the
+	// database doesn't know it wrote
+	ownLog := fs.logPath(fs.device.ID)
+	ownBehind, err := fs.mergeOwnLog(ctx, ownLog, lastSeqs[fs.device.ID], result)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(fs.dir, "*"+syncLogExt))
+	if err != nil {
+		return nil, errors.NewRepositoryError("FolderSync", err, errors.ErrCodeValidation)
+	}
+	for _, path := range paths {
+		deviceID := strings.TrimSuffix(filepath.Base(path), syncLogExt)
+		// Copies made by sync tools, like "id.sync-conflict-....jsonl", aren't valid IDs
+		if deviceID == fs.device.ID || types.ValidateDeviceID(deviceID) != nil {
+			continue
+		}
+		if err := fs.mergeLog(ctx, path, types.SyncDevice{ID: deviceID}, lastSeqs[deviceID], result); err != nil {
+			return nil, err
+		}
+	}
+
+	if ownBehind {
+		result.Errors = append(result.Errors, fmt.Sprintf(
+			"%s has fewer entries than this device wrote; local changes are not exported", ownLog))
+	} else {
+		if err := fs.export(ctx, ownLog, result); err != nil {
+			return nil, err
+		}
+		if err := fs.compact(ctx, ownLog); err != nil {
+			return nil, err
+		}
+	}
+
+	logging.LogOperation(fs.logger, "FolderSync", time.Since(start), map[string]interface{}{
+		"dir":      fs.dir,
+		"devices":  result.Devices,
+		"imported": result.Imported,
+		"exported": result.Exported,
+		"errors":   len(result.Errors),
+	})
+	return result, nil
+}
+
+// mergeOwnLog applies this device's own snapshot and log and trims an entry left half
+// written by an interrupted append. Reports whether the log is missing entries the
+// database says were written, up to lastSeq, in which case appending to it would leave
+// a gap.
+func (fs *FolderSync) mergeOwnLog(ctx context.Context, path string, lastSeq int64, result *types.SyncResult) (bool, error) {
+	base, err := fs.mergeSnapshot(ctx, fs.device, lastSeq, result)
+	if err != nil {
+		return false, err
+	}
+
+	entries, pos, readErr := fs.readLog(fs.device.ID, path, base, lastSeq)
+	if readErr != nil && !os.IsNotExist(readErr) {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, readErr))
+		delete(fs.positions, fs.device.ID)
+		return true, nil
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > pos.offset {
+		if err := os.Truncate(path, pos.offset); err != nil {
+			return false, errors.NewRepositoryErrorWithContext("FolderSync", err, errors.ClassifyError(err), map[string]string{
+				"path": path,
+			})
+		}
+	}
+
+	if _, err := fs.repo.ApplySyncEntries(ctx, fs.device, entries); err != nil {
+		delete(fs.positions, fs.device.ID)
+		if errors.IsConstraint(err) || errors.IsValidation(err) {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+			return true, nil
+		}
+		return false, err
+	}
+	fs.positions[fs.device.ID] = pos
+	return pos.seq < lastSeq, nil
+}
+
+// mergeLog applies another device's snapshot and log, given the last entry applied from
+// it. Problems with them are reported in result; only database failures are returned.
+func (fs *FolderSync) mergeLog(ctx context.Context, path string, device types.SyncDevice, lastSeq int64, result *types.SyncResult) error {
+	base, err := fs.mergeSnapshot(ctx, device, lastSeq, result)
+	if err != nil {
+		return err
+	}
+
+	entries, pos, readErr := fs.readLog(device.ID, path, base, lastSeq)
+	if readErr != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, readErr))
+	}
+
+	applied, err := fs.repo.ApplySyncEntries(ctx, device, entries)
+	result.Imported += applied
+	result.Devices++
+	if err != nil {
+		delete(fs.positions, device.ID)
+		if errors.IsConstraint(err) || errors.IsValidation(err) {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+			return nil
+		}
+		return err
+	}
+	if readErr != nil {
+		delete(fs.positions, device.ID)
+	} else {
+		fs.positions[device.ID] = pos
+	}
+	return nil
+}
+
+// mergeSnapshot applies a device's snapshot when it covers entries after lastSeq, and
+// returns the last entry it covers, which its log continues from. Problems with the
+// snapshot are reported in result; only database failures are returned.
+func (fs *FolderSync) mergeSnapshot(ctx context.Context, device types.SyncDevice, lastSeq int64, result *types.SyncResult) (int64, error) {
+	path := fs.snapshotPath(device.ID)
+	seq, err := readSyncSnapshotSeq(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+		}
+		return 0, nil
+	}
+	if seq <= lastSeq {
+		return seq, nil
+	}
+
+	snapshot, err := readSyncSnapshot(path)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+		return 0, nil
+	}
+	if _, err := fs.repo.ApplySyncSnapshot(ctx, device, snapshot); err != nil {
+		if errors.IsConstraint(err) || errors.IsValidation(err) {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+			return 0, nil
+		}
+		return 0, err
+	}
+	delete(fs.positions, device.ID)
+	return snapshot.Seq, nil
+}
+
+// readLog reads a device's log on from where it was last read up to, or from the start
+// when it was rewritten since, lastSeq went back, or it hasn't been read yet. A log read
+// from the start continues after the entry base.
+func (fs *FolderSync) readLog(deviceID, path string, base, lastSeq int64) ([]types.SyncLogEntry, logPosition, error) {
+	if pos, ok := fs.positions[deviceID]; ok && pos.offset > 0 && pos.seq <= lastSeq {
+		entries, complete, err := readSyncLogFrom(path, pos.offset)
+		if err == nil && (len(entries) == 0 || entries[0].Seq == pos.seq+1) {
+			return entries, logPositionAfter(entries, complete, pos.seq), nil
+		}
+		fs.logger.Debug("Rereading change log from the start", "path", path, "error", err)
+	}
+
+	entries, complete, err := readSyncLogFrom(path, 0)
+	return entries, logPositionAfter(entries, complete, base), err
+}
+
+// logPositionAfter returns the position after reading entries up to complete, where seq
+// is the entry before them
+func logPositionAfter(entries []types.SyncLogEntry, complete, seq int64) logPosition {
+	if len(entries) > 0 {
+		seq = entries[len(entries)-1].Seq
+	}
+	return logPosition{offset: complete, seq: seq}
+}
+
+// export appends local changes not written yet to this device's log, then records them
+// as written. A run interrupted in between is completed by the next one merging the log.
+func (fs *FolderSync) export(ctx context.Context, path string, result *types.SyncResult) error {
+	pending, err := fs.repo.PendingSyncEntries(ctx, fs.device)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	if err := appendSyncLog(path, pending); err != nil {
+		return errors.NewRepositoryErrorWithContext("FolderSync", err, errors.ClassifyError(err), map[string]string{
+			"path": path,
+		})
+	}
+	if _, err := fs.repo.ApplySyncEntries(ctx, fs.device, pending); err != nil {
+		return err
+	}
+	result.Exported = len(pending)
+	return nil
+}
+
+// compact replaces this device's log with a snapshot of everything it has written once
+// the log is larger than compactSyncLogSize. The snapshot is in place before the log is
+// emptied, so a device reading both at any point sees every entry in one or the other.
+func (fs *FolderSync) compact(ctx context.Context, path string) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < compactSyncLogSize {
+		return nil
+	}
+
+	snapshot, err := fs.repo.SyncSnapshot(ctx, fs.device)
+	if err != nil {
+		return err
+	}
+	entries, _, err := readSyncLogFrom(path, 0)
+	if err != nil {
+		// mergeOwnLog just read the log; leave it be until it reads fine again
+		fs.logger.Warn("Not compacting unreadable change log", "path", path, "error", err)
+		return nil
+	}
+	if len(entries) > 0 && entries[len(entries)-1].Seq < snapshot.Seq {
+		return nil
+	}
+
+	// Entries after the snapshot, if any, stay in the log
+	var rest []types.SyncLogEntry
+	for _, entry := range entries {
+		if entry.Seq > snapshot.Seq {
+			rest = append(rest, entry)
+		}
+	}
+
+	snapshotPath := fs.snapshotPath(fs.device.ID)
+	if err := writeSyncSnapshot(snapshotPath, snapshot); err != nil {
+		return errors.NewRepositoryErrorWithContext("FolderSync", err, errors.ClassifyError(err), map[string]string{
+			"path": snapshotPath,
+		})
+	}
+	if err := rewriteSyncLog(path, rest); err != nil {
+		return errors.NewRepositoryErrorWithContext("FolderSync", err, errors.ClassifyError(err), map[string]string{
+			"path": path,
+		})
+	}
+	delete(fs.positions, fs.device.ID)
+
+	fs.logger.Info("Compacted change log into a snapshot", "path", path, "seq", snapshot.Seq,
+		"entries", len(entries)-len(rest), "usage", len(snapshot.Usage))
+	return nil
+}
+
+// logPath returns the change log file of a device
+func (fs *FolderSync) logPath(deviceID string) string {
+	return filepath.Join(fs.dir, deviceID+syncLogExt)
+}
+
+// snapshotPath returns the file a device compacts its change log into
+func (fs *FolderSync) snapshotPath(deviceID string) string {
+	return filepath.Join(fs.dir, deviceID+syncSnapshotExt)
+}
+
+// readSyncLog reads the entries of a change log, one JSON object per line, and the
+// length of the complete lines read. A last line without a newline is still being
+// written, or was cut off, and is left for later; a malformed complete line stops
+// reading with an error.
+func readSyncLog(path string) ([]types.SyncLogEntry, int64, error) {
+	return readSyncLogFrom(path, 0)
+}
+
+// readSyncLogFrom reads a change log like readSyncLog, starting at offset, which must be
+// the end of a complete line. The length returned includes the offset.
+func readSyncLogFrom(path string, offset int64) ([]types.SyncLogEntry, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	if offset > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, 0, err
+		}
+		if info.Size() < offset {
+			return nil, 0, fmt.Errorf("change log is shorter than the %d bytes read before", offset)
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var entries []types.SyncLogEntry
+	complete := offset
+	reader := bufio.NewReaderSize(file, maxSyncLogLine)
+	for {
+		line, err := reader.ReadSlice('\n')
+		if err == io.EOF {
+			return entries, complete, nil
+		}
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				err = fmt.Errorf("entry after %d bytes is longer than %d bytes", complete, maxSyncLogLine)
+			}
+			return entries, complete, err
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			var entry types.SyncLogEntry
+			if err := json.Unmarshal(trimmed, &entry); err != nil {
+				return entries, complete, fmt.Errorf("malformed entry after %d bytes: %w", complete, err)
+			}
+			entries = append(entries, entry)
+		}
+		complete += int64(len(line))
+	}
+}
+
+// appendSyncLog appends entries to a change log and flushes it to disk
+func appendSyncLog(path string, entries []types.SyncLogEntry) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// rewriteSyncLog replaces a change log with entries, through a temporary file so the
+// log is never seen half written
+func rewriteSyncLog(path string, entries []types.SyncLogEntry) error {
+	temp := path + ".tmp"
+	os.Remove(temp)
+	file, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		if err := appendSyncLog(temp, entries); err != nil {
+			os.Remove(temp)
+			return err
+		}
+	}
+	return os.Rename(temp, path)
+}
+
+// writeSyncSnapshot replaces a snapshot file: a first line with the device and the last
+// entry covered, then one line per usage. The first line alone tells whether the
+// snapshot is needed, so devices that are up to date don't read the rest.
+func writeSyncSnapshot(path string, snapshot *types.SyncSnapshot) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	header := *snapshot
+	header.Usage = nil
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+	for _, usage := range snapshot.Usage {
+		if err := encoder.Encode(usage); err != nil {
+			return err
+		}
+	}
+
+	temp := path + ".tmp"
+	file, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		os.Remove(temp)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(temp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// readSyncSnapshotSeq reads the last entry a snapshot covers from its first line
+func readSyncSnapshotSeq(path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReaderSize(file, maxSyncLogLine).ReadSlice('\n')
+	if err != nil {
+		return 0, fmt.Errorf("snapshot has no complete first line: %w", err)
+	}
+	var header types.SyncSnapshot
+	if err := json.Unmarshal(line, &header); err != nil {
+		return 0, fmt.Errorf("malformed snapshot: %w", err)
+	}
+	return header.Seq, nil
+}
+
+// readSyncSnapshot reads a whole snapshot file
+func readSyncSnapshot(path string) (*types.SyncSnapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, maxSyncLogLine)
+	var snapshot types.SyncSnapshot
+	for first := true; ; first = false {
+		line, err := reader.ReadSlice('\n')
+		if err == io.EOF && len(line) == 0 && !first {
+			return &snapshot, nil
+		}
+		if err != nil {
+			// Snapshots are renamed into place whole, so a missing newline means damage
+			return nil, fmt.Errorf("snapshot is cut off: %w", err)
+		}
+		if first {
+			err = json.Unmarshal(line, &snapshot)
+		} else {
+			var usage types.SyncUsage
+			if err = json.Unmarshal(line, &usage); err == nil {
+				snapshot.Usage = append(snapshot.Usage, usage)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed snapshot: %w", err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwin/internal/types"
+)
+
+func TestFolderSync_MergesDevices(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	date := types.DateKey(time.Now())
+
+	desktopRepo, laptopRepo := setupSQLiteRepository(t), setupSQLiteRepository(t)
+	desktop, err := NewFolderSync(desktopRepo, dir, types.SyncDevice{ID: "desktop", Name: "Desktop"}, nil)
+	if err != nil {
+		t.Fatalf("NewFolderSync() error = %v", err)
+	}
+	laptop, err := NewFolderSync(laptopRepo, dir, types.SyncDevice{ID: "laptop", Name: "Laptop"}, nil)
+	if err != nil {
+		t.Fatalf("NewFolderSync() error = %v", err)
+	}
+
+	if err := desktopRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 600}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if err := desktopRepo.IncrementDailyUsage(ctx, date, 600); err != nil {
+		t.Fatalf("IncrementDailyUsage() error = %v", err)
+	}
+	if err := laptopRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 300}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if err := laptopRepo.IncrementDailyUsage(ctx, date, 300); err != nil {
+		t.Fatalf("IncrementDailyUsage() error = %v", err)
+	}
+
+	// The desktop's first run only exports; the laptop then has both logs to read
+	result, err := desktop.Sync(ctx)
+	if err != nil || result.Exported != 2 || result.Imported != 0 {
+		t.Fatalf("desktop Sync() = (%+v, %v), want 2 exported", result, err)
+	}
+	result, err = laptop.Sync(ctx)
+	if err != nil || result.Exported != 2 || result.Imported != 2 {
+		t.Fatalf("laptop Sync() = (%+v, %v), want 2 exported and 2 imported", result, err)
+	}
+	result, err = desktop.Sync(ctx)
+	if err != nil || result.Exported != 0 || result.Imported != 2 {
+		t.Fatalf("desktop Sync() again = (%+v, %v), want 2 imported", result, err)
+	}
+
+	// Syncing again changes nothing
+	result, err = laptop.Sync(ctx)
+	if err != nil || result.Exported != 0 || result.Imported != 0 || len(result.Errors) != 0 {
+		t.Fatalf("laptop Sync() again = (%+v, %v), want nothing to do", result, err)
+	}
+
+	for name, repo := range map[string]interface {
+		GetUsageHistoryForDevice(context.Context, int, string) (map[string]*types.UsageData, error)
+	}{"desktop": desktopRepo, "laptop": laptopRepo} {
+		history, err := repo.GetUsageHistoryForDevice(ctx, 1, types.DeviceScopeAll)
+		if err != nil {
+			t.Fatalf("%s GetUsageHistoryForDevice() error = %v", name, err)
+		}
+		usage := history[date.Format("2006-01-02")]
+		if usage == nil || usage.TotalTime != 900 {
+			t.Errorf("%s combined usage = %+v, want 900 seconds in total", name, usage)
+		}
+	}
+
+	// Removing usage propagates as a negative change
+	if err := laptopRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 100}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if _, err := laptop.Sync(ctx); err != nil {
+		t.Fatalf("laptop Sync() error = %v", err)
+	}
+	if _, err := desktop.Sync(ctx); err != nil {
+		t.Fatalf("desktop Sync() error = %v", err)
+	}
+	apps, err := desktopRepo.GetAppUsageByDateRangeForDevice(ctx, date, date, "laptop")
+	if err != nil {
+		t.Fatalf("GetAppUsageByDateRangeForDevice() error = %v", err)
+	}
+	if len(apps) != 1 || apps[0].Duration != 100 {
+		t.Errorf("laptop usage on the desktop = %+v, want Code at 100 seconds", apps)
+	}
+}
+
+func TestFolderSync_ToleratesDamagedLogs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	date := types.DateKey(time.Now())
+	repo := setupSQLiteRepository(t)
+
+	phone := types.SyncDevice{ID: "phone", Name: "Phone"}
+	if err := appendSyncLog(filepath.Join(dir, "phone.jsonl"), []types.SyncLogEntry{
+		types.NewSyncLogEntry(phone, 1, types.SyncEntryApp, "Chat", date, 120),
+	}); err != nil {
+		t.Fatalf("appendSyncLog() error = %v", err)
+	}
+	// An entry still being written, and a copy left by a sync tool
+	file, err := os.OpenFile(filepath.Join(dir, "phone.jsonl"), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	file.WriteString(`{"seq":2,"device":"pho`)
+	file.Close()
+	if err := os.WriteFile(filepath.Join(dir, "phone.sync-conflict-1.jsonl"), []byte("not json\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	syncer, err := NewFolderSync(repo, dir, types.SyncDevice{ID: "desktop"}, nil)
+	if err != nil {
+		t.Fatalf("NewFolderSync() error = %v", err)
+	}
+	result, err := syncer.Sync(ctx)
+	if err != nil || result.Imported != 1 || result.Devices != 1 || len(result.Errors) != 0 {
+		t.Fatalf("Sync() = (%+v, %v), want the complete entry imported without errors", result, err)
+	}
+
+	// A malformed complete entry is reported, keeping what came before it
+	if err := os.WriteFile(filepath.Join(dir, "tablet.jsonl"), []byte("{broken\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	result, err = syncer.Sync(ctx)
+	if err != nil || len(result.Errors) != 1 {
+		t.Errorf("Sync() with a malformed log = (%+v, %v), want one error reported", result, err)
+	}
+
+	if _, err := NewFolderSync(repo, dir, types.SyncDevice{ID: "../escape"}, nil); err == nil {
+		t.Error("Expected NewFolderSync to reject a device ID that isn't a plain file name")
+	}
+}
+
+func TestFolderSync_ReadsOnAndCompacts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	date := types.DateKey(time.Now())
+
+	defer func(size int64) { compactSyncLogSize = size }(compactSyncLogSize)
+	compactSyncLogSize = 1
+
+	desktopRepo, laptopRepo := setupSQLiteRepository(t), setupSQLiteRepository(t)
+	desktop, err := NewFolderSync(desktopRepo, dir, types.SyncDevice{ID: "desktop", Name: "Desktop"}, nil)
+	if err != nil {
+		t.Fatalf("NewFolderSync() error = %v", err)
+	}
+	laptop, err := NewFolderSync(laptopRepo, dir, types.SyncDevice{ID: "laptop", Name: "Laptop"}, nil)
+	if err != nil {
+		t.Fatalf("NewFolderSync() error = %v", err)
+	}
+
+	if err := desktopRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 600}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if err := desktopRepo.IncrementDailyUsage(ctx, date, 600); err != nil {
+		t.Fatalf("IncrementDailyUsage() error = %v", err)
+	}
+
+	// The desktop's log is compacted into a snapshot right after it is written
+	if result, err := desktop.Sync(ctx); err != nil || result.Exported != 2 {
+		t.Fatalf("desktop Sync() = (%+v, %v), want 2 exported", result, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "desktop.jsonl")); err != nil || info.Size() != 0 {
+		t.Fatalf("desktop log after compacting = (%v, %v), want it empty", info, err)
+	}
+	if seq, err := readSyncSnapshotSeq(filepath.Join(dir, "desktop.snapshot")); err != nil || seq != 2 {
+		t.Fatalf("readSyncSnapshotSeq() = (%d, %v), want the snapshot to cover entry 2", seq, err)
+	}
+
+	// A device that never read the log takes the usage from the snapshot
+	compactSyncLogSize = 1 << 20
+	if result, err := laptop.Sync(ctx); err != nil || len(result.Errors) != 0 {
+		t.Fatalf("laptop Sync() = (%+v, %v), want no errors", result, err)
+	}
+	assertDeviceUsage(t, laptopRepo, date, "desktop", 600)
+
+	// Later entries continue after the snapshot, and the desktop isn't behind its own log
+	if err := desktopRepo.BatchIncrementAppUsageDurations(ctx, date, map[string]int64{"Code": 60}); err != nil {
+		t.Fatalf("BatchIncrementAppUsageDurations() error = %v", err)
+	}
+	restarted, err := NewFolderSync(desktopRepo, dir, types.SyncDevice{ID: "desktop", Name: "Desktop"}, nil)
+	if err != nil {
+		t.Fatalf("NewFolderSync() error = %v", err)
+	}
+	if result, err := restarted.Sync(ctx); err != nil || result.Exported != 1 || len(result.Errors) != 0 {
+		t.Fatalf("restarted desktop Sync() = (%+v, %v), want 1 exported without errors", result, err)
+	}
+	if result, err := laptop.Sync(ctx); err != nil || result.Imported != 1 || len(result.Errors) != 0 {
+		t.Fatalf("laptop Sync() = (%+v, %v), want 1 imported", result, err)
+	}
+	assertDeviceUsage(t, laptopRepo, date, "desktop", 660)
+
+	// Entries read before aren't read again: damage to them goes unnoticed
+	logPath := filepath.Join(dir, "desktop.jsonl")
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	content[0] = '#'
+	if err := os.WriteFile(logPath, content, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if result, err := laptop.Sync(ctx); err != nil || result.Imported != 0 || len(result.Errors) != 0 {
+		t.Errorf("laptop Sync() after the log was read = (%+v, %v), want nothing read", result, err)
+	}
+}
+
+// assertDeviceUsage checks the time of Code merged from a device on date
+func assertDeviceUsage(t *testing.T, repo interface {
+	GetAppUsageByDateRangeForDevice(context.Context, time.Time, time.Time, string) ([]types.AppUsage, error)
+}, date time.Time, device string, want int64) {
+	t.Helper()
+	apps, err := repo.GetAppUsageByDateRangeForDevice(context.Background(), date, date, device)
+	if err != nil {
+		t.Fatalf("GetAppUsageByDateRangeForDevice() error = %v", err)
+	}
+	if len(apps) != 1 || apps[0].Name != "Code" || apps[0].Duration != want {
+		t.Errorf("%s usage = %+v, want Code at %d seconds", device, apps, want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,8 +28,14 @@ type MockRepository struct {
 	shouldFailTx     bool
 	deleteCallCount  int
 	historyCallCount int
+	focusEvents      []types.FocusEvent
+	nextEventID      int64
+	metadataWrites   int // number of app metadata records written
 }
 
+// Ensure MockRepository implements the optional focus event log
+var _ repository.FocusEventRepository = (*MockRepository)(nil)
+
 // NewMockRepository creates a new mock repository for testing
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
@@ -75,6 +82,27 @@ func (m *MockRepository) SaveDailyUsage(ctx context.Context, date time.Time, usa
 	return nil
 }
 
+// IncrementDailyUsage implements UsageRepository interface
+func (m *MockRepository) IncrementDailyUsage(ctx context.Context, date time.Time, additionalTime int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.saveCallCount++
+
+	if m.shouldFailSave {
+		return errors.NewRepositoryError("IncrementDailyUsage", fmt.Errorf("mock save failure"), errors.ErrCodeConnection)
+	}
+
+	dateKey := date.Format("2006-01-02")
+	if usage, exists := m.dailyUsage[dateKey]; exists {
+		usage.TotalTime += additionalTime
+	} else {
+		m.dailyUsage[dateKey] = &types.UsageData{TotalTime: additionalTime}
+	}
+
+	return nil
+}
+
 // GetDailyUsage implements UsageRepository interface
 func (m *MockRepository) GetDailyUsage(ctx context.Context, date time.Time) (*types.UsageData, error) {
 	m.mu.Lock()
@@ -311,28 +339,101 @@ func (m *MockRepository) BatchProcessAppUsage(ctx context.Context, date time.Tim
 
 // BatchIncrementAppUsageDurations implements UsageRepository interface
 func (m *MockRepository) BatchIncrementAppUsageDurations(ctx context.Context, date time.Time, increments map[string]int64) error {
+	appUsages := make([]types.AppUsage, 0, len(increments))
+	for appName, additionalDuration := range increments {
+		appUsages = append(appUsages, types.AppUsage{Name: appName, Duration: additionalDuration})
+	}
+	return m.BatchIncrementAppUsage(ctx, date, appUsages)
+}
+
+// BatchIncrementAppUsage implements UsageRepository interface
+func (m *MockRepository) BatchIncrementAppUsage(ctx context.Context, date time.Time, appUsages []types.AppUsage) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.batchCallCount++
+
 	if m.shouldFailBatch {
-		return errors.NewRepositoryError("BatchIncrementAppUsageDurations", fmt.Errorf("mock batch failure"), errors.ErrCodeConnection)
+		return errors.NewRepositoryError("BatchIncrementAppUsage", fmt.Errorf("mock batch failure"), errors.ErrCodeConnection)
 	}
 
 	dateKey := date.Format("2006-01-02")
-	if m.appUsage[dateKey] == nil {
-		return nil
+
+	// Increment durations, inserting missing apps like the SQLite implementation does
+	for _, appUsage := range appUsages {
+		if i := m.appIndexLocked(dateKey, appUsage); i >= 0 {
+			m.appUsage[dateKey][i].Duration += appUsage.Duration
+			continue
+		}
+		m.appUsage[dateKey] = append(m.appUsage[dateKey], types.AppUsage{
+			Name:     appUsage.Name,
+			Duration: appUsage.Duration,
+			ExePath:  appUsage.ExePath,
+			Date:     date,
+		})
 	}
 
-	// Increment durations
+	return nil
+}
+
+// appIndexLocked finds the row an app's usage belongs to on a date, by executable path
+// first and by name otherwise, like the SQLite implementation resolves applications.
+// Returns -1 when there is none. Must be called with m.mu held.
+func (m *MockRepository) appIndexLocked(dateKey string, appUsage types.AppUsage) int {
+	if appUsage.ExePath != "" {
+		for i, app := range m.appUsage[dateKey] {
+			if strings.EqualFold(app.ExePath, appUsage.ExePath) {
+				return i
+			}
+		}
+	}
 	for i, app := range m.appUsage[dateKey] {
-		if additionalDuration, exists := increments[app.Name]; exists {
-			m.appUsage[dateKey][i].Duration += additionalDuration
+		if app.Name == appUsage.Name {
+			return i
 		}
 	}
+	return -1
+}
+
+// BatchUpdateAppUsageMetadata implements UsageRepository interface
+func (m *MockRepository) BatchUpdateAppUsageMetadata(ctx context.Context, date time.Time, appUsages []types.AppUsage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.batchCallCount++
+
+	if m.shouldFailBatch {
+		return errors.NewRepositoryError("BatchUpdateAppUsageMetadata", fmt.Errorf("mock batch failure"), errors.ErrCodeConnection)
+	}
+
+	dateKey := date.Format("2006-01-02")
+
+	for _, appUsage := range appUsages {
+		m.metadataWrites++
+
+		if i := m.appIndexLocked(dateKey, appUsage); i >= 0 {
+			m.appUsage[dateKey][i].IconPath = appUsage.IconPath
+			m.appUsage[dateKey][i].ExePath = appUsage.ExePath
+			continue
+		}
+		m.appUsage[dateKey] = append(m.appUsage[dateKey], types.AppUsage{
+			Name:     appUsage.Name,
+			IconPath: appUsage.IconPath,
+			ExePath:  appUsage.ExePath,
+			Date:     date,
+		})
+	}
 
 	return nil
 }
 
+// GetMetadataWrites returns the number of app metadata records written
+func (m *MockRepository) GetMetadataWrites() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.metadataWrites
+}
+
 // GetAppUsageByDateRangePaginated implements UsageRepository interface
 func (m *MockRepository) GetAppUsageByDateRangePaginated(ctx context.Context, startDate, endDate time.Time, limit, offset int) (*types.PaginatedAppUsageResult, error) {
 	m.mu.RLock()
@@ -410,3 +511,96 @@ func (m *MockRepository) GetAppUsageByNameAndDateRange(ctx context.Context, appN
 
 	return result, nil
 }
+
+// AppendFocusEvent implements FocusEventRepository interface
+func (m *MockRepository) AppendFocusEvent(ctx context.Context, event *types.FocusEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shouldFailSave {
+		return errors.NewRepositoryError("AppendFocusEvent", fmt.Errorf("mock save failure"), errors.ErrCodeConnection)
+	}
+
+	m.nextEventID++
+	event.ID = m.nextEventID
+	m.focusEvents = append(m.focusEvents, *event)
+
+	// Keep events ordered the way the SQLite query returns them
+	sort.SliceStable(m.focusEvents, func(i, j int) bool {
+		return m.focusEvents[i].OccurredAt.Before(m.focusEvents[j].OccurredAt)
+	})
+
+	return nil
+}
+
+// GetFocusEvents implements FocusEventRepository interface
+func (m *MockRepository) GetFocusEvents(ctx context.Context, start, end time.Time) ([]types.FocusEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.shouldFailLoad {
+		return nil, errors.NewRepositoryError("GetFocusEvents", fmt.Errorf("mock load failure"), errors.ErrCodeConnection)
+	}
+
+	var result []types.FocusEvent
+	for _, event := range m.focusEvents {
+		if !event.OccurredAt.Before(start) && !event.OccurredAt.After(end) {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
+// GetLatestFocusEvent implements FocusEventRepository interface
+func (m *MockRepository) GetLatestFocusEvent(ctx context.Context, eventType types.FocusEventType) (*types.FocusEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.shouldFailLoad {
+		return nil, errors.NewRepositoryError("GetLatestFocusEvent", fmt.Errorf("mock load failure"), errors.ErrCodeConnection)
+	}
+
+	for i := len(m.focusEvents) - 1; i >= 0; i-- {
+		if m.focusEvents[i].Type == eventType {
+			event := m.focusEvents[i]
+			return &event, nil
+		}
+	}
+
+	return nil, errors.NewRepositoryError("GetLatestFocusEvent", fmt.Errorf("not found"), errors.ErrCodeNotFound)
+}
+
+// CompactFocusEvents implements FocusEventRepository interface
+func (m *MockRepository) CompactFocusEvents(ctx context.Context, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.focusEvents[:0]
+	for _, event := range m.focusEvents {
+		compactable := event.Type == types.FocusEventHeartbeat || event.Type == types.FocusEventCheckpoint
+		if compactable && event.OccurredAt.Before(before) {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	m.focusEvents = kept
+
+	return nil
+}
+
+// ReplaceUsageForDate implements FocusEventRepository interface
+func (m *MockRepository) ReplaceUsageForDate(ctx context.Context, date time.Time, usage *types.UsageData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shouldFailSave {
+		return errors.NewRepositoryError("ReplaceUsageForDate", fmt.Errorf("mock save failure"), errors.ErrCodeConnection)
+	}
+
+	dateKey := date.Format("2006-01-02")
+	m.dailyUsage[dateKey] = &types.UsageData{TotalTime: usage.TotalTime, Apps: []types.AppUsage{}}
+	m.appUsage[dateKey] = append([]types.AppUsage(nil), usage.Apps...)
+
+	return nil
+}
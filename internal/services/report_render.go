@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"qwin/internal/types"
+)
+
+// reportFuncs are the helpers shared by the Markdown and HTML report templates
+var reportFuncs = map[string]interface{}{
+	"duration": formatReportDuration,
+	"signed":   formatSignedDuration,
+	"percent":  func(share float64) string { return fmt.Sprintf("%.0f%%", share*100) },
+	"change":   func(percent float64) string { return fmt.Sprintf("%+.0f%%", percent) },
+	"date":     func(t time.Time) string { return t.Format("Mon, Jan 2") },
+	"stamp":    func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"hour":     formatReportHour,
+	"bar":      func(share float64) string { return fmt.Sprintf("%.1f", share*100) },
+	"dayShare": dayShare,
+	"mdEscape": escapeMarkdown,
+}
+
+var markdownReportTemplate = template.Must(template.New("report.md").Funcs(reportFuncs).Parse(`# {{.Title}}
+
+- **Total time:** {{duration .TotalTime}}
+- **Daily average:** {{duration .DailyAverage}} over {{.ActiveDays}} active day(s)
+{{- with .Previous}}
+- **Previous period:** {{duration .TotalTime}} ({{signed .Change}}{{if .TotalTime}}, {{change .ChangePercent}}{{end}})
+{{- end}}
+{{- with .BusiestHour}}
+- **Busiest hour:** {{hour .Hour}} ({{duration .Duration}})
+{{- end}}
+
+## Top apps
+{{if .TopApps}}
+| App | Time | Share |
+| --- | ---: | ---: |
+{{- range .TopApps}}
+| {{mdEscape .Name}} | {{duration .Duration}} | {{percent .Share}} |
+{{- end}}
+{{else}}
+No app usage recorded.
+{{end}}
+## Top categories
+{{if .TopCategories}}
+| Category | Time | Share |
+| --- | ---: | ---: |
+{{- range .TopCategories}}
+| {{mdEscape .Name}} | {{duration .Duration}} | {{percent .Share}} |
+{{- end}}
+{{else}}
+No app usage recorded.
+{{end}}
+## Day by day
+
+| Day | Time | Change |
+| --- | ---: | ---: |
+{{- range $i, $day := .Days}}
+| {{date $day.Date}} | {{duration $day.TotalTime}} | {{if $i}}{{signed $day.Change}}{{else}}–{{end}} |
+{{- end}}
+
+_Generated {{stamp .GeneratedAt}}_
+`))
+
+var htmlReportTemplate = htmltemplate.Must(htmltemplate.New("report.html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, -apple-system, "Segoe UI", sans-serif; margin: 2rem auto; max-width: 44rem; padding: 0 1rem; color: #1f2328; background: #fff; }
+h1 { font-size: 1.5rem; margin-bottom: 1rem; }
+h2 { font-size: 1.1rem; margin-top: 2rem; border-bottom: 1px solid #d0d7de; padding-bottom: .25rem; }
+.stats { display: grid; grid-template-columns: repeat(auto-fit, minmax(9rem, 1fr)); gap: .75rem; }
+.stat { border: 1px solid #d0d7de; border-radius: .5rem; padding: .75rem; }
+.stat .label { font-size: .75rem; color: #59636e; text-transform: uppercase; letter-spacing: .03em; }
+.stat .value { font-size: 1.25rem; font-weight: 600; margin-top: .25rem; }
+.stat .note { font-size: .8rem; color: #59636e; }
+table { width: 100%; border-collapse: collapse; }
+td, th { padding: .35rem .5rem; text-align: left; border-bottom: 1px solid #eaeef2; font-size: .9rem; }
+th { color: #59636e; font-weight: 500; }
+.num { text-align: right; white-space: nowrap; }
+.bar { background: #eaeef2; border-radius: .25rem; height: .5rem; min-width: 6rem; }
+.bar span { display: block; height: 100%; border-radius: .25rem; background: #0969da; }
+.up { color: #1a7f37; }
+.down { color: #cf222e; }
+footer { margin-top: 2rem; font-size: .75rem; color: #59636e; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="stats">
+  <div class="stat"><div class="label">Total time</div><div class="value">{{duration .TotalTime}}</div>
+  {{- with .Previous}}<div class="note">{{signed .Change}} vs previous{{if .TotalTime}} ({{change .ChangePercent}}){{end}}</div>{{end}}</div>
+  <div class="stat"><div class="label">Daily average</div><div class="value">{{duration .DailyAverage}}</div><div class="note">{{.ActiveDays}} active day(s)</div></div>
+  {{- with .BusiestHour}}
+  <div class="stat"><div class="label">Busiest hour</div><div class="value">{{hour .Hour}}</div><div class="note">{{duration .Duration}}</div></div>
+  {{- end}}
+</div>
+
+<h2>Top apps</h2>
+{{if .TopApps}}<table>
+{{- range .TopApps}}
+<tr><td>{{.Name}}</td><td class="num">{{duration .Duration}}</td><td><div class="bar"><span style="width: {{bar .Share}}%"></span></div></td><td class="num">{{percent .Share}}</td></tr>
+{{- end}}
+</table>{{else}}<p>No app usage recorded.</p>{{end}}
+
+<h2>Top categories</h2>
+{{if .TopCategories}}<table>
+{{- range .TopCategories}}
+<tr><td>{{.Name}}</td><td class="num">{{duration .Duration}}</td><td><div class="bar"><span style="width: {{bar .Share}}%"></span></div></td><td class="num">{{percent .Share}}</td></tr>
+{{- end}}
+</table>{{else}}<p>No app usage recorded.</p>{{end}}
+
+<h2>Day by day</h2>
+<table>
+<tr><th>Day</th><th class="num">Time</th><th></th><th class="num">Change</th></tr>
+{{- $report := .}}
+{{- range $i, $day := .Days}}
+<tr><td>{{date $day.Date}}</td><td class="num">{{duration $day.TotalTime}}</td><td><div class="bar"><span style="width: {{bar (dayShare $report $day)}}%"></span></div></td><td class="num{{if $i}}{{if gt $day.Change 0}} up{{else if lt $day.Change 0}} down{{end}}{{end}}">{{if $i}}{{signed $day.Change}}{{else}}–{{end}}</td></tr>
+{{- end}}
+</table>
+
+<footer>Generated {{stamp .GeneratedAt}}</footer>
+</body>
+</html>
+`))
+
+// RenderReportMarkdown renders a report as a Markdown document
+func RenderReportMarkdown(report *types.Report) (string, error) {
+	var buf bytes.Buffer
+	if err := markdownReportTemplate.Execute(&buf, report); err != nil {
+		return "", fmt.Errorf("failed to render markdown report: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// RenderReportHTML renders a report as a self-contained HTML page without external assets
+func RenderReportHTML(report *types.Report) (string, error) {
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, report); err != nil {
+		return "", fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// formatReportDuration formats seconds as hours and minutes, e.g. "5h 03m" or "42m"
+func formatReportDuration(seconds int64) string {
+	if seconds < 0 {
+		seconds = -seconds
+	}
+	hours := seconds / 3600
+	minutes := (seconds % 3600) / 60
+	if hours > 0 {
+		return fmt.Sprintf("%dh %02dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// formatSignedDuration formats a change in seconds with an explicit sign
+func formatSignedDuration(seconds int64) string {
+	if seconds < 0 {
+		return "-" + formatReportDuration(seconds)
+	}
+	return "+" + formatReportDuration(seconds)
+}
+
+// formatReportHour formats an hour of day as a one-hour range, e.g. "14:00–15:00"
+func formatReportHour(hour int) string {
+	return fmt.Sprintf("%02d:00–%02d:00", hour, (hour+1)%24)
+}
+
+// dayShare returns a day's total relative to the report's busiest day, for bar widths
+func dayShare(report *types.Report, day types.ReportDay) float64 {
+	var max int64
+	for _, d := range report.Days {
+		if d.TotalTime > max {
+			max = d.TotalTime
+		}
+	}
+	if max == 0 {
+		return 0
+	}
+	return float64(day.TotalTime) / float64(max)
+}
+
+// escapeMarkdown keeps app names from breaking Markdown tables
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer(`|`, `\|`, `*`, `\*`, `_`, `\_`, "`", "\\`").Replace(s)
+}
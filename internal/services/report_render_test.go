@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"qwin/internal/types"
+)
+
+func sampleReport() *types.Report {
+	start := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	return &types.Report{
+		Title:        "Weekly report: Jun 3 – Jun 4, 2024",
+		Period:       types.ReportPeriodWeekly,
+		Start:        start,
+		End:          start.AddDate(0, 0, 1),
+		GeneratedAt:  time.Date(2024, 6, 10, 8, 0, 0, 0, time.UTC),
+		TotalTime:    18180,
+		DailyAverage: 9090,
+		ActiveDays:   2,
+		Days: []types.ReportDay{
+			{Date: start, TotalTime: 7200},
+			{Date: start.AddDate(0, 0, 1), TotalTime: 10980, Change: 3780},
+		},
+		TopApps: []types.ReportItem{
+			{Name: "Code", Duration: 12000, Share: 0.75},
+			{Name: "<script>|x", Duration: 4000, Share: 0.25},
+		},
+		TopCategories: []types.ReportItem{{Name: types.CategoryDevelopment, Duration: 16000, Share: 1}},
+		BusiestHour:   &types.ReportHour{Hour: 14, Duration: 3600},
+		Previous:      &types.ReportComparison{TotalTime: 9090, Change: 9090, ChangePercent: 100},
+	}
+}
+
+func TestRenderReportMarkdown(t *testing.T) {
+	markdown, err := RenderReportMarkdown(sampleReport())
+	if err != nil {
+		t.Fatalf("RenderReportMarkdown failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"# Weekly report: Jun 3 – Jun 4, 2024",
+		"**Total time:** 5h 03m",
+		"**Previous period:** 2h 31m (+2h 31m, +100%)",
+		"**Busiest hour:** 14:00–15:00 (1h 00m)",
+		"| Code | 3h 20m | 75% |",
+		`| <script>\|x | 1h 06m | 25% |`,
+		"| Tue, Jun 4 | 3h 03m | +1h 03m |",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestRenderReportHTML(t *testing.T) {
+	html, err := RenderReportHTML(sampleReport())
+	if err != nil {
+		t.Fatalf("RenderReportHTML failed: %v", err)
+	}
+
+	if strings.Contains(html, "<script>") {
+		t.Error("app names must be escaped in HTML")
+	}
+	for _, external := range []string{"<link", "src=", "@import", "http://", "https://"} {
+		if strings.Contains(html, external) {
+			t.Errorf("HTML report should be self-contained but contains %q", external)
+		}
+	}
+	for _, want := range []string{"<title>Weekly report", "5h 03m", "width: 75.0%", "14:00–15:00", `class="num up"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+const (
+	// reportCheckInterval is how often the scheduler looks for newly completed periods
+	reportCheckInterval = time.Hour
+	// reportGenerateTimeout bounds a single scheduled generation run
+	reportGenerateTimeout = 2 * time.Minute
+)
+
+// ReportScheduler writes weekly and monthly reports into a directory once each period completes
+type ReportScheduler struct {
+	service *ReportService
+	dir     string
+	logger  logging.Logger
+
+	mutex   sync.Mutex
+	stopCh  chan struct{}
+	running bool
+}
+
+// NewReportScheduler creates a scheduler that writes reports built by service into dir
+func NewReportScheduler(service *ReportService, dir string, logger logging.Logger) *ReportScheduler {
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+	return &ReportScheduler{
+		service: service,
+		dir:     dir,
+		logger:  logger,
+	}
+}
+
+// Start generates any reports that are due and keeps checking periodically until Stop
+func (rs *ReportScheduler) Start() {
+	rs.mutex.Lock()
+	if rs.running {
+		rs.mutex.Unlock()
+		return
+	}
+	rs.running = true
+	rs.stopCh = make(chan struct{})
+	stopCh := rs.stopCh
+	rs.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(reportCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			rs.generateDue()
+
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends periodic report generation
+func (rs *ReportScheduler) Stop() {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	if !rs.running {
+		return
+	}
+	rs.running = false
+	close(rs.stopCh)
+}
+
+// Dir returns the directory reports are written to
+func (rs *ReportScheduler) Dir() string {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	return rs.dir
+}
+
+// SetDir changes the directory later reports are written to
+func (rs *ReportScheduler) SetDir(dir string) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.dir = dir
+}
+
+// generateDue runs GenerateDue for the current usage day and logs the outcome
+func (rs *ReportScheduler) generateDue() {
+	ctx, cancel := context.WithTimeout(context.Background(), reportGenerateTimeout)
+	defer cancel()
+
+	written, err := rs.GenerateDue(ctx, rs.service.Today())
+	if err != nil {
+		rs.logger.Error("Failed to generate scheduled reports", "dir", rs.Dir(), "error", err)
+		return
+	}
+	for _, path := range written {
+		rs.logger.Info("Generated scheduled report", "path", path)
+	}
+}
+
+// GenerateDue writes the reports for the last complete week and month before today
+// that haven't been written yet, and returns the paths of the files it created
+func (rs *ReportScheduler) GenerateDue(ctx context.Context, today time.Time) ([]string, error) {
+	weekStart, weekEnd := WeeklyPeriod(today)
+	year, week := weekStart.ISOWeek()
+	monthStart, monthEnd := MonthlyPeriod(today)
+
+	due := []struct {
+		period     types.ReportPeriod
+		start, end time.Time
+		name       string
+	}{
+		{types.ReportPeriodWeekly, weekStart, weekEnd, fmt.Sprintf("weekly-%d-W%02d", year, week)},
+		{types.ReportPeriodMonthly, monthStart, monthEnd, "monthly-" + monthStart.Format("2006-01")},
+	}
+
+	var written []string
+	for _, report := range due {
+		if rs.exists(report.name) {
+			continue
+		}
+		paths, err := rs.Generate(ctx, report.period, report.start, report.end, report.name)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, paths...)
+	}
+	return written, nil
+}
+
+// Generate builds the report for a period and writes it as name.md and name.html
+func (rs *ReportScheduler) Generate(ctx context.Context, period types.ReportPeriod, start, end time.Time, name string) ([]string, error) {
+	report, err := rs.service.BuildReport(ctx, period, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	markdown, err := RenderReportMarkdown(report)
+	if err != nil {
+		return nil, err
+	}
+	html, err := RenderReportHTML(report)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := rs.Dir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create reports directory %s: %w", dir, err)
+	}
+
+	// The HTML file is written last, so its presence marks a complete report
+	var written []string
+	for _, file := range []struct{ ext, content string }{{".md", markdown}, {".html", html}} {
+		path := filepath.Join(dir, name+file.ext)
+		if err := writeFileAtomic(path, []byte(file.content)); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// exists reports whether the report with the given name has already been written
+func (rs *ReportScheduler) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(rs.Dir(), name+".html"))
+	return err == nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path,
+// so readers never see a partially written report
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+func TestReportScheduler_GenerateDue(t *testing.T) {
+	repo := NewMockRepository()
+	seedReportUsage(t, repo, time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC), []int64{3600}, map[int][]types.AppUsage{
+		0: {{Name: "Code", Duration: 3600}},
+	})
+
+	dir := filepath.Join(t.TempDir(), "reports")
+	scheduler := NewReportScheduler(NewReportService(repo, logging.NewDefaultLogger()), dir, logging.NewDefaultLogger())
+	today := time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC)
+
+	written, err := scheduler.GenerateDue(context.Background(), today)
+	if err != nil {
+		t.Fatalf("GenerateDue failed: %v", err)
+	}
+
+	var names []string
+	for _, path := range written {
+		names = append(names, filepath.Base(path))
+	}
+	sort.Strings(names)
+	want := []string{"monthly-2024-05.html", "monthly-2024-05.md", "weekly-2024-W23.html", "weekly-2024-W23.md"}
+	if len(names) != len(want) {
+		t.Fatalf("written = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("written = %v, want %v", names, want)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "weekly-2024-W23.md"))
+	if err != nil {
+		t.Fatalf("failed to read weekly report: %v", err)
+	}
+	if len(content) == 0 {
+		t.Error("weekly report is empty")
+	}
+
+	// Reports that already exist are not regenerated
+	written, err = scheduler.GenerateDue(context.Background(), today.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("second GenerateDue failed: %v", err)
+	}
+	if len(written) != 0 {
+		t.Errorf("expected nothing to be regenerated, got %v", written)
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp*"))
+	if len(leftovers) != 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// reportTopN is how many apps and categories a report lists
+	reportTopN = 5
+	// maxReportDays bounds custom report periods
+	maxReportDays = 366
+)
+
+// ReportService builds usage reports for arbitrary periods from stored usage
+type ReportService struct {
+	repository repository.UsageRepository
+	eventLog   repository.FocusEventRepository // nil when the repository has no event log
+	logger     logging.Logger
+
+	mutex       sync.RWMutex
+	dayBoundary types.DayBoundary
+}
+
+// NewReportService creates a report service reading from the given repository
+func NewReportService(repo repository.UsageRepository, logger logging.Logger) *ReportService {
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+
+	// Hourly breakdowns come from the focus event log when the repository keeps one
+	eventLog, _ := repo.(repository.FocusEventRepository)
+
+	return &ReportService{
+		repository: repo,
+		eventLog:   eventLog,
+		logger:     logger,
+	}
+}
+
+// SetDayBoundary sets the day boundary used to map usage days to hours of the day
+func (s *ReportService) SetDayBoundary(boundary types.DayBoundary) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.dayBoundary = boundary
+}
+
+// Today returns the date key of the current usage day
+func (s *ReportService) Today() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.dayBoundary.DateOf(time.Now())
+}
+
+// WeeklyPeriod returns the last complete Monday-to-Sunday week before the given date
+func WeeklyPeriod(date time.Time) (time.Time, time.Time) {
+	date = types.DateKey(date)
+	// Days since Monday, with Sunday counting as the seventh day of the week
+	sinceMonday := (int(date.Weekday()) + 6) % 7
+	start := date.AddDate(0, 0, -sinceMonday-7)
+	return start, start.AddDate(0, 0, 6)
+}
+
+// MonthlyPeriod returns the last complete calendar month before the given date
+func MonthlyPeriod(date time.Time) (time.Time, time.Time) {
+	date = types.DateKey(date)
+	firstOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return firstOfMonth.AddDate(0, -1, 0), firstOfMonth.AddDate(0, 0, -1)
+}
+
+// BuildReport summarizes usage from start to end, both inclusive dates
+func (s *ReportService) BuildReport(ctx context.Context, period types.ReportPeriod, start, end time.Time) (*types.Report, error) {
+	start, end = types.DateKey(start), types.DateKey(end)
+	dayCount, err := periodDays("BuildReport", start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.Report{
+		Title:       reportTitle(period, start, end),
+		Period:      period,
+		Start:       start,
+		End:         end,
+		GeneratedAt: time.Now(),
+	}
+
+	days, err := s.dailyTotals(ctx, start, dayCount)
+	if err != nil {
+		return nil, err
+	}
+	report.Days = days
+	for _, day := range days {
+		report.TotalTime += day.TotalTime
+		if day.TotalTime > 0 {
+			report.ActiveDays++
+		}
+	}
+	if report.ActiveDays > 0 {
+		report.DailyAverage = report.TotalTime / int64(report.ActiveDays)
+	}
+
+	apps, err := s.repository.GetAppUsageByDateRange(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	report.TopApps, report.TopCategories = summarizeApps(apps)
+
+	report.BusiestHour = s.busiestHour(ctx, start, end)
+
+	// Compare against the equally long period right before this one
+	previousEnd := start.AddDate(0, 0, -1)
+	previousStart := previousEnd.AddDate(0, 0, -(dayCount - 1))
+	previousDays, err := s.dailyTotals(ctx, previousStart, dayCount)
+	if err != nil {
+		return nil, err
+	}
+	report.Previous = compareToPrevious(report.TotalTime, previousStart, previousEnd, previousDays)
+
+	return report, nil
+}
+
+// dailyTotals returns the stored total of each of dayCount days starting at start
+func (s *ReportService) dailyTotals(ctx context.Context, start time.Time, dayCount int) ([]types.ReportDay, error) {
+	totals, err := storedDailyTotals(ctx, s.repository, start, dayCount)
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]types.ReportDay, 0, dayCount)
+	for i, total := range totals {
+		day := types.ReportDay{Date: start.AddDate(0, 0, i), TotalTime: total}
+		if i > 0 {
+			day.Change = total - totals[i-1]
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// storedDailyTotals returns the stored total of each of dayCount days starting at start,
+// counting days without a record as zero
+func storedDailyTotals(ctx context.Context, repo repository.UsageRepository, start time.Time, dayCount int) ([]int64, error) {
+	totals := make([]int64, dayCount)
+	for i := range totals {
+		usage, err := repo.GetDailyUsage(ctx, start.AddDate(0, 0, i))
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if usage != nil {
+			totals[i] = usage.TotalTime
+		}
+	}
+	return totals, nil
+}
+
+// periodDays returns how many days an inclusive date range covers,
+// rejecting inverted ranges and ranges longer than maxReportDays
+func periodDays(operation string, start, end time.Time) (int, error) {
+	if end.Before(start) {
+		return 0, errors.NewRepositoryError(operation,
+			fmt.Errorf("period end %s is before start %s", end.Format("2006-01-02"), start.Format("2006-01-02")), errors.ErrCodeValidation)
+	}
+
+	dayCount := int(end.Sub(start).Hours()/24) + 1
+	if dayCount > maxReportDays {
+		return 0, errors.NewRepositoryError(operation,
+			fmt.Errorf("period of %d days exceeds the maximum of %d", dayCount, maxReportDays), errors.ErrCodeValidation)
+	}
+	return dayCount, nil
+}
+
+// summarizeApps totals app usage per app, by the name it is shown under, and per category,
+// largest first
+func summarizeApps(apps []types.AppUsage) ([]types.ReportItem, []types.ReportItem) {
+	byApp := make(map[string]int64)
+	byCategory := make(map[string]int64)
+	var total int64
+	for _, app := range apps {
+		byApp[app.Label()] += app.Duration
+		byCategory[types.CategorizeApp(app.Name)] += app.Duration
+		total += app.Duration
+	}
+	return topReportItems(byApp, total), topReportItems(byCategory, total)
+}
+
+// topReportItems returns the reportTopN largest entries with their share of total
+func topReportItems(durations map[string]int64, total int64) []types.ReportItem {
+	items := make([]types.ReportItem, 0, len(durations))
+	for name, duration := range durations {
+		if duration <= 0 {
+			continue
+		}
+		item := types.ReportItem{Name: name, Duration: duration}
+		if total > 0 {
+			item.Share = float64(duration) / float64(total)
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Duration != items[j].Duration {
+			return items[i].Duration > items[j].Duration
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	if len(items) > reportTopN {
+		items = items[:reportTopN]
+	}
+	return items
+}
+
+// busiestHour replays the focus event log over the period and returns the hour of day
+// with the most tracked time, or nil when the log has nothing for the period
+func (s *ReportService) busiestHour(ctx context.Context, start, end time.Time) *types.ReportHour {
+	if s.eventLog == nil {
+		return nil
+	}
+
+	s.mutex.RLock()
+	boundary := s.dayBoundary
+	s.mutex.RUnlock()
+
+	periodStart := boundary.Start(start)
+	periodEnd := boundary.End(end)
+
+	events, err := s.eventLog.GetFocusEvents(ctx, periodStart.Add(-focusReplayLookback), periodEnd)
+	if err != nil {
+		s.logger.Warn("Failed to read focus events for report", "error", err)
+		return nil
+	}
+
+	hours := hourlyTotals(events, periodStart, periodEnd, boundary.Location())
+
+	busiest := -1
+	for hour, seconds := range hours {
+		if seconds > 0 && (busiest < 0 || seconds > hours[busiest]) {
+			busiest = hour
+		}
+	}
+	if busiest < 0 {
+		return nil
+	}
+	return &types.ReportHour{Hour: busiest, Duration: int64(math.Round(hours[busiest]))}
+}
+
+// hourlyTotals sums tracked time between from and to per local hour of day
+func hourlyTotals(events []types.FocusEvent, from, to time.Time, loc *time.Location) [24]float64 {
+	var hours [24]float64
+	walkFocusIntervals(events, from, func(start, end time.Time, _ focusReplayState) {
+		if end.After(to) {
+			end = to
+		}
+		for start.Before(end) {
+			local := start.In(loc)
+			nextHour := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc).Add(time.Hour)
+
+			segmentEnd := end
+			if segmentEnd.After(nextHour) {
+				segmentEnd = nextHour
+			}
+			hours[local.Hour()] += segmentEnd.Sub(start).Seconds()
+			start = segmentEnd
+		}
+	})
+	return hours
+}
+
+// compareToPrevious relates total to the previous period's days
+func compareToPrevious(total int64, start, end time.Time, days []types.ReportDay) *types.ReportComparison {
+	comparison := &types.ReportComparison{Start: start, End: end}
+	for _, day := range days {
+		comparison.TotalTime += day.TotalTime
+	}
+	comparison.Change = total - comparison.TotalTime
+	comparison.ChangePercent = percentChange(comparison.Change, comparison.TotalTime)
+	return comparison
+}
+
+// percentChange expresses change relative to base, or 0 when there is no base to compare to
+func percentChange(change, base int64) float64 {
+	if base <= 0 {
+		return 0
+	}
+	return float64(change) / float64(base) * 100
+}
+
+// reportTitle names a report after its period
+func reportTitle(period types.ReportPeriod, start, end time.Time) string {
+	switch period {
+	case types.ReportPeriodWeekly:
+		return fmt.Sprintf("Weekly report: %s", formatDateSpan(start, end))
+	case types.ReportPeriodMonthly:
+		return fmt.Sprintf("Monthly report: %s", start.Format("January 2006"))
+	default:
+		return fmt.Sprintf("Usage report: %s", formatDateSpan(start, end))
+	}
+}
+
+// formatDateSpan formats an inclusive date range, e.g. "Jun 3 – Jun 9, 2024"
+func formatDateSpan(start, end time.Time) string {
+	if start.Equal(end) {
+		return start.Format("Jan 2, 2006")
+	}
+	if start.Year() == end.Year() {
+		return fmt.Sprintf("%s – %s", start.Format("Jan 2"), end.Format("Jan 2, 2006"))
+	}
+	return fmt.Sprintf("%s – %s", start.Format("Jan 2, 2006"), end.Format("Jan 2, 2006"))
+}
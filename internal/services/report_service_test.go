@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// seedReportUsage stores daily totals and app usage for consecutive days starting at start
+func seedReportUsage(t *testing.T, repo *MockRepository, start time.Time, totals []int64, apps map[int][]types.AppUsage) {
+	t.Helper()
+	ctx := context.Background()
+	for i, total := range totals {
+		date := start.AddDate(0, 0, i)
+		if err := repo.SaveDailyUsage(ctx, date, &types.UsageData{TotalTime: total}); err != nil {
+			t.Fatalf("SaveDailyUsage failed: %v", err)
+		}
+		for _, app := range apps[i] {
+			app.Date = date
+			if err := repo.SaveAppUsage(ctx, date, &app); err != nil {
+				t.Fatalf("SaveAppUsage failed: %v", err)
+			}
+		}
+	}
+}
+
+func TestReportService_BuildReport(t *testing.T) {
+	repo := NewMockRepository()
+	service := NewReportService(repo, logging.NewDefaultLogger())
+	service.SetDayBoundary(utcMidnight(t))
+
+	previousStart := time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC)
+	start := previousStart.AddDate(0, 0, 7)
+
+	seedReportUsage(t, repo, previousStart, []int64{1000, 1000, 1000, 1000, 0, 0, 0}, nil)
+	seedReportUsage(t, repo, start, []int64{3600, 7200, 0, 1800, 0, 0, 1800}, map[int][]types.AppUsage{
+		0: {{Name: "Code", Duration: 3000}, {Name: "chrome", Duration: 600}},
+		1: {{Name: "Code", Duration: 4000}, {Name: "Slack", Duration: 3000}},
+		3: {{Name: "Spotify", Duration: 1800}},
+	})
+
+	// An hour and a half of focus on the 4th, most of it between 14:00 and 15:00
+	at := time.Date(2024, 6, 4, 13, 30, 0, 0, time.UTC)
+	for _, event := range []types.FocusEvent{
+		{Type: types.FocusEventTrackingStarted, OccurredAt: at},
+		{Type: types.FocusEventAppSwitched, AppName: "Code", OccurredAt: at},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: at.Add(90 * time.Minute)},
+	} {
+		event := event
+		repo.AppendFocusEvent(context.Background(), &event)
+	}
+
+	report, err := service.BuildReport(context.Background(), types.ReportPeriodWeekly, start, start.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("BuildReport failed: %v", err)
+	}
+
+	if report.Title != "Weekly report: Jun 3 – Jun 9, 2024" {
+		t.Errorf("Title = %q", report.Title)
+	}
+	if report.TotalTime != 14400 || report.ActiveDays != 4 || report.DailyAverage != 3600 {
+		t.Errorf("totals = %d over %d days (avg %d), want 14400 over 4 (avg 3600)", report.TotalTime, report.ActiveDays, report.DailyAverage)
+	}
+	if len(report.Days) != 7 || report.Days[1].Change != 3600 || report.Days[2].Change != -7200 {
+		t.Errorf("day-over-day changes wrong: %+v", report.Days)
+	}
+
+	if len(report.TopApps) != 4 || report.TopApps[0].Name != "Code" || report.TopApps[0].Duration != 7000 {
+		t.Errorf("TopApps = %+v, want Code first with 7000s", report.TopApps)
+	}
+	if report.TopCategories[0].Name != types.CategoryDevelopment {
+		t.Errorf("TopCategories = %+v, want Development first", report.TopCategories)
+	}
+	var shares float64
+	for _, item := range report.TopCategories {
+		shares += item.Share
+	}
+	if shares < 0.999 || shares > 1.001 {
+		t.Errorf("category shares sum to %f, want 1", shares)
+	}
+
+	if report.BusiestHour == nil || report.BusiestHour.Hour != 14 || report.BusiestHour.Duration != 3600 {
+		t.Errorf("BusiestHour = %+v, want 14:00 with 3600s", report.BusiestHour)
+	}
+
+	if report.Previous == nil || report.Previous.TotalTime != 4000 || report.Previous.Change != 10400 {
+		t.Fatalf("Previous = %+v, want 4000s and +10400s", report.Previous)
+	}
+	if report.Previous.ChangePercent != 260 {
+		t.Errorf("ChangePercent = %f, want 260", report.Previous.ChangePercent)
+	}
+}
+
+func TestReportService_BuildReportValidation(t *testing.T) {
+	service := NewReportService(NewMockRepository(), logging.NewDefaultLogger())
+	start := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	if _, err := service.BuildReport(context.Background(), types.ReportPeriodCustom, start, start.AddDate(0, 0, -1)); !errors.IsValidation(err) {
+		t.Errorf("expected a validation error for an inverted period, got %v", err)
+	}
+	if _, err := service.BuildReport(context.Background(), types.ReportPeriodCustom, start, start.AddDate(2, 0, 0)); !errors.IsValidation(err) {
+		t.Errorf("expected a validation error for an overlong period, got %v", err)
+	}
+
+	report, err := service.BuildReport(context.Background(), types.ReportPeriodCustom, start, start)
+	if err != nil {
+		t.Fatalf("BuildReport on an empty repository failed: %v", err)
+	}
+	if report.TotalTime != 0 || report.BusiestHour != nil || report.Previous.ChangePercent != 0 {
+		t.Errorf("empty report = %+v", report)
+	}
+}
+
+func TestReportPeriods(t *testing.T) {
+	tests := []struct {
+		name       string
+		period     func(time.Time) (time.Time, time.Time)
+		date       time.Time
+		start, end string
+	}{
+		{"week from a Wednesday", WeeklyPeriod, time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC), "2024-06-03", "2024-06-09"},
+		{"week from a Monday", WeeklyPeriod, time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), "2024-06-03", "2024-06-09"},
+		{"week from a Sunday", WeeklyPeriod, time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC), "2024-06-03", "2024-06-09"},
+		{"month", MonthlyPeriod, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), "2024-02-01", "2024-02-29"},
+		{"month across a year", MonthlyPeriod, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2023-12-01", "2023-12-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.period(tt.date)
+			if got := start.Format("2006-01-02"); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := end.Format("2006-01-02"); got != tt.end {
+				t.Errorf("end = %s, want %s", got, tt.end)
+			}
+		})
+	}
+}
+
+func TestSummarizeApps_GroupsByAlias(t *testing.T) {
+	apps, categories := summarizeApps([]types.AppUsage{
+		{Name: "Code", DisplayName: "Visual Studio Code", Duration: 300},
+		{Name: "code", DisplayName: "Visual Studio Code", Duration: 100},
+		{Name: "chrome", Duration: 200},
+	})
+
+	if len(apps) != 2 || apps[0].Name != "Visual Studio Code" || apps[0].Duration != 400 {
+		t.Errorf("apps = %+v, want both names combined under the alias", apps)
+	}
+	if categories[0].Name != types.CategoryDevelopment || categories[0].Duration != 400 {
+		t.Errorf("categories = %+v, want Development first with 400s", categories)
+	}
+}
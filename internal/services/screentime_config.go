@@ -7,6 +7,8 @@ import (
 
 	"qwin/internal/infrastructure/errors"
 	"qwin/internal/platform"
+	"qwin/internal/repository"
+	"qwin/internal/types"
 )
 
 // ResetUsageData resets the usage data
@@ -14,18 +16,67 @@ func (st *ScreenTimeTracker) ResetUsageData() {
 	// Persist current data before reset
 	st.persistCurrentData()
 
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
 	st.mutex.Lock()
 	defer st.mutex.Unlock()
 
+	// Stored usage is kept, so later flushes add to it rather than replace it
+	st.resetPersistedStateLocked()
 	st.usageData = make(map[string]int64)
 	st.appInfoCache = make(map[string]*platform.AppInfo)
-	st.startTime = time.Now()
+	st.restartDayClockLocked(time.Now(), 0)
 	st.lastTime = time.Time{}
 	st.lastApp = ""
 
 	// Update current date
+	st.currentDate = st.dayBoundary.DateOf(time.Now())
+}
+
+// SetDayBoundary changes when usage days start and which timezone decides the date.
+// Usage tracked so far is flushed under the old boundary first; if the change moves the
+// current moment onto another usage day, tracking continues from that day's stored usage
+func (st *ScreenTimeTracker) SetDayBoundary(boundary types.DayBoundary) {
+	st.mutex.RLock()
+	started := !st.currentDate.IsZero()
+	st.mutex.RUnlock()
+
+	// Before Start there is no usage day yet, so nothing needs flushing
+	if started {
+		st.persistCurrentData()
+	}
+
+	st.persistMutex.Lock()
+	st.mutex.Lock()
 	now := time.Now()
-	st.currentDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	today := boundary.DateOf(now)
+	st.dayBoundary = boundary
+
+	dayChanged := started && !today.Equal(st.currentDate)
+	if dayChanged {
+		st.currentDate = today
+		st.usageData = make(map[string]int64)
+		st.restartDayClockLocked(now, 0)
+		st.resetPersistedStateLocked()
+	}
+	st.mutex.Unlock()
+	st.persistMutex.Unlock()
+
+	// Keep repository-side notions of "today" consistent with the tracker
+	if configurable, ok := st.repository.(repository.DayBoundaryConfigurable); ok {
+		configurable.SetDayBoundary(boundary)
+	}
+
+	if dayChanged {
+		st.loadTodaysData()
+	}
+}
+
+// DayBoundary returns the day boundary used to assign usage to dates
+func (st *ScreenTimeTracker) DayBoundary() types.DayBoundary {
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+	return st.dayBoundary
 }
 
 // CleanupOldData removes usage data older than the specified number of days
@@ -41,6 +41,59 @@ func TestScreenTimeTracker_ResetUsageData(t *testing.T) {
 	}
 }
 
+func TestScreenTimeTracker_SetDayBoundary(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	utc, err := types.NewDayBoundary(0, "UTC")
+	if err != nil {
+		t.Fatalf("NewDayBoundary failed: %v", err)
+	}
+	tracker.SetDayBoundary(utc)
+
+	now := time.Now()
+	tracker.mutex.Lock()
+	tracker.currentDate = utc.DateOf(now)
+	tracker.usageData["TestApp"] = 60
+	tracker.startTime = now.Add(-time.Minute)
+	tracker.mutex.Unlock()
+
+	// UTC+14 and UTC-12 are 26 hours apart, so one of them is always on another date
+	var shifted types.DayBoundary
+	for _, zone := range []string{"Etc/GMT-14", "Etc/GMT+12"} {
+		candidate, err := types.NewDayBoundary(0, zone)
+		if err != nil {
+			t.Skipf("timezone data unavailable: %v", err)
+		}
+		if !candidate.DateOf(now).Equal(utc.DateOf(now)) {
+			shifted = candidate
+			break
+		}
+	}
+
+	newDate := shifted.DateOf(now)
+	mockRepo.SaveAppUsage(ctx, newDate, &types.AppUsage{Name: "Stored", Duration: 300, Date: newDate})
+
+	tracker.SetDayBoundary(shifted)
+
+	if got := tracker.DayBoundary().Timezone; got != shifted.Timezone {
+		t.Errorf("DayBoundary().Timezone = %q, want %q", got, shifted.Timezone)
+	}
+
+	tracker.mutex.RLock()
+	defer tracker.mutex.RUnlock()
+	if !tracker.currentDate.Equal(newDate) {
+		t.Errorf("currentDate = %v, want %v", tracker.currentDate, newDate)
+	}
+	if _, exists := tracker.usageData["TestApp"]; exists {
+		t.Error("usage from the previous day should not carry over to the new day")
+	}
+	if got := tracker.usageData["Stored"]; got != 300 {
+		t.Errorf("Stored usage = %d, want 300 loaded for the new day", got)
+	}
+}
+
 func TestScreenTimeTracker_CleanupOldData(t *testing.T) {
 	mockRepo := NewMockRepository()
 	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
@@ -70,7 +123,7 @@ func TestScreenTimeTracker_ErrorHandling(t *testing.T) {
 		{
 			name:        "save failure",
 			failSave:    true,
-			expectError: false, // Service should handle gracefully
+			expectError: true, // Usage stays queued, but the caller learns it wasn't saved
 		},
 		{
 			name:        "load failure",
@@ -80,12 +133,12 @@ func TestScreenTimeTracker_ErrorHandling(t *testing.T) {
 		{
 			name:        "batch failure",
 			failBatch:   true,
-			expectError: false, // Service should handle gracefully
+			expectError: true, // Usage stays queued, but the caller learns it wasn't saved
 		},
 		{
 			name:        "transaction failure",
 			failTx:      true,
-			expectError: false, // Service should handle gracefully
+			expectError: true, // Usage stays queued, but the caller learns it wasn't saved
 		},
 	}
 
@@ -151,8 +204,8 @@ func TestScreenTimeTracker_PersistenceDisabledOperations(t *testing.T) {
 
 	// Test operations with persistence disabled
 	err := tracker.SaveCurrentDataNow()
-	if err != nil {
-		t.Errorf("SaveCurrentDataNow() with persistence disabled unexpected error = %v", err)
+	if err == nil {
+		t.Error("SaveCurrentDataNow() with persistence disabled should report that usage wasn't saved")
 	}
 
 	// Verify repository was not called (since persistence is disabled)
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/platform"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// focusEventWriteTimeout keeps event log writes from stalling the tracking loop
+	focusEventWriteTimeout = 1 * time.Second
+	// focusReplayLookback is how far before the replay window events are read to establish state
+	focusReplayLookback = 24 * time.Hour
+)
+
+// recordFocusEvent appends an event to the focus event log as it happens
+// Must be called without holding st.mutex
+func (st *ScreenTimeTracker) recordFocusEvent(eventType types.FocusEventType, appName, exePath string, at time.Time) {
+	st.mutex.Lock()
+	if st.eventLog == nil || !st.persistenceEnabled {
+		st.mutex.Unlock()
+		return
+	}
+	eventLog := st.eventLog
+	st.lastEventTime = at
+	st.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), focusEventWriteTimeout)
+	defer cancel()
+
+	event := &types.FocusEvent{
+		Type:       eventType,
+		AppName:    appName,
+		ExePath:    exePath,
+		OccurredAt: at,
+	}
+	if err := eventLog.AppendFocusEvent(ctx, event); err != nil {
+		st.logger.Warn("Failed to append focus event", "event_type", eventType, "app", appName, "error", err)
+	}
+}
+
+// recordHeartbeatIfDue writes a heartbeat when no event has been written recently,
+// so a crash loses at most focusHeartbeatInterval of attribution
+func (st *ScreenTimeTracker) recordHeartbeatIfDue(now time.Time) {
+	st.mutex.RLock()
+	due := st.running && !st.paused && st.excludedMode != types.IgnoreModeIgnore && now.Sub(st.lastEventTime) >= focusHeartbeatInterval
+	appName, exePath := splitAppKey(st.lastApp)
+	st.mutex.RUnlock()
+
+	if due {
+		st.recordFocusEvent(types.FocusEventHeartbeat, appName, exePath, now)
+	}
+}
+
+// checkpointEvent builds the checkpoint written alongside a snapshot attributed up to lastTime
+// Returns nil when nothing has been attributed yet
+func checkpointEvent(lastTime time.Time) *types.FocusEvent {
+	if lastTime.IsZero() {
+		return nil
+	}
+	return &types.FocusEvent{
+		Type:       types.FocusEventCheckpoint,
+		OccurredAt: lastTime,
+	}
+}
+
+// writeCheckpoint records that the snapshot being persisted covers everything up to the checkpoint
+// and compacts heartbeats that no longer carry information. Runs inside the persist transaction.
+func writeCheckpoint(ctx context.Context, txRepo repository.UsageRepository, checkpoint *types.FocusEvent) error {
+	if checkpoint == nil {
+		return nil
+	}
+
+	eventLog, ok := txRepo.(repository.FocusEventRepository)
+	if !ok {
+		return nil
+	}
+
+	if err := eventLog.AppendFocusEvent(ctx, checkpoint); err != nil {
+		return err
+	}
+
+	return eventLog.CompactFocusEvents(ctx, checkpoint.OccurredAt)
+}
+
+// reconcileFromEventLog applies events recorded after the last checkpoint to the stored usage.
+// This recovers attribution that was tracked but never flushed because the process died.
+func (st *ScreenTimeTracker) reconcileFromEventLog(ctx context.Context) {
+	if st.eventLog == nil {
+		return
+	}
+
+	var since time.Time
+	checkpoint, err := st.eventLog.GetLatestFocusEvent(ctx, types.FocusEventCheckpoint)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			st.logger.Error("Failed to read last focus checkpoint", "error", err)
+			return
+		}
+	} else {
+		since = checkpoint.OccurredAt
+	}
+
+	now := time.Now()
+	windowStart := now.Add(-focusReplayLookback)
+	if !since.IsZero() {
+		windowStart = since.Add(-focusReplayLookback)
+	}
+
+	events, err := st.eventLog.GetFocusEvents(ctx, windowStart, now)
+	if err != nil {
+		st.logger.Error("Failed to read focus events for reconciliation", "error", err)
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	lastEventTime := events[len(events)-1].OccurredAt
+	if !since.IsZero() && !lastEventTime.After(since) {
+		return // Everything in the log is already reflected in stored usage
+	}
+
+	st.mutex.RLock()
+	boundary := st.dayBoundary
+	st.mutex.RUnlock()
+
+	days, _ := replayFocusEvents(events, since, boundary)
+	if len(days) == 0 {
+		return
+	}
+
+	err = st.repository.WithTransaction(ctx, func(txRepo repository.UsageRepository) error {
+		for _, day := range days {
+			if err := applyReplayedDelta(ctx, txRepo, day); err != nil {
+				return err
+			}
+		}
+		return writeCheckpoint(ctx, txRepo, checkpointEvent(lastEventTime))
+	})
+	if err != nil {
+		st.logger.Error("Failed to reconcile unflushed focus events", "since", since, "error", err)
+		return
+	}
+
+	st.logger.Info("Reconciled unflushed focus events", "since", since, "until", lastEventTime, "days", len(days))
+}
+
+// applyReplayedDelta adds replayed attribution for one day on top of what is already stored
+func applyReplayedDelta(ctx context.Context, txRepo repository.UsageRepository, day *replayedDay) error {
+	delta := day.usageData()
+
+	if err := txRepo.BatchIncrementAppUsage(ctx, day.date, delta.Apps); err != nil {
+		return err
+	}
+
+	return txRepo.IncrementDailyUsage(ctx, day.date, delta.TotalTime)
+}
+
+// RebuildUsageFromEventLog recomputes app_usage and daily_usage for a day purely from the focus event log
+// Stored icons and executable paths are kept for apps that are still present after the rebuild
+func (st *ScreenTimeTracker) RebuildUsageFromEventLog(date time.Time) (*types.UsageData, error) {
+	if st.repository == nil || st.eventLog == nil {
+		return nil, errors.NewRepositoryError("RebuildUsageFromEventLog",
+			fmt.Errorf("focus event log is not available"),
+			errors.ErrCodeConnection)
+	}
+
+	ctx := context.Background()
+
+	// Keep flushes out while stored usage and the persisted baseline are replaced
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+
+	st.mutex.RLock()
+	boundary := st.dayBoundary
+	st.mutex.RUnlock()
+
+	dateKey := types.DateKey(date)
+	dayStart := boundary.Start(dateKey)
+	dayEnd := boundary.End(dateKey)
+
+	// Read past both ends of the day: earlier events establish state at the day start,
+	// later ones close intervals that are still open when the day ends
+	events, err := st.eventLog.GetFocusEvents(ctx, dayStart.Add(-focusReplayLookback), dayEnd.Add(focusReplayLookback))
+	if err != nil {
+		return nil, err
+	}
+
+	days, _ := replayFocusEvents(events, dayStart, boundary)
+	day, exists := days[dateKey.Format("2006-01-02")]
+	if !exists {
+		// Refuse to wipe days that predate the event log
+		return nil, errors.HandleNotFound("RebuildUsageFromEventLog", "focus_event", dateKey.Format("2006-01-02"))
+	}
+	rebuilt := day.usageData()
+
+	// Preserve metadata that the event log doesn't carry, matching apps by executable path
+	// first so programs sharing a name keep their own icons
+	existing, err := st.repository.GetAppUsageByDate(ctx, dateKey)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	metadata := make(map[string]types.AppUsage, len(existing)*2)
+	for _, app := range existing {
+		metadata[appKey(app.Name, app.ExePath)] = app
+		if _, exists := metadata[app.Name]; !exists {
+			metadata[app.Name] = app
+		}
+	}
+	// Keys as the tracker holds them, before stored paths are filled in
+	keys := make([]string, len(rebuilt.Apps))
+	for i := range rebuilt.Apps {
+		app := &rebuilt.Apps[i]
+		keys[i] = appKey(app.Name, app.ExePath)
+		stored, ok := metadata[keys[i]]
+		if !ok {
+			stored, ok = metadata[app.Name]
+		}
+		if ok {
+			app.IconPath = stored.IconPath
+			if app.ExePath == "" {
+				app.ExePath = stored.ExePath
+			}
+		}
+	}
+
+	if err := st.eventLog.ReplaceUsageForDate(ctx, dateKey, rebuilt); err != nil {
+		return nil, err
+	}
+
+	// Rebase the in-memory counters so the next flush only adds time tracked after the rebuild
+	st.mutex.Lock()
+	if st.currentDate.Equal(dateKey) {
+		st.resetPersistedStateLocked()
+		st.usageData = make(map[string]int64, len(rebuilt.Apps))
+		for i, app := range rebuilt.Apps {
+			st.usageData[keys[i]] = app.Duration
+			st.persistedUsage[keys[i]] = app.Duration
+			st.persistedMetadata[keys[i]] = platform.AppInfo{
+				Name:     app.Name,
+				IconPath: app.IconPath,
+				ExePath:  app.ExePath,
+			}
+		}
+		st.startTime = time.Now().Add(-time.Duration(rebuilt.TotalTime) * time.Second)
+		st.persistedTotal = rebuilt.TotalTime
+	}
+	st.mutex.Unlock()
+
+	st.logger.Info("Rebuilt usage from focus event log", "date", dateKey.Format("2006-01-02"), "apps", len(rebuilt.Apps))
+	return rebuilt, nil
+}
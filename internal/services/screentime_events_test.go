@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/platform"
+	"qwin/internal/types"
+)
+
+func TestScreenTimeTracker_ReconcileUnflushedEvents(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	// A previous session flushed 60s of Chrome at the checkpoint, then kept tracking and crashed
+	now := time.Now()
+	checkpointAt := now.Add(-2 * time.Minute)
+	today := types.DateKey(now)
+	if checkpointAt.Before(today) {
+		t.Skip("test needs two minutes of the current day")
+	}
+
+	mockRepo.SaveDailyUsage(ctx, today, &types.UsageData{TotalTime: 60})
+	mockRepo.SaveAppUsage(ctx, today, &types.AppUsage{Name: "Chrome", Duration: 60, Date: today})
+
+	for _, event := range []types.FocusEvent{
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: checkpointAt.Add(-time.Minute)},
+		{Type: types.FocusEventCheckpoint, OccurredAt: checkpointAt},
+		{Type: types.FocusEventAppSwitched, AppName: "Slack", OccurredAt: checkpointAt.Add(30 * time.Second)},
+		{Type: types.FocusEventHeartbeat, AppName: "Slack", OccurredAt: checkpointAt.Add(50 * time.Second)},
+	} {
+		event := event
+		mockRepo.AppendFocusEvent(ctx, &event)
+	}
+
+	tracker.mutex.Lock()
+	tracker.currentDate = today
+	tracker.mutex.Unlock()
+
+	tracker.loadTodaysData()
+
+	tracker.mutex.RLock()
+	chrome := tracker.usageData["Chrome"]
+	slack := tracker.usageData["Slack"]
+	tracker.mutex.RUnlock()
+
+	if chrome != 90 {
+		t.Errorf("Chrome = %d, want 90 (60 flushed + 30 replayed)", chrome)
+	}
+	if slack != 20 {
+		t.Errorf("Slack = %d, want 20 replayed", slack)
+	}
+
+	daily, err := mockRepo.GetDailyUsage(ctx, today)
+	if err != nil {
+		t.Fatalf("GetDailyUsage() error = %v", err)
+	}
+	if daily.TotalTime != 110 {
+		t.Errorf("daily total = %d, want 110", daily.TotalTime)
+	}
+
+	// A second load must not apply the same events again
+	tracker.mutex.Lock()
+	tracker.usageData = make(map[string]int64)
+	tracker.mutex.Unlock()
+	tracker.loadTodaysData()
+
+	tracker.mutex.RLock()
+	defer tracker.mutex.RUnlock()
+	if tracker.usageData["Chrome"] != 90 || tracker.usageData["Slack"] != 20 {
+		t.Errorf("reconciliation was applied twice: %v", tracker.usageData)
+	}
+}
+
+func TestScreenTimeTracker_RebuildUsageFromEventLog(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	date := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	start := date.Add(10 * time.Hour)
+
+	// Stored rows disagree with the log and include an app that never had focus
+	mockRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Chrome", Duration: 5, IconPath: "data:image/png;base64,AA==", Date: date})
+	mockRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Ghost", Duration: 999, Date: date})
+
+	for _, event := range []types.FocusEvent{
+		{Type: types.FocusEventTrackingStarted, OccurredAt: start},
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: start},
+		{Type: types.FocusEventLocked, OccurredAt: start.Add(40 * time.Second)},
+		{Type: types.FocusEventAppSwitched, AppName: "Chrome", OccurredAt: start.Add(100 * time.Second)},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: start.Add(120 * time.Second)},
+	} {
+		event := event
+		mockRepo.AppendFocusEvent(ctx, &event)
+	}
+
+	rebuilt, err := tracker.RebuildUsageFromEventLog(date)
+	if err != nil {
+		t.Fatalf("RebuildUsageFromEventLog() error = %v", err)
+	}
+
+	if rebuilt.TotalTime != 120 {
+		t.Errorf("TotalTime = %d, want 120", rebuilt.TotalTime)
+	}
+
+	apps, _ := mockRepo.GetAppUsageByDate(ctx, date)
+	if len(apps) != 1 {
+		t.Fatalf("expected only Chrome after rebuild, got %+v", apps)
+	}
+	if apps[0].Name != "Chrome" || apps[0].Duration != 60 {
+		t.Errorf("Chrome = %+v, want duration 60", apps[0])
+	}
+	if apps[0].IconPath == "" {
+		t.Error("rebuild should preserve stored icon")
+	}
+
+	// Days without any events are left untouched
+	if _, err := tracker.RebuildUsageFromEventLog(date.AddDate(0, 0, -10)); err == nil {
+		t.Error("expected an error when rebuilding a day with no events")
+	}
+}
+
+func TestScreenTimeTracker_InactivityEndsAttribution(t *testing.T) {
+	mockRepo := NewMockRepository()
+	windowAPI := &mockActivityWindowAPI{}
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "Chrome"})
+	tracker := NewScreenTimeTrackerWithWindowAPI(mockRepo, logging.NewDefaultLogger(), windowAPI)
+
+	tracker.trackCurrentApp()
+
+	windowAPI.locked = true
+	tracker.trackCurrentApp()
+	tracker.trackCurrentApp()
+
+	tracker.mutex.RLock()
+	lastApp := tracker.lastApp
+	tracker.mutex.RUnlock()
+	if lastApp != "" {
+		t.Errorf("lastApp = %q, want empty while locked", lastApp)
+	}
+
+	events, _ := mockRepo.GetFocusEvents(context.Background(), time.Time{}, time.Now().Add(time.Minute))
+	var switched, locked int
+	for _, event := range events {
+		switch event.Type {
+		case types.FocusEventAppSwitched:
+			switched++
+		case types.FocusEventLocked:
+			locked++
+		}
+	}
+	if switched != 1 || locked != 1 {
+		t.Errorf("got %d app_switched and %d locked events, want 1 of each", switched, locked)
+	}
+}
+
+// mockActivityWindowAPI is a MockWindowAPI that also reports user presence
+type mockActivityWindowAPI struct {
+	MockWindowAPI
+	locked bool
+	idle   time.Duration
+}
+
+func (m *mockActivityWindowAPI) IdleDuration() time.Duration {
+	return m.idle
+}
+
+func (m *mockActivityWindowAPI) IsSessionLocked() bool {
+	return m.locked
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"time"
+
+	"qwin/internal/types"
+)
+
+// SetIgnoreRules replaces the rules that keep applications out of usage stats.
+// Disabled rules are skipped; an invalid rule rejects the whole list.
+func (st *ScreenTimeTracker) SetIgnoreRules(rules []types.IgnoreRule) error {
+	ruleSet, err := types.CompileIgnoreRules(rules)
+	if err != nil {
+		return err
+	}
+
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.ignoreRules = ruleSet
+	return nil
+}
+
+// enterExcludedLocked stops attribution because the focused app matches a rule with the given mode.
+// Returns true when this starts a new excluded interval rather than continuing one.
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) enterExcludedLocked(mode types.IgnoreMode, now time.Time) bool {
+	// Credit the previous app up to the switch; the excluded app itself gets nothing
+	st.attributeElapsedLocked(now)
+
+	entered := st.excludedMode != mode
+	st.closeExcludedIntervalLocked(now)
+	st.excludedMode = mode
+	st.excludedSince = now
+	return entered
+}
+
+// leaveExcludedLocked ends an excluded interval because a regular app has focus again
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) leaveExcludedLocked(now time.Time) {
+	if st.excludedMode == "" {
+		return
+	}
+	st.closeExcludedIntervalLocked(now)
+	st.excludedMode = ""
+	st.excludedSince = time.Time{}
+}
+
+// closeExcludedIntervalLocked accounts for the excluded time since excludedSince.
+// Hidden time stays in the daily total; ignored time is removed from it by moving
+// the session start forward, the same way a pause is skipped.
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) closeExcludedIntervalLocked(now time.Time) {
+	if st.excludedMode != types.IgnoreModeIgnore || st.startTime.IsZero() || !now.After(st.excludedSince) {
+		return
+	}
+	st.startTime = st.startTime.Add(now.Sub(st.excludedSince))
+}
+
+// excludedEventType returns the focus event recorded when an excluded interval starts
+func excludedEventType(mode types.IgnoreMode) types.FocusEventType {
+	if mode == types.IgnoreModeIgnore {
+		return types.FocusEventAppIgnored
+	}
+	return types.FocusEventAppHidden
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/platform"
+	"qwin/internal/types"
+)
+
+func TestScreenTimeTracker_IgnoreRules(t *testing.T) {
+	mockRepo := NewMockRepository()
+	windowAPI := &MockWindowAPI{}
+	tracker := NewScreenTimeTrackerWithWindowAPI(mockRepo, logging.NewDefaultLogger(), windowAPI)
+
+	if err := tracker.SetIgnoreRules([]types.IgnoreRule{
+		{MatchType: types.IgnoreMatchName, Pattern: "LockApp", Mode: types.IgnoreModeIgnore, Enabled: true},
+		{MatchType: types.IgnoreMatchExePath, Pattern: "explorer.exe", Mode: types.IgnoreModeHide, Enabled: true},
+	}); err != nil {
+		t.Fatalf("SetIgnoreRules() error = %v", err)
+	}
+
+	start := time.Now()
+	tracker.mutex.Lock()
+	tracker.startTime = start.Add(-time.Hour)
+	tracker.lastApp = "Chrome"
+	tracker.lastTime = start.Add(-10 * time.Second)
+	tracker.mutex.Unlock()
+
+	// Switching to an ignored app closes Chrome's interval and attributes nothing further
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "LockApp"})
+	tracker.trackCurrentApp()
+
+	tracker.mutex.Lock()
+	if tracker.lastApp != "" || tracker.excludedMode != types.IgnoreModeIgnore {
+		t.Errorf("expected ignore mode with no active app, got lastApp=%q mode=%q", tracker.lastApp, tracker.excludedMode)
+	}
+	startBefore := tracker.startTime
+	// Pretend the lock screen stayed up for a minute
+	tracker.excludedSince = tracker.excludedSince.Add(-time.Minute)
+	tracker.mutex.Unlock()
+
+	tracker.trackCurrentApp()
+
+	// Hidden apps keep the time in the total but not in any app
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "explorer", ExePath: `C:\Windows\explorer.exe`})
+	tracker.trackCurrentApp()
+
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "Code"})
+	tracker.trackCurrentApp()
+
+	tracker.mutex.RLock()
+	shifted := tracker.startTime.Sub(startBefore)
+	usage := make(map[string]int64, len(tracker.usageData))
+	for name, duration := range tracker.usageData {
+		usage[name] = duration
+	}
+	lastApp := tracker.lastApp
+	tracker.mutex.RUnlock()
+
+	if shifted < time.Minute {
+		t.Errorf("ignored time should be removed from the total, start moved by %v", shifted)
+	}
+	if usage["Chrome"] < 10 {
+		t.Errorf("Chrome = %d, want the 10s before the switch", usage["Chrome"])
+	}
+	if _, exists := usage["LockApp"]; exists {
+		t.Error("ignored app should not be attributed")
+	}
+	if _, exists := usage["explorer"]; exists {
+		t.Error("hidden app should not be attributed")
+	}
+	if lastApp != "Code" {
+		t.Errorf("lastApp = %q, want Code after leaving the excluded apps", lastApp)
+	}
+
+	events, _ := mockRepo.GetFocusEvents(context.Background(), start.Add(-time.Hour), time.Now().Add(time.Minute))
+	var got []types.FocusEventType
+	for _, event := range events {
+		if event.AppName == "LockApp" || event.AppName == "explorer" {
+			t.Errorf("excluded app name leaked into the event log: %+v", event)
+		}
+		got = append(got, event.Type)
+	}
+	want := []types.FocusEventType{types.FocusEventAppIgnored, types.FocusEventAppHidden, types.FocusEventAppSwitched}
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("events = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestScreenTimeTracker_SetIgnoreRulesRejectsInvalid(t *testing.T) {
+	tracker := NewScreenTimeTrackerWithWindowAPI(NewMockRepository(), logging.NewDefaultLogger(), &MockWindowAPI{})
+
+	err := tracker.SetIgnoreRules([]types.IgnoreRule{
+		{MatchType: types.IgnoreMatchTitle, Pattern: "(", Mode: types.IgnoreModeIgnore, Enabled: true},
+	})
+	if err == nil {
+		t.Error("SetIgnoreRules() should reject an invalid title pattern")
+	}
+}
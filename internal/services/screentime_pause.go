@@ -0,0 +1,181 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"qwin/internal/types"
+)
+
+// Pause stops attributing time until Resume is called or, when duration is positive,
+// until duration has elapsed. Pausing again while paused replaces the deadline.
+func (st *ScreenTimeTracker) Pause(duration time.Duration) {
+	now := time.Now()
+
+	st.mutex.Lock()
+	alreadyPaused := st.paused
+	if !alreadyPaused {
+		// Close attribution for the active app at the moment of the pause
+		st.leaveExcludedLocked(now)
+		st.attributeElapsedLocked(now)
+		st.paused = true
+		st.pausedAt = now
+	}
+
+	if st.pauseTimer != nil {
+		st.pauseTimer.Stop()
+		st.pauseTimer = nil
+	}
+	st.pausedUntil = time.Time{}
+	if duration > 0 {
+		deadline := now.Add(duration)
+		st.pausedUntil = deadline
+		st.pauseTimer = time.AfterFunc(duration, func() { st.resumeAfterDeadline(deadline) })
+	}
+	running := st.running
+	st.mutex.Unlock()
+
+	if !alreadyPaused && running {
+		st.recordFocusEvent(types.FocusEventPaused, "", "", now)
+	}
+	st.notifyStateChange()
+}
+
+// Resume continues tracking after a pause; the paused interval is excluded from the day's total
+func (st *ScreenTimeTracker) Resume() {
+	now := time.Now()
+
+	st.mutex.Lock()
+	if !st.paused {
+		st.mutex.Unlock()
+		return
+	}
+
+	if st.pauseTimer != nil {
+		st.pauseTimer.Stop()
+		st.pauseTimer = nil
+	}
+
+	// Shift the session start so the total skips the paused interval
+	if !st.startTime.IsZero() && now.After(st.pausedAt) {
+		st.startTime = st.startTime.Add(now.Sub(st.pausedAt))
+	}
+
+	st.paused = false
+	st.pausedAt = time.Time{}
+	st.pausedUntil = time.Time{}
+
+	// The next tick starts attribution afresh and records which app has focus
+	st.lastApp = ""
+	st.lastTime = now
+	running := st.running
+	st.mutex.Unlock()
+
+	if running {
+		st.recordFocusEvent(types.FocusEventResumed, "", "", now)
+	}
+	st.notifyStateChange()
+}
+
+// resumeAfterDeadline ends a timed pause unless it was replaced or already ended
+func (st *ScreenTimeTracker) resumeAfterDeadline(deadline time.Time) {
+	st.mutex.RLock()
+	current := st.paused && st.pausedUntil.Equal(deadline)
+	st.mutex.RUnlock()
+
+	if current {
+		st.Resume()
+	}
+}
+
+// SetPrivateMode toggles recording all time to an anonymous bucket instead of the focused app
+func (st *ScreenTimeTracker) SetPrivateMode(enabled bool) {
+	st.mutex.Lock()
+	if st.privateMode == enabled {
+		st.mutex.Unlock()
+		return
+	}
+
+	// Time before the switch belongs to whatever was being recorded until now;
+	// clearing lastApp makes the next tick record the switch under the new mode
+	st.attributeElapsedLocked(time.Now())
+	st.privateMode = enabled
+	st.mutex.Unlock()
+
+	st.notifyStateChange()
+}
+
+// IsPaused returns whether tracking is paused
+func (st *ScreenTimeTracker) IsPaused() bool {
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+	return st.paused
+}
+
+// TrackingState returns the current pause and private mode state
+func (st *ScreenTimeTracker) TrackingState() types.TrackingState {
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+	return st.trackingStateLocked()
+}
+
+// SetStateChangeHandler registers a callback invoked after pause or private mode changes
+func (st *ScreenTimeTracker) SetStateChangeHandler(handler func(types.TrackingState)) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.onStateChange = handler
+}
+
+// trackingStateLocked builds the tracking state
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) trackingStateLocked() types.TrackingState {
+	state := types.TrackingState{
+		Running:     st.running,
+		Paused:      st.paused,
+		PrivateMode: st.privateMode,
+	}
+	if st.paused {
+		pausedAt := st.pausedAt
+		state.PausedAt = &pausedAt
+		if !st.pausedUntil.IsZero() {
+			pausedUntil := st.pausedUntil
+			state.PausedUntil = &pausedUntil
+		}
+	}
+	return state
+}
+
+// notifyStateChange reports the current tracking state to the registered handler
+// Must be called without holding st.mutex
+func (st *ScreenTimeTracker) notifyStateChange() {
+	st.mutex.RLock()
+	handler := st.onStateChange
+	state := st.trackingStateLocked()
+	st.mutex.RUnlock()
+
+	if handler != nil {
+		handler(state)
+	}
+}
+
+// attributeElapsedLocked credits the active app with time up to now and closes its interval
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) attributeElapsedLocked(now time.Time) {
+	if st.lastApp != "" && !st.lastTime.IsZero() {
+		if elapsed := now.Sub(st.lastTime).Seconds(); elapsed > 0 {
+			st.usageData[st.lastApp] += int64(math.Round(elapsed))
+		}
+	}
+	st.lastApp = ""
+	st.lastTime = now
+}
+
+// trackedUntilLocked returns the moment up to which session time counts:
+// the start of the current pause, or now when not paused
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) trackedUntilLocked(now time.Time) time.Time {
+	if st.paused && st.pausedAt.Before(now) {
+		return st.pausedAt
+	}
+	return now
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/platform"
+	"qwin/internal/types"
+)
+
+func TestScreenTimeTracker_PauseResume(t *testing.T) {
+	mockRepo := NewMockRepository()
+	windowAPI := &MockWindowAPI{}
+	tracker := NewScreenTimeTrackerWithWindowAPI(mockRepo, logging.NewDefaultLogger(), windowAPI)
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "Chrome"})
+
+	var mu sync.Mutex
+	var states []types.TrackingState
+	tracker.SetStateChangeHandler(func(state types.TrackingState) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, state)
+	})
+
+	now := time.Now()
+	tracker.mutex.Lock()
+	tracker.running = true
+	tracker.startTime = now.Add(-time.Hour)
+	tracker.lastApp = "Chrome"
+	tracker.lastTime = now.Add(-30 * time.Second)
+	tracker.mutex.Unlock()
+
+	tracker.Pause(0)
+
+	tracker.mutex.RLock()
+	chrome := tracker.usageData["Chrome"]
+	lastApp := tracker.lastApp
+	tracker.mutex.RUnlock()
+
+	if chrome < 30 {
+		t.Errorf("Chrome = %d, want the 30s before the pause attributed", chrome)
+	}
+	if lastApp != "" {
+		t.Errorf("lastApp = %q, want attribution closed while paused", lastApp)
+	}
+
+	// Ticks while paused attribute nothing
+	tracker.trackCurrentApp()
+	tracker.mutex.RLock()
+	if tracker.lastApp != "" || tracker.usageData["Chrome"] != chrome {
+		t.Error("trackCurrentApp should not attribute time while paused")
+	}
+	tracker.mutex.RUnlock()
+
+	state := tracker.TrackingState()
+	if !state.Paused || state.PausedAt == nil || state.PausedUntil != nil {
+		t.Errorf("TrackingState() = %+v, want an indefinite pause", state)
+	}
+
+	// Pretend the pause lasted ten minutes; the total must skip it
+	tracker.mutex.Lock()
+	tracker.pausedAt = tracker.pausedAt.Add(-10 * time.Minute)
+	tracker.mutex.Unlock()
+	frozen := tracker.GetUsageData().TotalTime
+
+	tracker.Resume()
+
+	if tracker.IsPaused() {
+		t.Fatal("expected tracker to be resumed")
+	}
+	if got := tracker.GetUsageData().TotalTime; got > frozen+1 {
+		t.Errorf("TotalTime after resume = %d, want about %d without the paused interval", got, frozen)
+	}
+
+	events, _ := mockRepo.GetFocusEvents(context.Background(), now.Add(-time.Minute), time.Now().Add(time.Minute))
+	var sawPaused, sawResumed bool
+	for _, event := range events {
+		sawPaused = sawPaused || event.Type == types.FocusEventPaused
+		sawResumed = sawResumed || event.Type == types.FocusEventResumed
+	}
+	if !sawPaused || !sawResumed {
+		t.Errorf("expected paused and resumed events in the log, got %+v", events)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) != 2 || !states[0].Paused || states[1].Paused {
+		t.Errorf("state notifications = %+v, want paused then resumed", states)
+	}
+}
+
+func TestScreenTimeTracker_TimedPauseResumes(t *testing.T) {
+	tracker := NewScreenTimeTrackerWithWindowAPI(NewMockRepository(), logging.NewDefaultLogger(), &MockWindowAPI{})
+
+	resumed := make(chan struct{}, 1)
+	tracker.SetStateChangeHandler(func(state types.TrackingState) {
+		if !state.Paused {
+			resumed <- struct{}{}
+		}
+	})
+
+	tracker.Pause(20 * time.Millisecond)
+	if state := tracker.TrackingState(); state.PausedUntil == nil {
+		t.Fatalf("TrackingState() = %+v, want a pause deadline", state)
+	}
+
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		t.Fatal("timed pause did not resume")
+	}
+	if tracker.IsPaused() {
+		t.Error("expected tracker to be resumed after the deadline")
+	}
+}
+
+func TestScreenTimeTracker_StartsPaused(t *testing.T) {
+	mockRepo := NewMockRepository()
+	windowAPI := &MockWindowAPI{}
+	tracker := NewScreenTimeTrackerWithWindowAPI(mockRepo, logging.NewDefaultLogger(), windowAPI)
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "Chrome"})
+
+	// A pause restored from the last run is applied before tracking starts
+	before := time.Now()
+	tracker.Pause(0)
+	tracker.Start()
+	defer tracker.Stop()
+
+	tracker.trackCurrentApp()
+	tracker.mutex.RLock()
+	lastApp, chrome := tracker.lastApp, tracker.usageData["Chrome"]
+	tracker.mutex.RUnlock()
+	if lastApp != "" || chrome != 0 {
+		t.Errorf("lastApp = %q, Chrome = %d; want nothing attributed on the first tick", lastApp, chrome)
+	}
+
+	events, _ := mockRepo.GetFocusEvents(context.Background(), before.Add(-time.Minute), time.Now().Add(time.Minute))
+	var sawPaused bool
+	for _, event := range events {
+		sawPaused = sawPaused || event.Type == types.FocusEventPaused
+	}
+	if !sawPaused {
+		t.Errorf("expected the session to be logged as paused, got %+v", events)
+	}
+}
+
+func TestScreenTimeTracker_PrivateMode(t *testing.T) {
+	windowAPI := &MockWindowAPI{}
+	tracker := NewScreenTimeTrackerWithWindowAPI(NewMockRepository(), logging.NewDefaultLogger(), windowAPI)
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "Chrome", ExePath: `C:\chrome.exe`})
+
+	now := time.Now()
+	tracker.mutex.Lock()
+	tracker.lastApp = "Chrome"
+	tracker.lastTime = now.Add(-20 * time.Second)
+	tracker.mutex.Unlock()
+
+	tracker.SetPrivateMode(true)
+
+	// The switch closes the Chrome interval, later time goes to the anonymous bucket
+	tracker.mutex.Lock()
+	chrome := tracker.usageData["Chrome"]
+	tracker.lastTime = tracker.lastTime.Add(-5 * time.Second)
+	tracker.mutex.Unlock()
+	tracker.trackCurrentApp()
+
+	tracker.mutex.Lock()
+	tracker.lastTime = tracker.lastTime.Add(-5 * time.Second)
+	tracker.mutex.Unlock()
+	tracker.trackCurrentApp()
+
+	tracker.mutex.RLock()
+	defer tracker.mutex.RUnlock()
+	if chrome < 20 {
+		t.Errorf("Chrome = %d, want time before private mode kept", chrome)
+	}
+	if tracker.usageData["Chrome"] != chrome {
+		t.Errorf("Chrome grew to %d in private mode", tracker.usageData["Chrome"])
+	}
+	if tracker.usageData[types.PrivateAppName] < 5 {
+		t.Errorf("%s = %d, want private time recorded", types.PrivateAppName, tracker.usageData[types.PrivateAppName])
+	}
+	if info := tracker.appInfoCache[types.PrivateAppName]; info == nil || info.ExePath != "" {
+		t.Errorf("private bucket should carry no executable path, got %+v", info)
+	}
+}
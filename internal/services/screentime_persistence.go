@@ -4,9 +4,11 @@ import (
 	"context"
 	"log"
 	"math"
+	"sort"
 	"time"
 
 	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
 	"qwin/internal/platform"
 	"qwin/internal/repository"
 	"qwin/internal/types"
@@ -36,146 +38,230 @@ func (st *ScreenTimeTracker) startPersistenceLoop() {
 	}()
 }
 
-// persistCurrentData saves current usage data to the database
+// usageDelta is the change in tracked usage for one day that has not been written yet
+type usageDelta struct {
+	date      time.Time
+	totalTime int64
+	durations map[string]int64 // seconds to add per app key
+	metadata  []types.AppUsage // apps whose icon or executable path changed
+	// until is the moment the delta's attribution runs up to; once it is written, the
+	// focus event log only needs replaying from there
+	until time.Time
+}
+
+// isEmpty reports whether the delta has nothing to write
+func (d *usageDelta) isEmpty() bool {
+	return d.totalTime == 0 && len(d.durations) == 0 && len(d.metadata) == 0
+}
+
+// persistCurrentData writes usage tracked since the last flush to the database. Failures
+// are logged; the usage is kept in the write-behind queue and retried on the next flush.
 func (st *ScreenTimeTracker) persistCurrentData() {
-	if st.repository == nil || !st.persistenceEnabled {
-		return
+	if err := st.flushCurrentData(); err != nil {
+		st.logger.Debug("Usage kept queued after a failed flush", "error", err)
 	}
+}
 
-	ctx := context.Background()
+// flushCurrentData writes usage tracked since the last flush, along with anything queued
+// while earlier flushes failed, and reports whether the write failed. While persistence
+// is disabled the usage is queued without touching the database.
+func (st *ScreenTimeTracker) flushCurrentData() error {
+	if st.repository == nil {
+		return nil
+	}
+
+	// Serialize flushes so each delta is applied exactly once
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+
+	// Every entry logged while writing this flush, here and in the repository, carries its ID
+	ctx, _ := logging.WithOperationID(context.Background(), "persist_id")
 
-	// Snapshot state under lock to minimize lock contention
+	// Collect the delta under lock; only changed apps are visited, nothing is copied wholesale
 	st.mutex.Lock()
+	enabled := st.persistenceEnabled
 	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-
-	if !today.Equal(st.currentDate) {
-		// Date has changed, persist old data and reset for new day
-		oldDate := st.currentDate
-		oldStartTime := st.startTime
-		oldUsageData := make(map[string]int64, len(st.usageData))
-		for k, v := range st.usageData {
-			oldUsageData[k] = v
-		}
-		oldAppInfoCache := make(map[string]*platform.AppInfo, len(st.appInfoCache))
-		for k, v := range st.appInfoCache {
-			oldAppInfoCache[k] = v
-		}
+	today := st.dayBoundary.DateOf(now)
 
-		// Update state for new day
+	delta := st.collectUsageDeltaLocked(st.trackedUntilLocked(now))
+
+	// From here on the delta is written or owned by the write-behind queue,
+	// so the next flush only collects what is tracked after this one
+	st.markDeltaPersistedLocked(delta)
+
+	// usageData is attributed up to lastTime, which is where replay must resume after a crash
+	checkpoint := checkpointEvent(st.lastTime)
+	delta.until = st.lastTime
+
+	dayRolled := !today.Equal(st.currentDate)
+	if dayRolled {
+		// Start the new day with empty counters; the old day's remainder is in the delta
 		st.currentDate = today
 		st.usageData = make(map[string]int64)
-		st.startTime = now
-		st.mutex.Unlock()
-
-		// Persist old data outside the lock
-		st.persistDataForDateWithSnapshot(ctx, oldDate, oldStartTime, oldUsageData, oldAppInfoCache, now)
-		return
+		st.restartDayClockLocked(now, 0)
+		st.resetPersistedStateLocked()
+	} else {
+		st.lastPersist = now
 	}
+	st.mutex.Unlock()
 
-	// Snapshot current day's data
-	currentDate := st.currentDate
-	startTime := st.startTime
-	usageDataCopy := make(map[string]int64, len(st.usageData))
-	for k, v := range st.usageData {
-		usageDataCopy[k] = v
-	}
-	appInfoCacheCopy := make(map[string]*platform.AppInfo, len(st.appInfoCache))
-	for k, v := range st.appInfoCache {
-		appInfoCacheCopy[k] = v
+	if dayRolled {
+		st.recordFocusEvent(types.FocusEventDayRolled, "", "", now)
 	}
-	st.lastPersist = now
-	st.mutex.Unlock()
 
-	// Persist current day's data outside the lock
-	st.persistDataForDateWithSnapshot(ctx, currentDate, startTime, usageDataCopy, appInfoCacheCopy, now)
+	return st.flushPending(ctx, delta, checkpoint, enabled)
 }
 
-// persistDataForDateWithSnapshot saves usage data for a specific date using provided snapshot data
-// This function does not access st.mutex and can be called without holding locks
-func (st *ScreenTimeTracker) persistDataForDateWithSnapshot(
-	ctx context.Context,
-	date time.Time,
-	startTime time.Time,
-	usageData map[string]int64,
-	appInfoCache map[string]*platform.AppInfo,
-	asOfTime time.Time,
-) {
-	// Calculate total time bounded to the target date using DST-safe calculation
-	var totalTime int64
-	if !startTime.IsZero() {
-		// Calculate start and end boundaries of the target date
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		nextMidnight := startOfDay.AddDate(0, 0, 1)
-
-		// Clamp the time interval to the target date boundaries
-		start := startTime
-		if start.Before(startOfDay) {
-			start = startOfDay
-		}
-		if start.After(nextMidnight) {
-			start = nextMidnight
-		}
+// collectUsageDeltaLocked computes what changed for the current date since the last flush
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) collectUsageDeltaLocked(asOfTime time.Time) *usageDelta {
+	delta := &usageDelta{
+		date:      st.currentDate,
+		durations: make(map[string]int64),
+	}
 
-		end := asOfTime
-		if end.After(nextMidnight) {
-			end = nextMidnight
-		}
-		if end.Before(start) {
-			end = start
+	if totalTime := boundedTotalTime(st.dayBoundary, st.currentDate, st.startTime, asOfTime); totalTime > st.persistedTotal {
+		delta.totalTime = totalTime - st.persistedTotal
+	}
+
+	for key, duration := range st.usageData {
+		if increment := duration - st.persistedUsage[key]; increment > 0 {
+			delta.durations[key] = increment
 		}
 
-		// Calculate elapsed time only within the target date boundaries using math.Round
-		if end.After(start) {
-			totalTime = int64(math.Round(end.Sub(start).Seconds()))
+		cachedInfo, exists := st.appInfoCache[key]
+		if !exists || (cachedInfo.IconPath == "" && cachedInfo.ExePath == "") {
+			continue
+		}
+		if written, ok := st.persistedMetadata[key]; ok &&
+			written.IconPath == cachedInfo.IconPath && written.ExePath == cachedInfo.ExePath {
+			continue
 		}
+		name, _ := splitAppKey(key)
+		delta.metadata = append(delta.metadata, types.AppUsage{
+			Name:      name,
+			IconPath:  cachedInfo.IconPath,
+			ExePath:   cachedInfo.ExePath,
+			Publisher: cachedInfo.Publisher,
+			Version:   cachedInfo.Version,
+			Date:      st.currentDate,
+		})
 	}
 
-	// Create usage data summary
-	usageDataSummary := &types.UsageData{
-		TotalTime: totalTime,
-	}
+	return delta
+}
 
-	// Prepare app usage data for batch save
-	appUsages := make([]types.AppUsage, 0, len(usageData))
-	for name, duration := range usageData {
-		appUsage := types.AppUsage{
-			Name:     name,
-			Duration: duration,
-			Date:     date,
+// markDeltaPersistedLocked advances the persisted baseline past a delta that was collected
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) markDeltaPersistedLocked(delta *usageDelta) {
+	st.persistedTotal += delta.totalTime
+	for key, increment := range delta.durations {
+		st.persistedUsage[key] += increment
+	}
+	for _, app := range delta.metadata {
+		st.persistedMetadata[appKey(app.Name, app.ExePath)] = platform.AppInfo{
+			Name:      app.Name,
+			IconPath:  app.IconPath,
+			ExePath:   app.ExePath,
+			Publisher: app.Publisher,
+			Version:   app.Version,
 		}
+	}
+}
 
-		// Add cached app info if available
-		if cachedInfo, exists := appInfoCache[name]; exists {
-			appUsage.IconPath = cachedInfo.IconPath
-			appUsage.ExePath = cachedInfo.ExePath
-		}
+// resetPersistedStateLocked forgets what has been written for the current date
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) resetPersistedStateLocked() {
+	st.persistedUsage = make(map[string]int64)
+	st.persistedMetadata = make(map[string]platform.AppInfo)
+	st.persistedTotal = 0
+}
 
-		appUsages = append(appUsages, appUsage)
+// flushUsageDeltas applies deltas as increments in a single transaction
+// When checkpoint is non-nil it is written to the focus event log in the same transaction
+// This function does not access st.mutex and can be called without holding locks
+func (st *ScreenTimeTracker) flushUsageDeltas(ctx context.Context, deltas []*usageDelta, checkpoint *types.FocusEvent) error {
+	pending := make([]*usageDelta, 0, len(deltas))
+	for _, delta := range deltas {
+		if !delta.isEmpty() {
+			pending = append(pending, delta)
+		}
+	}
+	if len(pending) == 0 && checkpoint == nil {
+		return nil
 	}
 
 	// Add timeout to avoid hanging on shutdown
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	// Wrap both operations in a transaction for atomicity
-	if err := st.repository.WithTransaction(ctx, func(txRepo repository.UsageRepository) error {
-		// Save daily usage summary
-		if err := txRepo.SaveDailyUsage(ctx, date, usageDataSummary); err != nil {
-			return err
-		}
+	return st.repository.WithTransaction(ctx, func(txRepo repository.UsageRepository) error {
+		for _, delta := range pending {
+			// Always touch the daily summary so app rows never exist without one
+			if err := txRepo.IncrementDailyUsage(ctx, delta.date, delta.totalTime); err != nil {
+				return err
+			}
 
-		// Batch save app usage data
-		if len(appUsages) > 0 {
-			if err := txRepo.BatchProcessAppUsage(ctx, date, appUsages, types.BatchStrategyUpsert); err != nil {
+			if err := txRepo.BatchIncrementAppUsage(ctx, delta.date, appUsageIncrements(delta.durations)); err != nil {
+				return err
+			}
+
+			if err := txRepo.BatchUpdateAppUsageMetadata(ctx, delta.date, delta.metadata); err != nil {
 				return err
 			}
 		}
 
-		return nil
-	}); err != nil {
-		st.logger.Error("Failed to persist usage snapshot", "date", date, "error", err)
+		return writeCheckpoint(ctx, txRepo, checkpoint)
+	})
+}
+
+// appUsageIncrements converts durations keyed by app key into the apps they are added to,
+// ordered by key so applications seen for the first time are created in a stable order
+func appUsageIncrements(durations map[string]int64) []types.AppUsage {
+	keys := make([]string, 0, len(durations))
+	for key := range durations {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+
+	apps := make([]types.AppUsage, 0, len(keys))
+	for _, key := range keys {
+		name, exePath := splitAppKey(key)
+		apps = append(apps, types.AppUsage{Name: name, ExePath: exePath, Duration: durations[key]})
+	}
+	return apps
+}
+
+// boundedTotalTime returns the seconds between startTime and asOfTime that fall within
+// the usage day identified by date under the given day boundary
+func boundedTotalTime(boundary types.DayBoundary, date, startTime, asOfTime time.Time) int64 {
+	if startTime.IsZero() {
+		return 0
+	}
+
+	// Calculate start and end boundaries of the target usage day
+	startOfDay := boundary.Start(date)
+	endOfDay := boundary.End(date)
+
+	// Clamp the time interval to the target date boundaries
+	start := startTime
+	if start.Before(startOfDay) {
+		start = startOfDay
+	}
+	if start.After(endOfDay) {
+		start = endOfDay
+	}
+
+	end := asOfTime
+	if end.After(endOfDay) {
+		end = endOfDay
+	}
+	if end.Before(start) {
+		end = start
+	}
+
+	// Calculate elapsed time only within the target date boundaries using math.Round
+	return int64(math.Round(end.Sub(start).Seconds()))
 }
 
 // loadTodaysData loads existing usage data for today from the database
@@ -184,8 +270,15 @@ func (st *ScreenTimeTracker) loadTodaysData() {
 		return
 	}
 
+	// Hold off flushes so the persisted baseline matches what was loaded
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+
 	ctx := context.Background()
 
+	// Fold in anything that was tracked but not flushed before the last shutdown
+	st.reconcileFromEventLog(ctx)
+
 	// Load daily usage data
 	dailyUsage, err := st.repository.GetDailyUsage(ctx, st.currentDate)
 	if err != nil && !errors.IsNotFound(err) {
@@ -203,22 +296,31 @@ func (st *ScreenTimeTracker) loadTodaysData() {
 	st.mutex.Lock()
 	defer st.mutex.Unlock()
 
+	st.resetPersistedStateLocked()
+
 	// Restore usage data if found
 	if dailyUsage != nil {
 		// Adjust start time to account for previously tracked time
 		// If we've already tracked dailyUsage.TotalTime seconds today,
 		// set startTime so that time.Since(startTime) equals that amount
-		now := time.Now()
-		st.startTime = now.Add(-time.Duration(dailyUsage.TotalTime) * time.Second)
+		st.restartDayClockLocked(time.Now(), time.Duration(dailyUsage.TotalTime)*time.Second)
+		st.persistedTotal = dailyUsage.TotalTime
 	}
 
 	// Restore app usage data
 	for _, appUsage := range appUsages {
-		st.usageData[appUsage.Name] = appUsage.Duration
+		key := appKey(appUsage.Name, appUsage.ExePath)
+		st.usageData[key] = appUsage.Duration
+		st.persistedUsage[key] = appUsage.Duration
+		st.persistedMetadata[key] = platform.AppInfo{
+			Name:     appUsage.Name,
+			IconPath: appUsage.IconPath,
+			ExePath:  appUsage.ExePath,
+		}
 
 		// Cache app info
 		if appUsage.IconPath != "" || appUsage.ExePath != "" {
-			st.appInfoCache[appUsage.Name] = &platform.AppInfo{
+			st.appInfoCache[key] = &platform.AppInfo{
 				Name:     appUsage.Name,
 				IconPath: appUsage.IconPath,
 				ExePath:  appUsage.ExePath,
@@ -229,14 +331,27 @@ func (st *ScreenTimeTracker) loadTodaysData() {
 	st.logger.Info("Loaded usage data for applications", "count", len(appUsages))
 }
 
-// SaveCurrentDataNow immediately persists current usage data to the database
+// SaveCurrentDataNow immediately persists current usage data to the database. It fails
+// when the usage couldn't be written, including while persistence is disabled; the usage
+// is then kept in the write-behind queue or journal.
 func (st *ScreenTimeTracker) SaveCurrentDataNow() error {
 	if st.repository == nil {
 		return errors.NewRepositoryError("SaveCurrentDataNow", nil, errors.ErrCodeConnection)
 	}
 
+	return st.flushCurrentData()
+}
+
+// ReloadCurrentDay flushes pending usage and re-reads the current day from the database,
+// picking up changes made to stored history behind the tracker's back
+func (st *ScreenTimeTracker) ReloadCurrentDay() {
 	st.persistCurrentData()
-	return nil
+
+	st.mutex.Lock()
+	st.usageData = make(map[string]int64)
+	st.mutex.Unlock()
+
+	st.loadTodaysData()
 }
 
 // LoadDataForDate loads usage data for a specific date (useful for data recovery)
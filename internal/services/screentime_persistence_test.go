@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"qwin/internal/infrastructure/logging"
+	"qwin/internal/platform"
 	"qwin/internal/types"
 )
 
@@ -29,10 +30,10 @@ func TestScreenTimeTracker_DataPersistence(t *testing.T) {
 	// Verify repository was called
 	save, _, batch, _, _, _ := mockRepo.GetCallCounts()
 	if save == 0 {
-		t.Error("SaveCurrentDataNow() did not call repository SaveDailyUsage")
+		t.Error("SaveCurrentDataNow() did not call repository IncrementDailyUsage")
 	}
 	if batch == 0 {
-		t.Error("SaveCurrentDataNow() did not call repository BatchProcessAppUsage")
+		t.Error("SaveCurrentDataNow() did not call repository BatchIncrementAppUsageDurations")
 	}
 }
 
@@ -147,3 +148,175 @@ func TestScreenTimeTracker_LoadDataForDate(t *testing.T) {
 		t.Errorf("LoadDataForDate() for non-existent date TotalTime = %d, want 0", emptyData.TotalTime)
 	}
 }
+
+func TestScreenTimeTracker_PersistWritesOnlyDeltas(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	now := time.Now()
+	today := types.DateKey(now)
+	mockRepo.SaveAppUsage(ctx, today, &types.AppUsage{Name: "App", Duration: 100, Date: today})
+
+	tracker.mutex.Lock()
+	tracker.currentDate = today
+	tracker.mutex.Unlock()
+	tracker.loadTodaysData()
+
+	appDuration := func(name string) int64 {
+		apps, _ := mockRepo.GetAppUsageByDate(ctx, today)
+		for _, app := range apps {
+			if app.Name == name {
+				return app.Duration
+			}
+		}
+		return 0
+	}
+
+	tracker.mutex.Lock()
+	tracker.usageData["App"] += 50
+	tracker.usageData["NewApp"] = 30
+	tracker.mutex.Unlock()
+
+	tracker.persistCurrentData()
+	if got := appDuration("App"); got != 150 {
+		t.Errorf("App = %d, want 150", got)
+	}
+	if got := appDuration("NewApp"); got != 30 {
+		t.Errorf("NewApp = %d, want 30", got)
+	}
+
+	// A flush without new usage must not add anything again
+	tracker.persistCurrentData()
+	if got := appDuration("App"); got != 150 {
+		t.Errorf("App = %d after idle flush, want 150", got)
+	}
+
+	// Time written by another tracker instance is kept
+	mockRepo.BatchIncrementAppUsageDurations(ctx, today, map[string]int64{"App": 1000})
+	tracker.mutex.Lock()
+	tracker.usageData["App"] += 10
+	tracker.mutex.Unlock()
+
+	tracker.persistCurrentData()
+	if got := appDuration("App"); got != 1160 {
+		t.Errorf("App = %d with a concurrent writer, want 1160", got)
+	}
+}
+
+func TestScreenTimeTracker_PersistSameNameByPath(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	pathA, pathB := `C:\Downloads\a\setup.exe`, `C:\Downloads\b\setup.exe`
+	today := types.DateKey(time.Now())
+
+	// Two programs sharing an executable name that were split into separate applications
+	mockRepo.mu.Lock()
+	mockRepo.appUsage[today.Format("2006-01-02")] = []types.AppUsage{
+		{Name: "setup", Duration: 100, ExePath: pathA, Date: today},
+		{Name: "setup", Duration: 10, ExePath: pathB, Date: today},
+	}
+	mockRepo.mu.Unlock()
+
+	tracker.mutex.Lock()
+	tracker.currentDate = today
+	tracker.mutex.Unlock()
+	tracker.loadTodaysData()
+
+	if usage := tracker.GetUsageData(); len(usage.Apps) != 2 {
+		t.Fatalf("GetUsageData() apps = %+v, want one per executable path", usage.Apps)
+	}
+
+	tracker.mutex.Lock()
+	tracker.usageData[appKey("setup", pathA)] += 40
+	tracker.usageData[appKey("setup", pathB)] += 20
+	tracker.mutex.Unlock()
+
+	tracker.persistCurrentData()
+	// A flush without new usage must not add anything again
+	tracker.persistCurrentData()
+
+	apps, _ := mockRepo.GetAppUsageByDate(ctx, today)
+	durations := make(map[string]int64)
+	for _, app := range apps {
+		durations[app.ExePath] += app.Duration
+	}
+	if len(apps) != 2 || durations[pathA] != 140 || durations[pathB] != 30 {
+		t.Errorf("stored apps = %+v, want 140s and 30s kept apart by path", apps)
+	}
+}
+
+func TestScreenTimeTracker_PersistMetadataOnlyOnChange(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+
+	now := time.Now()
+	chrome := appKey("Chrome", `C:\chrome.exe`)
+	tracker.mutex.Lock()
+	tracker.currentDate = types.DateKey(now)
+	tracker.usageData[chrome] = 60
+	tracker.appInfoCache[chrome] = &platform.AppInfo{Name: "Chrome", IconPath: "data:image/png;base64,AA==", ExePath: `C:\chrome.exe`}
+	tracker.mutex.Unlock()
+
+	tracker.persistCurrentData()
+	if got := mockRepo.GetMetadataWrites(); got != 1 {
+		t.Fatalf("metadata writes after first flush = %d, want 1", got)
+	}
+
+	tracker.mutex.Lock()
+	tracker.usageData[chrome] += 30
+	tracker.mutex.Unlock()
+
+	tracker.persistCurrentData()
+	if got := mockRepo.GetMetadataWrites(); got != 1 {
+		t.Errorf("metadata rewritten without a change, writes = %d", got)
+	}
+
+	tracker.mutex.Lock()
+	tracker.appInfoCache[chrome] = &platform.AppInfo{Name: "Chrome", IconPath: "data:image/png;base64,BB==", ExePath: `C:\chrome.exe`}
+	tracker.mutex.Unlock()
+
+	tracker.persistCurrentData()
+	if got := mockRepo.GetMetadataWrites(); got != 2 {
+		t.Errorf("metadata writes after icon change = %d, want 2", got)
+	}
+}
+
+func TestScreenTimeTracker_PersistRetriesFailedDeltas(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+	ctx := context.Background()
+
+	now := time.Now()
+	today := types.DateKey(now)
+	yesterday := today.AddDate(0, 0, -1)
+
+	// Usage from yesterday is still in memory when the day rolls over and the flush fails
+	tracker.mutex.Lock()
+	tracker.currentDate = yesterday
+	tracker.usageData["Editor"] = 120
+	tracker.mutex.Unlock()
+
+	mockRepo.SetFailureModes(false, false, true, false)
+	tracker.persistCurrentData()
+
+	tracker.mutex.Lock()
+	tracker.usageData["Browser"] = 45
+	tracker.mutex.Unlock()
+	tracker.persistCurrentData()
+
+	mockRepo.SetFailureModes(false, false, false, false)
+	tracker.persistCurrentData()
+
+	yesterdayApps, _ := mockRepo.GetAppUsageByDate(ctx, yesterday)
+	if len(yesterdayApps) != 1 || yesterdayApps[0].Duration != 120 {
+		t.Errorf("yesterday's usage was not retried: %+v", yesterdayApps)
+	}
+
+	todayApps, _ := mockRepo.GetAppUsageByDate(ctx, today)
+	if len(todayApps) != 1 || todayApps[0].Duration != 45 {
+		t.Errorf("today's usage was not retried: %+v", todayApps)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"qwin/internal/platform"
+	"qwin/internal/types"
+)
+
+// SetPrivacyRedactor sets how identifying detail is removed from what the tracker records.
+// Cached names, executable paths and titles are redacted right away, which moves usage
+// tracked under them to the redacted app; values already stored keep theirs until the
+// history is redacted. A nil redactor keeps everything.
+func (st *ScreenTimeTracker) SetPrivacyRedactor(redactor *types.PrivacyRedactor) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.privacy = redactor
+
+	redactKey := func(key string) string {
+		name, exePath := splitAppKey(key)
+		return appKey(redactor.Name(name), redactor.ExePath(exePath))
+	}
+
+	// Apps whose names or paths become the same are tracked as one from here on
+	appInfoCache := make(map[string]*platform.AppInfo, len(st.appInfoCache))
+	for key, info := range st.appInfoCache {
+		appInfoCache[redactKey(key)] = redactAppInfo(redactor, info)
+	}
+	st.appInfoCache = appInfoCache
+
+	persistedMetadata := make(map[string]platform.AppInfo, len(st.persistedMetadata))
+	for key, info := range st.persistedMetadata {
+		persistedMetadata[redactKey(key)] = info
+	}
+	st.persistedMetadata = persistedMetadata
+
+	st.usageData = redactDurationKeys(st.usageData, redactKey)
+	st.persistedUsage = redactDurationKeys(st.persistedUsage, redactKey)
+	if st.lastApp != "" {
+		st.lastApp = redactKey(st.lastApp)
+	}
+}
+
+// redactDurationKeys returns durations keyed by the redacted app keys, adding up apps
+// whose keys become the same
+func redactDurationKeys(durations map[string]int64, redactKey func(string) string) map[string]int64 {
+	redacted := make(map[string]int64, len(durations))
+	for key, duration := range durations {
+		redacted[redactKey(key)] += duration
+	}
+	return redacted
+}
+
+// redactAppInfo returns a copy of info with the redactor applied, leaving info untouched
+func redactAppInfo(redactor *types.PrivacyRedactor, info *platform.AppInfo) *platform.AppInfo {
+	if redactor == nil {
+		return info
+	}
+	redacted := *info
+	redacted.Name = redactor.Name(info.Name)
+	redacted.ExePath = redactor.ExePath(info.ExePath)
+	redacted.WindowTitle = redactor.Title(info.WindowTitle)
+	return &redacted
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/platform"
+	"qwin/internal/types"
+)
+
+func TestScreenTimeTracker_PrivacyRedactor(t *testing.T) {
+	mockRepo := NewMockRepository()
+	windowAPI := &MockWindowAPI{}
+	tracker := NewScreenTimeTrackerWithWindowAPI(mockRepo, logging.NewDefaultLogger(), windowAPI)
+
+	// Ignore rules still see the original title
+	if err := tracker.SetIgnoreRules([]types.IgnoreRule{
+		{MatchType: types.IgnoreMatchTitle, Pattern: "Private Browsing", Mode: types.IgnoreModeIgnore, Enabled: true},
+	}); err != nil {
+		t.Fatalf("SetIgnoreRules() error = %v", err)
+	}
+	tracker.SetPrivacyRedactor(types.NewPrivacyRedactor(types.PrivacyPolicy{
+		TitleRedaction:  types.TitleRedactionHash,
+		StripURLQueries: true,
+		DropUserPaths:   true,
+	}, "alice"))
+
+	start := time.Now()
+	windowAPI.SetCurrentApp(&platform.AppInfo{
+		Name:        "Code",
+		ExePath:     `C:\Users\alice\AppData\Local\Programs\Code\Code.exe`,
+		WindowTitle: "secret-project - Code",
+	})
+	tracker.trackCurrentApp()
+
+	tracker.mutex.RLock()
+	cached := *tracker.appInfoCache[appKey("Code", "Code.exe")]
+	tracker.mutex.RUnlock()
+	if cached.ExePath != "Code.exe" {
+		t.Errorf("cached ExePath = %q, want the file name only", cached.ExePath)
+	}
+	if cached.WindowTitle == "secret-project - Code" {
+		t.Error("cached window title was not redacted")
+	}
+
+	events, _ := mockRepo.GetFocusEvents(context.Background(), start.Add(-time.Minute), time.Now().Add(time.Minute))
+	if len(events) == 0 || events[len(events)-1].ExePath != "Code.exe" {
+		t.Errorf("focus events = %+v, want the switch recorded with the redacted path", events)
+	}
+
+	// Names carrying a URL are tracked without its query string
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "https://docs.example.com/page?session=42"})
+	tracker.trackCurrentApp()
+	tracker.mutex.RLock()
+	lastApp := tracker.lastApp
+	tracker.mutex.RUnlock()
+	if lastApp != "https://docs.example.com/page" {
+		t.Errorf("tracked app key = %q, want the URL without its query", lastApp)
+	}
+
+	windowAPI.SetCurrentApp(&platform.AppInfo{Name: "Firefox", WindowTitle: "Private Browsing"})
+	tracker.trackCurrentApp()
+	tracker.mutex.RLock()
+	mode := tracker.excludedMode
+	tracker.mutex.RUnlock()
+	if mode != types.IgnoreModeIgnore {
+		t.Errorf("excludedMode = %q, want the title rule to match before redaction", mode)
+	}
+}
+
+func TestScreenTimeTracker_PrivacyRedactorRekeysUsage(t *testing.T) {
+	tracker := NewScreenTimeTracker(NewMockRepository(), logging.NewDefaultLogger())
+
+	first := appKey("Code", `C:\Users\alice\a\Code.exe`)
+	second := appKey("Code", `C:\Users\alice\b\Code.exe`)
+	tracker.mutex.Lock()
+	tracker.usageData[first] = 30
+	tracker.usageData[second] = 20
+	tracker.persistedUsage[first] = 10
+	tracker.appInfoCache[first] = &platform.AppInfo{Name: "Code", ExePath: `C:\Users\alice\a\Code.exe`}
+	tracker.lastApp = second
+	tracker.mutex.Unlock()
+
+	tracker.SetPrivacyRedactor(types.NewPrivacyRedactor(types.PrivacyPolicy{DropUserPaths: true}, "alice"))
+
+	redacted := appKey("Code", "Code.exe")
+	tracker.mutex.RLock()
+	defer tracker.mutex.RUnlock()
+	if len(tracker.usageData) != 1 || tracker.usageData[redacted] != 50 || tracker.persistedUsage[redacted] != 10 {
+		t.Errorf("usageData = %v, persistedUsage = %v, want both paths combined under the redacted one", tracker.usageData, tracker.persistedUsage)
+	}
+	if tracker.lastApp != redacted {
+		t.Errorf("lastApp = %q, want %q", tracker.lastApp, redacted)
+	}
+	if info := tracker.appInfoCache[redacted]; info == nil || info.ExePath != "Code.exe" {
+		t.Errorf("appInfoCache = %v, want the redacted path cached", tracker.appInfoCache)
+	}
+}
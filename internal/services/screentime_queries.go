@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"qwin/internal/infrastructure/errors"
+	"qwin/internal/repository"
 	"qwin/internal/types"
 )
 
@@ -18,7 +19,8 @@ func (st *ScreenTimeTracker) GetHistoricalUsage(days int) (map[string]*types.Usa
 	return st.repository.GetUsageHistory(ctx, days)
 }
 
-// GetUsageForDate retrieves usage data for a specific date
+// GetUsageForDate retrieves usage data for a specific date, including any manual or
+// imported time entries next to the tracked apps
 func (st *ScreenTimeTracker) GetUsageForDate(date time.Time) (*types.UsageData, error) {
 	if st.repository == nil {
 		return nil, errors.NewRepositoryError("GetUsageForDate", nil, errors.ErrCodeConnection)
@@ -26,6 +28,27 @@ func (st *ScreenTimeTracker) GetUsageForDate(date time.Time) (*types.UsageData,
 
 	ctx := context.Background()
 
+	usage, err := st.getTrackedUsageForDate(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeEntries, ok := st.repository.(repository.TimeEntryRepository); ok {
+		entries, err := timeEntries.GetTimeEntries(ctx, date, date)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			usage.AddTimeEntries(entries)
+			st.sortAppsByDuration(usage.Apps)
+		}
+	}
+
+	return usage, nil
+}
+
+// getTrackedUsageForDate retrieves the usage the tracker recorded on a date
+func (st *ScreenTimeTracker) getTrackedUsageForDate(ctx context.Context, date time.Time) (*types.UsageData, error) {
 	// Get daily usage summary
 	dailyUsage, err := st.repository.GetDailyUsage(ctx, date)
 	if err != nil {
@@ -86,7 +109,7 @@ func (st *ScreenTimeTracker) GetAppUsageHistory(appName string, days int) ([]typ
 	ctx := context.Background()
 
 	// Calculate inclusive date range that includes today (matches GetUsageHistory behavior)
-	endDate := time.Now()
+	endDate := st.DayBoundary().DateOf(time.Now())
 	startDate := endDate.AddDate(0, 0, -days+1)
 
 	// Get app usage data filtered by app name at the database level
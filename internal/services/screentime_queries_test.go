@@ -95,6 +95,51 @@ func TestScreenTimeTracker_GetUsageForDate(t *testing.T) {
 	}
 }
 
+func TestScreenTimeTracker_GetUsageForDateWithTimeEntries(t *testing.T) {
+	repo := setupSQLiteRepository(t)
+	ctx := context.Background()
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	if err := repo.SaveDailyUsage(ctx, date, &types.UsageData{TotalTime: 600}); err != nil {
+		t.Fatalf("SaveDailyUsage() error = %v", err)
+	}
+	if err := repo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Editor", Duration: 600}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	meeting := &types.TimeEntry{Date: date, AppName: "Meeting", Duration: 1800, Source: types.TimeSourceManual}
+	if err := repo.CreateTimeEntry(ctx, meeting); err != nil {
+		t.Fatalf("CreateTimeEntry() error = %v", err)
+	}
+
+	tracker := NewScreenTimeTracker(repo, logging.NewDefaultLogger())
+	usage, err := tracker.GetUsageForDate(date)
+	if err != nil {
+		t.Fatalf("GetUsageForDate() error = %v", err)
+	}
+	if usage.TotalTime != 2400 || len(usage.Apps) != 2 {
+		t.Fatalf("GetUsageForDate() = %+v, want tracked and manual time", usage)
+	}
+	if usage.Apps[0].Name != "Meeting" || usage.Apps[0].EntryID != meeting.ID {
+		t.Errorf("apps should be sorted by duration with the entry marked, got %+v", usage.Apps)
+	}
+	if usage.BySource[types.TimeSourceTracked] != 600 || usage.BySource[types.TimeSourceManual] != 1800 {
+		t.Errorf("BySource = %v", usage.BySource)
+	}
+
+	// A day with only a manual entry still shows it
+	other := date.AddDate(0, 0, 1)
+	if err := repo.CreateTimeEntry(ctx, &types.TimeEntry{Date: other, AppName: "Meeting", Duration: 900, Source: types.TimeSourceManual}); err != nil {
+		t.Fatalf("CreateTimeEntry() error = %v", err)
+	}
+	usage, err = tracker.GetUsageForDate(other)
+	if err != nil {
+		t.Fatalf("GetUsageForDate() error = %v", err)
+	}
+	if usage.TotalTime != 900 || usage.BySource[types.TimeSourceTracked] != 0 {
+		t.Errorf("GetUsageForDate() = %+v, want only manual time", usage)
+	}
+}
+
 func TestScreenTimeTracker_GetUsageForDateRange(t *testing.T) {
 	mockRepo := NewMockRepository()
 
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+// GetUsageSplitForDate divides a date's usage between the schedule's working hours and
+// the rest of the day
+func (st *ScreenTimeTracker) GetUsageSplitForDate(date time.Time, schedule types.WorkSchedule) (*types.UsageSplit, error) {
+	date = types.DateKey(date)
+	usage, err := st.GetUsageForDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	splits, err := st.splitUsage(context.Background(), date, date, map[string]*types.UsageData{
+		date.Format("2006-01-02"): usage,
+	}, schedule)
+	if err != nil {
+		return nil, err
+	}
+	return splits[date.Format("2006-01-02")], nil
+}
+
+// GetUsageSplitHistory divides the usage of each of the last days, keyed like
+// GetHistoricalUsage, between the schedule's working hours and the rest of the day
+func (st *ScreenTimeTracker) GetUsageSplitHistory(days int, schedule types.WorkSchedule) (map[string]*types.UsageSplit, error) {
+	history, err := st.GetHistoricalUsage(days)
+	if err != nil {
+		return nil, err
+	}
+
+	end := st.DayBoundary().DateOf(time.Now())
+	return st.splitUsage(context.Background(), end.AddDate(0, 0, -days+1), end, history, schedule)
+}
+
+// splitUsage divides the stored usage of the dates from start to end between working
+// hours and the rest. The focus event log tells when usage happened; tracked time it can't
+// place, e.g. from before the log was kept or from other devices, counts as out of hours.
+// Time entries have no time of day and are kept apart as unplaced.
+func (st *ScreenTimeTracker) splitUsage(ctx context.Context, start, end time.Time, usage map[string]*types.UsageData, schedule types.WorkSchedule) (map[string]*types.UsageSplit, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, errors.NewRepositoryError("splitUsage", err, errors.ErrCodeValidation)
+	}
+
+	boundary := st.DayBoundary()
+	loc := boundary.Location()
+
+	inHours := make(map[string]*replayedDay)
+	if st.eventLog != nil && !schedule.IsEmpty() {
+		periodStart, periodEnd := boundary.Start(start), boundary.End(end)
+		events, err := st.eventLog.GetFocusEvents(ctx, periodStart.Add(-focusReplayLookback), periodEnd)
+		if err != nil {
+			return nil, err
+		}
+		walkFocusIntervals(events, periodStart, func(from, to time.Time, state focusReplayState) {
+			if to.After(periodEnd) {
+				to = periodEnd
+			}
+			attributeWorkingTime(inHours, from, to, state, boundary, schedule)
+		})
+	}
+
+	splits := make(map[string]*types.UsageSplit, len(usage))
+	for key, data := range usage {
+		date, err := time.Parse("2006-01-02", key)
+		if err != nil || date.Before(start) || date.After(end) {
+			continue
+		}
+
+		split := &types.UsageSplit{
+			Date:                 date,
+			Holiday:              schedule.IsHoliday(date),
+			ScheduledTime:        int64(schedule.WorkingTime(boundary.Start(date).In(loc), boundary.End(date)).Seconds()),
+			InHours:              types.UsageData{Apps: []types.AppUsage{}},
+			OutOfHours:           types.UsageData{Apps: []types.AppUsage{}},
+			Unplaced:             types.UsageData{Apps: []types.AppUsage{}},
+			InHoursByCategory:    make(map[string]int64),
+			OutOfHoursByCategory: make(map[string]int64),
+		}
+
+		// Only tracked rows are split; an app's replayed in-hours time is shared out across
+		// its rows so it is counted once however many rows carry the name
+		tracked := data.TotalTime
+		remaining := make(map[string]int64)
+		day := inHours[key]
+		inHoursByName := make(map[string]float64)
+		if day != nil {
+			for replayedKey, seconds := range day.apps {
+				name, _ := splitAppKey(replayedKey)
+				inHoursByName[name] += seconds
+			}
+		}
+		for _, app := range data.Apps {
+			if app.Source != "" && app.Source != types.TimeSourceTracked {
+				split.Unplaced.Apps = append(split.Unplaced.Apps, app)
+				split.Unplaced.TotalTime += app.Duration
+				tracked -= app.Duration
+				continue
+			}
+			if _, seen := remaining[app.Name]; !seen && day != nil {
+				remaining[app.Name] = int64(math.Round(inHoursByName[app.Name]))
+			}
+		}
+
+		if day != nil {
+			split.InHours.TotalTime = clampSeconds(day.totalTime, tracked)
+		}
+		split.OutOfHours.TotalTime = tracked - split.InHours.TotalTime
+
+		for _, app := range data.Apps {
+			if app.Source != "" && app.Source != types.TimeSourceTracked {
+				continue
+			}
+			in := clampSeconds(float64(remaining[app.Name]), app.Duration)
+			remaining[app.Name] -= in
+			category := types.CategorizeApp(app.Name)
+			if in > 0 {
+				inApp := app
+				inApp.Duration = in
+				split.InHours.Apps = append(split.InHours.Apps, inApp)
+				split.InHoursByCategory[category] += in
+			}
+			if out := app.Duration - in; out > 0 {
+				outApp := app
+				outApp.Duration = out
+				split.OutOfHours.Apps = append(split.OutOfHours.Apps, outApp)
+				split.OutOfHoursByCategory[category] += out
+			}
+		}
+		st.sortAppsByDuration(split.InHours.Apps)
+		st.sortAppsByDuration(split.OutOfHours.Apps)
+		st.sortAppsByDuration(split.Unplaced.Apps)
+
+		splits[key] = split
+	}
+	return splits, nil
+}
+
+// attributeWorkingTime adds the part of [start, end) within working hours to the session
+// total and to the current app, split by day
+func attributeWorkingTime(days map[string]*replayedDay, start, end time.Time, state focusReplayState, boundary types.DayBoundary, schedule types.WorkSchedule) {
+	for start.Before(end) {
+		date := boundary.DateOf(start)
+		segmentEnd := end
+		if dayEnd := boundary.End(date); segmentEnd.After(dayEnd) && dayEnd.After(start) {
+			segmentEnd = dayEnd
+		}
+
+		seconds := schedule.WorkingTime(start.In(boundary.Location()), segmentEnd).Seconds()
+		if seconds > 0 {
+			key := date.Format("2006-01-02")
+			day, exists := days[key]
+			if !exists {
+				day = &replayedDay{date: date, apps: make(map[string]float64)}
+				days[key] = day
+			}
+			day.totalTime += seconds
+			if state.currentApp != "" {
+				day.apps[appKey(state.currentApp, state.exePath)] += seconds
+			}
+		}
+
+		start = segmentEnd
+	}
+}
+
+// clampSeconds rounds replayed seconds, never exceeding the stored duration
+func clampSeconds(seconds float64, stored int64) int64 {
+	rounded := int64(math.Round(seconds))
+	if rounded > stored {
+		return stored
+	}
+	if rounded < 0 {
+		return 0
+	}
+	return rounded
+}
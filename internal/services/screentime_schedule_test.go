@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+func TestScreenTimeTracker_GetUsageSplitForDate(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+	boundary, err := types.NewDayBoundary(0, "UTC")
+	if err != nil {
+		t.Fatalf("NewDayBoundary() error = %v", err)
+	}
+	tracker.SetDayBoundary(boundary)
+	ctx := context.Background()
+
+	// 2024-05-06 is a Monday; work runs 09:00-17:00
+	date := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	schedule := types.WorkSchedule{Blocks: []types.WorkBlock{{Weekday: time.Monday, Start: "09:00", End: "17:00"}}}
+
+	// Slack from 08:30 to 09:30 and Code from 16:30 to 17:30; 10 more minutes of Code
+	// were synced from another device and aren't in the event log
+	mockRepo.SaveDailyUsage(ctx, date, &types.UsageData{TotalTime: 7800})
+	mockRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Slack", Duration: 3600, Date: date})
+	mockRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 4200, Date: date})
+	for _, event := range []types.FocusEvent{
+		{Type: types.FocusEventTrackingStarted, OccurredAt: date.Add(8*time.Hour + 30*time.Minute)},
+		{Type: types.FocusEventAppSwitched, AppName: "Slack", OccurredAt: date.Add(8*time.Hour + 30*time.Minute)},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: date.Add(9*time.Hour + 30*time.Minute)},
+		{Type: types.FocusEventTrackingStarted, OccurredAt: date.Add(16*time.Hour + 30*time.Minute)},
+		{Type: types.FocusEventAppSwitched, AppName: "Code", OccurredAt: date.Add(16*time.Hour + 30*time.Minute)},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: date.Add(17*time.Hour + 30*time.Minute)},
+	} {
+		event := event
+		mockRepo.AppendFocusEvent(ctx, &event)
+	}
+
+	split, err := tracker.GetUsageSplitForDate(date, schedule)
+	if err != nil {
+		t.Fatalf("GetUsageSplitForDate() error = %v", err)
+	}
+	if split.ScheduledTime != 8*3600 || split.Holiday {
+		t.Errorf("ScheduledTime = %d, Holiday = %v, want 8 working hours", split.ScheduledTime, split.Holiday)
+	}
+	if split.InHours.TotalTime != 3600 || split.OutOfHours.TotalTime != 4200 {
+		t.Errorf("totals = %d in hours, %d out of hours, want 3600 and 4200", split.InHours.TotalTime, split.OutOfHours.TotalTime)
+	}
+	if got := split.InHoursByCategory[types.CategoryCommunication]; got != 1800 {
+		t.Errorf("communication in hours = %d, want 1800", got)
+	}
+	if got := split.OutOfHoursByCategory[types.CategoryDevelopment]; got != 2400 {
+		t.Errorf("development out of hours = %d, want 2400 including the synced time", got)
+	}
+
+	// On a holiday nothing is in hours
+	schedule.Holidays = []string{"2024-05-06"}
+	split, err = tracker.GetUsageSplitForDate(date, schedule)
+	if err != nil {
+		t.Fatalf("GetUsageSplitForDate() error = %v", err)
+	}
+	if !split.Holiday || split.ScheduledTime != 0 || split.InHours.TotalTime != 0 || split.OutOfHours.TotalTime != 7800 {
+		t.Errorf("holiday split = %+v, want everything out of hours", split)
+	}
+
+	if _, err := tracker.GetUsageSplitForDate(date, types.WorkSchedule{Blocks: []types.WorkBlock{{Start: "17:00", End: "09:00"}}}); err == nil {
+		t.Error("Expected an invalid schedule to be rejected")
+	}
+}
+
+func TestScreenTimeTracker_SplitUsageLeavesTimeEntriesUnplaced(t *testing.T) {
+	mockRepo := NewMockRepository()
+	tracker := NewScreenTimeTracker(mockRepo, logging.NewDefaultLogger())
+	boundary, err := types.NewDayBoundary(0, "UTC")
+	if err != nil {
+		t.Fatalf("NewDayBoundary() error = %v", err)
+	}
+	tracker.SetDayBoundary(boundary)
+	ctx := context.Background()
+
+	date := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	schedule := types.WorkSchedule{Blocks: []types.WorkBlock{{Weekday: time.Monday, Start: "09:00", End: "17:00"}}}
+
+	// An hour of Code within working hours, plus a manual Code entry and a correction
+	for _, event := range []types.FocusEvent{
+		{Type: types.FocusEventTrackingStarted, OccurredAt: date.Add(10 * time.Hour)},
+		{Type: types.FocusEventAppSwitched, AppName: "Code", OccurredAt: date.Add(10 * time.Hour)},
+		{Type: types.FocusEventTrackingStopped, OccurredAt: date.Add(11 * time.Hour)},
+	} {
+		event := event
+		mockRepo.AppendFocusEvent(ctx, &event)
+	}
+	usage := &types.UsageData{TotalTime: 3600, Apps: []types.AppUsage{{Name: "Code", Duration: 3600, Date: date}}}
+	usage.AddTimeEntries([]types.TimeEntry{
+		{ID: 1, Date: date, AppName: "Code", Duration: 1800, Source: types.TimeSourceManual},
+		{ID: 2, Date: date, AppName: "Code", Duration: -600, Source: types.TimeSourceCorrection},
+	})
+
+	splits, err := tracker.splitUsage(ctx, date, date, map[string]*types.UsageData{"2024-05-06": usage}, schedule)
+	if err != nil {
+		t.Fatalf("splitUsage() error = %v", err)
+	}
+	split := splits["2024-05-06"]
+	if split.InHours.TotalTime != 3600 || split.OutOfHours.TotalTime != 0 {
+		t.Errorf("totals = %d in hours, %d out of hours, want only the tracked hour in hours",
+			split.InHours.TotalTime, split.OutOfHours.TotalTime)
+	}
+	if len(split.InHours.Apps) != 1 || split.InHours.Apps[0].Duration != 3600 {
+		t.Errorf("in-hours apps = %+v, want Code counted once", split.InHours.Apps)
+	}
+	if split.Unplaced.TotalTime != 1200 || len(split.Unplaced.Apps) != 2 {
+		t.Errorf("unplaced = %+v, want both time entries", split.Unplaced)
+	}
+	if got := split.InHoursByCategory[types.CategoryDevelopment]; got != 3600 {
+		t.Errorf("development in hours = %d, want 3600", got)
+	}
+}
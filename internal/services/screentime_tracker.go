@@ -1,8 +1,10 @@
 package services
 
 import (
+	"fmt"
 	"math"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,11 +14,18 @@ import (
 	"qwin/internal/types"
 )
 
-const defaultTopN = 5
+const (
+	defaultTopN = 5
+
+	// focusHeartbeatInterval bounds how much attribution a crash can lose
+	focusHeartbeatInterval = 10 * time.Second
+	// idleThreshold is how long without input before the user is considered idle
+	idleThreshold = 5 * time.Minute
+)
 
 // ScreenTimeTracker manages screen time tracking functionality
 type ScreenTimeTracker struct {
-	usageData          map[string]int64
+	usageData          map[string]int64 // seconds per app, keyed by appKey like every per-app map
 	appInfoCache       map[string]*platform.AppInfo
 	mutex              sync.RWMutex
 	lastApp            string
@@ -26,11 +35,39 @@ type ScreenTimeTracker struct {
 	stopTracking       chan struct{}
 	windowAPI          platform.WindowAPI
 	repository         repository.UsageRepository
+	eventLog           repository.FocusEventRepository // nil when the repository has no event log
 	logger             logging.Logger
 	persistTicker      *time.Ticker
 	lastPersist        time.Time
-	currentDate        time.Time
+	currentDate        time.Time         // date key of the usage day being tracked
+	dayBoundary        types.DayBoundary // decides which usage day a moment belongs to
 	persistenceEnabled bool
+	lastEventTime      time.Time // time of the last focus event written, used for heartbeats
+
+	// Pause and private mode; see screentime_pause.go
+	paused        bool
+	pausedAt      time.Time   // when the current pause began, or the current day started if later
+	pausedUntil   time.Time   // zero while paused indefinitely
+	pauseTimer    *time.Timer // resumes a timed pause
+	privateMode   bool
+	onStateChange func(types.TrackingState)
+
+	// Ignore rules; see screentime_ignore.go
+	ignoreRules   *types.IgnoreRuleSet
+	excludedMode  types.IgnoreMode // mode of the rule matching the focused app, empty when none does
+	excludedSince time.Time        // start of the excluded interval not yet accounted for
+
+	// Removes identifying detail before anything is recorded; see screentime_privacy.go
+	privacy *types.PrivacyRedactor
+
+	// Persisted baseline for currentDate; flushes write only the difference from it
+	persistMutex      sync.Mutex // serializes flushes, acquired before mutex
+	persistedUsage    map[string]int64
+	persistedMetadata map[string]platform.AppInfo
+	persistedTotal    int64
+
+	// Usage that couldn't be written yet; see screentime_writebehind.go. Guarded by persistMutex.
+	writeBehind writeBehindQueue
 }
 
 // NewScreenTimeTracker creates a new screen time tracker with repository dependency
@@ -44,13 +81,20 @@ func NewScreenTimeTrackerWithWindowAPI(repo repository.UsageRepository, logger l
 		logger = logging.NewDefaultLogger()
 	}
 
+	// The focus event log is optional; repositories that support it get crash recovery
+	eventLog, _ := repo.(repository.FocusEventRepository)
+
 	return &ScreenTimeTracker{
-		usageData:    make(map[string]int64),
-		appInfoCache: make(map[string]*platform.AppInfo),
+		usageData:         make(map[string]int64),
+		appInfoCache:      make(map[string]*platform.AppInfo),
+		persistedUsage:    make(map[string]int64),
+		persistedMetadata: make(map[string]platform.AppInfo),
+		writeBehind:       newWriteBehindQueue(),
 		// startTime will be set when Start() is called
 		// stopTracking channel will be created in Start()
 		windowAPI:  windowAPI,
 		repository: repo,
+		eventLog:   eventLog,
 		logger:     logger,
 		// currentDate is initialized in Start()
 		persistenceEnabled: true, // Default to enabled
@@ -71,6 +115,11 @@ func (st *ScreenTimeTracker) Start() {
 	if st.startTime.IsZero() {
 		st.startTime = now
 	}
+	// A pause restored before starting covers the session from its first moment
+	startPaused := st.paused
+	if startPaused && st.pausedAt.Before(now) {
+		st.pausedAt = now
+	}
 
 	// Create stop tracking channel for this session
 	st.stopTracking = make(chan struct{})
@@ -79,15 +128,26 @@ func (st *ScreenTimeTracker) Start() {
 	st.running = true
 	st.mutex.Unlock()
 
-	// Initialize current date to today's midnight
-	nowMidnight := time.Now()
+	// Initialize current date to today's date key
 	st.mutex.Lock()
-	st.currentDate = time.Date(nowMidnight.Year(), nowMidnight.Month(), nowMidnight.Day(), 0, 0, 0, 0, nowMidnight.Location())
+	st.currentDate = st.dayBoundary.DateOf(time.Now())
 	st.mutex.Unlock()
 
+	// Write usage journaled while the database was unavailable before loading today's totals
+	if st.hasJournaledWrites() {
+		st.persistCurrentData()
+	}
+
 	// Load existing data for today
 	st.loadTodaysData()
 
+	// Open a new session in the focus event log after any unflushed events were reconciled
+	startedAt := time.Now()
+	st.recordFocusEvent(types.FocusEventTrackingStarted, "", "", startedAt)
+	if startPaused {
+		st.recordFocusEvent(types.FocusEventPaused, "", "", startedAt)
+	}
+
 	// Start tracking loop
 	go st.trackingLoop()
 
@@ -122,19 +182,29 @@ func (st *ScreenTimeTracker) Stop() {
 	}
 
 	// Attribute any final elapsed time for the last active app
+	now := time.Now()
 	st.mutex.Lock()
+	st.leaveExcludedLocked(now)
 	if st.lastApp != "" && !st.lastTime.IsZero() {
-		elapsed := time.Since(st.lastTime).Seconds()
+		elapsed := now.Sub(st.lastTime).Seconds()
 		if elapsed > 0 {
 			st.usageData[st.lastApp] += int64(math.Round(elapsed))
 		}
+		// Close the attribution so a later Start() doesn't credit the stopped period
+		st.lastTime = now
+		st.lastApp = ""
 	}
 	st.mutex.Unlock()
 
+	st.recordFocusEvent(types.FocusEventTrackingStopped, "", "", now)
+
 	// Persist final data once (only if tracking was started)
 	if wasStarted {
 		st.persistCurrentData()
 	}
+
+	// Whatever still couldn't be written is kept on disk for the next run
+	st.spillPendingWrites()
 }
 
 // trackingLoop runs the main tracking loop
@@ -151,6 +221,7 @@ func (st *ScreenTimeTracker) trackingLoop() {
 		select {
 		case <-ticker.C:
 			st.trackCurrentApp()
+			st.recordHeartbeatIfDue(time.Now())
 		case <-stopCh:
 			return
 		}
@@ -159,6 +230,18 @@ func (st *ScreenTimeTracker) trackingLoop() {
 
 // trackCurrentApp tracks the currently active application
 func (st *ScreenTimeTracker) trackCurrentApp() {
+	if st.IsPaused() {
+		return
+	}
+
+	// Stop attributing time while the user is away, when the platform can tell
+	if monitor, ok := st.windowAPI.(platform.ActivityMonitor); ok {
+		if eventType, since := detectInactivity(monitor, time.Now()); eventType != "" {
+			st.markInactive(eventType, since)
+			return
+		}
+	}
+
 	appInfo := st.windowAPI.GetCurrentAppInfo()
 	if appInfo == nil || appInfo.Name == "" {
 		return
@@ -167,11 +250,43 @@ func (st *ScreenTimeTracker) trackCurrentApp() {
 	now := time.Now()
 
 	st.mutex.Lock()
-	defer st.mutex.Unlock()
 
-	// Cache app info if not already cached
-	if _, exists := st.appInfoCache[appInfo.Name]; !exists {
-		st.appInfoCache[appInfo.Name] = appInfo
+	// Nothing is attributed while paused; Resume restarts attribution from scratch
+	if st.paused {
+		st.mutex.Unlock()
+		return
+	}
+
+	// Apps matching an ignore rule are never attributed
+	if mode, matched := st.ignoreRules.Match(types.IgnoreTarget{
+		Name:        appInfo.Name,
+		ExePath:     appInfo.ExePath,
+		WindowTitle: appInfo.WindowTitle,
+	}); matched {
+		entered := st.enterExcludedLocked(mode, now)
+		st.mutex.Unlock()
+
+		if entered {
+			st.recordFocusEvent(excludedEventType(mode), "", "", now)
+		}
+		return
+	}
+	st.leaveExcludedLocked(now)
+
+	// Private mode records time without revealing which application had focus; otherwise
+	// the privacy policy applies, after ignore rules have seen the original values
+	if st.privateMode {
+		appInfo = &platform.AppInfo{Name: types.PrivateAppName}
+	} else {
+		appInfo = redactAppInfo(st.privacy, appInfo)
+	}
+
+	// Programs sharing a name are told apart by their executable path
+	key := appKey(appInfo.Name, appInfo.ExePath)
+
+	// Cache app info, refreshing it when the icon or executable path changes
+	if cached, exists := st.appInfoCache[key]; !exists || appInfoChanged(cached, appInfo) {
+		st.appInfoCache[key] = appInfo
 	}
 
 	// Attribute elapsed time to the previously active app, if any
@@ -182,9 +297,79 @@ func (st *ScreenTimeTracker) trackCurrentApp() {
 		}
 	}
 
+	switched := st.lastApp != key
+
 	// Set current app as the new active app
-	st.lastApp = appInfo.Name
+	st.lastApp = key
 	st.lastTime = now
+	st.mutex.Unlock()
+
+	if switched {
+		st.recordFocusEvent(types.FocusEventAppSwitched, appInfo.Name, appInfo.ExePath, now)
+	}
+}
+
+// appInfoChanged reports whether current carries metadata that differs from cached
+// Empty values are ignored so a failed icon extraction doesn't clear a known icon
+func appInfoChanged(cached, current *platform.AppInfo) bool {
+	return (current.IconPath != "" && current.IconPath != cached.IconPath) ||
+		(current.ExePath != "" && current.ExePath != cached.ExePath)
+}
+
+// appKey returns the key an app's usage is tracked under: its name, followed by its
+// executable path when known, so usage is attributed to the application the path belongs to
+func appKey(name, exePath string) string {
+	if exePath == "" {
+		return name
+	}
+	return name + "\x00" + exePath
+}
+
+// splitAppKey returns the name and executable path an app key was made of
+func splitAppKey(key string) (name, exePath string) {
+	name, exePath, _ = strings.Cut(key, "\x00")
+	return name, exePath
+}
+
+// detectInactivity reports whether the session is locked or idle and since when
+func detectInactivity(monitor platform.ActivityMonitor, now time.Time) (types.FocusEventType, time.Time) {
+	if monitor.IsSessionLocked() {
+		return types.FocusEventLocked, now
+	}
+
+	if idle := monitor.IdleDuration(); idle >= idleThreshold {
+		return types.FocusEventIdleStarted, now.Add(-idle)
+	}
+
+	return "", time.Time{}
+}
+
+// markInactive ends attribution for the active app when the user goes idle or locks the session
+func (st *ScreenTimeTracker) markInactive(eventType types.FocusEventType, since time.Time) {
+	st.mutex.Lock()
+	if st.lastApp == "" {
+		// Already inactive, nothing to close
+		st.mutex.Unlock()
+		return
+	}
+
+	// Time up to the last tick has already been attributed while input was pending,
+	// so the inactive period starts no earlier than lastTime
+	at := since
+	if at.Before(st.lastTime) {
+		at = st.lastTime
+	}
+
+	elapsed := at.Sub(st.lastTime).Seconds()
+	if elapsed > 0 {
+		st.usageData[st.lastApp] += int64(math.Round(elapsed))
+	}
+
+	st.lastApp = ""
+	st.lastTime = at
+	st.mutex.Unlock()
+
+	st.recordFocusEvent(eventType, "", "", at)
 }
 
 // GetUsageData returns the current usage data
@@ -195,7 +380,7 @@ func (st *ScreenTimeTracker) GetUsageData() *types.UsageData {
 	// Calculate total time since start (only if tracking has been started)
 	var totalTime int64
 	if !st.startTime.IsZero() {
-		end := time.Now()
+		end := st.trackedUntilLocked(time.Now())
 		if !st.running && !st.lastTime.IsZero() && st.lastTime.After(st.startTime) {
 			end = st.lastTime
 		}
@@ -206,14 +391,16 @@ func (st *ScreenTimeTracker) GetUsageData() *types.UsageData {
 
 	// Convert map to sorted slice with cached app info
 	apps := make([]types.AppUsage, 0, len(st.usageData))
-	for name, duration := range st.usageData {
+	for key, duration := range st.usageData {
+		name, exePath := splitAppKey(key)
 		appUsage := types.AppUsage{
 			Name:     name,
 			Duration: duration,
+			ExePath:  exePath,
 		}
 
 		// Add cached app info if available
-		if cachedInfo, exists := st.appInfoCache[name]; exists {
+		if cachedInfo, exists := st.appInfoCache[key]; exists {
 			appUsage.IconPath = cachedInfo.IconPath
 			appUsage.ExePath = cachedInfo.ExePath
 		}
@@ -235,6 +422,19 @@ func (st *ScreenTimeTracker) GetUsageData() *types.UsageData {
 	}
 }
 
+// restartDayClockLocked sets the session clock so that elapsed already counts as tracked at now.
+// A pause or excluded interval in progress restarts at now too, so time before it isn't skipped twice.
+// Must be called with st.mutex held
+func (st *ScreenTimeTracker) restartDayClockLocked(now time.Time, elapsed time.Duration) {
+	st.startTime = now.Add(-elapsed)
+	if st.paused {
+		st.pausedAt = now
+	}
+	if st.excludedMode != "" {
+		st.excludedSince = now
+	}
+}
+
 // CurrentDate returns the current date being tracked
 func (st *ScreenTimeTracker) CurrentDate() time.Time {
 	st.mutex.RLock()
@@ -249,6 +449,14 @@ func (st *ScreenTimeTracker) IsRunning() bool {
 	return st.running
 }
 
+// PlatformBackend names how the tracker finds the focused application
+func (st *ScreenTimeTracker) PlatformBackend() string {
+	if describer, ok := st.windowAPI.(platform.BackendDescriber); ok {
+		return describer.Backend()
+	}
+	return fmt.Sprintf("%T", st.windowAPI)
+}
+
 // sortAppsByDuration sorts apps by duration in descending order
 func (st *ScreenTimeTracker) sortAppsByDuration(apps []types.AppUsage) {
 	sort.Slice(apps, func(i, j int) bool {
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+const (
+	// maxQueuedDays bounds how many days of unwritten usage are held in memory;
+	// older days are spilled to the journal
+	maxQueuedDays = 7
+	// spillAfterFailures is how many flushes in a row may fail before queued usage is
+	// spilled to the journal, so a crash during a long outage doesn't lose it
+	spillAfterFailures = 3
+)
+
+// writeBehindQueue holds usage deltas that couldn't be written to the database yet, at most
+// one per day, and the journal file they are spilled to when the database stays unavailable.
+// It is only accessed with the tracker's persistMutex held.
+type writeBehindQueue struct {
+	deltas      []*usageDelta // oldest first
+	failures    int           // flushes in a row that failed
+	journalPath string        // empty keeps queued usage in memory only
+	journaled   bool          // whether the journal holds usage not yet written
+	lastFlushed time.Time     // when a flush last succeeded
+	retryConfig *errors.RetryConfig
+}
+
+// newWriteBehindQueue creates an empty queue retrying flushes with the default backoff
+func newWriteBehindQueue() writeBehindQueue {
+	return writeBehindQueue{retryConfig: errors.DefaultRetryConfig()}
+}
+
+// journalEntry is a usage delta as written to the journal, one JSON object per line
+type journalEntry struct {
+	Date      time.Time        `json:"date"`
+	TotalTime int64            `json:"totalTime"`
+	Durations map[string]int64 `json:"durations,omitempty"` // keyed by app key; older journals by name
+	Metadata  []types.AppUsage `json:"metadata,omitempty"`
+	Until     time.Time        `json:"until,omitempty"` // where the focus event log replay resumes once written
+}
+
+// mergeUsageDeltas folds each delta into the one for the same date, if there is one
+func mergeUsageDeltas(into []*usageDelta, deltas ...*usageDelta) []*usageDelta {
+	for _, delta := range deltas {
+		if delta == nil || delta.isEmpty() {
+			continue
+		}
+
+		var existing *usageDelta
+		for _, queued := range into {
+			if queued.date.Equal(delta.date) {
+				existing = queued
+				break
+			}
+		}
+		if existing == nil {
+			existing = &usageDelta{date: delta.date, durations: make(map[string]int64)}
+			into = append(into, existing)
+		}
+
+		existing.totalTime += delta.totalTime
+		if delta.until.After(existing.until) {
+			existing.until = delta.until
+		}
+		for key, increment := range delta.durations {
+			existing.durations[key] += increment
+		}
+		// The newest metadata for an app replaces what was queued before
+		for _, app := range delta.metadata {
+			replaced := false
+			for i := range existing.metadata {
+				if appKey(existing.metadata[i].Name, existing.metadata[i].ExePath) == appKey(app.Name, app.ExePath) {
+					existing.metadata[i] = app
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				existing.metadata = append(existing.metadata, app)
+			}
+		}
+	}
+	return into
+}
+
+// spill appends deltas to the journal and syncs it to disk
+func (q *writeBehindQueue) spill(deltas []*usageDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	if q.journalPath == "" {
+		return fmt.Errorf("no write-behind journal configured")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.journalPath), 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	file, err := os.OpenFile(q.journalPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, delta := range deltas {
+		if err := encoder.Encode(journalEntry{
+			Date:      delta.date,
+			TotalTime: delta.totalTime,
+			Durations: delta.durations,
+			Metadata:  delta.metadata,
+			Until:     delta.until,
+		}); err != nil {
+			return fmt.Errorf("failed to write journal: %w", err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	q.journaled = true
+	return nil
+}
+
+// readJournal returns the deltas spilled to the journal, merged per day. Lines that don't
+// parse, such as one cut short by a crash mid-write, are skipped.
+func (q *writeBehindQueue) readJournal() ([]*usageDelta, int, error) {
+	if !q.journaled {
+		return nil, 0, nil
+	}
+
+	file, err := os.Open(q.journalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			q.journaled = false
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	var deltas []*usageDelta
+	skipped := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			skipped++
+			continue
+		}
+		deltas = mergeUsageDeltas(deltas, &usageDelta{
+			date:      types.DateKey(entry.Date),
+			totalTime: entry.TotalTime,
+			durations: entry.Durations,
+			metadata:  entry.Metadata,
+			until:     entry.Until,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return deltas, skipped, nil
+}
+
+// clearJournal removes the journal once everything in it has been written
+func (q *writeBehindQueue) clearJournal() error {
+	if !q.journaled {
+		return nil
+	}
+	if err := os.Remove(q.journalPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	q.journaled = false
+	return nil
+}
+
+// SetWriteBehindJournal sets the file usage is spilled to while the database is unavailable.
+// A journal left behind by an earlier run is written to the database when tracking starts.
+func (st *ScreenTimeTracker) SetWriteBehindJournal(path string) {
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+
+	st.writeBehind.journalPath = path
+	_, err := os.Stat(path)
+	st.writeBehind.journaled = path != "" && err == nil
+}
+
+// hasJournaledWrites reports whether the journal holds usage waiting to be written
+func (st *ScreenTimeTracker) hasJournaledWrites() bool {
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+	return st.writeBehind.journaled
+}
+
+// PendingWrites returns how many days of usage are waiting to be written to the database,
+// in memory or in the journal
+func (st *ScreenTimeTracker) PendingWrites() int {
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+
+	journaled, _, err := st.writeBehind.readJournal()
+	if err != nil {
+		st.logger.Warn("Failed to read write-behind journal", "path", st.writeBehind.journalPath, "error", err)
+	}
+	return len(mergeUsageDeltas(mergeUsageDeltas(nil, st.writeBehind.deltas...), journaled...))
+}
+
+// PersistenceStatus reports when usage was last written to the database and how much
+// tracked usage is still waiting to be written
+func (st *ScreenTimeTracker) PersistenceStatus() types.PersistenceStatus {
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+
+	journaled, _, err := st.writeBehind.readJournal()
+	if err != nil {
+		st.logger.Warn("Failed to read write-behind journal", "path", st.writeBehind.journalPath, "error", err)
+	}
+	queued := mergeUsageDeltas(mergeUsageDeltas(nil, st.writeBehind.deltas...), journaled...)
+
+	status := types.PersistenceStatus{PendingDays: len(queued)}
+	if !st.writeBehind.lastFlushed.IsZero() {
+		lastFlushed := st.writeBehind.lastFlushed
+		status.LastPersistedAt = &lastFlushed
+	}
+	for _, delta := range queued {
+		for _, seconds := range delta.durations {
+			status.PendingSeconds += seconds
+		}
+	}
+
+	// Add what was tracked since the last flush collected the current day
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+	status.Enabled = st.persistenceEnabled
+	for key, duration := range st.usageData {
+		if unflushed := duration - st.persistedUsage[key]; unflushed > 0 {
+			status.PendingSeconds += unflushed
+		}
+	}
+	return status
+}
+
+// ResumePersistence re-enables persistence once the database is reachable again, writes
+// everything queued or journaled while it was away, and reloads the current day so usage
+// stored before the outage shows again
+func (st *ScreenTimeTracker) ResumePersistence() error {
+	st.SetPersistenceEnabled(true)
+	if err := st.flushCurrentData(); err != nil {
+		return err
+	}
+
+	st.mutex.RLock()
+	started := !st.currentDate.IsZero()
+	st.mutex.RUnlock()
+	if started {
+		st.ReloadCurrentDay()
+	}
+	return nil
+}
+
+// SaveAllPendingNow writes current usage along with everything queued or journaled, and
+// fails unless nothing is left waiting. Usage still waiting would otherwise be written to
+// whichever database the tracker is connected to next.
+func (st *ScreenTimeTracker) SaveAllPendingNow() error {
+	if err := st.SaveCurrentDataNow(); err != nil {
+		return err
+	}
+
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+
+	journaled, _, err := st.writeBehind.readJournal()
+	if err != nil {
+		return errors.NewRepositoryError("SaveAllPendingNow", err, errors.ErrCodeConnection)
+	}
+	if pending := len(mergeUsageDeltas(mergeUsageDeltas(nil, st.writeBehind.deltas...), journaled...)); pending > 0 {
+		return errors.NewRepositoryError("SaveAllPendingNow",
+			fmt.Errorf("usage for %d days is still waiting to be written", pending), errors.ErrCodeConnection)
+	}
+	return nil
+}
+
+// flushPending writes the queued and journaled deltas together with the latest ones,
+// retrying with the queue's backoff. What can't be written stays queued, and is spilled
+// to the journal once the database has failed too often or the queue is full.
+// Must be called with st.persistMutex held.
+func (st *ScreenTimeTracker) flushPending(ctx context.Context, latest *usageDelta, checkpoint *types.FocusEvent, enabled bool) error {
+	queue := &st.writeBehind
+	logger := logging.FromContext(ctx, st.logger)
+	inMemory := mergeUsageDeltas(queue.deltas, latest)
+	queue.deltas = nil
+
+	var err error
+	if enabled {
+		var journaled []*usageDelta
+		var skipped int
+		journaled, skipped, err = queue.readJournal()
+		if err != nil {
+			// An unreadable journal is left for a later attempt; memory can still be written
+			logger.Warn("Failed to read write-behind journal", "path", queue.journalPath, "error", err)
+			journaled = nil
+		} else if skipped > 0 {
+			logger.Warn("Skipped unreadable write-behind journal entries", "path", queue.journalPath, "count", skipped)
+		}
+
+		pending := append(append([]*usageDelta(nil), inMemory...), journaled...)
+		checkpoint = latestCheckpoint(checkpoint, pending)
+		err = errors.WithRetryContext(ctx, queue.retryConfig, func() error {
+			return st.flushUsageDeltas(ctx, pending, checkpoint)
+		}, "persistCurrentData")
+
+		if err == nil {
+			queue.failures = 0
+			queue.lastFlushed = time.Now()
+			if len(journaled) > 0 {
+				if clearErr := queue.clearJournal(); clearErr != nil {
+					logger.Error("Failed to remove replayed write-behind journal", "path", queue.journalPath, "error", clearErr)
+				} else {
+					logger.Info("Replayed write-behind journal", "days", len(journaled))
+				}
+			}
+			return nil
+		}
+		if errors.IsUnavailable(err) {
+			// Storage has been failing and the repository isn't calling it for now
+			logger.Debug("Storage unavailable, keeping usage queued", "days", len(pending))
+		} else {
+			logger.Error("Failed to persist usage delta", "days", len(pending), "error", err)
+		}
+	} else {
+		err = errors.NewRepositoryError("persistCurrentData", fmt.Errorf("persistence is disabled"), errors.ErrCodeConnection)
+	}
+
+	queue.failures++
+	queue.deltas = inMemory
+
+	// Keep memory bounded, and get usage onto disk once the outage looks like it will last
+	var spill []*usageDelta
+	switch {
+	case queue.journalPath != "" && (!enabled || queue.failures >= spillAfterFailures):
+		spill, queue.deltas = queue.deltas, nil
+	case len(queue.deltas) > maxQueuedDays:
+		overflow := len(queue.deltas) - maxQueuedDays
+		spill, queue.deltas = queue.deltas[:overflow], queue.deltas[overflow:]
+	}
+	if len(spill) == 0 {
+		return err
+	}
+
+	if queue.journalPath != "" {
+		spillErr := queue.spill(spill)
+		if spillErr == nil {
+			return err
+		}
+		logger.Error("Failed to spill usage to the write-behind journal", "path", queue.journalPath, "error", spillErr)
+
+		// Keep what fits in memory and try the journal again on the next flush
+		queue.deltas = mergeUsageDeltas(spill, queue.deltas...)
+		if len(queue.deltas) <= maxQueuedDays {
+			return err
+		}
+		overflow := len(queue.deltas) - maxQueuedDays
+		spill, queue.deltas = queue.deltas[:overflow], queue.deltas[overflow:]
+	}
+	logger.Error("Write-behind queue is full, dropping the oldest unwritten usage", "days", len(spill))
+	return err
+}
+
+// latestCheckpoint returns the checkpoint to write with deltas: the given one, or one at the
+// latest moment the deltas run up to when that is later. Usage journaled before a restart
+// covers time the focus event log also holds, so writing it without moving the checkpoint
+// would have the log replay that time again.
+func latestCheckpoint(checkpoint *types.FocusEvent, deltas []*usageDelta) *types.FocusEvent {
+	var until time.Time
+	if checkpoint != nil {
+		until = checkpoint.OccurredAt
+	}
+	for _, delta := range deltas {
+		if delta.until.After(until) {
+			until = delta.until
+		}
+	}
+	if checkpoint != nil && until.Equal(checkpoint.OccurredAt) {
+		return checkpoint
+	}
+	return checkpointEvent(until)
+}
+
+// spillPendingWrites moves everything still queued into the journal, so it survives exit
+func (st *ScreenTimeTracker) spillPendingWrites() {
+	st.persistMutex.Lock()
+	defer st.persistMutex.Unlock()
+
+	queue := &st.writeBehind
+	if len(queue.deltas) == 0 || queue.journalPath == "" {
+		return
+	}
+	if err := queue.spill(queue.deltas); err != nil {
+		st.logger.Error("Failed to spill usage to the write-behind journal", "path", queue.journalPath, "error", err)
+		return
+	}
+	st.logger.Warn("Usage could not be written before exit and was kept in the journal", "path", queue.journalPath, "days", len(queue.deltas))
+	queue.deltas = nil
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+// newWriteBehindTracker creates a tracker on today's date that journals to a temporary file
+// and doesn't wait between retries
+func newWriteBehindTracker(t *testing.T, repo *MockRepository, journal string) *ScreenTimeTracker {
+	t.Helper()
+
+	tracker := NewScreenTimeTracker(repo, logging.NewDefaultLogger())
+	tracker.SetWriteBehindJournal(journal)
+	tracker.writeBehind.retryConfig = &errors.RetryConfig{MaxAttempts: 1}
+
+	tracker.mutex.Lock()
+	tracker.currentDate = types.DateKey(time.Now())
+	tracker.mutex.Unlock()
+	return tracker
+}
+
+// trackUsage adds seconds of usage for an app to the tracker's current day
+func trackUsage(tracker *ScreenTimeTracker, name string, seconds int64) {
+	tracker.mutex.Lock()
+	tracker.usageData[name] += seconds
+	tracker.mutex.Unlock()
+}
+
+// storedDuration returns an app's stored usage for a date
+func storedDuration(repo *MockRepository, date time.Time, name string) int64 {
+	apps, _ := repo.GetAppUsageByDate(context.Background(), date)
+	for _, app := range apps {
+		if app.Name == name {
+			return app.Duration
+		}
+	}
+	return 0
+}
+
+func TestWriteBehind_SpillsToJournalAndReplays(t *testing.T) {
+	repo := NewMockRepository()
+	journal := filepath.Join(t.TempDir(), "pending-usage.jsonl")
+	tracker := newWriteBehindTracker(t, repo, journal)
+	today := tracker.CurrentDate()
+
+	repo.SetFailureModes(false, false, true, false)
+	for i := 1; i <= spillAfterFailures; i++ {
+		trackUsage(tracker, "Editor", 60)
+		tracker.persistCurrentData()
+
+		_, err := os.Stat(journal)
+		if spilled := err == nil; spilled != (i == spillAfterFailures) {
+			t.Fatalf("after %d failed flushes journal exists = %v", i, spilled)
+		}
+	}
+	if len(tracker.writeBehind.deltas) != 0 {
+		t.Errorf("Expected spilled usage to leave memory, %d days still queued", len(tracker.writeBehind.deltas))
+	}
+	if got := tracker.PendingWrites(); got != 1 {
+		t.Errorf("PendingWrites() = %d, want 1 day", got)
+	}
+
+	// Usage keeps accumulating in memory while the journal holds the rest
+	trackUsage(tracker, "Browser", 30)
+	tracker.persistCurrentData()
+
+	repo.SetFailureModes(false, false, false, false)
+	if err := tracker.ResumePersistence(); err != nil {
+		t.Fatalf("ResumePersistence failed: %v", err)
+	}
+
+	if got := storedDuration(repo, today, "Editor"); got != 3*60 {
+		t.Errorf("Editor = %d, want %d", got, 3*60)
+	}
+	if got := storedDuration(repo, today, "Browser"); got != 30 {
+		t.Errorf("Browser = %d, want 30", got)
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Errorf("Expected the replayed journal to be removed, stat error = %v", err)
+	}
+	if got := tracker.PendingWrites(); got != 0 {
+		t.Errorf("PendingWrites() = %d after replay, want 0", got)
+	}
+
+	// Nothing is written twice on later flushes
+	tracker.persistCurrentData()
+	if got := storedDuration(repo, today, "Editor"); got != 3*60 {
+		t.Errorf("Editor = %d after another flush, want %d", got, 3*60)
+	}
+}
+
+func TestWriteBehind_PersistenceDisabledJournalsForNextRun(t *testing.T) {
+	repo := NewMockRepository()
+	journal := filepath.Join(t.TempDir(), "pending-usage.jsonl")
+
+	tracker := newWriteBehindTracker(t, repo, journal)
+	today := tracker.CurrentDate()
+	tracker.SetPersistenceEnabled(false)
+
+	trackUsage(tracker, "Editor", 90)
+	tracker.persistCurrentData()
+
+	if _, _, batch, tx, _, _ := repo.GetCallCounts(); batch != 0 || tx != 0 {
+		t.Fatalf("Expected no database writes while persistence is disabled, got %d batches and %d transactions", batch, tx)
+	}
+	if _, err := os.Stat(journal); err != nil {
+		t.Fatalf("Expected usage to be journaled while persistence is disabled: %v", err)
+	}
+
+	// A later run picks the journal up on its first flush
+	next := newWriteBehindTracker(t, repo, journal)
+	if !next.hasJournaledWrites() {
+		t.Fatal("Expected the next run to find the journal")
+	}
+	next.persistCurrentData()
+
+	if got := storedDuration(repo, today, "Editor"); got != 90 {
+		t.Errorf("Editor = %d, want 90", got)
+	}
+	if next.hasJournaledWrites() {
+		t.Error("Expected the journal to be cleared after replay")
+	}
+}
+
+func TestWriteBehind_JournalReplayMovesCheckpoint(t *testing.T) {
+	repo := NewMockRepository()
+	journal := filepath.Join(t.TempDir(), "pending-usage.jsonl")
+	ctx := context.Background()
+
+	// The log holds five minutes of Editor after the last checkpoint; then the database
+	// went away and those five minutes were journaled instead of written
+	start := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	until := start.Add(5 * time.Minute)
+	for _, event := range []types.FocusEvent{
+		{Type: types.FocusEventCheckpoint, OccurredAt: start},
+		{Type: types.FocusEventAppSwitched, AppName: "Editor", OccurredAt: start},
+		{Type: types.FocusEventHeartbeat, AppName: "Editor", OccurredAt: until},
+	} {
+		event := event
+		repo.AppendFocusEvent(ctx, &event)
+	}
+
+	tracker := newWriteBehindTracker(t, repo, journal)
+	today := tracker.CurrentDate()
+	tracker.mutex.Lock()
+	tracker.usageData["Editor"] = 300
+	tracker.lastApp = "Editor"
+	tracker.lastTime = until
+	tracker.mutex.Unlock()
+	tracker.SetPersistenceEnabled(false)
+	tracker.persistCurrentData()
+
+	// The next run writes the journal, then reconciles the event log, as Start does
+	next := newWriteBehindTracker(t, repo, journal)
+	next.persistCurrentData()
+	next.loadTodaysData()
+
+	if got := storedDuration(repo, today, "Editor"); got != 300 {
+		t.Errorf("Editor = %d, want 300 counted once from the journal", got)
+	}
+	checkpoint, err := repo.GetLatestFocusEvent(ctx, types.FocusEventCheckpoint)
+	if err != nil || checkpoint.OccurredAt.Before(until) {
+		t.Errorf("latest checkpoint = (%+v, %v), want one at the journal's end %s", checkpoint, err, until)
+	}
+}
+
+func TestWriteBehind_QueueIsBounded(t *testing.T) {
+	repo := NewMockRepository()
+	tracker := newWriteBehindTracker(t, repo, "")
+	today := tracker.CurrentDate()
+
+	// Queue more days than fit, without a journal to spill them to
+	tracker.persistMutex.Lock()
+	for day := maxQueuedDays; day >= 0; day-- {
+		tracker.writeBehind.deltas = mergeUsageDeltas(tracker.writeBehind.deltas, &usageDelta{
+			date:      today.AddDate(0, 0, -day-1),
+			durations: map[string]int64{"Editor": 60},
+		})
+	}
+	tracker.persistMutex.Unlock()
+
+	repo.SetFailureModes(false, false, true, false)
+	trackUsage(tracker, "Editor", 60)
+	tracker.persistCurrentData()
+
+	tracker.persistMutex.Lock()
+	queued := tracker.writeBehind.deltas
+	tracker.persistMutex.Unlock()
+
+	if len(queued) != maxQueuedDays {
+		t.Fatalf("queued %d days, want %d", len(queued), maxQueuedDays)
+	}
+	if oldest := queued[0].date; !oldest.Equal(today.AddDate(0, 0, -maxQueuedDays+1)) {
+		t.Errorf("oldest queued day = %s, want the oldest days dropped first", oldest.Format("2006-01-02"))
+	}
+	if newest := queued[len(queued)-1]; !newest.date.Equal(today) || newest.durations["Editor"] != 60 {
+		t.Errorf("newest queued day = %+v, want today's usage", newest)
+	}
+}
+
+func TestWriteBehind_ReadJournalSkipsTruncatedLines(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "pending-usage.jsonl")
+	date := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	content := `{"date":"2024-06-01T00:00:00Z","totalTime":60,"durations":{"Editor":60}}
+{"date":"2024-06-01T00:00:00Z","totalTime":30,"durations":{"Editor":20,"Browser":10},"metadata":[{"name":"Browser","exePath":"C:\\browser.exe"}]}
+{"date":"2024-06-02T00:00:00Z","totalTi`
+	if err := os.WriteFile(journal, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	queue := newWriteBehindQueue()
+	queue.journalPath = journal
+	queue.journaled = true
+
+	deltas, skipped, err := queue.readJournal()
+	if err != nil {
+		t.Fatalf("readJournal failed: %v", err)
+	}
+	if skipped != 1 || len(deltas) != 1 {
+		t.Fatalf("got %d days and %d skipped lines, want 1 and 1", len(deltas), skipped)
+	}
+
+	delta := deltas[0]
+	if !delta.date.Equal(date) || delta.totalTime != 90 || delta.durations["Editor"] != 80 || delta.durations["Browser"] != 10 {
+		t.Errorf("merged delta = %+v", delta)
+	}
+	if len(delta.metadata) != 1 || delta.metadata[0].ExePath != `C:\browser.exe` {
+		t.Errorf("metadata = %+v", delta.metadata)
+	}
+}
+
+func TestWriteBehind_PersistenceStatus(t *testing.T) {
+	repo := NewMockRepository()
+	journal := filepath.Join(t.TempDir(), "pending-usage.jsonl")
+	tracker := newWriteBehindTracker(t, repo, journal)
+
+	status := tracker.PersistenceStatus()
+	if !status.Enabled || status.LastPersistedAt != nil || status.PendingSeconds != 0 || status.PendingDays != 0 {
+		t.Fatalf("PersistenceStatus() before tracking = %+v", status)
+	}
+
+	trackUsage(tracker, "Editor", 60)
+	if status := tracker.PersistenceStatus(); status.PendingSeconds != 60 {
+		t.Errorf("PendingSeconds = %d before the first flush, want 60", status.PendingSeconds)
+	}
+
+	tracker.persistCurrentData()
+	status = tracker.PersistenceStatus()
+	if status.LastPersistedAt == nil || status.PendingSeconds != 0 {
+		t.Errorf("PersistenceStatus() after a flush = %+v, want a persist time and nothing pending", status)
+	}
+	lastPersisted := *status.LastPersistedAt
+
+	// Failed flushes keep the last success and count what is queued and journaled
+	repo.SetFailureModes(false, false, true, false)
+	for i := 0; i < spillAfterFailures; i++ {
+		trackUsage(tracker, "Editor", 30)
+		tracker.persistCurrentData()
+	}
+	trackUsage(tracker, "Browser", 15)
+
+	status = tracker.PersistenceStatus()
+	if status.PendingSeconds != spillAfterFailures*30+15 || status.PendingDays != 1 {
+		t.Errorf("PersistenceStatus() after failures = %+v, want %d seconds over 1 day", status, spillAfterFailures*30+15)
+	}
+	if status.LastPersistedAt == nil || !status.LastPersistedAt.Equal(lastPersisted) {
+		t.Errorf("LastPersistedAt = %v, want the last successful flush at %v", status.LastPersistedAt, lastPersisted)
+	}
+}
+
+func TestWriteBehind_SaveCurrentDataNowReportsFailedWrite(t *testing.T) {
+	repo := NewMockRepository()
+	journal := filepath.Join(t.TempDir(), "pending-usage.jsonl")
+	tracker := newWriteBehindTracker(t, repo, journal)
+
+	trackUsage(tracker, "Editor", 60)
+	repo.SetFailureModes(false, false, true, false)
+	if err := tracker.SaveCurrentDataNow(); err == nil {
+		t.Fatal("SaveCurrentDataNow() = nil after the repository write failed")
+	}
+	if status := tracker.PersistenceStatus(); status.PendingSeconds != 60 {
+		t.Errorf("PendingSeconds = %d after the failed save, want 60 kept queued", status.PendingSeconds)
+	}
+
+	repo.SetFailureModes(false, false, false, false)
+	if err := tracker.SaveCurrentDataNow(); err != nil {
+		t.Fatalf("SaveCurrentDataNow() after the repository recovered: %v", err)
+	}
+	if got := storedDuration(repo, tracker.CurrentDate(), "Editor"); got != 60 {
+		t.Errorf("stored Editor = %d, want 60", got)
+	}
+}
+
+func TestWriteBehind_SaveAllPendingNowRequiresEmptyQueue(t *testing.T) {
+	repo := NewMockRepository()
+	journal := filepath.Join(t.TempDir(), "pending-usage.jsonl")
+	tracker := newWriteBehindTracker(t, repo, journal)
+
+	// A failed flush leaves the usage queued, so a profile switch must not go ahead
+	trackUsage(tracker, "Editor", 60)
+	repo.SetFailureModes(false, false, true, false)
+	if err := tracker.SaveAllPendingNow(); err == nil {
+		t.Fatal("SaveAllPendingNow() = nil with the repository failing")
+	}
+	if tracker.PendingWrites() == 0 {
+		t.Fatal("usage from the failed flush is no longer queued")
+	}
+
+	repo.SetFailureModes(false, false, false, false)
+	if err := tracker.SaveAllPendingNow(); err != nil {
+		t.Fatalf("SaveAllPendingNow() after the repository recovered: %v", err)
+	}
+	if got := storedDuration(repo, tracker.CurrentDate(), "Editor"); got != 60 {
+		t.Errorf("stored Editor = %d, want 60", got)
+	}
+
+	// A journal that can't be read may still hold usage, even though the flush succeeded
+	unreadable := t.TempDir()
+	tracker.SetWriteBehindJournal(unreadable)
+	trackUsage(tracker, "Editor", 30)
+	if err := tracker.SaveAllPendingNow(); err == nil {
+		t.Error("SaveAllPendingNow() = nil with an unreadable journal")
+	}
+}
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/repository"
+	"qwin/internal/types"
+)
+
+const (
+	// serverSyncInterval is how often usage is synced with the server while running
+	serverSyncInterval = 5 * time.Minute
+	// serverSyncTimeout bounds a single periodic sync run
+	serverSyncTimeout = 2 * time.Minute
+	// serverRequestTimeout bounds one request to the server
+	serverRequestTimeout = 30 * time.Second
+)
+
+// ServerSync combines usage across devices through a qwin sync server. Local changes are
+// numbered and queued in an outbox file before they are uploaded, so they survive being
+// offline and restarts, and each upload has an ID derived from the entries it carries so
+// the server recognizes a retry.
+type ServerSync struct {
+	repo    repository.DeviceSyncRepository
+	baseURL *url.URL
+	token   string
+	outbox  string
+	device  types.SyncDevice
+	client  *http.Client
+	logger  logging.Logger
+
+	runMutex sync.Mutex // serializes sync runs
+
+	mutex   sync.Mutex
+	stopCh  chan struct{}
+	running bool
+}
+
+// NewServerSync creates a sync of the local device's usage with the server at serverURL,
+// queueing changes in the outbox file. The repository must support device sync.
+func NewServerSync(repo repository.UsageRepository, serverURL, token, outbox string, device types.SyncDevice, logger logging.Logger) (*ServerSync, error) {
+	devices, ok := repo.(repository.DeviceSyncRepository)
+	if !ok {
+		return nil, errors.NewRepositoryError("NewServerSync",
+			fmt.Errorf("repository does not support device sync"), errors.ErrCodeValidation)
+	}
+	baseURL, err := url.Parse(strings.TrimSpace(serverURL))
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return nil, errors.NewRepositoryError("NewServerSync",
+			fmt.Errorf("sync server URL %q is not an http or https URL", serverURL), errors.ErrCodeValidation)
+	}
+	if token == "" {
+		return nil, errors.NewRepositoryError("NewServerSync", fmt.Errorf("access token is empty"), errors.ErrCodeValidation)
+	}
+	if outbox == "" {
+		return nil, errors.NewRepositoryError("NewServerSync", fmt.Errorf("outbox path is empty"), errors.ErrCodeValidation)
+	}
+	if err := types.ValidateDeviceID(device.ID); err != nil {
+		return nil, errors.NewRepositoryError("NewServerSync", err, errors.ErrCodeValidation)
+	}
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+
+	device.Local = true
+	return &ServerSync{
+		repo:    devices,
+		baseURL: baseURL,
+		token:   token,
+		outbox:  outbox,
+		device:  device,
+		client:  &http.Client{Timeout: serverRequestTimeout},
+		logger:  logger,
+	}, nil
+}
+
+// URL returns the sync server's URL
+func (ss *ServerSync) URL() string {
+	return ss.baseURL.String()
+}
+
+// Start syncs right away and then periodically until Stop
+func (ss *ServerSync) Start() {
+	ss.mutex.Lock()
+	if ss.running {
+		ss.mutex.Unlock()
+		return
+	}
+	ss.running = true
+	ss.stopCh = make(chan struct{})
+	stopCh := ss.stopCh
+	ss.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(serverSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), serverSyncTimeout)
+			if _, err := ss.Sync(ctx); err != nil {
+				ss.logger.Error("Failed to sync usage with server", "server", ss.baseURL.Host, "error", err)
+			}
+			cancel()
+
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends periodic syncing
+func (ss *ServerSync) Stop() {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	if !ss.running {
+		return
+	}
+	ss.running = false
+	close(ss.stopCh)
+}
+
+// Sync queues local changes, uploads the queue and merges the other devices' change logs.
+// When the server can't be reached the changes stay queued and the result says so;
+// only local failures are returned as errors.
+func (ss *ServerSync) Sync(ctx context.Context) (*types.SyncResult, error) {
+	ss.runMutex.Lock()
+	defer ss.runMutex.Unlock()
+	start := time.Now()
+
+	result := &types.SyncResult{}
+
+	queued, err := ss.loadOutbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, reachErr := ss.listDevices(ctx)
+	if reachErr == nil {
+		// Entries this device uploaded that the database doesn't know about, e.g. after
+		// a restore from backup, must count as exported before anything new is numbered
+		if _, err := ss.pull(ctx, ss.device); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return result, nil
+		}
+	}
+
+	lastSeq, err := ss.localSeq(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// A device that hasn't numbered anything yet may have entries on the server from
+	// before it was reset, so it waits to see them before numbering new ones
+	if reachErr == nil || lastSeq > 0 {
+		pending, err := ss.queue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		queued = append(queued, pending...)
+	}
+
+	if reachErr != nil {
+		result.Queued = len(queued)
+		result.Errors = append(result.Errors, fmt.Sprintf("sync server unavailable, changes stay queued: %v", reachErr))
+		return result, nil
+	}
+
+	uploaded, uploadErr := ss.upload(ctx, queued, remote)
+	result.Exported = uploaded
+	remaining := queued[uploaded:]
+	result.Queued = len(remaining)
+	if err := ss.rewriteOutbox(remaining); err != nil {
+		return nil, err
+	}
+	if uploadErr != nil {
+		result.Errors = append(result.Errors, uploadErr.Error())
+	}
+
+	for _, device := range remote {
+		if device.ID == ss.device.ID || types.ValidateDeviceID(device.ID) != nil {
+			continue
+		}
+		applied, err := ss.pull(ctx, device)
+		result.Imported += applied
+		result.Devices++
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+
+	logging.LogOperation(ss.logger, "ServerSync", time.Since(start), map[string]interface{}{
+		"server":   ss.baseURL.Host,
+		"devices":  result.Devices,
+		"imported": result.Imported,
+		"exported": result.Exported,
+		"queued":   result.Queued,
+		"errors":   len(result.Errors),
+	})
+	return result, nil
+}
+
+// loadOutbox reads the queued entries and counts them as exported, which a run
+// interrupted after queueing them may not have done
+func (ss *ServerSync) loadOutbox(ctx context.Context) ([]types.SyncLogEntry, error) {
+	entries, complete, err := readSyncLog(ss.outbox)
+	if err != nil && !os.IsNotExist(err) {
+		ss.logger.Warn("Dropping unreadable end of sync outbox", "path", ss.outbox, "error", err)
+	}
+	// A half written entry was never counted as exported, so it can go
+	if info, err := os.Stat(ss.outbox); err == nil && info.Size() > complete {
+		if err := os.Truncate(ss.outbox, complete); err != nil {
+			return nil, errors.NewRepositoryErrorWithContext("ServerSync", err, errors.ClassifyError(err), map[string]string{
+				"path": ss.outbox,
+			})
+		}
+	}
+	if _, err := ss.repo.ApplySyncEntries(ctx, ss.device, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// queue numbers the local changes not exported yet, adds them to the outbox and counts
+// them as exported
+func (ss *ServerSync) queue(ctx context.Context) ([]types.SyncLogEntry, error) {
+	pending, err := ss.repo.PendingSyncEntries(ctx, ss.device)
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	if err := appendSyncLog(ss.outbox, pending); err != nil {
+		return nil, errors.NewRepositoryErrorWithContext("ServerSync", err, errors.ClassifyError(err), map[string]string{
+			"path": ss.outbox,
+		})
+	}
+	if _, err := ss.repo.ApplySyncEntries(ctx, ss.device, pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// rewriteOutbox replaces the outbox with entries, removing it when there are none
+func (ss *ServerSync) rewriteOutbox(entries []types.SyncLogEntry) error {
+	var err error
+	if len(entries) == 0 {
+		if err = os.Remove(ss.outbox); os.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		temp := ss.outbox + ".tmp"
+		os.Remove(temp)
+		if err = appendSyncLog(temp, entries); err == nil {
+			err = os.Rename(temp, ss.outbox)
+		}
+	}
+	if err != nil {
+		return errors.NewRepositoryErrorWithContext("ServerSync", err, errors.ClassifyError(err), map[string]string{
+			"path": ss.outbox,
+		})
+	}
+	return nil
+}
+
+// upload sends queued entries the server doesn't hold yet and returns how many queued
+// entries the server now holds
+func (ss *ServerSync) upload(ctx context.Context, queued []types.SyncLogEntry, remote []types.SyncDevice) (int, error) {
+	var serverSeq int64
+	for _, device := range remote {
+		if device.ID == ss.device.ID {
+			serverSeq = device.LastSeq
+		}
+	}
+
+	held := func() int {
+		n := 0
+		for n < len(queued) && queued[n].Seq <= serverSeq {
+			n++
+		}
+		return n
+	}
+
+	for {
+		sent := held()
+		if sent == len(queued) {
+			return sent, nil
+		}
+		batch := queued[sent:]
+		if len(batch) > types.MaxSyncUploadEntries {
+			batch = batch[:types.MaxSyncUploadEntries]
+		}
+		upload := types.SyncUpload{
+			UploadID: fmt.Sprintf("%s-%d-%d", ss.device.ID, batch[0].Seq, batch[len(batch)-1].Seq),
+			Entries:  batch,
+		}
+
+		var result types.SyncUploadResult
+		status, err := ss.do(ctx, http.MethodPost, ss.entriesPath(ss.device.ID), nil, upload, &result)
+		if status == http.StatusConflict {
+			// The server misses entries no longer queued, which only resetting sync recovers from
+			return sent, fmt.Errorf("sync server holds entries up to %d of this device, but the outbox starts at %d; reset sync to upload all usage again",
+				result.LastSeq, batch[0].Seq)
+		}
+		if err != nil {
+			return sent, err
+		}
+		if result.LastSeq <= serverSeq {
+			return sent, fmt.Errorf("sync server did not accept entries from %d", batch[0].Seq)
+		}
+		serverSeq = result.LastSeq
+	}
+}
+
+// pull merges the entries of a device's change log the database hasn't applied yet
+func (ss *ServerSync) pull(ctx context.Context, device types.SyncDevice) (int, error) {
+	device.Local = device.ID == ss.device.ID
+	applied := 0
+	for {
+		after, err := ss.deviceSeq(ctx, device.ID)
+		if err != nil {
+			return applied, err
+		}
+
+		var page types.SyncEntriesPage
+		query := url.Values{"after": {fmt.Sprint(after)}}
+		if _, err := ss.do(ctx, http.MethodGet, ss.entriesPath(device.ID), query, nil, &page); err != nil {
+			return applied, err
+		}
+		n, err := ss.repo.ApplySyncEntries(ctx, device, page.Entries)
+		applied += n
+		if err != nil {
+			return applied, err
+		}
+		if !page.More || n == 0 {
+			return applied, nil
+		}
+	}
+}
+
+// listDevices reads the devices the server holds change logs for
+func (ss *ServerSync) listDevices(ctx context.Context) ([]types.SyncDevice, error) {
+	var devices []types.SyncDevice
+	_, err := ss.do(ctx, http.MethodGet, "/v1/devices", nil, nil, &devices)
+	return devices, err
+}
+
+// localSeq returns the last entry the local device has numbered
+func (ss *ServerSync) localSeq(ctx context.Context) (int64, error) {
+	return ss.deviceSeq(ctx, ss.device.ID)
+}
+
+// deviceSeq returns the last entry applied from a device, 0 when none was
+func (ss *ServerSync) deviceSeq(ctx context.Context, deviceID string) (int64, error) {
+	devices, err := ss.repo.ListSyncDevices(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, device := range devices {
+		if device.ID == deviceID {
+			return device.LastSeq, nil
+		}
+	}
+	return 0, nil
+}
+
+func (ss *ServerSync) entriesPath(deviceID string) string {
+	return "/v1/devices/" + url.PathEscape(deviceID) + "/entries"
+}
+
+// do sends a request with an optional JSON body and decodes a JSON answer into out. An
+// answer other than 200 OK is an error, but a 409 Conflict body is still decoded.
+func (ss *ServerSync) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (int, error) {
+	endpoint := ss.baseURL.JoinPath(path)
+	endpoint.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, errors.NewRepositoryError("ServerSync", err, errors.ErrCodeValidation)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), reader)
+	if err != nil {
+		return 0, errors.NewRepositoryError("ServerSync", err, errors.ErrCodeValidation)
+	}
+	req.Header.Set("Authorization", "Bearer "+ss.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := ss.client.Do(req)
+	if err != nil {
+		return 0, errors.NewRepositoryError("ServerSync", err, errors.ErrCodeConnection)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusConflict {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, errors.NewRepositoryError("ServerSync", err, errors.ErrCodeConnection)
+		}
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, nil
+	}
+
+	var problem struct {
+		Error string `json:"error"`
+	}
+	if resp.StatusCode != http.StatusConflict {
+		json.NewDecoder(resp.Body).Decode(&problem)
+	}
+	code := errors.ErrCodeConnection
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		code = errors.ErrCodePermission
+	case http.StatusBadRequest:
+		code = errors.ErrCodeValidation
+	case http.StatusConflict:
+		code = errors.ErrCodeConstraint
+	}
+	return resp.StatusCode, errors.NewRepositoryErrorWithContext("ServerSync",
+		fmt.Errorf("%s %s: %s %s", method, path, resp.Status, problem.Error), code, map[string]string{
+			"server": ss.baseURL.Host,
+		})
+}
+
+// OutboxPath returns where a server sync queues changes for a data directory
+func OutboxPath(dataDir string) string {
+	return filepath.Join(dataDir, "sync-outbox.jsonl")
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwin/internal/syncserver"
+	"qwin/internal/types"
+)
+
+func setupSyncServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store, err := syncserver.OpenStore(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	handler, err := syncserver.NewServer(store, map[string]string{"secret": syncserver.AnyDevice}, nil)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServerSync_EndToEnd(t *testing.T) {
+	ctx := context.Background()
+	server := setupSyncServer(t)
+	date := types.DateKey(time.Now())
+
+	desktopRepo, laptopRepo := setupSQLiteRepository(t), setupSQLiteRepository(t)
+	desktopOutbox := filepath.Join(t.TempDir(), "outbox.jsonl")
+	desktopDevice := types.SyncDevice{ID: "desktop", Name: "Desktop"}
+
+	if err := desktopRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Code", Duration: 600}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+	if err := laptopRepo.SaveAppUsage(ctx, date, &types.AppUsage{Name: "Mail", Duration: 120}); err != nil {
+		t.Fatalf("SaveAppUsage() error = %v", err)
+	}
+
+	// The desktop syncs once while the server is reachable, then goes offline
+	desktop, err := NewServerSync(desktopRepo, server.URL, "secret", desktopOutbox, desktopDevice, nil)
+	if err != nil {
+		t.Fatalf("NewServerSync() error = %v", err)
+	}
+	if result, err := desktop.Sync(ctx); err != nil || result.Exported != 1 || result.Queued != 0 {
+		t.Fatalf("Sync() = (%+v, %v), want Code uploaded", result, err)
+	}
+
+	offline := httptest.NewServer(nil)
+	offline.Close()
+	desktopOffline, err := NewServerSync(desktopRepo, offline.URL, "secret", desktopOutbox, desktopDevice, nil)
+	if err != nil {
+		t.Fatalf("NewServerSync() error = %v", err)
+	}
+	if err := desktopRepo.BatchIncrementAppUsageDurations(ctx, date, map[string]int64{"Code": 60}); err != nil {
+		t.Fatalf("BatchIncrementAppUsageDurations() error = %v", err)
+	}
+	result, err := desktopOffline.Sync(ctx)
+	if err != nil || result.Queued != 1 || len(result.Errors) != 1 {
+		t.Fatalf("offline Sync() = (%+v, %v), want the change queued", result, err)
+	}
+
+	// Back online, the queue is uploaded
+	result, err = desktop.Sync(ctx)
+	if err != nil || result.Exported != 1 || result.Queued != 0 || len(result.Errors) != 0 {
+		t.Fatalf("Sync() after reconnecting = (%+v, %v), want the queued change uploaded", result, err)
+	}
+
+	laptop, err := NewServerSync(laptopRepo, server.URL, "secret", filepath.Join(t.TempDir(), "outbox.jsonl"),
+		types.SyncDevice{ID: "laptop", Name: "Laptop"}, nil)
+	if err != nil {
+		t.Fatalf("NewServerSync() error = %v", err)
+	}
+	if result, err := laptop.Sync(ctx); err != nil || result.Imported != 2 || result.Exported != 1 {
+		t.Fatalf("laptop Sync() = (%+v, %v), want 2 imported and 1 uploaded", result, err)
+	}
+	if result, err := desktop.Sync(ctx); err != nil || result.Imported != 1 {
+		t.Fatalf("desktop Sync() = (%+v, %v), want the laptop's entry imported", result, err)
+	}
+
+	for name, repo := range map[string]interface {
+		GetAppUsageByDateRangeForDevice(context.Context, time.Time, time.Time, string) ([]types.AppUsage, error)
+	}{"desktop": desktopRepo, "laptop": laptopRepo} {
+		apps, err := repo.GetAppUsageByDateRangeForDevice(ctx, date, date, types.DeviceScopeAll)
+		if err != nil {
+			t.Fatalf("%s GetAppUsageByDateRangeForDevice() error = %v", name, err)
+		}
+		got := make(map[string]int64)
+		for _, app := range apps {
+			got[app.Name] = app.Duration
+		}
+		if got["Code"] != 660 || got["Mail"] != 120 {
+			t.Errorf("%s combined usage = %v, want Code 660 and Mail 120", name, got)
+		}
+	}
+
+	// After a reset the desktop learns its uploaded entries back from the server
+	// instead of numbering its history again
+	if err := desktopRepo.ResetSync(ctx); err != nil {
+		t.Fatalf("ResetSync() error = %v", err)
+	}
+	result, err = desktop.Sync(ctx)
+	if err != nil || result.Exported != 0 || result.Queued != 0 || len(result.Errors) != 0 {
+		t.Errorf("Sync() after reset = (%+v, %v), want nothing to upload", result, err)
+	}
+
+	if _, err := NewServerSync(desktopRepo, "ftp://example.com", "secret", desktopOutbox, desktopDevice, nil); err == nil {
+		t.Error("Expected NewServerSync to reject a URL that isn't http or https")
+	}
+}
@@ -0,0 +1,220 @@
+package syncserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/infrastructure/logging"
+	"qwin/internal/types"
+)
+
+const (
+	// maxUploadBytes bounds the body of an upload
+	maxUploadBytes = 8 << 20
+	// defaultPageSize and maxPageSize bound the entries returned by one read
+	defaultPageSize = 1000
+	maxPageSize     = 5000
+)
+
+// AnyDevice in a token list lets a token upload as any device
+const AnyDevice = "*"
+
+// Server serves the sync protocol described in docs/SYNC_PROTOCOL.md
+type Server struct {
+	store  *Store
+	tokens map[string]string
+	logger logging.Logger
+	mux    *http.ServeMux
+}
+
+// NewServer creates a server for the change logs in store. tokens maps each accepted
+// bearer token to the device ID it may upload as, or AnyDevice.
+func NewServer(store *Store, tokens map[string]string, logger logging.Logger) (*Server, error) {
+	if len(tokens) == 0 {
+		return nil, errors.NewRepositoryError("NewServer", fmt.Errorf("no access tokens are configured"), errors.ErrCodeValidation)
+	}
+	if logger == nil {
+		logger = logging.NewDefaultLogger()
+	}
+
+	s := &Server{store: store, tokens: tokens, logger: logger, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /v1/devices", s.handleDevices)
+	s.mux.HandleFunc("GET /v1/devices/{id}/entries", s.handleEntries)
+	s.mux.HandleFunc("POST /v1/devices/{id}/entries", s.handleUpload)
+	return s, nil
+}
+
+// ServeHTTP authenticates the request and dispatches it
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	device, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="qwin-sync"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid access token")
+		return
+	}
+	s.mux.ServeHTTP(w, r.WithContext(withTokenDevice(r.Context(), device)))
+}
+
+// authenticate returns the device the request's token may upload as
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+
+	// Compare with every token, so timing tells nothing about which one nearly matched
+	device, found := "", false
+	for candidate, allowed := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			device, found = allowed, true
+		}
+	}
+	return device, found
+}
+
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	devices, err := s.store.Devices(r.Context())
+	if err != nil {
+		s.writeStoreError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, devices)
+}
+
+func (s *Server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	after, err := queryInt(r, "after", 0)
+	if err != nil || after < 0 {
+		writeError(w, http.StatusBadRequest, "after must be a sequence number")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultPageSize)
+	if err != nil || limit <= 0 {
+		writeError(w, http.StatusBadRequest, "limit must be a positive number")
+		return
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	page, err := s.store.Entries(r.Context(), r.PathValue("id"), int64(after), int(limit))
+	if err != nil {
+		s.writeStoreError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	deviceID := r.PathValue("id")
+	if allowed := tokenDevice(r.Context()); allowed != AnyDevice && allowed != deviceID {
+		writeError(w, http.StatusForbidden, "token may not upload as this device")
+		return
+	}
+
+	var upload types.SyncUpload
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUploadBytes))
+	if err := decoder.Decode(&upload); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed upload: "+err.Error())
+		return
+	}
+
+	result, err := s.store.Append(r.Context(), deviceID, upload)
+	if err != nil {
+		if errors.IsConstraint(err) && result != nil {
+			// Tell the device where its next upload has to start
+			writeJSON(w, http.StatusConflict, result)
+			return
+		}
+		s.writeStoreError(w, r, err)
+		return
+	}
+
+	logging.LogOperation(s.logger, "SyncUpload", time.Since(start), map[string]interface{}{
+		"device_id": deviceID,
+		"upload_id": upload.UploadID,
+		"accepted":  result.Accepted,
+		"last_seq":  result.LastSeq,
+	})
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeStoreError answers with the status matching a store error; failures of the server
+// itself are logged rather than described to the client
+func (s *Server) writeStoreError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.IsValidation(err) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.logger.Error("Sync request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// ParseTokens reads a token list: one token per line, optionally followed by the device
+// ID it may upload as. Tokens without a device may upload as any device. Blank lines and
+// lines starting with # are skipped.
+func ParseTokens(r io.Reader) (map[string]string, error) {
+	tokens := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected a token and at most one device ID", line)
+		}
+		device := AnyDevice
+		if len(fields) == 2 {
+			device = fields[1]
+			if err := types.ValidateDeviceID(device); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		tokens[fields[0]] = device
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// tokenDeviceKey carries the device a request's token may upload as
+type tokenDeviceKey struct{}
+
+func withTokenDevice(ctx context.Context, device string) context.Context {
+	return context.WithValue(ctx, tokenDeviceKey{}, device)
+}
+
+func tokenDevice(ctx context.Context) string {
+	device, _ := ctx.Value(tokenDeviceKey{}).(string)
+	return device
+}
+
+// queryInt reads an integer query parameter, fallback when it is absent
+func queryInt(r *http.Request, name string, fallback int64) (int64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,139 @@
+package syncserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qwin/internal/types"
+)
+
+func setupServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server, err := NewServer(setupStore(t), map[string]string{
+		"shared-token": AnyDevice,
+		"laptop-token": "laptop",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func request(t *testing.T, server *httptest.Server, method, path, token string, body interface{}) *http.Response {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestServer_Auth(t *testing.T) {
+	server := setupServer(t)
+	upload := types.SyncUpload{UploadID: "a", Entries: laptopEntries(1, 1)}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       interface{}
+		wantStatus int
+	}{
+		{"no token", http.MethodGet, "/v1/devices", "", nil, http.StatusUnauthorized},
+		{"unknown token", http.MethodGet, "/v1/devices", "guess", nil, http.StatusUnauthorized},
+		{"device token reads", http.MethodGet, "/v1/devices", "laptop-token", nil, http.StatusOK},
+		{"device token uploads as another device", http.MethodPost, "/v1/devices/phone/entries", "laptop-token", upload, http.StatusForbidden},
+		{"device token uploads as its device", http.MethodPost, "/v1/devices/laptop/entries", "laptop-token", upload, http.StatusOK},
+		{"shared token reads any device", http.MethodGet, "/v1/devices/laptop/entries?after=0", "shared-token", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := request(t, server, tt.method, tt.path, tt.token, tt.body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServer_Upload(t *testing.T) {
+	server := setupServer(t)
+
+	resp := request(t, server, http.MethodPost, "/v1/devices/laptop/entries", "shared-token",
+		types.SyncUpload{UploadID: "a", Entries: laptopEntries(1, 2)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload status = %d, want 200", resp.StatusCode)
+	}
+
+	// A gap answers 409 with where to continue
+	resp = request(t, server, http.MethodPost, "/v1/devices/laptop/entries", "shared-token",
+		types.SyncUpload{UploadID: "b", Entries: laptopEntries(4, 4)})
+	var result types.SyncUploadResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusConflict || result.LastSeq != 2 {
+		t.Errorf("upload with a gap = %d %+v, want 409 at entry 2", resp.StatusCode, result)
+	}
+
+	resp = request(t, server, http.MethodPost, "/v1/devices/laptop/entries", "shared-token", "not an upload")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("malformed upload status = %d, want 400", resp.StatusCode)
+	}
+
+	resp = request(t, server, http.MethodGet, "/v1/devices/laptop/entries?after=1&limit=10", "shared-token", nil)
+	var page types.SyncEntriesPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Seq != 2 || page.More {
+		t.Errorf("entries after 1 = %+v, want entry 2 only", page)
+	}
+
+	resp = request(t, server, http.MethodGet, "/v1/devices/laptop/entries?after=x", "shared-token", nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid after status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestParseTokens(t *testing.T) {
+	tokens, err := ParseTokens(strings.NewReader("# tokens\nshared\n\nlaptop-token laptop\n"))
+	if err != nil {
+		t.Fatalf("ParseTokens() error = %v", err)
+	}
+	if len(tokens) != 2 || tokens["shared"] != AnyDevice || tokens["laptop-token"] != "laptop" {
+		t.Errorf("ParseTokens() = %v", tokens)
+	}
+
+	for _, input := range []string{"token laptop extra\n", "token ../laptop\n"} {
+		if _, err := ParseTokens(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseTokens(%q) should fail", input)
+		}
+	}
+
+	if _, err := NewServer(setupStore(t), map[string]string{}, nil); err == nil {
+		t.Error("Expected NewServer to require at least one token")
+	}
+}
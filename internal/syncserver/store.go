@@ -0,0 +1,254 @@
+// Package syncserver implements the qwin sync server, which keeps the change logs of
+// every device syncing through it and serves them back to the other devices.
+package syncserver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// uploadRetention is how long upload IDs are remembered for answering retries
+const uploadRetention = 7 * 24 * time.Hour
+
+// schema creates the server's tables. The server keeps change logs as uploaded; merging
+// them into usage is left to the devices.
+const schema = `
+CREATE TABLE IF NOT EXISTS devices (
+    device_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    last_seq INTEGER NOT NULL DEFAULT 0,
+    last_upload_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS entries (
+    device_id TEXT NOT NULL REFERENCES devices(device_id),
+    seq INTEGER NOT NULL,
+    device_name TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL,
+    app TEXT NOT NULL DEFAULT '',
+    date TEXT NOT NULL,
+    delta INTEGER NOT NULL,
+    PRIMARY KEY (device_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS uploads (
+    device_id TEXT NOT NULL,
+    upload_id TEXT NOT NULL,
+    accepted INTEGER NOT NULL,
+    last_seq INTEGER NOT NULL,
+    received_at DATETIME NOT NULL,
+    PRIMARY KEY (device_id, upload_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_received_at ON uploads(received_at);
+`
+
+// Store keeps device change logs in a SQLite database
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore opens, creating if needed, the store at path; ":memory:" keeps it in memory
+func OpenStore(ctx context.Context, path string) (*Store, error) {
+	dsn := path + "?_busy_timeout=5000&_foreign_keys=on"
+	if path != ":memory:" {
+		dsn += "&_journal_mode=WAL&_synchronous=NORMAL"
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, errors.NewRepositoryError("OpenStore", err, errors.ClassifyError(err))
+	}
+	// One connection serializes uploads, so appends never race on a device's numbering
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, errors.NewRepositoryErrorWithContext("OpenStore", err, errors.ClassifyError(err), map[string]string{
+			"path": path,
+		})
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Devices lists every device that has uploaded entries
+func (s *Store) Devices(ctx context.Context) ([]types.SyncDevice, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT device_id, name, last_seq, last_upload_at FROM devices ORDER BY device_id`)
+	if err != nil {
+		return nil, errors.NewRepositoryError("Devices", err, errors.ClassifyError(err))
+	}
+	defer rows.Close()
+
+	devices := []types.SyncDevice{}
+	for rows.Next() {
+		var device types.SyncDevice
+		var lastUpload sql.NullTime
+		if err := rows.Scan(&device.ID, &device.Name, &device.LastSeq, &lastUpload); err != nil {
+			return nil, errors.NewRepositoryError("Devices", err, errors.ClassifyError(err))
+		}
+		device.LastSyncedAt = lastUpload.Time
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewRepositoryError("Devices", err, errors.ClassifyError(err))
+	}
+	return devices, nil
+}
+
+// Entries reads up to limit entries of a device's change log after the entry after.
+// A device that never uploaded has no entries.
+func (s *Store) Entries(ctx context.Context, deviceID string, after int64, limit int) (*types.SyncEntriesPage, error) {
+	if err := types.ValidateDeviceID(deviceID); err != nil {
+		return nil, errors.NewRepositoryError("Entries", err, errors.ErrCodeValidation)
+	}
+	if limit <= 0 {
+		return nil, errors.NewRepositoryError("Entries", fmt.Errorf("limit must be positive"), errors.ErrCodeValidation)
+	}
+
+	// One more than asked for tells whether there is another page
+	rows, err := s.db.QueryContext(ctx, `SELECT seq, device_name, kind, app, date, delta FROM entries
+WHERE device_id = ? AND seq > ?
+ORDER BY seq
+LIMIT ?`, deviceID, after, limit+1)
+	if err != nil {
+		return nil, errors.NewRepositoryError("Entries", err, errors.ClassifyError(err))
+	}
+	defer rows.Close()
+
+	page := &types.SyncEntriesPage{Entries: []types.SyncLogEntry{}}
+	for rows.Next() {
+		entry := types.SyncLogEntry{DeviceID: deviceID}
+		if err := rows.Scan(&entry.Seq, &entry.DeviceName, &entry.Kind, &entry.App, &entry.Date, &entry.Delta); err != nil {
+			return nil, errors.NewRepositoryError("Entries", err, errors.ClassifyError(err))
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewRepositoryError("Entries", err, errors.ClassifyError(err))
+	}
+
+	if len(page.Entries) > limit {
+		page.Entries, page.More = page.Entries[:limit], true
+	}
+	return page, nil
+}
+
+// Append adds the entries of an upload that follow the last entry stored for the device.
+// Entries already stored are skipped and an upload ID seen before gets its first result
+// again, so uploads can be retried safely. An upload that would leave a gap is rejected
+// with a constraint error; the result still tells the last entry stored.
+func (s *Store) Append(ctx context.Context, deviceID string, upload types.SyncUpload) (*types.SyncUploadResult, error) {
+	if err := validateUpload(deviceID, upload); err != nil {
+		return nil, errors.NewRepositoryErrorWithContext("Append", err, errors.ErrCodeValidation, map[string]string{
+			"device_id": deviceID,
+		})
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.NewRepositoryError("Append", err, errors.ClassifyError(err))
+	}
+	defer tx.Rollback()
+
+	result := &types.SyncUploadResult{UploadID: upload.UploadID}
+	err = tx.QueryRowContext(ctx, `SELECT accepted, last_seq FROM uploads WHERE device_id = ? AND upload_id = ?`,
+		deviceID, upload.UploadID).Scan(&result.Accepted, &result.LastSeq)
+	if err == nil {
+		return result, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, errors.NewRepositoryError("Append", err, errors.ClassifyError(err))
+	}
+
+	if err := tx.QueryRowContext(ctx, `SELECT last_seq FROM devices WHERE device_id = ?`, deviceID).Scan(&result.LastSeq); err != nil && err != sql.ErrNoRows {
+		return nil, errors.NewRepositoryError("Append", err, errors.ClassifyError(err))
+	}
+
+	name := ""
+	var fresh []types.SyncLogEntry
+	for _, entry := range upload.Entries {
+		if entry.Seq <= result.LastSeq {
+			continue
+		}
+		if entry.Seq != result.LastSeq+int64(len(fresh))+1 {
+			return result, errors.NewRepositoryErrorWithContext("Append",
+				fmt.Errorf("change log entries %d to %d are missing", result.LastSeq+int64(len(fresh))+1, entry.Seq-1),
+				errors.ErrCodeConstraint, map[string]string{"device_id": deviceID})
+		}
+		fresh = append(fresh, entry)
+		if entry.DeviceName != "" {
+			name = entry.DeviceName
+		}
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO devices (device_id, name, last_seq, last_upload_at) VALUES (?, ?, ?, ?)
+ON CONFLICT(device_id) DO UPDATE SET
+    name = CASE WHEN excluded.name <> '' THEN excluded.name ELSE devices.name END,
+    last_seq = excluded.last_seq,
+    last_upload_at = excluded.last_upload_at`, deviceID, name, result.LastSeq+int64(len(fresh)), now); err != nil {
+		return nil, errors.NewRepositoryError("Append", err, errors.ClassifyError(err))
+	}
+	for _, entry := range fresh {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO entries (device_id, seq, device_name, kind, app, date, delta) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			deviceID, entry.Seq, entry.DeviceName, entry.Kind, entry.App, entry.Date, entry.Delta); err != nil {
+			return nil, errors.NewRepositoryErrorWithContext("Append", err, errors.ClassifyError(err), map[string]string{
+				"device_id": deviceID,
+				"seq":       fmt.Sprintf("%d", entry.Seq),
+			})
+		}
+	}
+	result.Accepted = len(fresh)
+	result.LastSeq += int64(len(fresh))
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO uploads (device_id, upload_id, accepted, last_seq, received_at) VALUES (?, ?, ?, ?, ?)`,
+		deviceID, upload.UploadID, result.Accepted, result.LastSeq, now); err != nil {
+		return nil, errors.NewRepositoryError("Append", err, errors.ClassifyError(err))
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM uploads WHERE received_at < ?`, now.Add(-uploadRetention)); err != nil {
+		return nil, errors.NewRepositoryError("Append", err, errors.ClassifyError(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.NewRepositoryError("Append", err, errors.ClassifyError(err))
+	}
+	return result, nil
+}
+
+// validateUpload checks an upload is named, not too large and holds valid entries of the
+// device, in order
+func validateUpload(deviceID string, upload types.SyncUpload) error {
+	if err := types.ValidateDeviceID(deviceID); err != nil {
+		return err
+	}
+	if upload.UploadID == "" || len(upload.UploadID) > 128 {
+		return fmt.Errorf("upload ID must have 1 to 128 characters")
+	}
+	if len(upload.Entries) > types.MaxSyncUploadEntries {
+		return fmt.Errorf("upload has %d entries, at most %d are accepted", len(upload.Entries), types.MaxSyncUploadEntries)
+	}
+	for i, entry := range upload.Entries {
+		if entry.DeviceID != deviceID {
+			return fmt.Errorf("change log entry %d belongs to device %q", entry.Seq, entry.DeviceID)
+		}
+		if err := entry.Validate(); err != nil {
+			return err
+		}
+		if i > 0 && entry.Seq <= upload.Entries[i-1].Seq {
+			return fmt.Errorf("change log entry %d is out of order", entry.Seq)
+		}
+	}
+	return nil
+}
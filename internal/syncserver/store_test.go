@@ -0,0 +1,109 @@
+package syncserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"qwin/internal/infrastructure/errors"
+	"qwin/internal/types"
+)
+
+func setupStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := OpenStore(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func laptopEntries(from, to int64) []types.SyncLogEntry {
+	laptop := types.SyncDevice{ID: "laptop", Name: "Laptop"}
+	date := types.DateKey(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	var entries []types.SyncLogEntry
+	for seq := from; seq <= to; seq++ {
+		entries = append(entries, types.NewSyncLogEntry(laptop, seq, types.SyncEntryApp, "Code", date, 60))
+	}
+	return entries
+}
+
+func TestStore_Append(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	result, err := store.Append(ctx, "laptop", types.SyncUpload{UploadID: "a", Entries: laptopEntries(1, 3)})
+	if err != nil || result.Accepted != 3 || result.LastSeq != 3 {
+		t.Fatalf("Append() = (%+v, %v), want 3 accepted", result, err)
+	}
+
+	// A retried upload gets its first result and changes nothing
+	result, err = store.Append(ctx, "laptop", types.SyncUpload{UploadID: "a", Entries: laptopEntries(1, 3)})
+	if err != nil || result.Accepted != 3 || result.LastSeq != 3 {
+		t.Errorf("Append() retried = (%+v, %v), want the first result", result, err)
+	}
+
+	// Entries already held are skipped under a new upload ID too
+	result, err = store.Append(ctx, "laptop", types.SyncUpload{UploadID: "b", Entries: laptopEntries(2, 5)})
+	if err != nil || result.Accepted != 2 || result.LastSeq != 5 {
+		t.Errorf("Append() overlapping = (%+v, %v), want 2 accepted up to 5", result, err)
+	}
+
+	// A gap is rejected, telling where to continue
+	result, err = store.Append(ctx, "laptop", types.SyncUpload{UploadID: "c", Entries: laptopEntries(8, 9)})
+	if !errors.IsConstraint(err) || result == nil || result.LastSeq != 5 {
+		t.Errorf("Append() with a gap = (%+v, %v), want a constraint error at 5", result, err)
+	}
+
+	invalid := map[string]types.SyncUpload{
+		"no upload ID":       {Entries: laptopEntries(6, 6)},
+		"another device":     {UploadID: "d", Entries: []types.SyncLogEntry{{Seq: 6, DeviceID: "phone", Kind: types.SyncEntryTotal, Date: "2024-05-01"}}},
+		"out of order":       {UploadID: "e", Entries: append(laptopEntries(7, 7), laptopEntries(6, 6)...)},
+		"too many entries":   {UploadID: "f", Entries: laptopEntries(6, types.MaxSyncUploadEntries+6)},
+		"invalid entry kind": {UploadID: "g", Entries: []types.SyncLogEntry{{Seq: 6, DeviceID: "laptop", Kind: "week", Date: "2024-05-01"}}},
+	}
+	for name, upload := range invalid {
+		if _, err := store.Append(ctx, "laptop", upload); !errors.IsValidation(err) {
+			t.Errorf("%s: Append() error = %v, want a validation error", name, err)
+		}
+	}
+
+	devices, err := store.Devices(ctx)
+	if err != nil {
+		t.Fatalf("Devices() error = %v", err)
+	}
+	if len(devices) != 1 || devices[0].ID != "laptop" || devices[0].Name != "Laptop" || devices[0].LastSeq != 5 {
+		t.Errorf("Devices() = %+v, want the laptop at entry 5", devices)
+	}
+}
+
+func TestStore_Entries(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Append(ctx, "laptop", types.SyncUpload{UploadID: "a", Entries: laptopEntries(1, 5)}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	page, err := store.Entries(ctx, "laptop", 1, 2)
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	if len(page.Entries) != 2 || page.Entries[0].Seq != 2 || !page.More {
+		t.Errorf("Entries() = %+v, want entries 2 and 3 with more to come", page)
+	}
+	if page.Entries[0] != laptopEntries(2, 2)[0] {
+		t.Errorf("Entries() returned %+v, want the entry as uploaded", page.Entries[0])
+	}
+
+	page, err = store.Entries(ctx, "laptop", 3, 2)
+	if err != nil || len(page.Entries) != 2 || page.More {
+		t.Errorf("Entries() last page = (%+v, %v), want entries 4 and 5 and no more", page, err)
+	}
+
+	page, err = store.Entries(ctx, "phone", 0, 10)
+	if err != nil || len(page.Entries) != 0 {
+		t.Errorf("Entries() of an unknown device = (%+v, %v), want none", page, err)
+	}
+}
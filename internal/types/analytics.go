@@ -0,0 +1,61 @@
+package types
+
+import "time"
+
+// TrendDirection describes which way an app's daily usage is heading
+type TrendDirection string
+
+const (
+	TrendRising  TrendDirection = "rising"
+	TrendFalling TrendDirection = "falling"
+	TrendFlat    TrendDirection = "flat"
+)
+
+// PeriodSummary totals usage over a range of whole days
+type PeriodSummary struct {
+	Start        time.Time `json:"start"` // date key of the first day
+	End          time.Time `json:"end"`   // date key of the last day, inclusive
+	TotalTime    int64     `json:"totalTime"`
+	DailyAverage int64     `json:"dailyAverage"` // over every day in the period
+	ActiveDays   int       `json:"activeDays"`
+}
+
+// PeriodComparison relates one period's usage to an earlier period of equal length
+type PeriodComparison struct {
+	Current       PeriodSummary   `json:"current"`
+	Previous      PeriodSummary   `json:"previous"`
+	Change        int64           `json:"change"`
+	ChangePercent float64         `json:"changePercent"` // 0 when the previous period has no usage
+	Apps          []AppComparison `json:"apps"`          // largest current usage first
+}
+
+// AppComparison relates an app's usage in one period to the previous one
+type AppComparison struct {
+	Name          string  `json:"name"`
+	Current       int64   `json:"current"`
+	Previous      int64   `json:"previous"`
+	Change        int64   `json:"change"`
+	ChangePercent float64 `json:"changePercent"` // 0 when the app wasn't used in the previous period
+}
+
+// AppMovers lists the apps whose usage grew or shrank the most between two periods
+type AppMovers struct {
+	Risers  []AppComparison `json:"risers"`  // largest increase first
+	Fallers []AppComparison `json:"fallers"` // largest decrease first
+}
+
+// RollingAveragePoint is one day's total with the average of the days leading up to it
+type RollingAveragePoint struct {
+	Date      time.Time `json:"date"`
+	TotalTime int64     `json:"totalTime"`
+	Average   float64   `json:"average"` // mean daily total over the window ending on Date
+}
+
+// AppTrend is the linear trend of an app's daily usage over a period
+type AppTrend struct {
+	Name         string         `json:"name"`
+	TotalTime    int64          `json:"totalTime"`
+	DailyAverage float64        `json:"dailyAverage"`
+	Slope        float64        `json:"slope"` // change in seconds per day
+	Direction    TrendDirection `json:"direction"`
+}
@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// Application is the canonical identity of a tracked program.
+// Usage rows reference it by ID, so history survives renamed binaries once
+// merged, and unrelated programs sharing an executable name can be split apart.
+type Application struct {
+	ID          int64     `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"` // executable base name as reported by the tracker
+	DisplayName string    `json:"displayName" db:"display_name"`
+	Alias       string    `json:"alias,omitempty" db:"alias"` // user-provided name
+	Publisher   string    `json:"publisher,omitempty" db:"publisher"`
+	Version     string    `json:"version,omitempty" db:"version"`
+	ExePaths    []string  `json:"exePaths" db:"-"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Label returns the name to show for the application, preferring the user's alias
+func (a *Application) Label() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	return a.DisplayName
+}
@@ -0,0 +1,69 @@
+package types
+
+import "strings"
+
+// Built-in categories applications are grouped into for summaries
+const (
+	CategoryBrowsing      = "Browsing"
+	CategoryDevelopment   = "Development"
+	CategoryCommunication = "Communication"
+	CategoryProductivity  = "Productivity"
+	CategoryDesign        = "Design"
+	CategoryEntertainment = "Entertainment"
+	CategorySystem        = "System"
+	CategoryPrivate       = "Private"
+	CategoryOther         = "Other"
+)
+
+// defaultAppCategories maps lowercase executable names to their category
+var defaultAppCategories = map[string]string{
+	"chrome":          CategoryBrowsing,
+	"msedge":          CategoryBrowsing,
+	"firefox":         CategoryBrowsing,
+	"brave":           CategoryBrowsing,
+	"opera":           CategoryBrowsing,
+	"code":            CategoryDevelopment,
+	"cursor":          CategoryDevelopment,
+	"devenv":          CategoryDevelopment,
+	"idea64":          CategoryDevelopment,
+	"goland64":        CategoryDevelopment,
+	"windowsterminal": CategoryDevelopment,
+	"powershell":      CategoryDevelopment,
+	"pwsh":            CategoryDevelopment,
+	"cmd":             CategoryDevelopment,
+	"slack":           CategoryCommunication,
+	"teams":           CategoryCommunication,
+	"ms-teams":        CategoryCommunication,
+	"discord":         CategoryCommunication,
+	"zoom":            CategoryCommunication,
+	"outlook":         CategoryCommunication,
+	"thunderbird":     CategoryCommunication,
+	"telegram":        CategoryCommunication,
+	"whatsapp":        CategoryCommunication,
+	"winword":         CategoryProductivity,
+	"excel":           CategoryProductivity,
+	"powerpnt":        CategoryProductivity,
+	"onenote":         CategoryProductivity,
+	"notion":          CategoryProductivity,
+	"obsidian":        CategoryProductivity,
+	"figma":           CategoryDesign,
+	"photoshop":       CategoryDesign,
+	"illustrator":     CategoryDesign,
+	"blender":         CategoryDesign,
+	"spotify":         CategoryEntertainment,
+	"vlc":             CategoryEntertainment,
+	"steam":           CategoryEntertainment,
+	"explorer":        CategorySystem,
+	"taskmgr":         CategorySystem,
+}
+
+// CategorizeApp returns the built-in category of an application by its executable name
+func CategorizeApp(name string) string {
+	if name == PrivateAppName {
+		return CategoryPrivate
+	}
+	if category, ok := defaultAppCategories[strings.ToLower(name)]; ok {
+		return category
+	}
+	return CategoryOther
+}
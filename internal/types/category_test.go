@@ -0,0 +1,21 @@
+package types
+
+import "testing"
+
+func TestCategorizeApp(t *testing.T) {
+	tests := map[string]string{
+		"Code":           CategoryDevelopment,
+		"chrome":         CategoryBrowsing,
+		"SLACK":          CategoryCommunication,
+		"explorer":       CategorySystem,
+		PrivateAppName:   CategoryPrivate,
+		"my-custom-tool": CategoryOther,
+		"":               CategoryOther,
+	}
+
+	for name, want := range tests {
+		if got := CategorizeApp(name); got != want {
+			t.Errorf("CategorizeApp(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
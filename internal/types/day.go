@@ -0,0 +1,77 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateKey returns the key a calendar date is stored under: midnight UTC of t's
+// year, month and day as seen in t's own location.
+// Keys don't depend on the machine's timezone, so a day recorded at home still
+// matches when it is read after traveling.
+func DateKey(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DateKeyEnd returns the last instant of the date key's day, for inclusive range queries
+func DateKeyEnd(t time.Time) time.Time {
+	return DateKey(t).AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+// DayBoundary decides which usage day a moment belongs to.
+// The zero value starts days at midnight in the system timezone.
+type DayBoundary struct {
+	// StartHour is the local hour at which a new usage day begins.
+	// Activity before it counts toward the previous day, so late-night sessions aren't split.
+	StartHour int `json:"startHour"`
+	// Timezone is the IANA name days are computed in. Empty follows the system timezone,
+	// so days move with the machine when traveling; a name pins them to that zone.
+	Timezone string `json:"timezone"`
+
+	location *time.Location
+}
+
+// NewDayBoundary creates a day boundary, validating the hour and resolving the timezone
+func NewDayBoundary(startHour int, timezone string) (DayBoundary, error) {
+	if startHour < 0 || startHour > 23 {
+		return DayBoundary{}, fmt.Errorf("day start hour must be between 0 and 23, got %d", startHour)
+	}
+
+	boundary := DayBoundary{StartHour: startHour, Timezone: timezone}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return DayBoundary{}, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+		boundary.location = loc
+	}
+
+	return boundary, nil
+}
+
+// Location returns the timezone days are computed in
+func (b DayBoundary) Location() *time.Location {
+	if b.location != nil {
+		return b.location
+	}
+	return time.Local
+}
+
+// DateOf returns the date key of the usage day containing t
+func (b DayBoundary) DateOf(t time.Time) time.Time {
+	local := t.In(b.Location())
+	if local.Hour() < b.StartHour {
+		local = local.AddDate(0, 0, -1)
+	}
+	return DateKey(local)
+}
+
+// Start returns the instant the usage day with the given date key begins
+func (b DayBoundary) Start(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), b.StartHour, 0, 0, 0, b.Location())
+}
+
+// End returns the instant the usage day with the given date key ends, which is when the next one starts
+func (b DayBoundary) End(date time.Time) time.Time {
+	return b.Start(DateKey(date).AddDate(0, 0, 1))
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDayBoundary_DateOf(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	nightOwl, err := NewDayBoundary(4, "Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("NewDayBoundary() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		boundary DayBoundary
+		at       time.Time
+		want     string
+	}{
+		{"before the start hour counts toward the previous day", nightOwl, time.Date(2024, 3, 10, 2, 30, 0, 0, tokyo), "2024-03-09"},
+		{"at the start hour begins the new day", nightOwl, time.Date(2024, 3, 10, 4, 0, 0, 0, tokyo), "2024-03-10"},
+		{"instants are converted to the boundary timezone", nightOwl, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), "2024-03-10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.boundary.DateOf(tt.at)
+			if got.Format("2006-01-02") != tt.want {
+				t.Errorf("DateOf() = %s, want %s", got.Format("2006-01-02"), tt.want)
+			}
+			if got.Location() != time.UTC || got.Hour() != 0 {
+				t.Errorf("DateOf() = %v, want a UTC midnight date key", got)
+			}
+		})
+	}
+
+	date := DateKey(time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC))
+	if start := nightOwl.Start(date); !start.Equal(time.Date(2024, 3, 9, 4, 0, 0, 0, tokyo)) {
+		t.Errorf("Start() = %v", start)
+	}
+	if end := nightOwl.End(date); !end.Equal(time.Date(2024, 3, 10, 4, 0, 0, 0, tokyo)) {
+		t.Errorf("End() = %v", end)
+	}
+}
+
+func TestNewDayBoundary_Validation(t *testing.T) {
+	if _, err := NewDayBoundary(24, ""); err == nil {
+		t.Error("expected an error for an hour past 23")
+	}
+	if _, err := NewDayBoundary(0, "Not/AZone"); err == nil {
+		t.Error("expected an error for an unknown timezone")
+	}
+	if b, err := NewDayBoundary(0, ""); err != nil || b.Location() != time.Local {
+		t.Errorf("empty timezone should follow the system timezone, got %v (err %v)", b.Location(), err)
+	}
+}
+
+func TestDateKey(t *testing.T) {
+	plus5 := time.FixedZone("UTC+5", 5*3600)
+	key := DateKey(time.Date(2024, 6, 1, 1, 0, 0, 0, plus5))
+	if !key.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("DateKey() = %v, want the calendar date in the time's own location", key)
+	}
+	if end := DateKeyEnd(key); !end.Equal(key.Add(24*time.Hour - time.Nanosecond)) {
+		t.Errorf("DateKeyEnd() = %v", end)
+	}
+}
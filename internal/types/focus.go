@@ -0,0 +1,44 @@
+package types
+
+import "time"
+
+// FocusEventType identifies a tracker state transition recorded in the focus event log
+type FocusEventType string
+
+const (
+	// FocusEventTrackingStarted marks the beginning of a tracking session
+	FocusEventTrackingStarted FocusEventType = "tracking_started"
+	// FocusEventTrackingStopped marks a clean end of a tracking session
+	FocusEventTrackingStopped FocusEventType = "tracking_stopped"
+	// FocusEventAppSwitched records that a different application gained focus
+	FocusEventAppSwitched FocusEventType = "app_switched"
+	// FocusEventIdleStarted records that the user stopped interacting with the machine
+	FocusEventIdleStarted FocusEventType = "idle_started"
+	// FocusEventLocked records that the session was locked
+	FocusEventLocked FocusEventType = "locked"
+	// FocusEventDayRolled records that the tracker crossed into a new day
+	FocusEventDayRolled FocusEventType = "day_rolled"
+	// FocusEventHeartbeat proves the tracker was still alive; it bounds crash loss
+	FocusEventHeartbeat FocusEventType = "heartbeat"
+	// FocusEventPaused records that the user paused tracking; nothing is attributed until resumed
+	FocusEventPaused FocusEventType = "paused"
+	// FocusEventResumed records that tracking continues after a pause
+	FocusEventResumed FocusEventType = "resumed"
+	// FocusEventAppIgnored records that an app matching an ignore rule gained focus;
+	// nothing is attributed, not even to the daily total, until another app is focused
+	FocusEventAppIgnored FocusEventType = "app_ignored"
+	// FocusEventAppHidden records that an app matching a hide rule gained focus;
+	// its time counts toward the daily total but not toward any app
+	FocusEventAppHidden FocusEventType = "app_hidden"
+	// FocusEventCheckpoint records that all attribution up to OccurredAt is persisted
+	FocusEventCheckpoint FocusEventType = "checkpoint"
+)
+
+// FocusEvent represents a single entry in the append-only focus event log
+type FocusEvent struct {
+	ID         int64          `json:"id" db:"id"`
+	Type       FocusEventType `json:"type" db:"event_type"`
+	AppName    string         `json:"appName" db:"app_name"`
+	ExePath    string         `json:"exePath" db:"exe_path"`
+	OccurredAt time.Time      `json:"occurredAt" db:"occurred_at"`
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// IconURLPrefix is the path under which stored icons are served to the frontend
+const IconURLPrefix = "/icons/"
+
+// Icon represents an application icon stored once and addressed by the hash of its bytes
+type Icon struct {
+	Hash string `json:"hash" db:"hash"`
+	Mime string `json:"mime" db:"mime"`
+	Data []byte `json:"-" db:"data"`
+	Size int64  `json:"size" db:"size"`
+}
+
+// NewIcon creates an icon from raw bytes, computing its content hash
+func NewIcon(mime string, data []byte) *Icon {
+	sum := sha256.Sum256(data)
+	return &Icon{
+		Hash: hex.EncodeToString(sum[:]),
+		Mime: mime,
+		Data: data,
+		Size: int64(len(data)),
+	}
+}
+
+// IconFromDataURL decodes a base64 data URL such as "data:image/png;base64,...".
+// Returns false when s is not a base64 data URL.
+func IconFromDataURL(s string) (*Icon, bool) {
+	rest, ok := strings.CutPrefix(s, "data:")
+	if !ok {
+		return nil, false
+	}
+
+	header, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil, false
+	}
+
+	mime, ok := strings.CutSuffix(header, ";base64")
+	if !ok || mime == "" {
+		return nil, false
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, false
+	}
+
+	return NewIcon(mime, data), true
+}
+
+// DataURL encodes the icon as a base64 data URL
+func (i *Icon) DataURL() string {
+	return "data:" + i.Mime + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// IconURL returns the URL the frontend uses to load the icon with the given hash
+func IconURL(hash string) string {
+	return IconURLPrefix + hash
+}
+
+// IconHashFromURL extracts the hash from a URL built by IconURL.
+// Returns false for any other value, including data URLs.
+func IconHashFromURL(s string) (string, bool) {
+	hash, ok := strings.CutPrefix(s, IconURLPrefix)
+	if !ok || hash == "" || strings.Contains(hash, "/") {
+		return "", false
+	}
+	return hash, true
+}
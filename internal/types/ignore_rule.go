@@ -0,0 +1,159 @@
+package types
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// IgnoreMatchType selects which property of the focused application a rule looks at
+type IgnoreMatchType string
+
+const (
+	// IgnoreMatchName compares the application name, ignoring case
+	IgnoreMatchName IgnoreMatchType = "name"
+	// IgnoreMatchExePath matches the executable path against a glob, ignoring case.
+	// Patterns without a separator are matched against the file name only.
+	IgnoreMatchExePath IgnoreMatchType = "exe_path"
+	// IgnoreMatchTitle matches the window title against a regular expression
+	IgnoreMatchTitle IgnoreMatchType = "title"
+)
+
+// IgnoreMode decides what happens to time spent in a matching application
+type IgnoreMode string
+
+const (
+	// IgnoreModeIgnore drops the time entirely, as if tracking were paused
+	IgnoreModeIgnore IgnoreMode = "ignore"
+	// IgnoreModeHide counts the time toward the daily total without attributing it to any app
+	IgnoreModeHide IgnoreMode = "hide"
+)
+
+// IgnoreRule keeps matching applications out of the per-app usage stats
+type IgnoreRule struct {
+	ID        int64           `json:"id" db:"id"`
+	MatchType IgnoreMatchType `json:"matchType" db:"match_type"`
+	Pattern   string          `json:"pattern" db:"pattern"`
+	Mode      IgnoreMode      `json:"mode" db:"mode"`
+	Enabled   bool            `json:"enabled" db:"enabled"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time       `json:"updatedAt" db:"updated_at"`
+}
+
+// Validate checks that the rule's match type and mode are known and its pattern compiles
+func (r *IgnoreRule) Validate() error {
+	if strings.TrimSpace(r.Pattern) == "" {
+		return fmt.Errorf("ignore rule pattern is empty")
+	}
+	switch r.Mode {
+	case IgnoreModeIgnore, IgnoreModeHide:
+	default:
+		return fmt.Errorf("unknown ignore mode %q", r.Mode)
+	}
+	_, err := compileIgnoreRule(*r)
+	return err
+}
+
+// IgnoreTarget is what rules are evaluated against
+type IgnoreTarget struct {
+	Name        string
+	ExePath     string
+	WindowTitle string
+}
+
+// IgnoreRuleSet is a compiled list of enabled ignore rules
+type IgnoreRuleSet struct {
+	rules []compiledIgnoreRule
+}
+
+type compiledIgnoreRule struct {
+	rule  IgnoreRule
+	title *regexp.Regexp
+}
+
+// CompileIgnoreRules compiles the enabled rules for matching; disabled rules are skipped
+func CompileIgnoreRules(rules []IgnoreRule) (*IgnoreRuleSet, error) {
+	set := &IgnoreRuleSet{}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		compiled, err := compileIgnoreRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		set.rules = append(set.rules, compiled)
+	}
+	return set, nil
+}
+
+// Match returns the mode of the rules matching target. When rules disagree,
+// ignoring wins over hiding because it discards strictly more.
+func (s *IgnoreRuleSet) Match(target IgnoreTarget) (IgnoreMode, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	var mode IgnoreMode
+	for _, rule := range s.rules {
+		if !rule.matches(target) {
+			continue
+		}
+		if rule.rule.Mode == IgnoreModeIgnore {
+			return IgnoreModeIgnore, true
+		}
+		mode = rule.rule.Mode
+	}
+	return mode, mode != ""
+}
+
+// compileIgnoreRule prepares a single rule, rejecting malformed patterns
+func compileIgnoreRule(rule IgnoreRule) (compiledIgnoreRule, error) {
+	compiled := compiledIgnoreRule{rule: rule}
+	switch rule.MatchType {
+	case IgnoreMatchName:
+	case IgnoreMatchExePath:
+		if _, err := path.Match(normalizeExeGlob(rule.Pattern), ""); err != nil {
+			return compiledIgnoreRule{}, fmt.Errorf("invalid exe path glob %q: %w", rule.Pattern, err)
+		}
+	case IgnoreMatchTitle:
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return compiledIgnoreRule{}, fmt.Errorf("invalid title pattern %q: %w", rule.Pattern, err)
+		}
+		compiled.title = re
+	default:
+		return compiledIgnoreRule{}, fmt.Errorf("unknown ignore match type %q", rule.MatchType)
+	}
+	return compiled, nil
+}
+
+// matches reports whether the rule applies to target
+func (c compiledIgnoreRule) matches(target IgnoreTarget) bool {
+	switch c.rule.MatchType {
+	case IgnoreMatchName:
+		return target.Name != "" && strings.EqualFold(target.Name, c.rule.Pattern)
+	case IgnoreMatchExePath:
+		if target.ExePath == "" {
+			return false
+		}
+		pattern := normalizeExeGlob(c.rule.Pattern)
+		exePath := normalizeExeGlob(target.ExePath)
+		if !strings.Contains(pattern, "/") {
+			exePath = path.Base(exePath)
+		}
+		matched, _ := path.Match(pattern, exePath)
+		return matched
+	case IgnoreMatchTitle:
+		return target.WindowTitle != "" && c.title.MatchString(target.WindowTitle)
+	default:
+		return false
+	}
+}
+
+// normalizeExeGlob makes paths comparable across separators and letter case
+func normalizeExeGlob(p string) string {
+	return strings.ToLower(strings.ReplaceAll(p, `\`, "/"))
+}
@@ -0,0 +1,63 @@
+package types
+
+import "testing"
+
+func TestIgnoreRuleSet_Match(t *testing.T) {
+	set, err := CompileIgnoreRules([]IgnoreRule{
+		{MatchType: IgnoreMatchName, Pattern: "LockApp", Mode: IgnoreModeIgnore, Enabled: true},
+		{MatchType: IgnoreMatchExePath, Pattern: "explorer.exe", Mode: IgnoreModeHide, Enabled: true},
+		{MatchType: IgnoreMatchExePath, Pattern: `C:\Tools\*.exe`, Mode: IgnoreModeHide, Enabled: true},
+		{MatchType: IgnoreMatchTitle, Pattern: `(?i)incognito`, Mode: IgnoreModeIgnore, Enabled: true},
+		{MatchType: IgnoreMatchName, Pattern: "Slack", Mode: IgnoreModeIgnore, Enabled: false},
+	})
+	if err != nil {
+		t.Fatalf("CompileIgnoreRules failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		target   IgnoreTarget
+		wantMode IgnoreMode
+	}{
+		{"name ignores case", IgnoreTarget{Name: "lockapp"}, IgnoreModeIgnore},
+		{"bare glob matches the file name", IgnoreTarget{Name: "explorer", ExePath: `C:\Windows\Explorer.EXE`}, IgnoreModeHide},
+		{"path glob matches the full path", IgnoreTarget{Name: "grep", ExePath: `c:\tools\grep.exe`}, IgnoreModeHide},
+		{"path glob does not cross directories", IgnoreTarget{Name: "grep", ExePath: `C:\Tools\bin\grep.exe`}, ""},
+		{"title regex", IgnoreTarget{Name: "chrome", WindowTitle: "New Incognito Tab"}, IgnoreModeIgnore},
+		{"ignore wins over hide", IgnoreTarget{Name: "explorer", ExePath: `C:\explorer.exe`, WindowTitle: "incognito"}, IgnoreModeIgnore},
+		{"disabled rules are skipped", IgnoreTarget{Name: "Slack"}, ""},
+		{"no match", IgnoreTarget{Name: "Code", ExePath: `C:\Code\Code.exe`}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, matched := set.Match(tt.target)
+			if mode != tt.wantMode || matched != (tt.wantMode != "") {
+				t.Errorf("Match() = (%q, %v), want %q", mode, matched, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestIgnoreRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    IgnoreRule
+		wantErr bool
+	}{
+		{"valid", IgnoreRule{MatchType: IgnoreMatchName, Pattern: "qwin", Mode: IgnoreModeHide}, false},
+		{"empty pattern", IgnoreRule{MatchType: IgnoreMatchName, Pattern: " ", Mode: IgnoreModeHide}, true},
+		{"unknown mode", IgnoreRule{MatchType: IgnoreMatchName, Pattern: "qwin", Mode: "drop"}, true},
+		{"unknown match type", IgnoreRule{MatchType: "pid", Pattern: "1", Mode: IgnoreModeIgnore}, true},
+		{"bad regex", IgnoreRule{MatchType: IgnoreMatchTitle, Pattern: "(", Mode: IgnoreModeIgnore}, true},
+		{"bad glob", IgnoreRule{MatchType: IgnoreMatchExePath, Pattern: "[", Mode: IgnoreModeIgnore}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
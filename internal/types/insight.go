@@ -0,0 +1,37 @@
+package types
+
+import "time"
+
+// InsightKind identifies what an insight measured
+type InsightKind string
+
+const (
+	// InsightDailyTotal flags a day whose total screen time stood out
+	InsightDailyTotal InsightKind = "daily_total"
+	// InsightAppUsage flags an app whose time on a day stood out
+	InsightAppUsage InsightKind = "app_usage"
+)
+
+// Insight records usage that deviated significantly from the user's own baseline
+type Insight struct {
+	ID       int64       `json:"id"`
+	Date     time.Time   `json:"date"` // date key of the day the usage happened on
+	Kind     InsightKind `json:"kind"`
+	AppName  string      `json:"appName,omitempty"` // empty for daily total insights
+	Value    int64       `json:"value"`             // seconds of usage on the day
+	Baseline float64     `json:"baseline"`          // typical seconds of usage per day
+	Score    float64     `json:"score"`             // robust z-score; positive means more than usual
+	// Message is a short sentence for display, phrased relative to today
+	Message string `json:"message"`
+	// Explanation describes the baseline the usage was compared against
+	Explanation string    `json:"explanation"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Ratio returns the usage as a multiple of the baseline, or 0 when there is no baseline
+func (i Insight) Ratio() float64 {
+	if i.Baseline <= 0 {
+		return 0
+	}
+	return float64(i.Value) / i.Baseline
+}
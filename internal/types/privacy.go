@@ -0,0 +1,142 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// TitleRedaction decides how window titles are kept
+type TitleRedaction string
+
+const (
+	// TitleRedactionNone keeps titles as the platform reports them
+	TitleRedactionNone TitleRedaction = "none"
+	// TitleRedactionHash replaces a title with a short hash, so equal titles can still be grouped
+	TitleRedactionHash TitleRedaction = "hash"
+	// TitleRedactionTruncate keeps only the first TitleMaxLength characters of a title
+	TitleRedactionTruncate TitleRedaction = "truncate"
+)
+
+// defaultTitleMaxLength is how much of a title is kept when truncating without a configured length
+const defaultTitleMaxLength = 20
+
+// PrivacyPolicy decides what identifying detail is removed from recorded data before it is stored.
+// The zero value keeps everything.
+type PrivacyPolicy struct {
+	// TitleRedaction applies to window titles; empty means TitleRedactionNone
+	TitleRedaction TitleRedaction `json:"titleRedaction"`
+	// TitleMaxLength is the number of characters truncated titles keep; 0 uses the default
+	TitleMaxLength int `json:"titleMaxLength"`
+	// StripURLQueries removes query strings and fragments from URLs in names and titles
+	StripURLQueries bool `json:"stripUrlQueries"`
+	// DropUserPaths reduces executable paths with a directory named after the user to the file name
+	DropUserPaths bool `json:"dropUserPaths"`
+}
+
+// Validate checks that the title redaction is known and the title length isn't negative
+func (p PrivacyPolicy) Validate() error {
+	switch p.TitleRedaction {
+	case "", TitleRedactionNone, TitleRedactionHash, TitleRedactionTruncate:
+	default:
+		return fmt.Errorf("unknown title redaction %q", p.TitleRedaction)
+	}
+	if p.TitleMaxLength < 0 {
+		return fmt.Errorf("title length must not be negative, got %d", p.TitleMaxLength)
+	}
+	return nil
+}
+
+// urlPattern finds URLs with a scheme within text
+var urlPattern = regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://[^\s"'<>]+`)
+
+// PrivacyRedactor applies a privacy policy for one user.
+// A nil redactor leaves everything unchanged.
+type PrivacyRedactor struct {
+	policy   PrivacyPolicy
+	username string
+}
+
+// NewPrivacyRedactor creates a redactor applying policy; username is the account whose
+// directories DropUserPaths removes, compared without regard to case
+func NewPrivacyRedactor(policy PrivacyPolicy, username string) *PrivacyRedactor {
+	return &PrivacyRedactor{policy: policy, username: strings.ToLower(username)}
+}
+
+// Policy returns the policy being applied
+func (r *PrivacyRedactor) Policy() PrivacyPolicy {
+	if r == nil {
+		return PrivacyPolicy{}
+	}
+	return r.policy
+}
+
+// Name redacts an application name
+func (r *PrivacyRedactor) Name(name string) string {
+	if r == nil {
+		return name
+	}
+	return r.stripURLQueries(name)
+}
+
+// Title redacts a window title. URLs are stripped before hashing or truncating, so
+// titles differing only in their query strings are treated as the same.
+func (r *PrivacyRedactor) Title(title string) string {
+	if r == nil || title == "" {
+		return title
+	}
+	title = r.stripURLQueries(title)
+
+	switch r.policy.TitleRedaction {
+	case TitleRedactionHash:
+		sum := sha256.Sum256([]byte(title))
+		return "#" + hex.EncodeToString(sum[:6])
+	case TitleRedactionTruncate:
+		limit := r.policy.TitleMaxLength
+		if limit == 0 {
+			limit = defaultTitleMaxLength
+		}
+		if utf8.RuneCountInString(title) <= limit {
+			return title
+		}
+		return string([]rune(title)[:limit]) + "…"
+	default:
+		return title
+	}
+}
+
+// ExePath redacts an executable path. Paths with a directory whose name contains the
+// username are reduced to the file name, which still identifies the application.
+func (r *PrivacyRedactor) ExePath(exePath string) string {
+	if r == nil || !r.policy.DropUserPaths || r.username == "" || exePath == "" {
+		return exePath
+	}
+
+	// Split on both separators, as paths may have been recorded on another platform
+	parts := strings.FieldsFunc(exePath, func(c rune) bool { return c == '/' || c == '\\' })
+	if len(parts) < 2 {
+		return exePath
+	}
+	for _, dir := range parts[:len(parts)-1] {
+		if strings.Contains(strings.ToLower(dir), r.username) {
+			return parts[len(parts)-1]
+		}
+	}
+	return exePath
+}
+
+// stripURLQueries removes query strings and fragments from URLs in s when the policy asks to
+func (r *PrivacyRedactor) stripURLQueries(s string) string {
+	if !r.policy.StripURLQueries || !strings.Contains(s, "://") {
+		return s
+	}
+	return urlPattern.ReplaceAllStringFunc(s, func(url string) string {
+		if i := strings.IndexAny(url, "?#"); i >= 0 {
+			return url[:i]
+		}
+		return url
+	})
+}
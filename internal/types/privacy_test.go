@@ -0,0 +1,78 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrivacyRedactor_Title(t *testing.T) {
+	const title = "Pull request #42 - https://git.example.com/pulls/42?token=abc#diff - Browser"
+
+	tests := []struct {
+		name   string
+		policy PrivacyPolicy
+		want   string
+	}{
+		{"kept by default", PrivacyPolicy{}, title},
+		{"url queries stripped", PrivacyPolicy{StripURLQueries: true}, "Pull request #42 - https://git.example.com/pulls/42 - Browser"},
+		{"truncated", PrivacyPolicy{TitleRedaction: TitleRedactionTruncate, TitleMaxLength: 12}, "Pull request…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewPrivacyRedactor(tt.policy, "alice").Title(title); got != tt.want {
+				t.Errorf("Title() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	hashing := NewPrivacyRedactor(PrivacyPolicy{TitleRedaction: TitleRedactionHash, StripURLQueries: true}, "alice")
+	hashed := hashing.Title(title)
+	if !strings.HasPrefix(hashed, "#") || strings.Contains(hashed, "Pull") {
+		t.Errorf("Title() = %q, want a hash", hashed)
+	}
+	if other := hashing.Title(strings.Replace(title, "token=abc", "token=xyz", 1)); other != hashed {
+		t.Errorf("Titles differing only in their query hashed to %q and %q", hashed, other)
+	}
+
+	var nilRedactor *PrivacyRedactor
+	if got := nilRedactor.Title(title); got != title {
+		t.Errorf("nil redactor changed the title to %q", got)
+	}
+}
+
+func TestPrivacyRedactor_ExePath(t *testing.T) {
+	redactor := NewPrivacyRedactor(PrivacyPolicy{DropUserPaths: true}, "Alice")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\Users\alice\AppData\Local\Programs\Code\Code.exe`, "Code.exe"},
+		{"/home/alice/bin/tool", "tool"},
+		{"/Users/alice.smith/Applications/Notes.app", "Notes.app"},
+		{`C:\Program Files\Git\git.exe`, `C:\Program Files\Git\git.exe`},
+		{"alice.exe", "alice.exe"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := redactor.ExePath(tt.path); got != tt.want {
+			t.Errorf("ExePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if got := NewPrivacyRedactor(PrivacyPolicy{}, "alice").ExePath("/home/alice/bin/tool"); got != "/home/alice/bin/tool" {
+		t.Errorf("ExePath() = %q with DropUserPaths off", got)
+	}
+}
+
+func TestPrivacyPolicy_Validate(t *testing.T) {
+	if err := (PrivacyPolicy{TitleRedaction: TitleRedactionHash}).Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+	if err := (PrivacyPolicy{TitleRedaction: "blur"}).Validate(); err == nil {
+		t.Error("Expected an unknown title redaction to be invalid")
+	}
+	if err := (PrivacyPolicy{TitleMaxLength: -1}).Validate(); err == nil {
+		t.Error("Expected a negative title length to be invalid")
+	}
+}
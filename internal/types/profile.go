@@ -0,0 +1,155 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultProfile is the profile whose database lives at the configured database path
+const DefaultProfile = "default"
+
+// Profile is a separate set of usage data, settings and rules kept in its own database
+type Profile struct {
+	Name         string `json:"name"`
+	Active       bool   `json:"active"`
+	DatabasePath string `json:"databasePath"`
+}
+
+// ProfileSwitch switches to a profile at a local time of day
+type ProfileSwitch struct {
+	Profile string `json:"profile"`
+	// At is the local time of day as HH:MM
+	At string `json:"at"`
+	// Weekdays limits the switch to these days; empty means every day
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+}
+
+// ProfileConfig lists the profiles, which one is active and when to switch between them
+type ProfileConfig struct {
+	Active   string          `json:"active"`
+	Profiles []string        `json:"profiles"`
+	Schedule []ProfileSwitch `json:"schedule,omitempty"`
+	// SwitchedAt is when the active profile last changed, so scheduled switches that
+	// came due earlier don't override a later manual choice
+	SwitchedAt time.Time `json:"switchedAt,omitempty"`
+}
+
+// NewProfileConfig returns the configuration of an install that only has the default profile
+func NewProfileConfig() *ProfileConfig {
+	return &ProfileConfig{Active: DefaultProfile, Profiles: []string{DefaultProfile}}
+}
+
+// ValidateProfileName checks that name can name a profile, and so its database directory
+func ValidateProfileName(name string) error {
+	if name == "" || len(name) > 64 {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	if strings.IndexFunc(name, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_')
+	}) >= 0 {
+		return fmt.Errorf("invalid profile name %q: only letters, digits, '-' and '_' are allowed", name)
+	}
+	return nil
+}
+
+// Validate checks the switch's profile name, time of day and weekdays
+func (s ProfileSwitch) Validate() error {
+	if err := ValidateProfileName(s.Profile); err != nil {
+		return err
+	}
+	if _, _, err := s.timeOfDay(); err != nil {
+		return err
+	}
+	for _, day := range s.Weekdays {
+		if day < time.Sunday || day > time.Saturday {
+			return fmt.Errorf("invalid weekday %d", day)
+		}
+	}
+	return nil
+}
+
+// timeOfDay returns the hour and minute the switch happens at
+func (s ProfileSwitch) timeOfDay() (int, int, error) {
+	at, err := time.Parse("15:04", s.At)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid switch time %q: want HH:MM", s.At)
+	}
+	return at.Hour(), at.Minute(), nil
+}
+
+// appliesOn reports whether the switch happens on the given weekday
+func (s ProfileSwitch) appliesOn(day time.Weekday) bool {
+	if len(s.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range s.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether a profile with the given name exists
+func (c *ProfileConfig) Has(name string) bool {
+	for _, profile := range c.Profiles {
+		if profile == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the profile names, that the active profile exists and that every
+// scheduled switch is valid and names an existing profile
+func (c *ProfileConfig) Validate() error {
+	seen := make(map[string]bool, len(c.Profiles))
+	for _, name := range c.Profiles {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate profile %q", name)
+		}
+		seen[name] = true
+	}
+	if !seen[c.Active] {
+		return fmt.Errorf("active profile %q does not exist", c.Active)
+	}
+	for _, s := range c.Schedule {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+		if !seen[s.Profile] {
+			return fmt.Errorf("scheduled profile %q does not exist", s.Profile)
+		}
+	}
+	return nil
+}
+
+// ScheduledProfile returns the profile the most recent scheduled switch at or before now
+// selected, and when that switch happened. It reports false when nothing is scheduled.
+func (c *ProfileConfig) ScheduledProfile(now time.Time) (string, time.Time, bool) {
+	var (
+		profile string
+		latest  time.Time
+	)
+
+	// Every switch happens at least once a week, so the last seven days hold the latest one
+	for daysBack := 0; daysBack <= 7; daysBack++ {
+		day := now.AddDate(0, 0, -daysBack)
+		for _, s := range c.Schedule {
+			hour, minute, err := s.timeOfDay()
+			if err != nil || !s.appliesOn(day.Weekday()) {
+				continue
+			}
+			at := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location())
+			if at.After(now) || !at.After(latest) {
+				continue
+			}
+			profile, latest = s.Profile, at
+		}
+	}
+	return profile, latest, profile != ""
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfileConfig_Validate(t *testing.T) {
+	valid := &ProfileConfig{
+		Active:   "work",
+		Profiles: []string{DefaultProfile, "work"},
+		Schedule: []ProfileSwitch{{Profile: "work", At: "09:00", Weekdays: []time.Weekday{time.Monday}}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err := NewProfileConfig().Validate(); err != nil {
+		t.Errorf("NewProfileConfig().Validate() error = %v", err)
+	}
+
+	invalid := map[string]*ProfileConfig{
+		"unknown active profile": {Active: "work", Profiles: []string{DefaultProfile}},
+		"duplicate profile":      {Active: "work", Profiles: []string{"work", "work"}},
+		"invalid name":           {Active: "../work", Profiles: []string{"../work"}},
+		"unknown scheduled":      {Active: "work", Profiles: []string{"work"}, Schedule: []ProfileSwitch{{Profile: "home", At: "18:00"}}},
+		"invalid time":           {Active: "work", Profiles: []string{"work"}, Schedule: []ProfileSwitch{{Profile: "work", At: "25:00"}}},
+		"invalid weekday":        {Active: "work", Profiles: []string{"work"}, Schedule: []ProfileSwitch{{Profile: "work", At: "09:00", Weekdays: []time.Weekday{7}}}},
+	}
+	for name, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() should fail", name)
+		}
+	}
+}
+
+func TestProfileConfig_ScheduledProfile(t *testing.T) {
+	config := &ProfileConfig{
+		Profiles: []string{DefaultProfile, "work"},
+		Schedule: []ProfileSwitch{
+			{Profile: "work", At: "09:00", Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}},
+			{Profile: DefaultProfile, At: "17:30"},
+		},
+	}
+
+	// 2024-05-06 is a Monday
+	tests := []struct {
+		name    string
+		now     time.Time
+		profile string
+		at      time.Time
+	}{
+		{"weekday morning", time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC), "work", time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)},
+		{"exactly at the switch", time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC), "work", time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)},
+		{"weekday evening", time.Date(2024, 5, 6, 18, 0, 0, 0, time.UTC), DefaultProfile, time.Date(2024, 5, 6, 17, 30, 0, 0, time.UTC)},
+		{"before the morning switch", time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC), DefaultProfile, time.Date(2024, 5, 5, 17, 30, 0, 0, time.UTC)},
+		{"weekend morning", time.Date(2024, 5, 11, 10, 0, 0, 0, time.UTC), DefaultProfile, time.Date(2024, 5, 10, 17, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		profile, at, ok := config.ScheduledProfile(tt.now)
+		if !ok || profile != tt.profile || !at.Equal(tt.at) {
+			t.Errorf("%s: ScheduledProfile() = (%q, %v, %v), want (%q, %v)", tt.name, profile, at, ok, tt.profile, tt.at)
+		}
+	}
+
+	if _, _, ok := NewProfileConfig().ScheduledProfile(time.Now()); ok {
+		t.Error("Expected no scheduled profile without a schedule")
+	}
+}
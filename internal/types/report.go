@@ -0,0 +1,63 @@
+package types
+
+import "time"
+
+// ReportPeriod identifies the kind of period a report covers
+type ReportPeriod string
+
+const (
+	ReportPeriodWeekly  ReportPeriod = "weekly"
+	ReportPeriodMonthly ReportPeriod = "monthly"
+	ReportPeriodCustom  ReportPeriod = "custom"
+)
+
+// Report summarizes usage over a period of whole days
+type Report struct {
+	Title       string       `json:"title"`
+	Period      ReportPeriod `json:"period"`
+	Start       time.Time    `json:"start"` // date key of the first day
+	End         time.Time    `json:"end"`   // date key of the last day, inclusive
+	GeneratedAt time.Time    `json:"generatedAt"`
+
+	TotalTime    int64 `json:"totalTime"`    // in seconds
+	DailyAverage int64 `json:"dailyAverage"` // over days with any usage
+	ActiveDays   int   `json:"activeDays"`
+
+	Days          []ReportDay  `json:"days"`
+	TopApps       []ReportItem `json:"topApps"`
+	TopCategories []ReportItem `json:"topCategories"`
+
+	// BusiestHour is nil when no focus events cover the period
+	BusiestHour *ReportHour `json:"busiestHour,omitempty"`
+	// Previous compares against the period of equal length right before this one
+	Previous *ReportComparison `json:"previous,omitempty"`
+}
+
+// ReportDay is the total for one day of a report and its change from the day before
+type ReportDay struct {
+	Date      time.Time `json:"date"`
+	TotalTime int64     `json:"totalTime"`
+	Change    int64     `json:"change"` // seconds more (or fewer) than the previous day
+}
+
+// ReportItem is an app or category with its share of the report's tracked app time
+type ReportItem struct {
+	Name     string  `json:"name"`
+	Duration int64   `json:"duration"`
+	Share    float64 `json:"share"` // fraction of all app time, 0..1
+}
+
+// ReportHour is the local hour of day with the most tracked time
+type ReportHour struct {
+	Hour     int   `json:"hour"` // 0-23
+	Duration int64 `json:"duration"`
+}
+
+// ReportComparison relates a report's total to the previous period's
+type ReportComparison struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	TotalTime     int64     `json:"totalTime"`
+	Change        int64     `json:"change"`
+	ChangePercent float64   `json:"changePercent"` // 0 when the previous period has no usage
+}
@@ -0,0 +1,178 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// WorkBlock is a span of working hours on a weekday, as local HH:MM times.
+// End is after Start; "24:00" ends the block at midnight.
+type WorkBlock struct {
+	Weekday time.Weekday `json:"weekday"`
+	Start   string       `json:"start"`
+	End     string       `json:"end"`
+}
+
+// WorkSchedule is when the user works: blocks of working hours on each weekday, and
+// holidays on which none of them apply. The zero value schedules no working hours.
+type WorkSchedule struct {
+	Blocks []WorkBlock `json:"blocks"`
+	// Holidays are calendar dates as YYYY-MM-DD
+	Holidays []string `json:"holidays,omitempty"`
+}
+
+// UsageSplit divides a day's usage between scheduled working hours and the rest of the day
+type UsageSplit struct {
+	Date    time.Time `json:"date"`
+	Holiday bool      `json:"holiday"`
+	// ScheduledTime is how many seconds of working hours the day has
+	ScheduledTime int64     `json:"scheduledTime"`
+	InHours       UsageData `json:"inHours"`
+	OutOfHours    UsageData `json:"outOfHours"`
+	// Unplaced holds the day's time entries, which have no time of day to split by
+	Unplaced UsageData `json:"unplaced"`
+	// InHoursByCategory and OutOfHoursByCategory total app time per category
+	InHoursByCategory    map[string]int64 `json:"inHoursByCategory"`
+	OutOfHoursByCategory map[string]int64 `json:"outOfHoursByCategory"`
+}
+
+// parseClock parses an HH:MM time of day into minutes after midnight, accepting "24:00"
+func parseClock(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: want HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// minutes returns the block's start and end as minutes after midnight
+func (b WorkBlock) minutes() (int, int, error) {
+	start, err := parseClock(b.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == 24*60 {
+		return 0, 0, fmt.Errorf("invalid start time %q", b.Start)
+	}
+	end, err := parseClock(b.End)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("work block %s-%s must end after it starts", b.Start, b.End)
+	}
+	return start, end, nil
+}
+
+// Validate checks the block's weekday and times
+func (b WorkBlock) Validate() error {
+	if b.Weekday < time.Sunday || b.Weekday > time.Saturday {
+		return fmt.Errorf("invalid weekday %d", b.Weekday)
+	}
+	_, _, err := b.minutes()
+	return err
+}
+
+// Validate checks every block and holiday
+func (s WorkSchedule) Validate() error {
+	for _, block := range s.Blocks {
+		if err := block.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, holiday := range s.Holidays {
+		if _, err := time.Parse("2006-01-02", holiday); err != nil {
+			return fmt.Errorf("invalid holiday %q: want YYYY-MM-DD", holiday)
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the schedule has no working hours at all
+func (s WorkSchedule) IsEmpty() bool {
+	return len(s.Blocks) == 0
+}
+
+// IsHoliday reports whether the calendar day containing t, in t's location, is a holiday
+func (s WorkSchedule) IsHoliday(t time.Time) bool {
+	date := t.Format("2006-01-02")
+	for _, holiday := range s.Holidays {
+		if holiday == date {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether t falls within working hours, reading the schedule in t's
+// location. Features that should only act during work, such as limits, check it.
+func (s WorkSchedule) Contains(t time.Time) bool {
+	return s.WorkingTime(t, t.Add(time.Nanosecond)) > 0
+}
+
+// WorkingTime returns how much of [start, end) falls within working hours, reading the
+// schedule in start's location
+func (s WorkSchedule) WorkingTime(start, end time.Time) time.Duration {
+	var total time.Duration
+	for _, r := range s.ranges(start, end) {
+		from, to := r[0], r[1]
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if to.After(from) {
+			total += to.Sub(from)
+		}
+	}
+	return total
+}
+
+// ranges returns the working hours of every calendar day that overlaps [start, end), in
+// start's location, sorted by start
+func (s WorkSchedule) ranges(start, end time.Time) [][2]time.Time {
+	if s.IsEmpty() || !end.After(start) {
+		return nil
+	}
+
+	loc := start.Location()
+	end = end.In(loc)
+	var ranges [][2]time.Time
+	for day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc); day.Before(end); day = day.AddDate(0, 0, 1) {
+		if s.IsHoliday(day) {
+			continue
+		}
+		for _, block := range s.Blocks {
+			if block.Weekday != day.Weekday() {
+				continue
+			}
+			from, to, err := block.minutes()
+			if err != nil {
+				continue
+			}
+			ranges = append(ranges, [2]time.Time{
+				time.Date(day.Year(), day.Month(), day.Day(), 0, from, 0, 0, loc),
+				time.Date(day.Year(), day.Month(), day.Day(), 0, to, 0, 0, loc),
+			})
+		}
+	}
+
+	// Overlapping blocks would otherwise count the shared time twice
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0].Before(ranges[j][0]) })
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && !r[0].After(merged[n-1][1]) {
+			if r[1].After(merged[n-1][1]) {
+				merged[n-1][1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkSchedule_Validate(t *testing.T) {
+	valid := WorkSchedule{
+		Blocks:   []WorkBlock{{Weekday: time.Monday, Start: "09:00", End: "12:00"}, {Weekday: time.Friday, Start: "20:00", End: "24:00"}},
+		Holidays: []string{"2024-12-25"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	invalid := map[string]WorkSchedule{
+		"ends before it starts": {Blocks: []WorkBlock{{Weekday: time.Monday, Start: "12:00", End: "09:00"}}},
+		"invalid time":          {Blocks: []WorkBlock{{Weekday: time.Monday, Start: "9am", End: "12:00"}}},
+		"starts at 24:00":       {Blocks: []WorkBlock{{Weekday: time.Monday, Start: "24:00", End: "24:00"}}},
+		"invalid weekday":       {Blocks: []WorkBlock{{Weekday: 7, Start: "09:00", End: "12:00"}}},
+		"invalid holiday":       {Holidays: []string{"25.12.2024"}},
+	}
+	for name, schedule := range invalid {
+		if err := schedule.Validate(); err == nil {
+			t.Errorf("%s: Validate() should fail", name)
+		}
+	}
+}
+
+func TestWorkSchedule_WorkingTime(t *testing.T) {
+	schedule := WorkSchedule{
+		Blocks: []WorkBlock{
+			{Weekday: time.Monday, Start: "09:00", End: "12:00"},
+			{Weekday: time.Monday, Start: "11:00", End: "13:00"}, // overlaps the first block
+			{Weekday: time.Tuesday, Start: "09:00", End: "17:00"},
+		},
+		Holidays: []string{"2024-05-07"},
+	}
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       time.Duration
+	}{
+		{"whole Monday", monday, monday.AddDate(0, 0, 1), 4 * time.Hour},
+		{"part of a block", monday.Add(8 * time.Hour), monday.Add(10 * time.Hour), time.Hour},
+		{"outside blocks", monday.Add(14 * time.Hour), monday.Add(20 * time.Hour), 0},
+		{"holiday Tuesday", monday.AddDate(0, 0, 1), monday.AddDate(0, 0, 2), 0},
+		{"across days", monday.Add(12 * time.Hour), monday.AddDate(0, 0, 7).Add(10 * time.Hour), 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := schedule.WorkingTime(tt.start, tt.end); got != tt.want {
+			t.Errorf("%s: WorkingTime() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if !schedule.Contains(monday.Add(9*time.Hour)) || schedule.Contains(monday.Add(13*time.Hour)) {
+		t.Error("Contains() should include block starts and exclude block ends")
+	}
+	if (WorkSchedule{}).Contains(monday.Add(10 * time.Hour)) {
+		t.Error("An empty schedule has no working hours")
+	}
+}
@@ -0,0 +1,190 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Device scopes accepted wherever usage can be read per device
+const (
+	// DeviceScopeLocal reads the usage tracked on this device only
+	DeviceScopeLocal = "local"
+	// DeviceScopeAll combines the usage of this device with every device synced from
+	DeviceScopeAll = "all"
+)
+
+// SyncEntryKind identifies what a change log entry adds to
+type SyncEntryKind string
+
+const (
+	// SyncEntryApp changes the time of one app on a day
+	SyncEntryApp SyncEntryKind = "app"
+	// SyncEntryTotal changes the total screen time of a day
+	SyncEntryTotal SyncEntryKind = "total"
+)
+
+// syncDateLayout is how change log entries write date keys
+const syncDateLayout = "2006-01-02"
+
+// SyncLogEntry is one change in a device's change log. Each device numbers its entries
+// from 1 without gaps and only ever appends to its own log, so merging logs is applying
+// every entry exactly once, in order, no matter which device reads them or when.
+type SyncLogEntry struct {
+	Seq        int64         `json:"seq"`
+	DeviceID   string        `json:"device"`
+	DeviceName string        `json:"deviceName,omitempty"`
+	Kind       SyncEntryKind `json:"kind"`
+	App        string        `json:"app,omitempty"` // empty for SyncEntryTotal
+	Date       string        `json:"date"`          // date key as YYYY-MM-DD
+	Delta      int64         `json:"delta"`         // seconds added; negative when usage was removed
+}
+
+// NewSyncLogEntry creates an entry for the usage day with the given date key
+func NewSyncLogEntry(device SyncDevice, seq int64, kind SyncEntryKind, app string, date time.Time, delta int64) SyncLogEntry {
+	return SyncLogEntry{
+		Seq:        seq,
+		DeviceID:   device.ID,
+		DeviceName: device.Name,
+		Kind:       kind,
+		App:        app,
+		Date:       DateKey(date).Format(syncDateLayout),
+		Delta:      delta,
+	}
+}
+
+// DateKey returns the date key of the day the entry changes
+func (e SyncLogEntry) DateKey() (time.Time, error) {
+	date, err := time.Parse(syncDateLayout, e.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q in change log entry %d", e.Date, e.Seq)
+	}
+	return date, nil
+}
+
+// Validate checks that the entry is numbered, names its device and changes a known kind of usage
+func (e SyncLogEntry) Validate() error {
+	if e.Seq <= 0 {
+		return fmt.Errorf("change log entry has invalid sequence number %d", e.Seq)
+	}
+	if err := ValidateDeviceID(e.DeviceID); err != nil {
+		return err
+	}
+	switch e.Kind {
+	case SyncEntryApp:
+		if e.App == "" {
+			return fmt.Errorf("app change log entry %d has no app name", e.Seq)
+		}
+	case SyncEntryTotal:
+		if e.App != "" {
+			return fmt.Errorf("total change log entry %d names an app", e.Seq)
+		}
+	default:
+		return fmt.Errorf("change log entry %d has unknown kind %q", e.Seq, e.Kind)
+	}
+	_, err := e.DateKey()
+	return err
+}
+
+// SyncSnapshot is a device's usage as of one entry of its change log. A device compacts
+// its log by writing a snapshot and dropping the entries it covers; a device that hasn't
+// applied them yet replaces its copy of the usage with the snapshot and continues after Seq.
+type SyncSnapshot struct {
+	DeviceID   string `json:"device"`
+	DeviceName string `json:"deviceName,omitempty"`
+	// Seq is the last change log entry the snapshot includes
+	Seq   int64       `json:"seq"`
+	Usage []SyncUsage `json:"usage,omitempty"`
+}
+
+// SyncUsage is the time of one app, or the total, on one day in a snapshot
+type SyncUsage struct {
+	Kind     SyncEntryKind `json:"kind"`
+	App      string        `json:"app,omitempty"` // empty for SyncEntryTotal
+	Date     string        `json:"date"`          // date key as YYYY-MM-DD
+	Duration int64         `json:"duration"`      // seconds
+}
+
+// Validate checks that the snapshot names its device and every usage is of a known kind
+func (s *SyncSnapshot) Validate() error {
+	if s.Seq < 0 {
+		return fmt.Errorf("snapshot has invalid sequence number %d", s.Seq)
+	}
+	if err := ValidateDeviceID(s.DeviceID); err != nil {
+		return err
+	}
+	for _, usage := range s.Usage {
+		// Checked as the entry that would add the usage to nothing
+		entry := SyncLogEntry{Seq: s.Seq + 1, DeviceID: s.DeviceID, Kind: usage.Kind, App: usage.App, Date: usage.Date}
+		if err := entry.Validate(); err != nil {
+			return fmt.Errorf("snapshot of %s up to entry %d: %w", s.DeviceID, s.Seq, err)
+		}
+	}
+	return nil
+}
+
+// ValidateDeviceID checks that id can name a device, and so its change log file.
+// The device scopes are reserved.
+func ValidateDeviceID(id string) error {
+	if id == "" || id == DeviceScopeLocal || id == DeviceScopeAll || len(id) > 64 {
+		return fmt.Errorf("invalid device ID %q", id)
+	}
+	if strings.IndexFunc(id, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_')
+	}) >= 0 {
+		return fmt.Errorf("invalid device ID %q: only letters, digits, '-' and '_' are allowed", id)
+	}
+	return nil
+}
+
+// SyncDevice is a device whose usage is synced
+type SyncDevice struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Local is set for the device this database belongs to
+	Local bool `json:"local"`
+	// LastSeq is the last change log entry applied from the device, or written by it when local
+	LastSeq      int64     `json:"lastSeq"`
+	LastSyncedAt time.Time `json:"lastSyncedAt"`
+}
+
+// SyncResult summarizes one sync run
+type SyncResult struct {
+	// Imported counts entries applied from other devices' change logs
+	Imported int `json:"imported"`
+	// Exported counts entries this device appended to its own change log
+	Exported int `json:"exported"`
+	// Devices counts the change logs that were read
+	Devices int `json:"devices"`
+	// Queued counts local entries waiting for a sync server to be reachable
+	Queued int `json:"queued,omitempty"`
+	// Errors lists change logs that could only be read partly or not at all
+	Errors []string `json:"errors,omitempty"`
+}
+
+// MaxSyncUploadEntries bounds the entries a sync server accepts in one upload
+const MaxSyncUploadEntries = 1000
+
+// SyncUpload is the body of an upload of a device's change log entries to a sync server
+type SyncUpload struct {
+	// UploadID names the upload, so a retry after a lost response is answered with the
+	// first response instead of being applied twice
+	UploadID string         `json:"uploadId"`
+	Entries  []SyncLogEntry `json:"entries"`
+}
+
+// SyncUploadResult is a sync server's answer to an upload. When the upload doesn't follow
+// the entries the server holds, LastSeq tells where the next upload has to continue.
+type SyncUploadResult struct {
+	UploadID string `json:"uploadId"`
+	Accepted int    `json:"accepted"`
+	// LastSeq is the last entry the server holds for the device
+	LastSeq int64 `json:"lastSeq"`
+}
+
+// SyncEntriesPage is a page of a device's change log read from a sync server
+type SyncEntriesPage struct {
+	Entries []SyncLogEntry `json:"entries"`
+	// More is set when entries after the page exist
+	More bool `json:"more"`
+}
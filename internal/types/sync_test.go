@@ -0,0 +1,56 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateDeviceID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"a1b2c3d4e5f60718", true},
+		{"work-laptop_2", true},
+		{"", false},
+		{DeviceScopeAll, false},
+		{DeviceScopeLocal, false},
+		{"../laptop", false},
+		{"laptop.sync-conflict", false},
+		{string(make([]byte, 65)), false},
+	}
+	for _, tt := range tests {
+		if err := ValidateDeviceID(tt.id); (err == nil) != tt.valid {
+			t.Errorf("ValidateDeviceID(%q) error = %v, want valid %v", tt.id, err, tt.valid)
+		}
+	}
+}
+
+func TestSyncLogEntry_Validate(t *testing.T) {
+	device := SyncDevice{ID: "laptop", Name: "Laptop"}
+	date := time.Date(2024, 3, 9, 23, 30, 0, 0, time.Local)
+
+	entry := NewSyncLogEntry(device, 1, SyncEntryApp, "Code", date, 60)
+	if err := entry.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if entry.Date != "2024-03-09" {
+		t.Errorf("Date = %q, want the date key of the day", entry.Date)
+	}
+	if key, err := entry.DateKey(); err != nil || !key.Equal(DateKey(date)) {
+		t.Errorf("DateKey() = (%v, %v), want %v", key, err, DateKey(date))
+	}
+
+	invalid := map[string]SyncLogEntry{
+		"unnumbered":        NewSyncLogEntry(device, 0, SyncEntryApp, "Code", date, 60),
+		"app without name":  NewSyncLogEntry(device, 1, SyncEntryApp, "", date, 60),
+		"total with an app": NewSyncLogEntry(device, 1, SyncEntryTotal, "Code", date, 60),
+		"unknown kind":      NewSyncLogEntry(device, 1, SyncEntryKind("week"), "", date, 60),
+		"bad date":          {Seq: 1, DeviceID: "laptop", Kind: SyncEntryTotal, Date: "09/03/2024"},
+	}
+	for name, entry := range invalid {
+		if err := entry.Validate(); err == nil {
+			t.Errorf("%s: expected Validate to fail", name)
+		}
+	}
+}
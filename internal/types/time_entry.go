@@ -0,0 +1,114 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeSource tells where usage came from
+type TimeSource string
+
+const (
+	// TimeSourceTracked is usage the tracker recorded
+	TimeSourceTracked TimeSource = "tracked"
+	// TimeSourceManual is usage added by hand, e.g. for meetings away from the computer
+	TimeSourceManual TimeSource = "manual"
+	// TimeSourceImported is usage brought in from another tool
+	TimeSourceImported TimeSource = "imported"
+	// TimeSourceCorrection adjusts an app's usage on a day by a signed duration, e.g. to
+	// take away time the tracker misattributed without overwriting what it recorded
+	TimeSourceCorrection TimeSource = "correction"
+)
+
+// MaxTimeEntryDuration bounds a single time entry to one day
+const MaxTimeEntryDuration = 24 * 60 * 60
+
+// TimeEntry is usage added to a day next to the tracked usage, which it never changes
+type TimeEntry struct {
+	ID        int64      `json:"id" db:"id"`
+	Date      time.Time  `json:"date" db:"date"` // date key of the day
+	AppName   string     `json:"appName" db:"app_name"`
+	Duration  int64      `json:"duration" db:"duration"` // in seconds
+	Source    TimeSource `json:"source" db:"source"`
+	Note      string     `json:"note" db:"note"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// Validate checks the entry's date, app name, duration and source. Only corrections may
+// be negative. Tracked usage is only ever recorded by the tracker, so it is not a valid source.
+func (e *TimeEntry) Validate() error {
+	if e.Date.IsZero() {
+		return fmt.Errorf("time entry date is missing")
+	}
+	if strings.TrimSpace(e.AppName) == "" {
+		return fmt.Errorf("time entry app name is empty")
+	}
+	switch e.Source {
+	case TimeSourceManual, TimeSourceImported:
+		if e.Duration <= 0 || e.Duration > MaxTimeEntryDuration {
+			return fmt.Errorf("time entry duration must be between 1 and %d seconds, got %d", MaxTimeEntryDuration, e.Duration)
+		}
+	case TimeSourceCorrection:
+		if e.Duration == 0 || e.Duration < -MaxTimeEntryDuration || e.Duration > MaxTimeEntryDuration {
+			return fmt.Errorf("correction must change usage by between 1 and %d seconds either way, got %d", MaxTimeEntryDuration, e.Duration)
+		}
+	default:
+		return fmt.Errorf("time entry source must be %q, %q or %q, got %q",
+			TimeSourceManual, TimeSourceImported, TimeSourceCorrection, e.Source)
+	}
+	return nil
+}
+
+// TimeEntryAction is the kind of change an audit record describes
+type TimeEntryAction string
+
+const (
+	TimeEntryCreated TimeEntryAction = "create"
+	TimeEntryUpdated TimeEntryAction = "update"
+	TimeEntryDeleted TimeEntryAction = "delete"
+)
+
+// TimeEntryEdit records one change to a time entry: the entry as it was after the change,
+// or as it was when it was deleted
+type TimeEntryEdit struct {
+	ID        int64           `json:"id" db:"id"`
+	EntryID   int64           `json:"entryId" db:"entry_id"`
+	Action    TimeEntryAction `json:"action" db:"action"`
+	Date      time.Time       `json:"date" db:"date"`
+	AppName   string          `json:"appName" db:"app_name"`
+	Duration  int64           `json:"duration" db:"duration"`
+	Source    TimeSource      `json:"source" db:"source"`
+	Note      string          `json:"note" db:"note"`
+	ChangedAt time.Time       `json:"changedAt" db:"changed_at"`
+}
+
+// AddTimeEntries adds entries to usage as apps of their own source, next to the tracked
+// apps, and counts them toward the total. Tracked apps are marked as such, and BySource
+// totals the usage of each source; corrections are listed with their signed duration and
+// BySource holds their net change.
+func (u *UsageData) AddTimeEntries(entries []TimeEntry) {
+	if u.BySource == nil {
+		for i := range u.Apps {
+			if u.Apps[i].Source == "" {
+				u.Apps[i].Source = TimeSourceTracked
+			}
+		}
+		u.BySource = map[TimeSource]int64{TimeSourceTracked: u.TotalTime}
+	}
+
+	for _, entry := range entries {
+		u.Apps = append(u.Apps, AppUsage{
+			Name:      entry.AppName,
+			Duration:  entry.Duration,
+			Date:      entry.Date,
+			Source:    entry.Source,
+			EntryID:   entry.ID,
+			CreatedAt: entry.CreatedAt,
+			UpdatedAt: entry.UpdatedAt,
+		})
+		u.TotalTime += entry.Duration
+		u.BySource[entry.Source] += entry.Duration
+	}
+}
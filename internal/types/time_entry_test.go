@@ -0,0 +1,64 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeEntry_Validate(t *testing.T) {
+	date := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	for _, valid := range []TimeEntry{
+		{Date: date, AppName: "Meeting", Duration: 3600, Source: TimeSourceManual},
+		{Date: date, AppName: "Browser", Duration: -1800, Source: TimeSourceCorrection},
+		{Date: date, AppName: "Browser", Duration: 600, Source: TimeSourceCorrection},
+	} {
+		if err := valid.Validate(); err != nil {
+			t.Fatalf("Validate(%+v) error = %v", valid, err)
+		}
+	}
+
+	invalid := map[string]TimeEntry{
+		"missing date":     {AppName: "Meeting", Duration: 3600, Source: TimeSourceManual},
+		"empty app name":   {Date: date, AppName: " ", Duration: 3600, Source: TimeSourceManual},
+		"no duration":      {Date: date, AppName: "Meeting", Source: TimeSourceManual},
+		"longer than day":  {Date: date, AppName: "Meeting", Duration: MaxTimeEntryDuration + 1, Source: TimeSourceManual},
+		"negative manual":  {Date: date, AppName: "Meeting", Duration: -60, Source: TimeSourceManual},
+		"empty correction": {Date: date, AppName: "Meeting", Source: TimeSourceCorrection},
+		"tracked source":   {Date: date, AppName: "Meeting", Duration: 3600, Source: TimeSourceTracked},
+		"unknown source":   {Date: date, AppName: "Meeting", Duration: 3600, Source: "guessed"},
+	}
+	for name, entry := range invalid {
+		if err := entry.Validate(); err == nil {
+			t.Errorf("%s: Validate() should fail", name)
+		}
+	}
+}
+
+func TestUsageData_AddTimeEntries(t *testing.T) {
+	usage := &UsageData{TotalTime: 600, Apps: []AppUsage{{Name: "Editor", Duration: 600}}}
+
+	usage.AddTimeEntries([]TimeEntry{
+		{ID: 1, AppName: "Editor", Duration: 300, Source: TimeSourceManual},
+		{ID: 2, AppName: "Call", Duration: 120, Source: TimeSourceImported},
+		{ID: 3, AppName: "Editor", Duration: -200, Source: TimeSourceCorrection},
+	})
+
+	if usage.TotalTime != 820 || len(usage.Apps) != 4 {
+		t.Fatalf("AddTimeEntries() = %+v", usage)
+	}
+	if usage.Apps[0].Source != TimeSourceTracked || usage.Apps[0].Duration != 600 {
+		t.Errorf("tracked app = %+v, want it unchanged and marked tracked", usage.Apps[0])
+	}
+	if usage.Apps[1].EntryID != 1 || usage.Apps[1].Source != TimeSourceManual {
+		t.Errorf("manual entry = %+v", usage.Apps[1])
+	}
+	if usage.Apps[3].Source != TimeSourceCorrection || usage.Apps[3].Duration != -200 {
+		t.Errorf("correction = %+v, want it listed with its signed duration", usage.Apps[3])
+	}
+	want := map[TimeSource]int64{TimeSourceTracked: 600, TimeSourceManual: 300, TimeSourceImported: 120, TimeSourceCorrection: -200}
+	for source, total := range want {
+		if usage.BySource[source] != total {
+			t.Errorf("BySource[%s] = %d, want %d", source, usage.BySource[source], total)
+		}
+	}
+}
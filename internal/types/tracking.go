@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+// PrivateAppName is the anonymous bucket that receives all time tracked in private mode
+const PrivateAppName = "Private"
+
+// TrackingState describes whether and how the tracker is currently recording usage
+type TrackingState struct {
+	Running     bool       `json:"running"`
+	Paused      bool       `json:"paused"`
+	PausedAt    *time.Time `json:"pausedAt,omitempty"`
+	PausedUntil *time.Time `json:"pausedUntil,omitempty"` // nil while paused means until resumed
+	PrivateMode bool       `json:"privateMode"`
+}
+
+// PersistenceStatus describes how far tracked usage has been written to the database
+type PersistenceStatus struct {
+	Enabled         bool       `json:"enabled"`
+	LastPersistedAt *time.Time `json:"lastPersistedAt,omitempty"` // nil until a write succeeds
+	PendingSeconds  int64      `json:"pendingSeconds"`            // Tracked app time not written yet
+	PendingDays     int        `json:"pendingDays"`               // Days queued or journaled after failed writes
+}
@@ -8,16 +8,42 @@ type AppUsage struct {
 	Name      string    `json:"name" db:"name"`
 	Duration  int64     `json:"duration" db:"duration"` // in seconds
 	IconPath  string    `json:"iconPath" db:"icon_path"`
+	IconHash  string    `json:"iconHash,omitempty" db:"icon_hash"` // set when the icon is stored in app_icons
 	ExePath   string    `json:"exePath" db:"exe_path"`
 	Date      time.Time `json:"date" db:"date"`
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+
+	// ApplicationID references the canonical application this usage belongs to, and
+	// DisplayName is its label when read from history
+	ApplicationID int64  `json:"applicationId,omitempty" db:"application_id"`
+	DisplayName   string `json:"displayName,omitempty" db:"-"`
+	// Publisher and Version describe the executable when known. They are stored
+	// on the application rather than on each usage row.
+	Publisher string `json:"publisher,omitempty" db:"-"`
+	Version   string `json:"version,omitempty" db:"-"`
+
+	// Source tells tracked usage apart from time entries when they are listed together;
+	// EntryID is then set for time entries
+	Source  TimeSource `json:"source,omitempty" db:"-"`
+	EntryID int64      `json:"entryId,omitempty" db:"-"`
+}
+
+// Label returns the name to show for the usage: its application's label when known,
+// otherwise the name it was tracked under
+func (a AppUsage) Label() string {
+	if a.DisplayName != "" {
+		return a.DisplayName
+	}
+	return a.Name
 }
 
 // UsageData represents the complete usage data
 type UsageData struct {
 	TotalTime int64      `json:"totalTime"` // in seconds
 	Apps      []AppUsage `json:"apps"`
+	// BySource totals usage per source once time entries were added; nil for tracked usage alone
+	BySource map[TimeSource]int64 `json:"bySource,omitempty"`
 }
 
 // DailyUsage represents daily usage summary
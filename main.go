@@ -3,6 +3,7 @@ package main
 import (
 	"embed"
 	"log"
+	"os"
 
 	"qwin/internal/app"
 	"qwin/internal/infrastructure/logging"
@@ -30,6 +31,15 @@ func main() {
 	if AppEnvironment == "" {
 		AppEnvironment = "development"
 	}
+
+	// "qwin migrate ..." manages the database schema without starting the UI
+	if handled, err := app.RunMigrationCommand(AppEnvironment, os.Args[1:], os.Stdout); handled {
+		if err != nil {
+			log.Fatalf("Migration command failed: %v", err)
+		}
+		return
+	}
+
 	log.Printf("Application starting in '%s' mode", AppEnvironment)
 
 	// Create an instance of the app structure
@@ -64,7 +74,8 @@ func main() {
 		AlwaysOnTop:       false,
 		BackgroundColour:  &options.RGBA{R: 0, G: 0, B: 0, A: 0},
 		AssetServer: &assetserver.Options{
-			Assets: assets,
+			Assets:  assets,
+			Handler: application.IconHandler(),
 		},
 		Menu:             nil,
 		Logger:           wailsLogger,